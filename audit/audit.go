@@ -0,0 +1,71 @@
+// Package audit records changes of resources in the append-only audit_log
+// table. Stores write an entry with the same transaction as the change, so
+// the log holds exactly the changes that were committed.
+package audit
+
+import (
+	"context"
+	"go-sample-rest-api/config"
+	"go-sample-rest-api/db"
+	auth2 "go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"go-sample-rest-api/utils"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+// FromRequest starts an entry for the action with the actor, request ID and
+// client IP of the request. The store completes it with the resource and
+// its changes.
+func FromRequest(r *http.Request, action string) types.AuditEntry {
+	return FromContext(r.Context(), action, utils.ClientIP(r, config.Envs.TrustProxyHeaders))
+}
+
+// FromContext is FromRequest for the APIs that are not served over plain
+// HTTP, which know the actor and request ID from ctx and the client IP.
+func FromContext(ctx context.Context, action, ip string) types.AuditEntry {
+	entry := types.AuditEntry{
+		ActorType: types.AuditActorSystem,
+		Action:    action,
+		RequestID: utils.GetRequestID(ctx),
+		IP:        ip,
+	}
+
+	if userID := auth2.GetUserIDFromContext(ctx); userID > 0 {
+		entry.ActorType = types.AuditActorUser
+		entry.ActorID = strconv.Itoa(userID)
+	}
+
+	return entry
+}
+
+// Changes compares the fields of a resource before and after a change and
+// returns the ones that differ. A nil map stands for a resource that did not
+// exist before or does not exist after.
+func Changes(before, after map[string]any) types.AuditChanges {
+	changes := types.AuditChanges{}
+	for field, value := range after {
+		if old, ok := before[field]; !ok || !reflect.DeepEqual(old, value) {
+			changes[field] = types.AuditChange{Before: before[field], After: value}
+		}
+	}
+	for field, old := range before {
+		if _, ok := after[field]; !ok {
+			changes[field] = types.AuditChange{Before: old}
+		}
+	}
+
+	return changes
+}
+
+// Write appends the entry. Call it with the transaction of the change.
+func Write(ctx context.Context, tx db.DB, entry types.AuditEntry) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO audit_log
+              (org_id, actor_type, actor_id, action, resource_type, resource_id, changes, request_id, ip)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		entry.OrgID, entry.ActorType, entry.ActorID, entry.Action, entry.ResourceType, entry.ResourceID,
+		entry.Changes, entry.RequestID, entry.IP)
+
+	return err
+}
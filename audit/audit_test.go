@@ -0,0 +1,63 @@
+package audit
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	auth2 "go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"net/http"
+	"testing"
+)
+
+func TestChanges(t *testing.T) {
+	t.Run("returns only changed fields", func(t *testing.T) {
+		// act
+		changes := Changes(
+			map[string]any{"name": "Front", "tags": types.Tags{"site": "berlin"}},
+			map[string]any{"name": "Back", "tags": types.Tags{"site": "berlin"}},
+		)
+
+		// assert
+		assert.Equal(t, types.AuditChanges{"name": {Before: "Front", After: "Back"}}, changes)
+	})
+
+	t.Run("created and deleted resources", func(t *testing.T) {
+		// act
+		created := Changes(nil, map[string]any{"role": "user"})
+		deleted := Changes(map[string]any{"role": "user"}, nil)
+
+		// assert
+		assert.Equal(t, types.AuditChanges{"role": {After: "user"}}, created)
+		assert.Equal(t, types.AuditChanges{"role": {Before: "user"}}, deleted)
+	})
+}
+
+func TestFromRequest(t *testing.T) {
+	t.Run("user of the token is the actor", func(t *testing.T) {
+		// arrange
+		req, _ := http.NewRequest(http.MethodDelete, "/camera_metadata/cam-1", nil)
+		req.RemoteAddr = "203.0.113.7:51234"
+		req = req.WithContext(context.WithValue(req.Context(), auth2.UserKey, 7))
+
+		// act
+		entry := FromRequest(req, "camera.delete")
+
+		// assert
+		assert.Equal(t, types.AuditActorUser, entry.ActorType)
+		assert.Equal(t, "7", entry.ActorID)
+		assert.Equal(t, "camera.delete", entry.Action)
+		assert.Equal(t, "203.0.113.7", entry.IP)
+	})
+
+	t.Run("without a user the system is the actor", func(t *testing.T) {
+		// arrange
+		req, _ := http.NewRequest(http.MethodPost, "/users/1/erase", nil)
+
+		// act
+		entry := FromRequest(req, "user.erase")
+
+		// assert
+		assert.Equal(t, types.AuditActorSystem, entry.ActorType)
+		assert.Empty(t, entry.ActorID)
+	})
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"go-sample-rest-api/types"
+	"net/http"
+)
+
+// Register creates an account and its organization. The account can log in
+// once its email address is verified with the link the API sends.
+func (c *Client) Register(ctx context.Context, payload types.RegisterUserPayload) error {
+	return c.do(ctx, request{method: http.MethodPost, path: "/register", body: payload})
+}
+
+// Login logs in with a password. The client keeps the credentials to log in
+// again when the access token expires. For accounts with two-factor
+// authentication Login returns a *TwoFactorRequiredError; such logins end
+// when the token expires.
+func (c *Client) Login(ctx context.Context, email, password string) error {
+	return c.login(ctx, types.LoginUserPayload{Email: email, Password: password})
+}
+
+// LoginWithCode completes a login with the challenge token of a
+// *TwoFactorRequiredError and a TOTP or recovery code.
+func (c *Client) LoginWithCode(ctx context.Context, challengeToken, code string) error {
+	var response struct {
+		Token string `json:"token"`
+	}
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/login/2fa",
+		body:   types.LoginChallengePayload{ChallengeToken: challengeToken, Code: code},
+		out:    &response,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.token, c.credentials = response.Token, nil
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Client) login(ctx context.Context, credentials types.LoginUserPayload) error {
+	var response struct {
+		Token          string `json:"token"`
+		ChallengeToken string `json:"challengeToken"`
+	}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/login", body: credentials, out: &response})
+	if err != nil {
+		return err
+	}
+
+	if response.ChallengeToken != "" {
+		return &TwoFactorRequiredError{ChallengeToken: response.ChallengeToken}
+	}
+
+	c.mu.Lock()
+	c.token, c.credentials = response.Token, &credentials
+	c.mu.Unlock()
+	return nil
+}
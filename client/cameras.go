@@ -0,0 +1,146 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/google/uuid"
+	"go-sample-rest-api/types"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ListCamerasOptions selects the cameras of ListCameras. The zero value lists
+// the first page of all cameras.
+type ListCamerasOptions struct {
+	GroupID *int
+	// Selector is a tag selector such as "site=berlin,!outdoor".
+	Selector string
+	Within   *types.BoundingBox
+	Near     *types.GeoCircle
+	Page     int
+	PageSize int
+}
+
+func (o ListCamerasOptions) query() url.Values {
+	query := url.Values{}
+	if o.GroupID != nil {
+		query.Set("group", strconv.Itoa(*o.GroupID))
+	}
+	if o.Selector != "" {
+		query.Set("selector", o.Selector)
+	}
+	if b := o.Within; b != nil {
+		query.Set("bbox", fmt.Sprintf("%s,%s,%s,%s", formatFloat(b.MinLongitude), formatFloat(b.MinLatitude),
+			formatFloat(b.MaxLongitude), formatFloat(b.MaxLatitude)))
+	}
+	if n := o.Near; n != nil {
+		query.Set("lat", formatFloat(n.Latitude))
+		query.Set("lon", formatFloat(n.Longitude))
+		query.Set("radius", formatFloat(n.RadiusMeters))
+	}
+	if o.Page > 0 {
+		query.Set("page", strconv.Itoa(o.Page))
+	}
+	if o.PageSize > 0 {
+		query.Set("pageSize", strconv.Itoa(o.PageSize))
+	}
+	return query
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// The API has no call to delete a single camera; cameras are removed with
+// the erasure of their owner.
+
+func (c *Client) CreateCamera(ctx context.Context, payload types.CameraMetadataPayload) (*types.CameraMetadataResponse, error) {
+	var camera types.CameraMetadataResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/camera_metadata", body: payload, out: &camera, auth: true})
+	if err != nil {
+		return nil, err
+	}
+	return &camera, nil
+}
+
+func (c *Client) GetCamera(ctx context.Context, camID string) (*types.CameraMetadataResponse, error) {
+	var camera types.CameraMetadataResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: cameraPath(camID, ""), out: &camera, auth: true})
+	if err != nil {
+		return nil, err
+	}
+	return &camera, nil
+}
+
+func (c *Client) ListCameras(ctx context.Context, options ListCamerasOptions) (*types.CameraList, error) {
+	var list types.CameraList
+	err := c.do(ctx, request{method: http.MethodGet, path: "/camera_metadata", query: options.query(), out: &list, auth: true})
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// InitializeCamera marks the camera as initialized, which is required before
+// uploading images. A second call fails with customerrors.AlreadyInitError.
+func (c *Client) InitializeCamera(ctx context.Context, camID string) error {
+	return c.do(ctx, request{method: http.MethodPatch, path: cameraPath(camID, "/init"), auth: true})
+}
+
+// SetCameraGroup moves the camera into the group, or out of its group for nil.
+func (c *Client) SetCameraGroup(ctx context.Context, camID string, groupID *int) (*types.CameraMetadataResponse, error) {
+	return c.updateCamera(ctx, http.MethodPut, camID, "/group", types.CameraGroupAssignmentPayload{GroupID: groupID})
+}
+
+// SetCameraTags replaces the tags of the camera.
+func (c *Client) SetCameraTags(ctx context.Context, camID string, tags types.Tags) (*types.CameraMetadataResponse, error) {
+	return c.updateCamera(ctx, http.MethodPut, camID, "/tags", types.CameraTagsPayload{Tags: tags})
+}
+
+func (c *Client) SetCameraLocation(ctx context.Context, camID string, location types.CameraLocationPayload) (*types.CameraMetadataResponse, error) {
+	return c.updateCamera(ctx, http.MethodPut, camID, "/location", location)
+}
+
+func (c *Client) DeleteCameraLocation(ctx context.Context, camID string) (*types.CameraMetadataResponse, error) {
+	return c.updateCamera(ctx, http.MethodDelete, camID, "/location", nil)
+}
+
+func (c *Client) updateCamera(ctx context.Context, method, camID, suffix string, payload any) (*types.CameraMetadataResponse, error) {
+	var camera types.CameraMetadataResponse
+	err := c.do(ctx, request{method: method, path: cameraPath(camID, suffix), body: payload, out: &camera, auth: true})
+	if err != nil {
+		return nil, err
+	}
+	return &camera, nil
+}
+
+// UploadImage stores the image as the image of the initialized camera under
+// a new image ID. Uploads are not retried.
+func (c *Client) UploadImage(ctx context.Context, camID string, image []byte) (*types.ImageUploadedResponse, error) {
+	query := url.Values{}
+	query.Set("imageID", uuid.New().String())
+	query.Set("image_as_bytes", base64.StdEncoding.EncodeToString(image))
+
+	var response types.ImageUploadedResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: cameraPath(camID, "/upload_image"), query: query, out: &response, auth: true})
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// DownloadImage returns the current image of the camera.
+func (c *Client) DownloadImage(ctx context.Context, camID string) ([]byte, error) {
+	var image []byte
+	err := c.do(ctx, request{method: http.MethodGet, path: cameraPath(camID, "/download_image"), out: &image, auth: true})
+	if err != nil {
+		return nil, err
+	}
+	return image, nil
+}
+
+func cameraPath(camID, suffix string) string {
+	return "/camera_metadata/" + url.PathEscape(camID) + suffix
+}
@@ -0,0 +1,301 @@
+// Package client is a typed Go client for the REST API under /api/v1. It
+// uses the request and response types of package types, turns error
+// responses into *APIError wrapping the matching customerrors type, logs in
+// again when the access token expired and retries idempotent requests that
+// failed for transient reasons.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-sample-rest-api/types"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultBackoff    = 200 * time.Millisecond
+	maxBackoff        = 5 * time.Second
+	// refreshMargin is how long before its expiry a token is replaced.
+	refreshMargin = time.Minute
+)
+
+// Client calls the API at one base URL. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	maxRetries int
+	backoff    time.Duration
+
+	mu    sync.Mutex
+	token string
+	// credentials of the last login with a password, used to log in again.
+	// Logins with a second factor cannot be repeated and leave it nil.
+	credentials *types.LoginUserPayload
+	// refreshMu serializes logins that replace an expired token.
+	refreshMu sync.Mutex
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sends the requests with httpClient instead of http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithRetries retries idempotent requests up to maxRetries times, waiting
+// backoff before the first retry and twice as long before each further one.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) { c.maxRetries, c.backoff = maxRetries, backoff }
+}
+
+// WithToken uses an access token obtained elsewhere, such as from single
+// sign-on. It is not replaced when it expires.
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// New returns a client for the API at baseURL, such as
+// "https://cameras.example.com/api/v1".
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		maxRetries: defaultMaxRetries,
+		backoff:    defaultBackoff,
+	}
+	for _, option := range options {
+		option(c)
+	}
+
+	return c
+}
+
+// Token returns the current access token, or "" before a login.
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// request is one call of the API. Body is sent as JSON. Out receives the
+// JSON response, or the raw body if it is a *[]byte.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   any
+	out    any
+	// auth sends the access token.
+	auth bool
+}
+
+// do sends the request. Idempotent requests are retried after transport
+// errors and answers that ask to try again; a request rejected for its
+// token is repeated once with a new token if the client can log in again.
+func (c *Client) do(ctx context.Context, req request) error {
+	var body []byte
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return fmt.Errorf("failed to encode request: %v", err)
+		}
+	}
+
+	token := ""
+	if req.auth {
+		var err error
+		if token, err = c.validToken(ctx); err != nil {
+			return err
+		}
+	}
+
+	resp, err := c.send(ctx, req, body, token)
+	if err == nil && req.auth && resp.StatusCode == http.StatusUnauthorized && c.canRefresh() {
+		resp.Body.Close()
+		if token, err = c.refresh(ctx, token); err != nil {
+			return err
+		}
+		resp, err = c.send(ctx, req, body, token)
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return newAPIError(resp)
+	}
+
+	switch out := req.out.(type) {
+	case nil:
+		return nil
+	case *[]byte:
+		*out, err = io.ReadAll(resp.Body)
+		return err
+	default:
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode response: %v", err)
+		}
+		return nil
+	}
+}
+
+// send sends the request with its retries and returns the last response.
+func (c *Client) send(ctx context.Context, req request, body []byte, token string) (*http.Response, error) {
+	target := c.baseURL + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+
+	retries := 0
+	if idempotent(req.method) {
+		retries = c.maxRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		httpReq, err := http.NewRequestWithContext(ctx, req.method, target, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			httpReq.Header.Set("Content-Type", "application/json")
+		}
+		if token != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := c.httpClient.Do(httpReq)
+		if attempt >= retries || !retryable(resp, err) || ctx.Err() != nil {
+			return resp, err
+		}
+
+		wait := c.backoff << attempt
+		if resp != nil {
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+				wait = time.Duration(seconds) * time.Second
+			}
+			resp.Body.Close()
+		}
+		if err := sleep(ctx, min(wait, maxBackoff)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// idempotent reports whether repeating a request of the method has the same
+// effect as sending it once.
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryable reports whether a failed attempt may succeed when repeated.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// sleep waits for d with up to a quarter of jitter, so clients that failed
+// together do not retry together.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d > 0 {
+		d += time.Duration(rand.Int63n(int64(d)/4 + 1))
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// validToken returns the access token, logging in again first when it is
+// about to expire.
+func (c *Client) validToken(ctx context.Context) (string, error) {
+	token := c.Token()
+	if token == "" {
+		return "", errors.New("not logged in")
+	}
+
+	if expiry, ok := tokenExpiry(token); ok && time.Until(expiry) < refreshMargin && c.canRefresh() {
+		return c.refresh(ctx, token)
+	}
+
+	return token, nil
+}
+
+func (c *Client) canRefresh() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.credentials != nil
+}
+
+// refresh replaces the stale token by logging in again. If another request
+// replaced it in the meantime, that token is used.
+func (c *Client) refresh(ctx context.Context, stale string) (string, error) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	c.mu.Lock()
+	token, credentials := c.token, c.credentials
+	c.mu.Unlock()
+	if token != stale || credentials == nil {
+		return token, nil
+	}
+
+	if err := c.login(ctx, *credentials); err != nil {
+		return "", fmt.Errorf("failed to refresh token: %w", err)
+	}
+
+	return c.Token(), nil
+}
+
+// tokenExpiry reads the expiry of the access token. The token is not
+// verified, only the server can do that.
+func tokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var claims struct {
+		ExpiresAt int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.ExpiresAt == 0 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(claims.ExpiresAt, 0), true
+}
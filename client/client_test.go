@@ -0,0 +1,270 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"go-sample-rest-api/customerrors"
+	"go-sample-rest-api/types"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const password = "Correct-horse-battery-9"
+
+// loggedIn registers and verifies an account and returns a client logged in
+// to it.
+func loggedIn(t *testing.T, s *testServer, email string, options ...Option) *Client {
+	t.Helper()
+	c := s.client(options...)
+	err := c.Register(context.Background(), types.RegisterUserPayload{FirstName: "Ann", LastName: "Lee", Email: email, Password: password})
+	if err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+	s.users.verify(email)
+	if err := c.Login(context.Background(), email, password); err != nil {
+		t.Fatalf("failed to log in: %v", err)
+	}
+	return c
+}
+
+func TestClient_Cameras(t *testing.T) {
+	t.Run("creates a camera and stores its image", func(t *testing.T) {
+		// arrange
+		s := startServer(t)
+		c := loggedIn(t, s, "ann@example.com")
+		ctx := context.Background()
+		image := []byte("\x89PNG image")
+
+		// act
+		created, err := c.CreateCamera(ctx, types.CameraMetadataPayload{CameraName: "gate", FirmwareVersion: "1.0.0"})
+		assert.NoError(t, err)
+		assert.NoError(t, c.InitializeCamera(ctx, created.CamID))
+		tagged, tagErr := c.SetCameraTags(ctx, created.CamID, types.Tags{"site": "berlin"})
+		uploaded, uploadErr := c.UploadImage(ctx, created.CamID, image)
+		downloaded, downloadErr := c.DownloadImage(ctx, created.CamID)
+		camera, getErr := c.GetCamera(ctx, created.CamID)
+		list, listErr := c.ListCameras(ctx, ListCamerasOptions{PageSize: 10})
+
+		// assert
+		assert.NoError(t, tagErr)
+		assert.Equal(t, types.Tags{"site": "berlin"}, tagged.Tags)
+		assert.NoError(t, uploadErr)
+		assert.NotEmpty(t, uploaded.ImageId)
+		assert.NoError(t, downloadErr)
+		assert.Equal(t, image, downloaded)
+		assert.NoError(t, getErr)
+		assert.Equal(t, "gate", camera.CameraName)
+		assert.NoError(t, listErr)
+		assert.Equal(t, 1, list.Total)
+		assert.Equal(t, 10, list.PageSize)
+		assert.Equal(t, created.CamID, list.Cameras[0].CamID)
+	})
+
+	t.Run("returns the errors of the API as customerrors", func(t *testing.T) {
+		// arrange
+		s := startServer(t)
+		c := loggedIn(t, s, "ann@example.com")
+		ctx := context.Background()
+		created, err := c.CreateCamera(ctx, types.CameraMetadataPayload{CameraName: "gate", FirmwareVersion: "1.0.0"})
+		assert.NoError(t, err)
+		unknown := "7f9c24e8-3b12-4fef-91e0-3e5d3f2c1a00"
+
+		// act
+		_, uploadErr := c.UploadImage(ctx, created.CamID, []byte("image"))
+		assert.NoError(t, c.InitializeCamera(ctx, created.CamID))
+		initErr := c.InitializeCamera(ctx, created.CamID)
+		_, getErr := c.GetCamera(ctx, unknown)
+
+		// assert
+		var notInit *customerrors.NotInitError
+		assert.ErrorAs(t, uploadErr, &notInit)
+		assert.Equal(t, created.CamID, notInit.ID)
+
+		var alreadyInit *customerrors.AlreadyInitError
+		assert.ErrorAs(t, initErr, &alreadyInit)
+
+		var notFound *customerrors.NotFoundError
+		assert.ErrorAs(t, getErr, &notFound)
+		assert.Equal(t, unknown, notFound.ID)
+		var apiErr *APIError
+		assert.ErrorAs(t, getErr, &apiErr)
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	})
+}
+
+func TestClient_Register(t *testing.T) {
+	// arrange
+	s := startServer(t)
+	c := s.client()
+
+	// act
+	err := c.Register(context.Background(), types.RegisterUserPayload{FirstName: "Ann", LastName: "Lee", Email: "ann@example.com", Password: "short"})
+
+	// assert
+	var policyErr *customerrors.PasswordPolicyError
+	assert.ErrorAs(t, err, &policyErr)
+	assert.NotEmpty(t, policyErr.Violations)
+}
+
+func TestClient_Login(t *testing.T) {
+	t.Run("rejects a wrong password", func(t *testing.T) {
+		// arrange
+		s := startServer(t)
+		loggedIn(t, s, "ann@example.com")
+		c := s.client()
+
+		// act
+		err := c.Login(context.Background(), "ann@example.com", "wrong")
+
+		// assert
+		var apiErr *APIError
+		assert.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+		assert.Empty(t, c.Token())
+	})
+
+	t.Run("asks for the second factor", func(t *testing.T) {
+		// arrange
+		s := startServer(t)
+		loggedIn(t, s, "ann@example.com")
+		s.users.update("ann@example.com", func(u *types.User) { u.TOTPEnabledAt.Valid = true })
+		c := s.client()
+
+		// act
+		err := c.Login(context.Background(), "ann@example.com", password)
+
+		// assert
+		var twoFactor *TwoFactorRequiredError
+		assert.ErrorAs(t, err, &twoFactor)
+		assert.NotEmpty(t, twoFactor.ChallengeToken)
+		assert.Empty(t, c.Token())
+	})
+}
+
+func TestClient_TokenRefresh(t *testing.T) {
+	t.Run("logs in again when the token is rejected", func(t *testing.T) {
+		// arrange
+		s := startServer(t)
+		c := loggedIn(t, s, "ann@example.com")
+		token := c.Token()
+		s.users.revokeSessions()
+
+		// act
+		_, err := c.ListCameras(context.Background(), ListCamerasOptions{})
+
+		// assert
+		assert.NoError(t, err)
+		assert.NotEqual(t, token, c.Token())
+		statuses := s.statuses()
+		assert.Equal(t, []int{http.StatusUnauthorized, http.StatusOK, http.StatusOK}, statuses[len(statuses)-3:])
+	})
+
+	t.Run("logs in again before the token expires", func(t *testing.T) {
+		// arrange
+		s := startServer(t)
+		c := loggedIn(t, s, "ann@example.com")
+		c.token = withExpiry(t, c.Token(), time.Now().Add(refreshMargin/2))
+		before := len(s.statuses())
+
+		// act
+		_, err := c.ListCameras(context.Background(), ListCamerasOptions{})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, []int{http.StatusOK, http.StatusOK}, s.statuses()[before:])
+	})
+
+	t.Run("keeps a token given to it", func(t *testing.T) {
+		// arrange
+		s := startServer(t)
+		token := loggedIn(t, s, "ann@example.com").Token()
+		c := s.client(WithToken(token))
+		s.users.revokeSessions()
+
+		// act
+		_, err := c.ListCameras(context.Background(), ListCamerasOptions{})
+
+		// assert
+		var apiErr *APIError
+		assert.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+		assert.Equal(t, token, c.Token())
+	})
+}
+
+// withExpiry returns the token with another expiry. Its signature no longer
+// matches, so the server would reject it.
+func withExpiry(t *testing.T, token string, expiry time.Time) string {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatalf("failed to decode token: %v", err)
+	}
+	claims["exp"] = expiry.Unix()
+	payload, _ = json.Marshal(claims)
+	parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+	return strings.Join(parts, ".")
+}
+
+func TestClient_Retries(t *testing.T) {
+	t.Run("retries idempotent requests", func(t *testing.T) {
+		// arrange
+		s := startServer(t)
+		c := loggedIn(t, s, "ann@example.com")
+		failures := 2
+		s.fail = func(r *http.Request) int {
+			if failures > 0 {
+				failures--
+				return http.StatusServiceUnavailable
+			}
+			return 0
+		}
+
+		// act
+		_, err := c.ListCameras(context.Background(), ListCamerasOptions{})
+
+		// assert
+		assert.NoError(t, err)
+		statuses := s.statuses()
+		assert.Equal(t, []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK}, statuses[len(statuses)-3:])
+	})
+
+	t.Run("gives up after the retries", func(t *testing.T) {
+		// arrange
+		s := startServer(t)
+		c := loggedIn(t, s, "ann@example.com")
+		before := len(s.statuses())
+		s.fail = func(r *http.Request) int { return http.StatusBadGateway }
+
+		// act
+		_, err := c.GetCamera(context.Background(), "7f9c24e8-3b12-4fef-91e0-3e5d3f2c1a00")
+
+		// assert
+		var apiErr *APIError
+		assert.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusBadGateway, apiErr.StatusCode)
+		assert.Len(t, s.statuses()[before:], 4)
+	})
+
+	t.Run("does not repeat other requests", func(t *testing.T) {
+		// arrange
+		s := startServer(t)
+		c := loggedIn(t, s, "ann@example.com")
+		before := len(s.statuses())
+		s.fail = func(r *http.Request) int { return http.StatusServiceUnavailable }
+
+		// act
+		_, err := c.CreateCamera(context.Background(), types.CameraMetadataPayload{CameraName: "gate", FirmwareVersion: "1.0.0"})
+
+		// assert
+		var apiErr *APIError
+		assert.ErrorAs(t, err, &apiErr)
+		assert.Len(t, s.statuses()[before:], 1)
+	})
+}
@@ -0,0 +1,310 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go-sample-rest-api/customerrors"
+	"go-sample-rest-api/mailer"
+	auth2 "go-sample-rest-api/service/auth"
+	"go-sample-rest-api/service/camerametadata"
+	"go-sample-rest-api/service/user"
+	"go-sample-rest-api/storage"
+	"go-sample-rest-api/types"
+	"go-sample-rest-api/utils"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer runs the real user and camera handlers on in-memory stores.
+type testServer struct {
+	*httptest.Server
+	users  *memoryUserStore
+	mu     sync.Mutex
+	status []int
+	// fail answers requests with its status code while it returns one.
+	fail func(r *http.Request) int
+}
+
+func startServer(t *testing.T) *testServer {
+	t.Helper()
+	s := &testServer{users: newMemoryUserStore()}
+
+	router := mux.NewRouter()
+	router.Use(utils.WithRequestID)
+	subrouter := router.PathPrefix("/api/v1").Subrouter()
+	user.NewHandler(s.users, auth2.NewAuthenticator(), discardMailer{}).RegisterRoutes(subrouter)
+	camerametadata.NewHandler(newMemoryCameraStore(), s.users, newMemoryImageStore(), nil).RegisterRoutes(subrouter)
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		if s.fail != nil {
+			if status := s.fail(r); status != 0 {
+				recorder.WriteHeader(status)
+			}
+		}
+		if !recorder.written {
+			router.ServeHTTP(recorder, r)
+		}
+
+		s.mu.Lock()
+		s.status = append(s.status, recorder.status)
+		s.mu.Unlock()
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// statuses returns the status codes of the requests so far.
+func (s *testServer) statuses() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int(nil), s.status...)
+}
+
+func (s *testServer) client(options ...Option) *Client {
+	return New(s.URL+"/api/v1", append([]Option{WithRetries(3, time.Millisecond)}, options...)...)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status  int
+	written bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status, r.written = status, true
+	r.ResponseWriter.WriteHeader(status)
+}
+
+type discardMailer struct{}
+
+func (discardMailer) Send(ctx context.Context, msg mailer.Message) error {
+	return nil
+}
+
+// memoryUserStore keeps the users, memberships and sessions the handlers of
+// the tests need. Every user is the admin of their own organization.
+type memoryUserStore struct {
+	types.UserStore
+	mu       sync.Mutex
+	users    map[int]*types.User
+	sessions map[string]int
+}
+
+func newMemoryUserStore() *memoryUserStore {
+	return &memoryUserStore{users: make(map[int]*types.User), sessions: make(map[string]int)}
+}
+
+// verify marks the email address as verified, as the link of the
+// verification email would.
+func (s *memoryUserStore) verify(email string) {
+	s.update(email, func(u *types.User) { u.EmailVerifiedAt = sql.NullTime{Time: time.Now(), Valid: true} })
+}
+
+func (s *memoryUserStore) update(email string, change func(u *types.User)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if u.Email == email {
+			change(u)
+		}
+	}
+}
+
+// revokeSessions ends every session, as their expiry would.
+func (s *memoryUserStore) revokeSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = make(map[string]int)
+}
+
+func (s *memoryUserStore) GetUserByEmail(ctx context.Context, email string) (*types.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if u.Email == email {
+			found := *u
+			return &found, nil
+		}
+	}
+	return nil, &customerrors.NotFoundError{ID: email}
+}
+
+func (s *memoryUserStore) GetUserByID(ctx context.Context, id int) (*types.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	if !ok {
+		return nil, &customerrors.NotFoundError{ID: strconv.Itoa(id)}
+	}
+	found := *u
+	return &found, nil
+}
+
+func (s *memoryUserStore) CreateUser(ctx context.Context, user types.User, organizationName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user.ID = len(s.users) + 1
+	user.CreatedAt = time.Now()
+	s.users[user.ID] = &user
+	return nil
+}
+
+func (s *memoryUserStore) UpdateUser(ctx context.Context, user types.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[user.ID] = &user
+	return nil
+}
+
+func (s *memoryUserStore) CreateUserToken(ctx context.Context, token types.UserToken) error {
+	return nil
+}
+
+func (s *memoryUserStore) GetOrganizationUser(ctx context.Context, orgID, userID int) (*types.User, error) {
+	u, err := s.GetUserByID(ctx, userID)
+	if err != nil || orgID != userID {
+		return nil, &customerrors.NotFoundError{ID: strconv.Itoa(userID)}
+	}
+	u.Role = types.RoleAdmin
+	return u, nil
+}
+
+func (s *memoryUserStore) ListMemberships(ctx context.Context, userID int) ([]types.Membership, error) {
+	return []types.Membership{{OrgID: userID, UserID: userID, Role: types.RoleAdmin}}, nil
+}
+
+func (s *memoryUserStore) CreateSession(ctx context.Context, session types.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[session.ID] = session.UserID
+	return nil
+}
+
+func (s *memoryUserStore) TouchSession(ctx context.Context, userID int, sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sessions[sessionID] != userID {
+		return &customerrors.NotFoundError{ID: sessionID}
+	}
+	return nil
+}
+
+// memoryCameraStore keeps cameras in creation order.
+type memoryCameraStore struct {
+	types.CameraMetadataStore
+	mu      sync.Mutex
+	cameras []*types.CameraMetadata
+}
+
+func newMemoryCameraStore() *memoryCameraStore {
+	return &memoryCameraStore{}
+}
+
+func (s *memoryCameraStore) CreateCameraMetadata(ctx context.Context, camera types.CameraMetadata, entry types.AuditEntry) (*types.CameraMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	camera.CamID = uuid.New().String()
+	s.cameras = append(s.cameras, &camera)
+	created := camera
+	return &created, nil
+}
+
+func (s *memoryCameraStore) GetCameraMetadataByID(ctx context.Context, orgID int, camID string) (*types.CameraMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.cameras {
+		if c.OrgID == orgID && c.CamID == camID {
+			found := *c
+			return &found, nil
+		}
+	}
+	return nil, &customerrors.NotFoundError{ID: camID}
+}
+
+func (s *memoryCameraStore) UpdateCameraMetadata(ctx context.Context, orgID int, camID string, change func(c *types.CameraMetadata) error, entry types.AuditEntry) (*types.CameraMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, c := range s.cameras {
+		if c.OrgID == orgID && c.CamID == camID {
+			camera := *c
+			if err := change(&camera); err != nil {
+				return nil, err
+			}
+			s.cameras[i] = &camera
+			updated := camera
+			return &updated, nil
+		}
+	}
+	return nil, &customerrors.NotFoundError{ID: camID}
+}
+
+func (s *memoryCameraStore) ClaimImageID(ctx context.Context, imageID, camID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.cameras {
+		if c.ImageId.Valid && c.ImageId.String == imageID && c.CamID != camID {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// ListCameraMetadata only applies the page of the filter.
+func (s *memoryCameraStore) ListCameraMetadata(ctx context.Context, orgID int, filter types.CameraFilter) ([]types.CameraMetadata, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var matches []types.CameraMetadata
+	for _, c := range s.cameras {
+		if c.OrgID == orgID {
+			matches = append(matches, *c)
+		}
+	}
+
+	page := matches[min(filter.Offset, len(matches)):]
+	return page[:min(filter.Limit, len(page))], len(matches), nil
+}
+
+type memoryImageStore struct {
+	mu     sync.Mutex
+	images map[string][]byte
+}
+
+func newMemoryImageStore() *memoryImageStore {
+	return &memoryImageStore{images: make(map[string][]byte)}
+}
+
+func (s *memoryImageStore) UploadImage(ctx context.Context, blobName string, imageData []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.images[blobName] = imageData
+	return nil
+}
+
+func (s *memoryImageStore) DownloadImage(ctx context.Context, blobName string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	image, ok := s.images[blobName]
+	if !ok {
+		return nil, &customerrors.NotFoundError{ID: blobName}
+	}
+	return image, nil
+}
+
+func (s *memoryImageStore) DeleteImage(ctx context.Context, blobName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.images, blobName)
+	return nil
+}
+
+func (s *memoryImageStore) ListImages(ctx context.Context) ([]storage.ImageInfo, error) {
+	return nil, nil
+}
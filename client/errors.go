@@ -0,0 +1,107 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-sample-rest-api/customerrors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// APIError is an error response of the API. When the response carries the
+// code of one of the errors of package customerrors, APIError wraps that
+// error, so it can be inspected with errors.As:
+//
+//	var notFound *customerrors.NotFoundError
+//	if errors.As(err, &notFound) { ... }
+type APIError struct {
+	StatusCode int
+	Message    string
+	err        error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.err
+}
+
+// TwoFactorRequiredError is returned by Login for accounts with two-factor
+// authentication. The login is completed with LoginWithCode.
+type TwoFactorRequiredError struct {
+	ChallengeToken string
+}
+
+func (e *TwoFactorRequiredError) Error() string {
+	return "two-factor authentication required"
+}
+
+func newAPIError(resp *http.Response) *APIError {
+	var body struct {
+		Error      string          `json:"error"`
+		Code       string          `json:"code"`
+		Details    json.RawMessage `json:"details"`
+		Violations []string        `json:"violations"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+	if body.Error == "" {
+		body.Error = http.StatusText(resp.StatusCode)
+	}
+
+	e := &APIError{StatusCode: resp.StatusCode, Message: body.Error}
+	if err := customerrors.FromCode(body.Code); err != nil && json.Unmarshal(body.Details, err) == nil {
+		e.err = err
+	} else if body.Violations != nil {
+		e.err = &customerrors.PasswordPolicyError{Violations: body.Violations}
+	} else {
+		e.err = parseError(body.Error)
+	}
+
+	return e
+}
+
+// parseError returns the customerrors error with the message, or nil. It is
+// only used for responses without a code, from servers older than the codes.
+func parseError(message string) error {
+	if id, ok := between(message, "item with ID ", " not found"); ok {
+		return &customerrors.NotFoundError{ID: id}
+	}
+	if id, ok := between(message, "camera with ID ", " not initialized"); ok {
+		return &customerrors.NotInitError{ID: id}
+	}
+	if id, ok := between(message, "camera with ID ", " is already initialized"); ok {
+		return &customerrors.AlreadyInitError{ID: id}
+	}
+	if name, ok := between(message, "item with name ", " already exists"); ok {
+		return &customerrors.AlreadyExistsError{Name: name}
+	}
+	if id, ok := between(message, "user with ID ", " also belongs to other organizations"); ok {
+		return &customerrors.SharedUserError{ID: id}
+	}
+	if rest, ok := strings.CutPrefix(message, "password does not meet the requirements: "); ok {
+		return &customerrors.PasswordPolicyError{Violations: strings.Split(rest, "; ")}
+	}
+	if rest, ok := strings.CutPrefix(message, "Azure blob storage err: "); ok {
+		return &customerrors.AzureStorageError{Message: rest}
+	}
+	if rest, ok := strings.CutPrefix(message, "quota exceeded: the organization may store at most "); ok {
+		limit, resource, _ := strings.Cut(rest, " ")
+		if n, err := strconv.ParseInt(limit, 10, 64); err == nil {
+			return &customerrors.QuotaExceededError{Resource: resource, Limit: n}
+		}
+	}
+
+	return nil
+}
+
+// between returns what is between prefix and suffix in s.
+func between(s, prefix, suffix string) (string, bool) {
+	rest, ok := strings.CutPrefix(s, prefix)
+	if !ok {
+		return "", false
+	}
+	return strings.CutSuffix(rest, suffix)
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go-sample-rest-api/customerrors"
+	"go-sample-rest-api/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// customErrors holds an error of every type of package customerrors.
+func customErrors() []error {
+	return []error{
+		&customerrors.NotFoundError{ID: "cam-1"},
+		&customerrors.NotInitError{ID: "cam-1"},
+		&customerrors.AlreadyInitError{ID: "cam-1"},
+		&customerrors.AzureStorageError{Message: "container not found"},
+		&customerrors.PasswordPolicyError{Violations: []string{"must contain a digit", "must contain a symbol"}},
+		&customerrors.QuotaExceededError{Resource: "cameras", Limit: 10},
+		&customerrors.AlreadyExistsError{Name: "img-1"},
+		&customerrors.SharedUserError{ID: "7"},
+	}
+}
+
+func TestNewAPIError(t *testing.T) {
+	t.Run("decodes every error by its code", func(t *testing.T) {
+		for _, want := range customErrors() {
+			// arrange
+			rr := httptest.NewRecorder()
+			utils.WriteError(rr, http.StatusConflict, want)
+
+			// act
+			err := newAPIError(rr.Result())
+
+			// assert
+			assert.Equal(t, want, errors.Unwrap(err), want.Error())
+			assert.Equal(t, want.Error(), err.Message)
+		}
+	})
+
+	t.Run("decodes a wrapped error by its code", func(t *testing.T) {
+		// arrange
+		rr := httptest.NewRecorder()
+		utils.WriteError(rr, http.StatusNotFound, errors.Join(errors.New("failed to get camera"), &customerrors.NotFoundError{ID: "cam-1"}))
+
+		// act
+		err := newAPIError(rr.Result())
+
+		// assert
+		var notFound *customerrors.NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+		assert.Equal(t, "cam-1", notFound.ID)
+	})
+
+	t.Run("wraps nothing for other errors", func(t *testing.T) {
+		// arrange
+		rr := httptest.NewRecorder()
+		utils.WriteError(rr, http.StatusBadRequest, errors.New("invalid payload"))
+
+		// act
+		err := newAPIError(rr.Result())
+
+		// assert
+		assert.Nil(t, errors.Unwrap(err))
+		assert.Equal(t, "invalid payload", err.Message)
+	})
+}
+
+func TestParseError(t *testing.T) {
+	for _, want := range customErrors() {
+		assert.Equal(t, want, parseError(want.Error()), want.Error())
+	}
+	assert.Nil(t, parseError("invalid payload"))
+}
@@ -1,25 +1,44 @@
 package api
 
 import (
+	"context"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 	"github.com/swaggo/http-swagger"
+	"go-sample-rest-api/config"
 	db2 "go-sample-rest-api/db"
 	_ "go-sample-rest-api/docs"
+	"go-sample-rest-api/graphqlapi"
+	"go-sample-rest-api/grpcapi"
 	"go-sample-rest-api/logging"
+	"go-sample-rest-api/mailer"
+	"go-sample-rest-api/metrics"
+	"go-sample-rest-api/service/auditlog"
 	auth2 "go-sample-rest-api/service/auth"
+	"go-sample-rest-api/service/cameragroup"
 	"go-sample-rest-api/service/camerametadata"
+	"go-sample-rest-api/service/events"
+	"go-sample-rest-api/service/oidc"
+	"go-sample-rest-api/service/organization"
+	"go-sample-rest-api/service/privacy"
 	"go-sample-rest-api/service/user"
+	"go-sample-rest-api/service/webhook"
 	"go-sample-rest-api/storage"
+	"go-sample-rest-api/utils"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"strings"
+	"time"
 )
 
 type APIServer struct {
 	address      string
 	db           db2.DB
 	azureStorage storage.ImageStore
+	broker       *events.Broker
 }
 
 func NewAPIServer(addr string, db db2.DB, azureStorage storage.ImageStore) *APIServer {
@@ -27,6 +46,7 @@ func NewAPIServer(addr string, db db2.DB, azureStorage storage.ImageStore) *APIS
 		address:      addr,
 		db:           db,
 		azureStorage: azureStorage,
+		broker:       events.NewBroker(int(config.Envs.EventsBufferSize)),
 	}
 }
 
@@ -34,24 +54,71 @@ func (s *APIServer) Run() error {
 	log := logging.GetLogger()
 
 	router := mux.NewRouter()
+	router.Use(utils.WithRequestID)
 	subrouter := router.PathPrefix("/api/v1").Subrouter()
 
 	//user
 	userStore := user.NewStore(s.db)
 	auth := auth2.NewAuthenticator()
-	userService := user.NewHandler(userStore, auth)
+	userService := user.NewHandler(userStore, auth, mailer.NewMailer(config.Envs))
 	userService.RegisterRoutes(subrouter)
 
+	// single sign-on, only when an identity provider is configured
+	if cfg := config.Envs; cfg.OIDCIssuerURL != "" {
+		provider := oidc.NewProvider(oidc.Config{
+			IssuerURL:    cfg.OIDCIssuerURL,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Scopes:       strings.Fields(cfg.OIDCScopes),
+		})
+		oidcService := oidc.NewHandler(userStore, auth, provider)
+		oidcService.RegisterRoutes(subrouter)
+	}
+
+	// camera groups
+	cameraGroupStore := cameragroup.NewStore(s.db)
+	cameraGroupService := cameragroup.NewHandler(cameraGroupStore, userStore)
+	cameraGroupService.RegisterRoutes(subrouter)
+
 	// cameraMetadata
 	cameraMetadataStore := camerametadata.NewStore(s.db)
-	cameraMetadataService := camerametadata.NewHandler(cameraMetadataStore, s.azureStorage)
+	cameraMetadataService := camerametadata.NewHandler(cameraMetadataStore, userStore, s.azureStorage, cameraGroupStore)
 	cameraMetadataService.RegisterRoutes(subrouter)
 
+	// organization of the access token and its usage
+	organizationStore := organization.NewStore(s.db)
+	organizationService := organization.NewHandler(organizationStore, cameraMetadataStore, userStore, camerametadata.QuotaFromConfig())
+	organizationService.RegisterRoutes(subrouter)
+
+	// audit log
+	auditLogStore := auditlog.NewStore(s.db)
+	auditLogService := auditlog.NewHandler(auditLogStore, userStore)
+	auditLogService.RegisterRoutes(subrouter)
+
+	// data export and erasure
+	privacyService := privacy.NewHandler(userStore, cameraMetadataStore, s.azureStorage, auditLogStore, auth)
+	privacyService.RegisterRoutes(subrouter)
+
+	// webhook subscriptions
+	webhookService := webhook.NewHandler(webhook.NewStore(s.db), userStore)
+	webhookService.RegisterRoutes(subrouter)
+
+	// live events
+	eventsService := events.NewHandler(s.broker, userStore)
+	eventsService.RegisterRoutes(subrouter)
+	liveService := events.NewLiveHandler(s.broker, cameraMetadataStore, userStore, s.azureStorage)
+	liveService.RegisterRoutes(subrouter)
+
+	// GraphQL queries over cameras and users
+	graphqlService := graphqlapi.NewHandler(cameraMetadataStore, userStore, auditLogStore)
+	graphqlService.RegisterRoutes(subrouter)
+
 	// Serve static files
 	subrouter.HandleFunc("/swagger.json", serveSwaggerFile).Methods(http.MethodGet)
 	subrouter.PathPrefix("/documentation/").Handler(httpSwagger.WrapHandler)
-	// metrics
-	router.Handle("/metrics", promhttp.Handler())
+	// public keys for verifying our access tokens
+	router.HandleFunc("/.well-known/jwks.json", auth2.JWKSHandler).Methods(http.MethodGet)
 
 	log.WithFields(logrus.Fields{
 		"address": s.address,
@@ -60,6 +127,52 @@ func (s *APIServer) Run() error {
 	return http.ListenAndServe(s.address, router)
 }
 
+// RunMetrics serves the Prometheus metrics on the address. They include the
+// usage of every organization, so the address must only be reachable by the
+// monitoring, not by clients of the API.
+func (s *APIServer) RunMetrics(address string) error {
+	prometheus.MustRegister(metrics.NewUsageCollector(camerametadata.NewStore(s.db), time.Duration(config.Envs.MetricsUsageMaxAgeInSeconds)*time.Second))
+
+	router := http.NewServeMux()
+	router.Handle("/metrics", promhttp.Handler())
+
+	logging.GetLogger().WithFields(logrus.Fields{
+		"address": address,
+	}).Info("Serving metrics on")
+
+	return http.ListenAndServe(address, router)
+}
+
+// RunGRPC serves the gRPC API on the address. It shares the stores and the
+// camera operations with the REST API.
+func (s *APIServer) RunGRPC(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	userStore := user.NewStore(s.db)
+	cameras := camerametadata.NewHandler(camerametadata.NewStore(s.db), userStore, s.azureStorage, cameragroup.NewStore(s.db))
+
+	logging.GetLogger().WithFields(logrus.Fields{
+		"address": address,
+	}).Info("Serving gRPC on")
+
+	return grpcapi.NewServer(userStore, cameras).Serve(listener)
+}
+
+// RunDispatcher delivers the events of the outbox to the webhooks until ctx
+// is done.
+func (s *APIServer) RunDispatcher(ctx context.Context) {
+	webhook.NewDispatcher(webhook.NewStore(s.db), webhook.LoadDispatcherConfig(config.Envs)).Run(ctx)
+}
+
+// RunEventListener streams the events of the outbox to the clients of
+// /events until ctx is done.
+func (s *APIServer) RunEventListener(ctx context.Context) {
+	events.Listen(ctx, db2.ConnString(config.Envs), events.NewStore(s.db), s.broker, int(config.Envs.EventsBufferSize))
+}
+
 func serveSwaggerFile(w http.ResponseWriter, r *http.Request) {
 	// Path to the swagger file
 	swaggerFilePath := "./docs/swagger.json"
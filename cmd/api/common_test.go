@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/db"
+	"go-sample-rest-api/storage"
 )
 
 type MockDB struct {
@@ -22,6 +24,22 @@ func (m *MockDB) Exec(query string, args ...interface{}) (sql.Result, error) {
 	return nil, nil // Implement as needed for the tests
 }
 
+func (m *MockDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, nil // Implement as needed for the tests
+}
+
+func (m *MockDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil // Implement as needed for the tests
+}
+
+func (m *MockDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return nil, nil // Implement as needed for the tests
+}
+
+func (m *MockDB) WithTx(ctx context.Context, fn func(tx db.DB) error) error {
+	return fn(m)
+}
+
 type MockAzureStorage struct {
 	mock.Mock
 }
@@ -38,3 +56,16 @@ func (m *MockAzureStorage) DownloadImage(ctx context.Context, blobName string) (
 	}
 	return args.Get(0).([]byte), args.Error(1)
 }
+
+func (m *MockAzureStorage) DeleteImage(ctx context.Context, blobName string) error {
+	args := m.Called(ctx, blobName)
+	return args.Error(0)
+}
+
+func (m *MockAzureStorage) ListImages(ctx context.Context) ([]storage.ImageInfo, error) {
+	args := m.Called(ctx)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]storage.ImageInfo), args.Error(1)
+}
@@ -1,16 +1,39 @@
 package main
 
 import (
+	"context"
 	"go-sample-rest-api/cmd/api"
 	"go-sample-rest-api/config"
 	db2 "go-sample-rest-api/db"
 	"go-sample-rest-api/logging"
+	"go-sample-rest-api/service/auth"
 	"go-sample-rest-api/storage"
 )
 
 func SetupServer() (*api.APIServer, error) {
 	log := logging.GetLogger()
 	cfg := config.Envs
+	keySet, err := auth.LoadKeySet(cfg)
+	if err != nil {
+		log.Error("Failed to load JWT signing keys:", err)
+		return nil, err
+	}
+	auth.UseKeySet(keySet)
+
+	hasher, err := auth.LoadHasher(cfg)
+	if err != nil {
+		log.Error("Failed to configure password hashing:", err)
+		return nil, err
+	}
+	auth.UseHasher(hasher)
+
+	policy, err := auth.LoadPasswordPolicy(cfg)
+	if err != nil {
+		log.Error("Failed to load password policy:", err)
+		return nil, err
+	}
+	auth.UsePasswordPolicy(policy)
+
 	db, err := db2.NewPostgresStorageConn(cfg)
 	if err != nil {
 		log.Error("Failed to connect to database:", err)
@@ -30,6 +53,19 @@ func main() {
 		return
 	}
 
+	go server.RunDispatcher(context.Background())
+	go server.RunEventListener(context.Background())
+	go func() {
+		if err := server.RunGRPC(":" + config.Envs.GRPCPort); err != nil {
+			logging.GetLogger().Fatal(err)
+		}
+	}()
+	go func() {
+		if err := server.RunMetrics(":" + config.Envs.MetricsPort); err != nil {
+			logging.GetLogger().Fatal(err)
+		}
+	}()
+
 	if err := server.Run(); err != nil {
 		logging.GetLogger().Fatal(err)
 	}
@@ -0,0 +1,14 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS emailVerifiedAt TIMESTAMP;
+-- accounts created before verification existed stay usable
+UPDATE users SET emailVerifiedAt = createdAt WHERE emailVerifiedAt IS NULL;
+
+CREATE TABLE IF NOT EXISTS user_tokens (
+    id          SERIAL PRIMARY KEY,
+    user_id     INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose     VARCHAR(32) NOT NULL,
+    token_hash  CHAR(64) NOT NULL UNIQUE,
+    expires_at  TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at     TIMESTAMP WITH TIME ZONE,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS user_tokens_user_id_purpose_idx ON user_tokens (user_id, purpose);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS user_recovery_codes;
+ALTER TABLE users DROP COLUMN IF EXISTS totpLastStep;
+ALTER TABLE users DROP COLUMN IF EXISTS totpEnabledAt;
+ALTER TABLE users DROP COLUMN IF EXISTS totpSecret;
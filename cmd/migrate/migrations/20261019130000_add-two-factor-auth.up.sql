@@ -0,0 +1,13 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totpSecret VARCHAR(64);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totpEnabledAt TIMESTAMP;
+-- last accepted time step, so a code cannot be used twice
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totpLastStep BIGINT;
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    id          SERIAL PRIMARY KEY,
+    user_id     INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash   CHAR(64) NOT NULL,
+    used_at     TIMESTAMP WITH TIME ZONE,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, code_hash)
+);
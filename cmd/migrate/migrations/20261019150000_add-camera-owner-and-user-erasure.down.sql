@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS erasedAt;
+DROP INDEX IF EXISTS idx_camera_metadata_owner_id;
+ALTER TABLE camera_metadata DROP COLUMN IF EXISTS owner_id;
@@ -0,0 +1,3 @@
+ALTER TABLE camera_metadata ADD COLUMN IF NOT EXISTS owner_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_camera_metadata_owner_id ON camera_metadata (owner_id);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS erasedAt TIMESTAMP;
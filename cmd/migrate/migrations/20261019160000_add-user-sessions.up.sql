@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id            UUID PRIMARY KEY,
+    user_id       INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent    TEXT NOT NULL DEFAULT '',
+    ip            VARCHAR(64) NOT NULL DEFAULT '',
+    created_at    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at    TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at    TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS user_sessions_user_id_idx ON user_sessions (user_id);
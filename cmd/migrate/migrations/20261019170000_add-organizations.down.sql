@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_camera_metadata_org_id;
+ALTER TABLE camera_metadata DROP COLUMN IF EXISTS org_id;
+
+-- a user keeps the highest role of any of their memberships
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'user';
+UPDATE users SET role = 'admin'
+    WHERE id IN (SELECT user_id FROM organization_members WHERE role = 'admin');
+
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS organizations (
+    id          SERIAL PRIMARY KEY,
+    name        VARCHAR(255) NOT NULL,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS organization_members (
+    org_id      INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id     INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role        VARCHAR(16) NOT NULL DEFAULT 'user',
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (org_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS organization_members_user_id_idx ON organization_members (user_id);
+
+-- existing users and cameras move into a default organization, keeping their roles
+INSERT INTO organizations (name) VALUES ('Default');
+INSERT INTO organization_members (org_id, user_id, role)
+    SELECT (SELECT MIN(id) FROM organizations), id, role FROM users;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
+
+ALTER TABLE camera_metadata ADD COLUMN IF NOT EXISTS org_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE;
+UPDATE camera_metadata SET org_id = (SELECT MIN(id) FROM organizations);
+ALTER TABLE camera_metadata ALTER COLUMN org_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_camera_metadata_org_id ON camera_metadata (org_id);
@@ -0,0 +1 @@
+ALTER TABLE camera_metadata DROP COLUMN IF EXISTS image_size_bytes;
@@ -0,0 +1,2 @@
+-- sizes of images uploaded before this migration are unknown and count as 0
+ALTER TABLE camera_metadata ADD COLUMN IF NOT EXISTS image_size_bytes BIGINT NOT NULL DEFAULT 0;
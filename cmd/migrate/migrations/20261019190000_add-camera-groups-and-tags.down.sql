@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_camera_metadata_tags;
+ALTER TABLE camera_metadata DROP COLUMN IF EXISTS tags;
+
+DROP INDEX IF EXISTS idx_camera_metadata_group_id;
+ALTER TABLE camera_metadata DROP COLUMN IF EXISTS group_id;
+
+DROP TABLE IF EXISTS camera_groups;
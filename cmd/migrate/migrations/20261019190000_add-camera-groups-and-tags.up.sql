@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS camera_groups (
+    id          SERIAL PRIMARY KEY,
+    org_id      INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name        VARCHAR(255) NOT NULL,
+    description VARCHAR(1000) NOT NULL DEFAULT '',
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (org_id, name)
+);
+
+-- cameras of a deleted group become ungrouped
+ALTER TABLE camera_metadata ADD COLUMN IF NOT EXISTS group_id INTEGER REFERENCES camera_groups(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_camera_metadata_group_id ON camera_metadata (group_id);
+
+ALTER TABLE camera_metadata ADD COLUMN IF NOT EXISTS tags JSONB NOT NULL DEFAULT '{}';
+CREATE INDEX IF NOT EXISTS idx_camera_metadata_tags ON camera_metadata USING GIN (tags);
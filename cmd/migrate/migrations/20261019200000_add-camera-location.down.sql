@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_camera_metadata_location;
+ALTER TABLE camera_metadata DROP CONSTRAINT IF EXISTS camera_metadata_location_check;
+
+ALTER TABLE camera_metadata DROP COLUMN IF EXISTS field_of_view;
+ALTER TABLE camera_metadata DROP COLUMN IF EXISTS heading;
+ALTER TABLE camera_metadata DROP COLUMN IF EXISTS longitude;
+ALTER TABLE camera_metadata DROP COLUMN IF EXISTS latitude;
@@ -0,0 +1,15 @@
+-- WGS 84 degrees; heading and field of view are optional and in degrees as well
+ALTER TABLE camera_metadata ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION;
+ALTER TABLE camera_metadata ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+ALTER TABLE camera_metadata ADD COLUMN IF NOT EXISTS heading DOUBLE PRECISION;
+ALTER TABLE camera_metadata ADD COLUMN IF NOT EXISTS field_of_view DOUBLE PRECISION;
+
+ALTER TABLE camera_metadata ADD CONSTRAINT camera_metadata_location_check CHECK (
+    (latitude IS NULL) = (longitude IS NULL)
+    AND (latitude IS NULL OR latitude BETWEEN -90 AND 90)
+    AND (longitude IS NULL OR longitude BETWEEN -180 AND 180)
+    AND (heading IS NULL OR (heading >= 0 AND heading < 360))
+    AND (field_of_view IS NULL OR (field_of_view > 0 AND field_of_view <= 360))
+);
+
+CREATE INDEX IF NOT EXISTS idx_camera_metadata_location ON camera_metadata (latitude, longitude) WHERE latitude IS NOT NULL;
@@ -0,0 +1,29 @@
+-- The log outlives what it records, so it has no foreign keys.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id            BIGSERIAL PRIMARY KEY,
+    org_id        INTEGER NOT NULL,
+    actor_type    VARCHAR(16) NOT NULL CHECK (actor_type IN ('user', 'device', 'system')),
+    actor_id      VARCHAR(255) NOT NULL DEFAULT '',
+    action        VARCHAR(64) NOT NULL,
+    resource_type VARCHAR(64) NOT NULL,
+    resource_id   VARCHAR(255) NOT NULL,
+    changes       JSONB NOT NULL DEFAULT '{}',
+    request_id    VARCHAR(128) NOT NULL DEFAULT '',
+    ip            VARCHAR(64) NOT NULL DEFAULT '',
+    created_at    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_org_created ON audit_log (org_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_resource ON audit_log (org_id, resource_type, resource_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log (org_id, actor_type, actor_id);
+
+-- append-only: entries can be added but never changed or removed
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
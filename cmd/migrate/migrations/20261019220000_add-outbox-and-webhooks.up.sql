@@ -0,0 +1,43 @@
+-- Domain events are written with the change that caused them and published
+-- by the dispatcher afterwards. Like the audit log they have no foreign keys.
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id           BIGSERIAL PRIMARY KEY,
+    org_id       INTEGER NOT NULL,
+    type         VARCHAR(64) NOT NULL,
+    data         JSONB NOT NULL DEFAULT '{}',
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_unpublished ON outbox_events (id) WHERE published_at IS NULL;
+
+-- an empty event_types array subscribes to every event
+CREATE TABLE IF NOT EXISTS webhooks (
+    id          SERIAL PRIMARY KEY,
+    org_id      INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    url         VARCHAR(2048) NOT NULL,
+    secret      VARCHAR(128) NOT NULL,
+    event_types JSONB NOT NULL DEFAULT '[]',
+    active      BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_org_id ON webhooks (org_id);
+
+-- one row per event and subscribed webhook; dead deliveries gave up retrying
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id               BIGSERIAL PRIMARY KEY,
+    webhook_id       INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_id         BIGINT NOT NULL REFERENCES outbox_events(id) ON DELETE CASCADE,
+    status           VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'dead')),
+    attempts         INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_status_code INTEGER,
+    last_error       TEXT NOT NULL DEFAULT '',
+    delivered_at     TIMESTAMP WITH TIME ZONE,
+    created_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (webhook_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, status, id DESC);
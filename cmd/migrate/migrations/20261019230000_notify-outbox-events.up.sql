@@ -0,0 +1,13 @@
+-- Notifies the listeners of the live event stream of every new event. The
+-- notification is sent when the transaction commits and carries the ID only,
+-- as payloads are limited to 8000 bytes.
+CREATE OR REPLACE FUNCTION outbox_events_notify() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('outbox_events', NEW.id::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER outbox_events_notify
+    AFTER INSERT ON outbox_events
+    FOR EACH ROW EXECUTE FUNCTION outbox_events_notify();
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_camera_metadata_image_id;
@@ -0,0 +1,11 @@
+-- images are stored under their ID, so two cameras must never share one.
+-- Earlier versions did not prevent it, so all but the newest camera of an ID
+-- lose their image before the index is created; the blob holds the image of
+-- whichever camera uploaded last, which cannot be told from the rows.
+UPDATE camera_metadata
+SET image_id = NULL, name_of_stored_picture = NULL, image_size_bytes = 0
+WHERE image_id IS NOT NULL
+  AND cam_id <> (SELECT newest.cam_id FROM camera_metadata newest
+                 WHERE newest.image_id = camera_metadata.image_id
+                 ORDER BY newest.created_at DESC, newest.cam_id DESC LIMIT 1);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_camera_metadata_image_id ON camera_metadata (image_id);
@@ -0,0 +1,11 @@
+-- An image ID is claimed by a camera before its blob is written, so two
+-- cameras uploading the same ID at once cannot overwrite each other's blob.
+CREATE TABLE IF NOT EXISTS camera_image_ids (
+    image_id    VARCHAR(36) PRIMARY KEY,
+    cam_id      VARCHAR(36) NOT NULL,
+    claimed_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS camera_image_ids_cam_id_idx ON camera_image_ids (cam_id);
+INSERT INTO camera_image_ids (image_id, cam_id)
+SELECT image_id, cam_id FROM camera_metadata WHERE image_id IS NOT NULL
+ON CONFLICT DO NOTHING;
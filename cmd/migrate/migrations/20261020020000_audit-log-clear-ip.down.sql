@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS audit_log_only_clear_ip ON audit_log;
+DROP FUNCTION IF EXISTS audit_log_only_clear_ip();
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,25 @@
+-- Erasing a user clears the IP addresses of their audit entries. Updates are
+-- refused row by row unless they only clear the ip column; deleting and
+-- truncating stay refused.
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+
+CREATE TRIGGER audit_log_append_only
+    BEFORE DELETE OR TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+
+CREATE OR REPLACE FUNCTION audit_log_only_clear_ip() RETURNS trigger AS $$
+BEGIN
+    IF NEW.ip = '' AND
+       (NEW.id, NEW.org_id, NEW.actor_type, NEW.actor_id, NEW.action, NEW.resource_type, NEW.resource_id,
+        NEW.changes, NEW.request_id, NEW.created_at) IS NOT DISTINCT FROM
+       (OLD.id, OLD.org_id, OLD.actor_type, OLD.actor_id, OLD.action, OLD.resource_type, OLD.resource_id,
+        OLD.changes, OLD.request_id, OLD.created_at) THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_only_clear_ip
+    BEFORE UPDATE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_only_clear_ip();
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go-sample-rest-api/config"
+	"go-sample-rest-api/db"
+	"go-sample-rest-api/reconcile"
+	"go-sample-rest-api/service/camerametadata"
+	"go-sample-rest-api/storage"
+	"log"
+	"os"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	deleteOrphans := flag.Bool("delete-orphans", false, "delete blobs no camera points at")
+	clearDangling := flag.Bool("clear-dangling", false, "clear the image of cameras pointing at a missing blob")
+	dryRun := flag.Bool("dry-run", false, "report the chosen repairs without making them")
+	minOrphanAge := flag.Duration("min-orphan-age", time.Hour, "ignore unreferenced blobs younger than this, as their upload may still be in progress")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: reconcile [-dry-run] [-delete-orphans] [-clear-dangling] [-min-orphan-age duration]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	conn, err := db.NewPostgresStorageConn(config.Envs)
+	if err != nil {
+		log.Fatalf("Failed to connect to the database: %v", err)
+	}
+	defer conn.Close()
+
+	images := storage.NewAzureStorage(config.Envs.AzureStorageAccountName, config.Envs.AzureContainerAccessKey, config.Envs.AzureContainerName)
+	if images == nil {
+		log.Fatalf("Failed to connect to the Azure Blob storage")
+	}
+
+	report, err := reconcile.Run(context.Background(), camerametadata.NewStore(db.NewSQLDB(conn)), images, reconcile.Options{
+		DeleteOrphans: *deleteOrphans,
+		ClearDangling: *clearDangling,
+		DryRun:        *dryRun,
+		MinOrphanAge:  *minOrphanAge,
+	})
+	if err != nil {
+		log.Fatalf("Reconciliation failed: %v", err)
+	}
+
+	for _, ref := range report.Dangling {
+		fmt.Printf("dangling\torg=%d\tcamera=%s\timage=%s\n", ref.OrgID, ref.CamID, ref.ImageID)
+	}
+	for _, blob := range report.Orphans {
+		fmt.Printf("orphan\tblob=%s\tbytes=%d\tmodified=%s\n", blob.Name, blob.SizeBytes, blob.LastModified.Format(time.RFC3339))
+	}
+	for _, blob := range report.Unknown {
+		fmt.Printf("unknown\tblob=%s\tbytes=%d\tmodified=%s\n", blob.Name, blob.SizeBytes, blob.LastModified.Format(time.RFC3339))
+	}
+	fmt.Printf("%d dangling, %d orphans, %d unknown, %d recent blobs skipped\n", len(report.Dangling), len(report.Orphans), len(report.Unknown), report.Recent)
+
+	switch {
+	case *dryRun && (*deleteOrphans || *clearDangling):
+		fmt.Println("Dry run: nothing was repaired.")
+	case *deleteOrphans || *clearDangling:
+		fmt.Printf("%d cleared, %d deleted, %d failed\n", report.Cleared, report.Deleted, report.Failed)
+	}
+
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
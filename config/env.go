@@ -6,17 +6,77 @@ import (
 )
 
 type Config struct {
-	DBUser                  string
-	DBPassword              string
-	DBName                  string
-	DBHost                  string
-	DBPort                  string
-	JWTSecret               string
-	JWTExpirationInSeconds  int64
-	ServerPort              string
-	AzureContainerName      string
-	AzureStorageAccountName string
-	AzureContainerAccessKey string
+	DBUser                      string
+	DBPassword                  string
+	DBName                      string
+	DBHost                      string
+	DBPort                      string
+	JWTSecret                   string
+	JWTExpirationInSeconds      int64
+	JWTIssuer                   string
+	JWTAudience                 string
+	JWTClockSkewInSeconds       int64
+	JWTSigningAlgorithm         string
+	JWTSigningKeyFile           string
+	JWTVerificationKeyFiles     string
+	ServerPort                  string
+	GRPCPort                    string
+	MetricsPort                 string
+	AzureContainerName          string
+	AzureStorageAccountName     string
+	AzureContainerAccessKey     string
+	OIDCIssuerURL               string
+	OIDCClientID                string
+	OIDCClientSecret            string
+	OIDCRedirectURL             string
+	OIDCScopes                  string
+	AppBaseURL                  string
+	EmailVerificationTTL        int64
+	PasswordResetTTL            int64
+	MailerDriver                string
+	MailFrom                    string
+	MailerFileDir               string
+	SMTPHost                    string
+	SMTPPort                    string
+	SMTPUsername                string
+	SMTPPassword                string
+	TrustProxyHeaders           bool
+	LoginFreeAttempts           int64
+	LoginBackoffBaseInSeconds   int64
+	LoginMaxBackoffInSeconds    int64
+	LoginLockoutThreshold       int64
+	LoginLockoutInSeconds       int64
+	LoginIPLockoutThreshold     int64
+	MFAChallengeTTLInSeconds    int64
+	TOTPIssuer                  string
+	PasswordHasher              string
+	Argon2MemoryInKiB           int64
+	Argon2Iterations            int64
+	Argon2Parallelism           int64
+	BcryptCost                  int64
+	PasswordMinLength           int64
+	PasswordMaxLength           int64
+	PasswordRequireUpper        bool
+	PasswordRequireLower        bool
+	PasswordRequireDigit        bool
+	PasswordRequireSymbol       bool
+	PasswordRejectPersonalInfo  bool
+	BreachedPasswordsPath       string
+	QuotaMaxCameras             int64
+	QuotaMaxStorageBytes        int64
+	MetricsUsageMaxAgeInSeconds int64
+	WebhookIntervalInSeconds    int64
+	WebhookBatchSize            int64
+	WebhookMaxAttempts          int64
+	WebhookBackoffBaseInSeconds int64
+	WebhookMaxBackoffInSeconds  int64
+	WebhookTimeoutInSeconds     int64
+	WebhookConcurrency          int64
+	WebhookAllowPrivateNetworks bool
+	EventsBufferSize            int64
+	EventsHeartbeatInSeconds    int64
+	GraphQLMaxDepth             int64
+	GraphQLMaxComplexity        int64
 }
 
 var Envs = initConfig()
@@ -27,16 +87,76 @@ func initConfig() Config {
 	godotenv.Load()
 
 	return Config{
-		DBUser:                  utils.GetEnv("DB_USER", "user"),
-		DBPassword:              utils.GetEnv("DB_PASSWORD", "password"),
-		DBName:                  utils.GetEnv("DB_NAME", "app"),
-		DBHost:                  utils.GetEnv("DB_HOST", "localhost"),
-		DBPort:                  utils.GetEnv("DB_PORT", "5432"),
-		JWTSecret:               utils.GetEnv("JWT_SECRET", "not-so-secret-now-is-it?"),
-		JWTExpirationInSeconds:  utils.GetEnvAsInt("JWT_EXPIRATION_IN_SECONDS", 3600*24*7),
-		ServerPort:              utils.GetEnv("SERVER_PORT", "8080"),
-		AzureContainerName:      utils.GetEnv("AZURE_CONTAINER_NAME", "test"),
-		AzureStorageAccountName: utils.GetEnv("AZURE_STORAGE_ACCOUNT_NAME", "test"),
-		AzureContainerAccessKey: utils.GetEnv("AZURE_CONTAINER_ACCESS_KEY", "test"),
+		DBUser:                      utils.GetEnv("DB_USER", "user"),
+		DBPassword:                  utils.GetEnv("DB_PASSWORD", "password"),
+		DBName:                      utils.GetEnv("DB_NAME", "app"),
+		DBHost:                      utils.GetEnv("DB_HOST", "localhost"),
+		DBPort:                      utils.GetEnv("DB_PORT", "5432"),
+		JWTSecret:                   utils.GetEnv("JWT_SECRET", "not-so-secret-now-is-it?"),
+		JWTExpirationInSeconds:      utils.GetEnvAsInt("JWT_EXPIRATION_IN_SECONDS", 3600*24*7),
+		JWTIssuer:                   utils.GetEnv("JWT_ISSUER", "go-sample-rest-api"),
+		JWTAudience:                 utils.GetEnv("JWT_AUDIENCE", "go-sample-rest-api"),
+		JWTClockSkewInSeconds:       utils.GetEnvAsInt("JWT_CLOCK_SKEW_IN_SECONDS", 30),
+		JWTSigningAlgorithm:         utils.GetEnv("JWT_SIGNING_ALGORITHM", "HS256"),
+		JWTSigningKeyFile:           utils.GetEnv("JWT_SIGNING_KEY_FILE", ""),
+		JWTVerificationKeyFiles:     utils.GetEnv("JWT_VERIFICATION_KEY_FILES", ""),
+		ServerPort:                  utils.GetEnv("SERVER_PORT", "8080"),
+		GRPCPort:                    utils.GetEnv("GRPC_PORT", "9090"),
+		MetricsPort:                 utils.GetEnv("METRICS_PORT", "9100"),
+		AzureContainerName:          utils.GetEnv("AZURE_CONTAINER_NAME", "test"),
+		AzureStorageAccountName:     utils.GetEnv("AZURE_STORAGE_ACCOUNT_NAME", "test"),
+		AzureContainerAccessKey:     utils.GetEnv("AZURE_CONTAINER_ACCESS_KEY", "test"),
+		OIDCIssuerURL:               utils.GetEnv("OIDC_ISSUER_URL", ""),
+		OIDCClientID:                utils.GetEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:            utils.GetEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:             utils.GetEnv("OIDC_REDIRECT_URL", "http://localhost:8080/api/v1/oidc/callback"),
+		OIDCScopes:                  utils.GetEnv("OIDC_SCOPES", "openid email profile"),
+		AppBaseURL:                  utils.GetEnv("APP_BASE_URL", "http://localhost:3000"),
+		EmailVerificationTTL:        utils.GetEnvAsInt("EMAIL_VERIFICATION_TTL_IN_SECONDS", 3600*48),
+		PasswordResetTTL:            utils.GetEnvAsInt("PASSWORD_RESET_TTL_IN_SECONDS", 3600),
+		MailerDriver:                utils.GetEnv("MAILER_DRIVER", "log"),
+		MailFrom:                    utils.GetEnv("MAIL_FROM", "no-reply@localhost"),
+		MailerFileDir:               utils.GetEnv("MAILER_FILE_DIR", "./tmp/mail"),
+		SMTPHost:                    utils.GetEnv("SMTP_HOST", "localhost"),
+		SMTPPort:                    utils.GetEnv("SMTP_PORT", "587"),
+		SMTPUsername:                utils.GetEnv("SMTP_USERNAME", ""),
+		SMTPPassword:                utils.GetEnv("SMTP_PASSWORD", ""),
+		TrustProxyHeaders:           utils.GetEnvAsBool("TRUST_PROXY_HEADERS", false),
+		LoginFreeAttempts:           utils.GetEnvAsInt("LOGIN_FREE_ATTEMPTS", 3),
+		LoginBackoffBaseInSeconds:   utils.GetEnvAsInt("LOGIN_BACKOFF_BASE_IN_SECONDS", 1),
+		LoginMaxBackoffInSeconds:    utils.GetEnvAsInt("LOGIN_MAX_BACKOFF_IN_SECONDS", 60),
+		LoginLockoutThreshold:       utils.GetEnvAsInt("LOGIN_LOCKOUT_THRESHOLD", 10),
+		LoginLockoutInSeconds:       utils.GetEnvAsInt("LOGIN_LOCKOUT_IN_SECONDS", 900),
+		LoginIPLockoutThreshold:     utils.GetEnvAsInt("LOGIN_IP_LOCKOUT_THRESHOLD", 100),
+		MFAChallengeTTLInSeconds:    utils.GetEnvAsInt("MFA_CHALLENGE_TTL_IN_SECONDS", 300),
+		TOTPIssuer:                  utils.GetEnv("TOTP_ISSUER", "go-sample-rest-api"),
+		PasswordHasher:              utils.GetEnv("PASSWORD_HASHER", "argon2id"),
+		Argon2MemoryInKiB:           utils.GetEnvAsInt("ARGON2_MEMORY_IN_KIB", 19*1024),
+		Argon2Iterations:            utils.GetEnvAsInt("ARGON2_ITERATIONS", 2),
+		Argon2Parallelism:           utils.GetEnvAsInt("ARGON2_PARALLELISM", 1),
+		BcryptCost:                  utils.GetEnvAsInt("BCRYPT_COST", 10),
+		PasswordMinLength:           utils.GetEnvAsInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordMaxLength:           utils.GetEnvAsInt("PASSWORD_MAX_LENGTH", 130),
+		PasswordRequireUpper:        utils.GetEnvAsBool("PASSWORD_REQUIRE_UPPER", false),
+		PasswordRequireLower:        utils.GetEnvAsBool("PASSWORD_REQUIRE_LOWER", false),
+		PasswordRequireDigit:        utils.GetEnvAsBool("PASSWORD_REQUIRE_DIGIT", false),
+		PasswordRequireSymbol:       utils.GetEnvAsBool("PASSWORD_REQUIRE_SYMBOL", false),
+		PasswordRejectPersonalInfo:  utils.GetEnvAsBool("PASSWORD_REJECT_PERSONAL_INFO", true),
+		BreachedPasswordsPath:       utils.GetEnv("BREACHED_PASSWORDS_PATH", ""),
+		QuotaMaxCameras:             utils.GetEnvAsInt("QUOTA_MAX_CAMERAS", 0),
+		QuotaMaxStorageBytes:        utils.GetEnvAsInt("QUOTA_MAX_STORAGE_BYTES", 0),
+		MetricsUsageMaxAgeInSeconds: utils.GetEnvAsInt("METRICS_USAGE_MAX_AGE_IN_SECONDS", 60),
+		WebhookIntervalInSeconds:    utils.GetEnvAsInt("WEBHOOK_INTERVAL_IN_SECONDS", 5),
+		WebhookBatchSize:            utils.GetEnvAsInt("WEBHOOK_BATCH_SIZE", 50),
+		WebhookMaxAttempts:          utils.GetEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 10),
+		WebhookBackoffBaseInSeconds: utils.GetEnvAsInt("WEBHOOK_BACKOFF_BASE_IN_SECONDS", 30),
+		WebhookMaxBackoffInSeconds:  utils.GetEnvAsInt("WEBHOOK_MAX_BACKOFF_IN_SECONDS", 6*3600),
+		WebhookTimeoutInSeconds:     utils.GetEnvAsInt("WEBHOOK_TIMEOUT_IN_SECONDS", 10),
+		WebhookConcurrency:          utils.GetEnvAsInt("WEBHOOK_CONCURRENCY", 8),
+		WebhookAllowPrivateNetworks: utils.GetEnvAsBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false),
+		EventsBufferSize:            utils.GetEnvAsInt("EVENTS_BUFFER_SIZE", 1000),
+		EventsHeartbeatInSeconds:    utils.GetEnvAsInt("EVENTS_HEARTBEAT_IN_SECONDS", 15),
+		GraphQLMaxDepth:             utils.GetEnvAsInt("GRAPHQL_MAX_DEPTH", 8),
+		GraphQLMaxComplexity:        utils.GetEnvAsInt("GRAPHQL_MAX_COMPLEXITY", 5000),
 	}
 }
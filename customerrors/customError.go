@@ -1,35 +1,145 @@
 package customerrors
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
+
+// Codes of the errors in error responses. They do not change with the
+// messages, so clients tell the errors apart by them.
+const (
+	CodeNotFound           = "not_found"
+	CodeNotInitialized     = "not_initialized"
+	CodeAlreadyInitialized = "already_initialized"
+	CodeStorage            = "storage_error"
+	CodePasswordPolicy     = "password_policy"
+	CodeQuotaExceeded      = "quota_exceeded"
+	CodeAlreadyExists      = "already_exists"
+	CodeSharedUser         = "shared_user"
+)
+
+// FromCode returns an empty error of the type with the code, to decode the
+// details of an error response into, or nil for an unknown code.
+func FromCode(code string) error {
+	switch code {
+	case CodeNotFound:
+		return &NotFoundError{}
+	case CodeNotInitialized:
+		return &NotInitError{}
+	case CodeAlreadyInitialized:
+		return &AlreadyInitError{}
+	case CodeStorage:
+		return &AzureStorageError{}
+	case CodePasswordPolicy:
+		return &PasswordPolicyError{}
+	case CodeQuotaExceeded:
+		return &QuotaExceededError{}
+	case CodeAlreadyExists:
+		return &AlreadyExistsError{}
+	case CodeSharedUser:
+		return &SharedUserError{}
+	}
+	return nil
+}
 
 type NotFoundError struct {
-	ID string
+	ID string `json:"id"`
 }
 
 func (e *NotFoundError) Error() string {
 	return fmt.Sprintf("item with ID %s not found", e.ID)
 }
 
+func (e *NotFoundError) Code() string {
+	return CodeNotFound
+}
+
 type NotInitError struct {
-	ID string
+	ID string `json:"id"`
 }
 
 func (e *NotInitError) Error() string {
 	return fmt.Sprintf("camera with ID %s not initialized", e.ID)
 }
 
+func (e *NotInitError) Code() string {
+	return CodeNotInitialized
+}
+
 type AlreadyInitError struct {
-	ID string
+	ID string `json:"id"`
 }
 
 func (e *AlreadyInitError) Error() string {
 	return fmt.Sprintf("camera with ID %s is already initialized", e.ID)
 }
 
+func (e *AlreadyInitError) Code() string {
+	return CodeAlreadyInitialized
+}
+
 type AzureStorageError struct {
-	Message string
+	Message string `json:"message"`
 }
 
 func (e *AzureStorageError) Error() string {
 	return fmt.Sprintf("Azure blob storage err: %v", e.Message)
 }
+
+func (e *AzureStorageError) Code() string {
+	return CodeStorage
+}
+
+// PasswordPolicyError lists every rule of the password policy a password breaks.
+type PasswordPolicyError struct {
+	Violations []string `json:"violations"`
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return fmt.Sprintf("password does not meet the requirements: %s", strings.Join(e.Violations, "; "))
+}
+
+func (e *PasswordPolicyError) Code() string {
+	return CodePasswordPolicy
+}
+
+// QuotaExceededError reports that an organization reached the limit of a resource.
+type QuotaExceededError struct {
+	Resource string `json:"resource"`
+	Limit    int64  `json:"limit"`
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("quota exceeded: the organization may store at most %d %s", e.Limit, e.Resource)
+}
+
+func (e *QuotaExceededError) Code() string {
+	return CodeQuotaExceeded
+}
+
+// AlreadyExistsError reports that the name of an item is taken.
+type AlreadyExistsError struct {
+	Name string `json:"name"`
+}
+
+func (e *AlreadyExistsError) Error() string {
+	return fmt.Sprintf("item with name %s already exists", e.Name)
+}
+
+func (e *AlreadyExistsError) Code() string {
+	return CodeAlreadyExists
+}
+
+// SharedUserError reports that a user also belongs to other organizations, so
+// one organization must not change their account on its own.
+type SharedUserError struct {
+	ID string `json:"id"`
+}
+
+func (e *SharedUserError) Error() string {
+	return fmt.Sprintf("user with ID %s also belongs to other organizations", e.ID)
+}
+
+func (e *SharedUserError) Code() string {
+	return CodeSharedUser
+}
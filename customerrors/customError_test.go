@@ -28,3 +28,27 @@ func TestAzureStorageError(t *testing.T) {
 	expectedMessage := "Azure blob storage err: test message"
 	assert.Equal(t, expectedMessage, err.Error(), "Error message should match expected output")
 }
+
+func TestPasswordPolicyError(t *testing.T) {
+	err := &PasswordPolicyError{Violations: []string{"must be at least 8 characters long", "must contain a digit"}}
+	expectedMessage := "password does not meet the requirements: must be at least 8 characters long; must contain a digit"
+	assert.Equal(t, expectedMessage, err.Error(), "Error message should match expected output")
+}
+
+func TestQuotaExceededError(t *testing.T) {
+	err := &QuotaExceededError{Resource: "cameras", Limit: 10}
+	expectedMessage := "quota exceeded: the organization may store at most 10 cameras"
+	assert.Equal(t, expectedMessage, err.Error(), "Error message should match expected output")
+}
+
+func TestAlreadyExistsError(t *testing.T) {
+	err := &AlreadyExistsError{Name: "Berlin"}
+	expectedMessage := "item with name Berlin already exists"
+	assert.Equal(t, expectedMessage, err.Error(), "Error message should match expected output")
+}
+
+func TestSharedUserError(t *testing.T) {
+	err := &SharedUserError{ID: "12"}
+	expectedMessage := "user with ID 12 also belongs to other organizations"
+	assert.Equal(t, expectedMessage, err.Error(), "Error message should match expected output")
+}
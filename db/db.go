@@ -2,8 +2,9 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 	"go-sample-rest-api/config"
 	"go-sample-rest-api/logging"
@@ -13,9 +14,7 @@ import (
 // and returns the sql.DB object to be used by the application.
 func NewPostgresStorageConn(conf config.Config) (*sql.DB, error) {
 	log := logging.GetLogger()
-	connStr := fmt.Sprintf("user=%s password=%s dbname=%s host=%s port=%s sslmode=disable",
-		conf.DBUser, conf.DBPassword, conf.DBName, conf.DBHost, conf.DBPort)
-	db, err := sql.Open("postgres", connStr)
+	db, err := sql.Open("postgres", ConnString(conf))
 	if err != nil {
 		log.WithFields(logrus.Fields{
 			"host":  conf.DBHost,
@@ -42,3 +41,15 @@ func NewPostgresStorageConn(conf config.Config) (*sql.DB, error) {
 	}).Info("Successfully connected to the db!")
 	return db, nil
 }
+
+// ConnString returns the connection string of the PostgreSQL database.
+func ConnString(conf config.Config) string {
+	return fmt.Sprintf("user=%s password=%s dbname=%s host=%s port=%s sslmode=disable",
+		conf.DBUser, conf.DBPassword, conf.DBName, conf.DBHost, conf.DBPort)
+}
+
+// IsUniqueViolation reports whether the statement failed on a unique constraint.
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
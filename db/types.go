@@ -1,7 +1,9 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 )
 
 // DB is an interface for interacting with the database
@@ -9,6 +11,15 @@ type DB interface {
 	Query(query string, args ...interface{}) (*sql.Rows, error)
 	QueryRow(query string, args ...interface{}) *sql.Row
 	Exec(query string, args ...interface{}) (sql.Result, error)
+	// QueryContext, QueryRowContext and ExecContext cancel the statement in
+	// Postgres when ctx is done, e.g. because the client went away.
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	// WithTx runs fn in a transaction, which is committed if fn returns nil
+	// and rolled back otherwise. Within a transaction it joins the running one.
+	// The transaction is rolled back when ctx is done before it commits.
+	WithTx(ctx context.Context, fn func(tx DB) error) error
 }
 
 // SQLDB implements the DB interface using an *sql.DB object
@@ -35,3 +46,69 @@ func (d *SQLDB) QueryRow(query string, args ...interface{}) *sql.Row {
 func (d *SQLDB) Exec(query string, args ...interface{}) (sql.Result, error) {
 	return d.db.Exec(query, args...)
 }
+
+// QueryContext executes a SQL query that returns rows within ctx
+func (d *SQLDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return d.db.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext executes a SQL query that is expected to return at most one row within ctx
+func (d *SQLDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return d.db.QueryRowContext(ctx, query, args...)
+}
+
+// ExecContext executes a SQL query that doesn't return rows within ctx
+func (d *SQLDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return d.db.ExecContext(ctx, query, args...)
+}
+
+// WithTx begins a transaction and passes it to fn
+func (d *SQLDB) WithTx(ctx context.Context, fn func(tx DB) error) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(&sqlTx{tx: tx}); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// sqlTx implements the DB interface within a running transaction
+type sqlTx struct {
+	tx *sql.Tx
+}
+
+func (t *sqlTx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return t.tx.Query(query, args...)
+}
+
+func (t *sqlTx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return t.tx.QueryRow(query, args...)
+}
+
+func (t *sqlTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return t.tx.Exec(query, args...)
+}
+
+func (t *sqlTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return t.tx.QueryContext(ctx, query, args...)
+}
+
+func (t *sqlTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return t.tx.QueryRowContext(ctx, query, args...)
+}
+
+func (t *sqlTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return t.tx.ExecContext(ctx, query, args...)
+}
+
+// WithTx runs fn in the running transaction
+func (t *sqlTx) WithTx(ctx context.Context, fn func(tx DB) error) error {
+	return fn(t)
+}
@@ -0,0 +1,87 @@
+package graphqlapi
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/types"
+)
+
+// mockCameraStore only answers what the resolvers read.
+type mockCameraStore struct {
+	mock.Mock
+	types.CameraMetadataStore
+}
+
+func (m *mockCameraStore) GetCameraMetadataByID(ctx context.Context, orgID int, camID string) (*types.CameraMetadata, error) {
+	args := m.Called(ctx, orgID, camID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.CameraMetadata), args.Error(1)
+}
+
+func (m *mockCameraStore) ListCameraMetadata(ctx context.Context, orgID int, filter types.CameraFilter) ([]types.CameraMetadata, int, error) {
+	args := m.Called(ctx, orgID, filter)
+	if args.Error(2) != nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]types.CameraMetadata), args.Int(1), args.Error(2)
+}
+
+// mockUserStore only answers what the resolvers read.
+type mockUserStore struct {
+	mock.Mock
+	types.UserStore
+}
+
+func (m *mockUserStore) GetOrganizationUser(ctx context.Context, orgID, userID int) (*types.User, error) {
+	args := m.Called(ctx, orgID, userID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.User), args.Error(1)
+}
+
+func (m *mockUserStore) GetOrganizationUsers(ctx context.Context, orgID int, userIDs []int) ([]types.User, error) {
+	args := m.Called(ctx, orgID, userIDs)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.User), args.Error(1)
+}
+
+func (m *mockUserStore) ListUsers(ctx context.Context, orgID int, query types.UserListQuery) ([]types.User, int, error) {
+	args := m.Called(ctx, orgID, query)
+	if args.Error(2) != nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]types.User), args.Int(1), args.Error(2)
+}
+
+type mockAuditLogStore struct {
+	mock.Mock
+}
+
+func (m *mockAuditLogStore) ListAuditEntries(ctx context.Context, orgID int, filter types.AuditFilter) ([]types.AuditEntry, int, error) {
+	args := m.Called(ctx, orgID, filter)
+	if args.Error(2) != nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]types.AuditEntry), args.Int(1), args.Error(2)
+}
+
+func (m *mockAuditLogStore) ListResourceChanges(ctx context.Context, orgID int, resourceType string, resourceIDs []string, field string) ([]types.AuditEntry, error) {
+	args := m.Called(ctx, orgID, resourceType, resourceIDs, field)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.AuditEntry), args.Error(1)
+}
+
+func (m *mockAuditLogStore) ListActorEntries(ctx context.Context, orgID int, actorType, actorID string) ([]types.AuditEntry, error) {
+	args := m.Called(ctx, orgID, actorType, actorID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.AuditEntry), args.Error(1)
+}
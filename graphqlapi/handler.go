@@ -0,0 +1,115 @@
+// Package graphqlapi serves the cameras and users of an organization as a
+// read-only GraphQL schema, so clients can fetch cameras together with their
+// image, owner and firmware history in one request. Resolvers read the same
+// stores as the REST handlers; owners and firmware histories are loaded in
+// batches per level of the query, see loader.
+package graphqlapi
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"go-sample-rest-api/config"
+	auth2 "go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"go-sample-rest-api/utils"
+	"net/http"
+)
+
+type Handler struct {
+	cameraStore   types.CameraMetadataStore
+	userStore     types.UserStore
+	auditStore    types.AuditLogStore
+	schema        graphql.Schema
+	maxDepth      int
+	maxComplexity int
+}
+
+func NewHandler(cameraStore types.CameraMetadataStore, userStore types.UserStore, auditStore types.AuditLogStore) *Handler {
+	h := &Handler{
+		cameraStore:   cameraStore,
+		userStore:     userStore,
+		auditStore:    auditStore,
+		maxDepth:      int(config.Envs.GraphQLMaxDepth),
+		maxComplexity: int(config.Envs.GraphQLMaxComplexity),
+	}
+
+	schema, err := h.newSchema()
+	if err != nil {
+		panic(fmt.Sprintf("invalid GraphQL schema: %v", err))
+	}
+	h.schema = schema
+
+	return h
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/graphql", auth2.WithJWTAuth(h.handleQuery, h.userStore)).Methods(http.MethodPost)
+}
+
+// handleQuery godoc
+// @Summary Query cameras and users with GraphQL
+// @Description Runs a GraphQL query over the cameras and members of the organization. Queries deeper than GRAPHQL_MAX_DEPTH or more complex than GRAPHQL_MAX_COMPLEXITY are rejected; every field costs 1 and the fields below cameras and users count once per entry of the page. Errors of single fields are returned next to the data of the others.
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Param query body types.GraphQLRequest true "The query"
+// @Success 200 {object} types.GraphQLResponse
+// @Failure 400 {object} types.GraphQLResponse "The query is invalid or exceeds the limits."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Router /graphql [post]
+func (h *Handler) handleQuery(w http.ResponseWriter, r *http.Request) {
+	var payload types.GraphQLRequest
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(payload.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, response(nil, gqlerrors.FormatErrors(err)))
+		return
+	}
+
+	if validation := graphql.ValidateDocument(&h.schema, doc, nil); !validation.IsValid {
+		utils.WriteJSON(w, http.StatusBadRequest, response(nil, validation.Errors))
+		return
+	}
+
+	if err := checkLimits(&h.schema, doc, payload.Variables, h.maxDepth, h.maxComplexity); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, response(nil, gqlerrors.FormatErrors(err)))
+		return
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           doc,
+		OperationName: payload.OperationName,
+		Args:          payload.Variables,
+		Context:       withLoaders(r.Context(), h.newLoaders(auth2.GetOrgIDFromContext(r.Context()))),
+	})
+	utils.WriteJSON(w, http.StatusOK, response(result.Data, result.Errors))
+}
+
+func response(data any, errs []gqlerrors.FormattedError) types.GraphQLResponse {
+	res := types.GraphQLResponse{Data: data}
+	for _, e := range errs {
+		graphQLError := types.GraphQLError{Message: e.Message, Path: e.Path}
+		for _, l := range e.Locations {
+			graphQLError.Locations = append(graphQLError.Locations, types.GraphQLLocation{Line: l.Line, Column: l.Column})
+		}
+		res.Errors = append(res.Errors, graphQLError)
+	}
+	return res
+}
@@ -0,0 +1,265 @@
+package graphqlapi
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/customerrors"
+	"go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const orgID = 3
+
+type testHandler struct {
+	*Handler
+	cameras *mockCameraStore
+	users   *mockUserStore
+	audit   *mockAuditLogStore
+}
+
+func newTestHandler() *testHandler {
+	h := &testHandler{cameras: new(mockCameraStore), users: new(mockUserStore), audit: new(mockAuditLogStore)}
+	h.Handler = NewHandler(h.cameras, h.users, h.audit)
+	return h
+}
+
+// query runs the query as user 1 with the role and decodes the response.
+func (h *testHandler) query(t *testing.T, role, query string, variables map[string]any) (int, types.GraphQLResponse) {
+	t.Helper()
+	body, _ := json.Marshal(types.GraphQLRequest{Query: query, Variables: variables})
+	req, _ := http.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	ctx := context.WithValue(req.Context(), auth.UserKey, 1)
+	ctx = context.WithValue(ctx, auth.OrgKey, orgID)
+	ctx = context.WithValue(ctx, auth.RoleKey, role)
+
+	rr := httptest.NewRecorder()
+	h.handleQuery(rr, req.WithContext(ctx))
+
+	var response types.GraphQLResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response %q: %v", rr.Body.String(), err)
+	}
+	return rr.Code, response
+}
+
+func camera(camID string, ownerID int64) types.CameraMetadata {
+	return types.CameraMetadata{
+		CamID:           camID,
+		CameraName:      "camera " + camID,
+		FirmwareVersion: "2.0.0",
+		OwnerID:         sql.NullInt64{Int64: ownerID, Valid: ownerID != 0},
+		OrgID:           orgID,
+		Tags:            types.Tags{"site": "berlin", "floor": "2"},
+	}
+}
+
+func TestHandleQuery_Cameras(t *testing.T) {
+	t.Run("loads owners and firmware history in one batch each", func(t *testing.T) {
+		// arrange
+		h := newTestHandler()
+		withImage := camera("cam-1", 1)
+		withImage.ImageId = sql.NullString{String: "img-1", Valid: true}
+		withImage.ImageSizeBytes = 2048
+		cameras := []types.CameraMetadata{withImage, camera("cam-2", 2), camera("cam-3", 1), camera("cam-4", 0)}
+		groupID := 7
+		h.cameras.On("ListCameraMetadata", mock.Anything, orgID, types.CameraFilter{
+			GroupID:  &groupID,
+			Selector: []types.TagRequirement{{Key: "site", Operator: types.TagEquals, Value: "berlin"}},
+			Limit:    2,
+			Offset:   2,
+		}).Return(cameras, 10, nil)
+		h.users.On("GetOrganizationUsers", mock.Anything, orgID, []int{1, 2}).
+			Return([]types.User{{ID: 1, FirstName: "Ann"}, {ID: 2, FirstName: "Bob"}}, nil).Once()
+		changedAt := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+		h.audit.On("ListResourceChanges", mock.Anything, orgID, "camera", []string{"cam-1", "cam-2", "cam-3", "cam-4"}, "firmware_version").
+			Return([]types.AuditEntry{
+				{ResourceID: "cam-1", ActorType: "user", ActorID: "1", CreatedAt: changedAt,
+					Changes: types.AuditChanges{"firmware_version": {Before: nil, After: "1.0.0"}}},
+				{ResourceID: "cam-1", ActorType: "user", ActorID: "1", CreatedAt: changedAt.Add(time.Hour),
+					Changes: types.AuditChanges{"firmware_version": {Before: "1.0.0", After: "2.0.0"}}},
+			}, nil).Once()
+
+		// act
+		code, response := h.query(t, types.RoleUser, `query($size: Int) {
+			cameras(groupId: 7, selector: "site=berlin", page: 2, pageSize: $size) {
+				total
+				cameras { id tags { key value } image { id sizeBytes } owner { firstName } firmwareHistory { version previousVersion } }
+			}
+		}`, map[string]any{"size": 2})
+
+		// assert
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, response.Errors)
+		list := response.Data.(map[string]any)["cameras"].(map[string]any)
+		assert.Equal(t, float64(10), list["total"])
+		result := list["cameras"].([]any)
+		assert.Len(t, result, 4)
+
+		first := result[0].(map[string]any)
+		assert.Equal(t, []any{map[string]any{"key": "floor", "value": "2"}, map[string]any{"key": "site", "value": "berlin"}}, first["tags"])
+		assert.Equal(t, map[string]any{"id": "img-1", "sizeBytes": float64(2048)}, first["image"])
+		assert.Equal(t, map[string]any{"firstName": "Ann"}, first["owner"])
+		assert.Equal(t, []any{
+			map[string]any{"version": "1.0.0", "previousVersion": nil},
+			map[string]any{"version": "2.0.0", "previousVersion": "1.0.0"},
+		}, first["firmwareHistory"])
+
+		assert.Equal(t, map[string]any{"firstName": "Bob"}, result[1].(map[string]any)["owner"])
+		assert.Nil(t, result[1].(map[string]any)["image"])
+		assert.Equal(t, []any{}, result[1].(map[string]any)["firmwareHistory"])
+		assert.Nil(t, result[3].(map[string]any)["owner"])
+		h.users.AssertExpectations(t)
+		h.audit.AssertExpectations(t)
+	})
+
+	t.Run("hides the email and role of other owners from members", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			role  string
+			owner map[string]any
+		}{
+			{name: "member", role: types.RoleUser, owner: map[string]any{"id": float64(2), "firstName": "Bob", "email": nil, "role": nil}},
+			{name: "admin", role: types.RoleAdmin, owner: map[string]any{"id": float64(2), "firstName": "Bob", "email": "bob@example.com", "role": types.RoleUser}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				// arrange
+				h := newTestHandler()
+				h.cameras.On("GetCameraMetadataByID", mock.Anything, orgID, "cam-2").Return(&types.CameraMetadata{CamID: "cam-2", OwnerID: sql.NullInt64{Int64: 2, Valid: true}}, nil)
+				h.users.On("GetOrganizationUsers", mock.Anything, orgID, []int{2}).
+					Return([]types.User{{ID: 2, FirstName: "Bob", Email: "bob@example.com", Role: types.RoleUser}}, nil)
+
+				// act
+				code, response := h.query(t, tt.role, `{ camera(id: "cam-2") { owner { id firstName email role } } }`, nil)
+
+				// assert
+				assert.Equal(t, http.StatusOK, code)
+				assert.Empty(t, response.Errors)
+				assert.Equal(t, map[string]any{"camera": map[string]any{"owner": tt.owner}}, response.Data)
+			})
+		}
+	})
+
+	t.Run("shows who changed the firmware only to admins", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			role   string
+			change map[string]any
+		}{
+			{name: "member", role: types.RoleUser, change: map[string]any{"version": "1.0.0", "actorType": nil, "actorId": nil}},
+			{name: "admin", role: types.RoleAdmin, change: map[string]any{"version": "1.0.0", "actorType": "user", "actorId": "1"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				// arrange
+				h := newTestHandler()
+				h.cameras.On("GetCameraMetadataByID", mock.Anything, orgID, "cam-1").Return(&types.CameraMetadata{CamID: "cam-1"}, nil)
+				h.audit.On("ListResourceChanges", mock.Anything, orgID, "camera", []string{"cam-1"}, "firmware_version").
+					Return([]types.AuditEntry{{ResourceID: "cam-1", ActorType: "user", ActorID: "1",
+						Changes: types.AuditChanges{"firmware_version": {Before: nil, After: "1.0.0"}}}}, nil)
+
+				// act
+				code, response := h.query(t, tt.role, `{ camera(id: "cam-1") { firmwareHistory { version actorType actorId } } }`, nil)
+
+				// assert
+				assert.Equal(t, http.StatusOK, code)
+				assert.Empty(t, response.Errors)
+				assert.Equal(t, map[string]any{"camera": map[string]any{"firmwareHistory": []any{tt.change}}}, response.Data)
+			})
+		}
+	})
+
+	t.Run("a camera that cannot be loaded is an error", func(t *testing.T) {
+		// arrange
+		h := newTestHandler()
+		h.cameras.On("GetCameraMetadataByID", mock.Anything, orgID, "cam-1").Return(nil, errors.New("db down"))
+
+		// act
+		code, response := h.query(t, types.RoleUser, `{ camera(id: "cam-1") { id } }`, nil)
+
+		// assert
+		assert.Equal(t, http.StatusOK, code)
+		assert.Len(t, response.Errors, 1)
+		assert.Equal(t, map[string]any{"camera": nil}, response.Data)
+	})
+
+	t.Run("an unknown camera is null", func(t *testing.T) {
+		// arrange
+		h := newTestHandler()
+		h.cameras.On("GetCameraMetadataByID", mock.Anything, orgID, "cam-9").Return(nil, &customerrors.NotFoundError{ID: "cam-9"})
+
+		// act
+		code, response := h.query(t, types.RoleUser, `{ camera(id: "cam-9") { id } }`, nil)
+
+		// assert
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, response.Errors)
+		assert.Equal(t, map[string]any{"camera": nil}, response.Data)
+	})
+}
+
+func TestHandleQuery_Users(t *testing.T) {
+	t.Run("me returns the user with their role", func(t *testing.T) {
+		// arrange
+		h := newTestHandler()
+		h.users.On("GetOrganizationUser", mock.Anything, orgID, 1).Return(&types.User{ID: 1, Email: "ann@example.com", Role: types.RoleUser}, nil)
+
+		// act
+		code, response := h.query(t, types.RoleUser, `{ me { email role } }`, nil)
+
+		// assert
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, map[string]any{"me": map[string]any{"email": "ann@example.com", "role": types.RoleUser}}, response.Data)
+	})
+
+	t.Run("users is for admins only", func(t *testing.T) {
+		// arrange
+		h := newTestHandler()
+
+		// act
+		code, response := h.query(t, types.RoleUser, `{ users { total } }`, nil)
+
+		// assert
+		assert.Equal(t, http.StatusOK, code)
+		assert.Len(t, response.Errors, 1)
+		assert.Equal(t, "permission denied", response.Errors[0].Message)
+		h.users.AssertNotCalled(t, "ListUsers", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestHandleQuery_Rejected(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"syntax error", `{ cameras { `},
+		{"unknown field", `{ cameras { unknown } }`},
+		{"too complex", `{ cameras(pageSize: 50) { cameras { id name } } }`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			h := newTestHandler()
+			h.maxComplexity = 100
+
+			// act
+			code, response := h.query(t, types.RoleUser, tt.query, nil)
+
+			// assert
+			assert.Equal(t, http.StatusBadRequest, code)
+			assert.Nil(t, response.Data)
+			assert.NotEmpty(t, response.Errors)
+			h.cameras.AssertNotCalled(t, "ListCameraMetadata", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"go-sample-rest-api/utils"
+	"strconv"
+	"strings"
+)
+
+// limits measures the depth and complexity of the operations of a validated
+// document. Every field costs 1, and the fields below a paged field count
+// once for every entry of its page. Introspection fields are free, so tools
+// can always load the schema.
+type limits struct {
+	schema    *graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+	// defaults are the default values of the variables of the operation
+	// being measured.
+	defaults map[string]ast.Value
+}
+
+// checkLimits fails if an operation of the document is deeper than maxDepth
+// or more complex than maxComplexity. The document must be valid, so that
+// every field exists and fragments do not form cycles.
+func checkLimits(schema *graphql.Schema, doc *ast.Document, variables map[string]any, maxDepth, maxComplexity int) error {
+	l := &limits{schema: schema, fragments: make(map[string]*ast.FragmentDefinition), variables: variables}
+	for _, definition := range doc.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			l.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	for _, definition := range doc.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok || operation.Operation != ast.OperationTypeQuery {
+			continue
+		}
+
+		l.defaults = make(map[string]ast.Value)
+		for _, variable := range operation.VariableDefinitions {
+			if variable.DefaultValue != nil {
+				l.defaults[variable.Variable.Name.Value] = variable.DefaultValue
+			}
+		}
+
+		depth, complexity := l.measure(schema.QueryType(), operation.SelectionSet)
+		if depth > maxDepth {
+			return fmt.Errorf("query depth %d exceeds the limit of %d", depth, maxDepth)
+		}
+		if complexity > maxComplexity {
+			return fmt.Errorf("query complexity %d exceeds the limit of %d", complexity, maxComplexity)
+		}
+	}
+
+	return nil
+}
+
+// measure returns the depth and the complexity of the selections on parent.
+func (l *limits) measure(parent *graphql.Object, set *ast.SelectionSet) (depth, complexity int) {
+	if parent == nil || set == nil {
+		return 0, 0
+	}
+
+	for _, selection := range set.Selections {
+		var d, c int
+		switch s := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name.Value, "__") {
+				continue
+			}
+			definition := parent.Fields()[s.Name.Value]
+			if definition == nil {
+				continue
+			}
+			d, c = l.measure(objectType(definition.Type), s.SelectionSet)
+			d, c = d+1, 1+l.pageSize(definition, s)*c
+		case *ast.InlineFragment:
+			d, c = l.measure(l.condition(s.TypeCondition, parent), s.SelectionSet)
+		case *ast.FragmentSpread:
+			if fragment := l.fragments[s.Name.Value]; fragment != nil {
+				d, c = l.measure(l.condition(fragment.TypeCondition, parent), fragment.SelectionSet)
+			}
+		}
+		depth = max(depth, d)
+		complexity += c
+	}
+
+	return depth, complexity
+}
+
+// pageSize returns the number of entries the field returns at most. Fields
+// without a pageSize argument return a single one.
+func (l *limits) pageSize(definition *graphql.FieldDefinition, field *ast.Field) int {
+	paged := false
+	for _, arg := range definition.Args {
+		paged = paged || arg.Name() == "pageSize"
+	}
+	if !paged {
+		return 1
+	}
+
+	size := 0
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "pageSize" {
+			continue
+		}
+		switch value := arg.Value.(type) {
+		case *ast.IntValue:
+			size, _ = strconv.Atoi(value.Value)
+		case *ast.Variable:
+			size = l.variable(value.Name.Value)
+		}
+	}
+	if size <= 0 {
+		return utils.DefaultPageSize
+	}
+	return min(size, utils.MaxPageSize)
+}
+
+// variable returns the page size a variable holds, falling back to its
+// default value. A page size that cannot be read counts as the largest one.
+func (l *limits) variable(name string) int {
+	value, ok := l.variables[name]
+	if !ok {
+		switch defaultValue := l.defaults[name].(type) {
+		case nil:
+			return 0
+		case *ast.IntValue:
+			size, _ := strconv.Atoi(defaultValue.Value)
+			return size
+		default:
+			return utils.MaxPageSize
+		}
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case float64:
+		// JSON numbers decode as float64
+		return int(v)
+	default:
+		return utils.MaxPageSize
+	}
+}
+
+// condition returns the type of a fragment, or parent if it has no condition.
+func (l *limits) condition(named *ast.Named, parent *graphql.Object) *graphql.Object {
+	if named == nil {
+		return parent
+	}
+	object, _ := l.schema.Type(named.Name.Value).(*graphql.Object)
+	return object
+}
+
+// objectType returns the object a field returns, or nil for a scalar.
+func objectType(t graphql.Type) *graphql.Object {
+	for {
+		switch wrapped := t.(type) {
+		case *graphql.NonNull:
+			t = wrapped.OfType
+		case *graphql.List:
+			t = wrapped.OfType
+		case *graphql.Object:
+			return wrapped
+		default:
+			return nil
+		}
+	}
+}
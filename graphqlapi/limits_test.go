@@ -0,0 +1,95 @@
+package graphqlapi
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCheckLimits(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		variables     map[string]any
+		maxDepth      int
+		maxComplexity int
+		wantErr       string
+	}{
+		{
+			name:          "counts every field of a page",
+			query:         `{ cameras(pageSize: 10) { total cameras { id owner { id } } } }`,
+			maxDepth:      4,
+			maxComplexity: 51,
+		},
+		{
+			name:          "rejects queries over the complexity",
+			query:         `{ cameras(pageSize: 10) { total cameras { id owner { id } } } }`,
+			maxDepth:      4,
+			maxComplexity: 50,
+			wantErr:       "query complexity 51 exceeds the limit of 50",
+		},
+		{
+			name:          "takes the page size from variables",
+			query:         `query($size: Int) { cameras(pageSize: $size) { cameras { id } } }`,
+			variables:     map[string]any{"size": float64(50)},
+			maxDepth:      4,
+			maxComplexity: 100,
+			wantErr:       "query complexity 101 exceeds the limit of 100",
+		},
+		{
+			name:          "takes the page size from variable defaults",
+			query:         `query($size: Int = 100) { cameras(pageSize: $size) { cameras { id } } }`,
+			maxDepth:      4,
+			maxComplexity: 200,
+			wantErr:       "query complexity 201 exceeds the limit of 200",
+		},
+		{
+			name:          "prefers variables over their defaults",
+			query:         `query($size: Int = 100) { cameras(pageSize: $size) { cameras { id } } }`,
+			variables:     map[string]any{"size": float64(10)},
+			maxDepth:      4,
+			maxComplexity: 21,
+		},
+		{
+			name:          "caps the page size",
+			query:         `{ users(pageSize: 1000) { users { id } } }`,
+			maxDepth:      4,
+			maxComplexity: 201,
+		},
+		{
+			name:          "follows fragments",
+			query:         `{ cameras { ...list } } fragment list on CameraList { cameras { owner { id } } }`,
+			maxDepth:      3,
+			maxComplexity: 1000,
+			wantErr:       "query depth 4 exceeds the limit of 3",
+		},
+		{
+			name:          "ignores introspection",
+			query:         `{ __schema { types { name fields { name type { name ofType { name ofType { name } } } } } } me { id } }`,
+			maxDepth:      2,
+			maxComplexity: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			schema, err := (&Handler{}).newSchema()
+			assert.NoError(t, err)
+			doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+			assert.NoError(t, err)
+			assert.True(t, graphql.ValidateDocument(&schema, doc, nil).IsValid)
+
+			// act
+			err = checkLimits(&schema, doc, tt.variables, tt.maxDepth, tt.maxComplexity)
+
+			// assert
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package graphqlapi
+
+import (
+	"context"
+	"sync"
+)
+
+// loader batches the keys loaded while one level of a query resolves into a
+// single call of fetch. graphql-go runs the thunks returned by resolvers only
+// after all fields of the level returned, so the first thunk that runs
+// fetches the keys of all of them. Results are kept for the rest of the
+// request, and a key missing from the fetched map loads as the zero value.
+type loader[K comparable, V any] struct {
+	fetch   func(ctx context.Context, keys []K) (map[K]V, error)
+	mu      sync.Mutex
+	pending []K
+	results map[K]*result[V]
+}
+
+type result[V any] struct {
+	done  bool
+	value V
+	err   error
+}
+
+func newLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{fetch: fetch, results: make(map[K]*result[V])}
+}
+
+// load queues the key and returns a thunk for its value.
+func (l *loader[K, V]) load(ctx context.Context, key K) func() (V, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.results[key]; !ok {
+		l.results[key] = &result[V]{}
+		l.pending = append(l.pending, key)
+	}
+
+	return func() (V, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		r := l.results[key]
+		if !r.done {
+			l.flush(ctx)
+		}
+		return r.value, r.err
+	}
+}
+
+// flush fetches the pending keys. It must be called with l.mu held.
+func (l *loader[K, V]) flush(ctx context.Context) {
+	keys := l.pending
+	l.pending = nil
+
+	values, err := l.fetch(ctx, keys)
+	for _, key := range keys {
+		r := l.results[key]
+		r.done, r.value, r.err = true, values[key], err
+	}
+}
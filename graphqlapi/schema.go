@@ -0,0 +1,479 @@
+package graphqlapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/graphql-go/graphql"
+	"go-sample-rest-api/customerrors"
+	auth2 "go-sample-rest-api/service/auth"
+	"go-sample-rest-api/service/camerametadata"
+	"go-sample-rest-api/types"
+	"go-sample-rest-api/utils"
+	"sort"
+	"strings"
+	"time"
+)
+
+// resourceCamera is the resource type of camera entries in the audit log.
+const resourceCamera = "camera"
+
+var errPermissionDenied = errors.New("permission denied")
+
+// firmwareChange is an entry of the audit log that set the firmware version
+// of a camera. The version a camera was created with has no previous one.
+type firmwareChange struct {
+	Version         string
+	PreviousVersion *string
+	ChangedAt       time.Time
+	ActorType       string
+	ActorID         string
+}
+
+type tag struct {
+	Key   string
+	Value string
+}
+
+// loaders batch the lookups of one request. The stores are queried once per
+// level of the query rather than once per camera.
+type loaders struct {
+	owners   *loader[int, *types.User]
+	firmware *loader[string, []firmwareChange]
+}
+
+type loadersKey struct{}
+
+func (h *Handler) newLoaders(orgID int) *loaders {
+	return &loaders{
+		owners: newLoader(func(ctx context.Context, ids []int) (map[int]*types.User, error) {
+			users, err := h.userStore.GetOrganizationUsers(ctx, orgID, ids)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load owners: %v", err)
+			}
+
+			owners := make(map[int]*types.User, len(users))
+			for i := range users {
+				owners[users[i].ID] = &users[i]
+			}
+			return owners, nil
+		}),
+		firmware: newLoader(func(ctx context.Context, camIDs []string) (map[string][]firmwareChange, error) {
+			entries, err := h.auditStore.ListResourceChanges(ctx, orgID, resourceCamera, camIDs, "firmware_version")
+			if err != nil {
+				return nil, fmt.Errorf("failed to load firmware history: %v", err)
+			}
+
+			history := make(map[string][]firmwareChange)
+			for _, e := range entries {
+				change := e.Changes["firmware_version"]
+				version, _ := change.After.(string)
+				c := firmwareChange{Version: version, ChangedAt: e.CreatedAt, ActorType: e.ActorType, ActorID: e.ActorID}
+				if previous, ok := change.Before.(string); ok {
+					c.PreviousVersion = &previous
+				}
+				history[e.ResourceID] = append(history[e.ResourceID], c)
+			}
+			return history, nil
+		}),
+	}
+}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// private resolves a field of a user that only admins and the user themself
+// may read. Other members, who reach users through the owners of cameras,
+// get null.
+func private(value func(u types.User) any) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		user := p.Source.(types.User)
+		if auth2.GetUserRoleFromContext(p.Context) != types.RoleAdmin && auth2.GetUserIDFromContext(p.Context) != user.ID {
+			return nil, nil
+		}
+		return value(user), nil
+	}
+}
+
+// adminOnly resolves a field of a firmware change that only admins may read,
+// since the actor of an audit entry is not shown to members elsewhere either.
+// Other members get null.
+func adminOnly(value func(c firmwareChange) any) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		if auth2.GetUserRoleFromContext(p.Context) != types.RoleAdmin {
+			return nil, nil
+		}
+		return value(p.Source.(firmwareChange)), nil
+	}
+}
+
+// newSchema returns the read-only schema over the cameras and users of the
+// organization of the access token.
+func (h *Handler) newSchema() (graphql.Schema, error) {
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id":        newField(graphql.NewNonNull(graphql.Int), func(u types.User) any { return u.ID }),
+			"firstName": newField(graphql.NewNonNull(graphql.String), func(u types.User) any { return u.FirstName }),
+			"lastName":  newField(graphql.NewNonNull(graphql.String), func(u types.User) any { return u.LastName }),
+			"email": &graphql.Field{
+				Type:        graphql.String,
+				Description: "The email, null unless the viewer is an admin or the user themself.",
+				Resolve:     private(func(u types.User) any { return u.Email }),
+			},
+			"role": &graphql.Field{
+				Type:        graphql.String,
+				Description: "The role in the organization of the access token, null unless the viewer is an admin or the user themself.",
+				Resolve:     private(func(u types.User) any { return u.Role }),
+			},
+			"createdAt": newField(graphql.NewNonNull(graphql.DateTime), func(u types.User) any { return u.CreatedAt }),
+		},
+	})
+
+	imageType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Image",
+		Description: "The current image of a camera.",
+		Fields: graphql.Fields{
+			"id":        newField(graphql.NewNonNull(graphql.ID), func(c types.CameraMetadata) any { return c.ImageId.String }),
+			"sizeBytes": newField(graphql.NewNonNull(graphql.Int), func(c types.CameraMetadata) any { return c.ImageSizeBytes }),
+			"container": newField(graphql.String, func(c types.CameraMetadata) any { return nullString(c.ContainerName) }),
+		},
+	})
+
+	tagType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Tag",
+		Fields: graphql.Fields{
+			"key":   newField(graphql.NewNonNull(graphql.String), func(t tag) any { return t.Key }),
+			"value": newField(graphql.NewNonNull(graphql.String), func(t tag) any { return t.Value }),
+		},
+	})
+
+	firmwareChangeType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "FirmwareChange",
+		Description: "A firmware version of a camera, read from the audit log.",
+		Fields: graphql.Fields{
+			"version":         newField(graphql.NewNonNull(graphql.String), func(c firmwareChange) any { return c.Version }),
+			"previousVersion": newField(graphql.String, func(c firmwareChange) any { return nullable(c.PreviousVersion) }),
+			"changedAt":       newField(graphql.NewNonNull(graphql.DateTime), func(c firmwareChange) any { return c.ChangedAt }),
+			"actorType": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Who changed the version, null unless the viewer is an admin.",
+				Resolve:     adminOnly(func(c firmwareChange) any { return c.ActorType }),
+			},
+			"actorId": &graphql.Field{
+				Type:        graphql.String,
+				Description: "The ID of who changed the version, null unless the viewer is an admin.",
+				Resolve:     adminOnly(func(c firmwareChange) any { return c.ActorID }),
+			},
+		},
+	})
+
+	cameraType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Camera",
+		Fields: graphql.Fields{
+			"id":              newField(graphql.NewNonNull(graphql.ID), func(c types.CameraMetadata) any { return c.CamID }),
+			"name":            newField(graphql.NewNonNull(graphql.String), func(c types.CameraMetadata) any { return c.CameraName }),
+			"firmwareVersion": newField(graphql.NewNonNull(graphql.String), func(c types.CameraMetadata) any { return c.FirmwareVersion }),
+			"createdAt":       newField(graphql.DateTime, func(c types.CameraMetadata) any { return nullTime(c.CreatedAt) }),
+			"initializedAt":   newField(graphql.DateTime, func(c types.CameraMetadata) any { return nullTime(c.InitializedAt) }),
+			"groupId": newField(graphql.Int, func(c types.CameraMetadata) any {
+				if !c.GroupID.Valid {
+					return nil
+				}
+				return c.GroupID.Int64
+			}),
+			"tags":        newField(graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(tagType))), func(c types.CameraMetadata) any { return sortedTags(c.Tags) }),
+			"latitude":    newField(graphql.Float, func(c types.CameraMetadata) any { return nullFloat(c.Latitude) }),
+			"longitude":   newField(graphql.Float, func(c types.CameraMetadata) any { return nullFloat(c.Longitude) }),
+			"heading":     newField(graphql.Float, func(c types.CameraMetadata) any { return nullFloat(c.Heading) }),
+			"fieldOfView": newField(graphql.Float, func(c types.CameraMetadata) any { return nullFloat(c.FieldOfView) }),
+			"image": newField(imageType, func(c types.CameraMetadata) any {
+				if !c.ImageId.Valid {
+					return nil
+				}
+				return c
+			}),
+			"owner": &graphql.Field{
+				Type:        userType,
+				Description: "The owner, null when the camera has none or the owner left the organization.",
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					camera := p.Source.(types.CameraMetadata)
+					if !camera.OwnerID.Valid {
+						return nil, nil
+					}
+
+					owner := loadersFrom(p.Context).owners.load(p.Context, int(camera.OwnerID.Int64))
+					return func() (any, error) {
+						user, err := owner()
+						if user == nil {
+							return nil, err
+						}
+						return *user, nil
+					}, nil
+				},
+			},
+			"firmwareHistory": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(firmwareChangeType))),
+				Description: "The firmware versions of the camera, oldest first.",
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					history := loadersFrom(p.Context).firmware.load(p.Context, p.Source.(types.CameraMetadata).CamID)
+					return func() (any, error) {
+						changes, err := history()
+						if changes == nil {
+							changes = []firmwareChange{}
+						}
+						return changes, err
+					}, nil
+				},
+			},
+		},
+	})
+
+	cameraListType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CameraList",
+		Fields: graphql.Fields{
+			"cameras":  newField(graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(cameraType))), func(l list) any { return l.items }),
+			"total":    newField(graphql.NewNonNull(graphql.Int), func(l list) any { return l.total }),
+			"page":     newField(graphql.NewNonNull(graphql.Int), func(l list) any { return l.page }),
+			"pageSize": newField(graphql.NewNonNull(graphql.Int), func(l list) any { return l.pageSize }),
+		},
+	})
+
+	userListType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "UserList",
+		Fields: graphql.Fields{
+			"users":    newField(graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(userType))), func(l list) any { return l.items }),
+			"total":    newField(graphql.NewNonNull(graphql.Int), func(l list) any { return l.total }),
+			"page":     newField(graphql.NewNonNull(graphql.Int), func(l list) any { return l.page }),
+			"pageSize": newField(graphql.NewNonNull(graphql.Int), func(l list) any { return l.pageSize }),
+		},
+	})
+
+	pageArgs := graphql.FieldConfigArgument{
+		"page":     &graphql.ArgumentConfig{Type: graphql.Int, Description: "The page, starting at 1."},
+		"pageSize": &graphql.ArgumentConfig{Type: graphql.Int, Description: fmt.Sprintf("The size of the page, %d by default and at most %d.", utils.DefaultPageSize, utils.MaxPageSize)},
+	}
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"me": &graphql.Field{
+				Type:        graphql.NewNonNull(userType),
+				Description: "The user of the access token.",
+				Resolve:     h.resolveMe,
+			},
+			"camera": &graphql.Field{
+				Type:        cameraType,
+				Description: "The camera with the ID, null when there is none.",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: h.resolveCamera,
+			},
+			"cameras": &graphql.Field{
+				Type:        graphql.NewNonNull(cameraListType),
+				Description: "A page of the cameras, oldest first, like GET /camera_metadata.",
+				Args: withArgs(pageArgs, graphql.FieldConfigArgument{
+					"groupId":  &graphql.ArgumentConfig{Type: graphql.Int},
+					"selector": &graphql.ArgumentConfig{Type: graphql.String, Description: `A tag selector such as "site=berlin,!outdoor".`},
+				}),
+				Resolve: h.resolveCameras,
+			},
+			"user": &graphql.Field{
+				Type:        userType,
+				Description: "The member with the ID, null when there is none. Admins only.",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: h.resolveUser,
+			},
+			"users": &graphql.Field{
+				Type:        graphql.NewNonNull(userListType),
+				Description: "A page of the members, like GET /users. Admins only.",
+				Args: withArgs(pageArgs, graphql.FieldConfigArgument{
+					"search": &graphql.ArgumentConfig{Type: graphql.String, Description: "Matches the name and email address."},
+				}),
+				Resolve: h.resolveUsers,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
+
+func (h *Handler) resolveMe(p graphql.ResolveParams) (any, error) {
+	user, err := h.userStore.GetOrganizationUser(p.Context, auth2.GetOrgIDFromContext(p.Context), auth2.GetUserIDFromContext(p.Context))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %v", err)
+	}
+
+	return *user, nil
+}
+
+func (h *Handler) resolveCamera(p graphql.ResolveParams) (any, error) {
+	camera, err := h.cameraStore.GetCameraMetadataByID(p.Context, auth2.GetOrgIDFromContext(p.Context), p.Args["id"].(string))
+	var notFound *customerrors.NotFoundError
+	if errors.As(err, &notFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get camera: %v", err)
+	}
+
+	return *camera, nil
+}
+
+func (h *Handler) resolveCameras(p graphql.ResolveParams) (any, error) {
+	page, pageSize, err := pagination(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := camerametadata.ParseSelector(stringArg(p.Args, "selector"))
+	if err != nil {
+		return nil, err
+	}
+	filter := types.CameraFilter{Selector: selector, Limit: pageSize, Offset: (page - 1) * pageSize}
+	if groupID, ok := p.Args["groupId"].(int); ok {
+		filter.GroupID = &groupID
+	}
+
+	cameras, total, err := h.cameraStore.ListCameraMetadata(p.Context, auth2.GetOrgIDFromContext(p.Context), filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cameras: %v", err)
+	}
+
+	return list{items: cameras, total: total, page: page, pageSize: pageSize}, nil
+}
+
+func (h *Handler) resolveUser(p graphql.ResolveParams) (any, error) {
+	if auth2.GetUserRoleFromContext(p.Context) != types.RoleAdmin {
+		return nil, errPermissionDenied
+	}
+
+	user, err := h.userStore.GetOrganizationUser(p.Context, auth2.GetOrgIDFromContext(p.Context), p.Args["id"].(int))
+	var notFound *customerrors.NotFoundError
+	if errors.As(err, &notFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %v", err)
+	}
+
+	return *user, nil
+}
+
+func (h *Handler) resolveUsers(p graphql.ResolveParams) (any, error) {
+	if auth2.GetUserRoleFromContext(p.Context) != types.RoleAdmin {
+		return nil, errPermissionDenied
+	}
+
+	page, pageSize, err := pagination(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	users, total, err := h.userStore.ListUsers(p.Context, auth2.GetOrgIDFromContext(p.Context), types.UserListQuery{
+		Search: strings.TrimSpace(stringArg(p.Args, "search")),
+		Limit:  pageSize,
+		Offset: (page - 1) * pageSize,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %v", err)
+	}
+
+	return list{items: users, total: total, page: page, pageSize: pageSize}, nil
+}
+
+// list is a page of cameras or users.
+type list struct {
+	items    any
+	total    int
+	page     int
+	pageSize int
+}
+
+// pagination applies the defaults and limits of utils.GetPagination.
+func pagination(args map[string]any) (int, int, error) {
+	page, _ := args["page"].(int)
+	pageSize, _ := args["pageSize"].(int)
+	if page < 0 || pageSize < 0 {
+		return 0, 0, errors.New("page and pageSize must not be negative")
+	}
+	if page == 0 {
+		page = 1
+	}
+	if pageSize == 0 {
+		pageSize = utils.DefaultPageSize
+	}
+	return page, min(pageSize, utils.MaxPageSize), nil
+}
+
+func stringArg(args map[string]any, name string) string {
+	value, _ := args[name].(string)
+	return value
+}
+
+func withArgs(args ...graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	all := graphql.FieldConfigArgument{}
+	for _, a := range args {
+		for name, arg := range a {
+			all[name] = arg
+		}
+	}
+	return all
+}
+
+// newField returns a field whose value is computed from its source.
+func newField[S any](t graphql.Output, value func(S) any) *graphql.Field {
+	return &graphql.Field{Type: t, Resolve: func(p graphql.ResolveParams) (any, error) {
+		return value(p.Source.(S)), nil
+	}}
+}
+
+// The values below are returned as untyped nil when they are not set, as a
+// nil pointer in an interface would not resolve to null.
+
+func nullable[T any](v *T) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func nullString(v sql.NullString) any {
+	if !v.Valid {
+		return nil
+	}
+	return v.String
+}
+
+func nullTime(v sql.NullTime) any {
+	if !v.Valid {
+		return nil
+	}
+	return v.Time
+}
+
+func nullFloat(v sql.NullFloat64) any {
+	if !v.Valid {
+		return nil
+	}
+	return v.Float64
+}
+
+// sortedTags returns the tags ordered by key, so responses are stable.
+func sortedTags(tags types.Tags) []tag {
+	sorted := make([]tag, 0, len(tags))
+	for key, value := range tags {
+		sorted = append(sorted, tag{Key: key, Value: value})
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Key < sorted[j].Key })
+	return sorted
+}
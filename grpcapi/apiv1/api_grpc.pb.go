@@ -0,0 +1,531 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: api/v1/api.proto
+
+// The gRPC API offers the user and camera operations of the REST API that
+// internal services need. Every call needs an access token from
+// POST /api/v1/login, sent as "authorization: Bearer <token>" metadata.
+//
+// The following operations are deliberately only offered by the REST API,
+// because people make them in the web app and not services:
+//   - users: creating (POST /users), deleting (DELETE /users/{id}), disabling
+//     and enabling (POST /users/{id}/disable, /enable), setting the role
+//     (PUT /users/{id}/role), everything under /users/me, and the export and
+//     erasure of personal data
+//   - cameras: setting the group (PUT /camera_metadata/{camID}/group), the
+//     tags (PUT .../tags) and the location (PUT and DELETE .../location), bulk
+//     updates (POST /camera_metadata/bulk) and live images
+
+package apiv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	UserService_GetCurrentUser_FullMethodName = "/api.v1.UserService/GetCurrentUser"
+	UserService_GetUser_FullMethodName        = "/api.v1.UserService/GetUser"
+	UserService_ListUsers_FullMethodName      = "/api.v1.UserService/ListUsers"
+)
+
+// UserServiceClient is the client API for UserService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type UserServiceClient interface {
+	// GetCurrentUser returns the user of the access token, like GET /users/me.
+	GetCurrentUser(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*User, error)
+	// GetUser returns a member of the organization, like GET /users/{id}.
+	// Admins only.
+	GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*User, error)
+	// ListUsers lists the members of the organization, like GET /users.
+	// Admins only.
+	ListUsers(ctx context.Context, in *ListUsersRequest, opts ...grpc.CallOption) (*ListUsersResponse, error)
+}
+
+type userServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewUserServiceClient(cc grpc.ClientConnInterface) UserServiceClient {
+	return &userServiceClient{cc}
+}
+
+func (c *userServiceClient) GetCurrentUser(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*User, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(User)
+	err := c.cc.Invoke(ctx, UserService_GetCurrentUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*User, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(User)
+	err := c.cc.Invoke(ctx, UserService_GetUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) ListUsers(ctx context.Context, in *ListUsersRequest, opts ...grpc.CallOption) (*ListUsersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListUsersResponse)
+	err := c.cc.Invoke(ctx, UserService_ListUsers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UserServiceServer is the server API for UserService service.
+// All implementations must embed UnimplementedUserServiceServer
+// for forward compatibility.
+type UserServiceServer interface {
+	// GetCurrentUser returns the user of the access token, like GET /users/me.
+	GetCurrentUser(context.Context, *emptypb.Empty) (*User, error)
+	// GetUser returns a member of the organization, like GET /users/{id}.
+	// Admins only.
+	GetUser(context.Context, *GetUserRequest) (*User, error)
+	// ListUsers lists the members of the organization, like GET /users.
+	// Admins only.
+	ListUsers(context.Context, *ListUsersRequest) (*ListUsersResponse, error)
+	mustEmbedUnimplementedUserServiceServer()
+}
+
+// UnimplementedUserServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedUserServiceServer struct{}
+
+func (UnimplementedUserServiceServer) GetCurrentUser(context.Context, *emptypb.Empty) (*User, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetCurrentUser not implemented")
+}
+func (UnimplementedUserServiceServer) GetUser(context.Context, *GetUserRequest) (*User, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetUser not implemented")
+}
+func (UnimplementedUserServiceServer) ListUsers(context.Context, *ListUsersRequest) (*ListUsersResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListUsers not implemented")
+}
+func (UnimplementedUserServiceServer) mustEmbedUnimplementedUserServiceServer() {}
+func (UnimplementedUserServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeUserServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to UserServiceServer will
+// result in compilation errors.
+type UnsafeUserServiceServer interface {
+	mustEmbedUnimplementedUserServiceServer()
+}
+
+func RegisterUserServiceServer(s grpc.ServiceRegistrar, srv UserServiceServer) {
+	// If the following call panics, it indicates UnimplementedUserServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&UserService_ServiceDesc, srv)
+}
+
+func _UserService_GetCurrentUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).GetCurrentUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_GetCurrentUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).GetCurrentUser(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_GetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).GetUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_GetUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).GetUser(ctx, req.(*GetUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_ListUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUsersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).ListUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_ListUsers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).ListUsers(ctx, req.(*ListUsersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// UserService_ServiceDesc is the grpc.ServiceDesc for UserService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var UserService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "api.v1.UserService",
+	HandlerType: (*UserServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetCurrentUser",
+			Handler:    _UserService_GetCurrentUser_Handler,
+		},
+		{
+			MethodName: "GetUser",
+			Handler:    _UserService_GetUser_Handler,
+		},
+		{
+			MethodName: "ListUsers",
+			Handler:    _UserService_ListUsers_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "api/v1/api.proto",
+}
+
+const (
+	CameraService_CreateCamera_FullMethodName     = "/api.v1.CameraService/CreateCamera"
+	CameraService_GetCamera_FullMethodName        = "/api.v1.CameraService/GetCamera"
+	CameraService_ListCameras_FullMethodName      = "/api.v1.CameraService/ListCameras"
+	CameraService_InitializeCamera_FullMethodName = "/api.v1.CameraService/InitializeCamera"
+	CameraService_UploadImage_FullMethodName      = "/api.v1.CameraService/UploadImage"
+	CameraService_DownloadImage_FullMethodName    = "/api.v1.CameraService/DownloadImage"
+)
+
+// CameraServiceClient is the client API for CameraService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CameraServiceClient interface {
+	// CreateCamera creates a camera owned by the user, like POST /camera_metadata.
+	CreateCamera(ctx context.Context, in *CreateCameraRequest, opts ...grpc.CallOption) (*Camera, error)
+	// GetCamera returns a camera, like GET /camera_metadata/{camID}.
+	GetCamera(ctx context.Context, in *GetCameraRequest, opts ...grpc.CallOption) (*Camera, error)
+	// ListCameras lists the cameras, oldest first, like GET /camera_metadata.
+	ListCameras(ctx context.Context, in *ListCamerasRequest, opts ...grpc.CallOption) (*ListCamerasResponse, error)
+	// InitializeCamera marks a camera as initialized, like
+	// PATCH /camera_metadata/{camID}/init.
+	InitializeCamera(ctx context.Context, in *InitializeCameraRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// UploadImage stores the image of a camera, like
+	// POST /camera_metadata/{camID}/upload_image. The first message holds the
+	// info, the following ones the chunks of the image.
+	UploadImage(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadImageRequest, UploadImageResponse], error)
+	// DownloadImage returns the image of a camera in chunks, like
+	// GET /camera_metadata/{camID}/download_image.
+	DownloadImage(ctx context.Context, in *DownloadImageRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ImageChunk], error)
+}
+
+type cameraServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCameraServiceClient(cc grpc.ClientConnInterface) CameraServiceClient {
+	return &cameraServiceClient{cc}
+}
+
+func (c *cameraServiceClient) CreateCamera(ctx context.Context, in *CreateCameraRequest, opts ...grpc.CallOption) (*Camera, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Camera)
+	err := c.cc.Invoke(ctx, CameraService_CreateCamera_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cameraServiceClient) GetCamera(ctx context.Context, in *GetCameraRequest, opts ...grpc.CallOption) (*Camera, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Camera)
+	err := c.cc.Invoke(ctx, CameraService_GetCamera_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cameraServiceClient) ListCameras(ctx context.Context, in *ListCamerasRequest, opts ...grpc.CallOption) (*ListCamerasResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListCamerasResponse)
+	err := c.cc.Invoke(ctx, CameraService_ListCameras_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cameraServiceClient) InitializeCamera(ctx context.Context, in *InitializeCameraRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, CameraService_InitializeCamera_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cameraServiceClient) UploadImage(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadImageRequest, UploadImageResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CameraService_ServiceDesc.Streams[0], CameraService_UploadImage_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[UploadImageRequest, UploadImageResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CameraService_UploadImageClient = grpc.ClientStreamingClient[UploadImageRequest, UploadImageResponse]
+
+func (c *cameraServiceClient) DownloadImage(ctx context.Context, in *DownloadImageRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ImageChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CameraService_ServiceDesc.Streams[1], CameraService_DownloadImage_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[DownloadImageRequest, ImageChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CameraService_DownloadImageClient = grpc.ServerStreamingClient[ImageChunk]
+
+// CameraServiceServer is the server API for CameraService service.
+// All implementations must embed UnimplementedCameraServiceServer
+// for forward compatibility.
+type CameraServiceServer interface {
+	// CreateCamera creates a camera owned by the user, like POST /camera_metadata.
+	CreateCamera(context.Context, *CreateCameraRequest) (*Camera, error)
+	// GetCamera returns a camera, like GET /camera_metadata/{camID}.
+	GetCamera(context.Context, *GetCameraRequest) (*Camera, error)
+	// ListCameras lists the cameras, oldest first, like GET /camera_metadata.
+	ListCameras(context.Context, *ListCamerasRequest) (*ListCamerasResponse, error)
+	// InitializeCamera marks a camera as initialized, like
+	// PATCH /camera_metadata/{camID}/init.
+	InitializeCamera(context.Context, *InitializeCameraRequest) (*emptypb.Empty, error)
+	// UploadImage stores the image of a camera, like
+	// POST /camera_metadata/{camID}/upload_image. The first message holds the
+	// info, the following ones the chunks of the image.
+	UploadImage(grpc.ClientStreamingServer[UploadImageRequest, UploadImageResponse]) error
+	// DownloadImage returns the image of a camera in chunks, like
+	// GET /camera_metadata/{camID}/download_image.
+	DownloadImage(*DownloadImageRequest, grpc.ServerStreamingServer[ImageChunk]) error
+	mustEmbedUnimplementedCameraServiceServer()
+}
+
+// UnimplementedCameraServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCameraServiceServer struct{}
+
+func (UnimplementedCameraServiceServer) CreateCamera(context.Context, *CreateCameraRequest) (*Camera, error) {
+	return nil, status.Error(codes.Unimplemented, "method CreateCamera not implemented")
+}
+func (UnimplementedCameraServiceServer) GetCamera(context.Context, *GetCameraRequest) (*Camera, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetCamera not implemented")
+}
+func (UnimplementedCameraServiceServer) ListCameras(context.Context, *ListCamerasRequest) (*ListCamerasResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListCameras not implemented")
+}
+func (UnimplementedCameraServiceServer) InitializeCamera(context.Context, *InitializeCameraRequest) (*emptypb.Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method InitializeCamera not implemented")
+}
+func (UnimplementedCameraServiceServer) UploadImage(grpc.ClientStreamingServer[UploadImageRequest, UploadImageResponse]) error {
+	return status.Error(codes.Unimplemented, "method UploadImage not implemented")
+}
+func (UnimplementedCameraServiceServer) DownloadImage(*DownloadImageRequest, grpc.ServerStreamingServer[ImageChunk]) error {
+	return status.Error(codes.Unimplemented, "method DownloadImage not implemented")
+}
+func (UnimplementedCameraServiceServer) mustEmbedUnimplementedCameraServiceServer() {}
+func (UnimplementedCameraServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafeCameraServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CameraServiceServer will
+// result in compilation errors.
+type UnsafeCameraServiceServer interface {
+	mustEmbedUnimplementedCameraServiceServer()
+}
+
+func RegisterCameraServiceServer(s grpc.ServiceRegistrar, srv CameraServiceServer) {
+	// If the following call panics, it indicates UnimplementedCameraServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CameraService_ServiceDesc, srv)
+}
+
+func _CameraService_CreateCamera_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateCameraRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CameraServiceServer).CreateCamera(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CameraService_CreateCamera_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CameraServiceServer).CreateCamera(ctx, req.(*CreateCameraRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CameraService_GetCamera_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCameraRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CameraServiceServer).GetCamera(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CameraService_GetCamera_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CameraServiceServer).GetCamera(ctx, req.(*GetCameraRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CameraService_ListCameras_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCamerasRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CameraServiceServer).ListCameras(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CameraService_ListCameras_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CameraServiceServer).ListCameras(ctx, req.(*ListCamerasRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CameraService_InitializeCamera_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InitializeCameraRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CameraServiceServer).InitializeCamera(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CameraService_InitializeCamera_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CameraServiceServer).InitializeCamera(ctx, req.(*InitializeCameraRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CameraService_UploadImage_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(CameraServiceServer).UploadImage(&grpc.GenericServerStream[UploadImageRequest, UploadImageResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CameraService_UploadImageServer = grpc.ClientStreamingServer[UploadImageRequest, UploadImageResponse]
+
+func _CameraService_DownloadImage_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadImageRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CameraServiceServer).DownloadImage(m, &grpc.GenericServerStream[DownloadImageRequest, ImageChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CameraService_DownloadImageServer = grpc.ServerStreamingServer[ImageChunk]
+
+// CameraService_ServiceDesc is the grpc.ServiceDesc for CameraService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CameraService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "api.v1.CameraService",
+	HandlerType: (*CameraServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateCamera",
+			Handler:    _CameraService_CreateCamera_Handler,
+		},
+		{
+			MethodName: "GetCamera",
+			Handler:    _CameraService_GetCamera_Handler,
+		},
+		{
+			MethodName: "ListCameras",
+			Handler:    _CameraService_ListCameras_Handler,
+		},
+		{
+			MethodName: "InitializeCamera",
+			Handler:    _CameraService_InitializeCamera_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "UploadImage",
+			Handler:       _CameraService_UploadImage_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "DownloadImage",
+			Handler:       _CameraService_DownloadImage_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "api/v1/api.proto",
+}
@@ -0,0 +1,208 @@
+package grpcapi
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"go-sample-rest-api/audit"
+	"go-sample-rest-api/grpcapi/apiv1"
+	auth2 "go-sample-rest-api/service/auth"
+	"go-sample-rest-api/service/camerametadata"
+	"go-sample-rest-api/types"
+	"go-sample-rest-api/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"io"
+)
+
+const (
+	// maxImageSize is the largest image UploadImage accepts.
+	maxImageSize = 32 << 20
+	// chunkSize is the size of the chunks DownloadImage sends.
+	chunkSize = 64 << 10
+)
+
+type cameraServer struct {
+	apiv1.UnimplementedCameraServiceServer
+	cameras *camerametadata.Handler
+}
+
+func (s *cameraServer) CreateCamera(ctx context.Context, req *apiv1.CreateCameraRequest) (*apiv1.Camera, error) {
+	payload := types.CameraMetadataPayload{CameraName: req.GetCameraName(), FirmwareVersion: req.GetFirmwareVersion()}
+	if err := utils.Validate.Struct(payload); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid payload: %v", err)
+	}
+
+	userID := auth2.GetUserIDFromContext(ctx)
+	camera, err := s.cameras.CreateCamera(ctx, auth2.GetOrgIDFromContext(ctx), sql.NullInt64{Int64: int64(userID), Valid: userID > 0},
+		payload, audit.FromContext(ctx, camerametadata.ActionCreate, clientIP(ctx)))
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	return cameraMessage(*camera), nil
+}
+
+func (s *cameraServer) GetCamera(ctx context.Context, req *apiv1.GetCameraRequest) (*apiv1.Camera, error) {
+	if err := checkUUID("cam_id", req.GetCamId()); err != nil {
+		return nil, err
+	}
+
+	camera, err := s.cameras.GetCamera(ctx, auth2.GetOrgIDFromContext(ctx), req.GetCamId())
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	return cameraMessage(*camera), nil
+}
+
+func (s *cameraServer) ListCameras(ctx context.Context, req *apiv1.ListCamerasRequest) (*apiv1.ListCamerasResponse, error) {
+	page, pageSize, err := pagination(req.GetPage(), req.GetPageSize())
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := camerametadata.ParseSelector(req.GetSelector())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	filter := types.CameraFilter{Selector: selector, Limit: pageSize, Offset: (page - 1) * pageSize}
+	if req.GroupId != nil {
+		groupID := int(req.GetGroupId())
+		filter.GroupID = &groupID
+	}
+
+	cameras, total, err := s.cameras.ListCameras(ctx, auth2.GetOrgIDFromContext(ctx), filter)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	response := &apiv1.ListCamerasResponse{Total: int32(total), Page: int32(page), PageSize: int32(pageSize)}
+	for _, c := range cameras {
+		response.Cameras = append(response.Cameras, cameraMessage(c))
+	}
+	return response, nil
+}
+
+func (s *cameraServer) InitializeCamera(ctx context.Context, req *apiv1.InitializeCameraRequest) (*emptypb.Empty, error) {
+	if err := checkUUID("cam_id", req.GetCamId()); err != nil {
+		return nil, err
+	}
+
+	err := s.cameras.InitializeCamera(ctx, auth2.GetOrgIDFromContext(ctx), req.GetCamId(),
+		audit.FromContext(ctx, camerametadata.ActionInitialize, clientIP(ctx)))
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *cameraServer) UploadImage(stream apiv1.CameraService_UploadImageServer) error {
+	ctx := stream.Context()
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	info := req.GetInfo()
+	if info == nil {
+		return status.Error(codes.InvalidArgument, "the first message must hold the info")
+	}
+	if err := checkUUID("cam_id", info.GetCamId()); err != nil {
+		return err
+	}
+	if err := checkUUID("image_id", info.GetImageId()); err != nil {
+		return err
+	}
+
+	var image bytes.Buffer
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if req.GetInfo() != nil {
+			return status.Error(codes.InvalidArgument, "only the first message may hold the info")
+		}
+		if image.Len()+len(req.GetChunk()) > maxImageSize {
+			return status.Errorf(codes.InvalidArgument, "the image is larger than %d bytes", maxImageSize)
+		}
+		image.Write(req.GetChunk())
+	}
+	if image.Len() == 0 {
+		return status.Error(codes.InvalidArgument, "the image is empty")
+	}
+
+	camera, err := s.cameras.UploadImage(ctx, auth2.GetOrgIDFromContext(ctx), info.GetCamId(), info.GetImageId(), image.Bytes(),
+		audit.FromContext(ctx, camerametadata.ActionUploadImage, clientIP(ctx)))
+	if err != nil {
+		return statusError(ctx, err)
+	}
+
+	return stream.SendAndClose(&apiv1.UploadImageResponse{
+		CamId:           camera.CamID,
+		CameraName:      camera.CameraName,
+		FirmwareVersion: camera.FirmwareVersion,
+		ImageId:         camera.ImageId.String,
+	})
+}
+
+func (s *cameraServer) DownloadImage(req *apiv1.DownloadImageRequest, stream apiv1.CameraService_DownloadImageServer) error {
+	if err := checkUUID("cam_id", req.GetCamId()); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	image, err := s.cameras.DownloadImage(ctx, auth2.GetOrgIDFromContext(ctx), req.GetCamId())
+	if err != nil {
+		return statusError(ctx, err)
+	}
+
+	for start := 0; start < len(image); start += chunkSize {
+		end := min(start+chunkSize, len(image))
+		if err := stream.Send(&apiv1.ImageChunk{Data: image[start:end]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkUUID(field, value string) error {
+	if _, err := uuid.Parse(value); err != nil {
+		return status.Error(codes.InvalidArgument, fmt.Sprintf("invalid %s: %v", field, err))
+	}
+	return nil
+}
+
+func cameraMessage(c types.CameraMetadata) *apiv1.Camera {
+	camera := &apiv1.Camera{
+		CamId:           c.CamID,
+		CameraName:      c.CameraName,
+		FirmwareVersion: c.FirmwareVersion,
+		CreatedAt:       timestamppb.New(c.CreatedAt.Time),
+		Tags:            c.Tags,
+	}
+	if c.GroupID.Valid {
+		camera.GroupId = &c.GroupID.Int64
+	}
+	camera.Latitude = optional(c.Latitude)
+	camera.Longitude = optional(c.Longitude)
+	camera.Heading = optional(c.Heading)
+	camera.FieldOfView = optional(c.FieldOfView)
+	return camera
+}
+
+func optional(f sql.NullFloat64) *float64 {
+	if !f.Valid {
+		return nil
+	}
+	return &f.Float64
+}
@@ -0,0 +1,107 @@
+package grpcapi
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/storage"
+	"go-sample-rest-api/types"
+)
+
+// mockUserStore only answers what authentication and the user service use.
+type mockUserStore struct {
+	mock.Mock
+	types.UserStore
+}
+
+func (m *mockUserStore) GetOrganizationUser(ctx context.Context, orgID, userID int) (*types.User, error) {
+	args := m.Called(ctx, orgID, userID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.User), args.Error(1)
+}
+
+func (m *mockUserStore) GetUserByID(ctx context.Context, id int) (*types.User, error) {
+	args := m.Called(ctx, id)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.User), args.Error(1)
+}
+
+func (m *mockUserStore) ListUsers(ctx context.Context, orgID int, query types.UserListQuery) ([]types.User, int, error) {
+	args := m.Called(ctx, orgID, query)
+	if args.Error(2) != nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]types.User), args.Int(1), args.Error(2)
+}
+
+func (m *mockUserStore) TouchSession(ctx context.Context, userID int, sessionID string) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+// mockCameraStore only answers what the camera operations use.
+type mockCameraStore struct {
+	mock.Mock
+	types.CameraMetadataStore
+}
+
+func (m *mockCameraStore) GetCameraMetadataByID(ctx context.Context, orgID int, camID string) (*types.CameraMetadata, error) {
+	args := m.Called(ctx, orgID, camID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.CameraMetadata), args.Error(1)
+}
+
+func (m *mockCameraStore) UpdateCameraMetadata(ctx context.Context, orgID int, camID string, change func(c *types.CameraMetadata) error, entry types.AuditEntry) (*types.CameraMetadata, error) {
+	args := m.Called(ctx, orgID, camID, entry)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+
+	// the returned camera is the locked row, which change modifies in place
+	camera := args.Get(0).(*types.CameraMetadata)
+	if err := change(camera); err != nil {
+		return nil, err
+	}
+
+	return camera, nil
+}
+
+func (m *mockCameraStore) ClaimImageID(ctx context.Context, imageID, camID string) (bool, error) {
+	args := m.Called(ctx, imageID, camID)
+	return args.Bool(0), args.Error(1)
+}
+
+type mockImageStore struct {
+	mock.Mock
+}
+
+func (m *mockImageStore) UploadImage(ctx context.Context, blobName string, imageData []byte) error {
+	args := m.Called(ctx, blobName, imageData)
+	return args.Error(0)
+}
+
+func (m *mockImageStore) DownloadImage(ctx context.Context, blobName string) ([]byte, error) {
+	args := m.Called(ctx, blobName)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func (m *mockImageStore) DeleteImage(ctx context.Context, blobName string) error {
+	args := m.Called(ctx, blobName)
+	return args.Error(0)
+}
+
+func (m *mockImageStore) ListImages(ctx context.Context) ([]storage.ImageInfo, error) {
+	args := m.Called(ctx)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]storage.ImageInfo), args.Error(1)
+}
@@ -0,0 +1,155 @@
+// Package grpcapi serves the user and camera operations over gRPC for
+// internal services. It shares the stores, the token authentication and the
+// camera operations with the REST handlers; only the translation from and to
+// protobuf lives here. The API is defined in proto/api/v1/api.proto and the
+// code in apiv1 is generated from it with make proto.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"go-sample-rest-api/customerrors"
+	"go-sample-rest-api/grpcapi/apiv1"
+	"go-sample-rest-api/logging"
+	auth2 "go-sample-rest-api/service/auth"
+	"go-sample-rest-api/service/camerametadata"
+	"go-sample-rest-api/types"
+	"go-sample-rest-api/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"strings"
+)
+
+// requestIDMetadata carries the ID of a call like the X-Request-ID header.
+const requestIDMetadata = "x-request-id"
+
+// NewServer returns a server with the user and camera services. Every call
+// is authenticated with the access token of its metadata.
+func NewServer(userStore types.UserStore, cameras *camerametadata.Handler) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			ctx, err := authenticate(ctx, userStore)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := authenticate(stream.Context(), userStore)
+			if err != nil {
+				return err
+			}
+			return handler(srv, &serverStream{ServerStream: stream, ctx: ctx})
+		}),
+	)
+	apiv1.RegisterUserServiceServer(server, &userServer{store: userStore})
+	apiv1.RegisterCameraServiceServer(server, &cameraServer{cameras: cameras})
+
+	return server
+}
+
+// serverStream replaces the context of a stream with the authenticated one.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// authenticate adds the request ID and the user of the access token to ctx,
+// like utils.WithRequestID and auth.WithJWTAuth do for HTTP.
+func authenticate(ctx context.Context, userStore types.UserStore) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx, requestID := utils.ContextWithRequestID(ctx, first(md.Get(requestIDMetadata)))
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadata, requestID))
+
+	token := strings.TrimPrefix(first(md.Get("authorization")), "Bearer ")
+	ctx, err := auth2.Authenticate(ctx, token, userStore)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return ctx, nil
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// requireAdmin is auth.WithAdmin for gRPC.
+func requireAdmin(ctx context.Context) error {
+	if auth2.GetUserRoleFromContext(ctx) != types.RoleAdmin {
+		return status.Error(codes.PermissionDenied, "permission denied")
+	}
+	return nil
+}
+
+// clientIP returns the address of the caller. Internal services connect
+// directly, so no proxy headers are considered.
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// statusError translates the errors of the stores and operations into the
+// status codes matching the ones of the REST API. Other errors are failures
+// of the service, whose details stay in the log.
+func statusError(ctx context.Context, err error) error {
+	var (
+		notFound     *customerrors.NotFoundError
+		notInit      *customerrors.NotInitError
+		alreadyInit  *customerrors.AlreadyInitError
+		quotaReached *customerrors.QuotaExceededError
+		alreadyTaken *customerrors.AlreadyExistsError
+	)
+	switch {
+	case errors.As(err, &notFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.As(err, &notInit):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.As(err, &alreadyInit), errors.As(err, &alreadyTaken):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.As(err, &quotaReached):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		logging.GetLogger().WithFields(logrus.Fields{
+			"requestID": utils.GetRequestID(ctx),
+			"error":     err,
+		}).Error("gRPC call failed")
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+// pagination applies the defaults and limits of utils.GetPagination.
+func pagination(page, pageSize int32) (int, int, error) {
+	if page < 0 || pageSize < 0 {
+		return 0, 0, status.Error(codes.InvalidArgument, "page and page_size must not be negative")
+	}
+	if page == 0 {
+		page = 1
+	}
+	if pageSize == 0 {
+		pageSize = utils.DefaultPageSize
+	}
+	if pageSize > utils.MaxPageSize {
+		pageSize = utils.MaxPageSize
+	}
+	return int(page), int(pageSize), nil
+}
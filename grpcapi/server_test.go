@@ -0,0 +1,270 @@
+package grpcapi
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/config"
+	"go-sample-rest-api/grpcapi/apiv1"
+	"go-sample-rest-api/service/auth"
+	"go-sample-rest-api/service/camerametadata"
+	"go-sample-rest-api/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+const (
+	orgID   = 3
+	userID  = 1
+	camID   = "7f9c24e8-3b12-4fef-91e0-3e5d3f2c1a00"
+	imageID = "0b3f6a2e-7c41-4d8e-9a55-2f1c6d9e8b10"
+)
+
+type testServer struct {
+	users   *mockUserStore
+	cameras *mockCameraStore
+	images  *mockImageStore
+	conn    *grpc.ClientConn
+}
+
+// startServer serves the API over an in-memory connection. The token of the
+// caller is accepted for a member with the role.
+func startServer(t *testing.T, role string) *testServer {
+	t.Helper()
+	s := &testServer{users: new(mockUserStore), cameras: new(mockCameraStore), images: new(mockImageStore)}
+	s.users.On("GetOrganizationUser", mock.Anything, orgID, userID).Return(&types.User{ID: userID, Role: role}, nil).Maybe()
+	s.users.On("TouchSession", mock.Anything, userID, "session-1").Return(nil).Maybe()
+
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(s.users, camerametadata.NewHandler(s.cameras, s.users, s.images, nil))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	s.conn = conn
+	return s
+}
+
+func withToken(t *testing.T) context.Context {
+	t.Helper()
+	token, err := auth.CreateJWT([]byte(config.Envs.JWTSecret), userID, orgID, "session-1")
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func initializedCamera() *types.CameraMetadata {
+	return &types.CameraMetadata{
+		CamID:         camID,
+		OrgID:         orgID,
+		CameraName:    "gate",
+		InitializedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	}
+}
+
+func TestServer_Authentication(t *testing.T) {
+	t.Run("rejects calls without a token", func(t *testing.T) {
+		// arrange
+		s := startServer(t, types.RoleUser)
+
+		// act
+		_, err := apiv1.NewUserServiceClient(s.conn).GetCurrentUser(context.Background(), &emptypb.Empty{})
+
+		// assert
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("returns the request ID", func(t *testing.T) {
+		// arrange
+		s := startServer(t, types.RoleUser)
+		s.users.On("GetUserByID", mock.Anything, userID).Return(&types.User{ID: userID, Email: "a@example.com"}, nil)
+		var header metadata.MD
+
+		// act
+		user, err := apiv1.NewUserServiceClient(s.conn).GetCurrentUser(
+			metadata.AppendToOutgoingContext(withToken(t), "x-request-id", "req-1"), &emptypb.Empty{}, grpc.Header(&header))
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, "a@example.com", user.GetEmail())
+		assert.Equal(t, types.RoleUser, user.GetRole())
+		assert.Equal(t, []string{"req-1"}, header.Get("x-request-id"))
+	})
+}
+
+func TestUserServer(t *testing.T) {
+	t.Run("GetUser is for admins only", func(t *testing.T) {
+		// arrange
+		s := startServer(t, types.RoleUser)
+
+		// act
+		_, err := apiv1.NewUserServiceClient(s.conn).GetUser(withToken(t), &apiv1.GetUserRequest{Id: 2})
+
+		// assert
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("ListUsers pages like the REST API", func(t *testing.T) {
+		// arrange
+		s := startServer(t, types.RoleAdmin)
+		s.users.On("ListUsers", mock.Anything, orgID, types.UserListQuery{Search: "ann", Limit: 100, Offset: 100}).
+			Return([]types.User{{ID: 2}}, 101, nil)
+
+		// act
+		response, err := apiv1.NewUserServiceClient(s.conn).ListUsers(withToken(t), &apiv1.ListUsersRequest{Search: " ann ", Page: 2, PageSize: 500})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Len(t, response.GetUsers(), 1)
+		assert.Equal(t, int32(101), response.GetTotal())
+		assert.Equal(t, int32(100), response.GetPageSize())
+		s.users.AssertExpectations(t)
+	})
+	t.Run("hides the cause of internal errors", func(t *testing.T) {
+		// arrange
+		s := startServer(t, types.RoleAdmin)
+		s.users.On("ListUsers", mock.Anything, orgID, mock.Anything).
+			Return(nil, 0, errors.New(`pq: relation "users" does not exist`))
+
+		// act
+		_, err := apiv1.NewUserServiceClient(s.conn).ListUsers(withToken(t), &apiv1.ListUsersRequest{})
+
+		// assert
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.Equal(t, "internal error", status.Convert(err).Message())
+	})
+}
+
+func TestCameraServer_UploadImage(t *testing.T) {
+	t.Run("stores the streamed image", func(t *testing.T) {
+		// arrange
+		s := startServer(t, types.RoleUser)
+		s.cameras.On("GetCameraMetadataByID", mock.Anything, orgID, camID).Return(initializedCamera(), nil)
+		s.cameras.On("ClaimImageID", mock.Anything, imageID, camID).Return(true, nil)
+		s.images.On("UploadImage", mock.Anything, imageID+".png", []byte("abcdef")).Return(nil)
+		var entry types.AuditEntry
+		saved := initializedCamera()
+		s.cameras.On("UpdateCameraMetadata", mock.Anything, orgID, camID, mock.Anything).
+			Run(func(args mock.Arguments) { entry = args.Get(3).(types.AuditEntry) }).
+			Return(saved, nil)
+
+		stream, err := apiv1.NewCameraServiceClient(s.conn).UploadImage(withToken(t))
+		assert.NoError(t, err)
+
+		// act
+		stream.Send(&apiv1.UploadImageRequest{Data: &apiv1.UploadImageRequest_Info{Info: &apiv1.ImageInfo{CamId: camID, ImageId: imageID}}})
+		stream.Send(&apiv1.UploadImageRequest{Data: &apiv1.UploadImageRequest_Chunk{Chunk: []byte("abc")}})
+		stream.Send(&apiv1.UploadImageRequest{Data: &apiv1.UploadImageRequest_Chunk{Chunk: []byte("def")}})
+		response, err := stream.CloseAndRecv()
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, imageID, response.GetImageId())
+		assert.Equal(t, imageID, saved.ImageId.String)
+		assert.Equal(t, int64(6), saved.ImageSizeBytes)
+		assert.Equal(t, camerametadata.ActionUploadImage, entry.Action)
+		assert.Equal(t, "1", entry.ActorID)
+		assert.NotEmpty(t, entry.RequestID)
+		s.cameras.AssertExpectations(t)
+		s.images.AssertExpectations(t)
+	})
+
+	t.Run("needs an initialized camera", func(t *testing.T) {
+		// arrange
+		s := startServer(t, types.RoleUser)
+		s.cameras.On("GetCameraMetadataByID", mock.Anything, orgID, camID).Return(&types.CameraMetadata{CamID: camID, OrgID: orgID}, nil)
+
+		stream, err := apiv1.NewCameraServiceClient(s.conn).UploadImage(withToken(t))
+		assert.NoError(t, err)
+
+		// act
+		stream.Send(&apiv1.UploadImageRequest{Data: &apiv1.UploadImageRequest_Info{Info: &apiv1.ImageInfo{CamId: camID, ImageId: imageID}}})
+		stream.Send(&apiv1.UploadImageRequest{Data: &apiv1.UploadImageRequest_Chunk{Chunk: []byte("abc")}})
+		_, err = stream.CloseAndRecv()
+
+		// assert
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		s.images.AssertNotCalled(t, "UploadImage", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("needs the info first", func(t *testing.T) {
+		// arrange
+		s := startServer(t, types.RoleUser)
+
+		stream, err := apiv1.NewCameraServiceClient(s.conn).UploadImage(withToken(t))
+		assert.NoError(t, err)
+
+		// act
+		stream.Send(&apiv1.UploadImageRequest{Data: &apiv1.UploadImageRequest_Chunk{Chunk: []byte("abc")}})
+		_, err = stream.CloseAndRecv()
+
+		// assert
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestCameraServer_DownloadImage(t *testing.T) {
+	t.Run("streams the image in chunks", func(t *testing.T) {
+		// arrange
+		s := startServer(t, types.RoleUser)
+		camera := initializedCamera()
+		camera.ImageId = sql.NullString{String: imageID, Valid: true}
+		image := bytes.Repeat([]byte{7}, chunkSize+10)
+		s.cameras.On("GetCameraMetadataByID", mock.Anything, orgID, camID).Return(camera, nil)
+		s.images.On("DownloadImage", mock.Anything, imageID+".png").Return(image, nil)
+
+		// act
+		stream, err := apiv1.NewCameraServiceClient(s.conn).DownloadImage(withToken(t), &apiv1.DownloadImageRequest{CamId: camID})
+		assert.NoError(t, err)
+		var received []byte
+		chunks := 0
+		for {
+			chunk, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			received = append(received, chunk.GetData()...)
+			chunks++
+		}
+
+		// assert
+		assert.Equal(t, image, received)
+		assert.Equal(t, 2, chunks)
+	})
+
+	t.Run("unknown camera", func(t *testing.T) {
+		// arrange
+		s := startServer(t, types.RoleUser)
+		s.cameras.On("GetCameraMetadataByID", mock.Anything, orgID, camID).Return(nil, errors.New("no rows"))
+
+		// act
+		stream, err := apiv1.NewCameraServiceClient(s.conn).DownloadImage(withToken(t), &apiv1.DownloadImageRequest{CamId: camID})
+		assert.NoError(t, err)
+		_, err = stream.Recv()
+
+		// assert
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
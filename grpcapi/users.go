@@ -0,0 +1,90 @@
+package grpcapi
+
+import (
+	"context"
+	"database/sql"
+	"go-sample-rest-api/grpcapi/apiv1"
+	auth2 "go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"strings"
+)
+
+type userServer struct {
+	apiv1.UnimplementedUserServiceServer
+	store types.UserStore
+}
+
+func (s *userServer) GetCurrentUser(ctx context.Context, _ *emptypb.Empty) (*apiv1.User, error) {
+	u, err := s.store.GetUserByID(ctx, auth2.GetUserIDFromContext(ctx))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+
+	// the role belongs to the membership in the organization of the token
+	u.Role = auth2.GetUserRoleFromContext(ctx)
+	return userMessage(*u), nil
+}
+
+func (s *userServer) GetUser(ctx context.Context, req *apiv1.GetUserRequest) (*apiv1.User, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	u, err := s.store.GetOrganizationUser(ctx, auth2.GetOrgIDFromContext(ctx), int(req.GetId()))
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	return userMessage(*u), nil
+}
+
+func (s *userServer) ListUsers(ctx context.Context, req *apiv1.ListUsersRequest) (*apiv1.ListUsersResponse, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	page, pageSize, err := pagination(req.GetPage(), req.GetPageSize())
+	if err != nil {
+		return nil, err
+	}
+
+	users, total, err := s.store.ListUsers(ctx, auth2.GetOrgIDFromContext(ctx), types.UserListQuery{
+		Search: strings.TrimSpace(req.GetSearch()),
+		Limit:  pageSize,
+		Offset: (page - 1) * pageSize,
+	})
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	response := &apiv1.ListUsersResponse{Total: int32(total), Page: int32(page), PageSize: int32(pageSize)}
+	for _, u := range users {
+		response.Users = append(response.Users, userMessage(u))
+	}
+	return response, nil
+}
+
+func userMessage(u types.User) *apiv1.User {
+	return &apiv1.User{
+		Id:              int64(u.ID),
+		FirstName:       u.FirstName,
+		LastName:        u.LastName,
+		Email:           u.Email,
+		Role:            u.Role,
+		CreatedAt:       timestamppb.New(u.CreatedAt),
+		EmailVerifiedAt: timestamp(u.EmailVerifiedAt),
+		TotpEnabledAt:   timestamp(u.TOTPEnabledAt),
+		DisabledAt:      timestamp(u.DisabledAt),
+	}
+}
+
+func timestamp(t sql.NullTime) *timestamppb.Timestamp {
+	if !t.Valid {
+		return nil
+	}
+	return timestamppb.New(t.Time)
+}
@@ -0,0 +1,36 @@
+package mailer
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go-sample-rest-api/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewMailer(t *testing.T) {
+	assert.IsType(t, &LogMailer{}, NewMailer(config.Config{}))
+	assert.IsType(t, &FileMailer{}, NewMailer(config.Config{MailerDriver: "file"}))
+	assert.IsType(t, &SMTPMailer{}, NewMailer(config.Config{MailerDriver: "smtp"}))
+}
+
+func TestFileMailer_Send(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m := NewFileMailer(dir, "noreply@example.com")
+
+	err := m.Send(context.Background(), Message{To: "jane@example.com", Subject: "Hello", Body: "line one\nline two"})
+	assert.Nil(t, err)
+
+	files, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+	assert.True(t, strings.HasSuffix(files[0].Name(), "-jane@example.com.eml"))
+
+	data, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "To: jane@example.com\r\n")
+	assert.Contains(t, string(data), "Subject: Hello\r\n")
+	assert.Contains(t, string(data), "line one\r\nline two")
+}
@@ -2,17 +2,10 @@ package metrics
 
 import (
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
-func init() {
-	// Register the default Go runtime metrics
-	prometheus.MustRegister(collectors.NewGoCollector())
-
-	// Register process metrics (CPU, memory usage, etc.)
-	prometheus.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
-}
+// The Go runtime and process metrics are registered by the default registry.
 
 var (
 	TotalRequests = promauto.NewCounter(prometheus.CounterOpts{
@@ -25,4 +18,9 @@ var (
 		Help:    "Histogram of response times for the API.",
 		Buckets: prometheus.LinearBuckets(0.01, 0.05, 20),
 	})
+
+	QuotaExceeded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "api_quota_exceeded_total",
+		Help: "Number of requests refused because an organization reached its quota.",
+	}, []string{"resource"})
 )
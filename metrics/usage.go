@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"go-sample-rest-api/logging"
+	"go-sample-rest-api/types"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// UsageSource lists the usage of every organization.
+type UsageSource interface {
+	ListUsage(ctx context.Context) (map[int]types.Usage, error)
+}
+
+var (
+	camerasDesc = prometheus.NewDesc("api_organization_cameras",
+		"Number of cameras of an organization.", []string{"organization"}, nil)
+	storageBytesDesc = prometheus.NewDesc("api_organization_storage_bytes",
+		"Bytes of camera images stored by an organization.", []string{"organization"}, nil)
+)
+
+// usageTimeout bounds the query of the usage of all organizations.
+const usageTimeout = 10 * time.Second
+
+// usageCollector reads the usage from the database, so the metrics stay right
+// when cameras are deleted or erased. The usage of all organizations is one
+// query over every camera, so a result is reused for maxAge.
+type usageCollector struct {
+	source UsageSource
+	maxAge time.Duration
+
+	mu       sync.Mutex
+	usage    map[int]types.Usage
+	loadedAt time.Time
+}
+
+func NewUsageCollector(source UsageSource, maxAge time.Duration) prometheus.Collector {
+	return &usageCollector{source: source, maxAge: maxAge}
+}
+
+func (c *usageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- camerasDesc
+	ch <- storageBytesDesc
+}
+
+func (c *usageCollector) Collect(ch chan<- prometheus.Metric) {
+	usage, err := c.load()
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Failed to collect usage metrics")
+		return
+	}
+
+	for orgID, u := range usage {
+		org := strconv.Itoa(orgID)
+		ch <- prometheus.MustNewConstMetric(camerasDesc, prometheus.GaugeValue, float64(u.Cameras), org)
+		ch <- prometheus.MustNewConstMetric(storageBytesDesc, prometheus.GaugeValue, float64(u.StorageBytes), org)
+	}
+}
+
+// load returns the usage read within maxAge, or reads it again.
+func (c *usageCollector) load() (map[int]types.Usage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.usage != nil && time.Since(c.loadedAt) < c.maxAge {
+		return c.usage, nil
+	}
+
+	// a scrape has no context; the timeout keeps a slow query from piling up
+	ctx, cancel := context.WithTimeout(context.Background(), usageTimeout)
+	defer cancel()
+
+	usage, err := c.source.ListUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.usage, c.loadedAt = usage, time.Now()
+	return usage, nil
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go-sample-rest-api/types"
+	"strings"
+	"testing"
+	"time"
+)
+
+type usageSourceFunc func() (map[int]types.Usage, error)
+
+func (f usageSourceFunc) ListUsage(ctx context.Context) (map[int]types.Usage, error) {
+	return f()
+}
+
+func TestUsageCollector(t *testing.T) {
+	t.Run("exports the usage of every organization", func(t *testing.T) {
+		// arrange
+		collector := NewUsageCollector(usageSourceFunc(func() (map[int]types.Usage, error) {
+			return map[int]types.Usage{
+				1: {Cameras: 2, StorageBytes: 2048},
+				5: {Cameras: 1, StorageBytes: 0},
+			}, nil
+		}), 0)
+
+		expected := `
+# HELP api_organization_cameras Number of cameras of an organization.
+# TYPE api_organization_cameras gauge
+api_organization_cameras{organization="1"} 2
+api_organization_cameras{organization="5"} 1
+# HELP api_organization_storage_bytes Bytes of camera images stored by an organization.
+# TYPE api_organization_storage_bytes gauge
+api_organization_storage_bytes{organization="1"} 2048
+api_organization_storage_bytes{organization="5"} 0
+`
+
+		// act
+		err := testutil.CollectAndCompare(collector, strings.NewReader(expected))
+
+		// assert
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("reuses the usage for maxAge", func(t *testing.T) {
+		// arrange
+		queries := 0
+		collector := NewUsageCollector(usageSourceFunc(func() (map[int]types.Usage, error) {
+			queries++
+			return map[int]types.Usage{1: {Cameras: int64(queries)}}, nil
+		}), time.Hour)
+
+		// act
+		testutil.CollectAndCount(collector)
+		count := testutil.CollectAndCount(collector)
+
+		// assert
+		if queries != 1 {
+			t.Errorf("expected one query, got %d", queries)
+		}
+		if count != 2 {
+			t.Errorf("expected the metrics of one organization, got %d", count)
+		}
+	})
+
+	t.Run("exports nothing when the database fails", func(t *testing.T) {
+		// arrange
+		collector := NewUsageCollector(usageSourceFunc(func() (map[int]types.Usage, error) {
+			return nil, fmt.Errorf("connection refused")
+		}), 0)
+
+		// act
+		count := testutil.CollectAndCount(collector)
+
+		// assert
+		if count != 0 {
+			t.Errorf("expected no metrics, got %d", count)
+		}
+	})
+}
@@ -0,0 +1,23 @@
+// Package outbox records domain events in the outbox_events table. Stores
+// write an event with the same transaction as the change, so exactly the
+// committed changes are published. The webhook dispatcher publishes them
+// afterwards.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"go-sample-rest-api/db"
+)
+
+// Write appends an event of the organization with data as its payload. Call
+// it with the transaction of the change.
+func Write(ctx context.Context, tx db.DB, orgID int, eventType string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO outbox_events (org_id, type, data) VALUES ($1, $2, $3)", orgID, eventType, payload)
+	return err
+}
@@ -0,0 +1,31 @@
+package outbox
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	db2 "go-sample-rest-api/db"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	t.Run("Write_insertsEventWithJSONData", func(t *testing.T) {
+		// arrange
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		mock.ExpectExec(`^INSERT INTO outbox_events \(org_id, type, data\) VALUES \(\$1, \$2, \$3\)$`).
+			WithArgs(3, "camera.created", []byte(`{"cam_id":"cam-1"}`)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		// act
+		err = Write(context.Background(), db2.NewSQLDB(db), 3, "camera.created", map[string]string{"cam_id": "cam-1"})
+
+		// assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,165 @@
+// Package reconcile finds the drift between cameras and the image blobs they
+// point at: cameras pointing at a blob that does not exist, and blobs no
+// camera points at. Both can be left behind when the database or the blob
+// storage fails halfway through a change.
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go-sample-rest-api/customerrors"
+	"go-sample-rest-api/logging"
+	"go-sample-rest-api/storage"
+	"go-sample-rest-api/types"
+	"strings"
+	"time"
+)
+
+// ActionClearImage is the audit action of a cleared dangling image.
+const ActionClearImage = "camera.clear_image"
+
+// imageExtension is appended to the image ID to get the name of its blob.
+const imageExtension = ".png"
+
+// CameraImages lists and clears the images of the cameras of all organizations.
+type CameraImages interface {
+	ListImageReferences(ctx context.Context) ([]types.ImageReference, error)
+	ClearImage(ctx context.Context, ref types.ImageReference, entry types.AuditEntry) error
+}
+
+// Options choose the repairs. Without any, Run only reports the drift.
+type Options struct {
+	DeleteOrphans bool
+	ClearDangling bool
+	// DryRun reports the chosen repairs without making them.
+	DryRun bool
+	// MinOrphanAge ignores younger blobs. An upload writes the blob before
+	// the camera points at it, so a fresh blob may not be referenced yet.
+	MinOrphanAge time.Duration
+}
+
+// Report is the drift Run found and what it repaired.
+type Report struct {
+	// Dangling are the cameras pointing at a blob that does not exist.
+	Dangling []types.ImageReference
+	// Orphans are the blobs no camera points at.
+	Orphans []storage.ImageInfo
+	// Unknown are the blobs not named like an image. They are never deleted,
+	// since the container may be shared with other data.
+	Unknown []storage.ImageInfo
+	// Recent counts the unreferenced blobs younger than MinOrphanAge.
+	Recent  int
+	Cleared int
+	Deleted int
+	Failed  int
+}
+
+// Run compares the cameras with the blobs and repairs the drift as chosen by
+// opts. A repair that fails is logged and counted, and Run goes on with the
+// next one.
+func Run(ctx context.Context, cameras CameraImages, images storage.ImageStore, opts Options) (*Report, error) {
+	// The cameras are listed first. An upload that completes in between
+	// writes its blob before the camera points at it, so it cannot show up
+	// as a dangling reference.
+	refs, err := cameras.ListImageReferences(ctx)
+	if err != nil {
+		return nil, err
+	}
+	blobs, err := images.ListImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := new(Report)
+
+	stored := make(map[string]bool, len(blobs))
+	for _, blob := range blobs {
+		stored[blob.Name] = true
+	}
+	referenced := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		referenced[ref.ImageID+imageExtension] = true
+		if !stored[ref.ImageID+imageExtension] {
+			report.Dangling = append(report.Dangling, ref)
+		}
+	}
+
+	cutoff := time.Now().Add(-opts.MinOrphanAge)
+	for _, blob := range blobs {
+		if referenced[blob.Name] {
+			continue
+		}
+		if !isImageBlob(blob.Name) {
+			report.Unknown = append(report.Unknown, blob)
+			continue
+		}
+		if blob.LastModified.After(cutoff) {
+			report.Recent++
+			continue
+		}
+		report.Orphans = append(report.Orphans, blob)
+	}
+
+	if opts.DryRun {
+		return report, nil
+	}
+	if opts.ClearDangling {
+		clearDangling(ctx, cameras, report)
+	}
+	if opts.DeleteOrphans {
+		deleteOrphans(ctx, images, report)
+	}
+
+	return report, nil
+}
+
+// isImageBlob reports whether the name is an image ID followed by
+// imageExtension, as written by uploads.
+func isImageBlob(name string) bool {
+	imageID, ok := strings.CutSuffix(name, imageExtension)
+	if !ok {
+		return false
+	}
+	_, err := uuid.Parse(imageID)
+	return err == nil
+}
+
+func clearDangling(ctx context.Context, cameras CameraImages, report *Report) {
+	log := logging.GetLogger()
+	for _, ref := range report.Dangling {
+		entry := types.AuditEntry{ActorType: types.AuditActorSystem, ActorID: "reconcile", Action: ActionClearImage}
+		err := cameras.ClearImage(ctx, ref, entry)
+
+		// the camera was deleted or got a new image since it was listed
+		var notFound *customerrors.NotFoundError
+		if errors.As(err, &notFound) {
+			continue
+		}
+		if err != nil {
+			report.Failed++
+			log.WithFields(logrus.Fields{
+				"image": ref,
+				"error": err,
+			}).Error("Failed to clear dangling image")
+			continue
+		}
+		report.Cleared++
+	}
+}
+
+func deleteOrphans(ctx context.Context, images storage.ImageStore, report *Report) {
+	log := logging.GetLogger()
+	for _, blob := range report.Orphans {
+		if err := images.DeleteImage(ctx, blob.Name); err != nil {
+			report.Failed++
+			log.WithFields(logrus.Fields{
+				"blob":  blob.Name,
+				"error": err,
+			}).Error("Failed to delete orphan image")
+			continue
+		}
+		report.Deleted++
+	}
+}
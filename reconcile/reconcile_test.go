@@ -0,0 +1,173 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/customerrors"
+	"go-sample-rest-api/storage"
+	"go-sample-rest-api/types"
+	"testing"
+	"time"
+)
+
+type mockCameraImages struct {
+	mock.Mock
+}
+
+func (m *mockCameraImages) ListImageReferences(ctx context.Context) ([]types.ImageReference, error) {
+	args := m.Called(ctx)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.ImageReference), args.Error(1)
+}
+
+func (m *mockCameraImages) ClearImage(ctx context.Context, ref types.ImageReference, entry types.AuditEntry) error {
+	args := m.Called(ctx, ref, entry)
+	return args.Error(0)
+}
+
+type mockImageStore struct {
+	mock.Mock
+	storage.ImageStore
+}
+
+func (m *mockImageStore) ListImages(ctx context.Context) ([]storage.ImageInfo, error) {
+	args := m.Called(ctx)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]storage.ImageInfo), args.Error(1)
+}
+
+func (m *mockImageStore) DeleteImage(ctx context.Context, blobName string) error {
+	args := m.Called(ctx, blobName)
+	return args.Error(0)
+}
+
+const (
+	storedID    = "5f0c6c3e-8a43-4d6b-9a51-4b8f7d2e1a01"
+	missingID   = "5f0c6c3e-8a43-4d6b-9a51-4b8f7d2e1a02"
+	orphanBlob  = "5f0c6c3e-8a43-4d6b-9a51-4b8f7d2e1a03.png"
+	recentBlob  = "5f0c6c3e-8a43-4d6b-9a51-4b8f7d2e1a04.png"
+	unknownBlob = "backups/dump.sql"
+)
+
+// setup returns a camera pointing at a stored image, a camera pointing at a
+// missing one, an orphan blob, a blob uploaded a moment ago and a blob that
+// is not an image.
+func setup() (*mockCameraImages, *mockImageStore, types.ImageReference) {
+	dangling := types.ImageReference{OrgID: 3, CamID: "cam-2", ImageID: missingID}
+	cameras := new(mockCameraImages)
+	cameras.On("ListImageReferences", mock.Anything).Return([]types.ImageReference{
+		{OrgID: 1, CamID: "cam-1", ImageID: storedID},
+		dangling,
+	}, nil)
+
+	old := time.Now().Add(-2 * time.Hour)
+	images := new(mockImageStore)
+	images.On("ListImages", mock.Anything).Return([]storage.ImageInfo{
+		{Name: storedID + ".png", LastModified: old},
+		{Name: orphanBlob, SizeBytes: 10, LastModified: old},
+		{Name: recentBlob, LastModified: time.Now()},
+		{Name: unknownBlob, LastModified: old},
+		{Name: "not-a-uuid.png", LastModified: old},
+	}, nil)
+
+	return cameras, images, dangling
+}
+
+func TestRun(t *testing.T) {
+	t.Run("Run_withoutRepairs_onlyReports", func(t *testing.T) {
+		// arrange
+		cameras, images, dangling := setup()
+
+		// act
+		report, err := Run(context.Background(), cameras, images, Options{MinOrphanAge: time.Hour})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, []types.ImageReference{dangling}, report.Dangling)
+		assert.Len(t, report.Orphans, 1)
+		assert.Equal(t, orphanBlob, report.Orphans[0].Name)
+		assert.Len(t, report.Unknown, 2)
+		assert.Equal(t, 1, report.Recent)
+		cameras.AssertNotCalled(t, "ClearImage", mock.Anything, mock.Anything, mock.Anything)
+		images.AssertNotCalled(t, "DeleteImage", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Run_withDryRun_makesNoRepairs", func(t *testing.T) {
+		// arrange
+		cameras, images, _ := setup()
+
+		// act
+		report, err := Run(context.Background(), cameras, images, Options{DeleteOrphans: true, ClearDangling: true, DryRun: true, MinOrphanAge: time.Hour})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Len(t, report.Dangling, 1)
+		assert.Len(t, report.Orphans, 1)
+		assert.Zero(t, report.Cleared)
+		assert.Zero(t, report.Deleted)
+		cameras.AssertNotCalled(t, "ClearImage", mock.Anything, mock.Anything, mock.Anything)
+		images.AssertNotCalled(t, "DeleteImage", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Run_withRepairs_clearsDanglingAndDeletesOrphans", func(t *testing.T) {
+		// arrange
+		cameras, images, dangling := setup()
+		cameras.On("ClearImage", mock.Anything, dangling, types.AuditEntry{
+			ActorType: types.AuditActorSystem,
+			ActorID:   "reconcile",
+			Action:    ActionClearImage,
+		}).Return(nil)
+		images.On("DeleteImage", mock.Anything, orphanBlob).Return(nil)
+
+		// act
+		report, err := Run(context.Background(), cameras, images, Options{DeleteOrphans: true, ClearDangling: true, MinOrphanAge: time.Hour})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Cleared)
+		assert.Equal(t, 1, report.Deleted)
+		assert.Zero(t, report.Failed)
+		cameras.AssertExpectations(t)
+		images.AssertExpectations(t)
+		images.AssertNotCalled(t, "DeleteImage", mock.Anything, recentBlob)
+		images.AssertNotCalled(t, "DeleteImage", mock.Anything, unknownBlob)
+		images.AssertNotCalled(t, "DeleteImage", mock.Anything, "not-a-uuid.png")
+	})
+
+	t.Run("Run_withFailingRepairs_countsFailuresAndSkipsChangedCameras", func(t *testing.T) {
+		// arrange
+		cameras, images, dangling := setup()
+		cameras.On("ClearImage", mock.Anything, dangling, mock.Anything).Return(&customerrors.NotFoundError{ID: dangling.CamID})
+		images.On("DeleteImage", mock.Anything, orphanBlob).Return(fmt.Errorf("delete err"))
+
+		// act
+		report, err := Run(context.Background(), cameras, images, Options{DeleteOrphans: true, ClearDangling: true, MinOrphanAge: time.Hour})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Zero(t, report.Cleared)
+		assert.Zero(t, report.Deleted)
+		assert.Equal(t, 1, report.Failed)
+	})
+
+	t.Run("Run_withListError_returnsError", func(t *testing.T) {
+		// arrange
+		cameras := new(mockCameraImages)
+		cameras.On("ListImageReferences", mock.Anything).Return(nil, fmt.Errorf("db err"))
+		images := new(mockImageStore)
+
+		// act
+		report, err := Run(context.Background(), cameras, images, Options{})
+
+		// assert
+		assert.Error(t, err)
+		assert.Nil(t, report)
+		images.AssertNotCalled(t, "ListImages", mock.Anything)
+	})
+}
@@ -0,0 +1,35 @@
+package auditlog
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/types"
+)
+
+type mockAuditLogStore struct {
+	mock.Mock
+}
+
+func (m *mockAuditLogStore) ListAuditEntries(ctx context.Context, orgID int, filter types.AuditFilter) ([]types.AuditEntry, int, error) {
+	args := m.Called(ctx, orgID, filter)
+	if args.Error(2) != nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]types.AuditEntry), args.Int(1), args.Error(2)
+}
+
+func (m *mockAuditLogStore) ListResourceChanges(ctx context.Context, orgID int, resourceType string, resourceIDs []string, field string) ([]types.AuditEntry, error) {
+	args := m.Called(ctx, orgID, resourceType, resourceIDs, field)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.AuditEntry), args.Error(1)
+}
+
+func (m *mockAuditLogStore) ListActorEntries(ctx context.Context, orgID int, actorType, actorID string) ([]types.AuditEntry, error) {
+	args := m.Called(ctx, orgID, actorType, actorID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.AuditEntry), args.Error(1)
+}
@@ -0,0 +1,101 @@
+package auditlog
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	auth2 "go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"go-sample-rest-api/utils"
+	"net/http"
+	"time"
+)
+
+// Handler serves the audit log of the organization of the access token to
+// its admins.
+type Handler struct {
+	store     types.AuditLogStore
+	userStore types.UserStore
+}
+
+func NewHandler(store types.AuditLogStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, userStore: userStore}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/audit_log", auth2.WithAdmin(h.handleListEntries, h.userStore)).Methods(http.MethodGet)
+}
+
+// handleListEntries godoc
+// @Summary List audit log entries
+// @Description Lists the recorded changes of the organization, newest first. Admins only.
+// @Tags audit log
+// @Produce json
+// @Param actor_type query string false "user, device or system"
+// @Param actor_id query string false "ID of the actor"
+// @Param action query string false "Action, e.g. camera.create"
+// @Param resource_type query string false "Resource type, e.g. camera"
+// @Param resource_id query string false "ID of the resource"
+// @Param request_id query string false "ID of the request that made the change"
+// @Param from query string false "Earliest time, inclusive (RFC 3339)"
+// @Param to query string false "Latest time, exclusive (RFC 3339)"
+// @Param page query int false "Page number, starting at 1"
+// @Param pageSize query int false "Entries per page (default 20, max 100)"
+// @Success 200 {object} types.AuditEntryList "One page of entries."
+// @Failure 400 {object} types.HTTPError "Bad Request if a parameter is invalid."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 403 {object} types.HTTPError "Forbidden if the user is not an admin."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /audit_log [get]
+func (h *Handler) handleListEntries(w http.ResponseWriter, r *http.Request) {
+	page, pageSize, err := utils.GetPagination(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := types.AuditFilter{
+		ActorType:    query.Get("actor_type"),
+		ActorID:      query.Get("actor_id"),
+		Action:       query.Get("action"),
+		ResourceType: query.Get("resource_type"),
+		ResourceID:   query.Get("resource_id"),
+		RequestID:    query.Get("request_id"),
+		Limit:        pageSize,
+		Offset:       (page - 1) * pageSize,
+	}
+	if filter.From, err = parseTime(query.Get("from")); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid from: %v", err))
+		return
+	}
+	if filter.To, err = parseTime(query.Get("to")); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid to: %v", err))
+		return
+	}
+
+	entries, total, err := h.store.ListAuditEntries(r.Context(), auth2.GetOrgIDFromContext(r.Context()), filter)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.AuditEntryList{
+		Entries:  entries,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	})
+}
+
+// parseTime parses an optional RFC 3339 time.
+func parseTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,72 @@
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func withOrganization(req *http.Request, orgID int) *http.Request {
+	ctx := context.WithValue(req.Context(), auth.UserKey, 1)
+	return req.WithContext(context.WithValue(ctx, auth.OrgKey, orgID))
+}
+
+func TestAuditLogService_Handle_ListEntries(t *testing.T) {
+	t.Run("passes the filters and pages", func(t *testing.T) {
+		// arrange
+		store := new(mockAuditLogStore)
+		handler := NewHandler(store, nil)
+
+		from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+		store.On("ListAuditEntries", mock.Anything, 3, types.AuditFilter{
+			ActorType:    "user",
+			ActorID:      "7",
+			ResourceType: "camera",
+			From:         &from,
+			Limit:        10,
+			Offset:       10,
+		}).Return([]types.AuditEntry{{ID: 42, Action: "camera.create"}}, 11, nil)
+
+		req, _ := http.NewRequest(http.MethodGet,
+			"/audit_log?actor_type=user&actor_id=7&resource_type=camera&from=2026-10-01T00:00:00Z&page=2&pageSize=10", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleListEntries(rr, withOrganization(req, 3))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		var list types.AuditEntryList
+		json.Unmarshal(rr.Body.Bytes(), &list)
+		if list.Total != 11 || list.Page != 2 || len(list.Entries) != 1 || list.Entries[0].ID != 42 {
+			t.Errorf("unexpected entries: %+v", list)
+		}
+		store.AssertExpectations(t)
+	})
+
+	t.Run("invalid time", func(t *testing.T) {
+		// arrange
+		store := new(mockAuditLogStore)
+		handler := NewHandler(store, nil)
+
+		req, _ := http.NewRequest(http.MethodGet, "/audit_log?to=yesterday", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleListEntries(rr, withOrganization(req, 3))
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+		store.AssertNotCalled(t, "ListAuditEntries", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,151 @@
+package auditlog
+
+import (
+	"context"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+	"go-sample-rest-api/db"
+	"go-sample-rest-api/logging"
+	"go-sample-rest-api/types"
+	"strings"
+)
+
+const entryColumns = "id, org_id, actor_type, actor_id, action, resource_type, resource_id, changes, request_id, ip, created_at"
+
+// Store reads the audit log. Entries are written by the stores of the
+// resources they record, see package audit.
+type Store struct {
+	db db.DB
+}
+
+func NewStore(db db.DB) *Store {
+	return &Store{db: db}
+}
+
+// ListAuditEntries returns the entries of the organization matching the
+// filter, newest first, and the total number of matches.
+func (s *Store) ListAuditEntries(ctx context.Context, orgID int, filter types.AuditFilter) ([]types.AuditEntry, int, error) {
+	where, args := auditFilter(orgID, filter)
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM audit_log WHERE "+where, args...).Scan(&total); err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"orgID": orgID,
+			"error": err,
+		}).Error("Error counting audit entries")
+		return nil, 0, err
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM audit_log WHERE %s ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d",
+		entryColumns, where, len(args)-1, len(args)), args...)
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"orgID": orgID,
+			"error": err,
+		}).Error("Error listing audit entries")
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := make([]types.AuditEntry, 0)
+	for rows.Next() {
+		var e types.AuditEntry
+		if err := rows.Scan(&e.ID, &e.OrgID, &e.ActorType, &e.ActorID, &e.Action, &e.ResourceType, &e.ResourceID,
+			&e.Changes, &e.RequestID, &e.IP, &e.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, total, rows.Err()
+}
+
+// ListResourceChanges returns the entries of the resources that changed the
+// field, oldest first.
+func (s *Store) ListResourceChanges(ctx context.Context, orgID int, resourceType string, resourceIDs []string, field string) ([]types.AuditEntry, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+entryColumns+" FROM audit_log WHERE org_id = $1 AND resource_type = $2 AND resource_id = ANY($3) AND changes ? $4 ORDER BY created_at, id",
+		orgID, resourceType, pq.Array(resourceIDs), field)
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"orgID":        orgID,
+			"resourceType": resourceType,
+			"field":        field,
+			"error":        err,
+		}).Error("Error listing resource changes")
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]types.AuditEntry, 0)
+	for rows.Next() {
+		var e types.AuditEntry
+		if err := rows.Scan(&e.ID, &e.OrgID, &e.ActorType, &e.ActorID, &e.Action, &e.ResourceType, &e.ResourceID,
+			&e.Changes, &e.RequestID, &e.IP, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// ListActorEntries returns the entries of the organization recording what
+// the actor did, oldest first.
+func (s *Store) ListActorEntries(ctx context.Context, orgID int, actorType, actorID string) ([]types.AuditEntry, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+entryColumns+" FROM audit_log WHERE org_id = $1 AND actor_type = $2 AND actor_id = $3 ORDER BY created_at, id",
+		orgID, actorType, actorID)
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"orgID":     orgID,
+			"actorType": actorType,
+			"actorID":   actorID,
+			"error":     err,
+		}).Error("Error listing actor entries")
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]types.AuditEntry, 0)
+	for rows.Next() {
+		var e types.AuditEntry
+		if err := rows.Scan(&e.ID, &e.OrgID, &e.ActorType, &e.ActorID, &e.Action, &e.ResourceType, &e.ResourceID,
+			&e.Changes, &e.RequestID, &e.IP, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// auditFilter returns the WHERE clause and its arguments for the filter.
+func auditFilter(orgID int, filter types.AuditFilter) (string, []any) {
+	conditions := []string{"org_id = $1"}
+	args := []any{orgID}
+
+	equal := func(column, value string) {
+		if value != "" {
+			args = append(args, value)
+			conditions = append(conditions, fmt.Sprintf("%s = $%d", column, len(args)))
+		}
+	}
+	equal("actor_type", filter.ActorType)
+	equal("actor_id", filter.ActorID)
+	equal("action", filter.Action)
+	equal("resource_type", filter.ResourceType)
+	equal("resource_id", filter.ResourceID)
+	equal("request_id", filter.RequestID)
+
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
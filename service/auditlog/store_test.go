@@ -0,0 +1,106 @@
+package auditlog
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	db2 "go-sample-rest-api/db"
+	"go-sample-rest-api/types"
+	"testing"
+	"time"
+)
+
+func setupMockDB(t *testing.T) (*db2.SQLDB, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	return db2.NewSQLDB(db), mock, func() { db.Close() }
+}
+
+func TestStore_ListAuditEntries(t *testing.T) {
+	t.Run("ListAuditEntries_withFilter_returnsNewestFirst", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+		to := from.AddDate(0, 1, 0)
+		where := `org_id = \$1 AND resource_type = \$2 AND resource_id = \$3 AND created_at >= \$4 AND created_at < \$5`
+
+		mock.ExpectQuery(`^SELECT COUNT\(\*\) FROM audit_log WHERE `+where+`$`).
+			WithArgs(3, "camera", "cam-1", from, to).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(`^SELECT .* FROM audit_log WHERE `+where+` ORDER BY created_at DESC, id DESC LIMIT \$6 OFFSET \$7$`).
+			WithArgs(3, "camera", "cam-1", from, to, 20, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "org_id", "actor_type", "actor_id", "action", "resource_type", "resource_id", "changes", "request_id", "ip", "created_at"}).
+				AddRow(42, 3, "user", "7", "camera.set_tags", "camera", "cam-1",
+					[]byte(`{"tags":{"before":{},"after":{"site":"berlin"}}}`), "req-1", "203.0.113.7", from))
+
+		// act
+		entries, total, err := store.ListAuditEntries(context.Background(), 3, types.AuditFilter{
+			ResourceType: "camera",
+			ResourceID:   "cam-1",
+			From:         &from,
+			To:           &to,
+			Limit:        20,
+		})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 1, total)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, map[string]any{"site": "berlin"}, entries[0].Changes["tags"].After)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStore_ListResourceChanges(t *testing.T) {
+	// arrange
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	store := NewStore(db)
+	createdAt := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`^SELECT .* FROM audit_log WHERE org_id = \$1 AND resource_type = \$2 AND resource_id = ANY\(\$3\) AND changes \? \$4 ORDER BY created_at, id$`).
+		WithArgs(3, "camera", `{"cam-1","cam-2"}`, "firmware_version").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "org_id", "actor_type", "actor_id", "action", "resource_type", "resource_id", "changes", "request_id", "ip", "created_at"}).
+			AddRow(40, 3, "user", "7", "camera.create", "camera", "cam-2",
+				[]byte(`{"firmware_version":{"before":null,"after":"1.0.0"}}`), "req-1", "203.0.113.7", createdAt))
+
+	// act
+	entries, err := store.ListResourceChanges(context.Background(), 3, "camera", []string{"cam-1", "cam-2"}, "firmware_version")
+
+	// assert
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "cam-2", entries[0].ResourceID)
+	assert.Equal(t, "1.0.0", entries[0].Changes["firmware_version"].After)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStore_ListActorEntries(t *testing.T) {
+	// arrange
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	store := NewStore(db)
+	createdAt := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`^SELECT .* FROM audit_log WHERE org_id = \$1 AND actor_type = \$2 AND actor_id = \$3 ORDER BY created_at, id$`).
+		WithArgs(3, "user", "7").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "org_id", "actor_type", "actor_id", "action", "resource_type", "resource_id", "changes", "request_id", "ip", "created_at"}).
+			AddRow(40, 3, "user", "7", "camera.create", "camera", "cam-2", []byte(`{}`), "req-1", "203.0.113.7", createdAt))
+
+	// act
+	entries, err := store.ListActorEntries(context.Background(), 3, "user", "7")
+
+	// assert
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "camera.create", entries[0].Action)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,9 +1,12 @@
 package auth
 
 type Authenticator interface {
-	CreateJWT(secret []byte, userID int) (string, error)
+	CreateJWT(secret []byte, userID, orgID int, sessionID string) (string, error)
+	CreateMFAChallenge(secret []byte, userID int) (string, error)
+	ValidateMFAChallenge(token string) (int, error)
 	HashPassword(password string) (string, error)
 	ComparePasswords(hashed string, plain []byte) bool
+	NeedsRehash(hashed string) bool
 }
 
 // Implementation that will be used in production
@@ -13,8 +16,16 @@ func NewAuthenticator() *RealAuthenticator {
 	return &RealAuthenticator{}
 }
 
-func (*RealAuthenticator) CreateJWT(secret []byte, userID int) (string, error) {
-	return CreateJWT(secret, userID)
+func (*RealAuthenticator) CreateJWT(secret []byte, userID, orgID int, sessionID string) (string, error) {
+	return CreateJWT(secret, userID, orgID, sessionID)
+}
+
+func (*RealAuthenticator) CreateMFAChallenge(secret []byte, userID int) (string, error) {
+	return CreateMFAChallenge(secret, userID)
+}
+
+func (*RealAuthenticator) ValidateMFAChallenge(token string) (int, error) {
+	return ValidateMFAChallenge(token)
 }
 
 func (*RealAuthenticator) HashPassword(password string) (string, error) {
@@ -24,3 +35,7 @@ func (*RealAuthenticator) HashPassword(password string) (string, error) {
 func (*RealAuthenticator) ComparePasswords(hashed string, plain []byte) bool {
 	return ComparePasswords(hashed, plain)
 }
+
+func (*RealAuthenticator) NeedsRehash(hashed string) bool {
+	return NeedsRehash(hashed)
+}
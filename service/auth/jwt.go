@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"go-sample-rest-api/config"
 	"go-sample-rest-api/logging"
@@ -16,7 +17,19 @@ import (
 
 type contextKey string
 
-const UserKey contextKey = "userID"
+const (
+	UserKey    contextKey = "userID"
+	RoleKey    contextKey = "role"
+	SessionKey contextKey = "sessionID"
+	OrgKey     contextKey = "orgID"
+)
+
+// AccessClaims are the claims of an access token: the registered claims and
+// the organization the token acts in.
+type AccessClaims struct {
+	jwt.RegisteredClaims
+	OrgID int `json:"org"`
+}
 
 type jwtValidatorFunc func(string) (*jwt.Token, error)
 
@@ -25,88 +38,201 @@ var validateJWT jwtValidatorFunc = func(tokenString string) (*jwt.Token, error)
 }
 
 func WithJWTAuth(handlerFunc http.HandlerFunc, store types.UserStore) http.HandlerFunc {
-
 	return func(w http.ResponseWriter, r *http.Request) {
-		log := logging.GetLogger()
-		tokenString := utils.GetTokenFromRequest(r)
-
-		token, err := validateJWT(tokenString)
+		ctx, err := Authenticate(r.Context(), utils.GetTokenFromRequest(r), store)
 		if err != nil {
-			log.WithFields(logrus.Fields{
-				"error": err,
-			}).Error("Failed to validate token")
-
-			permissionDenied(w)
+			unauthorized(w)
 			return
 		}
 
-		if !token.Valid {
-			log.Error("Invalid token")
-			permissionDenied(w)
-			return
-		}
+		// Call the function if the token is valid
+		handlerFunc(w, r.WithContext(ctx))
+	}
+}
 
-		claims := token.Claims.(jwt.MapClaims)
-		str := claims["userID"].(string)
+// ErrUnauthorized is returned by Authenticate for every rejected token.
+var ErrUnauthorized = fmt.Errorf("unauthorized")
 
-		userID, err := strconv.Atoi(str)
-		if err != nil {
-			log.WithFields(logrus.Fields{
-				"error": err,
-			}).Error("Failed to convert userID to int")
-			permissionDenied(w)
-			return
-		}
+// Authenticate checks the access token and returns ctx with the user, role,
+// organization and session of the token. It is shared by every API that
+// accepts access tokens.
+func Authenticate(ctx context.Context, tokenString string, store types.UserStore) (context.Context, error) {
+	log := logging.GetLogger()
+	token, err := validateJWT(tokenString)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Failed to validate token")
+		return nil, ErrUnauthorized
+	}
 
-		u, err := store.GetUserByID(userID)
-		if err != nil {
-			log.WithFields(logrus.Fields{
-				"error": err,
-			}).Error("Failed to get user by ID")
+	if !token.Valid {
+		log.Error("Invalid token")
+		return nil, ErrUnauthorized
+	}
+
+	claims, ok := token.Claims.(*AccessClaims)
+	if !ok {
+		log.Error("Unexpected token claims type")
+		return nil, ErrUnauthorized
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Failed to convert subject to user ID")
+		return nil, ErrUnauthorized
+	}
+
+	// the role comes from the membership, which is gone once the user leaves the organization
+	u, err := store.GetOrganizationUser(ctx, claims.OrgID, userID)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error": err,
+			"orgID": claims.OrgID,
+		}).Error("Failed to get user of organization")
+		return nil, ErrUnauthorized
+	}
+
+	if u.DisabledAt.Valid {
+		log.WithFields(logrus.Fields{
+			"userID": u.ID,
+		}).Warn("Rejected token of disabled user")
+		return nil, ErrUnauthorized
+	}
+
+	// the jti names the session, so a revoked session rejects its token
+	if err := store.TouchSession(ctx, u.ID, claims.ID); err != nil {
+		log.WithFields(logrus.Fields{
+			"error":  err,
+			"userID": u.ID,
+		}).Warn("Rejected token of inactive session")
+		return nil, ErrUnauthorized
+	}
+
+	// Add the user to the context
+	ctx = context.WithValue(ctx, UserKey, u.ID)
+	ctx = context.WithValue(ctx, RoleKey, u.Role)
+	ctx = context.WithValue(ctx, OrgKey, claims.OrgID)
+	ctx = context.WithValue(ctx, SessionKey, claims.ID)
+	return ctx, nil
+}
+
+// WithAdmin is WithJWTAuth for endpoints that only administrators of the
+// organization may call.
+func WithAdmin(handlerFunc http.HandlerFunc, store types.UserStore) http.HandlerFunc {
+	return WithJWTAuth(func(w http.ResponseWriter, r *http.Request) {
+		if GetUserRoleFromContext(r.Context()) != types.RoleAdmin {
 			permissionDenied(w)
 			return
 		}
 
-		// Add the user to the context
-		ctx := r.Context()
-		ctx = context.WithValue(ctx, UserKey, u.ID)
-		r = r.WithContext(ctx)
-
-		// Call the function if the token is valid
 		handlerFunc(w, r)
-	}
+	}, store)
+}
+
+// CreateJWT issues an access token for the given user using the registered
+// claims (sub, iss, aud, exp, iat, nbf and jti) and the org claim naming the
+// organization the user acts in. The jti is the ID of the session the token
+// belongs to. It is signed with the active key set; the secret is only used
+// when the service signs with HS256.
+func CreateJWT(secret []byte, userID, orgID int, sessionID string) (string, error) {
+	cfg := config.Envs
+	now := time.Now()
+	expiration := time.Second * time.Duration(cfg.JWTExpirationInSeconds)
+
+	return currentKeySet().sign(AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(userID),
+			Issuer:    cfg.JWTIssuer,
+			Audience:  jwt.ClaimStrings{cfg.JWTAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ID:        sessionID,
+		},
+		OrgID: orgID,
+	}, secret)
+}
+
+// SessionExpiry returns when a session started now ends, which is when its access token expires.
+func SessionExpiry() time.Time {
+	return time.Now().Add(time.Second * time.Duration(config.Envs.JWTExpirationInSeconds))
 }
 
-func CreateJWT(secret []byte, userID int) (string, error) {
-	expiration := time.Second * time.Duration(config.Envs.JWTExpirationInSeconds)
+// mfaAudience marks challenge tokens. They are signed with the same keys as
+// access tokens but carry a different audience, so WithJWTAuth rejects them.
+func mfaAudience() string {
+	return config.Envs.JWTAudience + ":mfa"
+}
+
+// CreateMFAChallenge issues a short-lived token proving that the user passed the
+// password check. It can only be exchanged for an access token together with a
+// second factor.
+func CreateMFAChallenge(secret []byte, userID int) (string, error) {
+	cfg := config.Envs
+	now := time.Now()
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"userID":    strconv.Itoa(int(userID)),
-		"expiresAt": time.Now().Add(expiration).Unix(),
-	})
+	return currentKeySet().sign(jwt.RegisteredClaims{
+		Subject:   strconv.Itoa(userID),
+		Issuer:    cfg.JWTIssuer,
+		Audience:  jwt.ClaimStrings{mfaAudience()},
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Second * time.Duration(cfg.MFAChallengeTTLInSeconds))),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ID:        uuid.New().String(),
+	}, secret)
+}
 
-	tokenString, err := token.SignedString(secret)
+// ValidateMFAChallenge verifies a challenge token and returns the user ID it was issued for.
+func ValidateMFAChallenge(tokenString string) (int, error) {
+	cfg := config.Envs
+	keys := currentKeySet()
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, keys.keyFunc,
+		jwt.WithValidMethods([]string{keys.Algorithm()}),
+		jwt.WithIssuer(cfg.JWTIssuer),
+		jwt.WithAudience(mfaAudience()),
+		jwt.WithLeeway(time.Second*time.Duration(cfg.JWTClockSkewInSeconds)),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
 	if err != nil {
-		return "", err
+		return 0, err
 	}
 
-	return tokenString, err
+	return strconv.Atoi(claims.Subject)
 }
 
+// validateJWTDefault parses the token into AccessClaims and checks the
+// signature, issuer, audience and time based claims with the configured clock skew.
 func validateJWTDefault(tokenString string) (*jwt.Token, error) {
-	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
+	cfg := config.Envs
+	keys := currentKeySet()
+	return jwt.ParseWithClaims(tokenString, &AccessClaims{}, keys.keyFunc,
+		jwt.WithValidMethods([]string{keys.Algorithm()}),
+		jwt.WithIssuer(cfg.JWTIssuer),
+		jwt.WithAudience(cfg.JWTAudience),
+		jwt.WithLeeway(time.Second*time.Duration(cfg.JWTClockSkewInSeconds)),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+}
 
-		return []byte(config.Envs.JWTSecret), nil
-	})
+func unauthorized(w http.ResponseWriter) {
+	utils.WriteError(w, http.StatusUnauthorized, ErrUnauthorized)
 }
 
 func permissionDenied(w http.ResponseWriter) {
 	utils.WriteError(w, http.StatusForbidden, fmt.Errorf("permission denied"))
 }
 
+func GetUserRoleFromContext(ctx context.Context) string {
+	role, _ := ctx.Value(RoleKey).(string)
+	return role
+}
+
 func GetUserIDFromContext(ctx context.Context) int {
 	userID, ok := ctx.Value(UserKey).(int)
 	if !ok {
@@ -115,3 +241,19 @@ func GetUserIDFromContext(ctx context.Context) int {
 
 	return userID
 }
+
+// GetOrgIDFromContext returns the organization of the access token, or -1
+// outside of an authenticated request.
+func GetOrgIDFromContext(ctx context.Context) int {
+	orgID, ok := ctx.Value(OrgKey).(int)
+	if !ok {
+		return -1
+	}
+
+	return orgID
+}
+
+func GetSessionIDFromContext(ctx context.Context) string {
+	sessionID, _ := ctx.Value(SessionKey).(string)
+	return sessionID
+}
@@ -1,32 +1,164 @@
 package auth
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
+	"go-sample-rest-api/config"
 	"go-sample-rest-api/types"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 type mockUserStore struct{}
 
-func (m *mockUserStore) GetUserByEmail(email string) (*types.User, error) {
+// Users known to mockUserStore.GetOrganizationUser; every other ID is a
+// regular user. All of them are members of memberOrgID only.
+const (
+	disabledUserID = 2
+	adminUserID    = 3
+	memberOrgID    = 1
+)
+
+// revokedSessionID is rejected by mockUserStore.TouchSession; every other session is active.
+const revokedSessionID = "revoked-session"
+
+func (m *mockUserStore) GetUserByEmail(ctx context.Context, email string) (*types.User, error) {
+	panic("implement me")
+}
+
+func (m *mockUserStore) CreateUser(ctx context.Context, user types.User, organizationName string) error {
+	panic("implement me")
+}
+
+func (m *mockUserStore) CreateOrganizationUser(ctx context.Context, orgID int, user types.User, role string, entry types.AuditEntry) error {
+	panic("implement me")
+}
+
+func (m *mockUserStore) GetOrganizationUser(ctx context.Context, orgID, userID int) (*types.User, error) {
+	if orgID != memberOrgID {
+		return nil, fmt.Errorf("user %d is not a member of organization %d", userID, orgID)
+	}
+	return m.GetUserByID(ctx, userID)
+}
+
+func (m *mockUserStore) GetOrganizationUsers(ctx context.Context, orgID int, userIDs []int) ([]types.User, error) {
+	panic("implement me")
+}
+
+func (m *mockUserStore) ListMemberships(ctx context.Context, userID int) ([]types.Membership, error) {
+	panic("implement me")
+}
+
+func (m *mockUserStore) SetUserRole(ctx context.Context, orgID, userID int, role string, entry types.AuditEntry) error {
+	panic("implement me")
+}
+
+func (m *mockUserStore) GetUserByID(ctx context.Context, id int) (*types.User, error) {
+	switch id {
+	case disabledUserID:
+		return &types.User{ID: id, Role: types.RoleUser, DisabledAt: sql.NullTime{Time: time.Now(), Valid: true}}, nil
+	case adminUserID:
+		return &types.User{ID: id, Role: types.RoleAdmin}, nil
+	default:
+		return &types.User{ID: id, Role: types.RoleUser}, nil
+	}
+}
+
+func (m *mockUserStore) UpdateUser(ctx context.Context, user types.User) error {
+	panic("implement me")
+}
+
+func (m *mockUserStore) ListUsers(ctx context.Context, orgID int, query types.UserListQuery) ([]types.User, int, error) {
+	panic("implement me")
+}
+
+func (m *mockUserStore) SetUserDisabled(ctx context.Context, orgID, userID int, disabled bool, entry types.AuditEntry) error {
 	panic("implement me")
 }
 
-func (m *mockUserStore) CreateUser(user types.User) error {
+func (m *mockUserStore) DeleteUser(ctx context.Context, orgID, userID int, entry types.AuditEntry) error {
 	panic("implement me")
 }
 
-func (m *mockUserStore) GetUserByID(id int) (*types.User, error) {
-	return &types.User{ID: id}, nil
+func (m *mockUserStore) AnonymizeUser(ctx context.Context, orgID, userID int, entry types.AuditEntry) error {
+	panic("implement me")
+}
+
+func (m *mockUserStore) UpdatePassword(ctx context.Context, userID int, hashedPassword string) error {
+	panic("implement me")
+}
+
+func (m *mockUserStore) ChangePassword(ctx context.Context, userID int, hashedPassword string, keepSessionID string) error {
+	panic("implement me")
+}
+
+func (m *mockUserStore) MarkEmailVerified(ctx context.Context, userID int) error {
+	panic("implement me")
+}
+
+func (m *mockUserStore) CreateUserToken(ctx context.Context, token types.UserToken) error {
+	panic("implement me")
+}
+
+func (m *mockUserStore) GetUserToken(ctx context.Context, purpose string, tokenHash string) (*types.UserToken, error) {
+	panic("implement me")
+}
+
+func (m *mockUserStore) ConsumeUserToken(ctx context.Context, purpose string, tokenHash string) (*types.UserToken, error) {
+	panic("implement me")
+}
+
+func (m *mockUserStore) SetTOTPSecret(ctx context.Context, userID int, secret string) error {
+	panic("implement me")
+}
+
+func (m *mockUserStore) EnableTOTP(ctx context.Context, userID int, step int64, recoveryCodeHashes []string) error {
+	panic("implement me")
+}
+
+func (m *mockUserStore) DisableTOTP(ctx context.Context, userID int) error {
+	panic("implement me")
+}
+
+func (m *mockUserStore) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	panic("implement me")
+}
+
+func (m *mockUserStore) ConsumeRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	panic("implement me")
+}
+
+func (m *mockUserStore) CreateSession(ctx context.Context, session types.Session) error {
+	panic("implement me")
+}
+
+func (m *mockUserStore) TouchSession(ctx context.Context, userID int, sessionID string) error {
+	if sessionID == revokedSessionID {
+		return fmt.Errorf("session revoked or expired")
+	}
+	return nil
+}
+
+func (m *mockUserStore) ListSessions(ctx context.Context, userID int) ([]types.Session, error) {
+	panic("implement me")
+}
+
+func (m *mockUserStore) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	panic("implement me")
+}
+
+func (m *mockUserStore) RevokeOtherSessions(ctx context.Context, userID int, keepSessionID string) error {
+	panic("implement me")
 }
 
 func TestCreateJWT(t *testing.T) {
 	secret := []byte("secret")
 
-	token, err := CreateJWT(secret, 1)
+	token, err := CreateJWT(secret, 1, memberOrgID, "session-1")
 	if err != nil {
 		t.Errorf("error creating JWT: %v", err)
 	}
@@ -36,6 +168,101 @@ func TestCreateJWT(t *testing.T) {
 	}
 }
 
+func TestValidateJWTDefault(t *testing.T) {
+	cfg := config.Envs
+	secret := []byte(cfg.JWTSecret)
+
+	sign := func(claims jwt.RegisteredClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+		if err != nil {
+			t.Fatalf("error signing token: %v", err)
+		}
+		return token
+	}
+	validClaims := func() jwt.RegisteredClaims {
+		now := time.Now()
+		return jwt.RegisteredClaims{
+			Subject:   "1",
+			Issuer:    cfg.JWTIssuer,
+			Audience:  jwt.ClaimStrings{cfg.JWTAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		}
+	}
+
+	t.Run("token created by CreateJWT is valid", func(t *testing.T) {
+		tokenString, err := CreateJWT(secret, 42, memberOrgID, "session-42")
+		if err != nil {
+			t.Fatalf("error creating JWT: %v", err)
+		}
+
+		token, err := validateJWTDefault(tokenString)
+		if err != nil {
+			t.Fatalf("expected token to be valid, got %v", err)
+		}
+
+		claims := token.Claims.(*AccessClaims)
+		if claims.Subject != "42" {
+			t.Errorf("expected subject 42, got %s", claims.Subject)
+		}
+		if claims.OrgID != memberOrgID {
+			t.Errorf("expected org %d, got %d", memberOrgID, claims.OrgID)
+		}
+		if claims.ID == "" {
+			t.Error("expected jti to be set")
+		}
+	})
+
+	t.Run("token within clock skew is valid", func(t *testing.T) {
+		claims := validClaims()
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Second))
+
+		if _, err := validateJWTDefault(sign(claims)); err != nil {
+			t.Errorf("expected token within leeway to be valid, got %v", err)
+		}
+	})
+
+	invalid := []struct {
+		name   string
+		mutate func(c *jwt.RegisteredClaims)
+	}{
+		{"expired token", func(c *jwt.RegisteredClaims) {
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+		}},
+		{"missing expiry", func(c *jwt.RegisteredClaims) { c.ExpiresAt = nil }},
+		{"not yet valid", func(c *jwt.RegisteredClaims) {
+			c.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Hour))
+		}},
+		{"issued in the future", func(c *jwt.RegisteredClaims) {
+			c.IssuedAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+		}},
+		{"wrong issuer", func(c *jwt.RegisteredClaims) { c.Issuer = "someone-else" }},
+		{"wrong audience", func(c *jwt.RegisteredClaims) { c.Audience = jwt.ClaimStrings{"other-api"} }},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			claims := validClaims()
+			tc.mutate(&claims)
+
+			if _, err := validateJWTDefault(sign(claims)); err == nil {
+				t.Errorf("%s: expected validation error", tc.name)
+			}
+		})
+	}
+
+	t.Run("wrong signing key", func(t *testing.T) {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims()).SignedString([]byte("other-secret"))
+		if err != nil {
+			t.Fatalf("error signing token: %v", err)
+		}
+
+		if _, err := validateJWTDefault(token); err == nil {
+			t.Error("expected validation error for wrong signing key")
+		}
+	})
+}
+
 func TestWithJWTAuth(t *testing.T) {
 	mockStore := &mockUserStore{}
 
@@ -62,8 +289,9 @@ func TestWithJWTAuth(t *testing.T) {
 				return func(tokenString string) (*jwt.Token, error) {
 					return &jwt.Token{
 						Valid: true,
-						Claims: jwt.MapClaims{
-							"userID": "1",
+						Claims: &AccessClaims{
+							RegisteredClaims: jwt.RegisteredClaims{Subject: "1"},
+							OrgID:            memberOrgID,
 						},
 					}, nil
 				}
@@ -78,7 +306,7 @@ func TestWithJWTAuth(t *testing.T) {
 					return nil, fmt.Errorf("unexpected signing method: alg")
 				}
 			},
-			expectedCode: http.StatusForbidden,
+			expectedCode: http.StatusUnauthorized,
 		},
 		{
 			name:  "No Token",
@@ -88,22 +316,86 @@ func TestWithJWTAuth(t *testing.T) {
 					return nil, fmt.Errorf("no token")
 				}
 			},
-			expectedCode: http.StatusForbidden,
+			expectedCode: http.StatusUnauthorized,
 		},
 		{
 			name:  "Token With Invalid UserID",
 			token: "bad_userid_token",
+			setupFunc: func() jwtValidatorFunc {
+				return func(tokenString string) (*jwt.Token, error) {
+					return &jwt.Token{
+						Valid: true,
+						Claims: &AccessClaims{
+							RegisteredClaims: jwt.RegisteredClaims{Subject: "abc"}, // Non-integer subject
+							OrgID:            memberOrgID,
+						},
+					}, nil
+				}
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:  "Token Of Disabled User",
+			token: "disabled_user_token",
+			setupFunc: func() jwtValidatorFunc {
+				return func(tokenString string) (*jwt.Token, error) {
+					return &jwt.Token{
+						Valid: true,
+						Claims: &AccessClaims{
+							RegisteredClaims: jwt.RegisteredClaims{Subject: "2"},
+							OrgID:            memberOrgID,
+						},
+					}, nil
+				}
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:  "Token Of Other Organization",
+			token: "other_org_token",
+			setupFunc: func() jwtValidatorFunc {
+				return func(tokenString string) (*jwt.Token, error) {
+					return &jwt.Token{
+						Valid: true,
+						Claims: &AccessClaims{
+							RegisteredClaims: jwt.RegisteredClaims{Subject: "1"},
+							OrgID:            memberOrgID + 1,
+						},
+					}, nil
+				}
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:  "Token Of Revoked Session",
+			token: "revoked_session_token",
+			setupFunc: func() jwtValidatorFunc {
+				return func(tokenString string) (*jwt.Token, error) {
+					return &jwt.Token{
+						Valid: true,
+						Claims: &AccessClaims{
+							RegisteredClaims: jwt.RegisteredClaims{Subject: "1", ID: revokedSessionID},
+							OrgID:            memberOrgID,
+						},
+					}, nil
+				}
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:  "Token With Unexpected Claims Type",
+			token: "map_claims_token",
 			setupFunc: func() jwtValidatorFunc {
 				return func(tokenString string) (*jwt.Token, error) {
 					return &jwt.Token{
 						Valid: true,
 						Claims: jwt.MapClaims{
-							"userID": "abc", // Non-integer userID
+							"userID": 1, // Legacy claim with a non-string value
 						},
 					}, nil
 				}
 			},
-			expectedCode: http.StatusForbidden,
+			expectedCode: http.StatusUnauthorized,
 		},
 	}
 
@@ -129,3 +421,40 @@ func TestWithJWTAuth(t *testing.T) {
 	// Reset the validateJWT function to prevent side effects in other tests
 	validateJWT = validateJWTDefault
 }
+
+func TestWithAdmin(t *testing.T) {
+	mockStore := &mockUserStore{}
+	defer func() { validateJWT = validateJWTDefault }()
+
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name         string
+		subject      string
+		expectedCode int
+	}{
+		{name: "Admin", subject: "3", expectedCode: http.StatusOK},
+		{name: "Regular User", subject: "1", expectedCode: http.StatusForbidden},
+		{name: "Disabled User", subject: "2", expectedCode: http.StatusUnauthorized},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			validateJWT = func(tokenString string) (*jwt.Token, error) {
+				claims := &AccessClaims{RegisteredClaims: jwt.RegisteredClaims{Subject: tc.subject}, OrgID: memberOrgID}
+				return &jwt.Token{Valid: true, Claims: claims}, nil
+			}
+
+			req, _ := http.NewRequest("GET", "/some-path", nil)
+			req.Header.Add("Authorization", "Bearer token")
+			rr := httptest.NewRecorder()
+			WithAdmin(testHandler, mockStore).ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedCode {
+				t.Errorf("%s: expected HTTP status %v, got %v", tc.name, tc.expectedCode, status)
+			}
+		})
+	}
+}
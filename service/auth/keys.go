@@ -0,0 +1,321 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"go-sample-rest-api/config"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// KeySet holds the key used to sign access tokens and every key that is
+// accepted when verifying them. Keys are identified by their RFC 7638
+// thumbprint, which is sent as the kid header of issued tokens.
+type KeySet struct {
+	method       jwt.SigningMethod
+	hmacSecret   []byte
+	signingKeyID string
+	signingKey   crypto.PrivateKey
+	verification map[string]crypto.PublicKey
+	order        []string
+}
+
+// JSONWebKey is the public part of a verification key as published in the JWKS document.
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// JSONWebKeySet is the document served at /.well-known/jwks.json.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+var (
+	keySetMu sync.RWMutex
+	keySet   = NewHMACKeySet([]byte(config.Envs.JWTSecret))
+)
+
+// UseKeySet replaces the key set used to sign and verify access tokens.
+func UseKeySet(ks *KeySet) {
+	keySetMu.Lock()
+	defer keySetMu.Unlock()
+	keySet = ks
+}
+
+func currentKeySet() *KeySet {
+	keySetMu.RLock()
+	defer keySetMu.RUnlock()
+	return keySet
+}
+
+// NewHMACKeySet returns a key set that signs and verifies with a shared HS256 secret.
+// Nothing is published in the JWKS document for it.
+func NewHMACKeySet(secret []byte) *KeySet {
+	return &KeySet{method: jwt.SigningMethodHS256, hmacSecret: secret}
+}
+
+// LoadKeySet builds the key set described by the JWT_* configuration. With the
+// default HS256 algorithm the shared JWT secret is used. For RS256 and EdDSA the
+// private key is read from JWTSigningKeyFile, and the public keys listed in
+// JWTVerificationKeyFiles stay valid for verification so keys can be rotated.
+func LoadKeySet(cfg config.Config) (*KeySet, error) {
+	switch cfg.JWTSigningAlgorithm {
+	case "", jwt.SigningMethodHS256.Alg():
+		return NewHMACKeySet([]byte(cfg.JWTSecret)), nil
+	case jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg():
+	default:
+		return nil, fmt.Errorf("unsupported JWT signing algorithm: %s", cfg.JWTSigningAlgorithm)
+	}
+
+	if cfg.JWTSigningKeyFile == "" {
+		return nil, fmt.Errorf("JWT_SIGNING_KEY_FILE is required for %s", cfg.JWTSigningAlgorithm)
+	}
+	privateKey, err := readPrivateKey(cfg.JWTSigningKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var verificationKeys []crypto.PublicKey
+	for _, path := range strings.Split(cfg.JWTVerificationKeyFiles, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		publicKey, err := readPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+		verificationKeys = append(verificationKeys, publicKey)
+	}
+
+	return NewAsymmetricKeySet(privateKey, verificationKeys...)
+}
+
+// NewAsymmetricKeySet returns a key set that signs with an RSA (RS256) or
+// Ed25519 (EdDSA) private key. The matching public key is always accepted for
+// verification, together with any additional keys that are still rotating out.
+func NewAsymmetricKeySet(signingKey crypto.PrivateKey, verificationKeys ...crypto.PublicKey) (*KeySet, error) {
+	var publicKey crypto.PublicKey
+	ks := &KeySet{signingKey: signingKey, verification: map[string]crypto.PublicKey{}}
+
+	switch key := signingKey.(type) {
+	case *rsa.PrivateKey:
+		ks.method = jwt.SigningMethodRS256
+		publicKey = &key.PublicKey
+	case ed25519.PrivateKey:
+		ks.method = jwt.SigningMethodEdDSA
+		publicKey = key.Public()
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %T", signingKey)
+	}
+
+	for _, key := range append([]crypto.PublicKey{publicKey}, verificationKeys...) {
+		if methodForKey(key) != ks.method {
+			return nil, fmt.Errorf("verification key type %T does not match signing algorithm %s", key, ks.method.Alg())
+		}
+		kid, err := thumbprint(key)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := ks.verification[kid]; exists {
+			continue
+		}
+		ks.verification[kid] = key
+		ks.order = append(ks.order, kid)
+	}
+	ks.signingKeyID = ks.order[0]
+
+	return ks, nil
+}
+
+// Algorithm returns the JWS algorithm used to sign new tokens.
+func (ks *KeySet) Algorithm() string {
+	return ks.method.Alg()
+}
+
+// sign signs the claims with the active key. The secret is only used by HS256
+// key sets, which keep signing with the secret handed in by the caller.
+func (ks *KeySet) sign(claims jwt.Claims, secret []byte) (string, error) {
+	token := jwt.NewWithClaims(ks.method, claims)
+	if ks.hmacSecret != nil {
+		return token.SignedString(secret)
+	}
+
+	token.Header["kid"] = ks.signingKeyID
+	return token.SignedString(ks.signingKey)
+}
+
+// keyFunc resolves the verification key for a parsed token from its kid header.
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	if token.Method.Alg() != ks.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	if ks.hmacSecret != nil {
+		return ks.hmacSecret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.verification[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %q", kid)
+	}
+
+	return key, nil
+}
+
+// JWKS returns the public verification keys. HS256 key sets publish no keys.
+func (ks *KeySet) JWKS() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, kid := range ks.order {
+		jwk, err := publicJWK(ks.verification[kid])
+		if err != nil {
+			continue
+		}
+		jwk.KeyID = kid
+		jwk.Use = "sig"
+		jwk.Algorithm = ks.method.Alg()
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+// JWKSHandler serves the public verification keys so other services can verify our tokens.
+func JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Header().Set("Content-Type", "application/jwk-set+json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(currentKeySet().JWKS())
+}
+
+func methodForKey(key crypto.PublicKey) jwt.SigningMethod {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA
+	default:
+		return nil
+	}
+}
+
+func publicJWK(key crypto.PublicKey) (JSONWebKey, error) {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return JSONWebKey{
+			KeyType: "RSA",
+			N:       base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:       base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}, nil
+	case ed25519.PublicKey:
+		return JSONWebKey{
+			KeyType: "OKP",
+			Curve:   "Ed25519",
+			X:       base64.RawURLEncoding.EncodeToString(key),
+		}, nil
+	default:
+		return JSONWebKey{}, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint used as key ID.
+func thumbprint(key crypto.PublicKey) (string, error) {
+	jwk, err := publicJWK(key)
+	if err != nil {
+		return "", err
+	}
+
+	// Members must be in lexicographic order without whitespace.
+	var canonical string
+	switch jwk.KeyType {
+	case "RSA":
+		canonical = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, jwk.E, jwk.N)
+	default:
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":"OKP","x":%q}`, jwk.Curve, jwk.X)
+	}
+
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+func readPEMBlock(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file %s: %v", path, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+
+	return block, nil
+}
+
+func readPrivateKey(path string) (crypto.PrivateKey, error) {
+	block, err := readPEMBlock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key %s: %v", path, err)
+	}
+
+	return key, nil
+}
+
+// readPublicKey accepts a PKIX public key, a certificate or a private key file.
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEMBlock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	}
+
+	privateKey, err := readPrivateKey(path)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T in %s", privateKey, path)
+	}
+
+	return signer.Public(), nil
+}
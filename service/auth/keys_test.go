@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"go-sample-rest-api/config"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	path := filepath.Join(t.TempDir(), "key.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("error writing key file: %v", err)
+	}
+	return path
+}
+
+func writePrivateKey(t *testing.T, key interface{}) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("error marshalling private key: %v", err)
+	}
+	return writePEM(t, "PRIVATE KEY", der)
+}
+
+func writePublicKey(t *testing.T, key interface{}) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("error marshalling public key: %v", err)
+	}
+	return writePEM(t, "PUBLIC KEY", der)
+}
+
+// withKeySet swaps the active key set for the duration of a test.
+func withKeySet(t *testing.T, ks *KeySet) {
+	previous := currentKeySet()
+	UseKeySet(ks)
+	t.Cleanup(func() { UseKeySet(previous) })
+}
+
+func TestLoadKeySet(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("defaults to HS256 with the shared secret", func(t *testing.T) {
+		ks, err := LoadKeySet(config.Config{JWTSecret: "secret"})
+		assert.Nil(t, err)
+		assert.Equal(t, "HS256", ks.Algorithm())
+		assert.Empty(t, ks.JWKS().Keys)
+	})
+
+	t.Run("loads an RS256 signing key and rotating verification keys", func(t *testing.T) {
+		oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ks, err := LoadKeySet(config.Config{
+			JWTSigningAlgorithm:     "RS256",
+			JWTSigningKeyFile:       writePrivateKey(t, rsaKey),
+			JWTVerificationKeyFiles: writePublicKey(t, &oldKey.PublicKey),
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, "RS256", ks.Algorithm())
+		assert.Len(t, ks.JWKS().Keys, 2)
+	})
+
+	t.Run("loads an EdDSA signing key", func(t *testing.T) {
+		ks, err := LoadKeySet(config.Config{
+			JWTSigningAlgorithm: "EdDSA",
+			JWTSigningKeyFile:   writePrivateKey(t, edKey),
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, "EdDSA", ks.Algorithm())
+	})
+
+	t.Run("rejects a key that does not match the algorithm", func(t *testing.T) {
+		_, err := LoadKeySet(config.Config{
+			JWTSigningAlgorithm:     "RS256",
+			JWTSigningKeyFile:       writePrivateKey(t, rsaKey),
+			JWTVerificationKeyFiles: writePublicKey(t, edKey.Public()),
+		})
+		assert.NotNil(t, err)
+	})
+
+	t.Run("requires a signing key file", func(t *testing.T) {
+		_, err := LoadKeySet(config.Config{JWTSigningAlgorithm: "RS256"})
+		assert.NotNil(t, err)
+	})
+
+	t.Run("rejects unknown algorithms", func(t *testing.T) {
+		_, err := LoadKeySet(config.Config{JWTSigningAlgorithm: "none"})
+		assert.NotNil(t, err)
+	})
+}
+
+func TestAsymmetricKeyRotation(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldSet, err := NewAsymmetricKeySet(oldKey)
+	assert.Nil(t, err)
+	withKeySet(t, oldSet)
+	oldToken, err := CreateJWT(nil, 7, memberOrgID, "session-7")
+	assert.Nil(t, err)
+
+	// Rotate: sign with the new key while the old key is still accepted.
+	rotated, err := NewAsymmetricKeySet(newKey, &oldKey.PublicKey)
+	assert.Nil(t, err)
+	UseKeySet(rotated)
+
+	newToken, err := CreateJWT(nil, 8, memberOrgID, "session-8")
+	assert.Nil(t, err)
+
+	token, err := validateJWTDefault(newToken)
+	assert.Nil(t, err)
+	assert.NotEqual(t, oldSet.signingKeyID, token.Header["kid"])
+
+	_, err = validateJWTDefault(oldToken)
+	assert.Nil(t, err, "tokens signed with a rotating key must stay valid")
+
+	// Drop the old key entirely.
+	retired, err := NewAsymmetricKeySet(newKey)
+	assert.Nil(t, err)
+	UseKeySet(retired)
+
+	_, err = validateJWTDefault(oldToken)
+	assert.NotNil(t, err, "tokens signed with a retired key must be rejected")
+}
+
+func TestAsymmetricKeySetRejectsHMACTokens(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, err := NewAsymmetricKeySet(edKey)
+	assert.Nil(t, err)
+
+	hmacToken, err := CreateJWT([]byte(config.Envs.JWTSecret), 1, memberOrgID, "session-1")
+	assert.Nil(t, err)
+
+	withKeySet(t, ks)
+	edToken, err := CreateJWT(nil, 1, memberOrgID, "session-1")
+	assert.Nil(t, err)
+
+	_, err = validateJWTDefault(edToken)
+	assert.Nil(t, err)
+
+	_, err = validateJWTDefault(hmacToken)
+	assert.NotNil(t, err)
+}
+
+func TestJWKSHandler(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, err := NewAsymmetricKeySet(rsaKey)
+	assert.Nil(t, err)
+	withKeySet(t, ks)
+
+	rr := httptest.NewRecorder()
+	JWKSHandler(rr, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var set JSONWebKeySet
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &set))
+	assert.Len(t, set.Keys, 1)
+	assert.Equal(t, "RSA", set.Keys[0].KeyType)
+	assert.Equal(t, "RS256", set.Keys[0].Algorithm)
+	assert.Equal(t, "AQAB", set.Keys[0].E)
+	assert.Equal(t, ks.signingKeyID, set.Keys[0].KeyID)
+
+	// The published key must be able to verify an issued token on its own.
+	tokenString, err := CreateJWT(nil, 3, memberOrgID, "session-3")
+	assert.Nil(t, err)
+	_, err = jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return &rsaKey.PublicKey, nil
+	}, jwt.WithValidMethods([]string{set.Keys[0].Algorithm}))
+	assert.Nil(t, err)
+}
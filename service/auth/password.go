@@ -1,9 +1,219 @@
 package auth
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"go-sample-rest-api/config"
+	"math"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordHasher creates and checks password hashes. Hashes carry their
+// algorithm and parameters, so a hash stays verifiable after the configured
+// hasher changes.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Compare reports whether plain matches a hash this hasher understands.
+	Compare(hashed string, plain []byte) bool
+	// Handles reports whether the hash was produced by this kind of hasher.
+	Handles(hashed string) bool
+	// Outdated reports whether a handled hash uses other parameters than the hasher.
+	Outdated(hashed string) bool
+}
+
+var (
+	hasherMu sync.RWMutex
+	hasher   PasswordHasher = NewArgon2idHasher(DefaultArgon2idParams)
+	// verifiers understand every hash format that may still be stored.
+	verifiers = []PasswordHasher{NewArgon2idHasher(DefaultArgon2idParams), NewBcryptHasher(bcrypt.DefaultCost)}
+)
+
+// UseHasher replaces the hasher used for new password hashes.
+func UseHasher(h PasswordHasher) {
+	hasherMu.Lock()
+	defer hasherMu.Unlock()
+	hasher = h
+}
+
+func currentHasher() PasswordHasher {
+	hasherMu.RLock()
+	defer hasherMu.RUnlock()
+	return hasher
+}
+
+// LoadHasher builds the hasher described by the PASSWORD_HASHER configuration.
+// Parameters that Argon2id or bcrypt cannot work with are rejected here, so a
+// misconfiguration fails at startup rather than at the first login.
+func LoadHasher(cfg config.Config) (PasswordHasher, error) {
+	switch cfg.PasswordHasher {
+	case "", "argon2id":
+		if cfg.Argon2MemoryInKiB < 1 || cfg.Argon2MemoryInKiB > math.MaxUint32 {
+			return nil, fmt.Errorf("ARGON2_MEMORY_IN_KIB must be between 1 and %d, got %d", uint32(math.MaxUint32), cfg.Argon2MemoryInKiB)
+		}
+		if cfg.Argon2Iterations < 1 || cfg.Argon2Iterations > math.MaxUint32 {
+			return nil, fmt.Errorf("ARGON2_ITERATIONS must be between 1 and %d, got %d", uint32(math.MaxUint32), cfg.Argon2Iterations)
+		}
+		if cfg.Argon2Parallelism < 1 || cfg.Argon2Parallelism > math.MaxUint8 {
+			return nil, fmt.Errorf("ARGON2_PARALLELISM must be between 1 and %d, got %d", math.MaxUint8, cfg.Argon2Parallelism)
+		}
+		return NewArgon2idHasher(Argon2idParams{
+			Memory:      uint32(cfg.Argon2MemoryInKiB),
+			Iterations:  uint32(cfg.Argon2Iterations),
+			Parallelism: uint8(cfg.Argon2Parallelism),
+			SaltLength:  DefaultArgon2idParams.SaltLength,
+			KeyLength:   DefaultArgon2idParams.KeyLength,
+		}), nil
+	case "bcrypt":
+		if cfg.BcryptCost < int64(bcrypt.MinCost) || cfg.BcryptCost > int64(bcrypt.MaxCost) {
+			return nil, fmt.Errorf("BCRYPT_COST must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, cfg.BcryptCost)
+		}
+		return NewBcryptHasher(int(cfg.BcryptCost)), nil
+	default:
+		return nil, fmt.Errorf("unsupported password hasher %q", cfg.PasswordHasher)
+	}
+}
 
 func HashPassword(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return currentHasher().Hash(password)
+}
+
+// ComparePasswords checks plain against a hash of any supported format.
+func ComparePasswords(hashed string, plain []byte) bool {
+	h := hasherFor(hashed)
+	return h != nil && h.Compare(hashed, plain)
+}
+
+// NeedsRehash reports whether the hash should be replaced by one from the
+// current hasher, because it uses another algorithm or outdated parameters.
+func NeedsRehash(hashed string) bool {
+	h := currentHasher()
+	return !h.Handles(hashed) || h.Outdated(hashed)
+}
+
+func hasherFor(hashed string) PasswordHasher {
+	if h := currentHasher(); h.Handles(hashed) {
+		return h
+	}
+	for _, h := range verifiers {
+		if h.Handles(hashed) {
+			return h
+		}
+	}
+	return nil
+}
+
+// Argon2idParams are the cost parameters of Argon2id. Memory is in KiB.
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams follow the OWASP recommendation of 19 MiB, two
+// iterations and one degree of parallelism.
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// Argon2idHasher stores hashes in the PHC string format,
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>.
+type Argon2idHasher struct {
+	params Argon2idParams
+}
+
+func NewArgon2idHasher(params Argon2idParams) *Argon2idHasher {
+	return &Argon2idHasher{params: params}
+}
+
+var phcEncoding = base64.RawStdEncoding
+
+func (a *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, a.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	p := a.params
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(key)), nil
+}
+
+func (a *Argon2idHasher) Compare(hashed string, plain []byte) bool {
+	p, salt, key, err := decodeArgon2id(hashed)
+	if err != nil {
+		return false
+	}
+
+	other := argon2.IDKey(plain, salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1
+}
+
+func (a *Argon2idHasher) Handles(hashed string) bool {
+	return strings.HasPrefix(hashed, "$argon2id$")
+}
+
+func (a *Argon2idHasher) Outdated(hashed string) bool {
+	p, salt, key, err := decodeArgon2id(hashed)
+	if err != nil {
+		return true
+	}
+
+	return p.Memory != a.params.Memory || p.Iterations != a.params.Iterations || p.Parallelism != a.params.Parallelism ||
+		uint32(len(salt)) != a.params.SaltLength || uint32(len(key)) != a.params.KeyLength
+}
+
+func decodeArgon2id(hashed string) (p Argon2idParams, salt, key []byte, err error) {
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, fmt.Errorf("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("unsupported argon2id version")
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2id parameters: %v", err)
+	}
+
+	if salt, err = phcEncoding.DecodeString(parts[4]); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2id salt: %v", err)
+	}
+	if key, err = phcEncoding.DecodeString(parts[5]); err != nil || len(key) == 0 {
+		return p, nil, nil, fmt.Errorf("invalid argon2id key")
+	}
+
+	return p, salt, key, nil
+}
+
+// BcryptHasher is kept to verify hashes created before Argon2id became the
+// default. bcrypt only looks at the first 72 bytes of a password and rejects
+// longer ones when hashing.
+type BcryptHasher struct {
+	cost int
+}
+
+func NewBcryptHasher(cost int) *BcryptHasher {
+	return &BcryptHasher{cost: cost}
+}
+
+func (b *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.cost)
 	if err != nil {
 		return "", err
 	}
@@ -11,7 +221,15 @@ func HashPassword(password string) (string, error) {
 	return string(hash), nil
 }
 
-func ComparePasswords(hashed string, plain []byte) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hashed), plain)
-	return err == nil
+func (b *BcryptHasher) Compare(hashed string, plain []byte) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hashed), plain) == nil
+}
+
+func (b *BcryptHasher) Handles(hashed string) bool {
+	return strings.HasPrefix(hashed, "$2a$") || strings.HasPrefix(hashed, "$2b$") || strings.HasPrefix(hashed, "$2y$")
+}
+
+func (b *BcryptHasher) Outdated(hashed string) bool {
+	cost, err := bcrypt.Cost([]byte(hashed))
+	return err != nil || cost != b.cost
 }
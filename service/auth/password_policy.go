@@ -0,0 +1,281 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"go-sample-rest-api/config"
+	"go-sample-rest-api/customerrors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PasswordPolicy describes the rules a new password has to follow.
+type PasswordPolicy struct {
+	MinLength          int
+	MaxLength          int
+	RequireUpper       bool
+	RequireLower       bool
+	RequireDigit       bool
+	RequireSymbol      bool
+	RejectPersonalInfo bool
+	// Breached is consulted when set, so known leaked passwords are refused.
+	Breached BreachedPasswords
+}
+
+// DefaultPasswordPolicy follows NIST SP 800-63B: a minimum length and no
+// personal information, without composition rules.
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:          8,
+	MaxLength:          130,
+	RejectPersonalInfo: true,
+}
+
+var (
+	policyMu sync.RWMutex
+	policy   = DefaultPasswordPolicy
+)
+
+// UsePasswordPolicy replaces the policy new passwords are checked against.
+func UsePasswordPolicy(p PasswordPolicy) {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	policy = p
+}
+
+func currentPasswordPolicy() PasswordPolicy {
+	policyMu.RLock()
+	defer policyMu.RUnlock()
+	return policy
+}
+
+// LoadPasswordPolicy builds the policy described by the PASSWORD_* configuration
+// and loads the breached password list from BreachedPasswordsPath when it is set.
+func LoadPasswordPolicy(cfg config.Config) (PasswordPolicy, error) {
+	p := PasswordPolicy{
+		MinLength:          int(cfg.PasswordMinLength),
+		MaxLength:          int(cfg.PasswordMaxLength),
+		RequireUpper:       cfg.PasswordRequireUpper,
+		RequireLower:       cfg.PasswordRequireLower,
+		RequireDigit:       cfg.PasswordRequireDigit,
+		RequireSymbol:      cfg.PasswordRequireSymbol,
+		RejectPersonalInfo: cfg.PasswordRejectPersonalInfo,
+	}
+
+	if cfg.BreachedPasswordsPath != "" {
+		breached, err := OpenBreachedPasswords(cfg.BreachedPasswordsPath)
+		if err != nil {
+			return p, err
+		}
+		p.Breached = breached
+	}
+
+	return p, nil
+}
+
+// CheckPassword checks a new password against the current policy. personal
+// holds the email address and names of the user. The returned
+// *customerrors.PasswordPolicyError lists every rule that is broken.
+func CheckPassword(password string, personal ...string) error {
+	return currentPasswordPolicy().Check(password, personal...)
+}
+
+func (p PasswordPolicy) Check(password string, personal ...string) error {
+	var violations []string
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d characters long", p.MaxLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		violations = append(violations, "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		violations = append(violations, "must contain a symbol")
+	}
+
+	if p.RejectPersonalInfo && containsPersonalInfo(password, personal) {
+		violations = append(violations, "must not contain your name or email address")
+	}
+
+	if p.Breached != nil {
+		breached, err := p.Breached.Contains(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			violations = append(violations, "appears in a list of breached passwords")
+		}
+	}
+
+	if len(violations) > 0 {
+		return &customerrors.PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// personalInfoMinLength skips short name parts like "Al", which would reject
+// too many unrelated passwords.
+const personalInfoMinLength = 3
+
+func containsPersonalInfo(password string, personal []string) bool {
+	password = strings.ToLower(password)
+
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+		// the domain of an email is shared with others and would reject
+		// passwords containing common words like "example" or "com"
+		if local, _, ok := strings.Cut(value, "@"); ok {
+			value = local
+		}
+		// also match the pieces of "jane.doe" or "Mary-Jane"
+		parts := append([]string{value}, strings.FieldsFunc(value, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})...)
+
+		for _, part := range parts {
+			if utf8.RuneCountInString(part) >= personalInfoMinLength && strings.Contains(password, part) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// BreachedPasswords tells whether a password is known from a data breach.
+type BreachedPasswords interface {
+	Contains(password string) (bool, error)
+}
+
+// OpenBreachedPasswords opens a list of SHA-1 password hashes in the format
+// published by Have I Been Pwned. path is either a single file with one
+// HASH[:COUNT] line per password, which is loaded into memory, or a directory
+// of range files named after the first five hex digits of the hash with
+// SUFFIX[:COUNT] lines, which are read on demand. Either way only the hash
+// prefix is used to find candidates, like the k-anonymity range API, and the
+// check works without network access.
+func OpenBreachedPasswords(path string) (BreachedPasswords, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %v", err)
+	}
+
+	if info.IsDir() {
+		return &breachedPasswordRanges{dir: path}, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %v", err)
+	}
+	defer f.Close()
+
+	return loadBreachedPasswordList(f)
+}
+
+// breachedPasswordList keeps the hash suffixes of an in-memory list by prefix.
+type breachedPasswordList struct {
+	ranges map[string]map[string]struct{}
+}
+
+func loadBreachedPasswordList(r io.Reader) (*breachedPasswordList, error) {
+	list := &breachedPasswordList{ranges: map[string]map[string]struct{}{}}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		hash := hashFromLine(scanner.Text())
+		if hash == "" {
+			continue
+		}
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("invalid breached password hash %q", hash)
+		}
+
+		prefix, suffix := hash[:5], hash[5:]
+		if list.ranges[prefix] == nil {
+			list.ranges[prefix] = map[string]struct{}{}
+		}
+		list.ranges[prefix][suffix] = struct{}{}
+	}
+
+	return list, scanner.Err()
+}
+
+func (l *breachedPasswordList) Contains(password string) (bool, error) {
+	prefix, suffix := passwordHashRange(password)
+	_, ok := l.ranges[prefix][suffix]
+	return ok, nil
+}
+
+// breachedPasswordRanges reads one range file per lookup.
+type breachedPasswordRanges struct {
+	dir string
+}
+
+func (d *breachedPasswordRanges) Contains(password string) (bool, error) {
+	prefix, suffix := passwordHashRange(password)
+
+	f, err := os.Open(filepath.Join(d.dir, prefix+".txt"))
+	if os.IsNotExist(err) {
+		f, err = os.Open(filepath.Join(d.dir, prefix))
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if hashFromLine(scanner.Text()) == suffix {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
+
+// passwordHashRange splits the uppercase hex SHA-1 of the password into the
+// five character range prefix and the remaining suffix.
+func passwordHashRange(password string) (prefix, suffix string) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	return hash[:5], hash[5:]
+}
+
+func hashFromLine(line string) string {
+	hash, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+	return strings.ToUpper(hash)
+}
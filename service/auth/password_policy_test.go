@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"errors"
+	"go-sample-rest-api/customerrors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func violations(err error) []string {
+	var policyErr *customerrors.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		return policyErr.Violations
+	}
+	return nil
+}
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	strict := PasswordPolicy{
+		MinLength:          10,
+		MaxLength:          20,
+		RequireUpper:       true,
+		RequireLower:       true,
+		RequireDigit:       true,
+		RequireSymbol:      true,
+		RejectPersonalInfo: true,
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		personal []string
+		expected []string
+	}{
+		{
+			name:     "accepts a password following every rule",
+			password: "Correct-Horse-42",
+			personal: []string{"jane.doe@example.com", "Jane", "Doe"},
+		},
+		{
+			name:     "lists every broken rule",
+			password: "jane",
+			personal: []string{"jane.doe@example.com", "Jane", "Doe"},
+			expected: []string{
+				"must be at least 10 characters long",
+				"must contain an uppercase letter",
+				"must contain a digit",
+				"must contain a symbol",
+				"must not contain your name or email address",
+			},
+		},
+		{
+			name:     "rejects parts of the email address",
+			password: "Xx-doe-12345",
+			personal: []string{"jane.doe@example.com"},
+			expected: []string{"must not contain your name or email address"},
+		},
+		{
+			name:     "ignores the email domain",
+			password: "Welcome2024!-com",
+			personal: []string{"jane@example.com"},
+		},
+		{
+			name:     "ignores very short name parts",
+			password: "Al-Pacino-1234",
+			personal: []string{"Al"},
+		},
+		{
+			name:     "rejects long passwords",
+			password: "Abcdefghij-1234567890",
+			expected: []string{"must be at most 20 characters long"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// act
+			err := strict.Check(tt.password, tt.personal...)
+
+			// assert
+			if !reflect.DeepEqual(violations(err), tt.expected) {
+				t.Errorf("unexpected violations: got %v want %v", violations(err), tt.expected)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicy_Breached(t *testing.T) {
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	t.Run("list file", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "breached.txt")
+		os.WriteFile(path, []byte("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\n7C4A8D09CA3762AF61E59520943DC26494F8941B:37359195\n"), 0o600)
+
+		breached, err := OpenBreachedPasswords(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		policy := PasswordPolicy{MinLength: 1, Breached: breached}
+
+		// act
+		knownErr := policy.Check("password")
+		unknownErr := policy.Check("correct horse battery staple")
+
+		// assert
+		if !reflect.DeepEqual(violations(knownErr), []string{"appears in a list of breached passwords"}) {
+			t.Errorf("expected the breached password to be rejected, got %v", knownErr)
+		}
+		if unknownErr != nil {
+			t.Errorf("expected an unknown password to pass, got %v", unknownErr)
+		}
+	})
+
+	t.Run("range directory", func(t *testing.T) {
+		// arrange
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, "5BAA6.txt"), []byte("1E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\r\n"), 0o600)
+
+		breached, err := OpenBreachedPasswords(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// act
+		known, _ := breached.Contains("password")
+		unknown, _ := breached.Contains("correct horse battery staple")
+
+		// assert
+		if !known || unknown {
+			t.Errorf("unexpected lookup result: known=%v unknown=%v", known, unknown)
+		}
+	})
+
+	t.Run("invalid list", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "breached.txt")
+		os.WriteFile(path, []byte("not-a-hash\n"), 0o600)
+
+		// act
+		_, err := OpenBreachedPasswords(path)
+
+		// assert
+		if err == nil || !strings.Contains(err.Error(), "invalid breached password hash") {
+			t.Errorf("expected an invalid hash error, got %v", err)
+		}
+	})
+}
@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"go-sample-rest-api/config"
+	"strings"
 	"testing"
 )
 
@@ -17,6 +19,10 @@ func TestHashPassword(t *testing.T) {
 	if hash == "password" {
 		t.Error("expected hash to be different from password")
 	}
+
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Errorf("expected an argon2id hash with the default parameters, got %s", hash)
+	}
 }
 
 func TestComparePasswords(t *testing.T) {
@@ -32,3 +38,96 @@ func TestComparePasswords(t *testing.T) {
 		t.Errorf("expected password to not match hash")
 	}
 }
+
+func TestComparePasswords_LongPassword(t *testing.T) {
+	// bcrypt would ignore everything after the first 72 bytes
+	long := strings.Repeat("a", 100)
+	hash, err := HashPassword(long)
+	if err != nil {
+		t.Fatalf("error hashing password: %v", err)
+	}
+
+	if ComparePasswords(hash, []byte(long[:72])) {
+		t.Errorf("expected a truncated password to not match")
+	}
+}
+
+func TestComparePasswords_Bcrypt(t *testing.T) {
+	hash, err := NewBcryptHasher(4).Hash("password")
+	if err != nil {
+		t.Fatalf("error hashing password: %v", err)
+	}
+
+	if !ComparePasswords(hash, []byte("password")) {
+		t.Errorf("expected password to match bcrypt hash")
+	}
+	if !NeedsRehash(hash) {
+		t.Errorf("expected bcrypt hash to need a rehash")
+	}
+}
+
+func TestComparePasswords_InvalidHash(t *testing.T) {
+	for _, hash := range []string{"", "password", "$argon2id$v=19$m=1,t=1,p=1$salt", "$argon2id$v=18$m=1,t=1,p=1$c2FsdA$a2V5"} {
+		if ComparePasswords(hash, []byte("password")) {
+			t.Errorf("expected %q to not match", hash)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	current, _ := HashPassword("password")
+	weaker, _ := NewArgon2idHasher(Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}).Hash("password")
+
+	if NeedsRehash(current) {
+		t.Errorf("expected a hash with the current parameters to be kept")
+	}
+	if !NeedsRehash(weaker) {
+		t.Errorf("expected a hash with outdated parameters to need a rehash")
+	}
+	if !ComparePasswords(weaker, []byte("password")) {
+		t.Errorf("expected a hash with outdated parameters to still verify")
+	}
+}
+
+func TestLoadHasher(t *testing.T) {
+	cfg := config.Config{PasswordHasher: "bcrypt", BcryptCost: 4}
+	h, err := LoadHasher(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := h.(*BcryptHasher); !ok {
+		t.Errorf("expected a bcrypt hasher, got %T", h)
+	}
+
+	if _, err := LoadHasher(config.Config{PasswordHasher: "md5"}); err == nil {
+		t.Errorf("expected an error for an unsupported hasher")
+	}
+}
+
+func TestLoadHasher_RejectsInvalidParameters(t *testing.T) {
+	valid := config.Config{PasswordHasher: "argon2id", Argon2MemoryInKiB: 19 * 1024, Argon2Iterations: 2, Argon2Parallelism: 1}
+	if _, err := LoadHasher(valid); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(cfg *config.Config)
+	}{
+		{name: "no parallelism", change: func(cfg *config.Config) { cfg.Argon2Parallelism = 0 }},
+		{name: "parallelism above 255", change: func(cfg *config.Config) { cfg.Argon2Parallelism = 256 }},
+		{name: "no iterations", change: func(cfg *config.Config) { cfg.Argon2Iterations = 0 }},
+		{name: "no memory", change: func(cfg *config.Config) { cfg.Argon2MemoryInKiB = 0 }},
+		{name: "memory above 32 bits", change: func(cfg *config.Config) { cfg.Argon2MemoryInKiB = 1 << 32 }},
+		{name: "bcrypt cost above the maximum", change: func(cfg *config.Config) { cfg.PasswordHasher, cfg.BcryptCost = "bcrypt", 32 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.change(&cfg)
+			if _, err := LoadHasher(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
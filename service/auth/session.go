@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"go-sample-rest-api/config"
+	"go-sample-rest-api/types"
+	"go-sample-rest-api/utils"
+	"net/http"
+)
+
+// maxUserAgentLength keeps arbitrary User-Agent headers from bloating the session table.
+const maxUserAgentLength = 512
+
+// StartSession records a new login of the user from the client of r and
+// returns the session ID to put into the access token with CreateJWT.
+func StartSession(store types.UserStore, r *http.Request, userID int) (string, error) {
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	session := types.Session{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		UserAgent: userAgent,
+		IP:        utils.ClientIP(r, config.Envs.TrustProxyHeaders),
+		ExpiresAt: SessionExpiry(),
+	}
+	if err := store.CreateSession(r.Context(), session); err != nil {
+		return "", err
+	}
+
+	return session.ID, nil
+}
+
+// ErrNoOrganization is returned by HomeOrganization for users without a membership.
+var ErrNoOrganization = fmt.Errorf("user is not a member of any organization")
+
+// HomeOrganization returns the organization a login acts in, which is the
+// oldest membership of the user.
+func HomeOrganization(ctx context.Context, store types.UserStore, userID int) (int, error) {
+	memberships, err := store.ListMemberships(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(memberships) == 0 {
+		return 0, ErrNoOrganization
+	}
+
+	return memberships[0].OrgID, nil
+}
+
+// IssueAccessToken logs the user in: it starts a session in the home
+// organization of the user and returns the access token for it.
+func IssueAccessToken(a Authenticator, store types.UserStore, r *http.Request, secret []byte, userID int) (string, error) {
+	orgID, err := HomeOrganization(r.Context(), store, userID)
+	if err != nil {
+		return "", err
+	}
+
+	sessionID, err := StartSession(store, r, userID)
+	if err != nil {
+		return "", err
+	}
+
+	return a.CreateJWT(secret, userID, orgID, sessionID)
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go-sample-rest-api/config"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 test key from RFC 6238 ("12345678901234567890"), base32 encoded.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// the RFC lists 8 digit codes, the last 6 digits are the 6 digit codes
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for unix, expected := range vectors {
+		code, err := TOTPCode(rfcSecret, time.Unix(unix, 0))
+		assert.Nil(t, err)
+		assert.Equal(t, expected, code, "time %d", unix)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code, _ := TOTPCode(rfcSecret, now)
+
+	t.Run("accepts the current code", func(t *testing.T) {
+		step, ok := ValidateTOTP(rfcSecret, code, now)
+		assert.True(t, ok)
+		assert.Equal(t, now.Unix()/30, step)
+	})
+
+	t.Run("accepts one period of clock drift", func(t *testing.T) {
+		_, ok := ValidateTOTP(rfcSecret, code, now.Add(30*time.Second))
+		assert.True(t, ok)
+	})
+
+	t.Run("rejects older codes", func(t *testing.T) {
+		_, ok := ValidateTOTP(rfcSecret, code, now.Add(2*time.Minute))
+		assert.False(t, ok)
+	})
+
+	t.Run("rejects malformed input", func(t *testing.T) {
+		_, ok := ValidateTOTP(rfcSecret, "12345", now)
+		assert.False(t, ok)
+		_, ok = ValidateTOTP("not base32!", code, now)
+		assert.False(t, ok)
+	})
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("Camera API", "jane@example.com", rfcSecret)
+
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Camera%20API:jane@example.com?"))
+	assert.Contains(t, uri, "secret="+rfcSecret)
+	assert.Contains(t, uri, "issuer=Camera+API")
+}
+
+func TestNewTOTPSecret(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	assert.Nil(t, err)
+	assert.Len(t, secret, 32)
+
+	_, err = TOTPCode(secret, time.Now())
+	assert.Nil(t, err)
+}
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, err := NewRecoveryCodes(10)
+	assert.Nil(t, err)
+	assert.Len(t, codes, 10)
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, code)
+		assert.False(t, seen[code], "recovery codes must be unique")
+		seen[code] = true
+	}
+
+	assert.Equal(t, "abcde23456", NormalizeRecoveryCode("ABCDE-23456"))
+}
+
+func TestMFAChallenge(t *testing.T) {
+	secret := []byte(config.Envs.JWTSecret)
+
+	challenge, err := CreateMFAChallenge(secret, 5)
+	assert.Nil(t, err)
+
+	userID, err := ValidateMFAChallenge(challenge)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, userID)
+
+	_, err = validateJWTDefault(challenge)
+	assert.NotNil(t, err, "a challenge must not be accepted as an access token")
+
+	accessToken, err := CreateJWT(secret, 5, memberOrgID, "session-5")
+	assert.Nil(t, err)
+	_, err = ValidateMFAChallenge(accessToken)
+	assert.NotNil(t, err, "an access token must not be accepted as a challenge")
+}
@@ -0,0 +1,45 @@
+package cameragroup
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/types"
+)
+
+type mockCameraGroupStore struct {
+	mock.Mock
+}
+
+func (m *mockCameraGroupStore) CreateCameraGroup(ctx context.Context, group types.CameraGroup, entry types.AuditEntry) (*types.CameraGroup, error) {
+	args := m.Called(ctx, group, entry)
+	if g := args.Get(0); g != nil {
+		return g.(*types.CameraGroup), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockCameraGroupStore) GetCameraGroup(ctx context.Context, orgID, groupID int) (*types.CameraGroup, error) {
+	args := m.Called(ctx, orgID, groupID)
+	if g := args.Get(0); g != nil {
+		return g.(*types.CameraGroup), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockCameraGroupStore) ListCameraGroups(ctx context.Context, orgID int) ([]types.CameraGroup, error) {
+	args := m.Called(ctx, orgID)
+	if g := args.Get(0); g != nil {
+		return g.([]types.CameraGroup), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockCameraGroupStore) UpdateCameraGroup(ctx context.Context, group types.CameraGroup, entry types.AuditEntry) error {
+	args := m.Called(ctx, group, entry)
+	return args.Error(0)
+}
+
+func (m *mockCameraGroupStore) DeleteCameraGroup(ctx context.Context, orgID, groupID int, entry types.AuditEntry) error {
+	args := m.Called(ctx, orgID, groupID, entry)
+	return args.Error(0)
+}
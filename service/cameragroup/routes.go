@@ -0,0 +1,228 @@
+package cameragroup
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"go-sample-rest-api/audit"
+	"go-sample-rest-api/customerrors"
+	auth2 "go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"go-sample-rest-api/utils"
+	"net/http"
+	"strconv"
+)
+
+// Actions of the audit entries of camera groups.
+const (
+	ActionCreateGroup = "camera_group.create"
+	ActionUpdateGroup = "camera_group.update"
+	ActionDeleteGroup = "camera_group.delete"
+)
+
+// Handler serves the camera groups of the organization of the access token.
+// Every member can read them; only admins change them.
+type Handler struct {
+	store     types.CameraGroupStore
+	userStore types.UserStore
+}
+
+func NewHandler(store types.CameraGroupStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, userStore: userStore}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/camera_groups", auth2.WithJWTAuth(h.handleListGroups, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/camera_groups", auth2.WithAdmin(h.handleCreateGroup, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/camera_groups/{groupID}", auth2.WithJWTAuth(h.handleGetGroup, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/camera_groups/{groupID}", auth2.WithAdmin(h.handleUpdateGroup, h.userStore)).Methods(http.MethodPatch)
+	router.HandleFunc("/camera_groups/{groupID}", auth2.WithAdmin(h.handleDeleteGroup, h.userStore)).Methods(http.MethodDelete)
+}
+
+// handleListGroups godoc
+// @Summary List camera groups
+// @Description Returns the camera groups of the organization, ordered by name.
+// @Tags camera groups
+// @Produce json
+// @Success 200 {array} types.CameraGroup "The groups."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /camera_groups [get]
+func (h *Handler) handleListGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.store.ListCameraGroups(r.Context(), auth2.GetOrgIDFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, groups)
+}
+
+// handleCreateGroup godoc
+// @Summary Create a camera group
+// @Description Creates a group, such as a site, in the organization. Names are unique per organization. Admins only.
+// @Tags camera groups
+// @Accept json
+// @Produce json
+// @Param group body types.CameraGroupPayload true "Group"
+// @Success 201 {object} types.CameraGroup "The new group."
+// @Failure 400 {object} types.HTTPError "Bad Request when the payload is invalid."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 403 {object} types.HTTPError "Forbidden if the user is not an admin."
+// @Failure 409 {object} types.HTTPError "Conflict if the organization has a group of that name."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /camera_groups [post]
+func (h *Handler) handleCreateGroup(w http.ResponseWriter, r *http.Request) {
+	payload, ok := parsePayload(w, r)
+	if !ok {
+		return
+	}
+
+	group, err := h.store.CreateCameraGroup(r.Context(), types.CameraGroup{
+		OrgID:       auth2.GetOrgIDFromContext(r.Context()),
+		Name:        payload.Name,
+		Description: payload.Description,
+	}, audit.FromRequest(r, ActionCreateGroup))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, group)
+}
+
+// handleGetGroup godoc
+// @Summary Get a camera group
+// @Tags camera groups
+// @Produce json
+// @Param groupID path int true "Group ID"
+// @Success 200 {object} types.CameraGroup "The group."
+// @Failure 400 {object} types.HTTPError "Bad Request when the ID is invalid."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 404 {object} types.HTTPError "Not Found if the organization has no such group."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /camera_groups/{groupID} [get]
+func (h *Handler) handleGetGroup(w http.ResponseWriter, r *http.Request) {
+	groupID, ok := parseGroupID(w, r)
+	if !ok {
+		return
+	}
+
+	group, err := h.store.GetCameraGroup(r.Context(), auth2.GetOrgIDFromContext(r.Context()), groupID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, group)
+}
+
+// handleUpdateGroup godoc
+// @Summary Update a camera group
+// @Description Changes the name and description of a group. Admins only.
+// @Tags camera groups
+// @Accept json
+// @Produce json
+// @Param groupID path int true "Group ID"
+// @Param group body types.CameraGroupPayload true "Group"
+// @Success 200 {object} types.CameraGroup "The updated group."
+// @Failure 400 {object} types.HTTPError "Bad Request when the ID or payload is invalid."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 403 {object} types.HTTPError "Forbidden if the user is not an admin."
+// @Failure 404 {object} types.HTTPError "Not Found if the organization has no such group."
+// @Failure 409 {object} types.HTTPError "Conflict if the organization has another group of that name."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /camera_groups/{groupID} [patch]
+func (h *Handler) handleUpdateGroup(w http.ResponseWriter, r *http.Request) {
+	groupID, ok := parseGroupID(w, r)
+	if !ok {
+		return
+	}
+
+	payload, ok := parsePayload(w, r)
+	if !ok {
+		return
+	}
+
+	orgID := auth2.GetOrgIDFromContext(r.Context())
+	err := h.store.UpdateCameraGroup(r.Context(), types.CameraGroup{
+		ID:          groupID,
+		OrgID:       orgID,
+		Name:        payload.Name,
+		Description: payload.Description,
+	}, audit.FromRequest(r, ActionUpdateGroup))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	h.handleGetGroup(w, r)
+}
+
+// handleDeleteGroup godoc
+// @Summary Delete a camera group
+// @Description Deletes a group. Its cameras are kept and no longer belong to a group. Admins only.
+// @Tags camera groups
+// @Param groupID path int true "Group ID"
+// @Success 204 "The group was deleted."
+// @Failure 400 {object} types.HTTPError "Bad Request when the ID is invalid."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 403 {object} types.HTTPError "Forbidden if the user is not an admin."
+// @Failure 404 {object} types.HTTPError "Not Found if the organization has no such group."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /camera_groups/{groupID} [delete]
+func (h *Handler) handleDeleteGroup(w http.ResponseWriter, r *http.Request) {
+	groupID, ok := parseGroupID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.DeleteCameraGroup(r.Context(), auth2.GetOrgIDFromContext(r.Context()), groupID, audit.FromRequest(r, ActionDeleteGroup)); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func parseGroupID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	groupID, err := strconv.Atoi(mux.Vars(r)["groupID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid group ID"))
+		return 0, false
+	}
+
+	return groupID, true
+}
+
+func parsePayload(w http.ResponseWriter, r *http.Request) (types.CameraGroupPayload, bool) {
+	var payload types.CameraGroupPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return payload, false
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", errors))
+		return payload, false
+	}
+
+	return payload, true
+}
+
+// writeStoreError answers 404 for a missing group, 409 for a duplicate name
+// and 500 for anything else.
+func writeStoreError(w http.ResponseWriter, err error) {
+	var notFound *customerrors.NotFoundError
+	var exists *customerrors.AlreadyExistsError
+	switch {
+	case errors.As(err, &notFound):
+		utils.WriteError(w, http.StatusNotFound, err)
+	case errors.As(err, &exists):
+		utils.WriteError(w, http.StatusConflict, err)
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
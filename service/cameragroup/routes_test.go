@@ -0,0 +1,203 @@
+package cameragroup
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/customerrors"
+	"go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const orgID = 3
+
+func withOrganization(req *http.Request) *http.Request {
+	ctx := context.WithValue(req.Context(), auth.UserKey, 1)
+	return req.WithContext(context.WithValue(ctx, auth.OrgKey, orgID))
+}
+
+func groupsRouter(handler *Handler) *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/camera_groups", handler.handleListGroups).Methods(http.MethodGet)
+	router.HandleFunc("/camera_groups", handler.handleCreateGroup).Methods(http.MethodPost)
+	router.HandleFunc("/camera_groups/{groupID}", handler.handleGetGroup).Methods(http.MethodGet)
+	router.HandleFunc("/camera_groups/{groupID}", handler.handleUpdateGroup).Methods(http.MethodPatch)
+	router.HandleFunc("/camera_groups/{groupID}", handler.handleDeleteGroup).Methods(http.MethodDelete)
+	return router
+}
+
+func TestCameraGroupService_Handle_List(t *testing.T) {
+	t.Run("lists the groups of the organization", func(t *testing.T) {
+		// arrange
+		store := new(mockCameraGroupStore)
+		handler := NewHandler(store, nil)
+
+		store.On("ListCameraGroups", mock.Anything, orgID).Return([]types.CameraGroup{{ID: 1, OrgID: orgID, Name: "Berlin"}}, nil)
+
+		req, _ := http.NewRequest(http.MethodGet, "/camera_groups", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		groupsRouter(handler).ServeHTTP(rr, withOrganization(req))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		var groups []types.CameraGroup
+		json.Unmarshal(rr.Body.Bytes(), &groups)
+		if len(groups) != 1 || groups[0].Name != "Berlin" {
+			t.Errorf("unexpected groups: %+v", groups)
+		}
+	})
+}
+
+func TestCameraGroupService_Handle_Create(t *testing.T) {
+	t.Run("creates the group in the organization of the token", func(t *testing.T) {
+		// arrange
+		store := new(mockCameraGroupStore)
+		handler := NewHandler(store, nil)
+
+		store.On("CreateCameraGroup", mock.Anything, types.CameraGroup{OrgID: orgID, Name: "Berlin", Description: "HQ"}, mock.Anything).
+			Return(&types.CameraGroup{ID: 7, OrgID: orgID, Name: "Berlin", Description: "HQ"}, nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/camera_groups", strings.NewReader(`{"name":"Berlin","description":"HQ"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		groupsRouter(handler).ServeHTTP(rr, withOrganization(req))
+
+		// assert
+		if status := rr.Code; status != http.StatusCreated {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusCreated)
+		}
+		store.AssertExpectations(t)
+	})
+
+	t.Run("rejects a group without a name", func(t *testing.T) {
+		// arrange
+		handler := NewHandler(new(mockCameraGroupStore), nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/camera_groups", strings.NewReader(`{"description":"HQ"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		groupsRouter(handler).ServeHTTP(rr, withOrganization(req))
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("name is taken", func(t *testing.T) {
+		// arrange
+		store := new(mockCameraGroupStore)
+		handler := NewHandler(store, nil)
+
+		store.On("CreateCameraGroup", mock.Anything, types.CameraGroup{OrgID: orgID, Name: "Berlin"}, mock.Anything).
+			Return(nil, &customerrors.AlreadyExistsError{Name: "Berlin"})
+
+		req, _ := http.NewRequest(http.MethodPost, "/camera_groups", strings.NewReader(`{"name":"Berlin"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		groupsRouter(handler).ServeHTTP(rr, withOrganization(req))
+
+		// assert
+		if status := rr.Code; status != http.StatusConflict {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusConflict)
+		}
+	})
+}
+
+func TestCameraGroupService_Handle_Get(t *testing.T) {
+	t.Run("group of another organization", func(t *testing.T) {
+		// arrange
+		store := new(mockCameraGroupStore)
+		handler := NewHandler(store, nil)
+
+		store.On("GetCameraGroup", mock.Anything, orgID, 9).Return(nil, &customerrors.NotFoundError{ID: "9"})
+
+		req, _ := http.NewRequest(http.MethodGet, "/camera_groups/9", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		groupsRouter(handler).ServeHTTP(rr, withOrganization(req))
+
+		// assert
+		if status := rr.Code; status != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+		}
+	})
+
+	t.Run("invalid group ID", func(t *testing.T) {
+		// arrange
+		handler := NewHandler(new(mockCameraGroupStore), nil)
+
+		req, _ := http.NewRequest(http.MethodGet, "/camera_groups/berlin", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		groupsRouter(handler).ServeHTTP(rr, withOrganization(req))
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestCameraGroupService_Handle_Update(t *testing.T) {
+	t.Run("renames the group", func(t *testing.T) {
+		// arrange
+		store := new(mockCameraGroupStore)
+		handler := NewHandler(store, nil)
+
+		store.On("UpdateCameraGroup", mock.Anything, types.CameraGroup{ID: 7, OrgID: orgID, Name: "Munich"}, mock.Anything).Return(nil)
+		store.On("GetCameraGroup", mock.Anything, orgID, 7).Return(&types.CameraGroup{ID: 7, OrgID: orgID, Name: "Munich"}, nil)
+
+		req, _ := http.NewRequest(http.MethodPatch, "/camera_groups/7", strings.NewReader(`{"name":"Munich"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		groupsRouter(handler).ServeHTTP(rr, withOrganization(req))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		var group types.CameraGroup
+		json.Unmarshal(rr.Body.Bytes(), &group)
+		if group.Name != "Munich" {
+			t.Errorf("unexpected group: %+v", group)
+		}
+	})
+}
+
+func TestCameraGroupService_Handle_Delete(t *testing.T) {
+	t.Run("deletes the group", func(t *testing.T) {
+		// arrange
+		store := new(mockCameraGroupStore)
+		handler := NewHandler(store, nil)
+
+		store.On("DeleteCameraGroup", mock.Anything, orgID, 7, mock.Anything).Return(nil)
+
+		req, _ := http.NewRequest(http.MethodDelete, "/camera_groups/7", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		groupsRouter(handler).ServeHTTP(rr, withOrganization(req))
+
+		// assert
+		if status := rr.Code; status != http.StatusNoContent {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNoContent)
+		}
+		store.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,187 @@
+package cameragroup
+
+import (
+	"context"
+	"database/sql"
+	"github.com/sirupsen/logrus"
+	"go-sample-rest-api/audit"
+	"go-sample-rest-api/customerrors"
+	"go-sample-rest-api/db"
+	"go-sample-rest-api/logging"
+	"go-sample-rest-api/types"
+	"strconv"
+)
+
+const groupColumns = "id, org_id, name, description, created_at"
+
+type Store struct {
+	db db.DB
+}
+
+func NewStore(db db.DB) *Store {
+	return &Store{db: db}
+}
+
+// CreateCameraGroup saves the group. It fails with an AlreadyExistsError when
+// the organization has a group of the same name.
+func (s *Store) CreateCameraGroup(ctx context.Context, group types.CameraGroup, entry types.AuditEntry) (*types.CameraGroup, error) {
+	log := logging.GetLogger()
+	g := new(types.CameraGroup)
+	err := s.db.WithTx(ctx, func(tx db.DB) error {
+		err := tx.QueryRowContext(ctx, "INSERT INTO camera_groups (org_id, name, description) VALUES ($1, $2, $3) RETURNING "+groupColumns,
+			group.OrgID, group.Name, group.Description).
+			Scan(&g.ID, &g.OrgID, &g.Name, &g.Description, &g.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		return writeAudit(ctx, tx, entry, *g, nil, auditState(*g))
+	})
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, &customerrors.AlreadyExistsError{Name: group.Name}
+		}
+		log.WithFields(logrus.Fields{
+			"orgID": group.OrgID,
+			"error": err,
+		}).Error("Error creating camera group")
+		return nil, err
+	}
+
+	log.WithFields(logrus.Fields{
+		"orgID":   g.OrgID,
+		"groupID": g.ID,
+	}).Info("Camera group created")
+	return g, nil
+}
+
+func (s *Store) GetCameraGroup(ctx context.Context, orgID, groupID int) (*types.CameraGroup, error) {
+	g := new(types.CameraGroup)
+	err := s.db.QueryRowContext(ctx, "SELECT "+groupColumns+" FROM camera_groups WHERE id = $1 AND org_id = $2", groupID, orgID).
+		Scan(&g.ID, &g.OrgID, &g.Name, &g.Description, &g.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &customerrors.NotFoundError{ID: strconv.Itoa(groupID)}
+		}
+		logging.GetLogger().WithFields(logrus.Fields{
+			"orgID":   orgID,
+			"groupID": groupID,
+			"error":   err,
+		}).Error("Error retrieving camera group")
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// ListCameraGroups returns the groups of the organization ordered by name.
+func (s *Store) ListCameraGroups(ctx context.Context, orgID int) ([]types.CameraGroup, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+groupColumns+" FROM camera_groups WHERE org_id = $1 ORDER BY name", orgID)
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"orgID": orgID,
+			"error": err,
+		}).Error("Error listing camera groups")
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := make([]types.CameraGroup, 0)
+	for rows.Next() {
+		var g types.CameraGroup
+		if err := rows.Scan(&g.ID, &g.OrgID, &g.Name, &g.Description, &g.CreatedAt); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+
+	return groups, rows.Err()
+}
+
+// UpdateCameraGroup saves the name and description of the group.
+func (s *Store) UpdateCameraGroup(ctx context.Context, group types.CameraGroup, entry types.AuditEntry) error {
+	log := logging.GetLogger()
+	err := s.db.WithTx(ctx, func(tx db.DB) error {
+		var before types.CameraGroup
+		err := tx.QueryRowContext(ctx, "SELECT "+groupColumns+" FROM camera_groups WHERE id = $1 AND org_id = $2 FOR UPDATE", group.ID, group.OrgID).
+			Scan(&before.ID, &before.OrgID, &before.Name, &before.Description, &before.CreatedAt)
+		if err == sql.ErrNoRows {
+			return &customerrors.NotFoundError{ID: strconv.Itoa(group.ID)}
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE camera_groups SET name = $1, description = $2 WHERE id = $3 AND org_id = $4",
+			group.Name, group.Description, group.ID, group.OrgID); err != nil {
+			return err
+		}
+
+		return writeAudit(ctx, tx, entry, before, auditState(before), auditState(group))
+	})
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return &customerrors.AlreadyExistsError{Name: group.Name}
+		}
+		log.WithFields(logrus.Fields{
+			"orgID":   group.OrgID,
+			"groupID": group.ID,
+			"error":   err,
+		}).Error("Error updating camera group")
+		return err
+	}
+
+	log.WithFields(logrus.Fields{
+		"orgID":   group.OrgID,
+		"groupID": group.ID,
+	}).Info("Camera group updated")
+	return nil
+}
+
+// DeleteCameraGroup removes the group. Its cameras stay and become ungrouped.
+func (s *Store) DeleteCameraGroup(ctx context.Context, orgID, groupID int, entry types.AuditEntry) error {
+	log := logging.GetLogger()
+	err := s.db.WithTx(ctx, func(tx db.DB) error {
+		var before types.CameraGroup
+		err := tx.QueryRowContext(ctx, "DELETE FROM camera_groups WHERE id = $1 AND org_id = $2 RETURNING "+groupColumns, groupID, orgID).
+			Scan(&before.ID, &before.OrgID, &before.Name, &before.Description, &before.CreatedAt)
+		if err == sql.ErrNoRows {
+			return &customerrors.NotFoundError{ID: strconv.Itoa(groupID)}
+		}
+		if err != nil {
+			return err
+		}
+
+		return writeAudit(ctx, tx, entry, before, auditState(before), nil)
+	})
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"orgID":   orgID,
+			"groupID": groupID,
+			"error":   err,
+		}).Error("Error deleting camera group")
+		return err
+	}
+
+	log.WithFields(logrus.Fields{
+		"orgID":   orgID,
+		"groupID": groupID,
+	}).Info("Camera group deleted")
+	return nil
+}
+
+func writeAudit(ctx context.Context, tx db.DB, entry types.AuditEntry, group types.CameraGroup, before, after map[string]any) error {
+	entry.OrgID = group.OrgID
+	entry.ResourceType = "camera_group"
+	entry.ResourceID = strconv.Itoa(group.ID)
+	entry.Changes = audit.Changes(before, after)
+	return audit.Write(ctx, tx, entry)
+}
+
+// auditState returns the fields of a group that the audit log compares.
+func auditState(g types.CameraGroup) map[string]any {
+	return map[string]any{
+		"name":        g.Name,
+		"description": g.Description,
+	}
+}
@@ -0,0 +1,170 @@
+package cameragroup
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"go-sample-rest-api/customerrors"
+	db2 "go-sample-rest-api/db"
+	"go-sample-rest-api/types"
+	"testing"
+	"time"
+)
+
+var groupRowColumns = []string{"id", "org_id", "name", "description", "created_at"}
+
+func setupMockDB(t *testing.T) (*db2.SQLDB, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	return db2.NewSQLDB(db), mock, func() { db.Close() }
+}
+
+func TestStore_CreateCameraGroup(t *testing.T) {
+	t.Run("CreateCameraGroup_withNewName_returnsGroup", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`^INSERT INTO camera_groups \(org_id, name, description\) VALUES \(\$1, \$2, \$3\) RETURNING`).
+			WithArgs(3, "Berlin", "HQ").
+			WillReturnRows(sqlmock.NewRows(groupRowColumns).AddRow(7, 3, "Berlin", "HQ", time.Now()))
+		mock.ExpectExec(`^INSERT INTO audit_log`).
+			WithArgs(3, "user", "1", "camera_group.create", "camera_group", "7", sqlmock.AnyArg(), "", "").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		// act
+		group, err := store.CreateCameraGroup(context.Background(), types.CameraGroup{OrgID: 3, Name: "Berlin", Description: "HQ"},
+			types.AuditEntry{ActorType: "user", ActorID: "1", Action: "camera_group.create"})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 7, group.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("CreateCameraGroup_withTakenName_returnsAlreadyExists", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`^INSERT INTO camera_groups`).
+			WillReturnError(&pq.Error{Code: "23505"})
+		mock.ExpectRollback()
+
+		// act
+		_, err := store.CreateCameraGroup(context.Background(), types.CameraGroup{OrgID: 3, Name: "Berlin"}, types.AuditEntry{})
+
+		// assert
+		var exists *customerrors.AlreadyExistsError
+		assert.ErrorAs(t, err, &exists)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStore_ListCameraGroups(t *testing.T) {
+	t.Run("ListCameraGroups_returnsGroupsOfOrganization", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		mock.ExpectQuery(`^SELECT id, org_id, name, description, created_at FROM camera_groups WHERE org_id = \$1 ORDER BY name$`).
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows(groupRowColumns).
+				AddRow(7, 3, "Berlin", "", time.Now()).
+				AddRow(8, 3, "Munich", "", time.Now()))
+
+		// act
+		groups, err := store.ListCameraGroups(context.Background(), 3)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Len(t, groups, 2)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStore_UpdateCameraGroup(t *testing.T) {
+	t.Run("UpdateCameraGroup_ofOtherOrganization_returnsNotFound", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`^SELECT id, org_id, name, description, created_at FROM camera_groups WHERE id = \$1 AND org_id = \$2 FOR UPDATE$`).
+			WithArgs(7, 3).
+			WillReturnRows(sqlmock.NewRows(groupRowColumns))
+		mock.ExpectRollback()
+
+		// act
+		err := store.UpdateCameraGroup(context.Background(), types.CameraGroup{ID: 7, OrgID: 3, Name: "Munich"}, types.AuditEntry{})
+
+		// assert
+		var notFound *customerrors.NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("UpdateCameraGroup_withNewName_recordsChange", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`^SELECT id, org_id, name, description, created_at FROM camera_groups WHERE id = \$1 AND org_id = \$2 FOR UPDATE$`).
+			WithArgs(7, 3).
+			WillReturnRows(sqlmock.NewRows(groupRowColumns).AddRow(7, 3, "Berlin", "", time.Now()))
+		mock.ExpectExec(`^UPDATE camera_groups SET name = \$1, description = \$2 WHERE id = \$3 AND org_id = \$4$`).
+			WithArgs("Munich", "", 7, 3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`^INSERT INTO audit_log`).
+			WithArgs(3, "user", "1", "camera_group.update", "camera_group", "7",
+				types.AuditChanges{"name": {Before: "Berlin", After: "Munich"}}, "", "").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		// act
+		err := store.UpdateCameraGroup(context.Background(), types.CameraGroup{ID: 7, OrgID: 3, Name: "Munich"},
+			types.AuditEntry{ActorType: "user", ActorID: "1", Action: "camera_group.update"})
+
+		// assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStore_DeleteCameraGroup(t *testing.T) {
+	t.Run("DeleteCameraGroup_withKnownID_deletesIt", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`^DELETE FROM camera_groups WHERE id = \$1 AND org_id = \$2 RETURNING`).
+			WithArgs(7, 3).
+			WillReturnRows(sqlmock.NewRows(groupRowColumns).AddRow(7, 3, "Berlin", "", time.Now()))
+		mock.ExpectExec(`^INSERT INTO audit_log`).
+			WithArgs(3, "user", "1", "camera_group.delete", "camera_group", "7", sqlmock.AnyArg(), "", "").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		// act
+		err := store.DeleteCameraGroup(context.Background(), 3, 7, types.AuditEntry{ActorType: "user", ActorID: "1", Action: "camera_group.delete"})
+
+		// assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
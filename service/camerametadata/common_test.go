@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/storage"
 	"go-sample-rest-api/types"
 	"net/http"
 )
@@ -14,8 +15,8 @@ type MockCameraStore struct {
 	mock.Mock
 }
 
-func (m *MockCameraStore) CreateCameraMetadata(c types.CameraMetadata) (*types.CameraMetadata, error) {
-	args := m.Called(c)
+func (m *MockCameraStore) CreateCameraMetadata(ctx context.Context, c types.CameraMetadata, entry types.AuditEntry) (*types.CameraMetadata, error) {
+	args := m.Called(ctx, c, entry)
 	if args.Error(1) != nil {
 		return nil, args.Error(1)
 	}
@@ -23,16 +24,27 @@ func (m *MockCameraStore) CreateCameraMetadata(c types.CameraMetadata) (*types.C
 	return args.Get(0).(*types.CameraMetadata), args.Error(1)
 }
 
-func (m *MockCameraStore) UpdateCameraMetadata(c types.CameraMetadata) (*types.CameraMetadata, error) {
-	args := m.Called(c)
+func (m *MockCameraStore) UpdateCameraMetadata(ctx context.Context, orgID int, camID string, change func(c *types.CameraMetadata) error, entry types.AuditEntry) (*types.CameraMetadata, error) {
+	args := m.Called(ctx, orgID, camID, entry)
 	if args.Error(1) != nil {
 		return nil, args.Error(1)
 	}
 
-	return args.Get(0).(*types.CameraMetadata), args.Error(1)
+	// the returned camera is the locked row, which change modifies in place
+	camera := args.Get(0).(*types.CameraMetadata)
+	if err := change(camera); err != nil {
+		return nil, err
+	}
+
+	return camera, nil
 }
-func (m *MockCameraStore) GetCameraMetadataByID(c string) (*types.CameraMetadata, error) {
-	args := m.Called(c)
+
+func (m *MockCameraStore) ClaimImageID(ctx context.Context, imageID, camID string) (bool, error) {
+	args := m.Called(ctx, imageID, camID)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockCameraStore) GetCameraMetadataByID(ctx context.Context, orgID int, c string) (*types.CameraMetadata, error) {
+	args := m.Called(ctx, orgID, c)
 	if args.Error(1) != nil {
 		return nil, args.Error(1)
 	}
@@ -40,6 +52,46 @@ func (m *MockCameraStore) GetCameraMetadataByID(c string) (*types.CameraMetadata
 	return args.Get(0).(*types.CameraMetadata), args.Error(1)
 }
 
+func (m *MockCameraStore) ListCameraMetadataByOwner(ctx context.Context, orgID, ownerID int) ([]types.CameraMetadata, error) {
+	args := m.Called(ctx, orgID, ownerID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).([]types.CameraMetadata), args.Error(1)
+}
+
+func (m *MockCameraStore) ReassignCameraMetadata(ctx context.Context, orgID, fromOwnerID, toOwnerID int, entry types.AuditEntry) error {
+	args := m.Called(ctx, orgID, fromOwnerID, toOwnerID, entry)
+	return args.Error(0)
+}
+
+func (m *MockCameraStore) DeleteCameraMetadata(ctx context.Context, orgID int, camID string, entry types.AuditEntry) error {
+	args := m.Called(ctx, orgID, camID, entry)
+	return args.Error(0)
+}
+
+func (m *MockCameraStore) GetUsage(ctx context.Context, orgID int) (*types.Usage, error) {
+	args := m.Called(ctx, orgID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.Usage), args.Error(1)
+}
+
+func (m *MockCameraStore) ListCameraMetadata(ctx context.Context, orgID int, filter types.CameraFilter) ([]types.CameraMetadata, int, error) {
+	args := m.Called(ctx, orgID, filter)
+	if args.Error(2) != nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]types.CameraMetadata), args.Int(1), args.Error(2)
+}
+
+func (m *MockCameraStore) BulkUpdateCameraMetadata(ctx context.Context, orgID int, filter types.CameraFilter, update types.CameraBulkUpdate, entry types.AuditEntry) (int64, error) {
+	args := m.Called(ctx, orgID, filter, update, entry)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 type MockAzureStorage struct {
 	mock.Mock
 }
@@ -57,6 +109,19 @@ func (m *MockAzureStorage) DownloadImage(ctx context.Context, blobName string) (
 	return args.Get(0).([]byte), args.Error(1)
 }
 
+func (m *MockAzureStorage) DeleteImage(ctx context.Context, blobName string) error {
+	args := m.Called(ctx, blobName)
+	return args.Error(0)
+}
+
+func (m *MockAzureStorage) ListImages(ctx context.Context) ([]storage.ImageInfo, error) {
+	args := m.Called(ctx)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]storage.ImageInfo), args.Error(1)
+}
+
 type FailWriter struct {
 	http.ResponseWriter
 	fail bool
@@ -68,3 +133,17 @@ func (fw *FailWriter) Write(data []byte) (int, error) {
 	}
 	return fw.ResponseWriter.Write(data)
 }
+
+// MockCameraGroupStore only answers GetCameraGroup; the handlers use no other method.
+type MockCameraGroupStore struct {
+	mock.Mock
+	types.CameraGroupStore
+}
+
+func (m *MockCameraGroupStore) GetCameraGroup(ctx context.Context, orgID, groupID int) (*types.CameraGroup, error) {
+	args := m.Called(ctx, orgID, groupID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.CameraGroup), args.Error(1)
+}
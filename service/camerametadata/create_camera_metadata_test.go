@@ -2,12 +2,14 @@ package camerametadata
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/service/auth"
 	"go-sample-rest-api/types"
 	"net/http"
 	"net/http/httptest"
@@ -20,7 +22,7 @@ func TestHandler_CreateCameraMetadata(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		payload := types.CameraMetadataPayload{
 			CameraName:      "camera-name",
@@ -39,8 +41,8 @@ func TestHandler_CreateCameraMetadata(t *testing.T) {
 		}
 
 		var capturedArg types.CameraMetadata
-		mockCameraStore.On("CreateCameraMetadata", mock.AnythingOfType("types.CameraMetadata")).Run(func(args mock.Arguments) {
-			capturedArg = args.Get(0).(types.CameraMetadata)
+		mockCameraStore.On("CreateCameraMetadata", mock.Anything, mock.AnythingOfType("types.CameraMetadata"), mock.Anything).Run(func(args mock.Arguments) {
+			capturedArg = args.Get(1).(types.CameraMetadata)
 		}).Return(&expectedCamera, nil)
 
 		cameraData, err := json.Marshal(payload)
@@ -79,7 +81,7 @@ func TestHandler_CreateCameraMetadata(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 		// Act
 		req, err := http.NewRequest(http.MethodPost, "/camera_metadata", bytes.NewBufferString("{invalid json"))
 		if err != nil {
@@ -101,7 +103,7 @@ func TestHandler_CreateCameraMetadata(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		payload := types.CameraMetadataPayload{
 			CameraName:      "",
@@ -131,14 +133,14 @@ func TestHandler_CreateCameraMetadata(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		payload := types.CameraMetadataPayload{
 			CameraName:      "camera-name",
 			FirmwareVersion: "v123",
 		}
 
-		mockCameraStore.On("CreateCameraMetadata", mock.AnythingOfType("types.CameraMetadata")).Return(nil, fmt.Errorf("DB error"))
+		mockCameraStore.On("CreateCameraMetadata", mock.Anything, mock.AnythingOfType("types.CameraMetadata"), mock.Anything).Return(nil, fmt.Errorf("DB error"))
 
 		cameraData, err := json.Marshal(payload)
 		if err != nil {
@@ -164,3 +166,37 @@ func TestHandler_CreateCameraMetadata(t *testing.T) {
 		mockCameraStore.AssertExpectations(t)
 	})
 }
+
+func TestHandler_CreateCameraMetadata_setsOwnerAndOrganization(t *testing.T) {
+	// arrange
+	mockCameraStore := new(MockCameraStore)
+	handler := NewHandler(mockCameraStore, nil, new(MockAzureStorage), nil)
+
+	var capturedArg types.CameraMetadata
+	mockCameraStore.On("CreateCameraMetadata", mock.Anything, mock.AnythingOfType("types.CameraMetadata"), mock.Anything).Run(func(args mock.Arguments) {
+		capturedArg = args.Get(1).(types.CameraMetadata)
+	}).Return(&types.CameraMetadata{CamID: uuid.New().String()}, nil)
+
+	cameraData, _ := json.Marshal(types.CameraMetadataPayload{CameraName: "camera-name", FirmwareVersion: "v123"})
+	req, err := http.NewRequest(http.MethodPost, "/camera_metadata", bytes.NewBuffer(cameraData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.WithValue(req.Context(), auth.UserKey, 7)
+	req = req.WithContext(context.WithValue(ctx, auth.OrgKey, 3))
+	rr := httptest.NewRecorder()
+
+	// act
+	handler.CreateCameraMetadata(rr, req)
+
+	// assert
+	if rr.Code != http.StatusCreated {
+		t.Errorf("expected status code %d, got %d", http.StatusCreated, rr.Code)
+	}
+	if !capturedArg.OwnerID.Valid || capturedArg.OwnerID.Int64 != 7 {
+		t.Errorf("expected owner 7, got %v", capturedArg.OwnerID)
+	}
+	if capturedArg.OrgID != 3 {
+		t.Errorf("expected organization 3, got %v", capturedArg.OrgID)
+	}
+}
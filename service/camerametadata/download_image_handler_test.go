@@ -21,7 +21,7 @@ func TestHandler_DownloadImageHandler(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		timeNow := time.Now()
 		nullTime := sql.NullTime{
@@ -43,7 +43,7 @@ func TestHandler_DownloadImageHandler(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		mockCameraStore.On("GetCameraMetadataByID", camID).Return(&expectedCamera, nil)
+		mockCameraStore.On("GetCameraMetadataByID", mock.Anything, mock.Anything, camID).Return(&expectedCamera, nil)
 		mockAzureStorage.On("DownloadImage",
 			mock.AnythingOfType("*context.valueCtx"), imageID+".png").Return(imageData, nil)
 
@@ -71,7 +71,7 @@ func TestHandler_DownloadImageHandler(t *testing.T) {
 		// Arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		camID := uuid.New().String()
 		imageID := uuid.New().String()
@@ -82,7 +82,7 @@ func TestHandler_DownloadImageHandler(t *testing.T) {
 			ImageId: sql.NullString{String: imageID, Valid: true},
 		}
 
-		mockCameraStore.On("GetCameraMetadataByID", camID).Return(&expectedCamera, nil)
+		mockCameraStore.On("GetCameraMetadataByID", mock.Anything, mock.Anything, camID).Return(&expectedCamera, nil)
 		mockAzureStorage.On("DownloadImage", mock.Anything, imageID+".png").Return(imageData, nil)
 
 		// Act
@@ -107,7 +107,7 @@ func TestHandler_DownloadImageHandler(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		timeNow := time.Now()
 		nullTime := sql.NullTime{
@@ -125,7 +125,7 @@ func TestHandler_DownloadImageHandler(t *testing.T) {
 			ImageId:         sql.NullString{String: imageID, Valid: true},
 		}
 
-		mockCameraStore.On("GetCameraMetadataByID", camID).Return(&expectedCamera, nil)
+		mockCameraStore.On("GetCameraMetadataByID", mock.Anything, mock.Anything, camID).Return(&expectedCamera, nil)
 		mockAzureStorage.On("DownloadImage",
 			mock.AnythingOfType("*context.valueCtx"), imageID+".png").Return(nil, fmt.Errorf("download err"))
 
@@ -152,7 +152,7 @@ func TestHandler_DownloadImageHandler(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		timeNow := time.Now()
 		nullTime := sql.NullTime{
@@ -168,7 +168,7 @@ func TestHandler_DownloadImageHandler(t *testing.T) {
 			InitializedAt:   nullTime,
 		}
 
-		mockCameraStore.On("GetCameraMetadataByID", camID).Return(&expectedCamera, nil)
+		mockCameraStore.On("GetCameraMetadataByID", mock.Anything, mock.Anything, camID).Return(&expectedCamera, nil)
 
 		// Act
 		req, err := http.NewRequest(http.MethodGet, "/camera_metadata/"+camID+"/download_image", nil)
@@ -193,11 +193,11 @@ func TestHandler_DownloadImageHandler(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		camID := uuid.New().String()
 
-		mockCameraStore.On("GetCameraMetadataByID", camID).Return(nil, fmt.Errorf("not found"))
+		mockCameraStore.On("GetCameraMetadataByID", mock.Anything, mock.Anything, camID).Return(nil, fmt.Errorf("not found"))
 
 		// Act
 		req, err := http.NewRequest(http.MethodGet, "/camera_metadata/"+camID+"/download_image", nil)
@@ -222,7 +222,7 @@ func TestHandler_DownloadImageHandler(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		camID := "123"
 
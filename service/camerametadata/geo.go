@@ -0,0 +1,93 @@
+package camerametadata
+
+import (
+	"fmt"
+	"go-sample-rest-api/types"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// FormatGeoJSON selects a GeoJSON FeatureCollection as the listing output.
+	FormatGeoJSON = "geojson"
+	// maxRadiusMeters keeps a radius query below half the circumference of the earth.
+	maxRadiusMeters = 20000000
+)
+
+// parseLocationFilter reads the bbox=minLon,minLat,maxLon,maxLat and the
+// lat, lon and radius (in meters) query parameters.
+func parseLocationFilter(query url.Values, filter *types.CameraFilter) error {
+	if v := query.Get("bbox"); v != "" {
+		parts := strings.Split(v, ",")
+		if len(parts) != 4 {
+			return fmt.Errorf("invalid bbox: %q, want minLon,minLat,maxLon,maxLat", v)
+		}
+		var c [4]float64
+		for i, part := range parts {
+			f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return fmt.Errorf("invalid bbox: %q, want minLon,minLat,maxLon,maxLat", v)
+			}
+			c[i] = f
+		}
+		box := types.BoundingBox{MinLongitude: c[0], MinLatitude: c[1], MaxLongitude: c[2], MaxLatitude: c[3]}
+		if !validLongitude(box.MinLongitude) || !validLongitude(box.MaxLongitude) ||
+			!validLatitude(box.MinLatitude) || !validLatitude(box.MaxLatitude) || box.MinLatitude > box.MaxLatitude {
+			return fmt.Errorf("invalid bbox: %q, want minLon,minLat,maxLon,maxLat", v)
+		}
+		filter.Within = &box
+	}
+
+	lat, lon, radius := query.Get("lat"), query.Get("lon"), query.Get("radius")
+	if lat == "" && lon == "" && radius == "" {
+		return nil
+	}
+	if lat == "" || lon == "" || radius == "" {
+		return fmt.Errorf("lat, lon and radius are required together")
+	}
+
+	var circle types.GeoCircle
+	var err error
+	if circle.Latitude, err = strconv.ParseFloat(lat, 64); err != nil || !validLatitude(circle.Latitude) {
+		return fmt.Errorf("invalid lat: %q", lat)
+	}
+	if circle.Longitude, err = strconv.ParseFloat(lon, 64); err != nil || !validLongitude(circle.Longitude) {
+		return fmt.Errorf("invalid lon: %q", lon)
+	}
+	if circle.RadiusMeters, err = strconv.ParseFloat(radius, 64); err != nil || circle.RadiusMeters <= 0 || circle.RadiusMeters > maxRadiusMeters {
+		return fmt.Errorf("invalid radius: %q, want meters up to %d", radius, maxRadiusMeters)
+	}
+	filter.Near = &circle
+
+	return nil
+}
+
+func validLatitude(v float64) bool {
+	return v >= -90 && v <= 90
+}
+
+func validLongitude(v float64) bool {
+	return v >= -180 && v <= 180
+}
+
+// featureCollection converts a page of cameras to GeoJSON.
+func featureCollection(list types.CameraList) types.GeoJSONFeatureCollection {
+	collection := types.GeoJSONFeatureCollection{
+		Type:     "FeatureCollection",
+		Features: make([]types.GeoJSONFeature, 0, len(list.Cameras)),
+		Total:    list.Total,
+		Page:     list.Page,
+		PageSize: list.PageSize,
+	}
+
+	for _, c := range list.Cameras {
+		feature := types.GeoJSONFeature{Type: "Feature", ID: c.CamID, Properties: c}
+		if c.Latitude != nil && c.Longitude != nil {
+			feature.Geometry = &types.GeoJSONPoint{Type: "Point", Coordinates: [2]float64{*c.Longitude, *c.Latitude}}
+		}
+		collection.Features = append(collection.Features, feature)
+	}
+
+	return collection
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
 	"go-sample-rest-api/types"
 	"net/http"
 	"net/http/httptest"
@@ -17,7 +18,7 @@ func TestHandler_GetCameraMetaData(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		timeNow := time.Now()
 		nullTime := sql.NullTime{
@@ -32,7 +33,7 @@ func TestHandler_GetCameraMetaData(t *testing.T) {
 			CreatedAt:       nullTime,
 		}
 
-		mockCameraStore.On("GetCameraMetadataByID", camID).Return(&expectedCamera, nil)
+		mockCameraStore.On("GetCameraMetadataByID", mock.Anything, mock.Anything, camID).Return(&expectedCamera, nil)
 
 		// Act
 		req, err := http.NewRequest(http.MethodGet, "/camera_metadata/"+camID, nil)
@@ -57,11 +58,11 @@ func TestHandler_GetCameraMetaData(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		camID := uuid.New().String()
 
-		mockCameraStore.On("GetCameraMetadataByID", camID).Return(nil, fmt.Errorf("Not Found"))
+		mockCameraStore.On("GetCameraMetadataByID", mock.Anything, mock.Anything, camID).Return(nil, fmt.Errorf("Not Found"))
 
 		// Act
 		req, err := http.NewRequest(http.MethodGet, "/camera_metadata/"+camID, nil)
@@ -86,7 +87,7 @@ func TestHandler_GetCameraMetaData(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		camID := "123"
 
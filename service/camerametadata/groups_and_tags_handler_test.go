@@ -0,0 +1,260 @@
+package camerametadata
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/customerrors"
+	"go-sample-rest-api/types"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func camerasRouter(handler *Handler) *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/camera_metadata", handler.ListCameraMetadata).Methods(http.MethodGet)
+	router.HandleFunc("/camera_metadata/bulk", handler.BulkUpdateCameraMetadata).Methods(http.MethodPost)
+	router.HandleFunc("/camera_metadata/{camID}/group", handler.SetCameraGroup).Methods(http.MethodPut)
+	router.HandleFunc("/camera_metadata/{camID}/tags", handler.SetCameraTags).Methods(http.MethodPut)
+	return router
+}
+
+func TestHandler_ListCameraMetadata(t *testing.T) {
+	t.Run("ListCameraMetadata_withGroupAndSelector_returnsPage", func(t *testing.T) {
+		// arrange
+		mockCameraStore := new(MockCameraStore)
+		handler := NewHandler(mockCameraStore, nil, nil, nil)
+
+		groupID := 2
+		filter := types.CameraFilter{
+			GroupID:  &groupID,
+			Selector: []types.TagRequirement{{Key: "site", Operator: types.TagEquals, Value: "berlin"}},
+			Limit:    5,
+			Offset:   5,
+		}
+		mockCameraStore.On("ListCameraMetadata", mock.Anything, 3, filter).Return([]types.CameraMetadata{
+			{CamID: uuid.New().String(), CameraName: "Gate", GroupID: sql.NullInt64{Int64: 2, Valid: true}, Tags: types.Tags{"site": "berlin"}},
+		}, 6, nil)
+
+		req, _ := http.NewRequest(http.MethodGet, "/camera_metadata?group=2&selector=site%3Dberlin&page=2&pageSize=5", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		camerasRouter(handler).ServeHTTP(rr, withOrganization(req, 3))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		var list types.CameraList
+		json.Unmarshal(rr.Body.Bytes(), &list)
+		if list.Total != 6 || len(list.Cameras) != 1 || *list.Cameras[0].GroupID != 2 || list.Cameras[0].Tags["site"] != "berlin" {
+			t.Errorf("unexpected camera list: %+v", list)
+		}
+	})
+
+	t.Run("ListCameraMetadata_withInvalidSelector_returnBadRequest", func(t *testing.T) {
+		// arrange
+		handler := NewHandler(new(MockCameraStore), nil, nil, nil)
+
+		req, _ := http.NewRequest(http.MethodGet, "/camera_metadata?selector=site%3Dberlin%2C%3Dx", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		camerasRouter(handler).ServeHTTP(rr, withOrganization(req, 3))
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestHandler_SetCameraGroup(t *testing.T) {
+	t.Run("SetCameraGroup_withGroupOfOrganization_movesCamera", func(t *testing.T) {
+		// arrange
+		mockCameraStore := new(MockCameraStore)
+		mockGroupStore := new(MockCameraGroupStore)
+		handler := NewHandler(mockCameraStore, nil, nil, mockGroupStore)
+
+		camID := uuid.New().String()
+		mockGroupStore.On("GetCameraGroup", mock.Anything, 3, 4).Return(&types.CameraGroup{ID: 4, OrgID: 3}, nil)
+		camera := &types.CameraMetadata{CamID: camID, OrgID: 3}
+		mockCameraStore.On("UpdateCameraMetadata", mock.Anything, 3, camID, mock.Anything).Return(camera, nil)
+
+		req, _ := http.NewRequest(http.MethodPut, "/camera_metadata/"+camID+"/group", strings.NewReader(`{"group_id":4}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		camerasRouter(handler).ServeHTTP(rr, withOrganization(req, 3))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		if want := (sql.NullInt64{Int64: 4, Valid: true}); camera.GroupID != want {
+			t.Errorf("camera saved with wrong group: got %v want %v", camera.GroupID, want)
+		}
+		mockCameraStore.AssertExpectations(t)
+	})
+
+	t.Run("SetCameraGroup_withGroupOfOtherOrganization_returnBadRequest", func(t *testing.T) {
+		// arrange
+		mockCameraStore := new(MockCameraStore)
+		mockGroupStore := new(MockCameraGroupStore)
+		handler := NewHandler(mockCameraStore, nil, nil, mockGroupStore)
+
+		mockGroupStore.On("GetCameraGroup", mock.Anything, 3, 9).Return(nil, &customerrors.NotFoundError{ID: "9"})
+
+		req, _ := http.NewRequest(http.MethodPut, "/camera_metadata/"+uuid.New().String()+"/group", strings.NewReader(`{"group_id":9}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		camerasRouter(handler).ServeHTTP(rr, withOrganization(req, 3))
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+		mockCameraStore.AssertNotCalled(t, "UpdateCameraMetadata", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("SetCameraGroup_withNull_removesCameraFromGroup", func(t *testing.T) {
+		// arrange
+		mockCameraStore := new(MockCameraStore)
+		handler := NewHandler(mockCameraStore, nil, nil, new(MockCameraGroupStore))
+
+		camID := uuid.New().String()
+		camera := &types.CameraMetadata{CamID: camID, OrgID: 3, GroupID: sql.NullInt64{Int64: 4, Valid: true}}
+		mockCameraStore.On("UpdateCameraMetadata", mock.Anything, 3, camID, mock.Anything).Return(camera, nil)
+
+		req, _ := http.NewRequest(http.MethodPut, "/camera_metadata/"+camID+"/group", strings.NewReader(`{"group_id":null}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		camerasRouter(handler).ServeHTTP(rr, withOrganization(req, 3))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		if camera.GroupID.Valid {
+			t.Errorf("camera saved with group %v, want none", camera.GroupID.Int64)
+		}
+		mockCameraStore.AssertExpectations(t)
+	})
+}
+
+func TestHandler_SetCameraTags(t *testing.T) {
+	t.Run("SetCameraTags_withValidTags_replacesTags", func(t *testing.T) {
+		// arrange
+		mockCameraStore := new(MockCameraStore)
+		handler := NewHandler(mockCameraStore, nil, nil, nil)
+
+		camID := uuid.New().String()
+		camera := &types.CameraMetadata{CamID: camID, OrgID: 3, Tags: types.Tags{"old": "x"}}
+		mockCameraStore.On("UpdateCameraMetadata", mock.Anything, 3, camID, mock.Anything).Return(camera, nil)
+
+		req, _ := http.NewRequest(http.MethodPut, "/camera_metadata/"+camID+"/tags", strings.NewReader(`{"tags":{"site":"berlin"}}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		camerasRouter(handler).ServeHTTP(rr, withOrganization(req, 3))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		if want := (types.Tags{"site": "berlin"}); !reflect.DeepEqual(camera.Tags, want) {
+			t.Errorf("camera saved with wrong tags: got %v want %v", camera.Tags, want)
+		}
+		mockCameraStore.AssertExpectations(t)
+	})
+
+	t.Run("SetCameraTags_withInvalidKey_returnBadRequest", func(t *testing.T) {
+		// arrange
+		handler := NewHandler(new(MockCameraStore), nil, nil, nil)
+
+		req, _ := http.NewRequest(http.MethodPut, "/camera_metadata/"+uuid.New().String()+"/tags", strings.NewReader(`{"tags":{"bad key":"x"}}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		camerasRouter(handler).ServeHTTP(rr, withOrganization(req, 3))
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestHandler_BulkUpdateCameraMetadata(t *testing.T) {
+	t.Run("BulkUpdateCameraMetadata_withSelector_returnsUpdatedCount", func(t *testing.T) {
+		// arrange
+		mockCameraStore := new(MockCameraStore)
+		mockGroupStore := new(MockCameraGroupStore)
+		handler := NewHandler(mockCameraStore, nil, nil, mockGroupStore)
+
+		groupID := 4
+		mockGroupStore.On("GetCameraGroup", mock.Anything, 3, 4).Return(&types.CameraGroup{ID: 4, OrgID: 3}, nil)
+		mockCameraStore.On("BulkUpdateCameraMetadata", mock.Anything, 3,
+			types.CameraFilter{Selector: []types.TagRequirement{{Key: "site", Operator: types.TagEquals, Value: "berlin"}}},
+			types.CameraBulkUpdate{SetGroupID: &groupID, RemoveTags: []string{"old"}}, mock.Anything).
+			Return(int64(12), nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/camera_metadata/bulk", strings.NewReader(`{"selector":"site=berlin","set_group_id":4,"remove_tags":["old"]}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		camerasRouter(handler).ServeHTTP(rr, withOrganization(req, 3))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		var response types.CameraBulkResponse
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		if response.Updated != 12 {
+			t.Errorf("expected 12 updated cameras, got %d", response.Updated)
+		}
+	})
+
+	t.Run("BulkUpdateCameraMetadata_withoutSelection_returnBadRequest", func(t *testing.T) {
+		// arrange
+		mockCameraStore := new(MockCameraStore)
+		handler := NewHandler(mockCameraStore, nil, nil, nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/camera_metadata/bulk", strings.NewReader(`{"set_tags":{"site":"berlin"}}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		camerasRouter(handler).ServeHTTP(rr, withOrganization(req, 3))
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+		mockCameraStore.AssertNotCalled(t, "BulkUpdateCameraMetadata", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("BulkUpdateCameraMetadata_withNothingToChange_returnBadRequest", func(t *testing.T) {
+		// arrange
+		handler := NewHandler(new(MockCameraStore), nil, nil, nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/camera_metadata/bulk", strings.NewReader(`{"selector":"site=berlin"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		camerasRouter(handler).ServeHTTP(rr, withOrganization(req, 3))
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/customerrors"
 	"go-sample-rest-api/types"
 	"net/http"
 	"net/http/httptest"
@@ -18,7 +19,7 @@ func TestHandler_InitializeCameraMetaData(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		timeNow := time.Now()
 		nullTime := sql.NullTime{
@@ -33,11 +34,8 @@ func TestHandler_InitializeCameraMetaData(t *testing.T) {
 			CreatedAt:       nullTime,
 		}
 
-		var capturedArg types.CameraMetadata
-		mockCameraStore.On("GetCameraMetadataByID", camID).Return(&expectedCamera, nil)
-		mockCameraStore.On("UpdateCameraMetadata", mock.AnythingOfType("types.CameraMetadata")).Run(func(args mock.Arguments) {
-			capturedArg = args.Get(0).(types.CameraMetadata)
-		}).Return(&expectedCamera, nil)
+		capturedArg := expectedCamera
+		mockCameraStore.On("UpdateCameraMetadata", mock.Anything, mock.Anything, camID, mock.Anything).Return(&capturedArg, nil)
 		// Act
 		req, err := http.NewRequest(http.MethodPatch, "/camera_metadata/"+camID+"/init", nil)
 		if err != nil {
@@ -60,6 +58,9 @@ func TestHandler_InitializeCameraMetaData(t *testing.T) {
 		if capturedArg.FirmwareVersion != expectedCamera.FirmwareVersion {
 			t.Errorf("expected FirmwareVersion %s, got %s", expectedCamera.FirmwareVersion, capturedArg.FirmwareVersion)
 		}
+		if !capturedArg.InitializedAt.Valid {
+			t.Errorf("expected the camera to be saved as initialized")
+		}
 
 		mockCameraStore.AssertExpectations(t)
 	})
@@ -68,7 +69,7 @@ func TestHandler_InitializeCameraMetaData(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		timeNow := time.Now()
 		nullTime := sql.NullTime{
@@ -84,7 +85,7 @@ func TestHandler_InitializeCameraMetaData(t *testing.T) {
 			InitializedAt:   nullTime,
 		}
 
-		mockCameraStore.On("GetCameraMetadataByID", camID).Return(&expectedCamera, nil)
+		mockCameraStore.On("UpdateCameraMetadata", mock.Anything, mock.Anything, camID, mock.Anything).Return(&expectedCamera, nil)
 
 		// Act
 		req, err := http.NewRequest(http.MethodPatch, "/camera_metadata/"+camID+"/init", nil)
@@ -109,23 +110,11 @@ func TestHandler_InitializeCameraMetaData(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
-		timeNow := time.Now()
-		nullTime := sql.NullTime{
-			Time:  timeNow,
-			Valid: true,
-		}
 		camID := uuid.New().String()
-		expectedCamera := types.CameraMetadata{
-			CamID:           camID,
-			CameraName:      "camera-name",
-			FirmwareVersion: "v123",
-			CreatedAt:       nullTime,
-		}
 
-		mockCameraStore.On("GetCameraMetadataByID", camID).Return(&expectedCamera, nil)
-		mockCameraStore.On("UpdateCameraMetadata", mock.AnythingOfType("types.CameraMetadata")).Return(nil, fmt.Errorf("Failed"))
+		mockCameraStore.On("UpdateCameraMetadata", mock.Anything, mock.Anything, camID, mock.Anything).Return(nil, fmt.Errorf("Failed"))
 		// Act
 		req, err := http.NewRequest(http.MethodPatch, "/camera_metadata/"+camID+"/init", nil)
 		if err != nil {
@@ -149,11 +138,11 @@ func TestHandler_InitializeCameraMetaData(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		camID := uuid.New().String()
 
-		mockCameraStore.On("GetCameraMetadataByID", camID).Return(nil, fmt.Errorf("Not Found"))
+		mockCameraStore.On("UpdateCameraMetadata", mock.Anything, mock.Anything, camID, mock.Anything).Return(nil, &customerrors.NotFoundError{ID: camID})
 		// Act
 		req, err := http.NewRequest(http.MethodPatch, "/camera_metadata/"+camID+"/init", nil)
 		if err != nil {
@@ -177,7 +166,7 @@ func TestHandler_InitializeCameraMetaData(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		camID := "123"
 
@@ -202,7 +191,7 @@ func TestHandler_InitializeCameraMetaData(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		// Act
 		req, err := http.NewRequest(http.MethodPatch, "/camera_metadata//init", nil)
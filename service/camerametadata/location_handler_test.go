@@ -0,0 +1,214 @@
+package camerametadata
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/types"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func locationRouter(handler *Handler) *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/camera_metadata", handler.ListCameraMetadata).Methods(http.MethodGet)
+	router.HandleFunc("/camera_metadata/{camID}/location", handler.SetCameraLocation).Methods(http.MethodPut)
+	router.HandleFunc("/camera_metadata/{camID}/location", handler.DeleteCameraLocation).Methods(http.MethodDelete)
+	return router
+}
+
+func TestHandler_SetCameraLocation(t *testing.T) {
+	t.Run("SetCameraLocation_withHeading_placesCamera", func(t *testing.T) {
+		// arrange
+		mockCameraStore := new(MockCameraStore)
+		handler := NewHandler(mockCameraStore, nil, nil, nil)
+
+		camID := uuid.New().String()
+		saved := &types.CameraMetadata{CamID: camID, OrgID: 3, FieldOfView: sql.NullFloat64{Float64: 90, Valid: true}}
+		mockCameraStore.On("UpdateCameraMetadata", mock.Anything, 3, camID, mock.Anything).Return(saved, nil)
+
+		req, _ := http.NewRequest(http.MethodPut, "/camera_metadata/"+camID+"/location", strings.NewReader(`{"latitude":0,"longitude":13.405,"heading":270}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		locationRouter(handler).ServeHTTP(rr, withOrganization(req, 3))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		mockCameraStore.AssertExpectations(t)
+		if want := (types.CameraMetadata{
+			CamID:     camID,
+			OrgID:     3,
+			Latitude:  sql.NullFloat64{Float64: 0, Valid: true},
+			Longitude: sql.NullFloat64{Float64: 13.405, Valid: true},
+			Heading:   sql.NullFloat64{Float64: 270, Valid: true},
+		}); !reflect.DeepEqual(*saved, want) {
+			t.Errorf("camera saved as %+v, want %+v", *saved, want)
+		}
+		var camera types.CameraMetadataResponse
+		json.Unmarshal(rr.Body.Bytes(), &camera)
+		if camera.Latitude == nil || *camera.Latitude != 0 || camera.FieldOfView != nil {
+			t.Errorf("unexpected camera: %+v", camera)
+		}
+	})
+
+	t.Run("SetCameraLocation_withInvalidLocation_returnBadRequest", func(t *testing.T) {
+		for _, body := range []string{
+			`{"longitude":13.405}`,
+			`{"latitude":91,"longitude":13.405}`,
+			`{"latitude":52.52,"longitude":-181}`,
+			`{"latitude":52.52,"longitude":13.405,"heading":360}`,
+			`{"latitude":52.52,"longitude":13.405,"field_of_view":0}`,
+		} {
+			// arrange
+			mockCameraStore := new(MockCameraStore)
+			handler := NewHandler(mockCameraStore, nil, nil, nil)
+
+			req, _ := http.NewRequest(http.MethodPut, "/camera_metadata/"+uuid.New().String()+"/location", strings.NewReader(body))
+			rr := httptest.NewRecorder()
+
+			// act
+			locationRouter(handler).ServeHTTP(rr, withOrganization(req, 3))
+
+			// assert
+			if status := rr.Code; status != http.StatusBadRequest {
+				t.Errorf("%s: handler returned wrong status code: got %v want %v", body, status, http.StatusBadRequest)
+			}
+			mockCameraStore.AssertNotCalled(t, "UpdateCameraMetadata", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("DeleteCameraLocation_clearsLocation", func(t *testing.T) {
+		// arrange
+		mockCameraStore := new(MockCameraStore)
+		handler := NewHandler(mockCameraStore, nil, nil, nil)
+
+		camID := uuid.New().String()
+		saved := &types.CameraMetadata{
+			CamID:     camID,
+			OrgID:     3,
+			Latitude:  sql.NullFloat64{Float64: 52.52, Valid: true},
+			Longitude: sql.NullFloat64{Float64: 13.405, Valid: true},
+		}
+		mockCameraStore.On("UpdateCameraMetadata", mock.Anything, 3, camID, mock.Anything).Return(saved, nil)
+
+		req, _ := http.NewRequest(http.MethodDelete, "/camera_metadata/"+camID+"/location", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		locationRouter(handler).ServeHTTP(rr, withOrganization(req, 3))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		if saved.Latitude.Valid || saved.Longitude.Valid {
+			t.Errorf("camera saved with location %+v, want none", *saved)
+		}
+		mockCameraStore.AssertExpectations(t)
+	})
+}
+
+func TestHandler_ListCameraMetadata_Location(t *testing.T) {
+	t.Run("ListCameraMetadata_withBoundingBoxAndRadius_filtersByLocation", func(t *testing.T) {
+		// arrange
+		mockCameraStore := new(MockCameraStore)
+		handler := NewHandler(mockCameraStore, nil, nil, nil)
+
+		filter := types.CameraFilter{
+			Within: &types.BoundingBox{MinLongitude: 13.3, MinLatitude: 52.4, MaxLongitude: 13.5, MaxLatitude: 52.6},
+			Near:   &types.GeoCircle{Latitude: 52.52, Longitude: 13.405, RadiusMeters: 500},
+			Limit:  20,
+		}
+		mockCameraStore.On("ListCameraMetadata", mock.Anything, 3, filter).Return([]types.CameraMetadata{}, 0, nil)
+
+		req, _ := http.NewRequest(http.MethodGet, "/camera_metadata?bbox=13.3,52.4,13.5,52.6&lat=52.52&lon=13.405&radius=500", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		locationRouter(handler).ServeHTTP(rr, withOrganization(req, 3))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		mockCameraStore.AssertExpectations(t)
+	})
+
+	t.Run("ListCameraMetadata_withInvalidArea_returnBadRequest", func(t *testing.T) {
+		for _, query := range []string{
+			"bbox=13.3,52.4,13.5",
+			"bbox=13.3,52.6,13.5,52.4",
+			"bbox=13.3,52.4,190,52.6",
+			"lat=52.52&lon=13.405",
+			"lat=52.52&lon=13.405&radius=-1",
+			"lat=95&lon=13.405&radius=100",
+			"format=kml",
+		} {
+			// arrange
+			handler := NewHandler(new(MockCameraStore), nil, nil, nil)
+
+			req, _ := http.NewRequest(http.MethodGet, "/camera_metadata?"+query, nil)
+			rr := httptest.NewRecorder()
+
+			// act
+			locationRouter(handler).ServeHTTP(rr, withOrganization(req, 3))
+
+			// assert
+			if status := rr.Code; status != http.StatusBadRequest {
+				t.Errorf("%s: handler returned wrong status code: got %v want %v", query, status, http.StatusBadRequest)
+			}
+		}
+	})
+
+	t.Run("ListCameraMetadata_asGeoJSON_returnsFeatureCollection", func(t *testing.T) {
+		// arrange
+		mockCameraStore := new(MockCameraStore)
+		handler := NewHandler(mockCameraStore, nil, nil, nil)
+
+		placed, unplaced := uuid.New().String(), uuid.New().String()
+		mockCameraStore.On("ListCameraMetadata", mock.Anything, 3, types.CameraFilter{Limit: 20}).Return([]types.CameraMetadata{
+			{
+				CamID:      placed,
+				CameraName: "Gate",
+				Latitude:   sql.NullFloat64{Float64: 52.52, Valid: true},
+				Longitude:  sql.NullFloat64{Float64: 13.405, Valid: true},
+				Heading:    sql.NullFloat64{Float64: 90, Valid: true},
+			},
+			{CamID: unplaced, CameraName: "Lobby"},
+		}, 2, nil)
+
+		req, _ := http.NewRequest(http.MethodGet, "/camera_metadata?format=geojson", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		locationRouter(handler).ServeHTTP(rr, withOrganization(req, 3))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		if contentType := rr.Header().Get("Content-Type"); contentType != "application/geo+json" {
+			t.Errorf("unexpected content type %q", contentType)
+		}
+		var collection types.GeoJSONFeatureCollection
+		json.Unmarshal(rr.Body.Bytes(), &collection)
+		if collection.Type != "FeatureCollection" || len(collection.Features) != 2 {
+			t.Fatalf("unexpected collection: %+v", collection)
+		}
+		gate := collection.Features[0]
+		if gate.ID != placed || gate.Geometry == nil || gate.Geometry.Coordinates != [2]float64{13.405, 52.52} || *gate.Properties.Heading != 90 {
+			t.Errorf("unexpected feature: %+v", gate)
+		}
+		if collection.Features[1].Geometry != nil {
+			t.Errorf("expected no geometry for a camera without location, got %+v", collection.Features[1].Geometry)
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package camerametadata
+
+import (
+	"context"
+	"go-sample-rest-api/config"
+	"go-sample-rest-api/customerrors"
+	"go-sample-rest-api/metrics"
+	"go-sample-rest-api/types"
+)
+
+// QuotaFromConfig returns the quota every organization gets.
+func QuotaFromConfig() types.Quota {
+	return types.Quota{
+		MaxCameras:      config.Envs.QuotaMaxCameras,
+		MaxStorageBytes: config.Envs.QuotaMaxStorageBytes,
+	}
+}
+
+// checkQuota fails with a QuotaExceededError when adding the cameras and bytes
+// would take the organization over its quota. It rejects a request before
+// any work is done, such as uploading an image; the store checks the quota
+// again under a lock of the organization when it writes, so concurrent
+// requests cannot exceed it together.
+func (h *Handler) checkQuota(ctx context.Context, orgID int, cameras, bytes int64) error {
+	if !limits(h.quota, cameras, bytes) {
+		return nil
+	}
+
+	usage, err := h.store.GetUsage(ctx, orgID)
+	if err != nil {
+		return err
+	}
+
+	return exceedsQuota(h.quota, *usage, cameras, bytes)
+}
+
+// limits reports whether the quota limits adding the cameras and bytes.
+// Shrinking is always allowed, so an organization above a lowered quota can
+// still replace images with smaller ones.
+func limits(quota types.Quota, cameras, bytes int64) bool {
+	return (quota.MaxCameras > 0 && cameras > 0) || (quota.MaxStorageBytes > 0 && bytes > 0)
+}
+
+// exceedsQuota fails with a QuotaExceededError when adding the cameras and
+// bytes to the usage would go over the quota.
+func exceedsQuota(quota types.Quota, usage types.Usage, cameras, bytes int64) error {
+	if quota.MaxCameras > 0 && cameras > 0 && usage.Cameras+cameras > quota.MaxCameras {
+		return quotaExceeded("cameras", quota.MaxCameras)
+	}
+	if quota.MaxStorageBytes > 0 && bytes > 0 && usage.StorageBytes+bytes > quota.MaxStorageBytes {
+		return quotaExceeded("bytes of images", quota.MaxStorageBytes)
+	}
+
+	return nil
+}
+
+// quotaExceeded counts the rejection and returns its error.
+func quotaExceeded(resource string, limit int64) error {
+	metrics.QuotaExceeded.WithLabelValues(resource).Inc()
+	return &customerrors.QuotaExceededError{Resource: resource, Limit: limit}
+}
@@ -0,0 +1,153 @@
+package camerametadata
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"go-sample-rest-api/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func withOrganization(req *http.Request, orgID int) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), auth.OrgKey, orgID))
+}
+
+func imageSize(t *testing.T) int64 {
+	data, err := base64.StdEncoding.DecodeString(utils.NormalizeBase64(Base64Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return int64(len(data))
+}
+
+func TestHandler_CreateCameraMetadata_Quota(t *testing.T) {
+	t.Run("CreateCameraMetadata_atCameraQuota_returnForbidden", func(t *testing.T) {
+		// arrange
+		mockCameraStore := new(MockCameraStore)
+		handler := NewHandler(mockCameraStore, nil, new(MockAzureStorage), nil)
+		handler.quota = types.Quota{MaxCameras: 2}
+
+		mockCameraStore.On("GetUsage", mock.Anything, 3).Return(&types.Usage{Cameras: 2}, nil)
+
+		cameraData, _ := json.Marshal(types.CameraMetadataPayload{CameraName: "camera-name", FirmwareVersion: "v123"})
+		req, _ := http.NewRequest(http.MethodPost, "/camera_metadata", bytes.NewBuffer(cameraData))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.CreateCameraMetadata(rr, withOrganization(req, 3))
+
+		// assert
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d, got %d", http.StatusForbidden, rr.Code)
+		}
+		mockCameraStore.AssertNotCalled(t, "CreateCameraMetadata", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("CreateCameraMetadata_belowCameraQuota_returnCreated", func(t *testing.T) {
+		// arrange
+		mockCameraStore := new(MockCameraStore)
+		handler := NewHandler(mockCameraStore, nil, new(MockAzureStorage), nil)
+		handler.quota = types.Quota{MaxCameras: 2}
+
+		mockCameraStore.On("GetUsage", mock.Anything, 3).Return(&types.Usage{Cameras: 1}, nil)
+		mockCameraStore.On("CreateCameraMetadata", mock.Anything, mock.AnythingOfType("types.CameraMetadata"), mock.Anything).
+			Return(&types.CameraMetadata{CamID: uuid.New().String()}, nil)
+
+		cameraData, _ := json.Marshal(types.CameraMetadataPayload{CameraName: "camera-name", FirmwareVersion: "v123"})
+		req, _ := http.NewRequest(http.MethodPost, "/camera_metadata", bytes.NewBuffer(cameraData))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.CreateCameraMetadata(rr, withOrganization(req, 3))
+
+		// assert
+		if rr.Code != http.StatusCreated {
+			t.Errorf("expected status code %d, got %d", http.StatusCreated, rr.Code)
+		}
+	})
+}
+
+func TestHandler_UploadImageHandler_Quota(t *testing.T) {
+	setup := func(t *testing.T, quota types.Quota, usage types.Usage, previousSize int64) (*MockCameraStore, *MockAzureStorage, *httptest.ResponseRecorder) {
+		mockCameraStore := new(MockCameraStore)
+		mockAzureStorage := new(MockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
+		handler.quota = quota
+
+		camID := uuid.New().String()
+		imageID := uuid.New().String()
+		camera := types.CameraMetadata{
+			CamID:          camID,
+			OrgID:          3,
+			InitializedAt:  sql.NullTime{Time: time.Now(), Valid: true},
+			ImageSizeBytes: previousSize,
+		}
+		mockCameraStore.On("GetCameraMetadataByID", mock.Anything, 3, camID).Return(&camera, nil)
+		mockCameraStore.On("GetUsage", mock.Anything, 3).Return(&usage, nil)
+		lockedCamera := camera
+		mockCameraStore.On("UpdateCameraMetadata", mock.Anything, 3, camID, mock.Anything).Return(&lockedCamera, nil)
+		mockCameraStore.On("ClaimImageID", mock.Anything, imageID, camID).Return(true, nil)
+		mockAzureStorage.On("UploadImage", mock.Anything, imageID+".png", mock.AnythingOfType("[]uint8")).Return(nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/camera_metadata/"+camID+"/upload_image?imageID="+imageID+"&image_as_bytes="+Base64Data, nil)
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/camera_metadata/{camID}/upload_image", handler.UploadImageHandler).Methods(http.MethodPost)
+		router.ServeHTTP(rr, withOrganization(req, 3))
+
+		return mockCameraStore, mockAzureStorage, rr
+	}
+
+	t.Run("UploadImageHandler_overStorageQuota_returnForbidden", func(t *testing.T) {
+		// arrange
+		size := imageSize(t)
+
+		// act
+		mockCameraStore, mockAzureStorage, rr := setup(t, types.Quota{MaxStorageBytes: 1000 + size - 1}, types.Usage{StorageBytes: 1000}, 0)
+
+		// assert
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d, got %d", http.StatusForbidden, rr.Code)
+		}
+		mockCameraStore.AssertNotCalled(t, "UpdateCameraMetadata", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockAzureStorage.AssertNotCalled(t, "UploadImage", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("UploadImageHandler_replacingImage_countsOnlyTheDifference", func(t *testing.T) {
+		// arrange
+		size := imageSize(t)
+
+		// act
+		mockCameraStore, _, rr := setup(t, types.Quota{MaxStorageBytes: 1000 + size}, types.Usage{StorageBytes: 1000 + 10}, 10)
+
+		// assert
+		if rr.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+		updated := mockCameraStore.Calls[len(mockCameraStore.Calls)-1].ReturnArguments.Get(0).(*types.CameraMetadata)
+		if updated.ImageSizeBytes != size {
+			t.Errorf("expected the image size %d to be stored, got %d", size, updated.ImageSizeBytes)
+		}
+	})
+
+	t.Run("UploadImageHandler_withoutQuota_skipsUsage", func(t *testing.T) {
+		// act
+		mockCameraStore, _, rr := setup(t, types.Quota{}, types.Usage{}, 0)
+
+		// assert
+		if rr.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+		mockCameraStore.AssertNotCalled(t, "GetUsage", mock.Anything, mock.Anything)
+	})
+}
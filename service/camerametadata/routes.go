@@ -1,49 +1,74 @@
 package camerametadata
 
 import (
+	"context"
 	"database/sql"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
-	"go-sample-rest-api/config"
-	"go-sample-rest-api/customerrors"
+	"go-sample-rest-api/audit"
 	"go-sample-rest-api/logging"
+	auth2 "go-sample-rest-api/service/auth"
 	"go-sample-rest-api/storage"
 	"go-sample-rest-api/types"
 	"go-sample-rest-api/utils"
 	"net/http"
-	"time"
+	"strconv"
+	"strings"
+)
+
+// Actions of the camera audit entries.
+const (
+	ActionCreate         = "camera.create"
+	ActionInitialize     = "camera.initialize"
+	ActionUploadImage    = "camera.upload_image"
+	ActionSetGroup       = "camera.set_group"
+	ActionSetTags        = "camera.set_tags"
+	ActionSetLocation    = "camera.set_location"
+	ActionDeleteLocation = "camera.delete_location"
+	ActionBulkUpdate     = "camera.bulk_update"
 )
 
 type Handler struct {
 	store        types.CameraMetadataStore
+	userStore    types.UserStore
 	azureStorage storage.ImageStore
+	groupStore   types.CameraGroupStore
+	quota        types.Quota
 }
 
-func NewHandler(store types.CameraMetadataStore, azureStorage storage.ImageStore) *Handler {
-	return &Handler{store: store, azureStorage: azureStorage}
+func NewHandler(store types.CameraMetadataStore, userStore types.UserStore, azureStorage storage.ImageStore, groupStore types.CameraGroupStore) *Handler {
+	return &Handler{store: store, userStore: userStore, azureStorage: azureStorage, groupStore: groupStore, quota: QuotaFromConfig()}
 }
 
 func (h *Handler) RegisterRoutes(router *mux.Router) {
-	router.HandleFunc("/camera_metadata", h.CreateCameraMetadata).Methods(http.MethodPost)
-	router.HandleFunc("/camera_metadata/{camID}/init", h.InitializeCameraMetaData).Methods(http.MethodPatch)
-	router.HandleFunc("/camera_metadata/{camID}", h.GetCameraMetaData).Methods(http.MethodGet)
-	router.HandleFunc("/camera_metadata/{camID}/upload_image", h.UploadImageHandler).Methods(http.MethodPost)
-	router.HandleFunc("/camera_metadata/{camID}/download_image", h.DownloadImageHandler).Methods(http.MethodGet)
+	router.HandleFunc("/camera_metadata", auth2.WithJWTAuth(h.CreateCameraMetadata, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/camera_metadata", auth2.WithJWTAuth(h.ListCameraMetadata, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/camera_metadata/bulk", auth2.WithAdmin(h.BulkUpdateCameraMetadata, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/camera_metadata/{camID}/group", auth2.WithJWTAuth(h.SetCameraGroup, h.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/camera_metadata/{camID}/tags", auth2.WithJWTAuth(h.SetCameraTags, h.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/camera_metadata/{camID}/location", auth2.WithJWTAuth(h.SetCameraLocation, h.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/camera_metadata/{camID}/location", auth2.WithJWTAuth(h.DeleteCameraLocation, h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/camera_metadata/{camID}/init", auth2.WithJWTAuth(h.InitializeCameraMetaData, h.userStore)).Methods(http.MethodPatch)
+	router.HandleFunc("/camera_metadata/{camID}", auth2.WithJWTAuth(h.GetCameraMetaData, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/camera_metadata/{camID}/upload_image", auth2.WithJWTAuth(h.UploadImageHandler, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/camera_metadata/{camID}/download_image", auth2.WithJWTAuth(h.DownloadImageHandler, h.userStore)).Methods(http.MethodGet)
 }
 
 // CreateCameraMetadata godoc
 // @Summary Create camera metadata
-// @Description Creates a new camera metadata entry.
+// @Description Creates a new camera metadata entry owned by the authenticated user and their organization.
 // @Tags camera
 // @Accept json
 // @Produce json
 // @Param cameraMetadata body types.CameraMetadataPayload true "Camera Metadata Info"
 // @Success 201 {object} types.CameraMetadataResponse "Camera metadata successfully created."
 // @Failure 400 {object} types.HTTPError "Invalid request parameters."
+// @Failure 403 {object} types.HTTPError "The organization reached its camera quota."
 // @Failure 500 {object} types.HTTPError "Internal server error."
 // @Router /camera_metadata [post]
 func (h *Handler) CreateCameraMetadata(writer http.ResponseWriter, request *http.Request) {
@@ -66,31 +91,17 @@ func (h *Handler) CreateCameraMetadata(writer http.ResponseWriter, request *http
 		}).Error("Validation failed for cameraMetadata request")
 		return
 	}
-	timeNow := time.Now()
-	nullTime := sql.NullTime{
-		Time:  timeNow,
-		Valid: true,
-	}
-	savedCamera, err := h.store.CreateCameraMetadata(types.CameraMetadata{
-		CameraName:      cameraMetadata.CameraName,
-		FirmwareVersion: cameraMetadata.FirmwareVersion,
-		CreatedAt:       nullTime,
-	})
 
+	savedCamera, err := h.CreateCamera(request.Context(), auth2.GetOrgIDFromContext(request.Context()), ownerID(request),
+		cameraMetadata, audit.FromRequest(request, ActionCreate))
 	if err != nil {
-		utils.WriteError(writer, http.StatusInternalServerError, err)
+		writeOperationError(writer, err)
 		log.WithFields(logrus.Fields{
 			"error": err,
 		}).Error("Failed to create camera metadata")
 		return
 	}
-	cameraResponse := types.CameraMetadataResponse{
-		CamID:           savedCamera.CamID,
-		CameraName:      savedCamera.CameraName,
-		FirmwareVersion: savedCamera.FirmwareVersion,
-		CreatedAt:       savedCamera.CreatedAt.Time,
-	}
-	utils.WriteJSON(writer, http.StatusCreated, cameraResponse)
+	utils.WriteJSON(writer, http.StatusCreated, cameraResponse(*savedCamera))
 }
 
 // InitializeCameraMetaData godoc
@@ -116,27 +127,9 @@ func (h *Handler) InitializeCameraMetaData(writer http.ResponseWriter, request *
 		return
 	}
 
-	cameraMetadata, err := h.store.GetCameraMetadataByID(camID)
-	if err != nil {
-		utils.WriteError(writer, http.StatusNotFound, &customerrors.NotFoundError{ID: camID})
-		return
-	}
-
-	if cameraMetadata.InitializedAt.Valid {
-		utils.WriteError(writer, http.StatusConflict, &customerrors.AlreadyInitError{ID: camID})
-		return
-	}
-
-	timeNow := time.Now()
-	nullTime := sql.NullTime{
-		Time:  timeNow,
-		Valid: true,
-	}
-	cameraMetadata.InitializedAt = nullTime
-
-	_, err = h.store.UpdateCameraMetadata(*cameraMetadata)
+	err = h.InitializeCamera(request.Context(), auth2.GetOrgIDFromContext(request.Context()), camID, audit.FromRequest(request, ActionInitialize))
 	if err != nil {
-		utils.WriteError(writer, http.StatusInternalServerError, fmt.Errorf("failed to update camera metadata: %v", err))
+		writeOperationError(writer, err)
 		return
 	}
 
@@ -164,20 +157,13 @@ func (h *Handler) GetCameraMetaData(writer http.ResponseWriter, request *http.Re
 		return
 	}
 
-	cameraMetadata, err := h.store.GetCameraMetadataByID(camID)
+	cameraMetadata, err := h.GetCamera(request.Context(), auth2.GetOrgIDFromContext(request.Context()), camID)
 	if err != nil {
-		utils.WriteError(writer, http.StatusNotFound, fmt.Errorf("camera given ID: %v not found", camID))
+		writeOperationError(writer, err)
 		return
 	}
 
-	cameraResponse := types.CameraMetadataResponse{
-		CamID:           cameraMetadata.CamID,
-		CameraName:      cameraMetadata.CameraName,
-		FirmwareVersion: cameraMetadata.FirmwareVersion,
-		CreatedAt:       cameraMetadata.CreatedAt.Time,
-	}
-
-	utils.WriteJSON(writer, http.StatusOK, cameraResponse)
+	utils.WriteJSON(writer, http.StatusOK, cameraResponse(*cameraMetadata))
 }
 
 // UploadImageHandler godoc
@@ -187,15 +173,16 @@ func (h *Handler) GetCameraMetaData(writer http.ResponseWriter, request *http.Re
 // @Accept multipart/form-data
 // @Produce json
 // @Param camID path string true "Camera ID"
-// @Param imageID query string true "Image ID"
+// @Param imageID query string true "Image ID, a new UUID"
 // @Param image_as_bytes body string true "Base64 encoded image data"
 // @Success 200 {object} types.ImageUploadedResponse "Image uploaded successfully."
 // @Failure 400 {object} types.HTTPError "Bad request parameters."
+// @Failure 403 {object} types.HTTPError "The image would exceed the storage quota of the organization."
 // @Failure 404 {object} types.HTTPError "Camera metadata not found."
+// @Failure 409 {object} types.HTTPError "The image ID belongs to another camera."
 // @Failure 500 {object} types.HTTPError "Failed to upload image."
 // @Router /camera_metadata/{camID}/upload_image [post]
 func (h *Handler) UploadImageHandler(writer http.ResponseWriter, request *http.Request) {
-	log := logging.GetLogger()
 	vars := mux.Vars(request)
 	camID := vars["camID"]
 
@@ -228,38 +215,13 @@ func (h *Handler) UploadImageHandler(writer http.ResponseWriter, request *http.R
 		return
 	}
 
-	cameraMetadata, err := h.store.GetCameraMetadataByID(camID)
-	if err != nil {
-		utils.WriteError(writer, http.StatusNotFound, &customerrors.NotFoundError{ID: camID})
-		return
-	}
-
-	if !cameraMetadata.InitializedAt.Valid {
-		utils.WriteError(writer, http.StatusBadRequest, &customerrors.NotInitError{ID: camID})
-		return
-	}
-
-	cameraMetadata.ImageId = sql.NullString{String: imageID, Valid: true}
-	cameraMetadata.NameOfStoredPicture = sql.NullString{String: imageID, Valid: true}
-	cameraMetadata.ContainerName = sql.NullString{String: config.Envs.AzureContainerName, Valid: true}
-
-	_, err = h.store.UpdateCameraMetadata(*cameraMetadata)
+	cameraMetadata, err := h.UploadImage(request.Context(), auth2.GetOrgIDFromContext(request.Context()), camID, imageID, imageData,
+		audit.FromRequest(request, ActionUploadImage))
 	if err != nil {
-		utils.WriteError(writer, http.StatusInternalServerError, fmt.Errorf("failed to update camera metadata: %v", err))
+		writeOperationError(writer, err)
 		return
 	}
 
-	err = h.azureStorage.UploadImage(request.Context(), imageID+".png", imageData)
-
-	if err != nil {
-		utils.WriteError(writer, http.StatusInternalServerError, fmt.Errorf("failed to upload image: %v", err))
-		return
-	}
-
-	log.WithFields(logrus.Fields{
-		"camera": cameraMetadata,
-	}).Info("Image uploaded successfully")
-
 	response := types.ImageUploadedResponse{
 		CamID:           cameraMetadata.CamID,
 		CameraName:      cameraMetadata.CameraName,
@@ -269,6 +231,19 @@ func (h *Handler) UploadImageHandler(writer http.ResponseWriter, request *http.R
 	utils.WriteJSON(writer, http.StatusOK, response)
 }
 
+// deleteImage removes the blob of an image the camera no longer points at.
+// Failures are only logged: the upload itself has already been decided and
+// the reconcile command cleans up what is left behind. The request context
+// may already be canceled, so the deletion does not inherit its cancellation.
+func (h *Handler) deleteImage(ctx context.Context, imageID, message string) {
+	if err := h.azureStorage.DeleteImage(context.WithoutCancel(ctx), imageID+".png"); err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"imageID": imageID,
+			"error":   err,
+		}).Warn(message)
+	}
+}
+
 // DownloadImageHandler godoc
 // @Summary Download an image from a camera
 // @Description Downloads an image file associated with a camera.
@@ -291,20 +266,9 @@ func (h *Handler) DownloadImageHandler(writer http.ResponseWriter, request *http
 		return
 	}
 
-	cameraMetadata, err := h.store.GetCameraMetadataByID(camID)
+	image, err := h.DownloadImage(request.Context(), auth2.GetOrgIDFromContext(request.Context()), camID)
 	if err != nil {
-		utils.WriteError(writer, http.StatusNotFound, &customerrors.NotFoundError{ID: camID})
-		return
-	}
-
-	if !cameraMetadata.ImageId.Valid {
-		utils.WriteError(writer, http.StatusNotFound, &customerrors.NotFoundError{ID: "ImageId"})
-		return
-	}
-
-	image, err := h.azureStorage.DownloadImage(request.Context(), cameraMetadata.ImageId.String+".png")
-	if err != nil {
-		utils.WriteError(writer, http.StatusInternalServerError, fmt.Errorf("failed to download image: %v", err))
+		writeOperationError(writer, err)
 		return
 	}
 
@@ -320,3 +284,352 @@ func (h *Handler) DownloadImageHandler(writer http.ResponseWriter, request *http
 	writer.WriteHeader(http.StatusOK)
 	log.Infof("Successfully sent image for camera ID: %s", camID)
 }
+
+// ListCameraMetadata godoc
+// @Summary List cameras
+// @Description Lists the cameras of the organization, oldest first. The selector is a comma separated list of tag terms that all have to match: key=value, key!=value, key (the tag exists) and !key (it does not). bbox, and lat with lon and radius, only return cameras with a location in the area; a radius query sorts them by distance, nearest first. format=geojson returns a GeoJSON FeatureCollection.
+// @Tags camera
+// @Produce json
+// @Produce application/geo+json
+// @Param group query int false "Only cameras of this group"
+// @Param selector query string false "Tag selector, e.g. site=berlin,!outdoor"
+// @Param bbox query string false "Bounding box minLon,minLat,maxLon,maxLat"
+// @Param lat query number false "Latitude of the center of a radius query"
+// @Param lon query number false "Longitude of the center of a radius query"
+// @Param radius query number false "Radius in meters"
+// @Param format query string false "geojson for a GeoJSON FeatureCollection"
+// @Param page query int false "Page, starting at 1"
+// @Param pageSize query int false "Cameras per page"
+// @Success 200 {object} types.CameraList "The cameras."
+// @Failure 400 {object} types.HTTPError "Invalid group, selector, area, format or pagination."
+// @Failure 500 {object} types.HTTPError "Internal server error."
+// @Router /camera_metadata [get]
+func (h *Handler) ListCameraMetadata(writer http.ResponseWriter, request *http.Request) {
+	page, pageSize, err := utils.GetPagination(request)
+	if err != nil {
+		utils.WriteError(writer, http.StatusBadRequest, err)
+		return
+	}
+
+	query := request.URL.Query()
+	format := query.Get("format")
+	if format != "" && format != FormatGeoJSON {
+		utils.WriteError(writer, http.StatusBadRequest, fmt.Errorf("invalid format: %q", format))
+		return
+	}
+
+	filter, err := parseFilter(query.Get("group"), query.Get("selector"))
+	if err != nil {
+		utils.WriteError(writer, http.StatusBadRequest, err)
+		return
+	}
+	if err := parseLocationFilter(query, &filter); err != nil {
+		utils.WriteError(writer, http.StatusBadRequest, err)
+		return
+	}
+	filter.Limit = pageSize
+	filter.Offset = (page - 1) * pageSize
+
+	cameras, total, err := h.ListCameras(request.Context(), auth2.GetOrgIDFromContext(request.Context()), filter)
+	if err != nil {
+		utils.WriteError(writer, http.StatusInternalServerError, err)
+		return
+	}
+
+	list := types.CameraList{Cameras: make([]types.CameraMetadataResponse, 0, len(cameras)), Total: total, Page: page, PageSize: pageSize}
+	for _, c := range cameras {
+		list.Cameras = append(list.Cameras, cameraResponse(c))
+	}
+
+	if format == FormatGeoJSON {
+		writer.Header().Set("Content-Type", "application/geo+json")
+		writer.WriteHeader(http.StatusOK)
+		json.NewEncoder(writer).Encode(featureCollection(list))
+		return
+	}
+
+	utils.WriteJSON(writer, http.StatusOK, list)
+}
+
+// SetCameraGroup godoc
+// @Summary Move a camera to a group
+// @Description Puts the camera into a group of the organization, or removes it from its group when group_id is null.
+// @Tags camera
+// @Accept json
+// @Produce json
+// @Param camID path string true "Camera ID"
+// @Param group body types.CameraGroupAssignmentPayload true "Group"
+// @Success 200 {object} types.CameraMetadataResponse "The camera."
+// @Failure 400 {object} types.HTTPError "Invalid camera ID or unknown group."
+// @Failure 404 {object} types.HTTPError "Camera not found."
+// @Failure 500 {object} types.HTTPError "Internal server error."
+// @Router /camera_metadata/{camID}/group [put]
+func (h *Handler) SetCameraGroup(writer http.ResponseWriter, request *http.Request) {
+	var payload types.CameraGroupAssignmentPayload
+	if err := utils.ParseJSON(request, &payload); err != nil {
+		utils.WriteError(writer, http.StatusBadRequest, err)
+		return
+	}
+
+	orgID := auth2.GetOrgIDFromContext(request.Context())
+	if payload.GroupID != nil {
+		if err := h.checkGroup(request.Context(), orgID, *payload.GroupID); err != nil {
+			utils.WriteError(writer, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	h.updateCamera(writer, request, ActionSetGroup, func(c *types.CameraMetadata) {
+		c.GroupID = sql.NullInt64{}
+		if payload.GroupID != nil {
+			c.GroupID = sql.NullInt64{Int64: int64(*payload.GroupID), Valid: true}
+		}
+	})
+}
+
+// SetCameraTags godoc
+// @Summary Replace the tags of a camera
+// @Description Replaces all key=value tags of the camera. Keys have up to 63 letters, digits, '.', '_', '/' and '-'; values up to 255 characters without commas.
+// @Tags camera
+// @Accept json
+// @Produce json
+// @Param camID path string true "Camera ID"
+// @Param tags body types.CameraTagsPayload true "Tags"
+// @Success 200 {object} types.CameraMetadataResponse "The camera."
+// @Failure 400 {object} types.HTTPError "Invalid camera ID or tags."
+// @Failure 404 {object} types.HTTPError "Camera not found."
+// @Failure 500 {object} types.HTTPError "Internal server error."
+// @Router /camera_metadata/{camID}/tags [put]
+func (h *Handler) SetCameraTags(writer http.ResponseWriter, request *http.Request) {
+	var payload types.CameraTagsPayload
+	if err := utils.ParseJSON(request, &payload); err != nil {
+		utils.WriteError(writer, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := validateTags(payload.Tags); err != nil {
+		utils.WriteError(writer, http.StatusBadRequest, err)
+		return
+	}
+
+	h.updateCamera(writer, request, ActionSetTags, func(c *types.CameraMetadata) {
+		c.Tags = payload.Tags
+	})
+}
+
+// SetCameraLocation godoc
+// @Summary Place a camera
+// @Description Sets the latitude and longitude of the camera in WGS 84 degrees, and optionally its heading (degrees clockwise from north) and horizontal field of view. Omitted optional values are cleared.
+// @Tags camera
+// @Accept json
+// @Produce json
+// @Param camID path string true "Camera ID"
+// @Param location body types.CameraLocationPayload true "Location"
+// @Success 200 {object} types.CameraMetadataResponse "The camera."
+// @Failure 400 {object} types.HTTPError "Invalid camera ID or location."
+// @Failure 404 {object} types.HTTPError "Camera not found."
+// @Failure 500 {object} types.HTTPError "Internal server error."
+// @Router /camera_metadata/{camID}/location [put]
+func (h *Handler) SetCameraLocation(writer http.ResponseWriter, request *http.Request) {
+	var payload types.CameraLocationPayload
+	if err := utils.ParseJSON(request, &payload); err != nil {
+		utils.WriteError(writer, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(writer, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", errors))
+		return
+	}
+
+	h.updateCamera(writer, request, ActionSetLocation, func(c *types.CameraMetadata) {
+		c.Latitude = nullFloat(payload.Latitude)
+		c.Longitude = nullFloat(payload.Longitude)
+		c.Heading = nullFloat(payload.Heading)
+		c.FieldOfView = nullFloat(payload.FieldOfView)
+	})
+}
+
+// DeleteCameraLocation godoc
+// @Summary Remove the location of a camera
+// @Tags camera
+// @Produce json
+// @Param camID path string true "Camera ID"
+// @Success 200 {object} types.CameraMetadataResponse "The camera."
+// @Failure 400 {object} types.HTTPError "Invalid camera ID."
+// @Failure 404 {object} types.HTTPError "Camera not found."
+// @Failure 500 {object} types.HTTPError "Internal server error."
+// @Router /camera_metadata/{camID}/location [delete]
+func (h *Handler) DeleteCameraLocation(writer http.ResponseWriter, request *http.Request) {
+	h.updateCamera(writer, request, ActionDeleteLocation, func(c *types.CameraMetadata) {
+		c.Latitude = sql.NullFloat64{}
+		c.Longitude = sql.NullFloat64{}
+		c.Heading = sql.NullFloat64{}
+		c.FieldOfView = sql.NullFloat64{}
+	})
+}
+
+// BulkUpdateCameraMetadata godoc
+// @Summary Change many cameras at once
+// @Description Moves every camera of the organization matching group_id and selector to set_group_id (or out of its group with clear_group), sets set_tags and removes remove_tags. A group or selector is required. Admins only.
+// @Tags camera
+// @Accept json
+// @Produce json
+// @Param bulk body types.CameraBulkPayload true "Cameras and change"
+// @Success 200 {object} types.CameraBulkResponse "The number of changed cameras."
+// @Failure 400 {object} types.HTTPError "Invalid selection or change, or unknown group."
+// @Failure 403 {object} types.HTTPError "Forbidden if the user is not an admin."
+// @Failure 500 {object} types.HTTPError "Internal server error."
+// @Router /camera_metadata/bulk [post]
+func (h *Handler) BulkUpdateCameraMetadata(writer http.ResponseWriter, request *http.Request) {
+	var payload types.CameraBulkPayload
+	if err := utils.ParseJSON(request, &payload); err != nil {
+		utils.WriteError(writer, http.StatusBadRequest, err)
+		return
+	}
+
+	if payload.GroupID == nil && strings.TrimSpace(payload.Selector) == "" {
+		utils.WriteError(writer, http.StatusBadRequest, fmt.Errorf("group_id or selector is required"))
+		return
+	}
+	if payload.SetGroupID == nil && !payload.ClearGroup && len(payload.SetTags) == 0 && len(payload.RemoveTags) == 0 {
+		utils.WriteError(writer, http.StatusBadRequest, fmt.Errorf("nothing to change"))
+		return
+	}
+	if payload.SetGroupID != nil && payload.ClearGroup {
+		utils.WriteError(writer, http.StatusBadRequest, fmt.Errorf("set_group_id and clear_group cannot be combined"))
+		return
+	}
+
+	requirements, err := ParseSelector(payload.Selector)
+	if err != nil {
+		utils.WriteError(writer, http.StatusBadRequest, err)
+		return
+	}
+	if err := validateTags(payload.SetTags); err != nil {
+		utils.WriteError(writer, http.StatusBadRequest, err)
+		return
+	}
+	for _, key := range payload.RemoveTags {
+		if err := validateTag(key, ""); err != nil {
+			utils.WriteError(writer, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	orgID := auth2.GetOrgIDFromContext(request.Context())
+	if payload.SetGroupID != nil {
+		if err := h.checkGroup(request.Context(), orgID, *payload.SetGroupID); err != nil {
+			utils.WriteError(writer, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	updated, err := h.store.BulkUpdateCameraMetadata(request.Context(), orgID,
+		types.CameraFilter{GroupID: payload.GroupID, Selector: requirements},
+		types.CameraBulkUpdate{
+			SetGroupID: payload.SetGroupID,
+			ClearGroup: payload.ClearGroup,
+			SetTags:    payload.SetTags,
+			RemoveTags: payload.RemoveTags,
+		}, audit.FromRequest(request, ActionBulkUpdate))
+	if err != nil {
+		utils.WriteError(writer, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(writer, http.StatusOK, types.CameraBulkResponse{Updated: updated})
+}
+
+// updateCamera applies change to the camera of the path and saves it with an
+// audit entry for action.
+func (h *Handler) updateCamera(writer http.ResponseWriter, request *http.Request, action string, change func(c *types.CameraMetadata)) {
+	camID := mux.Vars(request)["camID"]
+	if _, err := uuid.Parse(camID); err != nil {
+		utils.WriteError(writer, http.StatusBadRequest, fmt.Errorf("invalid camID: %v", err))
+		return
+	}
+
+	cameraMetadata, err := h.UpdateCamera(request.Context(), auth2.GetOrgIDFromContext(request.Context()), camID, func(c *types.CameraMetadata) error {
+		change(c)
+		return nil
+	}, audit.FromRequest(request, action))
+	if err != nil {
+		writeOperationError(writer, err)
+		return
+	}
+
+	utils.WriteJSON(writer, http.StatusOK, cameraResponse(*cameraMetadata))
+}
+
+// checkGroup makes sure the group belongs to the organization.
+func (h *Handler) checkGroup(ctx context.Context, orgID, groupID int) error {
+	if _, err := h.groupStore.GetCameraGroup(ctx, orgID, groupID); err != nil {
+		return fmt.Errorf("unknown group %d", groupID)
+	}
+
+	return nil
+}
+
+// parseFilter reads the group and selector query parameters.
+func parseFilter(group, selector string) (types.CameraFilter, error) {
+	var filter types.CameraFilter
+	if group != "" {
+		groupID, err := strconv.Atoi(group)
+		if err != nil {
+			return filter, fmt.Errorf("invalid group: %q", group)
+		}
+		filter.GroupID = &groupID
+	}
+
+	requirements, err := ParseSelector(selector)
+	if err != nil {
+		return filter, err
+	}
+	filter.Selector = requirements
+
+	return filter, nil
+}
+
+func cameraResponse(c types.CameraMetadata) types.CameraMetadataResponse {
+	response := types.CameraMetadataResponse{
+		CamID:           c.CamID,
+		CameraName:      c.CameraName,
+		FirmwareVersion: c.FirmwareVersion,
+		CreatedAt:       c.CreatedAt.Time,
+		Tags:            c.Tags,
+	}
+	if c.GroupID.Valid {
+		response.GroupID = &c.GroupID.Int64
+	}
+	if response.Tags == nil {
+		response.Tags = types.Tags{}
+	}
+	response.Latitude = floatPointer(c.Latitude)
+	response.Longitude = floatPointer(c.Longitude)
+	response.Heading = floatPointer(c.Heading)
+	response.FieldOfView = floatPointer(c.FieldOfView)
+
+	return response
+}
+
+func nullFloat(v *float64) sql.NullFloat64 {
+	if v == nil {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: *v, Valid: true}
+}
+
+func floatPointer(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Float64
+}
+
+// ownerID returns the authenticated user as the owner of a new camera.
+func ownerID(request *http.Request) sql.NullInt64 {
+	userID := auth2.GetUserIDFromContext(request.Context())
+	return sql.NullInt64{Int64: int64(userID), Valid: userID > 0}
+}
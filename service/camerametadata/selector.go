@@ -0,0 +1,78 @@
+package camerametadata
+
+import (
+	"fmt"
+	"go-sample-rest-api/types"
+	"regexp"
+	"strings"
+)
+
+const (
+	maxTagsPerCamera = 50
+	maxTagValueLen   = 255
+)
+
+var tagKeyPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._/-]{0,61}[A-Za-z0-9])?$`)
+
+// ParseSelector parses a comma separated tag selector such as
+// "site=berlin,env!=test,outdoor,!deprecated". An empty selector matches
+// every camera.
+func ParseSelector(selector string) ([]types.TagRequirement, error) {
+	var requirements []types.TagRequirement
+	if strings.TrimSpace(selector) == "" {
+		return requirements, nil
+	}
+
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.TrimSpace(term)
+
+		var r types.TagRequirement
+		switch {
+		case strings.HasPrefix(term, "!") && !strings.Contains(term, "="):
+			r = types.TagRequirement{Key: term[1:], Operator: types.TagNotExists}
+		case strings.Contains(term, "!="):
+			key, value, _ := strings.Cut(term, "!=")
+			r = types.TagRequirement{Key: strings.TrimSpace(key), Operator: types.TagNotEquals, Value: strings.TrimSpace(value)}
+		case strings.Contains(term, "="):
+			key, value, _ := strings.Cut(term, "=")
+			r = types.TagRequirement{Key: strings.TrimSpace(key), Operator: types.TagEquals, Value: strings.TrimSpace(value)}
+		default:
+			r = types.TagRequirement{Key: term, Operator: types.TagExists}
+		}
+
+		if err := validateTag(r.Key, r.Value); err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %v", term, err)
+		}
+		requirements = append(requirements, r)
+	}
+
+	return requirements, nil
+}
+
+// validateTags checks the tags of a camera.
+func validateTags(tags types.Tags) error {
+	if len(tags) > maxTagsPerCamera {
+		return fmt.Errorf("a camera can have at most %d tags", maxTagsPerCamera)
+	}
+
+	for key, value := range tags {
+		if err := validateTag(key, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateTag checks a key and value. Values cannot contain commas, which
+// separate the terms of a selector.
+func validateTag(key, value string) error {
+	if !tagKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid tag key %q: use up to 63 letters, digits, '.', '_', '/' and '-', starting and ending with a letter or digit", key)
+	}
+	if len(value) > maxTagValueLen || strings.Contains(value, ",") {
+		return fmt.Errorf("invalid value of tag %q: use up to %d characters and no commas", key, maxTagValueLen)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package camerametadata
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go-sample-rest-api/types"
+	"strings"
+	"testing"
+)
+
+func TestParseSelector(t *testing.T) {
+	t.Run("ParseSelector_withEveryOperator_returnsRequirements", func(t *testing.T) {
+		// act
+		requirements, err := ParseSelector("site=berlin, env!=test,outdoor,!deprecated")
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, []types.TagRequirement{
+			{Key: "site", Operator: types.TagEquals, Value: "berlin"},
+			{Key: "env", Operator: types.TagNotEquals, Value: "test"},
+			{Key: "outdoor", Operator: types.TagExists},
+			{Key: "deprecated", Operator: types.TagNotExists},
+		}, requirements)
+	})
+
+	t.Run("ParseSelector_withEmptySelector_matchesEverything", func(t *testing.T) {
+		// act
+		requirements, err := ParseSelector("")
+
+		// assert
+		assert.NoError(t, err)
+		assert.Empty(t, requirements)
+	})
+
+	t.Run("ParseSelector_withInvalidKey_returnsError", func(t *testing.T) {
+		for _, selector := range []string{"site=berlin,", "=berlin", "-site=berlin", "si te=berlin"} {
+			// act
+			_, err := ParseSelector(selector)
+
+			// assert
+			assert.Error(t, err, selector)
+		}
+	})
+}
+
+func TestValidateTags(t *testing.T) {
+	t.Run("validateTags_withValidTags_returnsNil", func(t *testing.T) {
+		assert.NoError(t, validateTags(types.Tags{"site": "berlin", "k8s.io/zone": "", "floor_2": "east-wing"}))
+	})
+
+	t.Run("validateTags_withCommaInValue_returnsError", func(t *testing.T) {
+		assert.Error(t, validateTags(types.Tags{"site": "berlin,munich"}))
+	})
+
+	t.Run("validateTags_withTooLongValue_returnsError", func(t *testing.T) {
+		assert.Error(t, validateTags(types.Tags{"site": strings.Repeat("a", 256)}))
+	})
+}
@@ -0,0 +1,211 @@
+package camerametadata
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"go-sample-rest-api/config"
+	"go-sample-rest-api/customerrors"
+	"go-sample-rest-api/logging"
+	"go-sample-rest-api/types"
+	"go-sample-rest-api/utils"
+	"net/http"
+	"time"
+)
+
+// The operations below are shared by the HTTP handlers and the gRPC API. A
+// camera or image that cannot be found is reported as NotFoundError, the
+// state of a camera as AlreadyInitError or NotInitError, an image ID of
+// another camera as AlreadyExistsError and a full quota as
+// QuotaExceededError. Any other error is a failure of the service.
+
+// CreateCamera creates a camera of the organization owned by ownerID.
+func (h *Handler) CreateCamera(ctx context.Context, orgID int, ownerID sql.NullInt64, payload types.CameraMetadataPayload, entry types.AuditEntry) (*types.CameraMetadata, error) {
+	if err := h.checkQuota(ctx, orgID, 1, 0); err != nil {
+		return nil, err
+	}
+
+	return h.store.CreateCameraMetadata(ctx, types.CameraMetadata{
+		CameraName:      payload.CameraName,
+		FirmwareVersion: payload.FirmwareVersion,
+		CreatedAt:       sql.NullTime{Time: time.Now(), Valid: true},
+		OwnerID:         ownerID,
+		OrgID:           orgID,
+	}, entry)
+}
+
+// GetCamera returns the camera of the organization.
+func (h *Handler) GetCamera(ctx context.Context, orgID int, camID string) (*types.CameraMetadata, error) {
+	camera, err := h.store.GetCameraMetadataByID(ctx, orgID, camID)
+	if err != nil {
+		return nil, &customerrors.NotFoundError{ID: camID}
+	}
+
+	return camera, nil
+}
+
+// ListCameras returns one page of the cameras of the organization matching
+// the filter, and the number of all matching cameras.
+func (h *Handler) ListCameras(ctx context.Context, orgID int, filter types.CameraFilter) ([]types.CameraMetadata, int, error) {
+	return h.store.ListCameraMetadata(ctx, orgID, filter)
+}
+
+// UpdateCamera applies change to the camera of the organization and saves it.
+// The camera is locked while change runs, so change sees the latest state
+// and concurrent updates do not overwrite each other. Errors of change are
+// returned as they are.
+func (h *Handler) UpdateCamera(ctx context.Context, orgID int, camID string, change func(c *types.CameraMetadata) error, entry types.AuditEntry) (*types.CameraMetadata, error) {
+	var changeErr error
+	camera, err := h.store.UpdateCameraMetadata(ctx, orgID, camID, func(c *types.CameraMetadata) error {
+		changeErr = change(c)
+		return changeErr
+	}, entry)
+
+	var (
+		notFound     *customerrors.NotFoundError
+		alreadyTaken *customerrors.AlreadyExistsError
+		quotaReached *customerrors.QuotaExceededError
+	)
+	switch {
+	case err == nil:
+		return camera, nil
+	case changeErr != nil:
+		return nil, changeErr
+	case errors.As(err, &notFound):
+		return nil, &customerrors.NotFoundError{ID: camID}
+	case errors.As(err, &alreadyTaken), errors.As(err, &quotaReached):
+		return nil, err
+	default:
+		return nil, fmt.Errorf("failed to update camera metadata: %v", err)
+	}
+}
+
+// InitializeCamera marks the camera as initialized, which it may only be once.
+func (h *Handler) InitializeCamera(ctx context.Context, orgID int, camID string, entry types.AuditEntry) error {
+	_, err := h.UpdateCamera(ctx, orgID, camID, func(c *types.CameraMetadata) error {
+		if c.InitializedAt.Valid {
+			return &customerrors.AlreadyInitError{ID: camID}
+		}
+
+		c.InitializedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		return nil
+	}, entry)
+
+	return err
+}
+
+// UploadImage stores the image under imageID as the image of the initialized
+// camera, replacing its previous image. imageID must not name the image of
+// another camera.
+func (h *Handler) UploadImage(ctx context.Context, orgID int, camID, imageID string, imageData []byte, entry types.AuditEntry) (*types.CameraMetadata, error) {
+	cameraMetadata, err := h.GetCamera(ctx, orgID, camID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !cameraMetadata.InitializedAt.Valid {
+		return nil, &customerrors.NotInitError{ID: camID}
+	}
+
+	// the new image replaces the previous one of the camera
+	imageSize := int64(len(imageData))
+	if err := h.checkQuota(ctx, cameraMetadata.OrgID, 0, imageSize-cameraMetadata.ImageSizeBytes); err != nil {
+		return nil, err
+	}
+
+	// Blobs are named by their image ID only, so the ID is claimed before the
+	// blob is written; an ID of another camera would overwrite its image.
+	claimed, err := h.store.ClaimImageID(ctx, imageID, camID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim image ID: %v", err)
+	}
+	if !claimed {
+		return nil, &customerrors.AlreadyExistsError{Name: imageID}
+	}
+
+	// The blob is written before the camera points at it, so a failed upload
+	// leaves the previous image in place and the row never references a
+	// missing blob.
+	if err := h.azureStorage.UploadImage(ctx, imageID+".png", imageData); err != nil {
+		return nil, fmt.Errorf("failed to upload image: %v", err)
+	}
+
+	// The previous image is read again under the lock of the update, so of
+	// two concurrent uploads the second replaces the image of the first.
+	previousImageID := cameraMetadata.ImageId
+	cameraMetadata, err = h.UpdateCamera(ctx, orgID, camID, func(c *types.CameraMetadata) error {
+		previousImageID = c.ImageId
+		c.ImageId = sql.NullString{String: imageID, Valid: true}
+		c.ImageSizeBytes = imageSize
+		c.NameOfStoredPicture = sql.NullString{String: imageID, Valid: true}
+		c.ContainerName = sql.NullString{String: config.Envs.AzureContainerName, Valid: true}
+		return nil
+	}, entry)
+	if err != nil {
+		// Re-uploading under the current image ID overwrote the blob the row
+		// still points at, and an ID taken by another camera meanwhile names
+		// its image, so only a blob with a new name is removed again.
+		var alreadyTaken *customerrors.AlreadyExistsError
+		if (!previousImageID.Valid || previousImageID.String != imageID) && !errors.As(err, &alreadyTaken) {
+			h.deleteImage(ctx, imageID, "Failed to remove uploaded image after update error")
+		}
+		return nil, err
+	}
+
+	if previousImageID.Valid && previousImageID.String != imageID {
+		h.deleteImage(ctx, previousImageID.String, "Failed to remove replaced image")
+	}
+
+	logging.GetLogger().WithFields(logrus.Fields{
+		"camera": cameraMetadata,
+	}).Info("Image uploaded successfully")
+
+	return cameraMetadata, nil
+}
+
+// DownloadImage returns the current image of the camera.
+func (h *Handler) DownloadImage(ctx context.Context, orgID int, camID string) ([]byte, error) {
+	cameraMetadata, err := h.GetCamera(ctx, orgID, camID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !cameraMetadata.ImageId.Valid {
+		return nil, &customerrors.NotFoundError{ID: "ImageId"}
+	}
+
+	image, err := h.azureStorage.DownloadImage(ctx, cameraMetadata.ImageId.String+".png")
+	if err != nil {
+		return nil, fmt.Errorf("failed to download image: %v", err)
+	}
+
+	return image, nil
+}
+
+// writeOperationError answers an error of the operations above with its
+// status code.
+func writeOperationError(writer http.ResponseWriter, err error) {
+	var (
+		notFound     *customerrors.NotFoundError
+		notInit      *customerrors.NotInitError
+		alreadyInit  *customerrors.AlreadyInitError
+		quotaReached *customerrors.QuotaExceededError
+		alreadyTaken *customerrors.AlreadyExistsError
+	)
+	switch {
+	case errors.As(err, &notFound):
+		utils.WriteError(writer, http.StatusNotFound, err)
+	case errors.As(err, &notInit):
+		utils.WriteError(writer, http.StatusBadRequest, err)
+	case errors.As(err, &alreadyInit):
+		utils.WriteError(writer, http.StatusConflict, err)
+	case errors.As(err, &quotaReached):
+		utils.WriteError(writer, http.StatusForbidden, err)
+	case errors.As(err, &alreadyTaken):
+		utils.WriteError(writer, http.StatusConflict, err)
+	default:
+		utils.WriteError(writer, http.StatusInternalServerError, err)
+	}
+}
@@ -1,32 +1,72 @@
 package camerametadata
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"github.com/sirupsen/logrus"
+	"go-sample-rest-api/audit"
 	"go-sample-rest-api/customerrors"
 	"go-sample-rest-api/db"
 	"go-sample-rest-api/logging"
+	"go-sample-rest-api/outbox"
 	"go-sample-rest-api/types"
+	"maps"
+	"math"
+	"strings"
+	"time"
 )
 
+const cameraColumns = `cam_id, image_id, camera_name, firmware_version, container_name,
+              name_of_stored_picture, created_at, onboarded_at, initialized_at, owner_id, org_id, image_size_bytes,
+              group_id, tags, latitude, longitude, heading, field_of_view`
+
+// earthRadiusMeters is the mean radius used for distances between cameras.
+const earthRadiusMeters = 6371008.8
+
 type Store struct {
 	db db.DB
+	// quota is checked by the writes that add cameras or bytes of images.
+	quota types.Quota
 }
 
 func NewStore(db db.DB) *Store {
-	return &Store{db: db}
+	return &Store{db: db, quota: QuotaFromConfig()}
 }
 
-func (s *Store) CreateCameraMetadata(camera types.CameraMetadata) (*types.CameraMetadata, error) {
+// CreateCameraMetadata saves a new camera and records entry in the audit log.
+// It fails with a QuotaExceededError if the organization has all the cameras
+// its quota allows.
+func (s *Store) CreateCameraMetadata(ctx context.Context, camera types.CameraMetadata, entry types.AuditEntry) (*types.CameraMetadata, error) {
 	log := logging.GetLogger()
 	query := `INSERT INTO camera_metadata (
-        camera_name, firmware_version, created_at) VALUES ($1, $2, $3) 
-        RETURNING cam_id, camera_name, firmware_version, created_at`
+        camera_name, firmware_version, created_at, owner_id, org_id) VALUES ($1, $2, $3, $4, $5) 
+        RETURNING cam_id, camera_name, firmware_version, created_at, owner_id, org_id`
 
 	var savedCamera types.CameraMetadata
 
-	err := s.db.QueryRow(query, camera.CameraName, camera.FirmwareVersion, camera.CreatedAt).
-		Scan(&savedCamera.CamID, &savedCamera.CameraName, &savedCamera.FirmwareVersion, &savedCamera.CreatedAt)
+	err := s.db.WithTx(ctx, func(tx db.DB) error {
+		if err := s.reserveQuota(ctx, tx, camera.OrgID, 1, 0); err != nil {
+			return err
+		}
+
+		err := tx.QueryRowContext(ctx, query, camera.CameraName, camera.FirmwareVersion, camera.CreatedAt, camera.OwnerID, camera.OrgID).
+			Scan(&savedCamera.CamID, &savedCamera.CameraName, &savedCamera.FirmwareVersion, &savedCamera.CreatedAt, &savedCamera.OwnerID, &savedCamera.OrgID)
+		if err != nil {
+			return err
+		}
+
+		if err := writeAudit(ctx, tx, entry, savedCamera.OrgID, savedCamera.CamID, nil, auditState(savedCamera)); err != nil {
+			return err
+		}
+
+		return writeEvent(ctx, tx, entry.Action, savedCamera)
+	})
+	var quotaReached *customerrors.QuotaExceededError
+	if errors.As(err, &quotaReached) {
+		return nil, err
+	}
 	if err != nil {
 		log.WithFields(logrus.Fields{
 			"camera": camera,
@@ -40,7 +80,15 @@ func (s *Store) CreateCameraMetadata(camera types.CameraMetadata) (*types.Camera
 	return &savedCamera, nil
 }
 
-func (s *Store) UpdateCameraMetadata(camera types.CameraMetadata) (*types.CameraMetadata, error) {
+// UpdateCameraMetadata applies change to the camera of the organization and
+// saves it, recording entry with the changed fields in the audit log. The row
+// is locked from the read to the write, so concurrent updates of a camera are
+// applied one after the other and change always sees the latest state. It
+// fails with a NotFoundError unless the camera belongs to orgID, with an
+// AlreadyExistsError if another camera references the new image and with a
+// QuotaExceededError if a larger image exceeds the storage quota; an error of
+// change aborts the update and is returned as it is.
+func (s *Store) UpdateCameraMetadata(ctx context.Context, orgID int, camID string, change func(c *types.CameraMetadata) error, entry types.AuditEntry) (*types.CameraMetadata, error) {
 	log := logging.GetLogger()
 	query := `
         UPDATE camera_metadata
@@ -51,40 +99,87 @@ func (s *Store) UpdateCameraMetadata(camera types.CameraMetadata) (*types.Camera
             created_at = $5, 
             onboarded_at = $6, 
             initialized_at = $7,
-            image_id = $8
-        WHERE cam_id = $9;
+            image_id = $8,
+            image_size_bytes = $9,
+            group_id = $10,
+            tags = $11,
+            latitude = $12,
+            longitude = $13,
+            heading = $14,
+            field_of_view = $15
+        WHERE cam_id = $16 AND org_id = $17;
     `
 
-	_, err := s.db.Exec(query, camera.CameraName, camera.FirmwareVersion, camera.ContainerName, camera.NameOfStoredPicture, camera.CreatedAt, camera.OnboardedAt, camera.InitializedAt, camera.ImageId, camera.CamID)
+	var camera types.CameraMetadata
+	err := s.db.WithTx(ctx, func(tx db.DB) error {
+		err := scanCamera(tx.QueryRowContext(ctx, "SELECT "+cameraColumns+" FROM camera_metadata WHERE cam_id = $1 AND org_id = $2 FOR UPDATE",
+			camID, orgID), &camera)
+		if err == sql.ErrNoRows {
+			return &customerrors.NotFoundError{ID: camID}
+		}
+		if err != nil {
+			return err
+		}
+
+		before := camera
+		before.Tags = maps.Clone(camera.Tags)
+		if err := change(&camera); err != nil {
+			return err
+		}
+
+		// a larger image counts against the storage quota
+		if err := s.reserveQuota(ctx, tx, orgID, 0, camera.ImageSizeBytes-before.ImageSizeBytes); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, query, camera.CameraName, camera.FirmwareVersion, camera.ContainerName, camera.NameOfStoredPicture, camera.CreatedAt, camera.OnboardedAt, camera.InitializedAt, camera.ImageId, camera.ImageSizeBytes, camera.GroupID, camera.Tags,
+			camera.Latitude, camera.Longitude, camera.Heading, camera.FieldOfView, camID, orgID)
+		if err != nil {
+			return err
+		}
+
+		if err := writeAudit(ctx, tx, entry, orgID, camID, auditState(before), auditState(camera)); err != nil {
+			return err
+		}
+
+		return writeEvent(ctx, tx, entry.Action, camera)
+	})
+	var quotaReached *customerrors.QuotaExceededError
+	if errors.As(err, &quotaReached) {
+		return nil, err
+	}
 	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, &customerrors.AlreadyExistsError{Name: camera.ImageId.String}
+		}
 		log.WithFields(logrus.Fields{
-			"camera": camera,
-			"error":  err,
+			"camID": camID,
+			"orgID": orgID,
+			"error": err,
 		}).Error("Error updating camera metadata")
 		return nil, err
 	}
+
 	log.WithFields(logrus.Fields{
 		"camera": camera,
 	}).Info("Camera metadata updated successfully")
 	return &camera, nil
 }
 
-func (s *Store) GetCameraMetadataByID(camID string) (*types.CameraMetadata, error) {
+func (s *Store) GetCameraMetadataByID(ctx context.Context, orgID int, camID string) (*types.CameraMetadata, error) {
 	log := logging.GetLogger()
-	query := `SELECT cam_id, image_id, camera_name, firmware_version, container_name,
-              name_of_stored_picture, created_at, onboarded_at, initialized_at 
-              FROM camera_metadata WHERE cam_id = $1`
+	query := `SELECT ` + cameraColumns + ` FROM camera_metadata WHERE cam_id = $1 AND org_id = $2`
 
-	row := s.db.QueryRow(query, camID)
+	row := s.db.QueryRowContext(ctx, query, camID, orgID)
 
 	c := new(types.CameraMetadata)
 
-	err := row.Scan(&c.CamID, &c.ImageId, &c.CameraName, &c.FirmwareVersion, &c.ContainerName,
-		&c.NameOfStoredPicture, &c.CreatedAt, &c.OnboardedAt, &c.InitializedAt)
+	err := scanCamera(row, c)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.WithFields(logrus.Fields{
 				"camID": camID,
+				"orgID": orgID,
 			}).Error("Camera metadata not found")
 
 			return nil, &customerrors.NotFoundError{ID: camID}
@@ -98,3 +193,566 @@ func (s *Store) GetCameraMetadataByID(camID string) (*types.CameraMetadata, erro
 
 	return c, nil
 }
+
+// ListCameraMetadataByOwner returns the cameras of the user in the organization, oldest first.
+func (s *Store) ListCameraMetadataByOwner(ctx context.Context, orgID, ownerID int) ([]types.CameraMetadata, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+cameraColumns+` FROM camera_metadata WHERE owner_id = $1 AND org_id = $2 ORDER BY created_at`, ownerID, orgID)
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"orgID":   orgID,
+			"ownerID": ownerID,
+			"error":   err,
+		}).Error("Error listing camera metadata")
+		return nil, err
+	}
+	defer rows.Close()
+
+	cameras := make([]types.CameraMetadata, 0)
+	for rows.Next() {
+		var c types.CameraMetadata
+		if err := scanCamera(rows, &c); err != nil {
+			return nil, err
+		}
+		cameras = append(cameras, c)
+	}
+
+	return cameras, rows.Err()
+}
+
+// ReassignCameraMetadata hands the cameras of one user in the organization over to another.
+func (s *Store) ReassignCameraMetadata(ctx context.Context, orgID, fromOwnerID, toOwnerID int, entry types.AuditEntry) error {
+	log := logging.GetLogger()
+	var n int
+	err := s.db.WithTx(ctx, func(tx db.DB) error {
+		camIDs, err := queryCamIDs(ctx, tx, "UPDATE camera_metadata SET owner_id = $1 WHERE owner_id = $2 AND org_id = $3 RETURNING cam_id",
+			toOwnerID, fromOwnerID, orgID)
+		if err != nil {
+			return err
+		}
+
+		n = len(camIDs)
+		for _, camID := range camIDs {
+			err := writeAudit(ctx, tx, entry, orgID, camID,
+				map[string]any{"owner_id": int64(fromOwnerID)}, map[string]any{"owner_id": int64(toOwnerID)})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"orgID":       orgID,
+			"fromOwnerID": fromOwnerID,
+			"toOwnerID":   toOwnerID,
+			"error":       err,
+		}).Error("Error reassigning camera metadata")
+		return err
+	}
+
+	log.WithFields(logrus.Fields{
+		"orgID":       orgID,
+		"fromOwnerID": fromOwnerID,
+		"toOwnerID":   toOwnerID,
+		"cameras":     n,
+	}).Info("Camera metadata reassigned")
+	return nil
+}
+
+// DeleteCameraMetadata deletes the camera and records entry with its last
+// values in the audit log.
+func (s *Store) DeleteCameraMetadata(ctx context.Context, orgID int, camID string, entry types.AuditEntry) error {
+	log := logging.GetLogger()
+	err := s.db.WithTx(ctx, func(tx db.DB) error {
+		var before types.CameraMetadata
+		err := scanCamera(tx.QueryRowContext(ctx, "DELETE FROM camera_metadata WHERE cam_id = $1 AND org_id = $2 RETURNING "+cameraColumns, camID, orgID), &before)
+		if err == sql.ErrNoRows {
+			return &customerrors.NotFoundError{ID: camID}
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM camera_image_ids WHERE cam_id = $1", camID); err != nil {
+			return err
+		}
+
+		return writeAudit(ctx, tx, entry, orgID, camID, auditState(before), nil)
+	})
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"camID": camID,
+			"error": err,
+		}).Error("Error deleting camera metadata")
+		return err
+	}
+
+	log.WithFields(logrus.Fields{
+		"camID": camID,
+	}).Info("Camera metadata deleted")
+	return nil
+}
+
+// ListCameraMetadata returns the cameras of the organization matching the
+// filter, oldest first or nearest first for filter.Near, and the total number
+// of matches.
+func (s *Store) ListCameraMetadata(ctx context.Context, orgID int, filter types.CameraFilter) ([]types.CameraMetadata, int, error) {
+	where, args, err := cameraFilter(orgID, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM camera_metadata WHERE "+where, args...).Scan(&total); err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"orgID": orgID,
+			"error": err,
+		}).Error("Error counting camera metadata")
+		return nil, 0, err
+	}
+
+	orderBy := "created_at, cam_id"
+	if filter.Near != nil {
+		args = append(args, filter.Near.Latitude, filter.Near.Longitude)
+		orderBy = distance(len(args)-1, len(args)) + ", " + orderBy
+	}
+
+	query := "SELECT " + cameraColumns + " FROM camera_metadata WHERE " + where + " ORDER BY " + orderBy
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit, filter.Offset)
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"orgID": orgID,
+			"error": err,
+		}).Error("Error listing camera metadata")
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	cameras := make([]types.CameraMetadata, 0)
+	for rows.Next() {
+		var c types.CameraMetadata
+		if err := scanCamera(rows, &c); err != nil {
+			return nil, 0, err
+		}
+		cameras = append(cameras, c)
+	}
+
+	return cameras, total, rows.Err()
+}
+
+// BulkUpdateCameraMetadata applies the update to every camera of the
+// organization matching the filter and returns how many it changed. Tags are
+// removed after the new ones are set. The group must belong to the organization.
+// Every changed camera gets its own audit entry.
+func (s *Store) BulkUpdateCameraMetadata(ctx context.Context, orgID int, filter types.CameraFilter, update types.CameraBulkUpdate, entry types.AuditEntry) (int64, error) {
+	log := logging.GetLogger()
+	where, args, err := cameraFilter(orgID, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	filterArgs := len(args)
+	var sets []string
+	if update.SetGroupID != nil {
+		args = append(args, *update.SetGroupID)
+		sets = append(sets, fmt.Sprintf("group_id = $%d", len(args)))
+	} else if update.ClearGroup {
+		sets = append(sets, "group_id = NULL")
+	}
+	if len(update.SetTags) > 0 || len(update.RemoveTags) > 0 {
+		tags := "tags"
+		if len(update.SetTags) > 0 {
+			args = append(args, update.SetTags)
+			tags = fmt.Sprintf("(tags || $%d::jsonb)", len(args))
+		}
+		for _, key := range update.RemoveTags {
+			args = append(args, key)
+			tags += fmt.Sprintf(" - $%d::text", len(args))
+		}
+		sets = append(sets, "tags = "+tags)
+	}
+	if len(sets) == 0 {
+		return 0, nil
+	}
+
+	var n int64
+	err = s.db.WithTx(ctx, func(tx db.DB) error {
+		before, err := queryGroupsAndTags(ctx, tx, "SELECT cam_id, group_id, tags FROM camera_metadata WHERE "+where+" FOR UPDATE", args[:filterArgs]...)
+		if err != nil {
+			return err
+		}
+
+		after, err := queryGroupsAndTags(ctx, tx, "UPDATE camera_metadata SET "+strings.Join(sets, ", ")+" WHERE "+where+" RETURNING cam_id, group_id, tags", args...)
+		if err != nil {
+			return err
+		}
+
+		n = int64(len(after))
+		for camID, state := range after {
+			if err := writeAudit(ctx, tx, entry, orgID, camID, before[camID], state); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"orgID": orgID,
+			"error": err,
+		}).Error("Error bulk updating camera metadata")
+		return 0, err
+	}
+
+	log.WithFields(logrus.Fields{
+		"orgID":   orgID,
+		"cameras": n,
+	}).Info("Camera metadata bulk updated")
+	return n, nil
+}
+
+// cameraFilter builds the WHERE clause selecting the cameras of the
+// organization that match the filter, starting with parameter $1.
+func cameraFilter(orgID int, filter types.CameraFilter) (string, []any, error) {
+	conditions := []string{"org_id = $1"}
+	args := []any{orgID}
+
+	if filter.GroupID != nil {
+		args = append(args, *filter.GroupID)
+		conditions = append(conditions, fmt.Sprintf("group_id = $%d", len(args)))
+	}
+
+	for _, r := range filter.Selector {
+		switch r.Operator {
+		case types.TagEquals:
+			args = append(args, types.Tags{r.Key: r.Value})
+			conditions = append(conditions, fmt.Sprintf("tags @> $%d::jsonb", len(args)))
+		case types.TagNotEquals:
+			// like a Kubernetes label selector, cameras without the key match too
+			args = append(args, r.Key, r.Value)
+			conditions = append(conditions, fmt.Sprintf("(tags ->> $%d::text) IS DISTINCT FROM $%d", len(args)-1, len(args)))
+		case types.TagExists:
+			args = append(args, r.Key)
+			conditions = append(conditions, fmt.Sprintf("tags ? $%d", len(args)))
+		case types.TagNotExists:
+			args = append(args, r.Key)
+			conditions = append(conditions, fmt.Sprintf("NOT (tags ? $%d)", len(args)))
+		default:
+			return "", nil, fmt.Errorf("unknown tag operator %q", r.Operator)
+		}
+	}
+
+	if b := filter.Within; b != nil {
+		args = append(args, b.MinLatitude, b.MaxLatitude, b.MinLongitude, b.MaxLongitude)
+		longitude := "longitude BETWEEN $%[3]d AND $%[4]d"
+		if b.MinLongitude > b.MaxLongitude {
+			longitude = "(longitude >= $%[3]d OR longitude <= $%[4]d)"
+		}
+		n := len(args)
+		conditions = append(conditions, fmt.Sprintf("latitude BETWEEN $%[1]d AND $%[2]d AND "+longitude, n-3, n-2, n-1, n))
+	}
+
+	if c := filter.Near; c != nil {
+		// the latitude range lets the location index skip most cameras
+		delta := c.RadiusMeters / earthRadiusMeters * 180 / math.Pi
+		args = append(args, c.Latitude-delta, c.Latitude+delta, c.Latitude, c.Longitude, c.RadiusMeters)
+		n := len(args)
+		conditions = append(conditions, fmt.Sprintf("latitude BETWEEN $%d AND $%d AND %s <= $%d", n-4, n-3, distance(n-2, n-1), n))
+	}
+
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+// distance is the SQL expression for the great-circle distance in meters
+// between a camera and the point in the given parameters, using the haversine
+// formula. Rounding can take the argument of ASIN slightly above 1 for nearly
+// antipodal points, which Postgres rejects, so it is capped.
+func distance(latitude, longitude int) string {
+	return fmt.Sprintf("(%.1f * 2 * ASIN(LEAST(1, SQRT(POWER(SIN(RADIANS(latitude - $%[2]d) / 2), 2) + "+
+		"COS(RADIANS($%[2]d)) * COS(RADIANS(latitude)) * POWER(SIN(RADIANS(longitude - $%[3]d) / 2), 2)))))",
+		earthRadiusMeters, latitude, longitude)
+}
+
+// ClaimImageID reserves the image ID for the camera and reports whether it
+// succeeded. An ID claimed by another camera, in any organization, is refused,
+// because the blobs are named by the ID alone. Claims are kept until the
+// camera is deleted, and the claim of concurrent uploads is decided by the
+// primary key, so only one camera ever writes the blob of an ID.
+func (s *Store) ClaimImageID(ctx context.Context, imageID, camID string) (bool, error) {
+	var owner string
+	err := s.db.QueryRowContext(ctx, `INSERT INTO camera_image_ids (image_id, cam_id) VALUES ($1, $2)
+              ON CONFLICT (image_id) DO UPDATE SET claimed_at = camera_image_ids.claimed_at WHERE camera_image_ids.cam_id = EXCLUDED.cam_id
+              RETURNING cam_id`, imageID, camID).Scan(&owner)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"imageID": imageID,
+			"error":   err,
+		}).Error("Error claiming image ID")
+		return false, err
+	}
+
+	return true, nil
+}
+
+// reserveQuota locks the organization and fails with a QuotaExceededError if
+// adding the cameras and bytes takes it over the quota. The lock is held until
+// tx ends, so the checks and writes of concurrent requests of an organization
+// run one after the other.
+func (s *Store) reserveQuota(ctx context.Context, tx db.DB, orgID int, cameras, bytes int64) error {
+	if !limits(s.quota, cameras, bytes) {
+		return nil
+	}
+
+	var locked int
+	if err := tx.QueryRowContext(ctx, "SELECT id FROM organizations WHERE id = $1 FOR NO KEY UPDATE", orgID).Scan(&locked); err != nil {
+		return err
+	}
+
+	var usage types.Usage
+	err := tx.QueryRowContext(ctx, "SELECT COUNT(*), COALESCE(SUM(image_size_bytes), 0) FROM camera_metadata WHERE org_id = $1", orgID).
+		Scan(&usage.Cameras, &usage.StorageBytes)
+	if err != nil {
+		return err
+	}
+
+	return exceedsQuota(s.quota, usage, cameras, bytes)
+}
+
+// GetUsage counts the cameras of the organization and the bytes of their images.
+func (s *Store) GetUsage(ctx context.Context, orgID int) (*types.Usage, error) {
+	u := new(types.Usage)
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*), COALESCE(SUM(image_size_bytes), 0) FROM camera_metadata WHERE org_id = $1", orgID).
+		Scan(&u.Cameras, &u.StorageBytes)
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"orgID": orgID,
+			"error": err,
+		}).Error("Error retrieving usage")
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// ListUsage returns the usage of every organization that has cameras.
+func (s *Store) ListUsage(ctx context.Context) (map[int]types.Usage, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT org_id, COUNT(*), COALESCE(SUM(image_size_bytes), 0) FROM camera_metadata GROUP BY org_id")
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Error listing usage")
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := make(map[int]types.Usage)
+	for rows.Next() {
+		var orgID int
+		var u types.Usage
+		if err := rows.Scan(&orgID, &u.Cameras, &u.StorageBytes); err != nil {
+			return nil, err
+		}
+		usage[orgID] = u
+	}
+
+	return usage, rows.Err()
+}
+
+// ListImageReferences returns the image of every camera that has one, in all
+// organizations.
+func (s *Store) ListImageReferences(ctx context.Context) ([]types.ImageReference, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT org_id, cam_id, image_id FROM camera_metadata WHERE image_id IS NOT NULL ORDER BY org_id, cam_id")
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Error listing image references")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []types.ImageReference
+	for rows.Next() {
+		var ref types.ImageReference
+		if err := rows.Scan(&ref.OrgID, &ref.CamID, &ref.ImageID); err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+
+	return refs, rows.Err()
+}
+
+// ClearImage removes the image from the camera of the reference and records
+// entry in the audit log. It fails with a NotFoundError when the camera no
+// longer points at that image, so a newer upload is never cleared.
+func (s *Store) ClearImage(ctx context.Context, ref types.ImageReference, entry types.AuditEntry) error {
+	log := logging.GetLogger()
+	err := s.db.WithTx(ctx, func(tx db.DB) error {
+		var before types.CameraMetadata
+		err := scanCamera(tx.QueryRowContext(ctx, "SELECT "+cameraColumns+" FROM camera_metadata WHERE cam_id = $1 AND org_id = $2 AND image_id = $3 FOR UPDATE",
+			ref.CamID, ref.OrgID, ref.ImageID), &before)
+		if err == sql.ErrNoRows {
+			return &customerrors.NotFoundError{ID: ref.CamID}
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE camera_metadata
+              SET image_id = NULL, name_of_stored_picture = NULL, container_name = NULL, image_size_bytes = 0
+              WHERE cam_id = $1 AND org_id = $2`, ref.CamID, ref.OrgID)
+		if err != nil {
+			return err
+		}
+
+		after := before
+		after.ImageId = sql.NullString{}
+		after.ImageSizeBytes = 0
+		return writeAudit(ctx, tx, entry, ref.OrgID, ref.CamID, auditState(before), auditState(after))
+	})
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"image": ref,
+			"error": err,
+		}).Error("Error clearing camera image")
+		return err
+	}
+
+	log.WithFields(logrus.Fields{
+		"image": ref,
+	}).Info("Camera image cleared")
+	return nil
+}
+
+// writeAudit completes the entry for a camera and appends it to the audit log.
+func writeAudit(ctx context.Context, tx db.DB, entry types.AuditEntry, orgID int, camID string, before, after map[string]any) error {
+	entry.OrgID = orgID
+	entry.ResourceType = "camera"
+	entry.ResourceID = camID
+	entry.Changes = audit.Changes(before, after)
+	return audit.Write(ctx, tx, entry)
+}
+
+// cameraEvents are the events published for the actions of audit entries.
+// The other changes of cameras publish no event.
+var cameraEvents = map[string]string{
+	ActionCreate:      types.EventCameraCreated,
+	ActionInitialize:  types.EventCameraInitialized,
+	ActionUploadImage: types.EventCameraImageUploaded,
+}
+
+// writeEvent appends the event of the action to the outbox, if it has one.
+func writeEvent(ctx context.Context, tx db.DB, action string, c types.CameraMetadata) error {
+	eventType, ok := cameraEvents[action]
+	if !ok {
+		return nil
+	}
+
+	data := types.CameraEvent{CameraMetadataResponse: cameraResponse(c)}
+	if c.ImageId.Valid {
+		data.ImageID = &c.ImageId.String
+	}
+	return outbox.Write(ctx, tx, c.OrgID, eventType, data)
+}
+
+// auditState returns the fields of a camera that the audit log compares.
+// Times are formatted, so that values read from the database and set by a
+// handler compare equal.
+func auditState(c types.CameraMetadata) map[string]any {
+	state := map[string]any{
+		"camera_name":      c.CameraName,
+		"firmware_version": c.FirmwareVersion,
+		"image_id":         nil,
+		"image_size_bytes": c.ImageSizeBytes,
+		"initialized_at":   nil,
+		"owner_id":         nil,
+		"group_id":         nil,
+		"tags":             c.Tags,
+		"latitude":         nil,
+		"longitude":        nil,
+		"heading":          nil,
+		"field_of_view":    nil,
+	}
+	if c.ImageId.Valid {
+		state["image_id"] = c.ImageId.String
+	}
+	if c.InitializedAt.Valid {
+		state["initialized_at"] = c.InitializedAt.Time.UTC().Format(time.RFC3339Nano)
+	}
+	if c.OwnerID.Valid {
+		state["owner_id"] = c.OwnerID.Int64
+	}
+	if c.GroupID.Valid {
+		state["group_id"] = c.GroupID.Int64
+	}
+	if c.Tags == nil {
+		state["tags"] = types.Tags{}
+	}
+	for field, v := range map[string]sql.NullFloat64{
+		"latitude":      c.Latitude,
+		"longitude":     c.Longitude,
+		"heading":       c.Heading,
+		"field_of_view": c.FieldOfView,
+	} {
+		if v.Valid {
+			state[field] = v.Float64
+		}
+	}
+
+	return state
+}
+
+// queryGroupsAndTags runs a query returning cam_id, group_id and tags, and
+// returns the audit state of the group and tags by camera.
+func queryGroupsAndTags(ctx context.Context, tx db.DB, query string, args ...any) (map[string]map[string]any, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := make(map[string]map[string]any)
+	for rows.Next() {
+		var c types.CameraMetadata
+		if err := rows.Scan(&c.CamID, &c.GroupID, &c.Tags); err != nil {
+			return nil, err
+		}
+		state := auditState(c)
+		states[c.CamID] = map[string]any{"group_id": state["group_id"], "tags": state["tags"]}
+	}
+
+	return states, rows.Err()
+}
+
+func queryCamIDs(ctx context.Context, tx db.DB, query string, args ...any) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var camIDs []string
+	for rows.Next() {
+		var camID string
+		if err := rows.Scan(&camID); err != nil {
+			return nil, err
+		}
+		camIDs = append(camIDs, camID)
+	}
+
+	return camIDs, rows.Err()
+}
+
+// scanCamera reads the cameraColumns from a *sql.Row or *sql.Rows.
+func scanCamera(row interface{ Scan(dest ...any) error }, c *types.CameraMetadata) error {
+	return row.Scan(&c.CamID, &c.ImageId, &c.CameraName, &c.FirmwareVersion, &c.ContainerName,
+		&c.NameOfStoredPicture, &c.CreatedAt, &c.OnboardedAt, &c.InitializedAt, &c.OwnerID, &c.OrgID, &c.ImageSizeBytes,
+		&c.GroupID, &c.Tags, &c.Latitude, &c.Longitude, &c.Heading, &c.FieldOfView)
+}
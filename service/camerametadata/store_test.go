@@ -1,16 +1,23 @@
 package camerametadata
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
+	"go-sample-rest-api/customerrors"
 	db2 "go-sample-rest-api/db"
 	"go-sample-rest-api/types"
+	"math"
 	"testing"
 	"time"
 )
 
+var cameraRowColumns = []string{"cam_id", "image_id", "camera_name", "firmware_version", "container_name", "name_of_stored_picture", "created_at", "onboarded_at", "initialized_at", "owner_id", "org_id", "image_size_bytes", "group_id", "tags", "latitude", "longitude", "heading", "field_of_view"}
+
 func setupMockDB(t *testing.T) (*db2.SQLDB, sqlmock.Sqlmock, func()) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -30,7 +37,7 @@ func TestStore_CreateCameraMetadata(t *testing.T) {
 		db, mock, cleanup := setupMockDB(t)
 		defer cleanup()
 
-		store := Store{db}
+		store := Store{db: db}
 
 		timeNow := time.Now()
 		nullTime := sql.NullTime{
@@ -41,17 +48,28 @@ func TestStore_CreateCameraMetadata(t *testing.T) {
 			CameraName:      "Test Camera",
 			FirmwareVersion: "v1.0",
 			CreatedAt:       nullTime,
+			OrgID:           3,
 		}
 
 		expectedID := uuid.New().String()
 
+		mock.ExpectBegin()
 		mock.ExpectQuery(`INSERT INTO camera_metadata`).
-			WithArgs(camera.CameraName, camera.FirmwareVersion, camera.CreatedAt).
-			WillReturnRows(sqlmock.NewRows([]string{"cam_id", "camera_name", "firmware_version", "created_at"}).
-				AddRow(expectedID, camera.CameraName, camera.FirmwareVersion, camera.CreatedAt))
+			WithArgs(camera.CameraName, camera.FirmwareVersion, camera.CreatedAt, camera.OwnerID, camera.OrgID).
+			WillReturnRows(sqlmock.NewRows([]string{"cam_id", "camera_name", "firmware_version", "created_at", "owner_id", "org_id"}).
+				AddRow(expectedID, camera.CameraName, camera.FirmwareVersion, camera.CreatedAt, camera.OwnerID, camera.OrgID))
+		mock.ExpectExec(`INSERT INTO audit_log`).
+			WithArgs(3, "user", "7", "camera.create", "camera", expectedID, sqlmock.AnyArg(), "req-1", "203.0.113.7").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(`INSERT INTO outbox_events`).
+			WithArgs(3, types.EventCameraCreated, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
 
 		// act
-		savedCamera, err := store.CreateCameraMetadata(camera)
+		savedCamera, err := store.CreateCameraMetadata(context.Background(), camera, types.AuditEntry{
+			ActorType: "user", ActorID: "7", Action: "camera.create", RequestID: "req-1", IP: "203.0.113.7",
+		})
 
 		// assert
 		if err := mock.ExpectationsWereMet(); err != nil {
@@ -70,7 +88,7 @@ func TestStore_CreateCameraMetadata(t *testing.T) {
 		db, mock, cleanup := setupMockDB(t)
 		defer cleanup()
 
-		store := Store{db}
+		store := Store{db: db}
 
 		timeNow := time.Now()
 		nullTime := sql.NullTime{
@@ -83,12 +101,14 @@ func TestStore_CreateCameraMetadata(t *testing.T) {
 			CreatedAt:       nullTime,
 		}
 
+		mock.ExpectBegin()
 		mock.ExpectQuery(`INSERT INTO camera_metadata`).
-			WithArgs(camera.CameraName, camera.FirmwareVersion, camera.CreatedAt).
+			WithArgs(camera.CameraName, camera.FirmwareVersion, camera.CreatedAt, camera.OwnerID, camera.OrgID).
 			WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
 
 		// act
-		_, err := store.CreateCameraMetadata(camera)
+		_, err := store.CreateCameraMetadata(context.Background(), camera, types.AuditEntry{})
 
 		// assert
 		if err := mock.ExpectationsWereMet(); err != nil {
@@ -103,6 +123,125 @@ func TestStore_CreateCameraMetadata(t *testing.T) {
 			t.Errorf("expected a sql.ErrConnDone error, got %v", err)
 		}
 	})
+	t.Run("UpdateCameraMetadata_withImageIDOfOtherCamera_returnsAlreadyExists", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+		store := Store{db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`^SELECT .* FROM camera_metadata WHERE cam_id = \$1 AND org_id = \$2 FOR UPDATE$`).
+			WithArgs("123", 4).
+			WillReturnRows(sqlmock.NewRows(cameraRowColumns).
+				AddRow("123", nil, "Test Camera", "v1.0", nil, nil, time.Now(), nil, nil, nil, 4, 0, nil, []byte(`{}`), nil, nil, nil, nil))
+		mock.ExpectExec("UPDATE camera_metadata").WillReturnError(&pq.Error{Code: "23505"})
+		mock.ExpectRollback()
+
+		change := func(c *types.CameraMetadata) error {
+			c.ImageId = sql.NullString{String: "img-1", Valid: true}
+			return nil
+		}
+
+		// act
+		updatedCamera, err := store.UpdateCameraMetadata(context.Background(), 4, "123", change, types.AuditEntry{})
+
+		// assert
+		var alreadyExists *customerrors.AlreadyExistsError
+		assert.ErrorAs(t, err, &alreadyExists)
+		assert.Equal(t, "img-1", alreadyExists.Name)
+		assert.Nil(t, updatedCamera)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("UpdateCameraMetadata_withChangeError_rollsBack", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+		store := Store{db: db}
+
+		createdAt := time.Now()
+		initializedAt := time.Now()
+		mock.ExpectBegin()
+		mock.ExpectQuery(`^SELECT .* FROM camera_metadata WHERE cam_id = \$1 AND org_id = \$2 FOR UPDATE$`).
+			WithArgs("123", 4).
+			WillReturnRows(sqlmock.NewRows(cameraRowColumns).
+				AddRow("123", nil, "Test Camera", "v1.0", nil, nil, createdAt, nil, initializedAt, nil, 4, 0, nil, []byte(`{}`), nil, nil, nil, nil))
+		mock.ExpectRollback()
+
+		var seen types.CameraMetadata
+		change := func(c *types.CameraMetadata) error {
+			seen = *c
+			return &customerrors.AlreadyInitError{ID: c.CamID}
+		}
+
+		// act
+		updatedCamera, err := store.UpdateCameraMetadata(context.Background(), 4, "123", change, types.AuditEntry{})
+
+		// assert
+		var alreadyInit *customerrors.AlreadyInitError
+		assert.ErrorAs(t, err, &alreadyInit)
+		assert.Nil(t, updatedCamera)
+		assert.True(t, seen.InitializedAt.Valid, "change should see the locked row")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStore_Quota(t *testing.T) {
+	t.Run("CreateCameraMetadata_atCameraQuota_returnQuotaExceededError", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := Store{db: db, quota: types.Quota{MaxCameras: 2}}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id FROM organizations WHERE id = \$1 FOR NO KEY UPDATE`).
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectQuery(`SELECT COUNT\(\*\), COALESCE\(SUM\(image_size_bytes\), 0\) FROM camera_metadata WHERE org_id = \$1`).
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"count", "sum"}).AddRow(2, 0))
+		mock.ExpectRollback()
+
+		// act
+		_, err := store.CreateCameraMetadata(context.Background(), types.CameraMetadata{CameraName: "Test Camera", OrgID: 3}, types.AuditEntry{})
+
+		// assert
+		assert.NoError(t, mock.ExpectationsWereMet(), "Expectations were not met")
+		assert.IsType(t, &customerrors.QuotaExceededError{}, err)
+	})
+	t.Run("UpdateCameraMetadata_withLargerImageOverStorageQuota_returnQuotaExceededError", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := Store{db: db, quota: types.Quota{MaxStorageBytes: 1000}}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`^SELECT .* FROM camera_metadata WHERE cam_id = \$1 AND org_id = \$2 FOR UPDATE$`).
+			WithArgs("123", 3).
+			WillReturnRows(sqlmock.NewRows(cameraRowColumns).
+				AddRow("123", "img-1", "Camera", "v1.0", nil, nil, time.Now(), nil, time.Now(), nil, 3, 400, nil, []byte(`{}`), nil, nil, nil, nil))
+		mock.ExpectQuery(`SELECT id FROM organizations WHERE id = \$1 FOR NO KEY UPDATE`).
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectQuery(`SELECT COUNT\(\*\), COALESCE\(SUM\(image_size_bytes\), 0\) FROM camera_metadata WHERE org_id = \$1`).
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"count", "sum"}).AddRow(2, 900))
+		mock.ExpectRollback()
+
+		change := func(c *types.CameraMetadata) error {
+			c.ImageId = sql.NullString{String: "img-2", Valid: true}
+			c.ImageSizeBytes = 600
+			return nil
+		}
+
+		// act
+		_, err := store.UpdateCameraMetadata(context.Background(), 3, "123", change, types.AuditEntry{})
+
+		// assert
+		assert.NoError(t, mock.ExpectationsWereMet(), "Expectations were not met")
+		assert.IsType(t, &customerrors.QuotaExceededError{}, err)
+	})
 }
 
 func TestStore_GetCameraMetadataByID(t *testing.T) {
@@ -111,18 +250,18 @@ func TestStore_GetCameraMetadataByID(t *testing.T) {
 		db, mock, cleanup := setupMockDB(t)
 		defer cleanup()
 
-		store := Store{db}
+		store := Store{db: db}
 
 		camID := uuid.New().String()
 
-		rows := sqlmock.NewRows([]string{"cam_id", "image_id", "camera_name", "firmware_version", "container_name", "name_of_stored_picture", "created_at", "onboarded_at", "initialized_at"}).
-			AddRow(camID, nil, "Test Camera", "v1.0", nil, nil, time.Now(), time.Now(), time.Now())
-		mock.ExpectQuery(`^SELECT cam_id, image_id, camera_name, firmware_version, container_name, name_of_stored_picture, created_at, onboarded_at, initialized_at FROM camera_metadata WHERE cam_id = \$1$`).
-			WithArgs(camID).
+		rows := sqlmock.NewRows(cameraRowColumns).
+			AddRow(camID, nil, "Test Camera", "v1.0", nil, nil, time.Now(), time.Now(), time.Now(), 7, 3, 0, nil, []byte(`{"site":"berlin"}`), nil, nil, nil, nil)
+		mock.ExpectQuery(`^SELECT cam_id, image_id, camera_name, firmware_version, container_name, name_of_stored_picture, created_at, onboarded_at, initialized_at, owner_id, org_id, image_size_bytes, group_id, tags, latitude, longitude, heading, field_of_view FROM camera_metadata WHERE cam_id = \$1 AND org_id = \$2$`).
+			WithArgs(camID, 3).
 			WillReturnRows(rows)
 
 		// act
-		cameraMetadata, err := store.GetCameraMetadataByID(camID)
+		cameraMetadata, err := store.GetCameraMetadataByID(context.Background(), 3, camID)
 
 		// assert
 		assert.NoError(t, mock.ExpectationsWereMet())
@@ -130,6 +269,8 @@ func TestStore_GetCameraMetadataByID(t *testing.T) {
 		assert.NoError(t, err)
 		assert.NotNil(t, cameraMetadata)
 		assert.Equal(t, camID, cameraMetadata.CamID)
+		assert.Equal(t, 3, cameraMetadata.OrgID)
+		assert.Equal(t, "berlin", cameraMetadata.Tags["site"])
 
 		if err := mock.ExpectationsWereMet(); err != nil {
 			t.Errorf("there were unfulfilled expectations: %s", err)
@@ -143,15 +284,15 @@ func TestStore_GetCameraMetadataByID(t *testing.T) {
 		// arrange
 		db, mock, cleanup := setupMockDB(t)
 		defer cleanup()
-		store := Store{db}
+		store := Store{db: db}
 
 		camID := "non-existent-id"
-		mock.ExpectQuery(`^SELECT.*FROM camera_metadata WHERE cam_id = \$1$`).
-			WithArgs(camID).
+		mock.ExpectQuery(`^SELECT.*FROM camera_metadata WHERE cam_id = \$1 AND org_id = \$2$`).
+			WithArgs(camID, 3).
 			WillReturnError(sql.ErrNoRows)
 
 		// act
-		cameraMetadata, err := store.GetCameraMetadataByID(camID)
+		cameraMetadata, err := store.GetCameraMetadataByID(context.Background(), 3, camID)
 
 		// assert
 		assert.Error(t, err)
@@ -164,15 +305,15 @@ func TestStore_GetCameraMetadataByID(t *testing.T) {
 		db, mock, cleanup := setupMockDB(t)
 		defer cleanup()
 
-		store := Store{db}
+		store := Store{db: db}
 
 		camID := "non-existent-id"
-		mock.ExpectQuery(`^SELECT.*FROM camera_metadata WHERE cam_id = \$1$`).
-			WithArgs(camID).
+		mock.ExpectQuery(`^SELECT.*FROM camera_metadata WHERE cam_id = \$1 AND org_id = \$2$`).
+			WithArgs(camID, 3).
 			WillReturnError(sql.ErrConnDone)
 
 		// act
-		_, err := store.GetCameraMetadataByID(camID)
+		_, err := store.GetCameraMetadataByID(context.Background(), 3, camID)
 
 		// assert
 		if err := mock.ExpectationsWereMet(); err != nil {
@@ -194,7 +335,7 @@ func TestStore_UpdateCameraMetadata(t *testing.T) {
 		db, mock, cleanup := setupMockDB(t)
 		defer cleanup()
 
-		store := Store{db}
+		store := Store{db: db}
 
 		cam := types.CameraMetadata{
 			CamID:               "123",
@@ -208,13 +349,37 @@ func TestStore_UpdateCameraMetadata(t *testing.T) {
 			InitializedAt:       sql.NullTime{Time: time.Now(), Valid: true},
 		}
 
+		mock.ExpectBegin()
+		mock.ExpectQuery(`^SELECT .* FROM camera_metadata WHERE cam_id = \$1 AND org_id = \$2 FOR UPDATE$`).
+			WithArgs(cam.CamID, cam.OrgID).
+			WillReturnRows(sqlmock.NewRows(cameraRowColumns).
+				AddRow(cam.CamID, nil, "Old Name", "v1.0", nil, nil, cam.CreatedAt.Time, nil, nil, nil, cam.OrgID, 0, nil, []byte(`{}`), nil, nil, nil, nil))
 		mock.ExpectExec("UPDATE camera_metadata").WithArgs(
 			cam.CameraName, cam.FirmwareVersion, cam.ContainerName, cam.NameOfStoredPicture,
-			cam.CreatedAt, cam.OnboardedAt, cam.InitializedAt, cam.ImageId, cam.CamID,
+			cam.CreatedAt, cam.OnboardedAt, cam.InitializedAt, cam.ImageId, cam.ImageSizeBytes, cam.GroupID, cam.Tags,
+			cam.Latitude, cam.Longitude, cam.Heading, cam.FieldOfView, cam.CamID, cam.OrgID,
 		).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO audit_log").
+			WithArgs(cam.OrgID, "user", "7", "camera.initialize", "camera", cam.CamID, types.AuditChanges{
+				"camera_name":    {Before: "Old Name", After: "Test Camera"},
+				"image_id":       {Before: nil, After: "234"},
+				"initialized_at": {Before: nil, After: cam.InitializedAt.Time.UTC().Format(time.RFC3339Nano)},
+			}, "", "").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		imageID := "234"
+		event, _ := json.Marshal(types.CameraEvent{CameraMetadataResponse: cameraResponse(cam), ImageID: &imageID})
+		mock.ExpectExec("INSERT INTO outbox_events").
+			WithArgs(cam.OrgID, types.EventCameraInitialized, event).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		change := func(c *types.CameraMetadata) error {
+			*c = cam
+			return nil
+		}
 
 		// act
-		updatedCamera, err := store.UpdateCameraMetadata(cam)
+		updatedCamera, err := store.UpdateCameraMetadata(context.Background(), cam.OrgID, cam.CamID, change, types.AuditEntry{ActorType: "user", ActorID: "7", Action: "camera.initialize"})
 
 		// assert
 		assert.NoError(t, mock.ExpectationsWereMet(), "Expectations were not met")
@@ -227,7 +392,7 @@ func TestStore_UpdateCameraMetadata(t *testing.T) {
 		// arrange
 		db, mock, cleanup := setupMockDB(t)
 		defer cleanup()
-		store := Store{db}
+		store := Store{db: db}
 
 		cam := types.CameraMetadata{
 			CamID:               "123",
@@ -241,13 +406,14 @@ func TestStore_UpdateCameraMetadata(t *testing.T) {
 			InitializedAt:       sql.NullTime{Time: time.Now(), Valid: true},
 		}
 
-		mock.ExpectExec("UPDATE camera_metadata").WithArgs(
-			cam.CameraName, cam.FirmwareVersion, cam.ContainerName, cam.NameOfStoredPicture,
-			cam.CreatedAt, cam.OnboardedAt, cam.InitializedAt, cam.ImageId, cam.CamID,
-		).WillReturnError(sql.ErrNoRows)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`^SELECT .* FROM camera_metadata WHERE cam_id = \$1 AND org_id = \$2 FOR UPDATE$`).
+			WithArgs(cam.CamID, cam.OrgID).
+			WillReturnRows(sqlmock.NewRows(cameraRowColumns))
+		mock.ExpectRollback()
 
 		// act
-		updatedCamera, err := store.UpdateCameraMetadata(cam)
+		updatedCamera, err := store.UpdateCameraMetadata(context.Background(), cam.OrgID, cam.CamID, func(c *types.CameraMetadata) error { return nil }, types.AuditEntry{})
 
 		// assert
 		assert.NoError(t, mock.ExpectationsWereMet(), "Expectations were not met")
@@ -255,12 +421,35 @@ func TestStore_UpdateCameraMetadata(t *testing.T) {
 		assert.Error(t, err, "Expected an error when no rows are affected")
 		assert.Nil(t, updatedCamera, "No camera metadata should be returned when no rows are affected")
 	})
+	t.Run("UpdateCameraMetadata_ofOtherOrganization_returnsNotFound", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+		store := Store{db: db}
+
+		cam := types.CameraMetadata{CamID: "123", CameraName: "Test Camera", OrgID: 4}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`^SELECT .* FROM camera_metadata WHERE cam_id = \$1 AND org_id = \$2 FOR UPDATE$`).
+			WithArgs(cam.CamID, 4).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		// act
+		updatedCamera, err := store.UpdateCameraMetadata(context.Background(), cam.OrgID, cam.CamID, func(c *types.CameraMetadata) error { return nil }, types.AuditEntry{})
+
+		// assert
+		var notFound *customerrors.NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+		assert.Nil(t, updatedCamera)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 	t.Run("UpdateCameraMetadata_withError_toReturnError", func(t *testing.T) {
 		// arrange
 		db, mock, cleanup := setupMockDB(t)
 		defer cleanup()
 
-		store := Store{db}
+		store := Store{db: db}
 
 		cam := types.CameraMetadata{
 			CamID:               "123",
@@ -274,13 +463,25 @@ func TestStore_UpdateCameraMetadata(t *testing.T) {
 			InitializedAt:       sql.NullTime{Time: time.Now(), Valid: true},
 		}
 
+		mock.ExpectBegin()
+		mock.ExpectQuery(`^SELECT .* FROM camera_metadata WHERE cam_id = \$1 AND org_id = \$2 FOR UPDATE$`).
+			WithArgs(cam.CamID, cam.OrgID).
+			WillReturnRows(sqlmock.NewRows(cameraRowColumns).
+				AddRow(cam.CamID, nil, "Test Camera", "v1.0", nil, nil, cam.CreatedAt.Time, nil, nil, nil, cam.OrgID, 0, nil, []byte(`{}`), nil, nil, nil, nil))
 		mock.ExpectExec("UPDATE camera_metadata").WithArgs(
 			cam.CameraName, cam.FirmwareVersion, cam.ContainerName, cam.NameOfStoredPicture,
-			cam.CreatedAt, cam.OnboardedAt, cam.InitializedAt, cam.ImageId, cam.CamID,
+			cam.CreatedAt, cam.OnboardedAt, cam.InitializedAt, cam.ImageId, cam.ImageSizeBytes, cam.GroupID, cam.Tags,
+			cam.Latitude, cam.Longitude, cam.Heading, cam.FieldOfView, cam.CamID, cam.OrgID,
 		).WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		change := func(c *types.CameraMetadata) error {
+			*c = cam
+			return nil
+		}
 
 		// act
-		_, err := store.UpdateCameraMetadata(cam)
+		_, err := store.UpdateCameraMetadata(context.Background(), cam.OrgID, cam.CamID, change, types.AuditEntry{})
 
 		// assert
 		if err := mock.ExpectationsWereMet(); err != nil {
@@ -296,3 +497,414 @@ func TestStore_UpdateCameraMetadata(t *testing.T) {
 		}
 	})
 }
+
+func TestStore_ListCameraMetadataByOwner(t *testing.T) {
+	t.Run("ListCameraMetadataByOwner_withCameras_returnsThem", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := Store{db: db}
+
+		rows := sqlmock.NewRows(cameraRowColumns).
+			AddRow(uuid.New().String(), nil, "Front Door", "v1.0", nil, nil, time.Now(), nil, nil, 7, 3, 0, nil, []byte(`{}`), nil, nil, nil, nil).
+			AddRow(uuid.New().String(), "img", "Garage", "v1.1", "container", "img", time.Now(), nil, time.Now(), 7, 3, 1024, 2, []byte(`{"site":"berlin"}`), nil, nil, nil, nil)
+		mock.ExpectQuery(`^SELECT .* FROM camera_metadata WHERE owner_id = \$1 AND org_id = \$2 ORDER BY created_at$`).
+			WithArgs(7, 3).
+			WillReturnRows(rows)
+
+		// act
+		cameras, err := store.ListCameraMetadataByOwner(context.Background(), 3, 7)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Len(t, cameras, 2)
+		assert.Equal(t, "Garage", cameras[1].CameraName)
+		assert.Equal(t, int64(7), cameras[1].OwnerID.Int64)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStore_ReassignCameraMetadata(t *testing.T) {
+	t.Run("ReassignCameraMetadata_updatesOwner", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := Store{db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`UPDATE camera_metadata SET owner_id = \$1 WHERE owner_id = \$2 AND org_id = \$3 RETURNING cam_id`).
+			WithArgs(2, 7, 3).
+			WillReturnRows(sqlmock.NewRows([]string{"cam_id"}).AddRow("cam-1").AddRow("cam-2"))
+		for _, camID := range []string{"cam-1", "cam-2"} {
+			mock.ExpectExec(`INSERT INTO audit_log`).
+				WithArgs(3, "user", "1", "camera.reassign", "camera", camID,
+					types.AuditChanges{"owner_id": {Before: int64(7), After: int64(2)}}, "", "").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+		}
+		mock.ExpectCommit()
+
+		// act
+		err := store.ReassignCameraMetadata(context.Background(), 3, 7, 2, types.AuditEntry{ActorType: "user", ActorID: "1", Action: "camera.reassign"})
+
+		// assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStore_DeleteCameraMetadata(t *testing.T) {
+	t.Run("DeleteCameraMetadata_withUnknownID_returnsNotFound", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := Store{db: db}
+		camID := uuid.New().String()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`DELETE FROM camera_metadata WHERE cam_id = \$1 AND org_id = \$2 RETURNING`).
+			WithArgs(camID, 3).
+			WillReturnRows(sqlmock.NewRows(cameraRowColumns))
+		mock.ExpectRollback()
+
+		// act
+		err := store.DeleteCameraMetadata(context.Background(), 3, camID, types.AuditEntry{})
+
+		// assert
+		var notFound *customerrors.NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStore_ListCameraMetadata(t *testing.T) {
+	t.Run("ListCameraMetadata_withGroupAndSelector_filtersAndPages", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := Store{db: db}
+		groupID := 2
+		filter := types.CameraFilter{
+			GroupID: &groupID,
+			Selector: []types.TagRequirement{
+				{Key: "site", Operator: types.TagEquals, Value: "berlin"},
+				{Key: "env", Operator: types.TagNotEquals, Value: "test"},
+				{Key: "outdoor", Operator: types.TagExists},
+				{Key: "deprecated", Operator: types.TagNotExists},
+			},
+			Limit:  10,
+			Offset: 20,
+		}
+		where := `org_id = \$1 AND group_id = \$2 AND tags @> \$3::jsonb AND \(tags ->> \$4::text\) IS DISTINCT FROM \$5 AND tags \? \$6 AND NOT \(tags \? \$7\)`
+
+		mock.ExpectQuery(`^SELECT COUNT\(\*\) FROM camera_metadata WHERE `+where+`$`).
+			WithArgs(3, 2, types.Tags{"site": "berlin"}, "env", "test", "outdoor", "deprecated").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(21))
+		mock.ExpectQuery(`^SELECT .* FROM camera_metadata WHERE `+where+` ORDER BY created_at, cam_id LIMIT \$8 OFFSET \$9$`).
+			WithArgs(3, 2, types.Tags{"site": "berlin"}, "env", "test", "outdoor", "deprecated", 10, 20).
+			WillReturnRows(sqlmock.NewRows(cameraRowColumns).
+				AddRow(uuid.New().String(), nil, "Gate", "v1.0", nil, nil, time.Now(), nil, nil, 7, 3, 0, 2, []byte(`{"site":"berlin","outdoor":""}`), 52.52, 13.405, 90.0, 60.0))
+
+		// act
+		cameras, total, err := store.ListCameraMetadata(context.Background(), 3, filter)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 21, total)
+		assert.Len(t, cameras, 1)
+		assert.Equal(t, int64(2), cameras[0].GroupID.Int64)
+		assert.Equal(t, 52.52, cameras[0].Latitude.Float64)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("ListCameraMetadata_withBoundingBoxAcrossAntimeridian_matchesBothSides", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := Store{db: db}
+		filter := types.CameraFilter{Within: &types.BoundingBox{MinLongitude: 170, MinLatitude: -20, MaxLongitude: -170, MaxLatitude: -10}}
+		where := `org_id = \$1 AND latitude BETWEEN \$2 AND \$3 AND \(longitude >= \$4 OR longitude <= \$5\)`
+
+		mock.ExpectQuery(`^SELECT COUNT\(\*\) FROM camera_metadata WHERE `+where+`$`).
+			WithArgs(3, -20.0, -10.0, 170.0, -170.0).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(`^SELECT .* FROM camera_metadata WHERE `+where+` ORDER BY created_at, cam_id$`).
+			WithArgs(3, -20.0, -10.0, 170.0, -170.0).
+			WillReturnRows(sqlmock.NewRows([]string{"cam_id"}))
+
+		// act
+		_, total, err := store.ListCameraMetadata(context.Background(), 3, filter)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 0, total)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("ListCameraMetadata_withRadius_sortsByDistance", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := Store{db: db}
+		filter := types.CameraFilter{Near: &types.GeoCircle{Latitude: 52.52, Longitude: 13.405, RadiusMeters: 1000}}
+		delta := 1000 / earthRadiusMeters * 180 / math.Pi
+
+		mock.ExpectQuery(`^SELECT COUNT\(\*\) FROM camera_metadata WHERE org_id = \$1 AND latitude BETWEEN \$2 AND \$3 AND \(6371008.8 \* 2 \* ASIN\(LEAST\(1, .*\$4.*\$5.*\)\)\) <= \$6$`).
+			WithArgs(3, 52.52-delta, 52.52+delta, 52.52, 13.405, 1000.0).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(`^SELECT .* ORDER BY \(6371008.8 \* 2 \* ASIN\(LEAST\(1, .*\$7.*\$8.*\)\)\), created_at, cam_id$`).
+			WithArgs(3, 52.52-delta, 52.52+delta, 52.52, 13.405, 1000.0, 52.52, 13.405).
+			WillReturnRows(sqlmock.NewRows([]string{"cam_id"}))
+
+		// act
+		_, _, err := store.ListCameraMetadata(context.Background(), 3, filter)
+
+		// assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStore_BulkUpdateCameraMetadata(t *testing.T) {
+	t.Run("BulkUpdateCameraMetadata_setsGroupAndTags", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := Store{db: db}
+		groupID := 4
+		filter := types.CameraFilter{Selector: []types.TagRequirement{{Key: "site", Operator: types.TagEquals, Value: "berlin"}}}
+		update := types.CameraBulkUpdate{SetGroupID: &groupID, SetTags: types.Tags{"zone": "a"}, RemoveTags: []string{"old"}}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`^SELECT cam_id, group_id, tags FROM camera_metadata WHERE org_id = \$1 AND tags @> \$2::jsonb FOR UPDATE$`).
+			WithArgs(3, types.Tags{"site": "berlin"}).
+			WillReturnRows(sqlmock.NewRows([]string{"cam_id", "group_id", "tags"}).
+				AddRow("cam-1", nil, []byte(`{"site":"berlin","old":"x"}`)))
+		mock.ExpectQuery(`^UPDATE camera_metadata SET group_id = \$3, tags = \(tags \|\| \$4::jsonb\) - \$5::text WHERE org_id = \$1 AND tags @> \$2::jsonb RETURNING cam_id, group_id, tags$`).
+			WithArgs(3, types.Tags{"site": "berlin"}, 4, types.Tags{"zone": "a"}, "old").
+			WillReturnRows(sqlmock.NewRows([]string{"cam_id", "group_id", "tags"}).
+				AddRow("cam-1", 4, []byte(`{"site":"berlin","zone":"a"}`)))
+		mock.ExpectExec(`INSERT INTO audit_log`).
+			WithArgs(3, "user", "1", "camera.bulk_update", "camera", "cam-1", types.AuditChanges{
+				"group_id": {Before: nil, After: int64(4)},
+				"tags":     {Before: types.Tags{"site": "berlin", "old": "x"}, After: types.Tags{"site": "berlin", "zone": "a"}},
+			}, "", "").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		// act
+		updated, err := store.BulkUpdateCameraMetadata(context.Background(), 3, filter, update, types.AuditEntry{ActorType: "user", ActorID: "1", Action: "camera.bulk_update"})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), updated)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("BulkUpdateCameraMetadata_clearsGroup", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := Store{db: db}
+		groupID := 4
+
+		rows := sqlmock.NewRows([]string{"cam_id", "group_id", "tags"}).
+			AddRow("cam-1", 4, []byte(`{}`)).
+			AddRow("cam-2", 4, []byte(`{}`))
+		mock.ExpectBegin()
+		mock.ExpectQuery(`^SELECT cam_id, group_id, tags FROM camera_metadata WHERE org_id = \$1 AND group_id = \$2 FOR UPDATE$`).
+			WithArgs(3, 4).
+			WillReturnRows(rows)
+		mock.ExpectQuery(`^UPDATE camera_metadata SET group_id = NULL WHERE org_id = \$1 AND group_id = \$2 RETURNING cam_id, group_id, tags$`).
+			WithArgs(3, 4).
+			WillReturnRows(sqlmock.NewRows([]string{"cam_id", "group_id", "tags"}).
+				AddRow("cam-1", nil, []byte(`{}`)).
+				AddRow("cam-2", nil, []byte(`{}`)))
+		mock.ExpectExec(`INSERT INTO audit_log`).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(`INSERT INTO audit_log`).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		// act
+		updated, err := store.BulkUpdateCameraMetadata(context.Background(), 3, types.CameraFilter{GroupID: &groupID}, types.CameraBulkUpdate{ClearGroup: true}, types.AuditEntry{})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), updated)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStore_ClaimImageID(t *testing.T) {
+	t.Run("ClaimImageID_newID_returnsTrue", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := Store{db: db}
+
+		mock.ExpectQuery(`^INSERT INTO camera_image_ids \(image_id, cam_id\) VALUES \(\$1, \$2\)\s+ON CONFLICT \(image_id\) DO UPDATE .* WHERE camera_image_ids.cam_id = EXCLUDED.cam_id\s+RETURNING cam_id$`).
+			WithArgs("img-1", "cam-1").
+			WillReturnRows(sqlmock.NewRows([]string{"cam_id"}).AddRow("cam-1"))
+
+		// act
+		claimed, err := store.ClaimImageID(context.Background(), "img-1", "cam-1")
+
+		// assert
+		assert.NoError(t, err)
+		assert.True(t, claimed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("ClaimImageID_ofOtherCamera_returnsFalse", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := Store{db: db}
+
+		mock.ExpectQuery(`^INSERT INTO camera_image_ids`).
+			WithArgs("img-1", "cam-1").
+			WillReturnRows(sqlmock.NewRows([]string{"cam_id"}))
+
+		// act
+		claimed, err := store.ClaimImageID(context.Background(), "img-1", "cam-1")
+
+		// assert
+		assert.NoError(t, err)
+		assert.False(t, claimed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStore_GetUsage(t *testing.T) {
+	t.Run("GetUsage_sumsTheCamerasOfTheOrganization", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := Store{db: db}
+
+		mock.ExpectQuery(`^SELECT COUNT\(\*\), COALESCE\(SUM\(image_size_bytes\), 0\) FROM camera_metadata WHERE org_id = \$1$`).
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"count", "sum"}).AddRow(4, 4096))
+
+		// act
+		usage, err := store.GetUsage(context.Background(), 3)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, types.Usage{Cameras: 4, StorageBytes: 4096}, *usage)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStore_ListUsage(t *testing.T) {
+	t.Run("ListUsage_groupsByOrganization", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := Store{db: db}
+
+		mock.ExpectQuery(`^SELECT org_id, COUNT\(\*\), COALESCE\(SUM\(image_size_bytes\), 0\) FROM camera_metadata GROUP BY org_id$`).
+			WillReturnRows(sqlmock.NewRows([]string{"org_id", "count", "sum"}).
+				AddRow(1, 2, 100).
+				AddRow(3, 1, 0))
+
+		// act
+		usage, err := store.ListUsage(context.Background())
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, map[int]types.Usage{1: {Cameras: 2, StorageBytes: 100}, 3: {Cameras: 1}}, usage)
+	})
+}
+
+func TestStore_ListImageReferences(t *testing.T) {
+	t.Run("ListImageReferences_returnsCamerasWithImage", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := Store{db: db}
+
+		mock.ExpectQuery(`^SELECT org_id, cam_id, image_id FROM camera_metadata WHERE image_id IS NOT NULL ORDER BY org_id, cam_id$`).
+			WillReturnRows(sqlmock.NewRows([]string{"org_id", "cam_id", "image_id"}).
+				AddRow(1, "cam-1", "img-1").
+				AddRow(3, "cam-2", "img-2"))
+
+		// act
+		refs, err := store.ListImageReferences(context.Background())
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, []types.ImageReference{
+			{OrgID: 1, CamID: "cam-1", ImageID: "img-1"},
+			{OrgID: 3, CamID: "cam-2", ImageID: "img-2"},
+		}, refs)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStore_ClearImage(t *testing.T) {
+	t.Run("ClearImage_withCurrentImage_clearsAndAudits", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := Store{db: db}
+		ref := types.ImageReference{OrgID: 3, CamID: uuid.New().String(), ImageID: "img-1"}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT .* FROM camera_metadata WHERE cam_id = \$1 AND org_id = \$2 AND image_id = \$3 FOR UPDATE`).
+			WithArgs(ref.CamID, ref.OrgID, ref.ImageID).
+			WillReturnRows(sqlmock.NewRows(cameraRowColumns).
+				AddRow(ref.CamID, ref.ImageID, "Gate", "v1.0", "images", ref.ImageID, time.Now(), nil, nil, 7, 3, 2048, nil, []byte(`{}`), nil, nil, nil, nil))
+		mock.ExpectExec(`UPDATE camera_metadata\s+SET image_id = NULL, name_of_stored_picture = NULL, container_name = NULL, image_size_bytes = 0\s+WHERE cam_id = \$1 AND org_id = \$2`).
+			WithArgs(ref.CamID, ref.OrgID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO audit_log`).
+			WithArgs(3, "system", "reconcile", "camera.clear_image", "camera", ref.CamID, types.AuditChanges{
+				"image_id":         {Before: "img-1", After: nil},
+				"image_size_bytes": {Before: int64(2048), After: int64(0)},
+			}, "", "").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		// act
+		err := store.ClearImage(context.Background(), ref, types.AuditEntry{ActorType: "system", ActorID: "reconcile", Action: "camera.clear_image"})
+
+		// assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("ClearImage_withReplacedImage_returnsNotFound", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := Store{db: db}
+		ref := types.ImageReference{OrgID: 3, CamID: uuid.New().String(), ImageID: "img-1"}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT .* FROM camera_metadata WHERE cam_id = \$1 AND org_id = \$2 AND image_id = \$3 FOR UPDATE`).
+			WithArgs(ref.CamID, ref.OrgID, ref.ImageID).
+			WillReturnRows(sqlmock.NewRows(cameraRowColumns))
+		mock.ExpectRollback()
+
+		// act
+		err := store.ClearImage(context.Background(), ref, types.AuditEntry{})
+
+		// assert
+		var notFound *customerrors.NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
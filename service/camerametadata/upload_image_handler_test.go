@@ -19,7 +19,7 @@ func TestHandler_UploadImageHandler(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		timeNow := time.Now()
 		nullTime := sql.NullTime{
@@ -36,11 +36,10 @@ func TestHandler_UploadImageHandler(t *testing.T) {
 			InitializedAt:   nullTime,
 		}
 
-		var capturedArg types.CameraMetadata
-		mockCameraStore.On("GetCameraMetadataByID", camID).Return(&expectedCamera, nil)
-		mockCameraStore.On("UpdateCameraMetadata", mock.AnythingOfType("types.CameraMetadata")).Run(func(args mock.Arguments) {
-			capturedArg = args.Get(0).(types.CameraMetadata)
-		}).Return(&expectedCamera, nil)
+		capturedArg := expectedCamera
+		mockCameraStore.On("GetCameraMetadataByID", mock.Anything, mock.Anything, camID).Return(&expectedCamera, nil)
+		mockCameraStore.On("UpdateCameraMetadata", mock.Anything, mock.Anything, camID, mock.Anything).Return(&capturedArg, nil)
+		mockCameraStore.On("ClaimImageID", mock.Anything, imageID, camID).Return(true, nil)
 		mockAzureStorage.On("UploadImage",
 			mock.AnythingOfType("*context.valueCtx"), imageID+".png", mock.AnythingOfType("[]uint8")).Return(nil)
 		url := "/camera_metadata/" + camID + "/upload_image?imageID=" + imageID + "&image_as_bytes=" + Base64Data
@@ -78,11 +77,11 @@ func TestHandler_UploadImageHandler(t *testing.T) {
 		mockAzureStorage.AssertExpectations(t)
 	})
 
-	t.Run("UploadImageHandler_withUploadError_returnInternalServerError", func(t *testing.T) {
+	t.Run("UploadImageHandler_withUploadError_keepsCameraUnchanged", func(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		timeNow := time.Now()
 		nullTime := sql.NullTime{
@@ -99,11 +98,8 @@ func TestHandler_UploadImageHandler(t *testing.T) {
 			InitializedAt:   nullTime,
 		}
 
-		var capturedArg types.CameraMetadata
-		mockCameraStore.On("GetCameraMetadataByID", camID).Return(&expectedCamera, nil)
-		mockCameraStore.On("UpdateCameraMetadata", mock.AnythingOfType("types.CameraMetadata")).Run(func(args mock.Arguments) {
-			capturedArg = args.Get(0).(types.CameraMetadata)
-		}).Return(&expectedCamera, nil)
+		mockCameraStore.On("GetCameraMetadataByID", mock.Anything, mock.Anything, camID).Return(&expectedCamera, nil)
+		mockCameraStore.On("ClaimImageID", mock.Anything, imageID, camID).Return(true, nil)
 		mockAzureStorage.On("UploadImage",
 			mock.AnythingOfType("*context.valueCtx"), imageID+".png", mock.AnythingOfType("[]uint8")).Return(fmt.Errorf("upload err"))
 		url := "/camera_metadata/" + camID + "/upload_image?imageID=" + imageID + "&image_as_bytes=" + Base64Data
@@ -124,28 +120,69 @@ func TestHandler_UploadImageHandler(t *testing.T) {
 			t.Errorf("expected status code %d, got %d", http.StatusInternalServerError, rr.Code)
 		}
 
-		if capturedArg.CameraName != expectedCamera.CameraName {
-			t.Errorf("expected CameraName %s, got %s", expectedCamera.CameraName, capturedArg.CameraName)
+		mockCameraStore.AssertExpectations(t)
+		mockCameraStore.AssertNotCalled(t, "UpdateCameraMetadata", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockAzureStorage.AssertExpectations(t)
+		mockAzureStorage.AssertNotCalled(t, "DeleteImage", mock.Anything, mock.Anything)
+	})
+
+	t.Run("UploadImageHandler_withUpdateCameraMetadataError_deletesUploadedImage", func(t *testing.T) {
+		//arrange
+		mockCameraStore := new(MockCameraStore)
+		mockAzureStorage := new(MockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
+
+		timeNow := time.Now()
+		nullTime := sql.NullTime{
+			Time:  timeNow,
+			Valid: true,
 		}
-		if capturedArg.FirmwareVersion != expectedCamera.FirmwareVersion {
-			t.Errorf("expected FirmwareVersion %s, got %s", expectedCamera.FirmwareVersion, capturedArg.FirmwareVersion)
+		camID := uuid.New().String()
+		imageID := uuid.New().String()
+		previousImageID := uuid.New().String()
+		expectedCamera := types.CameraMetadata{
+			CamID:           camID,
+			CameraName:      "camera-name",
+			FirmwareVersion: "v123",
+			CreatedAt:       nullTime,
+			InitializedAt:   nullTime,
+			ImageId:         sql.NullString{String: previousImageID, Valid: true},
 		}
-		if capturedArg.NameOfStoredPicture.String != imageID {
-			t.Errorf("expected NameOfStoredPicture %s, got %s", expectedCamera.FirmwareVersion, capturedArg.FirmwareVersion)
+
+		mockCameraStore.On("GetCameraMetadataByID", mock.Anything, mock.Anything, camID).Return(&expectedCamera, nil)
+		mockCameraStore.On("UpdateCameraMetadata", mock.Anything, mock.Anything, camID, mock.Anything).Return(nil, fmt.Errorf("update error"))
+		mockCameraStore.On("ClaimImageID", mock.Anything, imageID, camID).Return(true, nil)
+		mockAzureStorage.On("UploadImage",
+			mock.AnythingOfType("*context.valueCtx"), imageID+".png", mock.AnythingOfType("[]uint8")).Return(nil)
+		mockAzureStorage.On("DeleteImage", mock.Anything, imageID+".png").Return(nil)
+		url := "/camera_metadata/" + camID + "/upload_image?imageID=" + imageID + "&image_as_bytes=" + Base64Data
+
+		// Act
+		req, err := http.NewRequest(http.MethodPost, url, nil)
+		if err != nil {
+			t.Fatal(err)
 		}
-		if capturedArg.ImageId.String != imageID {
-			t.Errorf("expected ImageId %s, got %s", expectedCamera.FirmwareVersion, capturedArg.FirmwareVersion)
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/camera_metadata/{camID}/upload_image", handler.UploadImageHandler).Methods(http.MethodPost)
+		router.ServeHTTP(rr, req)
+
+		// Assert
+		if rr.Code != http.StatusInternalServerError {
+			t.Errorf("expected status code %d, got %d", http.StatusInternalServerError, rr.Code)
 		}
 
 		mockCameraStore.AssertExpectations(t)
 		mockAzureStorage.AssertExpectations(t)
+		mockAzureStorage.AssertNotCalled(t, "DeleteImage", mock.Anything, previousImageID+".png")
 	})
 
-	t.Run("UploadImageHandler_withUpdateCameraMetadataError_returnInternalServerError", func(t *testing.T) {
+	t.Run("UploadImageHandler_withUpdateErrorOnSameImageID_keepsImage", func(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		timeNow := time.Now()
 		nullTime := sql.NullTime{
@@ -160,15 +197,14 @@ func TestHandler_UploadImageHandler(t *testing.T) {
 			FirmwareVersion: "v123",
 			CreatedAt:       nullTime,
 			InitializedAt:   nullTime,
+			ImageId:         sql.NullString{String: imageID, Valid: true},
 		}
 
-		var capturedArg types.CameraMetadata
-		mockCameraStore.On("GetCameraMetadataByID", camID).Return(&expectedCamera, nil)
-		mockCameraStore.On("UpdateCameraMetadata", mock.AnythingOfType("types.CameraMetadata")).Run(func(args mock.Arguments) {
-			capturedArg = args.Get(0).(types.CameraMetadata)
-		}).Return(nil, fmt.Errorf("update error"))
+		mockCameraStore.On("GetCameraMetadataByID", mock.Anything, mock.Anything, camID).Return(&expectedCamera, nil)
+		mockCameraStore.On("UpdateCameraMetadata", mock.Anything, mock.Anything, camID, mock.Anything).Return(nil, fmt.Errorf("update error"))
+		mockCameraStore.On("ClaimImageID", mock.Anything, imageID, camID).Return(true, nil)
 		mockAzureStorage.On("UploadImage",
-			mock.AnythingOfType("*context.valueCtx"), imageID+".png", mock.AnythingOfType("[]uint8")).Return(fmt.Errorf("upload err"))
+			mock.AnythingOfType("*context.valueCtx"), imageID+".png", mock.AnythingOfType("[]uint8")).Return(nil)
 		url := "/camera_metadata/" + camID + "/upload_image?imageID=" + imageID + "&image_as_bytes=" + Base64Data
 
 		// Act
@@ -187,27 +223,155 @@ func TestHandler_UploadImageHandler(t *testing.T) {
 			t.Errorf("expected status code %d, got %d", http.StatusInternalServerError, rr.Code)
 		}
 
-		if capturedArg.CameraName != expectedCamera.CameraName {
-			t.Errorf("expected CameraName %s, got %s", expectedCamera.CameraName, capturedArg.CameraName)
+		mockCameraStore.AssertExpectations(t)
+		mockAzureStorage.AssertExpectations(t)
+		mockAzureStorage.AssertNotCalled(t, "DeleteImage", mock.Anything, mock.Anything)
+	})
+
+	t.Run("UploadImageHandler_withPreviousImage_deletesReplacedImage", func(t *testing.T) {
+		//arrange
+		mockCameraStore := new(MockCameraStore)
+		mockAzureStorage := new(MockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
+
+		timeNow := time.Now()
+		nullTime := sql.NullTime{
+			Time:  timeNow,
+			Valid: true,
 		}
-		if capturedArg.FirmwareVersion != expectedCamera.FirmwareVersion {
-			t.Errorf("expected FirmwareVersion %s, got %s", expectedCamera.FirmwareVersion, capturedArg.FirmwareVersion)
+		camID := uuid.New().String()
+		imageID := uuid.New().String()
+		previousImageID := uuid.New().String()
+		expectedCamera := types.CameraMetadata{
+			CamID:           camID,
+			CameraName:      "camera-name",
+			FirmwareVersion: "v123",
+			CreatedAt:       nullTime,
+			InitializedAt:   nullTime,
+			ImageId:         sql.NullString{String: previousImageID, Valid: true},
 		}
-		if capturedArg.NameOfStoredPicture.String != imageID {
-			t.Errorf("expected NameOfStoredPicture %s, got %s", expectedCamera.FirmwareVersion, capturedArg.FirmwareVersion)
+
+		mockCameraStore.On("GetCameraMetadataByID", mock.Anything, mock.Anything, camID).Return(&expectedCamera, nil)
+		lockedCamera := expectedCamera
+		mockCameraStore.On("UpdateCameraMetadata", mock.Anything, mock.Anything, camID, mock.Anything).Return(&lockedCamera, nil)
+		mockCameraStore.On("ClaimImageID", mock.Anything, imageID, camID).Return(true, nil)
+		mockAzureStorage.On("UploadImage",
+			mock.AnythingOfType("*context.valueCtx"), imageID+".png", mock.AnythingOfType("[]uint8")).Return(nil)
+		// a failed cleanup of the replaced image does not fail the upload
+		mockAzureStorage.On("DeleteImage", mock.Anything, previousImageID+".png").Return(fmt.Errorf("delete err"))
+		url := "/camera_metadata/" + camID + "/upload_image?imageID=" + imageID + "&image_as_bytes=" + Base64Data
+
+		// Act
+		req, err := http.NewRequest(http.MethodPost, url, nil)
+		if err != nil {
+			t.Fatal(err)
 		}
-		if capturedArg.ImageId.String != imageID {
-			t.Errorf("expected ImageId %s, got %s", expectedCamera.FirmwareVersion, capturedArg.FirmwareVersion)
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/camera_metadata/{camID}/upload_image", handler.UploadImageHandler).Methods(http.MethodPost)
+		router.ServeHTTP(rr, req)
+
+		// Assert
+		if rr.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		mockCameraStore.AssertExpectations(t)
+		mockAzureStorage.AssertExpectations(t)
+	})
+
+	t.Run("UploadImageHandler_withImageReplacedMeanwhile_deletesLatestImage", func(t *testing.T) {
+		//arrange
+		mockCameraStore := new(MockCameraStore)
+		mockAzureStorage := new(MockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
+
+		nullTime := sql.NullTime{Time: time.Now(), Valid: true}
+		camID := uuid.New().String()
+		imageID := uuid.New().String()
+		readImageID := uuid.New().String()
+		concurrentImageID := uuid.New().String()
+		expectedCamera := types.CameraMetadata{
+			CamID:         camID,
+			InitializedAt: nullTime,
+			ImageId:       sql.NullString{String: readImageID, Valid: true},
+		}
+		// another upload replaced the image before the row was locked
+		lockedCamera := expectedCamera
+		lockedCamera.ImageId = sql.NullString{String: concurrentImageID, Valid: true}
+
+		mockCameraStore.On("GetCameraMetadataByID", mock.Anything, mock.Anything, camID).Return(&expectedCamera, nil)
+		mockCameraStore.On("UpdateCameraMetadata", mock.Anything, mock.Anything, camID, mock.Anything).Return(&lockedCamera, nil)
+		mockCameraStore.On("ClaimImageID", mock.Anything, imageID, camID).Return(true, nil)
+		mockAzureStorage.On("UploadImage", mock.Anything, imageID+".png", mock.AnythingOfType("[]uint8")).Return(nil)
+		mockAzureStorage.On("DeleteImage", mock.Anything, concurrentImageID+".png").Return(nil)
+		url := "/camera_metadata/" + camID + "/upload_image?imageID=" + imageID + "&image_as_bytes=" + Base64Data
+
+		// Act
+		req, err := http.NewRequest(http.MethodPost, url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/camera_metadata/{camID}/upload_image", handler.UploadImageHandler).Methods(http.MethodPost)
+		router.ServeHTTP(rr, req)
+
+		// Assert
+		if rr.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+		if lockedCamera.ImageId.String != imageID {
+			t.Errorf("expected ImageId %s, got %s", imageID, lockedCamera.ImageId.String)
+		}
+
+		mockAzureStorage.AssertExpectations(t)
+		mockAzureStorage.AssertNotCalled(t, "DeleteImage", mock.Anything, readImageID+".png")
+	})
+
+	t.Run("UploadImageHandler_withImageIDOfOtherCamera_returnConflict", func(t *testing.T) {
+		//arrange
+		mockCameraStore := new(MockCameraStore)
+		mockAzureStorage := new(MockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
+
+		camID := uuid.New().String()
+		imageID := uuid.New().String()
+		expectedCamera := types.CameraMetadata{
+			CamID:         camID,
+			InitializedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		}
+
+		mockCameraStore.On("GetCameraMetadataByID", mock.Anything, mock.Anything, camID).Return(&expectedCamera, nil)
+		mockCameraStore.On("ClaimImageID", mock.Anything, imageID, camID).Return(false, nil)
+		url := "/camera_metadata/" + camID + "/upload_image?imageID=" + imageID + "&image_as_bytes=" + Base64Data
+
+		// Act
+		req, err := http.NewRequest(http.MethodPost, url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/camera_metadata/{camID}/upload_image", handler.UploadImageHandler).Methods(http.MethodPost)
+		router.ServeHTTP(rr, req)
+
+		// Assert
+		if rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d, got %d", http.StatusConflict, rr.Code)
 		}
 
 		mockCameraStore.AssertExpectations(t)
+		mockCameraStore.AssertNotCalled(t, "UpdateCameraMetadata", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockAzureStorage.AssertNotCalled(t, "UploadImage", mock.Anything, mock.Anything, mock.Anything)
 	})
 
 	t.Run("UploadImageHandler_withNotInitCamera_returnBadRequest", func(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		timeNow := time.Now()
 		nullTime := sql.NullTime{
@@ -223,7 +387,7 @@ func TestHandler_UploadImageHandler(t *testing.T) {
 			CreatedAt:       nullTime,
 		}
 
-		mockCameraStore.On("GetCameraMetadataByID", camID).Return(&expectedCamera, nil)
+		mockCameraStore.On("GetCameraMetadataByID", mock.Anything, mock.Anything, camID).Return(&expectedCamera, nil)
 		url := "/camera_metadata/" + camID + "/upload_image?imageID=" + imageID + "&image_as_bytes=" + Base64Data
 
 		// Act
@@ -249,12 +413,12 @@ func TestHandler_UploadImageHandler(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		camID := uuid.New().String()
 		imageID := uuid.New().String()
 
-		mockCameraStore.On("GetCameraMetadataByID", camID).Return(nil, fmt.Errorf("not found"))
+		mockCameraStore.On("GetCameraMetadataByID", mock.Anything, mock.Anything, camID).Return(nil, fmt.Errorf("not found"))
 		url := "/camera_metadata/" + camID + "/upload_image?imageID=" + imageID + "&image_as_bytes=" + Base64Data
 
 		// Act
@@ -280,7 +444,7 @@ func TestHandler_UploadImageHandler(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		camID := uuid.New().String()
 		imageID := uuid.New().String()
@@ -310,7 +474,7 @@ func TestHandler_UploadImageHandler(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		camID := uuid.New().String()
 		imageID := "12"
@@ -340,7 +504,7 @@ func TestHandler_UploadImageHandler(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		camID := uuid.New().String()
 		imageID := ""
@@ -370,7 +534,7 @@ func TestHandler_UploadImageHandler(t *testing.T) {
 		//arrange
 		mockCameraStore := new(MockCameraStore)
 		mockAzureStorage := new(MockAzureStorage)
-		handler := NewHandler(mockCameraStore, mockAzureStorage)
+		handler := NewHandler(mockCameraStore, nil, mockAzureStorage, nil)
 
 		imageID := uuid.New().String()
 		camID := "123"
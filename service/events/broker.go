@@ -0,0 +1,163 @@
+package events
+
+import (
+	"encoding/json"
+	"go-sample-rest-api/types"
+	"sync"
+)
+
+// subscriptionBuffer is the number of events a subscriber may fall behind
+// before it is dropped.
+const subscriptionBuffer = 64
+
+// Filter selects the events of a subscription. Events always belong to OrgID;
+// CamID and GroupID narrow them down when set.
+type Filter struct {
+	OrgID   int
+	CamID   string
+	GroupID *int
+}
+
+type entry struct {
+	seq     uint64
+	event   types.Event
+	camID   string
+	groupID *int64
+}
+
+func (f Filter) matches(e entry) bool {
+	if e.event.OrgID != f.OrgID {
+		return false
+	}
+	if f.CamID != "" && e.camID != f.CamID {
+		return false
+	}
+	if f.GroupID != nil && (e.groupID == nil || *e.groupID != int64(*f.GroupID)) {
+		return false
+	}
+
+	return true
+}
+
+// Broker fans the published events out to the subscriptions and keeps the
+// latest of them, so that a client that reconnects can resume where it left
+// off. Events are kept in the order they were published, which is the order
+// their transactions committed in and not necessarily the order of their IDs.
+type Broker struct {
+	mu            sync.Mutex
+	size          int
+	seq           uint64
+	entries       []entry
+	index         map[int64]uint64
+	lastID        int64
+	subscriptions map[*Subscription]struct{}
+}
+
+// NewBroker returns a broker that keeps the latest size events.
+func NewBroker(size int) *Broker {
+	if size < 1 {
+		size = 1
+	}
+	return &Broker{
+		size:          size,
+		index:         make(map[int64]uint64),
+		subscriptions: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription receives the events matching its filter on C. C is closed
+// when the subscriber falls too far behind or the subscription is closed.
+type Subscription struct {
+	C      <-chan types.Event
+	c      chan types.Event
+	filter Filter
+	broker *Broker
+}
+
+// Close ends the subscription.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.remove(s)
+}
+
+// Publish keeps the event and sends it to the matching subscriptions. An
+// event that is kept already is ignored.
+func (b *Broker) Publish(event types.Event) {
+	// the filters look at the camera the event is about
+	var data struct {
+		CamID   string `json:"cam_id"`
+		GroupID *int64 `json:"group_id"`
+	}
+	json.Unmarshal(event.Data, &data)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.index[event.ID]; ok {
+		return
+	}
+	b.seq++
+	e := entry{seq: b.seq, event: event, camID: data.CamID, groupID: data.GroupID}
+	if len(b.entries) == b.size {
+		delete(b.index, b.entries[0].event.ID)
+		b.entries = b.entries[1:]
+	}
+	b.entries = append(b.entries, e)
+	b.index[event.ID] = e.seq
+	if event.ID > b.lastID {
+		b.lastID = event.ID
+	}
+
+	for s := range b.subscriptions {
+		if !s.filter.matches(e) {
+			continue
+		}
+		select {
+		case s.c <- event:
+		default:
+			// the subscriber resumes from its last event when it reconnects
+			b.remove(s)
+		}
+	}
+}
+
+// LastID returns the highest ID of the published events, or 0.
+func (b *Broker) LastID() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastID
+}
+
+// Subscribe starts a subscription. With a lastEventID other than 0 it also
+// returns the kept events published after that event, and ok is false when
+// the event is no longer kept, as events may have been missed.
+func (b *Broker) Subscribe(filter Filter, lastEventID int64) (sub *Subscription, missed []types.Event, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ok = true
+	if lastEventID != 0 {
+		seq, found := b.index[lastEventID]
+		if found {
+			for _, e := range b.entries[seq-b.entries[0].seq+1:] {
+				if filter.matches(e) {
+					missed = append(missed, e.event)
+				}
+			}
+		}
+		ok = found
+	}
+
+	c := make(chan types.Event, subscriptionBuffer)
+	sub = &Subscription{C: c, c: c, filter: filter, broker: b}
+	b.subscriptions[sub] = struct{}{}
+	return sub, missed, ok
+}
+
+func (b *Broker) remove(s *Subscription) {
+	if _, ok := b.subscriptions[s]; ok {
+		delete(b.subscriptions, s)
+		close(s.c)
+	}
+}
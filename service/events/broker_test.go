@@ -0,0 +1,124 @@
+package events
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"go-sample-rest-api/types"
+	"testing"
+)
+
+func cameraEvent(id int64, orgID int, camID string, groupID *int64) types.Event {
+	data, _ := json.Marshal(map[string]any{"cam_id": camID, "group_id": groupID})
+	return types.Event{ID: id, Type: types.EventCameraCreated, OrgID: orgID, Data: data}
+}
+
+func ids(events []types.Event) []int64 {
+	result := make([]int64, 0, len(events))
+	for _, e := range events {
+		result = append(result, e.ID)
+	}
+	return result
+}
+
+func TestBroker_Publish(t *testing.T) {
+	t.Run("sends matching events only", func(t *testing.T) {
+		// arrange
+		broker := NewBroker(10)
+		group := 2
+		groupID := int64(2)
+		all, _, _ := broker.Subscribe(Filter{OrgID: 1}, 0)
+		camera, _, _ := broker.Subscribe(Filter{OrgID: 1, CamID: "a"}, 0)
+		inGroup, _, _ := broker.Subscribe(Filter{OrgID: 1, GroupID: &group}, 0)
+
+		// act
+		broker.Publish(cameraEvent(1, 1, "a", nil))
+		broker.Publish(cameraEvent(2, 1, "b", &groupID))
+		broker.Publish(cameraEvent(3, 2, "a", &groupID))
+
+		// assert
+		assert.Len(t, all.C, 2)
+		assert.Equal(t, int64(1), (<-camera.C).ID)
+		assert.Len(t, camera.C, 0)
+		assert.Equal(t, int64(2), (<-inGroup.C).ID)
+		assert.Len(t, inGroup.C, 0)
+	})
+
+	t.Run("ignores events it keeps already", func(t *testing.T) {
+		// arrange
+		broker := NewBroker(10)
+		sub, _, _ := broker.Subscribe(Filter{OrgID: 1}, 0)
+
+		// act
+		broker.Publish(cameraEvent(1, 1, "a", nil))
+		broker.Publish(cameraEvent(1, 1, "a", nil))
+
+		// assert
+		assert.Len(t, sub.C, 1)
+	})
+
+	t.Run("drops a subscriber that falls behind", func(t *testing.T) {
+		// arrange
+		broker := NewBroker(10)
+		sub, _, _ := broker.Subscribe(Filter{OrgID: 1}, 0)
+
+		// act
+		for i := int64(1); i <= subscriptionBuffer+1; i++ {
+			broker.Publish(cameraEvent(i, 1, "a", nil))
+		}
+
+		// assert
+		received := 0
+		for range sub.C {
+			received++
+		}
+		assert.Equal(t, subscriptionBuffer, received)
+		sub.Close()
+	})
+}
+
+func TestBroker_Subscribe(t *testing.T) {
+	t.Run("returns the missed events in publishing order", func(t *testing.T) {
+		// arrange
+		broker := NewBroker(10)
+		for _, id := range []int64{1, 3, 2, 4} {
+			broker.Publish(cameraEvent(id, 1, "a", nil))
+		}
+		broker.Publish(cameraEvent(5, 2, "a", nil))
+
+		// act
+		_, missed, ok := broker.Subscribe(Filter{OrgID: 1}, 3)
+
+		// assert
+		assert.True(t, ok)
+		assert.Equal(t, []int64{2, 4}, ids(missed))
+	})
+
+	t.Run("reports events that are no longer kept", func(t *testing.T) {
+		// arrange
+		broker := NewBroker(2)
+		for id := int64(1); id <= 3; id++ {
+			broker.Publish(cameraEvent(id, 1, "a", nil))
+		}
+
+		// act
+		_, missed, ok := broker.Subscribe(Filter{OrgID: 1}, 1)
+
+		// assert
+		assert.False(t, ok)
+		assert.Empty(t, missed)
+		assert.Equal(t, int64(3), broker.LastID())
+	})
+
+	t.Run("without a last event ID", func(t *testing.T) {
+		// arrange
+		broker := NewBroker(2)
+		broker.Publish(cameraEvent(1, 1, "a", nil))
+
+		// act
+		_, missed, ok := broker.Subscribe(Filter{OrgID: 1}, 0)
+
+		// assert
+		assert.True(t, ok)
+		assert.Empty(t, missed)
+	})
+}
@@ -0,0 +1,53 @@
+package events
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/storage"
+	"go-sample-rest-api/types"
+)
+
+// mockCameraStore only answers GetCameraMetadataByID; the handlers use no
+// other method.
+type mockCameraStore struct {
+	mock.Mock
+	types.CameraMetadataStore
+}
+
+func (m *mockCameraStore) GetCameraMetadataByID(ctx context.Context, orgID int, camID string) (*types.CameraMetadata, error) {
+	args := m.Called(ctx, orgID, camID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.CameraMetadata), args.Error(1)
+}
+
+type mockImageStore struct {
+	mock.Mock
+}
+
+func (m *mockImageStore) UploadImage(ctx context.Context, blobName string, imageData []byte) error {
+	args := m.Called(ctx, blobName, imageData)
+	return args.Error(0)
+}
+
+func (m *mockImageStore) DownloadImage(ctx context.Context, blobName string) ([]byte, error) {
+	args := m.Called(ctx, blobName)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func (m *mockImageStore) DeleteImage(ctx context.Context, blobName string) error {
+	args := m.Called(ctx, blobName)
+	return args.Error(0)
+}
+
+func (m *mockImageStore) ListImages(ctx context.Context) ([]storage.ImageInfo, error) {
+	args := m.Called(ctx)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]storage.ImageInfo), args.Error(1)
+}
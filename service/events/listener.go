@@ -0,0 +1,83 @@
+package events
+
+import (
+	"context"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+	"go-sample-rest-api/logging"
+	"go-sample-rest-api/types"
+	"strconv"
+	"time"
+)
+
+// channel is the channel the outbox_events trigger notifies on.
+const channel = "outbox_events"
+
+// Source loads the events the listener is notified of.
+type Source interface {
+	GetEvent(ctx context.Context, id int64) (*types.Event, error)
+	ListRecentEvents(ctx context.Context, afterID int64, limit int) ([]types.Event, error)
+}
+
+// Listen publishes the events of the outbox to the broker as their
+// transactions commit, until ctx is done. It starts by publishing the latest
+// events, so that clients can resume across restarts, and catches up the same
+// way after it lost the connection. Events committed with a lower ID than the
+// last one published while the connection was lost are not caught up.
+func Listen(ctx context.Context, connString string, source Source, broker *Broker, size int) {
+	log := logging.GetLogger()
+	listener := pq.NewListener(connString, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"event": event,
+				"error": err,
+			}).Warn("Event listener connection problem")
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(channel); err != nil {
+		log.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Failed to listen for events")
+		return
+	}
+	catchUp(ctx, source, broker, size)
+
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-listener.Notify:
+			if n == nil {
+				// reconnected, notifications may have been lost
+				catchUp(ctx, source, broker, size)
+				continue
+			}
+			id, err := strconv.ParseInt(n.Extra, 10, 64)
+			if err != nil {
+				continue
+			}
+			event, err := source.GetEvent(ctx, id)
+			if err != nil {
+				continue
+			}
+			broker.Publish(*event)
+		case <-ping.C:
+			go listener.Ping()
+		}
+	}
+}
+
+// catchUp publishes the latest events after the last published one.
+func catchUp(ctx context.Context, source Source, broker *Broker, size int) {
+	events, err := source.ListRecentEvents(ctx, broker.LastID(), size)
+	if err != nil {
+		return
+	}
+	for _, event := range events {
+		broker.Publish(event)
+	}
+}
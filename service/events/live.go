@@ -0,0 +1,182 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	"go-sample-rest-api/config"
+	"go-sample-rest-api/customerrors"
+	"go-sample-rest-api/logging"
+	auth2 "go-sample-rest-api/service/auth"
+	"go-sample-rest-api/storage"
+	"go-sample-rest-api/types"
+	"go-sample-rest-api/utils"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// maxThumbnailWidth is the largest thumbnail a client can ask for.
+	maxThumbnailWidth = 640
+	// writeWait is the time a client has to take a message before it is
+	// dropped.
+	writeWait = 10 * time.Second
+)
+
+var upgrader = websocket.Upgrader{
+	// the access token is sent explicitly and never by the browser on its
+	// own, so pages of other origins cannot act for the user
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// LiveHandler pushes the images uploaded for a camera over a WebSocket.
+type LiveHandler struct {
+	broker       *Broker
+	store        types.CameraMetadataStore
+	userStore    types.UserStore
+	azureStorage storage.ImageStore
+	thumbnails   *thumbnailCache
+	heartbeat    time.Duration
+}
+
+func NewLiveHandler(broker *Broker, store types.CameraMetadataStore, userStore types.UserStore, azureStorage storage.ImageStore) *LiveHandler {
+	return &LiveHandler{
+		broker:       broker,
+		store:        store,
+		userStore:    userStore,
+		azureStorage: azureStorage,
+		thumbnails:   newThumbnailCache(),
+		heartbeat:    time.Duration(config.Envs.EventsHeartbeatInSeconds) * time.Second,
+	}
+}
+
+func (h *LiveHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/camera_metadata/{camID}/live", auth2.WithJWTAuth(h.handleLive, h.userStore)).Methods(http.MethodGet)
+}
+
+// handleLive godoc
+// @Summary Watch the images of a camera
+// @Description Upgrades to a WebSocket that receives a types.LiveImageMessage as JSON text message for every image uploaded for the camera. With thumbnail_width each message carries a JPEG thumbnail of at most that width. Clients that do not take a message within 10 seconds or fall behind are disconnected with close code 1013 and should reconnect. The server pings every EVENTS_HEARTBEAT_IN_SECONDS; browsers send the access token as token query parameter.
+// @Tags camera
+// @Param camID path string true "Camera ID"
+// @Param thumbnail_width query int false "Width of the thumbnails in pixels, at most 640"
+// @Success 101 {object} types.LiveImageMessage "Switching Protocols"
+// @Failure 400 {object} types.HTTPError "Invalid camera ID or thumbnail width, or not a WebSocket request."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 404 {object} types.HTTPError "Camera not found."
+// @Router /camera_metadata/{camID}/live [get]
+func (h *LiveHandler) handleLive(w http.ResponseWriter, r *http.Request) {
+	camID := mux.Vars(r)["camID"]
+	if _, err := uuid.Parse(camID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid camID: %v", err))
+		return
+	}
+
+	var width int
+	if value := r.URL.Query().Get("thumbnail_width"); value != "" {
+		var err error
+		if width, err = strconv.Atoi(value); err != nil || width < 1 || width > maxThumbnailWidth {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid thumbnail_width: %q", value))
+			return
+		}
+	}
+
+	orgID := auth2.GetOrgIDFromContext(r.Context())
+	if _, err := h.store.GetCameraMetadataByID(r.Context(), orgID, camID); err != nil {
+		utils.WriteError(w, http.StatusNotFound, &customerrors.NotFoundError{ID: camID})
+		return
+	}
+
+	sub, _, _ := h.broker.Subscribe(Filter{OrgID: orgID, CamID: camID}, 0)
+	defer sub.Close()
+
+	// the upgrader answers failed upgrades itself
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go h.readPump(conn, cancel)
+
+	ping := time.NewTicker(h.heartbeat)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, open := <-sub.C:
+			if !open {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow"), time.Now().Add(writeWait))
+				return
+			}
+			if event.Type != types.EventCameraImageUploaded {
+				continue
+			}
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteJSON(h.message(ctx, event, width)); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readPump handles the pongs and the close of the client, which sends
+// nothing else, and calls done when the connection is gone.
+func (h *LiveHandler) readPump(conn *websocket.Conn, done context.CancelFunc) {
+	defer done()
+	conn.SetReadLimit(512)
+	conn.SetReadDeadline(time.Now().Add(2 * h.heartbeat))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * h.heartbeat))
+	})
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// message returns the message for the upload event. A thumbnail that cannot
+// be made is left out.
+func (h *LiveHandler) message(ctx context.Context, event types.Event, width int) types.LiveImageMessage {
+	msg := types.LiveImageMessage{EventID: event.ID, UploadedAt: event.CreatedAt}
+	json.Unmarshal(event.Data, &msg.Camera)
+	if width == 0 || msg.Camera.ImageID == nil {
+		return msg
+	}
+
+	key := thumbnailKey{imageID: *msg.Camera.ImageID, width: width}
+	if data, ok := h.thumbnails.get(key, event.ID); ok {
+		msg.Thumbnail = data
+		return msg
+	}
+
+	image, err := h.azureStorage.DownloadImage(ctx, key.imageID+".png")
+	if err == nil {
+		msg.Thumbnail, err = thumbnail(image, width)
+	}
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"camID":   msg.Camera.CamID,
+			"imageID": key.imageID,
+			"error":   err,
+		}).Warn("Failed to make thumbnail")
+		return msg
+	}
+	h.thumbnails.add(key, event.ID, msg.Thumbnail)
+
+	return msg
+}
@@ -0,0 +1,289 @@
+package events
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/types"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const liveCamID = "7f9c24e8-3b12-4fef-91e0-3e5d3f2c1a00"
+
+func liveServer(handler *LiveHandler) *httptest.Server {
+	router := mux.NewRouter()
+	router.HandleFunc("/camera_metadata/{camID}/live", func(w http.ResponseWriter, r *http.Request) {
+		handler.handleLive(w, withOrganization(r))
+	}).Methods(http.MethodGet)
+	return httptest.NewServer(router)
+}
+
+func dial(t *testing.T, server *httptest.Server, query string) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/camera_metadata/" + liveCamID + "/live" + query
+	return websocket.DefaultDialer.Dial(url, nil)
+}
+
+func uploadEvent(id int64, imageID string) types.Event {
+	data, _ := json.Marshal(types.CameraEvent{
+		CameraMetadataResponse: types.CameraMetadataResponse{CamID: liveCamID},
+		ImageID:                &imageID,
+	})
+	return types.Event{ID: id, Type: types.EventCameraImageUploaded, OrgID: orgID, Data: data}
+}
+
+func pngImage(width, height int) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)))
+	return buf.Bytes()
+}
+
+// hugePNG returns a tiny PNG whose header declares width x height pixels.
+func hugePNG(width, height uint32) []byte {
+	data := pngImage(1, 1)
+	// the IHDR chunk follows the 8 byte signature: length, type, data, CRC
+	binary.BigEndian.PutUint32(data[16:], width)
+	binary.BigEndian.PutUint32(data[20:], height)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+// waitForSubscription waits until the handler subscribed, as the upgrade
+// completes before.
+func waitForSubscription(t *testing.T, broker *Broker) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		broker.mu.Lock()
+		n := len(broker.subscriptions)
+		broker.mu.Unlock()
+		if n > 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("handler did not subscribe")
+}
+
+func TestLiveHandler_Handle_Live(t *testing.T) {
+	t.Run("sends the uploaded images with thumbnails", func(t *testing.T) {
+		// arrange
+		broker := NewBroker(10)
+		store := new(mockCameraStore)
+		images := new(mockImageStore)
+		handler := NewLiveHandler(broker, store, nil, images)
+		handler.heartbeat = time.Minute
+		server := liveServer(handler)
+		defer server.Close()
+
+		store.On("GetCameraMetadataByID", mock.Anything, orgID, liveCamID).Return(&types.CameraMetadata{CamID: liveCamID, OrgID: orgID}, nil)
+		images.On("DownloadImage", mock.Anything, "img-1.png").Return(pngImage(400, 200), nil).Once()
+
+		conn, _, err := dial(t, server, "?thumbnail_width=100")
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		defer conn.Close()
+		waitForSubscription(t, broker)
+
+		// act
+		broker.Publish(types.Event{ID: 1, Type: types.EventCameraInitialized, OrgID: orgID, Data: []byte(`{"cam_id":"` + liveCamID + `"}`)})
+		broker.Publish(uploadEvent(2, "img-1"))
+
+		// assert
+		var msg types.LiveImageMessage
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		assert.Equal(t, int64(2), msg.EventID)
+		assert.Equal(t, "img-1", *msg.Camera.ImageID)
+		config, err := jpeg.DecodeConfig(bytes.NewReader(msg.Thumbnail))
+		assert.NoError(t, err)
+		assert.Equal(t, 100, config.Width)
+		assert.Equal(t, 50, config.Height)
+		store.AssertExpectations(t)
+		images.AssertExpectations(t)
+	})
+
+	t.Run("makes a new thumbnail when an image is uploaded again", func(t *testing.T) {
+		// arrange
+		broker := NewBroker(10)
+		store := new(mockCameraStore)
+		images := new(mockImageStore)
+		handler := NewLiveHandler(broker, store, nil, images)
+		handler.heartbeat = time.Minute
+		server := liveServer(handler)
+		defer server.Close()
+
+		store.On("GetCameraMetadataByID", mock.Anything, orgID, liveCamID).Return(&types.CameraMetadata{CamID: liveCamID, OrgID: orgID}, nil)
+		images.On("DownloadImage", mock.Anything, "img-1.png").Return(pngImage(400, 200), nil).Once()
+		images.On("DownloadImage", mock.Anything, "img-1.png").Return(pngImage(200, 200), nil).Once()
+
+		conn, _, err := dial(t, server, "?thumbnail_width=100")
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		defer conn.Close()
+		waitForSubscription(t, broker)
+
+		// act
+		broker.Publish(uploadEvent(1, "img-1"))
+		broker.Publish(uploadEvent(2, "img-1"))
+
+		// assert
+		var first, second types.LiveImageMessage
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if err := conn.ReadJSON(&first); err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		if err := conn.ReadJSON(&second); err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		config, err := jpeg.DecodeConfig(bytes.NewReader(first.Thumbnail))
+		assert.NoError(t, err)
+		assert.Equal(t, 50, config.Height)
+		config, err = jpeg.DecodeConfig(bytes.NewReader(second.Thumbnail))
+		assert.NoError(t, err)
+		assert.Equal(t, 100, config.Height)
+		images.AssertExpectations(t)
+	})
+
+	t.Run("sends no thumbnail of an image declaring too many pixels", func(t *testing.T) {
+		// arrange
+		broker := NewBroker(10)
+		store := new(mockCameraStore)
+		images := new(mockImageStore)
+		handler := NewLiveHandler(broker, store, nil, images)
+		handler.heartbeat = time.Minute
+		server := liveServer(handler)
+		defer server.Close()
+
+		store.On("GetCameraMetadataByID", mock.Anything, orgID, liveCamID).Return(&types.CameraMetadata{CamID: liveCamID, OrgID: orgID}, nil)
+		images.On("DownloadImage", mock.Anything, "img-1.png").Return(hugePNG(30000, 30000), nil)
+
+		conn, _, err := dial(t, server, "?thumbnail_width=100")
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		defer conn.Close()
+		waitForSubscription(t, broker)
+
+		// act
+		broker.Publish(uploadEvent(1, "img-1"))
+
+		// assert
+		var msg types.LiveImageMessage
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		assert.Equal(t, int64(1), msg.EventID)
+		assert.Empty(t, msg.Thumbnail)
+	})
+
+	t.Run("sends no thumbnail when the image cannot be read", func(t *testing.T) {
+		// arrange
+		broker := NewBroker(10)
+		store := new(mockCameraStore)
+		images := new(mockImageStore)
+		handler := NewLiveHandler(broker, store, nil, images)
+		handler.heartbeat = time.Minute
+		server := liveServer(handler)
+		defer server.Close()
+
+		store.On("GetCameraMetadataByID", mock.Anything, orgID, liveCamID).Return(&types.CameraMetadata{CamID: liveCamID, OrgID: orgID}, nil)
+		images.On("DownloadImage", mock.Anything, "img-1.png").Return(nil, errors.New("gone"))
+
+		conn, _, err := dial(t, server, "?thumbnail_width=100")
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		defer conn.Close()
+		waitForSubscription(t, broker)
+
+		// act
+		broker.Publish(uploadEvent(1, "img-1"))
+
+		// assert
+		var msg types.LiveImageMessage
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		assert.Equal(t, int64(1), msg.EventID)
+		assert.Empty(t, msg.Thumbnail)
+	})
+
+	t.Run("drops a client that falls behind", func(t *testing.T) {
+		// arrange
+		broker := NewBroker(10)
+		store := new(mockCameraStore)
+		handler := NewLiveHandler(broker, store, nil, new(mockImageStore))
+		handler.heartbeat = time.Minute
+		server := liveServer(handler)
+		defer server.Close()
+
+		store.On("GetCameraMetadataByID", mock.Anything, orgID, liveCamID).Return(&types.CameraMetadata{CamID: liveCamID, OrgID: orgID}, nil)
+
+		conn, _, err := dial(t, server, "")
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		defer conn.Close()
+		waitForSubscription(t, broker)
+
+		// act
+		broker.mu.Lock()
+		for sub := range broker.subscriptions {
+			broker.remove(sub)
+		}
+		broker.mu.Unlock()
+
+		// assert
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, _, err = conn.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.CloseTryAgainLater), "unexpected error: %v", err)
+	})
+
+	t.Run("unknown camera", func(t *testing.T) {
+		// arrange
+		store := new(mockCameraStore)
+		server := liveServer(NewLiveHandler(NewBroker(10), store, nil, nil))
+		defer server.Close()
+
+		store.On("GetCameraMetadataByID", mock.Anything, orgID, liveCamID).Return(nil, errors.New("not found"))
+
+		// act
+		_, resp, err := dial(t, server, "")
+
+		// assert
+		assert.ErrorIs(t, err, websocket.ErrBadHandshake)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("invalid thumbnail width", func(t *testing.T) {
+		// arrange
+		server := liveServer(NewLiveHandler(NewBroker(10), new(mockCameraStore), nil, nil))
+		defer server.Close()
+
+		// act
+		_, resp, err := dial(t, server, "?thumbnail_width=1000")
+
+		// assert
+		assert.ErrorIs(t, err, websocket.ErrBadHandshake)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
@@ -0,0 +1,144 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"go-sample-rest-api/config"
+	"go-sample-rest-api/logging"
+	auth2 "go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"go-sample-rest-api/utils"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Handler streams the events of the organization of the access token as
+// server-sent events.
+type Handler struct {
+	broker    *Broker
+	userStore types.UserStore
+	heartbeat time.Duration
+}
+
+func NewHandler(broker *Broker, userStore types.UserStore) *Handler {
+	return &Handler{
+		broker:    broker,
+		userStore: userStore,
+		heartbeat: time.Duration(config.Envs.EventsHeartbeatInSeconds) * time.Second,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/events", auth2.WithJWTAuth(h.handleEvents, h.userStore)).Methods(http.MethodGet)
+}
+
+// handleEvents godoc
+// @Summary Stream events
+// @Description Streams the camera events of the organization as server-sent events: camera.created, camera.initialized and camera.image_uploaded. Each event has the event ID as id, the event type as event and the event as data. A reconnecting client sends the Last-Event-ID header and first receives the events it missed; when they are no longer kept it receives a reset event instead and should reload the cameras. A heartbeat comment is sent when there was nothing else to send for a while.
+// @Tags events
+// @Produce text/event-stream
+// @Param camID query string false "Only events of this camera"
+// @Param group query int false "Only events of cameras in this group"
+// @Param Last-Event-ID header int false "ID of the last received event"
+// @Success 200 {string} string "The event stream."
+// @Failure 400 {object} types.HTTPError "Invalid camID, group or Last-Event-ID."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /events [get]
+func (h *Handler) handleEvents(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var lastEventID int64
+	if header := r.Header.Get("Last-Event-ID"); header != "" {
+		if lastEventID, err = strconv.ParseInt(header, 10, 64); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid Last-Event-ID: %q", header))
+			return
+		}
+	}
+
+	sub, missed, ok := h.broker.Subscribe(filter, lastEventID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// keeps nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if !ok {
+		io.WriteString(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, event := range missed {
+		writeEvent(w, event)
+	}
+	rc := http.NewResponseController(w)
+	if err := rc.Flush(); err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Event stream cannot be flushed")
+		return
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, open := <-sub.C:
+			if !open {
+				// fell behind, the client resumes when it reconnects
+				return
+			}
+			err = writeEvent(w, event)
+		case <-heartbeat.C:
+			_, err = io.WriteString(w, ": heartbeat\n\n")
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+		heartbeat.Reset(h.heartbeat)
+	}
+}
+
+// parseFilter reads the camID and group query parameters.
+func parseFilter(r *http.Request) (Filter, error) {
+	filter := Filter{OrgID: auth2.GetOrgIDFromContext(r.Context())}
+	query := r.URL.Query()
+	if camID := query.Get("camID"); camID != "" {
+		if _, err := uuid.Parse(camID); err != nil {
+			return filter, fmt.Errorf("invalid camID: %v", err)
+		}
+		filter.CamID = camID
+	}
+	if group := query.Get("group"); group != "" {
+		groupID, err := strconv.Atoi(group)
+		if err != nil {
+			return filter, fmt.Errorf("invalid group: %q", group)
+		}
+		filter.GroupID = &groupID
+	}
+
+	return filter, nil
+}
+
+func writeEvent(w io.Writer, event types.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
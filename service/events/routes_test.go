@@ -0,0 +1,159 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"go-sample-rest-api/service/auth"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const orgID = 3
+
+func withOrganization(req *http.Request) *http.Request {
+	ctx := context.WithValue(req.Context(), auth.UserKey, 1)
+	return req.WithContext(context.WithValue(ctx, auth.OrgKey, orgID))
+}
+
+func eventsServer(handler *Handler) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.handleEvents(w, withOrganization(r))
+	}))
+}
+
+// readFrame reads the lines of the next event or comment of the stream.
+func readFrame(t *testing.T, reader *bufio.Reader) string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read the stream: %v", err)
+		}
+		if line == "\n" {
+			return strings.Join(lines, "\n")
+		}
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+}
+
+func openStream(t *testing.T, url string, lastEventID string) (*http.Response, *bufio.Reader) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	return resp, bufio.NewReader(resp.Body)
+}
+
+func TestEventsService_Handle_Events(t *testing.T) {
+	t.Run("streams the events of the camera", func(t *testing.T) {
+		// arrange
+		broker := NewBroker(10)
+		server := eventsServer(&Handler{broker: broker, heartbeat: time.Minute})
+		defer server.Close()
+
+		resp, reader := openStream(t, server.URL+"?camID=7f9c24e8-3b12-4fef-91e0-3e5d3f2c1a00", "")
+		defer resp.Body.Close()
+
+		// act
+		broker.Publish(cameraEvent(1, orgID, "00000000-0000-0000-0000-000000000000", nil))
+		broker.Publish(cameraEvent(2, orgID, "7f9c24e8-3b12-4fef-91e0-3e5d3f2c1a00", nil))
+
+		// assert
+		if status := resp.StatusCode; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+			t.Errorf("unexpected content type: %s", contentType)
+		}
+		frame := readFrame(t, reader)
+		if !strings.HasPrefix(frame, "id: 2\nevent: camera.created\ndata: {\"id\":2,") {
+			t.Errorf("unexpected frame: %q", frame)
+		}
+	})
+
+	t.Run("resumes after the last event ID", func(t *testing.T) {
+		// arrange
+		broker := NewBroker(10)
+		for id := int64(1); id <= 3; id++ {
+			broker.Publish(cameraEvent(id, orgID, "a", nil))
+		}
+		server := eventsServer(&Handler{broker: broker, heartbeat: time.Minute})
+		defer server.Close()
+
+		// act
+		resp, reader := openStream(t, server.URL, "1")
+		defer resp.Body.Close()
+
+		// assert
+		for _, want := range []string{"id: 2\n", "id: 3\n"} {
+			if frame := readFrame(t, reader); !strings.HasPrefix(frame, want) {
+				t.Errorf("unexpected frame: %q, want %q", frame, want)
+			}
+		}
+	})
+
+	t.Run("resets when the last event is no longer kept", func(t *testing.T) {
+		// arrange
+		broker := NewBroker(1)
+		broker.Publish(cameraEvent(1, orgID, "a", nil))
+		broker.Publish(cameraEvent(2, orgID, "a", nil))
+		server := eventsServer(&Handler{broker: broker, heartbeat: time.Minute})
+		defer server.Close()
+
+		// act
+		resp, reader := openStream(t, server.URL, "1")
+		defer resp.Body.Close()
+
+		// assert
+		if frame := readFrame(t, reader); frame != "event: reset\ndata: {}" {
+			t.Errorf("unexpected frame: %q", frame)
+		}
+	})
+
+	t.Run("sends heartbeats", func(t *testing.T) {
+		// arrange
+		server := eventsServer(&Handler{broker: NewBroker(10), heartbeat: 10 * time.Millisecond})
+		defer server.Close()
+
+		// act
+		resp, reader := openStream(t, server.URL, "")
+		defer resp.Body.Close()
+
+		// assert
+		if frame := readFrame(t, reader); frame != ": heartbeat" {
+			t.Errorf("unexpected frame: %q", frame)
+		}
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		for name, test := range map[string]struct{ query, lastEventID string }{
+			"invalid camID":       {query: "?camID=abc"},
+			"invalid group":       {query: "?group=abc"},
+			"invalid lastEventID": {lastEventID: "abc"},
+		} {
+			t.Run(name, func(t *testing.T) {
+				// arrange
+				server := eventsServer(&Handler{broker: NewBroker(10), heartbeat: time.Minute})
+				defer server.Close()
+
+				// act
+				resp, _ := openStream(t, server.URL+test.query, test.lastEventID)
+				resp.Body.Close()
+
+				// assert
+				if status := resp.StatusCode; status != http.StatusBadRequest {
+					t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+				}
+			})
+		}
+	})
+}
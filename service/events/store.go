@@ -0,0 +1,68 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"github.com/sirupsen/logrus"
+	"go-sample-rest-api/customerrors"
+	"go-sample-rest-api/db"
+	"go-sample-rest-api/logging"
+	"go-sample-rest-api/types"
+	"strconv"
+)
+
+const eventColumns = "id, org_id, type, data, created_at"
+
+// Store reads the events of the outbox.
+type Store struct {
+	db db.DB
+}
+
+func NewStore(db db.DB) *Store {
+	return &Store{db: db}
+}
+
+// GetEvent returns the event with the ID.
+func (s *Store) GetEvent(ctx context.Context, id int64) (*types.Event, error) {
+	var e types.Event
+	err := s.db.QueryRowContext(ctx, "SELECT "+eventColumns+" FROM outbox_events WHERE id = $1", id).
+		Scan(&e.ID, &e.OrgID, &e.Type, &e.Data, &e.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &customerrors.NotFoundError{ID: strconv.FormatInt(id, 10)}
+		}
+		logging.GetLogger().WithFields(logrus.Fields{
+			"eventID": id,
+			"error":   err,
+		}).Error("Error retrieving event")
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// ListRecentEvents returns the latest limit events with an ID above afterID,
+// oldest first.
+func (s *Store) ListRecentEvents(ctx context.Context, afterID int64, limit int) ([]types.Event, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+eventColumns+" FROM (SELECT "+eventColumns+` FROM outbox_events
+              WHERE id > $1 ORDER BY id DESC LIMIT $2) recent ORDER BY id`, afterID, limit)
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"afterID": afterID,
+			"error":   err,
+		}).Error("Error listing recent events")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []types.Event
+	for rows.Next() {
+		var e types.Event
+		if err := rows.Scan(&e.ID, &e.OrgID, &e.Type, &e.Data, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
@@ -0,0 +1,85 @@
+package events
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"go-sample-rest-api/customerrors"
+	db2 "go-sample-rest-api/db"
+	"testing"
+	"time"
+)
+
+var eventRowColumns = []string{"id", "org_id", "type", "data", "created_at"}
+
+func setupMockDB(t *testing.T) (*db2.SQLDB, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	return db2.NewSQLDB(db), mock, func() { db.Close() }
+}
+
+func TestStore_GetEvent(t *testing.T) {
+	t.Run("GetEvent_returnsEvent", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		mock.ExpectQuery(`^SELECT id, org_id, type, data, created_at FROM outbox_events WHERE id = \$1`).
+			WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows(eventRowColumns).AddRow(7, 3, "camera.created", []byte(`{"cam_id":"c"}`), time.Now()))
+
+		// act
+		event, err := NewStore(db).GetEvent(context.Background(), 7)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), event.ID)
+		assert.Equal(t, 3, event.OrgID)
+		assert.JSONEq(t, `{"cam_id":"c"}`, string(event.Data))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("GetEvent_notFound", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		mock.ExpectQuery(`^SELECT id, org_id, type, data, created_at FROM outbox_events WHERE id = \$1`).
+			WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows(eventRowColumns))
+
+		// act
+		_, err := NewStore(db).GetEvent(context.Background(), 7)
+
+		// assert
+		assert.IsType(t, &customerrors.NotFoundError{}, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStore_ListRecentEvents(t *testing.T) {
+	t.Run("ListRecentEvents_returnsOldestFirst", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		mock.ExpectQuery(`WHERE id > \$1 ORDER BY id DESC LIMIT \$2\) recent ORDER BY id$`).
+			WithArgs(int64(4), 100).
+			WillReturnRows(sqlmock.NewRows(eventRowColumns).
+				AddRow(5, 3, "camera.created", []byte(`{}`), time.Now()).
+				AddRow(6, 3, "camera.initialized", []byte(`{}`), time.Now()))
+
+		// act
+		events, err := NewStore(db).ListRecentEvents(context.Background(), 4, 100)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Len(t, events, 2)
+		assert.Equal(t, int64(5), events[0].ID)
+		assert.Equal(t, "camera.initialized", events[1].Type)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,128 @@
+package events
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"sync"
+)
+
+// thumbnailCacheSize is the number of thumbnails kept, so that the clients
+// watching a camera share one download and resize per image.
+const thumbnailCacheSize = 32
+
+// maxThumbnailPixels is the largest image a thumbnail is made of. The header
+// is checked before decoding, since a small file can declare a huge image.
+const maxThumbnailPixels = 40_000_000
+
+type thumbnailKey struct {
+	imageID string
+	width   int
+}
+
+// cachedThumbnail is a thumbnail with the upload event it was made for.
+type cachedThumbnail struct {
+	eventID int64
+	data    []byte
+}
+
+type thumbnailCache struct {
+	mu    sync.Mutex
+	items map[thumbnailKey]cachedThumbnail
+	order []thumbnailKey
+}
+
+func newThumbnailCache() *thumbnailCache {
+	return &thumbnailCache{items: make(map[thumbnailKey]cachedThumbnail)}
+}
+
+// get returns the thumbnail made for the upload event. An image can be
+// uploaded again under the same ID, so a thumbnail of another event is stale.
+func (c *thumbnailCache) get(key thumbnailKey, eventID int64) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, ok := c.items[key]
+	if !ok || item.eventID != eventID {
+		return nil, false
+	}
+	return item.data, true
+}
+
+// add keeps the thumbnail of the upload event in place of the one of an
+// earlier upload under the same image ID.
+func (c *thumbnailCache) add(key thumbnailKey, eventID int64, thumbnail []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if item, ok := c.items[key]; ok {
+		if item.eventID < eventID {
+			c.items[key] = cachedThumbnail{eventID: eventID, data: thumbnail}
+		}
+		return
+	}
+	if len(c.order) == thumbnailCacheSize {
+		delete(c.items, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.items[key] = cachedThumbnail{eventID: eventID, data: thumbnail}
+	c.order = append(c.order, key)
+}
+
+// thumbnail returns the image as a JPEG at most width pixels wide. Smaller
+// images keep their size, and images of more than maxThumbnailPixels are
+// refused.
+func thumbnail(data []byte, width int) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxThumbnailPixels {
+		return nil, fmt.Errorf("image of %dx%d pixels is too large for a thumbnail", cfg.Width, cfg.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := src.Bounds()
+	dst := src
+	if bounds.Dx() > width {
+		height := max(1, bounds.Dy()*width/bounds.Dx())
+		dst = downscale(src, width, height)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 75}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// downscale averages the source pixels that fall into each target pixel.
+func downscale(src image.Image, width, height int) image.Image {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*bounds.Dy()/height)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*bounds.Dx()/width)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
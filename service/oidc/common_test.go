@@ -0,0 +1,330 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/types"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type mockUserStore struct {
+	mock.Mock
+}
+
+func (m *mockUserStore) GetUserByEmail(ctx context.Context, email string) (*types.User, error) {
+	args := m.Called(ctx, email)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*types.User), args.Error(1)
+}
+
+func (m *mockUserStore) CreateUser(ctx context.Context, u types.User, organizationName string) error {
+	args := m.Called(ctx, u, organizationName)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) CreateOrganizationUser(ctx context.Context, orgID int, u types.User, role string, entry types.AuditEntry) error {
+	args := m.Called(ctx, orgID, u, role, entry)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) GetOrganizationUser(ctx context.Context, orgID, userID int) (*types.User, error) {
+	args := m.Called(ctx, orgID, userID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.User), args.Error(1)
+}
+
+func (m *mockUserStore) GetOrganizationUsers(ctx context.Context, orgID int, userIDs []int) ([]types.User, error) {
+	args := m.Called(ctx, orgID, userIDs)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.User), args.Error(1)
+}
+
+func (m *mockUserStore) ListMemberships(ctx context.Context, userID int) ([]types.Membership, error) {
+	args := m.Called(ctx, userID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.Membership), args.Error(1)
+}
+
+func (m *mockUserStore) SetUserRole(ctx context.Context, orgID, userID int, role string, entry types.AuditEntry) error {
+	args := m.Called(ctx, orgID, userID, role, entry)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) GetUserByID(ctx context.Context, id int) (*types.User, error) {
+	args := m.Called(ctx, id)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.User), args.Error(1)
+}
+
+func (m *mockUserStore) UpdateUser(ctx context.Context, u types.User) error {
+	args := m.Called(ctx, u)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) ListUsers(ctx context.Context, orgID int, query types.UserListQuery) ([]types.User, int, error) {
+	args := m.Called(ctx, orgID, query)
+	if args.Error(2) != nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]types.User), args.Int(1), args.Error(2)
+}
+
+func (m *mockUserStore) SetUserDisabled(ctx context.Context, orgID, userID int, disabled bool, entry types.AuditEntry) error {
+	args := m.Called(ctx, orgID, userID, disabled, entry)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) DeleteUser(ctx context.Context, orgID, userID int, entry types.AuditEntry) error {
+	args := m.Called(ctx, orgID, userID, entry)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) AnonymizeUser(ctx context.Context, orgID, userID int, entry types.AuditEntry) error {
+	args := m.Called(ctx, orgID, userID, entry)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) UpdatePassword(ctx context.Context, userID int, hashedPassword string) error {
+	args := m.Called(ctx, userID, hashedPassword)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) ChangePassword(ctx context.Context, userID int, hashedPassword string, keepSessionID string) error {
+	args := m.Called(ctx, userID, hashedPassword, keepSessionID)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) MarkEmailVerified(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) CreateUserToken(ctx context.Context, token types.UserToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) GetUserToken(ctx context.Context, purpose string, tokenHash string) (*types.UserToken, error) {
+	args := m.Called(ctx, purpose, tokenHash)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.UserToken), args.Error(1)
+}
+
+func (m *mockUserStore) ConsumeUserToken(ctx context.Context, purpose string, tokenHash string) (*types.UserToken, error) {
+	args := m.Called(ctx, purpose, tokenHash)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.UserToken), args.Error(1)
+}
+
+func (m *mockUserStore) SetTOTPSecret(ctx context.Context, userID int, secret string) error {
+	args := m.Called(ctx, userID, secret)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) EnableTOTP(ctx context.Context, userID int, step int64, recoveryCodeHashes []string) error {
+	args := m.Called(ctx, userID, step, recoveryCodeHashes)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) DisableTOTP(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	args := m.Called(ctx, userID, step)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) ConsumeRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	args := m.Called(ctx, userID, codeHash)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) CreateSession(ctx context.Context, session types.Session) error {
+	args := m.Called(ctx, session)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) TouchSession(ctx context.Context, userID int, sessionID string) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) ListSessions(ctx context.Context, userID int) ([]types.Session, error) {
+	args := m.Called(ctx, userID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.Session), args.Error(1)
+}
+
+func (m *mockUserStore) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) RevokeOtherSessions(ctx context.Context, userID int, keepSessionID string) error {
+	args := m.Called(ctx, userID, keepSessionID)
+	return args.Error(0)
+}
+
+type MockAuthenticator struct {
+	mock.Mock
+}
+
+func (m *MockAuthenticator) CreateJWT(secret []byte, userID, orgID int, sessionID string) (string, error) {
+	args := m.Called(secret, userID, orgID, sessionID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAuthenticator) CreateMFAChallenge(secret []byte, userID int) (string, error) {
+	args := m.Called(secret, userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAuthenticator) ValidateMFAChallenge(token string) (int, error) {
+	args := m.Called(token)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockAuthenticator) HashPassword(password string) (string, error) {
+	args := m.Called(password)
+	if args.Error(1) != nil {
+		return "", args.Error(1)
+	}
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAuthenticator) ComparePasswords(hashed string, plain []byte) bool {
+	args := m.Called(hashed, plain)
+	return args.Bool(0)
+}
+
+func (m *MockAuthenticator) NeedsRehash(hashed string) bool {
+	args := m.Called(hashed)
+	return args.Bool(0)
+}
+
+// mockProvider is a minimal OpenID Connect provider. Every authorization
+// request is approved for the configured claims.
+type mockProvider struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+	claims   jwt.MapClaims
+
+	mu     sync.Mutex
+	issued map[string]authorization
+}
+
+type authorization struct {
+	challenge string
+	nonce     string
+}
+
+func newMockProvider(t *testing.T, clientID string) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &mockProvider{key: key, clientID: clientID, issued: map[string]authorization{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test-key",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", p.handleToken)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+
+	return p
+}
+
+// authorize simulates the user approving the request and returns the authorization code.
+func (p *mockProvider) authorize(t *testing.T, authURL string) (code, state string) {
+	req := httptest.NewRequest(http.MethodGet, authURL, nil)
+	query := req.URL.Query()
+	if query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("expected S256 code challenge, got %q", query.Get("code_challenge_method"))
+	}
+
+	code = "code-" + query.Get("state")
+	p.mu.Lock()
+	p.issued[code] = authorization{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	p.mu.Unlock()
+
+	return code, query.Get("state")
+}
+
+func (p *mockProvider) handleToken(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	p.mu.Lock()
+	auth, ok := p.issued[r.PostForm.Get("code")]
+	delete(p.issued, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != auth.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   p.server.URL,
+		"aud":   p.clientID,
+		"sub":   "provider-subject",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": auth.nonce,
+	}
+	for k, v := range p.claims {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test-key"
+	idToken, _ := token.SignedString(p.key)
+
+	json.NewEncoder(w).Encode(map[string]string{"access_token": "access", "token_type": "Bearer", "id_token": idToken})
+}
@@ -0,0 +1,262 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"go-sample-rest-api/config"
+	"go-sample-rest-api/logging"
+	auth2 "go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"go-sample-rest-api/utils"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pendingLoginTTL bounds how long a user may take at the identity provider.
+const pendingLoginTTL = 10 * time.Minute
+
+type pendingLogin struct {
+	codeVerifier string
+	nonce        string
+	expiresAt    time.Time
+}
+
+// pendingLogins keeps the PKCE verifier and nonce of started logins, keyed by state.
+type pendingLogins struct {
+	mu     sync.Mutex
+	logins map[string]pendingLogin
+}
+
+func newPendingLogins() *pendingLogins {
+	return &pendingLogins{logins: map[string]pendingLogin{}}
+}
+
+func (p *pendingLogins) put(state string, login pendingLogin) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for key, l := range p.logins {
+		if now.After(l.expiresAt) {
+			delete(p.logins, key)
+		}
+	}
+	p.logins[state] = login
+}
+
+// take removes and returns the login for state, so every state is single use.
+func (p *pendingLogins) take(state string) (pendingLogin, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	login, ok := p.logins[state]
+	delete(p.logins, state)
+	if !ok || time.Now().After(login.expiresAt) {
+		return pendingLogin{}, false
+	}
+
+	return login, true
+}
+
+type Handler struct {
+	store    types.UserStore
+	auth     auth2.Authenticator
+	provider *Provider
+	pending  *pendingLogins
+}
+
+func NewHandler(store types.UserStore, auth auth2.Authenticator, provider *Provider) *Handler {
+	return &Handler{store: store, auth: auth, provider: provider, pending: newPendingLogins()}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/oidc/login", h.handleLogin).Methods(http.MethodGet)
+	router.HandleFunc("/oidc/callback", h.handleCallback).Methods(http.MethodGet)
+}
+
+// handleLogin godoc
+// @Summary Start single sign-on
+// @Description Redirects to the identity provider using the authorization code flow with PKCE.
+// @Tags auth
+// @Success 302 "Redirect to the identity provider."
+// @Failure 502 {object} types.HTTPError "Identity provider is unavailable."
+// @Router /oidc/login [get]
+func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
+	state := randomString()
+	nonce := randomString()
+	codeVerifier := randomString()
+
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	authURL, err := h.provider.AuthCodeURL(r.Context(), state, nonce, base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Failed to build OIDC authorization request")
+		utils.WriteError(w, http.StatusBadGateway, fmt.Errorf("identity provider unavailable"))
+		return
+	}
+
+	h.pending.put(state, pendingLogin{
+		codeVerifier: codeVerifier,
+		nonce:        nonce,
+		expiresAt:    time.Now().Add(pendingLoginTTL),
+	})
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// handleCallback godoc
+// @Summary Complete single sign-on
+// @Description Exchanges the authorization code, provisions or links the user by email and returns a JWT.
+// @Tags auth
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State from the login request"
+// @Success 200 {object} map[string]string "token: JWT Token on successful login, or challengeToken for /login/2fa if a second factor is required."
+// @Failure 400 {object} types.HTTPError "Unknown or expired login state."
+// @Failure 401 {object} types.HTTPError "The identity provider did not authenticate the user."
+// @Failure 403 {object} types.HTTPError "The identity provider did not return a verified email, or the account is disabled."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /oidc/callback [get]
+func (h *Handler) handleCallback(w http.ResponseWriter, r *http.Request) {
+	log := logging.GetLogger()
+	query := r.URL.Query()
+
+	login, ok := h.pending.take(query.Get("state"))
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unknown or expired login state"))
+		return
+	}
+
+	if errCode := query.Get("error"); errCode != "" {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("identity provider error: %s", errCode))
+		return
+	}
+
+	code := query.Get("code")
+	if code == "" {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing authorization code"))
+		return
+	}
+
+	claims, err := h.provider.Exchange(r.Context(), code, login.codeVerifier, login.nonce)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Failed to complete OIDC login")
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("single sign-on failed"))
+		return
+	}
+
+	// Users are linked by email, so a provider that does not vouch for the
+	// address could log in as any local user with it.
+	if claims.Email == "" || claims.EmailVerified == nil || !*claims.EmailVerified {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("identity provider did not return a verified email"))
+		return
+	}
+
+	u, err := h.findOrProvisionUser(r.Context(), claims)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error": err,
+			"email": claims.Email,
+		}).Error("Failed to provision OIDC user")
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if u.DisabledAt.Valid {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("account disabled"))
+		return
+	}
+
+	secret := []byte(config.Envs.JWTSecret)
+	if u.TOTPEnabledAt.Valid {
+		// single sign-on replaces the password, not the second factor
+		challenge, err := h.auth.CreateMFAChallenge(secret, u.ID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, map[string]string{"challengeToken": challenge})
+		return
+	}
+
+	token, err := auth2.IssueAccessToken(h.auth, h.store, r, secret, u.ID)
+	if errors.Is(err, auth2.ErrNoOrganization) {
+		utils.WriteError(w, http.StatusForbidden, err)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"token": token})
+}
+
+// findOrProvisionUser links the login to the local user with the same email,
+// creating one with an unusable random password if none exists yet. The email
+// counts as verified because the identity provider vouched for it.
+func (h *Handler) findOrProvisionUser(ctx context.Context, claims *IDTokenClaims) (*types.User, error) {
+	email := claims.Email
+	if u, err := h.store.GetUserByEmail(ctx, email); err == nil {
+		if !u.EmailVerifiedAt.Valid {
+			if err := h.store.MarkEmailVerified(ctx, u.ID); err != nil {
+				return nil, err
+			}
+		}
+		return u, nil
+	}
+
+	firstName, lastName := claims.GivenName, claims.FamilyName
+	if firstName == "" && lastName == "" {
+		firstName, lastName, _ = strings.Cut(claims.Name, " ")
+	}
+
+	hashedPassword, err := h.auth.HashPassword(randomString())
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.store.CreateUser(ctx, types.User{
+		FirstName: firstName,
+		LastName:  lastName,
+		Email:     email,
+		Password:  hashedPassword,
+	}, types.PersonalOrganizationName(firstName, lastName, email))
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := h.store.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.store.MarkEmailVerified(ctx, u.ID); err != nil {
+		return nil, err
+	}
+
+	logging.GetLogger().WithFields(logrus.Fields{
+		"email":   email,
+		"subject": claims.Subject,
+	}).Info("Provisioned user from OIDC login")
+
+	return u, nil
+}
+
+// randomString returns 32 random bytes, base64url encoded (43 characters, a valid PKCE verifier).
+func randomString() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
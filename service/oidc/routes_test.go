@@ -0,0 +1,248 @@
+package oidc
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/types"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+const testClientID = "camera-api"
+
+func newTestHandler(t *testing.T) (*Handler, *mockProvider, *mockUserStore, *MockAuthenticator, *mux.Router) {
+	idp := newMockProvider(t, testClientID)
+	provider := NewProvider(Config{
+		IssuerURL:   idp.server.URL,
+		ClientID:    testClientID,
+		RedirectURL: "http://localhost/api/v1/oidc/callback",
+		Scopes:      []string{"openid", "email", "profile"},
+	})
+
+	store := new(mockUserStore)
+	auth := new(MockAuthenticator)
+	handler := NewHandler(store, auth, provider)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	return handler, idp, store, auth, router
+}
+
+// startLogin calls /oidc/login and lets the mock provider approve the redirect.
+func startLogin(t *testing.T, idp *mockProvider, router *mux.Router) (code, state string) {
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/oidc/login", nil))
+	if rr.Code != http.StatusFound {
+		t.Fatalf("expected status code %d, got %d", http.StatusFound, rr.Code)
+	}
+
+	return idp.authorize(t, rr.Header().Get("Location"))
+}
+
+func callback(router *mux.Router, code, state string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	target := "/oidc/callback?" + url.Values{"code": {code}, "state": {state}}.Encode()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+	return rr
+}
+
+func TestOIDCLogin(t *testing.T) {
+	t.Run("links an existing user by email", func(t *testing.T) {
+		// arrange
+		_, idp, store, auth, router := newTestHandler(t)
+		idp.claims = map[string]any{"email": "jane@example.com", "email_verified": true}
+		store.On("GetUserByEmail", mock.Anything, "jane@example.com").Return(&types.User{ID: 7, Email: "jane@example.com"}, nil)
+		store.On("MarkEmailVerified", mock.Anything, 7).Return(nil)
+		store.On("ListMemberships", mock.Anything, 7).Return([]types.Membership{{OrgID: 1, UserID: 7}}, nil)
+		store.On("CreateSession", mock.Anything, mock.AnythingOfType("types.Session")).Return(nil)
+		auth.On("CreateJWT", mock.Anything, 7, 1, mock.Anything).Return("service-token", nil)
+
+		// act
+		code, state := startLogin(t, idp, router)
+		rr := callback(router, code, state)
+
+		// assert
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		var body map[string]string
+		json.Unmarshal(rr.Body.Bytes(), &body)
+		if body["token"] != "service-token" {
+			t.Errorf("expected service token, got %q", body["token"])
+		}
+		store.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything, mock.Anything)
+		store.AssertExpectations(t)
+		auth.AssertExpectations(t)
+	})
+
+	t.Run("provisions a new user", func(t *testing.T) {
+		// arrange
+		_, idp, store, auth, router := newTestHandler(t)
+		idp.claims = map[string]any{"email": "new@example.com", "email_verified": true, "given_name": "New", "family_name": "User"}
+		store.On("GetUserByEmail", mock.Anything, "new@example.com").Return(nil, fmt.Errorf("user not found")).Once()
+		auth.On("HashPassword", mock.Anything).Return("unusable-hash", nil)
+		store.On("CreateUser", mock.Anything, types.User{FirstName: "New", LastName: "User", Email: "new@example.com", Password: "unusable-hash"}, "New User").Return(nil)
+		store.On("GetUserByEmail", mock.Anything, "new@example.com").Return(&types.User{ID: 9, Email: "new@example.com"}, nil).Once()
+		store.On("MarkEmailVerified", mock.Anything, 9).Return(nil)
+		store.On("ListMemberships", mock.Anything, 9).Return([]types.Membership{{OrgID: 1, UserID: 9}}, nil)
+		store.On("CreateSession", mock.Anything, mock.AnythingOfType("types.Session")).Return(nil)
+		auth.On("CreateJWT", mock.Anything, 9, 1, mock.Anything).Return("service-token", nil)
+
+		// act
+		code, state := startLogin(t, idp, router)
+		rr := callback(router, code, state)
+
+		// assert
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		store.AssertExpectations(t)
+		auth.AssertExpectations(t)
+	})
+
+	t.Run("requires the second factor when 2FA is enabled", func(t *testing.T) {
+		// arrange
+		_, idp, store, auth, router := newTestHandler(t)
+		idp.claims = map[string]any{"email": "jane@example.com", "email_verified": true}
+		store.On("GetUserByEmail", mock.Anything, "jane@example.com").Return(&types.User{
+			ID:              7,
+			EmailVerifiedAt: sql.NullTime{Time: time.Now(), Valid: true},
+			TOTPEnabledAt:   sql.NullTime{Time: time.Now(), Valid: true},
+		}, nil)
+		auth.On("CreateMFAChallenge", mock.Anything, 7).Return("challenge", nil)
+
+		// act
+		code, state := startLogin(t, idp, router)
+		rr := callback(router, code, state)
+
+		// assert
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		var body map[string]string
+		json.Unmarshal(rr.Body.Bytes(), &body)
+		if body["challengeToken"] != "challenge" || body["token"] != "" {
+			t.Errorf("expected only a challenge token, got %v", body)
+		}
+		auth.AssertNotCalled(t, "CreateJWT", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects unverified emails", func(t *testing.T) {
+		// arrange
+		_, idp, _, _, router := newTestHandler(t)
+		idp.claims = map[string]any{"email": "jane@example.com", "email_verified": false}
+
+		// act
+		code, state := startLogin(t, idp, router)
+		rr := callback(router, code, state)
+
+		// assert
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d, got %d", http.StatusForbidden, rr.Code)
+		}
+	})
+
+	t.Run("rejects emails without the verification claim", func(t *testing.T) {
+		// arrange
+		_, idp, store, _, router := newTestHandler(t)
+		idp.claims = map[string]any{"email": "jane@example.com"}
+
+		// act
+		code, state := startLogin(t, idp, router)
+		rr := callback(router, code, state)
+
+		// assert
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d, got %d", http.StatusForbidden, rr.Code)
+		}
+		store.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything)
+		store.AssertNotCalled(t, "MarkEmailVerified", mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects an unknown state", func(t *testing.T) {
+		// arrange
+		_, idp, _, _, router := newTestHandler(t)
+
+		// act
+		code, _ := startLogin(t, idp, router)
+		rr := callback(router, code, "forged-state")
+
+		// assert
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("state can only be used once", func(t *testing.T) {
+		// arrange
+		_, idp, store, auth, router := newTestHandler(t)
+		idp.claims = map[string]any{"email": "jane@example.com", "email_verified": true}
+		store.On("GetUserByEmail", mock.Anything, "jane@example.com").Return(&types.User{ID: 7, EmailVerifiedAt: sql.NullTime{Time: time.Now(), Valid: true}}, nil)
+		store.On("ListMemberships", mock.Anything, 7).Return([]types.Membership{{OrgID: 1, UserID: 7}}, nil)
+		store.On("CreateSession", mock.Anything, mock.AnythingOfType("types.Session")).Return(nil)
+		auth.On("CreateJWT", mock.Anything, 7, 1, mock.Anything).Return("service-token", nil)
+
+		// act
+		code, state := startLogin(t, idp, router)
+		first := callback(router, code, state)
+		second := callback(router, code, state)
+
+		// assert
+		if first.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, first.Code)
+		}
+		if second.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, second.Code)
+		}
+	})
+
+	t.Run("rejects an ID token issued for another client", func(t *testing.T) {
+		// arrange
+		_, idp, _, _, router := newTestHandler(t)
+		idp.claims = map[string]any{"email": "jane@example.com", "aud": "other-client"}
+
+		// act
+		code, state := startLogin(t, idp, router)
+		rr := callback(router, code, state)
+
+		// assert
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected status code %d, got %d", http.StatusUnauthorized, rr.Code)
+		}
+	})
+
+	t.Run("rejects a replayed nonce", func(t *testing.T) {
+		// arrange
+		_, idp, _, _, router := newTestHandler(t)
+		idp.claims = map[string]any{"email": "jane@example.com", "nonce": "attacker-nonce"}
+
+		// act
+		code, state := startLogin(t, idp, router)
+		rr := callback(router, code, state)
+
+		// assert
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected status code %d, got %d", http.StatusUnauthorized, rr.Code)
+		}
+	})
+
+	t.Run("fails when the provider is unreachable", func(t *testing.T) {
+		// arrange
+		handler := NewHandler(new(mockUserStore), new(MockAuthenticator), NewProvider(Config{IssuerURL: "http://127.0.0.1:1"}))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleLogin(rr, httptest.NewRequest(http.MethodGet, "/oidc/login", nil))
+
+		// assert
+		if rr.Code != http.StatusBadGateway {
+			t.Errorf("expected status code %d, got %d", http.StatusBadGateway, rr.Code)
+		}
+	})
+}
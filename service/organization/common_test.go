@@ -0,0 +1,38 @@
+package organization
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/types"
+)
+
+type mockOrganizationStore struct {
+	mock.Mock
+}
+
+func (m *mockOrganizationStore) GetOrganizationByID(ctx context.Context, orgID int) (*types.Organization, error) {
+	args := m.Called(ctx, orgID)
+	if org := args.Get(0); org != nil {
+		return org.(*types.Organization), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockOrganizationStore) RenameOrganization(ctx context.Context, orgID int, name string, entry types.AuditEntry) error {
+	args := m.Called(ctx, orgID, name, entry)
+	return args.Error(0)
+}
+
+// mockCameraStore only answers GetUsage; the handlers use no other method.
+type mockCameraStore struct {
+	mock.Mock
+	types.CameraMetadataStore
+}
+
+func (m *mockCameraStore) GetUsage(ctx context.Context, orgID int) (*types.Usage, error) {
+	args := m.Called(ctx, orgID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.Usage), args.Error(1)
+}
@@ -0,0 +1,120 @@
+package organization
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"go-sample-rest-api/audit"
+	"go-sample-rest-api/customerrors"
+	auth2 "go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"go-sample-rest-api/utils"
+	"net/http"
+)
+
+// ActionRenameOrganization is the action of the audit entry of a rename.
+const ActionRenameOrganization = "organization.rename"
+
+// Handler serves the organization of the access token. Members are managed
+// through the admin user endpoints.
+type Handler struct {
+	store       types.OrganizationStore
+	cameraStore types.CameraMetadataStore
+	userStore   types.UserStore
+	quota       types.Quota
+}
+
+func NewHandler(store types.OrganizationStore, cameraStore types.CameraMetadataStore, userStore types.UserStore, quota types.Quota) *Handler {
+	return &Handler{store: store, cameraStore: cameraStore, userStore: userStore, quota: quota}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/organizations/current", auth2.WithJWTAuth(h.handleGetCurrentOrganization, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/organizations/current", auth2.WithAdmin(h.handleUpdateCurrentOrganization, h.userStore)).Methods(http.MethodPatch)
+	router.HandleFunc("/organizations/current/usage", auth2.WithJWTAuth(h.handleGetCurrentUsage, h.userStore)).Methods(http.MethodGet)
+}
+
+// handleGetCurrentOrganization godoc
+// @Summary Get the current organization
+// @Description Returns the organization the access token acts in.
+// @Tags organizations
+// @Produce json
+// @Success 200 {object} types.Organization "The organization."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /organizations/current [get]
+func (h *Handler) handleGetCurrentOrganization(w http.ResponseWriter, r *http.Request) {
+	org, err := h.store.GetOrganizationByID(r.Context(), auth2.GetOrgIDFromContext(r.Context()))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, org)
+}
+
+// handleUpdateCurrentOrganization godoc
+// @Summary Rename the current organization
+// @Description Changes the name of the organization the access token acts in. Admins only.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param organization body types.UpdateOrganizationPayload true "New name"
+// @Success 200 {object} types.Organization "The renamed organization."
+// @Failure 400 {object} types.HTTPError "Bad Request when the payload is invalid."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 403 {object} types.HTTPError "Forbidden if the user is not an admin."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /organizations/current [patch]
+func (h *Handler) handleUpdateCurrentOrganization(w http.ResponseWriter, r *http.Request) {
+	var payload types.UpdateOrganizationPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", errors))
+		return
+	}
+
+	orgID := auth2.GetOrgIDFromContext(r.Context())
+	if err := h.store.RenameOrganization(r.Context(), orgID, payload.Name, audit.FromRequest(r, ActionRenameOrganization)); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	h.handleGetCurrentOrganization(w, r)
+}
+
+// handleGetCurrentUsage godoc
+// @Summary Get the usage of the current organization
+// @Description Returns the number of cameras and the bytes of images the organization stores, and its quota. A quota of 0 is unlimited.
+// @Tags organizations
+// @Produce json
+// @Success 200 {object} types.UsageResponse "The usage and quota."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /organizations/current/usage [get]
+func (h *Handler) handleGetCurrentUsage(w http.ResponseWriter, r *http.Request) {
+	usage, err := h.cameraStore.GetUsage(r.Context(), auth2.GetOrgIDFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.UsageResponse{Usage: *usage, Quota: h.quota})
+}
+
+// writeStoreError answers 404 for a missing organization and 500 for anything else.
+func writeStoreError(w http.ResponseWriter, err error) {
+	var notFound *customerrors.NotFoundError
+	if errors.As(err, &notFound) {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteError(w, http.StatusInternalServerError, err)
+}
@@ -0,0 +1,134 @@
+package organization
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/customerrors"
+	"go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func withOrganization(req *http.Request, orgID int) *http.Request {
+	ctx := context.WithValue(req.Context(), auth.UserKey, 1)
+	return req.WithContext(context.WithValue(ctx, auth.OrgKey, orgID))
+}
+
+func TestOrganizationService_Handle_GetCurrent(t *testing.T) {
+	t.Run("returns the organization of the token", func(t *testing.T) {
+		// arrange
+		store := new(mockOrganizationStore)
+		handler := NewHandler(store, nil, nil, types.Quota{})
+
+		store.On("GetOrganizationByID", mock.Anything, 3).Return(&types.Organization{ID: 3, Name: "Acme"}, nil)
+
+		req, _ := http.NewRequest(http.MethodGet, "/organizations/current", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleGetCurrentOrganization(rr, withOrganization(req, 3))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		var org types.Organization
+		json.Unmarshal(rr.Body.Bytes(), &org)
+		if org.ID != 3 || org.Name != "Acme" {
+			t.Errorf("unexpected organization: %+v", org)
+		}
+	})
+
+	t.Run("organization was deleted", func(t *testing.T) {
+		// arrange
+		store := new(mockOrganizationStore)
+		handler := NewHandler(store, nil, nil, types.Quota{})
+
+		store.On("GetOrganizationByID", mock.Anything, 3).Return(nil, &customerrors.NotFoundError{ID: "3"})
+
+		req, _ := http.NewRequest(http.MethodGet, "/organizations/current", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleGetCurrentOrganization(rr, withOrganization(req, 3))
+
+		// assert
+		if status := rr.Code; status != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+		}
+	})
+}
+
+func TestOrganizationService_Handle_UpdateCurrent(t *testing.T) {
+	t.Run("renames the organization of the token", func(t *testing.T) {
+		// arrange
+		store := new(mockOrganizationStore)
+		handler := NewHandler(store, nil, nil, types.Quota{})
+
+		store.On("RenameOrganization", mock.Anything, 3, "Acme Security", mock.MatchedBy(func(e types.AuditEntry) bool {
+			return e.Action == ActionRenameOrganization && e.ActorType == types.AuditActorUser && e.ActorID == "1"
+		})).Return(nil)
+		store.On("GetOrganizationByID", mock.Anything, 3).Return(&types.Organization{ID: 3, Name: "Acme Security"}, nil)
+
+		req, _ := http.NewRequest(http.MethodPatch, "/organizations/current", strings.NewReader(`{"name":"Acme Security"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleUpdateCurrentOrganization(rr, withOrganization(req, 3))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		store.AssertExpectations(t)
+	})
+
+	t.Run("rejects an empty name", func(t *testing.T) {
+		// arrange
+		store := new(mockOrganizationStore)
+		handler := NewHandler(store, nil, nil, types.Quota{})
+
+		req, _ := http.NewRequest(http.MethodPatch, "/organizations/current", strings.NewReader(`{"name":""}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleUpdateCurrentOrganization(rr, withOrganization(req, 3))
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+		store.AssertNotCalled(t, "RenameOrganization", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestOrganizationService_Handle_GetCurrentUsage(t *testing.T) {
+	t.Run("returns the usage and quota of the organization of the token", func(t *testing.T) {
+		// arrange
+		cameraStore := new(mockCameraStore)
+		quota := types.Quota{MaxCameras: 10, MaxStorageBytes: 1 << 20}
+		handler := NewHandler(new(mockOrganizationStore), cameraStore, nil, quota)
+
+		cameraStore.On("GetUsage", mock.Anything, 3).Return(&types.Usage{Cameras: 4, StorageBytes: 2048}, nil)
+
+		req, _ := http.NewRequest(http.MethodGet, "/organizations/current/usage", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleGetCurrentUsage(rr, withOrganization(req, 3))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		var response types.UsageResponse
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		if response.Usage.Cameras != 4 || response.Usage.StorageBytes != 2048 || response.Quota != quota {
+			t.Errorf("unexpected usage: %+v", response)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package organization
+
+import (
+	"context"
+	"database/sql"
+	"github.com/sirupsen/logrus"
+	"go-sample-rest-api/audit"
+	"go-sample-rest-api/customerrors"
+	"go-sample-rest-api/db"
+	"go-sample-rest-api/logging"
+	"go-sample-rest-api/types"
+	"strconv"
+)
+
+type Store struct {
+	db db.DB
+}
+
+func NewStore(db db.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) GetOrganizationByID(ctx context.Context, orgID int) (*types.Organization, error) {
+	o := new(types.Organization)
+	err := s.db.QueryRowContext(ctx, "SELECT id, name, created_at FROM organizations WHERE id = $1", orgID).
+		Scan(&o.ID, &o.Name, &o.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &customerrors.NotFoundError{ID: strconv.Itoa(orgID)}
+		}
+		logging.GetLogger().WithFields(logrus.Fields{
+			"orgID": orgID,
+			"error": err,
+		}).Error("Error retrieving organization")
+		return nil, err
+	}
+
+	return o, nil
+}
+
+func (s *Store) RenameOrganization(ctx context.Context, orgID int, name string, entry types.AuditEntry) error {
+	log := logging.GetLogger()
+	err := s.db.WithTx(ctx, func(tx db.DB) error {
+		var before string
+		err := tx.QueryRowContext(ctx, "SELECT name FROM organizations WHERE id = $1 FOR UPDATE", orgID).Scan(&before)
+		if err == sql.ErrNoRows {
+			return &customerrors.NotFoundError{ID: strconv.Itoa(orgID)}
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE organizations SET name = $1 WHERE id = $2", name, orgID); err != nil {
+			return err
+		}
+
+		entry.OrgID = orgID
+		entry.ResourceType = "organization"
+		entry.ResourceID = strconv.Itoa(orgID)
+		entry.Changes = audit.Changes(map[string]any{"name": before}, map[string]any{"name": name})
+		return audit.Write(ctx, tx, entry)
+	})
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"orgID": orgID,
+			"error": err,
+		}).Error("Error renaming organization")
+		return err
+	}
+
+	log.WithFields(logrus.Fields{
+		"orgID": orgID,
+		"name":  name,
+	}).Info("Organization renamed")
+	return nil
+}
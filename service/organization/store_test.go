@@ -0,0 +1,88 @@
+package organization
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"go-sample-rest-api/customerrors"
+	db2 "go-sample-rest-api/db"
+	"go-sample-rest-api/types"
+	"testing"
+	"time"
+)
+
+func setupMockDB(t *testing.T) (*db2.SQLDB, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	return db2.NewSQLDB(db), mock, func() { db.Close() }
+}
+
+func TestStore_GetOrganizationByID(t *testing.T) {
+	t.Run("GetOrganizationByID_withKnownID_returnsIt", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		mock.ExpectQuery(`^SELECT id, name, created_at FROM organizations WHERE id = \$1$`).
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at"}).AddRow(3, "Acme", time.Now()))
+
+		// act
+		org, err := store.GetOrganizationByID(context.Background(), 3)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, "Acme", org.Name)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("GetOrganizationByID_withUnknownID_returnsNotFound", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		mock.ExpectQuery(`^SELECT id, name, created_at FROM organizations`).
+			WithArgs(9).
+			WillReturnError(sql.ErrNoRows)
+
+		// act
+		_, err := store.GetOrganizationByID(context.Background(), 9)
+
+		// assert
+		var notFound *customerrors.NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+	})
+}
+
+func TestStore_RenameOrganization(t *testing.T) {
+	// arrange
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	store := NewStore(db)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT name FROM organizations WHERE id = \$1 FOR UPDATE`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Acme"))
+	mock.ExpectExec(`UPDATE organizations SET name = \$1 WHERE id = \$2`).
+		WithArgs("Acme Security", 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO audit_log`).
+		WithArgs(3, "user", "1", "organization.rename", "organization", "3",
+			types.AuditChanges{"name": {Before: "Acme", After: "Acme Security"}}, "", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	// act
+	err := store.RenameOrganization(context.Background(), 3, "Acme Security", types.AuditEntry{ActorType: "user", ActorID: "1", Action: "organization.rename"})
+
+	// assert
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,361 @@
+package privacy
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/storage"
+	"go-sample-rest-api/types"
+)
+
+type mockUserStore struct {
+	mock.Mock
+}
+
+func (m *mockUserStore) UpdateUser(ctx context.Context, u types.User) error {
+	args := m.Called(ctx, u)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) GetUserByEmail(ctx context.Context, email string) (*types.User, error) {
+	args := m.Called(ctx, email)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*types.User), args.Error(1)
+}
+
+func (m *mockUserStore) CreateUser(ctx context.Context, u types.User, organizationName string) error {
+	args := m.Called(ctx, u, organizationName)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) CreateOrganizationUser(ctx context.Context, orgID int, u types.User, role string, entry types.AuditEntry) error {
+	args := m.Called(ctx, orgID, u, role, entry)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) GetOrganizationUser(ctx context.Context, orgID, userID int) (*types.User, error) {
+	args := m.Called(ctx, orgID, userID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.User), args.Error(1)
+}
+
+func (m *mockUserStore) GetOrganizationUsers(ctx context.Context, orgID int, userIDs []int) ([]types.User, error) {
+	args := m.Called(ctx, orgID, userIDs)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.User), args.Error(1)
+}
+
+func (m *mockUserStore) ListMemberships(ctx context.Context, userID int) ([]types.Membership, error) {
+	args := m.Called(ctx, userID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.Membership), args.Error(1)
+}
+
+func (m *mockUserStore) SetUserRole(ctx context.Context, orgID, userID int, role string, entry types.AuditEntry) error {
+	args := m.Called(ctx, orgID, userID, role, entry)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) GetUserByID(ctx context.Context, id int) (*types.User, error) {
+	args := m.Called(ctx, id)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.User), args.Error(1)
+}
+
+func (m *mockUserStore) ListUsers(ctx context.Context, orgID int, query types.UserListQuery) ([]types.User, int, error) {
+	args := m.Called(ctx, orgID, query)
+	if args.Error(2) != nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]types.User), args.Int(1), args.Error(2)
+}
+
+func (m *mockUserStore) SetUserDisabled(ctx context.Context, orgID, userID int, disabled bool, entry types.AuditEntry) error {
+	args := m.Called(ctx, orgID, userID, disabled, entry)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) DeleteUser(ctx context.Context, orgID, userID int, entry types.AuditEntry) error {
+	args := m.Called(ctx, orgID, userID, entry)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) AnonymizeUser(ctx context.Context, orgID, userID int, entry types.AuditEntry) error {
+	args := m.Called(ctx, orgID, userID, entry)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) UpdatePassword(ctx context.Context, userID int, hashedPassword string) error {
+	args := m.Called(ctx, userID, hashedPassword)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) ChangePassword(ctx context.Context, userID int, hashedPassword string, keepSessionID string) error {
+	args := m.Called(ctx, userID, hashedPassword, keepSessionID)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) MarkEmailVerified(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) CreateUserToken(ctx context.Context, token types.UserToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) GetUserToken(ctx context.Context, purpose string, tokenHash string) (*types.UserToken, error) {
+	args := m.Called(ctx, purpose, tokenHash)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.UserToken), args.Error(1)
+}
+
+func (m *mockUserStore) ConsumeUserToken(ctx context.Context, purpose string, tokenHash string) (*types.UserToken, error) {
+	args := m.Called(ctx, purpose, tokenHash)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.UserToken), args.Error(1)
+}
+
+func (m *mockUserStore) SetTOTPSecret(ctx context.Context, userID int, secret string) error {
+	args := m.Called(ctx, userID, secret)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) EnableTOTP(ctx context.Context, userID int, step int64, recoveryCodeHashes []string) error {
+	args := m.Called(ctx, userID, step, recoveryCodeHashes)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) DisableTOTP(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	args := m.Called(ctx, userID, step)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) ConsumeRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	args := m.Called(ctx, userID, codeHash)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) CreateSession(ctx context.Context, session types.Session) error {
+	args := m.Called(ctx, session)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) TouchSession(ctx context.Context, userID int, sessionID string) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) ListSessions(ctx context.Context, userID int) ([]types.Session, error) {
+	args := m.Called(ctx, userID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.Session), args.Error(1)
+}
+
+func (m *mockUserStore) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) RevokeOtherSessions(ctx context.Context, userID int, keepSessionID string) error {
+	args := m.Called(ctx, userID, keepSessionID)
+	return args.Error(0)
+}
+
+type MockAuthenticator struct {
+	mock.Mock
+}
+
+func (m *MockAuthenticator) CreateJWT(secret []byte, userID, orgID int, sessionID string) (string, error) {
+	args := m.Called(secret, userID, orgID, sessionID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAuthenticator) CreateMFAChallenge(secret []byte, userID int) (string, error) {
+	args := m.Called(secret, userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAuthenticator) ValidateMFAChallenge(token string) (int, error) {
+	args := m.Called(token)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockAuthenticator) HashPassword(password string) (string, error) {
+	args := m.Called(password)
+	if args.Error(1) != nil {
+		return "", args.Error(1)
+	}
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAuthenticator) ComparePasswords(hashed string, plain []byte) bool {
+	args := m.Called(hashed, plain)
+	return args.Bool(0)
+}
+
+func (m *MockAuthenticator) NeedsRehash(hashed string) bool {
+	args := m.Called(hashed)
+	return args.Bool(0)
+}
+
+type mockCameraStore struct {
+	mock.Mock
+}
+
+func (m *mockCameraStore) CreateCameraMetadata(ctx context.Context, c types.CameraMetadata, entry types.AuditEntry) (*types.CameraMetadata, error) {
+	args := m.Called(ctx, c, entry)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*types.CameraMetadata), args.Error(1)
+}
+
+func (m *mockCameraStore) UpdateCameraMetadata(ctx context.Context, orgID int, camID string, change func(c *types.CameraMetadata) error, entry types.AuditEntry) (*types.CameraMetadata, error) {
+	args := m.Called(ctx, orgID, camID, entry)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+
+	// the returned camera is the locked row, which change modifies in place
+	camera := args.Get(0).(*types.CameraMetadata)
+	if err := change(camera); err != nil {
+		return nil, err
+	}
+
+	return camera, nil
+}
+
+func (m *mockCameraStore) ClaimImageID(ctx context.Context, imageID, camID string) (bool, error) {
+	args := m.Called(ctx, imageID, camID)
+	return args.Bool(0), args.Error(1)
+}
+func (m *mockCameraStore) GetCameraMetadataByID(ctx context.Context, orgID int, c string) (*types.CameraMetadata, error) {
+	args := m.Called(ctx, orgID, c)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*types.CameraMetadata), args.Error(1)
+}
+
+func (m *mockCameraStore) ListCameraMetadataByOwner(ctx context.Context, orgID, ownerID int) ([]types.CameraMetadata, error) {
+	args := m.Called(ctx, orgID, ownerID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).([]types.CameraMetadata), args.Error(1)
+}
+
+func (m *mockCameraStore) ReassignCameraMetadata(ctx context.Context, orgID, fromOwnerID, toOwnerID int, entry types.AuditEntry) error {
+	args := m.Called(ctx, orgID, fromOwnerID, toOwnerID, entry)
+	return args.Error(0)
+}
+
+func (m *mockCameraStore) DeleteCameraMetadata(ctx context.Context, orgID int, camID string, entry types.AuditEntry) error {
+	args := m.Called(ctx, orgID, camID, entry)
+	return args.Error(0)
+}
+
+func (m *mockCameraStore) GetUsage(ctx context.Context, orgID int) (*types.Usage, error) {
+	args := m.Called(ctx, orgID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.Usage), args.Error(1)
+}
+
+func (m *mockCameraStore) ListCameraMetadata(ctx context.Context, orgID int, filter types.CameraFilter) ([]types.CameraMetadata, int, error) {
+	args := m.Called(ctx, orgID, filter)
+	if args.Error(2) != nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]types.CameraMetadata), args.Int(1), args.Error(2)
+}
+
+func (m *mockCameraStore) BulkUpdateCameraMetadata(ctx context.Context, orgID int, filter types.CameraFilter, update types.CameraBulkUpdate, entry types.AuditEntry) (int64, error) {
+	args := m.Called(ctx, orgID, filter, update, entry)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type mockImageStore struct {
+	mock.Mock
+}
+
+func (m *mockImageStore) UploadImage(ctx context.Context, blobName string, imageData []byte) error {
+	args := m.Called(ctx, blobName, imageData)
+	return args.Error(0)
+}
+
+func (m *mockImageStore) DownloadImage(ctx context.Context, blobName string) ([]byte, error) {
+	args := m.Called(ctx, blobName)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func (m *mockImageStore) DeleteImage(ctx context.Context, blobName string) error {
+	args := m.Called(ctx, blobName)
+	return args.Error(0)
+}
+
+func (m *mockImageStore) ListImages(ctx context.Context) ([]storage.ImageInfo, error) {
+	args := m.Called(ctx)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]storage.ImageInfo), args.Error(1)
+}
+
+type mockAuditLogStore struct {
+	mock.Mock
+}
+
+func (m *mockAuditLogStore) ListAuditEntries(ctx context.Context, orgID int, filter types.AuditFilter) ([]types.AuditEntry, int, error) {
+	args := m.Called(ctx, orgID, filter)
+	if args.Error(2) != nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]types.AuditEntry), args.Int(1), args.Error(2)
+}
+
+func (m *mockAuditLogStore) ListResourceChanges(ctx context.Context, orgID int, resourceType string, resourceIDs []string, field string) ([]types.AuditEntry, error) {
+	args := m.Called(ctx, orgID, resourceType, resourceIDs, field)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.AuditEntry), args.Error(1)
+}
+
+func (m *mockAuditLogStore) ListActorEntries(ctx context.Context, orgID int, actorType, actorID string) ([]types.AuditEntry, error) {
+	args := m.Called(ctx, orgID, actorType, actorID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.AuditEntry), args.Error(1)
+}
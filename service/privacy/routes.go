@@ -0,0 +1,381 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"go-sample-rest-api/audit"
+	"go-sample-rest-api/customerrors"
+	"go-sample-rest-api/logging"
+	auth2 "go-sample-rest-api/service/auth"
+	"go-sample-rest-api/storage"
+	"go-sample-rest-api/types"
+	"go-sample-rest-api/utils"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Actions of the audit entries of an erasure.
+const (
+	ActionEraseUser       = "user.erase"
+	ActionDeleteCamera    = "camera.delete"
+	ActionReassignCameras = "camera.reassign"
+)
+
+// recentLogin is how long after logging in users can erase their account
+// without a password or code, which users of single sign-on do not have.
+const recentLogin = 5 * time.Minute
+
+// Handler serves the data export and erasure endpoints, which span users,
+// cameras, their stored images and the audit log. Admins reach the members of
+// their own organization only.
+type Handler struct {
+	users    types.UserStore
+	cameras  types.CameraMetadataStore
+	images   storage.ImageStore
+	auditLog types.AuditLogStore
+	auth     auth2.Authenticator
+}
+
+func NewHandler(users types.UserStore, cameras types.CameraMetadataStore, images storage.ImageStore, auditLog types.AuditLogStore, auth auth2.Authenticator) *Handler {
+	return &Handler{users: users, cameras: cameras, images: images, auditLog: auditLog, auth: auth}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/users/me/export", auth2.WithJWTAuth(h.handleExportCurrentUser, h.users)).Methods(http.MethodGet)
+	router.HandleFunc("/users/me/erase", auth2.WithJWTAuth(h.handleEraseCurrentUser, h.users)).Methods(http.MethodPost)
+
+	router.HandleFunc("/users/{userID}/export", auth2.WithAdmin(h.handleExportUser, h.users)).Methods(http.MethodGet)
+	router.HandleFunc("/users/{userID}/erase", auth2.WithAdmin(h.handleEraseUser, h.users)).Methods(http.MethodPost)
+}
+
+// handleExportCurrentUser godoc
+// @Summary Export my data
+// @Description Downloads everything stored about the authenticated user in their organization as a JSON file.
+// @Tags privacy
+// @Produce json
+// @Success 200 {object} types.DataExport "The data export."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /users/me/export [get]
+func (h *Handler) handleExportCurrentUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.export(ctx, w, auth2.GetOrgIDFromContext(ctx), auth2.GetUserIDFromContext(ctx), true)
+}
+
+// handleExportUser godoc
+// @Summary Export the data of a user
+// @Description Downloads everything stored about a member of the organization as a JSON file. Admins only.
+// @Tags privacy
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} types.DataExport "The data export."
+// @Failure 400 {object} types.HTTPError "Bad Request if the user ID is invalid."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 403 {object} types.HTTPError "Forbidden if the user is not an admin."
+// @Failure 404 {object} types.HTTPError "Not Found if the user is not a member of the organization."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /users/{id}/export [get]
+func (h *Handler) handleExportUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["userID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid user ID"))
+		return
+	}
+
+	h.export(r.Context(), w, auth2.GetOrgIDFromContext(r.Context()), userID, false)
+}
+
+// export writes the data of the user in the organization. Only users
+// exporting their own data (self) see their memberships in other
+// organizations.
+func (h *Handler) export(ctx context.Context, w http.ResponseWriter, orgID, userID int, self bool) {
+	u, err := h.users.GetOrganizationUser(ctx, orgID, userID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	cameras, err := h.cameras.ListCameraMetadataByOwner(ctx, orgID, userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	memberships, err := h.users.ListMemberships(ctx, userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !self {
+		memberships = slices.DeleteFunc(memberships, func(m types.Membership) bool { return m.OrgID != orgID })
+	}
+
+	sessions, err := h.users.ListSessions(ctx, userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	entries, err := h.auditLog.ListActorEntries(ctx, orgID, types.AuditActorUser, strconv.Itoa(userID))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	exportedAt := time.Now().UTC()
+	filename := fmt.Sprintf("user-%d-export-%s.json", userID, exportedAt.Format("20060102T150405Z"))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	exports := make([]types.CameraExport, len(cameras))
+	for i, c := range cameras {
+		exports[i] = types.NewCameraExport(c)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.DataExport{
+		ExportedAt:   exportedAt,
+		Profile:      types.NewUserResponse(*u),
+		Cameras:      exports,
+		Memberships:  memberships,
+		Sessions:     sessions,
+		AuditEntries: entries,
+	})
+}
+
+// handleEraseCurrentUser godoc
+// @Summary Erase my account
+// @Description Deletes the cameras and images of the authenticated user and anonymizes the account.
+// @Description The account cannot be used afterwards. Requires the current password, a TOTP code when two-factor
+// @Description authentication is enabled, or a session that started in the last 5 minutes.
+// @Tags privacy
+// @Accept json
+// @Produce json
+// @Param erase body types.EraseAccountPayload true "Current password or TOTP code"
+// @Success 204 {object} nil "Account erased."
+// @Failure 400 {object} types.HTTPError "Bad Request when the payload is invalid, the password or code is wrong, or the login is not recent."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 409 {object} types.HTTPError "Conflict if the user also belongs to other organizations."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /users/me/erase [post]
+func (h *Handler) handleEraseCurrentUser(w http.ResponseWriter, r *http.Request) {
+	var payload types.EraseAccountPayload
+	if !parseAndValidate(w, r, &payload) {
+		return
+	}
+
+	u, err := h.users.GetUserByID(r.Context(), auth2.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("unauthorized"))
+		return
+	}
+
+	if !h.confirmErasure(w, r, u, payload) {
+		return
+	}
+
+	orgID := auth2.GetOrgIDFromContext(r.Context())
+	if !h.checkNotShared(w, r, orgID, u.ID) {
+		return
+	}
+
+	if err := h.deleteCameras(r, orgID, u.ID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := h.users.AnonymizeUser(r.Context(), orgID, u.ID, audit.FromRequest(r, ActionEraseUser)); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// confirmErasure checks the password, or the TOTP code, of the payload. With
+// neither, the session of the request must have started within recentLogin,
+// so users of single sign-on confirm by logging in again.
+func (h *Handler) confirmErasure(w http.ResponseWriter, r *http.Request, u *types.User, payload types.EraseAccountPayload) bool {
+	switch {
+	case payload.Password != "":
+		if !h.auth.ComparePasswords(u.Password, []byte(payload.Password)) {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("password is incorrect"))
+			return false
+		}
+	case payload.Code != "":
+		step, ok := auth2.ValidateTOTP(u.TOTPSecret.String, payload.Code, time.Now())
+		if !u.TOTPEnabledAt.Valid || !ok || h.users.UseTOTPStep(r.Context(), u.ID, step) != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid code"))
+			return false
+		}
+	default:
+		sessions, err := h.users.ListSessions(r.Context(), u.ID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to list sessions: %v", err))
+			return false
+		}
+		sessionID := auth2.GetSessionIDFromContext(r.Context())
+		recent := slices.ContainsFunc(sessions, func(s types.Session) bool {
+			return s.ID == sessionID && time.Since(s.CreatedAt) < recentLogin
+		})
+		if !recent {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("password or code required, or log in again"))
+			return false
+		}
+	}
+
+	return true
+}
+
+// handleEraseUser godoc
+// @Summary Erase a user
+// @Description Anonymizes a member of the organization. Their cameras are handed over to reassignTo, another member,
+// @Description when it is set and deleted together with their images otherwise. Admins only.
+// @Tags privacy
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param erase body types.EraseUserPayload false "Where the cameras of the user go"
+// @Success 204 {object} nil "User erased."
+// @Failure 400 {object} types.HTTPError "Bad Request if the user ID or the new owner is invalid, or the user is the caller."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 403 {object} types.HTTPError "Forbidden if the user is not an admin."
+// @Failure 404 {object} types.HTTPError "Not Found if the user is not a member of the organization."
+// @Failure 409 {object} types.HTTPError "Conflict if the user also belongs to other organizations."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /users/{id}/erase [post]
+func (h *Handler) handleEraseUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["userID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid user ID"))
+		return
+	}
+
+	if userID == auth2.GetUserIDFromContext(r.Context()) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("admins cannot erase their own account here, use /users/me/erase"))
+		return
+	}
+
+	var payload types.EraseUserPayload
+	if r.ContentLength != 0 && !parseAndValidate(w, r, &payload) {
+		return
+	}
+
+	orgID := auth2.GetOrgIDFromContext(r.Context())
+	if _, err := h.users.GetOrganizationUser(r.Context(), orgID, userID); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	if !h.checkNotShared(w, r, orgID, userID) {
+		return
+	}
+
+	if payload.ReassignTo != nil {
+		if *payload.ReassignTo == userID {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("cannot reassign cameras to the erased user"))
+			return
+		}
+		if owner, err := h.users.GetOrganizationUser(r.Context(), orgID, *payload.ReassignTo); err != nil || owner.DisabledAt.Valid {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("new owner %d is not a member of the organization or is disabled", *payload.ReassignTo))
+			return
+		}
+		err = h.cameras.ReassignCameraMetadata(r.Context(), orgID, userID, *payload.ReassignTo, audit.FromRequest(r, ActionReassignCameras))
+	} else {
+		err = h.deleteCameras(r, orgID, userID)
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := h.users.AnonymizeUser(r.Context(), orgID, userID, audit.FromRequest(r, ActionEraseUser)); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkNotShared answers 409 if the user also belongs to other organizations.
+// Only the cameras in this organization would be removed, and the account is
+// not this organization's alone to erase.
+func (h *Handler) checkNotShared(w http.ResponseWriter, r *http.Request, orgID, userID int) bool {
+	memberships, err := h.users.ListMemberships(r.Context(), userID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return false
+	}
+
+	for _, m := range memberships {
+		if m.OrgID != orgID {
+			writeStoreError(w, &customerrors.SharedUserError{ID: strconv.Itoa(userID)})
+			return false
+		}
+	}
+	return true
+}
+
+// deleteCameras removes the stored images and then the metadata of every
+// camera of the user in the organization. It stops at the first failure, so a
+// retry picks up the cameras that are left.
+func (h *Handler) deleteCameras(r *http.Request, orgID, ownerID int) error {
+	cameras, err := h.cameras.ListCameraMetadataByOwner(r.Context(), orgID, ownerID)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range cameras {
+		if c.ImageId.Valid {
+			if err := h.images.DeleteImage(r.Context(), c.ImageId.String+".png"); err != nil {
+				return fmt.Errorf("failed to delete image of camera %s: %v", c.CamID, err)
+			}
+		}
+		if err := h.cameras.DeleteCameraMetadata(r.Context(), orgID, c.CamID, audit.FromRequest(r, ActionDeleteCamera)); err != nil {
+			return err
+		}
+	}
+
+	logging.GetLogger().WithFields(logrus.Fields{
+		"orgID":   orgID,
+		"userID":  ownerID,
+		"cameras": len(cameras),
+	}).Info("Cameras of user deleted")
+	return nil
+}
+
+// writeStoreError answers 404 for a missing user and 500 for anything else.
+func writeStoreError(w http.ResponseWriter, err error) {
+	var notFound *customerrors.NotFoundError
+	if errors.As(err, &notFound) {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	var shared *customerrors.SharedUserError
+	if errors.As(err, &shared) {
+		utils.WriteError(w, http.StatusConflict, err)
+		return
+	}
+
+	utils.WriteError(w, http.StatusInternalServerError, err)
+}
+
+func parseAndValidate(w http.ResponseWriter, r *http.Request, payload any) bool {
+	if err := utils.ParseJSON(r, payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return false
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", errors))
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,417 @@
+package privacy
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/customerrors"
+	"go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fixture struct {
+	users    *mockUserStore
+	cameras  *mockCameraStore
+	images   *mockImageStore
+	auditLog *mockAuditLogStore
+	auth     *MockAuthenticator
+	router   *mux.Router
+}
+
+// orgID is the organization of the caller injected by serve.
+const orgID = 3
+
+// newFixture registers the handlers without the auth middleware; the caller is
+// injected into the request context by serve.
+func newFixture() *fixture {
+	f := &fixture{
+		users:    new(mockUserStore),
+		cameras:  new(mockCameraStore),
+		images:   new(mockImageStore),
+		auditLog: new(mockAuditLogStore),
+		auth:     new(MockAuthenticator),
+	}
+	handler := NewHandler(f.users, f.cameras, f.images, f.auditLog, f.auth)
+
+	f.router = mux.NewRouter()
+	f.router.HandleFunc("/users/me/export", handler.handleExportCurrentUser).Methods(http.MethodGet)
+	f.router.HandleFunc("/users/me/erase", handler.handleEraseCurrentUser).Methods(http.MethodPost)
+	f.router.HandleFunc("/users/{userID}/export", handler.handleExportUser).Methods(http.MethodGet)
+	f.router.HandleFunc("/users/{userID}/erase", handler.handleEraseUser).Methods(http.MethodPost)
+	return f
+}
+
+func (f *fixture) serve(req *http.Request, userID int) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	ctx := context.WithValue(req.Context(), auth.UserKey, userID)
+	f.router.ServeHTTP(rr, req.WithContext(context.WithValue(ctx, auth.OrgKey, orgID)))
+	return rr
+}
+
+func testCameras() []types.CameraMetadata {
+	return []types.CameraMetadata{
+		{CamID: "cam-1", CameraName: "Front Door", OwnerID: sql.NullInt64{Int64: 7, Valid: true}},
+		{CamID: "cam-2", CameraName: "Garage", ImageId: sql.NullString{String: "img-2", Valid: true}, OwnerID: sql.NullInt64{Int64: 7, Valid: true}},
+	}
+}
+
+func testMemberships() []types.Membership {
+	return []types.Membership{
+		{OrgID: orgID, UserID: 7, Role: types.RoleUser},
+		{OrgID: 8, UserID: 7, Role: types.RoleAdmin},
+	}
+}
+
+func TestPrivacyService_Handle_Export(t *testing.T) {
+	t.Run("exports the profile and cameras of the current user as an attachment", func(t *testing.T) {
+		// arrange
+		f := newFixture()
+		f.users.On("GetOrganizationUser", mock.Anything, orgID, 7).Return(&types.User{ID: 7, Email: "jane@example.com", Password: "hash", Role: types.RoleUser}, nil)
+		f.cameras.On("ListCameraMetadataByOwner", mock.Anything, orgID, 7).Return(testCameras(), nil)
+		f.users.On("ListMemberships", mock.Anything, 7).Return(testMemberships(), nil)
+		f.users.On("ListSessions", mock.Anything, 7).Return([]types.Session{{ID: "session-1", UserID: 7, IP: "203.0.113.7"}}, nil)
+		f.auditLog.On("ListActorEntries", mock.Anything, orgID, types.AuditActorUser, "7").
+			Return([]types.AuditEntry{{ID: 40, OrgID: orgID, ActorType: types.AuditActorUser, ActorID: "7", Action: "camera.create"}}, nil)
+
+		req, _ := http.NewRequest(http.MethodGet, "/users/me/export", nil)
+
+		// act
+		rr := f.serve(req, 7)
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		if disposition := rr.Header().Get("Content-Disposition"); !strings.HasPrefix(disposition, `attachment; filename="user-7-export-`) {
+			t.Errorf("unexpected Content-Disposition: %q", disposition)
+		}
+		if strings.Contains(rr.Body.String(), "hash") {
+			t.Errorf("export must not contain the password hash: %s", rr.Body.String())
+		}
+		var export types.DataExport
+		json.Unmarshal(rr.Body.Bytes(), &export)
+		if export.Profile.Email != "jane@example.com" || len(export.Cameras) != 2 {
+			t.Errorf("unexpected export: %+v", export)
+		}
+		if export.Cameras[0].ImageID != nil || *export.Cameras[1].ImageID != "img-2" {
+			t.Errorf("expected image IDs or null, got %s", rr.Body.String())
+		}
+		if len(export.Memberships) != 2 || len(export.Sessions) != 1 || len(export.AuditEntries) != 1 {
+			t.Errorf("expected the memberships, sessions and audit entries, got %+v", export)
+		}
+	})
+
+	t.Run("admin export only holds the membership in the organization", func(t *testing.T) {
+		// arrange
+		f := newFixture()
+		f.users.On("GetOrganizationUser", mock.Anything, orgID, 7).Return(&types.User{ID: 7, Email: "jane@example.com"}, nil)
+		f.cameras.On("ListCameraMetadataByOwner", mock.Anything, orgID, 7).Return([]types.CameraMetadata{}, nil)
+		f.users.On("ListMemberships", mock.Anything, 7).Return(testMemberships(), nil)
+		f.users.On("ListSessions", mock.Anything, 7).Return([]types.Session{}, nil)
+		f.auditLog.On("ListActorEntries", mock.Anything, orgID, types.AuditActorUser, "7").Return([]types.AuditEntry{}, nil)
+
+		req, _ := http.NewRequest(http.MethodGet, "/users/7/export", nil)
+
+		// act
+		rr := f.serve(req, 1)
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		var export types.DataExport
+		json.Unmarshal(rr.Body.Bytes(), &export)
+		if len(export.Memberships) != 1 || export.Memberships[0].OrgID != orgID {
+			t.Errorf("expected only the membership in organization %d, got %+v", orgID, export.Memberships)
+		}
+	})
+
+	t.Run("admin export of a user outside the organization", func(t *testing.T) {
+		// arrange
+		f := newFixture()
+		f.users.On("GetOrganizationUser", mock.Anything, orgID, 9).Return(nil, &customerrors.NotFoundError{ID: "9"})
+
+		req, _ := http.NewRequest(http.MethodGet, "/users/9/export", nil)
+
+		// act
+		rr := f.serve(req, 1)
+
+		// assert
+		if status := rr.Code; status != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+		}
+	})
+}
+
+func TestPrivacyService_Handle_EraseCurrentUser(t *testing.T) {
+	t.Run("deletes cameras and images and anonymizes the user", func(t *testing.T) {
+		// arrange
+		f := newFixture()
+		f.users.On("GetUserByID", mock.Anything, 7).Return(&types.User{ID: 7, Password: "hash"}, nil)
+		f.auth.On("ComparePasswords", "hash", []byte("secret")).Return(true)
+		f.users.On("ListMemberships", mock.Anything, 7).Return(testMemberships()[:1], nil)
+		f.cameras.On("ListCameraMetadataByOwner", mock.Anything, orgID, 7).Return(testCameras(), nil)
+		f.images.On("DeleteImage", mock.Anything, "img-2.png").Return(nil)
+		f.cameras.On("DeleteCameraMetadata", mock.Anything, orgID, "cam-1", mock.Anything).Return(nil)
+		f.cameras.On("DeleteCameraMetadata", mock.Anything, orgID, "cam-2", mock.Anything).Return(nil)
+		f.users.On("AnonymizeUser", mock.Anything, orgID, 7, mock.Anything).Return(nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/users/me/erase", bytes.NewBufferString(`{"password":"secret"}`))
+
+		// act
+		rr := f.serve(req, 7)
+
+		// assert
+		if status := rr.Code; status != http.StatusNoContent {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusNoContent)
+		}
+		f.images.AssertExpectations(t)
+		f.cameras.AssertExpectations(t)
+		f.users.AssertExpectations(t)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		// arrange
+		f := newFixture()
+		f.users.On("GetUserByID", mock.Anything, 7).Return(&types.User{ID: 7, Password: "hash"}, nil)
+		f.auth.On("ComparePasswords", "hash", []byte("guess")).Return(false)
+
+		req, _ := http.NewRequest(http.MethodPost, "/users/me/erase", bytes.NewBufferString(`{"password":"guess"}`))
+
+		// act
+		rr := f.serve(req, 7)
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+		f.users.AssertNotCalled(t, "AnonymizeUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("accepts a TOTP code in place of the password", func(t *testing.T) {
+		// arrange
+		f := newFixture()
+		secret, _ := auth.NewTOTPSecret()
+		code, _ := auth.TOTPCode(secret, time.Now())
+		f.users.On("GetUserByID", mock.Anything, 7).Return(&types.User{ID: 7, Password: "hash",
+			TOTPSecret: sql.NullString{String: secret, Valid: true}, TOTPEnabledAt: sql.NullTime{Time: time.Now(), Valid: true}}, nil)
+		f.users.On("UseTOTPStep", mock.Anything, 7, mock.Anything).Return(nil)
+		f.users.On("ListMemberships", mock.Anything, 7).Return(testMemberships()[:1], nil)
+		f.cameras.On("ListCameraMetadataByOwner", mock.Anything, orgID, 7).Return([]types.CameraMetadata{}, nil)
+		f.users.On("AnonymizeUser", mock.Anything, orgID, 7, mock.Anything).Return(nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/users/me/erase", bytes.NewBufferString(`{"code":"`+code+`"}`))
+
+		// act
+		rr := f.serve(req, 7)
+
+		// assert
+		if status := rr.Code; status != http.StatusNoContent {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusNoContent)
+		}
+		f.users.AssertExpectations(t)
+		f.auth.AssertNotCalled(t, "ComparePasswords", mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects a code without two-factor authentication", func(t *testing.T) {
+		// arrange
+		f := newFixture()
+		f.users.On("GetUserByID", mock.Anything, 7).Return(&types.User{ID: 7, Password: "hash"}, nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/users/me/erase", bytes.NewBufferString(`{"code":"123456"}`))
+
+		// act
+		rr := f.serve(req, 7)
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+		f.users.AssertNotCalled(t, "AnonymizeUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("without password or code requires a recent login", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			createdAt time.Time
+			want      int
+		}{
+			{name: "recent", createdAt: time.Now().Add(-time.Minute), want: http.StatusNoContent},
+			{name: "old", createdAt: time.Now().Add(-time.Hour), want: http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				// arrange
+				f := newFixture()
+				f.users.On("GetUserByID", mock.Anything, 7).Return(&types.User{ID: 7, Password: "random"}, nil)
+				f.users.On("ListSessions", mock.Anything, 7).Return([]types.Session{
+					{ID: "other-session", CreatedAt: time.Now()},
+					{ID: "current-session", CreatedAt: tt.createdAt},
+				}, nil)
+				f.users.On("ListMemberships", mock.Anything, 7).Return(testMemberships()[:1], nil)
+				f.cameras.On("ListCameraMetadataByOwner", mock.Anything, orgID, 7).Return([]types.CameraMetadata{}, nil)
+				f.users.On("AnonymizeUser", mock.Anything, orgID, 7, mock.Anything).Return(nil)
+
+				req, _ := http.NewRequest(http.MethodPost, "/users/me/erase", bytes.NewBufferString(`{}`))
+				req = req.WithContext(context.WithValue(req.Context(), auth.SessionKey, "current-session"))
+
+				// act
+				rr := f.serve(req, 7)
+
+				// assert
+				if status := rr.Code; status != tt.want {
+					t.Errorf("handler returned wrong status code: got %v want %v", status, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("keeps the account when an image cannot be deleted", func(t *testing.T) {
+		// arrange
+		f := newFixture()
+		f.users.On("GetUserByID", mock.Anything, 7).Return(&types.User{ID: 7, Password: "hash"}, nil)
+		f.auth.On("ComparePasswords", "hash", []byte("secret")).Return(true)
+		f.users.On("ListMemberships", mock.Anything, 7).Return(testMemberships()[:1], nil)
+		f.cameras.On("ListCameraMetadataByOwner", mock.Anything, orgID, 7).Return(testCameras(), nil)
+		f.cameras.On("DeleteCameraMetadata", mock.Anything, orgID, "cam-1", mock.Anything).Return(nil)
+		f.images.On("DeleteImage", mock.Anything, "img-2.png").Return(fmt.Errorf("storage unavailable"))
+
+		req, _ := http.NewRequest(http.MethodPost, "/users/me/erase", bytes.NewBufferString(`{"password":"secret"}`))
+
+		// act
+		rr := f.serve(req, 7)
+
+		// assert
+		if status := rr.Code; status != http.StatusInternalServerError {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusInternalServerError)
+		}
+		f.cameras.AssertNotCalled(t, "DeleteCameraMetadata", mock.Anything, orgID, "cam-2", mock.Anything)
+		f.users.AssertNotCalled(t, "AnonymizeUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestPrivacyService_Handle_EraseUser(t *testing.T) {
+	t.Run("reassigns the cameras to another user", func(t *testing.T) {
+		// arrange
+		f := newFixture()
+		f.users.On("GetOrganizationUser", mock.Anything, orgID, 7).Return(&types.User{ID: 7}, nil)
+		f.users.On("ListMemberships", mock.Anything, 7).Return(testMemberships()[:1], nil)
+		f.users.On("GetOrganizationUser", mock.Anything, orgID, 2).Return(&types.User{ID: 2}, nil)
+		f.cameras.On("ReassignCameraMetadata", mock.Anything, orgID, 7, 2, mock.Anything).Return(nil)
+		f.users.On("AnonymizeUser", mock.Anything, orgID, 7, mock.Anything).Return(nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/users/7/erase", bytes.NewBufferString(`{"reassignTo":2}`))
+
+		// act
+		rr := f.serve(req, 1)
+
+		// assert
+		if status := rr.Code; status != http.StatusNoContent {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusNoContent)
+		}
+		f.cameras.AssertExpectations(t)
+		f.users.AssertExpectations(t)
+	})
+
+	t.Run("deletes the cameras without a body", func(t *testing.T) {
+		// arrange
+		f := newFixture()
+		f.users.On("GetOrganizationUser", mock.Anything, orgID, 7).Return(&types.User{ID: 7}, nil)
+		f.users.On("ListMemberships", mock.Anything, 7).Return(testMemberships()[:1], nil)
+		f.cameras.On("ListCameraMetadataByOwner", mock.Anything, orgID, 7).Return([]types.CameraMetadata{testCameras()[0]}, nil)
+		f.cameras.On("DeleteCameraMetadata", mock.Anything, orgID, "cam-1", mock.Anything).Return(nil)
+		f.users.On("AnonymizeUser", mock.Anything, orgID, 7, mock.Anything).Return(nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/users/7/erase", nil)
+
+		// act
+		rr := f.serve(req, 1)
+
+		// assert
+		if status := rr.Code; status != http.StatusNoContent {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNoContent)
+		}
+		f.users.AssertExpectations(t)
+	})
+
+	t.Run("rejects a disabled new owner", func(t *testing.T) {
+		// arrange
+		f := newFixture()
+		f.users.On("GetOrganizationUser", mock.Anything, orgID, 7).Return(&types.User{ID: 7}, nil)
+		f.users.On("ListMemberships", mock.Anything, 7).Return(testMemberships()[:1], nil)
+		f.users.On("GetOrganizationUser", mock.Anything, orgID, 2).Return(&types.User{ID: 2, DisabledAt: sql.NullTime{Time: time.Now(), Valid: true}}, nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/users/7/erase", bytes.NewBufferString(`{"reassignTo":2}`))
+
+		// act
+		rr := f.serve(req, 1)
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+		f.users.AssertNotCalled(t, "AnonymizeUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects a new owner outside the organization", func(t *testing.T) {
+		// arrange
+		f := newFixture()
+		f.users.On("GetOrganizationUser", mock.Anything, orgID, 7).Return(&types.User{ID: 7}, nil)
+		f.users.On("ListMemberships", mock.Anything, 7).Return(testMemberships()[:1], nil)
+		f.users.On("GetOrganizationUser", mock.Anything, orgID, 8).Return(nil, &customerrors.NotFoundError{ID: "8"})
+
+		req, _ := http.NewRequest(http.MethodPost, "/users/7/erase", bytes.NewBufferString(`{"reassignTo":8}`))
+
+		// act
+		rr := f.serve(req, 1)
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+		f.cameras.AssertNotCalled(t, "ReassignCameraMetadata", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("refuses a user of other organizations", func(t *testing.T) {
+		// arrange
+		f := newFixture()
+		f.users.On("GetOrganizationUser", mock.Anything, orgID, 7).Return(&types.User{ID: 7}, nil)
+		f.users.On("ListMemberships", mock.Anything, 7).Return(testMemberships(), nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/users/7/erase", nil)
+
+		// act
+		rr := f.serve(req, 1)
+
+		// assert
+		if status := rr.Code; status != http.StatusConflict {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusConflict)
+		}
+		f.cameras.AssertNotCalled(t, "ListCameraMetadataByOwner", mock.Anything, mock.Anything, mock.Anything)
+		f.users.AssertNotCalled(t, "AnonymizeUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("refuses the caller's own account", func(t *testing.T) {
+		// arrange
+		f := newFixture()
+
+		req, _ := http.NewRequest(http.MethodPost, "/users/1/erase", nil)
+
+		// act
+		rr := f.serve(req, 1)
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+	})
+}
@@ -0,0 +1,281 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"go-sample-rest-api/audit"
+	"go-sample-rest-api/config"
+	"go-sample-rest-api/customerrors"
+	auth2 "go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"go-sample-rest-api/utils"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Actions of the audit entries of user administration.
+const (
+	ActionInviteUser  = "user.invite"
+	ActionSetUserRole = "user.set_role"
+	ActionDisableUser = "user.disable"
+	ActionEnableUser  = "user.enable"
+	ActionDeleteUser  = "user.delete"
+)
+
+// handleListUsers godoc
+// @Summary List users
+// @Description Lists the members of the organization ordered by ID. Admins only.
+// @Tags users
+// @Produce json
+// @Param search query string false "Matches first name, last name or email"
+// @Param page query int false "Page number, starting at 1"
+// @Param pageSize query int false "Users per page (default 20, max 100)"
+// @Success 200 {object} types.UserList "One page of users."
+// @Failure 400 {object} types.HTTPError "Bad Request if the pagination parameters are invalid."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 403 {object} types.HTTPError "Forbidden if the user is not an admin."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /users [get]
+func (h *Handler) handleListUsers(w http.ResponseWriter, r *http.Request) {
+	page, pageSize, err := utils.GetPagination(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	users, total, err := h.store.ListUsers(r.Context(), auth2.GetOrgIDFromContext(r.Context()), types.UserListQuery{
+		Search: strings.TrimSpace(r.URL.Query().Get("search")),
+		Limit:  pageSize,
+		Offset: (page - 1) * pageSize,
+	})
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	responses := make([]types.UserResponse, len(users))
+	for i, u := range users {
+		responses[i] = types.NewUserResponse(u)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.UserList{
+		Users:    responses,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	})
+}
+
+// handleDisableUser godoc
+// @Summary Disable a user
+// @Description Blocks login and rejects the access tokens of the user. Admins only.
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} nil "User disabled."
+// @Failure 400 {object} types.HTTPError "Bad Request if the user ID is invalid or is the caller's own."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 403 {object} types.HTTPError "Forbidden if the user is not an admin."
+// @Failure 404 {object} types.HTTPError "Not Found if the user is not a member of the organization."
+// @Failure 409 {object} types.HTTPError "Conflict if the user also belongs to other organizations."
+// @Router /users/{id}/disable [post]
+func (h *Handler) handleDisableUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserDisabled(w, r, true)
+}
+
+// handleEnableUser godoc
+// @Summary Enable a user
+// @Description Lifts a previous disable. Admins only.
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} nil "User enabled."
+// @Failure 400 {object} types.HTTPError "Bad Request if the user ID is invalid or is the caller's own."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 403 {object} types.HTTPError "Forbidden if the user is not an admin."
+// @Failure 404 {object} types.HTTPError "Not Found if the user is not a member of the organization."
+// @Failure 409 {object} types.HTTPError "Conflict if the user also belongs to other organizations."
+// @Router /users/{id}/enable [post]
+func (h *Handler) handleEnableUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserDisabled(w, r, false)
+}
+
+func (h *Handler) setUserDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	userID, ok := otherUserID(w, r)
+	if !ok {
+		return
+	}
+
+	action := ActionEnableUser
+	if disabled {
+		action = ActionDisableUser
+	}
+
+	if err := h.store.SetUserDisabled(r.Context(), auth2.GetOrgIDFromContext(r.Context()), userID, disabled, audit.FromRequest(r, action)); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+// handleDeleteUser godoc
+// @Summary Delete a user
+// @Description Deletes the user account. Admins only.
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 204 {object} nil "User deleted."
+// @Failure 400 {object} types.HTTPError "Bad Request if the user ID is invalid or is the caller's own."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 403 {object} types.HTTPError "Forbidden if the user is not an admin."
+// @Failure 404 {object} types.HTTPError "Not Found if the user is not a member of the organization."
+// @Failure 409 {object} types.HTTPError "Conflict if the user also belongs to other organizations."
+// @Router /users/{id} [delete]
+func (h *Handler) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := otherUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.DeleteUser(r.Context(), auth2.GetOrgIDFromContext(r.Context()), userID, audit.FromRequest(r, ActionDeleteUser)); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleCreateUser godoc
+// @Summary Add a user to the organization
+// @Description Creates a user in the organization of the admin and emails them an invitation link to choose a password. Admins only.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param user body types.CreateUserPayload true "New user; role defaults to user"
+// @Success 201 {object} nil "User created and invited."
+// @Failure 400 {object} types.HTTPError "Bad Request when the payload is invalid."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 403 {object} types.HTTPError "Forbidden if the user is not an admin."
+// @Failure 409 {object} types.HTTPError "Conflict if the email is already registered."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /users [post]
+func (h *Handler) handleCreateUser(w http.ResponseWriter, r *http.Request) {
+	var payload types.CreateUserPayload
+	if !parseAndValidate(w, r, &payload) {
+		return
+	}
+
+	if payload.Role == "" {
+		payload.Role = types.RoleUser
+	}
+
+	// an existing account cannot be pulled into another organization, its
+	// owner would become subject to that organization's admins
+	if _, err := h.store.GetUserByEmail(r.Context(), payload.Email); err == nil {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("user with email %s already exists", payload.Email))
+		return
+	}
+
+	// the account stays unusable until the invitation link sets a password
+	token, _, err := auth2.NewOpaqueToken()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	hashedPassword, err := h.auth.HashPassword(token)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	err = h.store.CreateOrganizationUser(r.Context(), auth2.GetOrgIDFromContext(r.Context()), types.User{
+		FirstName: payload.FirstName,
+		LastName:  payload.LastName,
+		Email:     payload.Email,
+		Password:  hashedPassword,
+	}, payload.Role, audit.FromRequest(r, ActionInviteUser))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if u, err := h.store.GetUserByEmail(r.Context(), payload.Email); err == nil {
+		h.sendTokenEmail(r.Context(), u, types.TokenPurposePasswordReset, config.Envs.EmailVerificationTTL,
+			"You have been invited", "reset-password",
+			"An account was created for you. Open the link below to choose your password:")
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, nil)
+}
+
+// handleSetUserRole godoc
+// @Summary Change the role of a user
+// @Description Makes a member of the organization an admin or a regular user. Admins only.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param role body types.UpdateRolePayload true "New role"
+// @Success 200 {object} nil "Role changed."
+// @Failure 400 {object} types.HTTPError "Bad Request if the payload or user ID is invalid or is the caller's own."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 403 {object} types.HTTPError "Forbidden if the user is not an admin."
+// @Failure 404 {object} types.HTTPError "Not Found if the user is not a member of the organization."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /users/{id}/role [put]
+func (h *Handler) handleSetUserRole(w http.ResponseWriter, r *http.Request) {
+	userID, ok := otherUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var payload types.UpdateRolePayload
+	if !parseAndValidate(w, r, &payload) {
+		return
+	}
+
+	if err := h.store.SetUserRole(r.Context(), auth2.GetOrgIDFromContext(r.Context()), userID, payload.Role, audit.FromRequest(r, ActionSetUserRole)); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+// writeStoreError answers 404 for a missing user and 500 for anything else.
+func writeStoreError(w http.ResponseWriter, err error) {
+	var notFound *customerrors.NotFoundError
+	if errors.As(err, &notFound) {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	var shared *customerrors.SharedUserError
+	if errors.As(err, &shared) {
+		utils.WriteError(w, http.StatusConflict, err)
+		return
+	}
+
+	utils.WriteError(w, http.StatusInternalServerError, err)
+}
+
+// otherUserID reads the userID path parameter and refuses the caller's own ID,
+// so an admin cannot lock themselves out by accident, and an organization
+// always keeps the admin making the change.
+func otherUserID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userID, err := strconv.Atoi(mux.Vars(r)["userID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid user ID"))
+		return 0, false
+	}
+
+	if userID == auth2.GetUserIDFromContext(r.Context()) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("admins cannot disable, delete or demote their own account"))
+		return 0, false
+	}
+
+	return userID, true
+}
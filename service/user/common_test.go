@@ -1,7 +1,9 @@
 package user
 
 import (
+	"context"
 	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/mailer"
 	"go-sample-rest-api/types"
 )
 
@@ -9,13 +11,13 @@ type mockUserStore struct {
 	mock.Mock
 }
 
-func (m *mockUserStore) UpdateUser(u types.User) error {
-	args := m.Called(u)
+func (m *mockUserStore) UpdateUser(ctx context.Context, u types.User) error {
+	args := m.Called(ctx, u)
 	return args.Error(0)
 }
 
-func (m *mockUserStore) GetUserByEmail(email string) (*types.User, error) {
-	args := m.Called(email)
+func (m *mockUserStore) GetUserByEmail(ctx context.Context, email string) (*types.User, error) {
+	args := m.Called(ctx, email)
 	if args.Error(1) != nil {
 		return nil, args.Error(1)
 	}
@@ -23,28 +25,184 @@ func (m *mockUserStore) GetUserByEmail(email string) (*types.User, error) {
 	return args.Get(0).(*types.User), args.Error(1)
 }
 
-func (m *mockUserStore) CreateUser(u types.User) error {
-	args := m.Called(u)
+func (m *mockUserStore) CreateUser(ctx context.Context, u types.User, organizationName string) error {
+	args := m.Called(ctx, u, organizationName)
 	return args.Error(0)
 }
 
-func (m *mockUserStore) GetUserByID(id int) (*types.User, error) {
-	args := m.Called(id)
+func (m *mockUserStore) CreateOrganizationUser(ctx context.Context, orgID int, u types.User, role string, entry types.AuditEntry) error {
+	args := m.Called(ctx, orgID, u, role, entry)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) GetOrganizationUser(ctx context.Context, orgID, userID int) (*types.User, error) {
+	args := m.Called(ctx, orgID, userID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.User), args.Error(1)
+}
+
+func (m *mockUserStore) GetOrganizationUsers(ctx context.Context, orgID int, userIDs []int) ([]types.User, error) {
+	args := m.Called(ctx, orgID, userIDs)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.User), args.Error(1)
+}
+
+func (m *mockUserStore) ListMemberships(ctx context.Context, userID int) ([]types.Membership, error) {
+	args := m.Called(ctx, userID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.Membership), args.Error(1)
+}
+
+func (m *mockUserStore) SetUserRole(ctx context.Context, orgID, userID int, role string, entry types.AuditEntry) error {
+	args := m.Called(ctx, orgID, userID, role, entry)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) GetUserByID(ctx context.Context, id int) (*types.User, error) {
+	args := m.Called(ctx, id)
 	if args.Error(1) != nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*types.User), args.Error(1)
 }
 
+func (m *mockUserStore) ListUsers(ctx context.Context, orgID int, query types.UserListQuery) ([]types.User, int, error) {
+	args := m.Called(ctx, orgID, query)
+	if args.Error(2) != nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]types.User), args.Int(1), args.Error(2)
+}
+
+func (m *mockUserStore) SetUserDisabled(ctx context.Context, orgID, userID int, disabled bool, entry types.AuditEntry) error {
+	args := m.Called(ctx, orgID, userID, disabled, entry)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) DeleteUser(ctx context.Context, orgID, userID int, entry types.AuditEntry) error {
+	args := m.Called(ctx, orgID, userID, entry)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) AnonymizeUser(ctx context.Context, orgID, userID int, entry types.AuditEntry) error {
+	args := m.Called(ctx, orgID, userID, entry)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) UpdatePassword(ctx context.Context, userID int, hashedPassword string) error {
+	args := m.Called(ctx, userID, hashedPassword)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) ChangePassword(ctx context.Context, userID int, hashedPassword string, keepSessionID string) error {
+	args := m.Called(ctx, userID, hashedPassword, keepSessionID)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) MarkEmailVerified(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) CreateUserToken(ctx context.Context, token types.UserToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) GetUserToken(ctx context.Context, purpose string, tokenHash string) (*types.UserToken, error) {
+	args := m.Called(ctx, purpose, tokenHash)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.UserToken), args.Error(1)
+}
+
+func (m *mockUserStore) ConsumeUserToken(ctx context.Context, purpose string, tokenHash string) (*types.UserToken, error) {
+	args := m.Called(ctx, purpose, tokenHash)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.UserToken), args.Error(1)
+}
+
+func (m *mockUserStore) SetTOTPSecret(ctx context.Context, userID int, secret string) error {
+	args := m.Called(ctx, userID, secret)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) EnableTOTP(ctx context.Context, userID int, step int64, recoveryCodeHashes []string) error {
+	args := m.Called(ctx, userID, step, recoveryCodeHashes)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) DisableTOTP(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	args := m.Called(ctx, userID, step)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) ConsumeRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	args := m.Called(ctx, userID, codeHash)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) CreateSession(ctx context.Context, session types.Session) error {
+	args := m.Called(ctx, session)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) TouchSession(ctx context.Context, userID int, sessionID string) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) ListSessions(ctx context.Context, userID int) ([]types.Session, error) {
+	args := m.Called(ctx, userID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.Session), args.Error(1)
+}
+
+func (m *mockUserStore) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *mockUserStore) RevokeOtherSessions(ctx context.Context, userID int, keepSessionID string) error {
+	args := m.Called(ctx, userID, keepSessionID)
+	return args.Error(0)
+}
+
 type MockAuthenticator struct {
 	mock.Mock
 }
 
-func (m *MockAuthenticator) CreateJWT(secret []byte, userID int) (string, error) {
+func (m *MockAuthenticator) CreateJWT(secret []byte, userID, orgID int, sessionID string) (string, error) {
+	args := m.Called(secret, userID, orgID, sessionID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAuthenticator) CreateMFAChallenge(secret []byte, userID int) (string, error) {
 	args := m.Called(secret, userID)
 	return args.String(0), args.Error(1)
 }
 
+func (m *MockAuthenticator) ValidateMFAChallenge(token string) (int, error) {
+	args := m.Called(token)
+	return args.Int(0), args.Error(1)
+}
+
 func (m *MockAuthenticator) HashPassword(password string) (string, error) {
 	args := m.Called(password)
 	if args.Error(1) != nil {
@@ -57,3 +215,17 @@ func (m *MockAuthenticator) ComparePasswords(hashed string, plain []byte) bool {
 	args := m.Called(hashed, plain)
 	return args.Bool(0)
 }
+
+func (m *MockAuthenticator) NeedsRehash(hashed string) bool {
+	args := m.Called(hashed)
+	return args.Bool(0)
+}
+
+type mockMailer struct {
+	mock.Mock
+}
+
+func (m *mockMailer) Send(ctx context.Context, msg mailer.Message) error {
+	args := m.Called(msg)
+	return args.Error(0)
+}
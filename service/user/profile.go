@@ -0,0 +1,144 @@
+package user
+
+import (
+	"fmt"
+	"go-sample-rest-api/config"
+	auth2 "go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"go-sample-rest-api/utils"
+	"net/http"
+	"strings"
+)
+
+// handleGetCurrentUser godoc
+// @Summary Get the current user
+// @Description Returns the profile of the authenticated user.
+// @Tags users
+// @Produce json
+// @Success 200 {object} types.UserResponse "Profile of the current user."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Router /users/me [get]
+func (h *Handler) handleGetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.NewUserResponse(*u))
+}
+
+// handleUpdateCurrentUser godoc
+// @Summary Update the current user
+// @Description Changes the name or email of the authenticated user. A new email has to be verified again before the next login.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param user body types.UpdateUserPayload true "Fields to change"
+// @Success 200 {object} types.UserResponse "Updated profile."
+// @Failure 400 {object} types.HTTPError "Bad Request when the payload is invalid."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 409 {object} types.HTTPError "Conflict if the email belongs to another user."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /users/me [patch]
+func (h *Handler) handleUpdateCurrentUser(w http.ResponseWriter, r *http.Request) {
+	var payload types.UpdateUserPayload
+	if !parseAndValidate(w, r, &payload) {
+		return
+	}
+
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if payload.FirstName != nil {
+		u.FirstName = *payload.FirstName
+	}
+	if payload.LastName != nil {
+		u.LastName = *payload.LastName
+	}
+
+	emailChanged := payload.Email != nil && !strings.EqualFold(*payload.Email, u.Email)
+	if emailChanged {
+		if _, err := h.store.GetUserByEmail(r.Context(), *payload.Email); err == nil {
+			utils.WriteError(w, http.StatusConflict, fmt.Errorf("user with email %s already exists", *payload.Email))
+			return
+		}
+		u.Email = *payload.Email
+	}
+
+	if err := h.store.UpdateUser(r.Context(), *u); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	updated, err := h.store.GetUserByID(r.Context(), u.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if emailChanged {
+		h.sendVerificationEmail(r.Context(), updated)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.NewUserResponse(*updated))
+}
+
+// handleChangePassword godoc
+// @Summary Change password
+// @Description Sets a new password for the authenticated user after checking the current one.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param password body types.ChangePasswordPayload true "Current and new password"
+// @Success 200 {object} nil "Password changed; the sessions on other devices are revoked."
+// @Failure 400 {object} types.HTTPError "Bad Request when the payload is invalid, the current password is wrong or the new password breaks the password policy."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 429 {object} types.HTTPError "Too many failed attempts; see Retry-After."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /users/me/password [post]
+func (h *Handler) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	var payload types.ChangePasswordPayload
+	if !parseAndValidate(w, r, &payload) {
+		return
+	}
+
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// a stolen access token must not allow guessing the password at full speed
+	account := strings.ToLower(u.Email)
+	ip := utils.ClientIP(r, config.Envs.TrustProxyHeaders)
+	if wait := h.throttle.RetryAfter(account, ip); wait > 0 {
+		writeTooManyAttempts(w, wait)
+		return
+	}
+
+	if !h.auth.ComparePasswords(u.Password, []byte(payload.CurrentPassword)) {
+		h.throttle.Failure(account, ip)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("current password is incorrect"))
+		return
+	}
+	h.throttle.Success(account)
+
+	if !checkPasswordPolicy(w, payload.NewPassword, u.Email, u.FirstName, u.LastName) {
+		return
+	}
+
+	hashedPassword, err := h.auth.HashPassword(payload.NewPassword)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// the device that changed the password stays logged in
+	if err := h.store.ChangePassword(r.Context(), u.ID, hashedPassword, auth2.GetSessionIDFromContext(r.Context())); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
@@ -1,45 +1,86 @@
 package user
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"github.com/sirupsen/logrus"
 	"go-sample-rest-api/config"
+	"go-sample-rest-api/customerrors"
+	"go-sample-rest-api/logging"
+	"go-sample-rest-api/mailer"
 	auth2 "go-sample-rest-api/service/auth"
 	"go-sample-rest-api/types"
 	"go-sample-rest-api/utils"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
 )
 
+// dummyPasswordHash is compared against when the email is unknown, so failed
+// logins take the same time whether or not the account exists. It uses the
+// default Argon2id parameters; keep it in sync when they change.
+const dummyPasswordHash = "$argon2id$v=19$m=19456,t=2,p=1$tApVFvyHb/TIukY47Ik6LQ$yPZqpz/Gvn+5melfzT28ixA3Ukwbd0b/SZGFk/b62oQ"
+
+var errInvalidCredentials = fmt.Errorf("invalid email or password")
+
 type Handler struct {
-	store types.UserStore
-	auth  auth2.Authenticator
+	store    types.UserStore
+	auth     auth2.Authenticator
+	mailer   mailer.Mailer
+	throttle *loginThrottle
 }
 
-func NewHandler(store types.UserStore, auth auth2.Authenticator) *Handler {
-	return &Handler{store: store, auth: auth}
+func NewHandler(store types.UserStore, auth auth2.Authenticator, mailer mailer.Mailer) *Handler {
+	return &Handler{store: store, auth: auth, mailer: mailer, throttle: newLoginThrottle(DefaultThrottlePolicies())}
 }
 
 func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/login", h.handleLogin).Methods("POST")
+	router.HandleFunc("/login/2fa", h.handleLoginChallenge).Methods(http.MethodPost)
 	router.HandleFunc("/register", h.handleRegister).Methods("POST")
+	router.HandleFunc("/verify_email", h.handleVerifyEmail).Methods(http.MethodPost)
+	router.HandleFunc("/verify_email/resend", h.handleResendVerification).Methods(http.MethodPost)
+	router.HandleFunc("/password_reset", h.handleRequestPasswordReset).Methods(http.MethodPost)
+	router.HandleFunc("/password_reset/confirm", h.handleConfirmPasswordReset).Methods(http.MethodPost)
+
+	router.HandleFunc("/users/me", auth2.WithJWTAuth(h.handleGetCurrentUser, h.store)).Methods(http.MethodGet)
+	router.HandleFunc("/users/me", auth2.WithJWTAuth(h.handleUpdateCurrentUser, h.store)).Methods(http.MethodPatch)
+	router.HandleFunc("/users/me/password", auth2.WithJWTAuth(h.handleChangePassword, h.store)).Methods(http.MethodPost)
+	router.HandleFunc("/users/me/2fa", auth2.WithJWTAuth(h.handleEnrollTOTP, h.store)).Methods(http.MethodPost)
+	router.HandleFunc("/users/me/2fa/verify", auth2.WithJWTAuth(h.handleVerifyTOTP, h.store)).Methods(http.MethodPost)
+	router.HandleFunc("/users/me/2fa/disable", auth2.WithJWTAuth(h.handleDisableTOTP, h.store)).Methods(http.MethodPost)
+	router.HandleFunc("/users/me/sessions", auth2.WithJWTAuth(h.handleListSessions, h.store)).Methods(http.MethodGet)
+	router.HandleFunc("/users/me/sessions", auth2.WithJWTAuth(h.handleRevokeOtherSessions, h.store)).Methods(http.MethodDelete)
+	router.HandleFunc("/users/me/sessions/{sessionID}", auth2.WithJWTAuth(h.handleRevokeSession, h.store)).Methods(http.MethodDelete)
 
-	// admin routes
-	router.HandleFunc("/users/{userID}", auth2.WithJWTAuth(h.handleGetUser, h.store)).Methods(http.MethodGet)
+	// admin routes, registered after /users/me so "me" is not taken for a user ID
+	router.HandleFunc("/users", auth2.WithAdmin(h.handleListUsers, h.store)).Methods(http.MethodGet)
+	router.HandleFunc("/users", auth2.WithAdmin(h.handleCreateUser, h.store)).Methods(http.MethodPost)
+	router.HandleFunc("/users/{userID}", auth2.WithAdmin(h.handleGetUser, h.store)).Methods(http.MethodGet)
+	router.HandleFunc("/users/{userID}", auth2.WithAdmin(h.handleDeleteUser, h.store)).Methods(http.MethodDelete)
+	router.HandleFunc("/users/{userID}/disable", auth2.WithAdmin(h.handleDisableUser, h.store)).Methods(http.MethodPost)
+	router.HandleFunc("/users/{userID}/enable", auth2.WithAdmin(h.handleEnableUser, h.store)).Methods(http.MethodPost)
+	router.HandleFunc("/users/{userID}/role", auth2.WithAdmin(h.handleSetUserRole, h.store)).Methods(http.MethodPut)
 }
 
 // handleLogin godoc
 // @Summary User login
-// @Description Login with email and password.
+// @Description Login with email and password. When two-factor authentication is enabled, a short-lived
+// @Description challengeToken is returned instead of the token; exchange it at /login/2fa.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param user body types.LoginUserPayload true "Login Credentials"
-// @Success 200 {object} map[string]string "token: JWT Token on successful login."
-// @Failure 400 {object} types.HTTPError "Bad Request when the payload is invalid."
-// @Failure 404 {object} types.HTTPError "Not Found, invalid email or password."
+// @Success 200 {object} map[string]string "token: JWT Token on successful login, or challengeToken if a second factor is required."
+// @Failure 400 {object} types.HTTPError "Bad Request when the payload is invalid or the email or password is wrong."
+// @Failure 403 {object} types.HTTPError "Forbidden if the account is disabled or the email address is not verified yet."
+// @Failure 429 {object} types.HTTPError "Too many failed attempts for the account or client; see Retry-After."
 // @Failure 500 {object} types.HTTPError "Internal Server Error"
 // @Router /login [post]
 func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
@@ -55,36 +96,106 @@ func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	u, err := h.store.GetUserByEmail(user.Email)
-	if err != nil || u == nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("not found, invalid email or password"))
+	account := strings.ToLower(user.Email)
+	ip := utils.ClientIP(r, config.Envs.TrustProxyHeaders)
+	if wait := h.throttle.RetryAfter(account, ip); wait > 0 {
+		writeTooManyAttempts(w, wait)
+		return
+	}
+
+	// Compare against a dummy hash for unknown emails, so the response and its
+	// timing are the same whether or not the account exists.
+	u, err := h.store.GetUserByEmail(r.Context(), user.Email)
+	hashed := dummyPasswordHash
+	if err == nil && u != nil {
+		hashed = u.Password
+	}
+	if !h.auth.ComparePasswords(hashed, []byte(user.Password)) || err != nil || u == nil {
+		h.throttle.Failure(account, ip)
+		utils.WriteError(w, http.StatusBadRequest, errInvalidCredentials)
 		return
 	}
 
-	if !h.auth.ComparePasswords(u.Password, []byte(user.Password)) {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid email or password"))
+	if u.DisabledAt.Valid {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("account disabled"))
 		return
 	}
 
+	if !u.EmailVerifiedAt.Valid {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("email address not verified"))
+		return
+	}
+
+	h.upgradePasswordHash(r.Context(), u, user.Password)
+
 	secret := []byte(config.Envs.JWTSecret)
-	token, err := h.auth.CreateJWT(secret, u.ID)
+	if u.TOTPEnabledAt.Valid {
+		// the failure counter is only reset once the second factor passed too,
+		// so a known password does not buy fresh attempts at guessing codes
+		challenge, err := h.auth.CreateMFAChallenge(secret, u.ID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, map[string]string{"challengeToken": challenge})
+		return
+	}
+	h.throttle.Success(account)
+
+	token, err := auth2.IssueAccessToken(h.auth, h.store, r, secret, u.ID)
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
+		writeLoginError(w, err)
 		return
 	}
 
 	utils.WriteJSON(w, http.StatusOK, map[string]string{"token": token})
 }
 
+// writeLoginError answers 403 for a user without an organization, who has
+// nothing to log in to, and 500 for anything else.
+func writeLoginError(w http.ResponseWriter, err error) {
+	if errors.Is(err, auth2.ErrNoOrganization) {
+		utils.WriteError(w, http.StatusForbidden, err)
+		return
+	}
+
+	utils.WriteError(w, http.StatusInternalServerError, err)
+}
+
+// upgradePasswordHash replaces a hash from an older algorithm or with outdated
+// parameters while the plain password is at hand. A failure only means the
+// upgrade is tried again on the next login.
+func (h *Handler) upgradePasswordHash(ctx context.Context, u *types.User, password string) {
+	if !h.auth.NeedsRehash(u.Password) {
+		return
+	}
+
+	log := logging.GetLogger().WithFields(logrus.Fields{
+		"userID": u.ID,
+	})
+	hashedPassword, err := h.auth.HashPassword(password)
+	if err == nil {
+		err = h.store.UpdatePassword(ctx, u.ID, hashedPassword)
+	}
+	if err != nil {
+		log.WithField("error", err).Error("Failed to upgrade password hash")
+		return
+	}
+
+	log.Info("Password hash upgraded")
+}
+
 // handleRegister godoc
 // @Summary Register a new user
-// @Description Register a new user with name, email, and password.
+// @Description Register a new user with name, email, and password. A verification link is sent to the email address.
+// @Description The user becomes the admin of a new organization, named organizationName or after the user.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param user body types.RegisterUserPayload true "Register Information"
 // @Success 201 {object} nil "Successfully registered and no content returned."
-// @Failure 400 {object} types.HTTPError "Bad Request if the payload is invalid or user exists."
+// @Failure 400 {object} types.HTTPError "Bad Request if the payload is invalid, the password breaks the password policy or user exists."
 // @Failure 500 {object} types.HTTPError "Internal Server Error if database error occurs."
 // @Router /register [post]
 func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
@@ -100,8 +211,12 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !checkPasswordPolicy(w, user.Password, user.Email, user.FirstName, user.LastName) {
+		return
+	}
+
 	// check if user exists
-	_, err := h.store.GetUserByEmail(user.Email)
+	_, err := h.store.GetUserByEmail(r.Context(), user.Email)
 	if err == nil {
 		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("user with email %s already exists", user.Email))
 		return
@@ -114,30 +229,183 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.store.CreateUser(types.User{
+	organizationName := user.OrganizationName
+	if organizationName == "" {
+		organizationName = types.PersonalOrganizationName(user.FirstName, user.LastName, user.Email)
+	}
+
+	err = h.store.CreateUser(r.Context(), types.User{
 		FirstName: user.FirstName,
 		LastName:  user.LastName,
 		Email:     user.Email,
 		Password:  hashedPassword,
-	})
+	}, organizationName)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
 
+	// the account exists at this point, a failed email can be resent later
+	if u, err := h.store.GetUserByEmail(r.Context(), user.Email); err == nil {
+		h.sendVerificationEmail(r.Context(), u)
+	}
+
 	utils.WriteJSON(w, http.StatusCreated, nil)
 }
 
+// handleVerifyEmail godoc
+// @Summary Verify email address
+// @Description Verifies the email address with the token from the verification email.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body types.VerifyEmailPayload true "Verification token"
+// @Success 200 {object} nil "Email address verified."
+// @Failure 400 {object} types.HTTPError "Bad Request if the token is invalid, expired or already used."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /verify_email [post]
+func (h *Handler) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var payload types.VerifyEmailPayload
+	if !parseAndValidate(w, r, &payload) {
+		return
+	}
+
+	token, err := h.store.ConsumeUserToken(r.Context(), types.TokenPurposeEmailVerification, auth2.HashOpaqueToken(payload.Token))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid or expired token"))
+		return
+	}
+
+	if err := h.store.MarkEmailVerified(r.Context(), token.UserID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+// handleResendVerification godoc
+// @Summary Resend verification email
+// @Description Sends a new verification link. The response does not reveal whether the email is registered.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param email body types.EmailPayload true "Email address"
+// @Success 202 {object} nil "Accepted."
+// @Failure 400 {object} types.HTTPError "Bad Request when the payload is invalid."
+// @Router /verify_email/resend [post]
+func (h *Handler) handleResendVerification(w http.ResponseWriter, r *http.Request) {
+	var payload types.EmailPayload
+	if !parseAndValidate(w, r, &payload) {
+		return
+	}
+
+	if u, err := h.store.GetUserByEmail(r.Context(), payload.Email); err == nil && !u.EmailVerifiedAt.Valid {
+		h.sendVerificationEmail(r.Context(), u)
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, nil)
+}
+
+// handleRequestPasswordReset godoc
+// @Summary Request password reset
+// @Description Sends a password reset link. The response does not reveal whether the email is registered.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param email body types.EmailPayload true "Email address"
+// @Success 202 {object} nil "Accepted."
+// @Failure 400 {object} types.HTTPError "Bad Request when the payload is invalid."
+// @Router /password_reset [post]
+func (h *Handler) handleRequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var payload types.EmailPayload
+	if !parseAndValidate(w, r, &payload) {
+		return
+	}
+
+	if u, err := h.store.GetUserByEmail(r.Context(), payload.Email); err == nil {
+		h.sendTokenEmail(r.Context(), u, types.TokenPurposePasswordReset, config.Envs.PasswordResetTTL,
+			"Reset your password", "reset-password",
+			"Someone asked to reset the password of your account. If it was you, open the link below to choose a new password:")
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, nil)
+}
+
+// handleConfirmPasswordReset godoc
+// @Summary Reset password
+// @Description Sets a new password with the token from the password reset email.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param reset body types.ResetPasswordPayload true "Reset token and new password"
+// @Success 200 {object} nil "Password changed; every session of the user is revoked."
+// @Failure 400 {object} types.HTTPError "Bad Request if the token is invalid, expired or already used, or the password breaks the password policy."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /password_reset/confirm [post]
+func (h *Handler) handleConfirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var payload types.ResetPasswordPayload
+	if !parseAndValidate(w, r, &payload) {
+		return
+	}
+
+	tokenHash := auth2.HashOpaqueToken(payload.Token)
+	token, err := h.store.GetUserToken(r.Context(), types.TokenPurposePasswordReset, tokenHash)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid or expired token"))
+		return
+	}
+
+	u, err := h.store.GetUserByID(r.Context(), token.UserID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// checked before the single-use token is redeemed, so a rejected password
+	// can be corrected with the same link
+	if !checkPasswordPolicy(w, payload.Password, u.Email, u.FirstName, u.LastName) {
+		return
+	}
+
+	if _, err := h.store.ConsumeUserToken(r.Context(), types.TokenPurposePasswordReset, tokenHash); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid or expired token"))
+		return
+	}
+
+	hashedPassword, err := h.auth.HashPassword(payload.Password)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// whoever reset the password must not share the account with a thief
+	if err := h.store.ChangePassword(r.Context(), token.UserID, hashedPassword, ""); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// the reset link proved that the user controls the mailbox
+	if err := h.store.MarkEmailVerified(r.Context(), token.UserID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
 // GetUser godoc
 // @Summary Get a user by ID
-// @Description Get detailed information about a user.
+// @Description Get detailed information about a member of the organization. Admins only.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param id path int true "User ID"
-// @Success 200 {object} types.User "Successful retrieval of user detail."
+// @Success 200 {object} types.UserResponse "Successful retrieval of user detail."
 // @Failure 400 {object} types.HTTPError "Bad Request if user ID is missing or invalid."
-// @Failure 404 {object} types.HTTPError "Not Found if user does not exist."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 403 {object} types.HTTPError "Forbidden if the user is not an admin."
+// @Failure 404 {object} types.HTTPError "Not Found if the user is not a member of the organization."
 // @Failure 500 {object} types.HTTPError "Internal Server Error"
 // @Router /users/{id} [get]
 func (h *Handler) handleGetUser(w http.ResponseWriter, r *http.Request) {
@@ -154,11 +422,94 @@ func (h *Handler) handleGetUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.store.GetUserByID(userID)
+	user, err := h.store.GetOrganizationUser(r.Context(), auth2.GetOrgIDFromContext(r.Context()), userID)
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
+		writeStoreError(w, err)
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, user)
+	utils.WriteJSON(w, http.StatusOK, types.NewUserResponse(*user))
+}
+
+func (h *Handler) sendVerificationEmail(ctx context.Context, u *types.User) {
+	h.sendTokenEmail(ctx, u, types.TokenPurposeEmailVerification, config.Envs.EmailVerificationTTL,
+		"Verify your email address", "verify-email",
+		"Welcome! Open the link below to verify your email address:")
+}
+
+// sendTokenEmail issues a new single-use token and mails a link containing it.
+// Failures are logged only, so responses do not reveal whether an account exists.
+func (h *Handler) sendTokenEmail(ctx context.Context, u *types.User, purpose string, ttlInSeconds int64, subject, path, intro string) {
+	log := logging.GetLogger()
+
+	token, hash, err := auth2.NewOpaqueToken()
+	if err == nil {
+		err = h.store.CreateUserToken(ctx, types.UserToken{
+			UserID:    u.ID,
+			Purpose:   purpose,
+			TokenHash: hash,
+			ExpiresAt: time.Now().Add(time.Duration(ttlInSeconds) * time.Second),
+		})
+	}
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error":   err,
+			"userID":  u.ID,
+			"purpose": purpose,
+		}).Error("Failed to create user token")
+		return
+	}
+
+	link := fmt.Sprintf("%s/%s?token=%s", strings.TrimSuffix(config.Envs.AppBaseURL, "/"), path, url.QueryEscape(token))
+	err = h.mailer.Send(ctx, mailer.Message{
+		To:      u.Email,
+		Subject: subject,
+		Body:    fmt.Sprintf("%s\n\n%s\n\nThe link expires in %s.\n", intro, link, time.Duration(ttlInSeconds)*time.Second),
+	})
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error":   err,
+			"userID":  u.ID,
+			"purpose": purpose,
+		}).Error("Failed to send email")
+	}
+}
+
+// checkPasswordPolicy checks a new password against the password policy and
+// answers 400 with the list of broken rules when it does not pass.
+func checkPasswordPolicy(w http.ResponseWriter, password string, personal ...string) bool {
+	err := auth2.CheckPassword(password, personal...)
+	if err == nil {
+		return true
+	}
+
+	var policyErr *customerrors.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		utils.WriteJSON(w, http.StatusBadRequest, map[string]any{
+			"error":      policyErr.Error(),
+			"code":       policyErr.Code(),
+			"details":    policyErr,
+			"violations": policyErr.Violations,
+		})
+		return false
+	}
+
+	utils.WriteError(w, http.StatusInternalServerError, err)
+	return false
+}
+
+// parseAndValidate decodes the JSON body into payload and validates it, writing a 400 response on failure.
+func parseAndValidate(w http.ResponseWriter, r *http.Request, payload any) bool {
+	if err := utils.ParseJSON(r, payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return false
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", errors))
+		return false
+	}
+
+	return true
 }
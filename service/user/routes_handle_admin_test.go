@@ -0,0 +1,273 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/customerrors"
+	"go-sample-rest-api/mailer"
+	"go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// adminOrgID is the organization of the admin injected by withAdmin.
+const adminOrgID = 3
+
+func withAdmin(req *http.Request, userID int) *http.Request {
+	ctx := context.WithValue(req.Context(), auth.UserKey, userID)
+	ctx = context.WithValue(ctx, auth.OrgKey, adminOrgID)
+	return req.WithContext(context.WithValue(ctx, auth.RoleKey, types.RoleAdmin))
+}
+
+// adminRouter registers the admin handlers without the auth middleware; the
+// caller is injected into the request context as WithAdmin would do it.
+func adminRouter(handler *Handler) *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/users", handler.handleListUsers).Methods(http.MethodGet)
+	router.HandleFunc("/users", handler.handleCreateUser).Methods(http.MethodPost)
+	router.HandleFunc("/users/{userID}/role", handler.handleSetUserRole).Methods(http.MethodPut)
+	router.HandleFunc("/users/{userID}", handler.handleDeleteUser).Methods(http.MethodDelete)
+	router.HandleFunc("/users/{userID}/disable", handler.handleDisableUser).Methods(http.MethodPost)
+	router.HandleFunc("/users/{userID}/enable", handler.handleEnableUser).Methods(http.MethodPost)
+	return router
+}
+
+func TestUserService_Handle_AdminListUsers(t *testing.T) {
+	t.Run("lists a page of users matching the search", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		handler := NewHandler(mockUserStore, new(MockAuthenticator), new(mockMailer))
+
+		mockUserStore.On("ListUsers", mock.Anything, adminOrgID, types.UserListQuery{Search: "jane", Limit: 10, Offset: 20}).
+			Return([]types.User{*verifiedUser()}, 21, nil)
+
+		req, _ := http.NewRequest(http.MethodGet, "/users?search=jane&page=3&pageSize=10", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		adminRouter(handler).ServeHTTP(rr, withAdmin(req, 1))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		var list types.UserList
+		json.Unmarshal(rr.Body.Bytes(), &list)
+		if list.Total != 21 || list.Page != 3 || list.PageSize != 10 || len(list.Users) != 1 {
+			t.Errorf("unexpected user list: %+v", list)
+		}
+	})
+
+	t.Run("rejects invalid pagination", func(t *testing.T) {
+		// arrange
+		handler := NewHandler(new(mockUserStore), new(MockAuthenticator), new(mockMailer))
+
+		req, _ := http.NewRequest(http.MethodGet, "/users?page=-1", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		adminRouter(handler).ServeHTTP(rr, withAdmin(req, 1))
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestUserService_Handle_AdminManageUsers(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		setup        func(store *mockUserStore)
+		expectedCode int
+	}{
+		{
+			name:   "disable",
+			method: http.MethodPost,
+			path:   "/users/5/disable",
+			setup: func(store *mockUserStore) {
+				store.On("SetUserDisabled", mock.Anything, adminOrgID, 5, true, mock.Anything).Return(nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "enable",
+			method: http.MethodPost,
+			path:   "/users/5/enable",
+			setup: func(store *mockUserStore) {
+				store.On("SetUserDisabled", mock.Anything, adminOrgID, 5, false, mock.Anything).Return(nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "delete",
+			method: http.MethodDelete,
+			path:   "/users/5",
+			setup: func(store *mockUserStore) {
+				store.On("DeleteUser", mock.Anything, adminOrgID, 5, mock.Anything).Return(nil)
+			},
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:   "unknown user",
+			method: http.MethodDelete,
+			path:   "/users/99",
+			setup: func(store *mockUserStore) {
+				store.On("DeleteUser", mock.Anything, adminOrgID, 99, mock.Anything).Return(&customerrors.NotFoundError{ID: "99"})
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:   "database error",
+			method: http.MethodPost,
+			path:   "/users/5/disable",
+			setup: func(store *mockUserStore) {
+				store.On("SetUserDisabled", mock.Anything, adminOrgID, 5, true, mock.Anything).Return(fmt.Errorf("connection reset"))
+			},
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			name:   "promote",
+			method: http.MethodPut,
+			path:   "/users/5/role",
+			body:   `{"role":"admin"}`,
+			setup: func(store *mockUserStore) {
+				store.On("SetUserRole", mock.Anything, adminOrgID, 5, types.RoleAdmin, mock.Anything).Return(nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "promote user of another organization",
+			method: http.MethodPut,
+			path:   "/users/8/role",
+			body:   `{"role":"admin"}`,
+			setup: func(store *mockUserStore) {
+				store.On("SetUserRole", mock.Anything, adminOrgID, 8, types.RoleAdmin, mock.Anything).Return(&customerrors.NotFoundError{ID: "8"})
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "unknown role",
+			method:       http.MethodPut,
+			path:         "/users/5/role",
+			body:         `{"role":"owner"}`,
+			setup:        func(store *mockUserStore) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "demote own account",
+			method:       http.MethodPut,
+			path:         "/users/1/role",
+			body:         `{"role":"user"}`,
+			setup:        func(store *mockUserStore) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "own account",
+			method:       http.MethodDelete,
+			path:         "/users/1",
+			setup:        func(store *mockUserStore) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid ID",
+			method:       http.MethodPost,
+			path:         "/users/abc/disable",
+			setup:        func(store *mockUserStore) {},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// arrange
+			mockUserStore := new(mockUserStore)
+			tc.setup(mockUserStore)
+			handler := NewHandler(mockUserStore, new(MockAuthenticator), new(mockMailer))
+
+			req, _ := http.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+
+			// act
+			adminRouter(handler).ServeHTTP(rr, withAdmin(req, 1))
+
+			// assert
+			if status := rr.Code; status != tc.expectedCode {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tc.expectedCode)
+			}
+			mockUserStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserService_Handle_AdminCreateUser(t *testing.T) {
+	t.Run("creates the user in the organization and mails an invitation", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		mockAuth := new(MockAuthenticator)
+		mockMailer := new(mockMailer)
+		handler := NewHandler(mockUserStore, mockAuth, mockMailer)
+
+		mockUserStore.On("GetUserByEmail", mock.Anything, "jane@example.com").Return(nil, fmt.Errorf("user not found")).Once()
+		mockAuth.On("HashPassword", mock.AnythingOfType("string")).Return("unusable-hash", nil)
+		mockUserStore.On("CreateOrganizationUser", mock.Anything, adminOrgID, types.User{
+			FirstName: "Jane", LastName: "Doe", Email: "jane@example.com", Password: "unusable-hash",
+		}, types.RoleUser, mock.Anything).Return(nil)
+		mockUserStore.On("GetUserByEmail", mock.Anything, "jane@example.com").Return(&types.User{ID: 9, Email: "jane@example.com"}, nil)
+		var stored types.UserToken
+		mockUserStore.On("CreateUserToken", mock.Anything, mock.AnythingOfType("types.UserToken")).Run(func(args mock.Arguments) {
+			stored = args.Get(1).(types.UserToken)
+		}).Return(nil)
+		var sent mailer.Message
+		mockMailer.On("Send", mock.AnythingOfType("mailer.Message")).Run(func(args mock.Arguments) {
+			sent = args.Get(0).(mailer.Message)
+		}).Return(nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"firstName":"Jane","lastName":"Doe","email":"jane@example.com"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		adminRouter(handler).ServeHTTP(rr, withAdmin(req, 1))
+
+		// assert
+		if status := rr.Code; status != http.StatusCreated {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusCreated)
+		}
+		mockUserStore.AssertExpectations(t)
+		if stored.Purpose != types.TokenPurposePasswordReset || stored.UserID != 9 {
+			t.Errorf("expected a password reset token for the new user, got %+v", stored)
+		}
+		if stored.TokenHash != auth.HashOpaqueToken(tokenFromMessage(t, sent)) {
+			t.Error("expected the invitation to carry the stored token")
+		}
+	})
+
+	t.Run("refuses an email that is already registered", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		handler := NewHandler(mockUserStore, new(MockAuthenticator), new(mockMailer))
+
+		mockUserStore.On("GetUserByEmail", mock.Anything, "jane@example.com").Return(&types.User{ID: 9}, nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"firstName":"Jane","lastName":"Doe","email":"jane@example.com","role":"admin"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		adminRouter(handler).ServeHTTP(rr, withAdmin(req, 1))
+
+		// assert
+		if status := rr.Code; status != http.StatusConflict {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusConflict)
+		}
+		mockUserStore.AssertNotCalled(t, "CreateOrganizationUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
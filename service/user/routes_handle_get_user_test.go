@@ -1,12 +1,13 @@
 package user
 
 import (
-	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/customerrors"
 	"go-sample-rest-api/types"
 	"go-sample-rest-api/utils"
 )
@@ -14,7 +15,7 @@ import (
 func TestUserService_Handle_GetUser(t *testing.T) {
 	t.Run("missing user ID in URL", func(t *testing.T) {
 		// arrange
-		handler := NewHandler(new(mockUserStore), new(MockAuthenticator))
+		handler := NewHandler(new(mockUserStore), new(MockAuthenticator), new(mockMailer))
 		req, _ := http.NewRequest(http.MethodGet, "/users/", nil) // Missing userID in the URL
 		rr := httptest.NewRecorder()
 
@@ -29,7 +30,7 @@ func TestUserService_Handle_GetUser(t *testing.T) {
 
 	t.Run("invalid user ID", func(t *testing.T) {
 		// arrange
-		handler := NewHandler(new(mockUserStore), new(MockAuthenticator))
+		handler := NewHandler(new(mockUserStore), new(MockAuthenticator), new(mockMailer))
 		router := mux.NewRouter()
 		router.HandleFunc("/users/{userID}", handler.handleGetUser)
 		req, _ := http.NewRequest(http.MethodGet, "/users/abc", nil) // 'abc' is not a valid user ID
@@ -44,40 +45,40 @@ func TestUserService_Handle_GetUser(t *testing.T) {
 		}
 	})
 
-	t.Run("user not found", func(t *testing.T) {
+	t.Run("user not in the organization", func(t *testing.T) {
 		// arrange
 		mockUserStore := new(mockUserStore)
-		handler := NewHandler(mockUserStore, new(MockAuthenticator))
+		handler := NewHandler(mockUserStore, new(MockAuthenticator), new(mockMailer))
 		router := mux.NewRouter()
 		router.HandleFunc("/users/{userID}", handler.handleGetUser)
 
-		mockUserStore.On("GetUserByID", 1).Return(nil, fmt.Errorf("user not found"))
+		mockUserStore.On("GetOrganizationUser", mock.Anything, adminOrgID, 1).Return(nil, &customerrors.NotFoundError{ID: "1"})
 		req, _ := http.NewRequest(http.MethodGet, "/users/1", nil)
 		rr := httptest.NewRecorder()
 
 		// act
-		router.ServeHTTP(rr, req)
+		router.ServeHTTP(rr, withAdmin(req, 2))
 
 		// assert
-		if status := rr.Code; status != http.StatusInternalServerError {
-			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusInternalServerError)
+		if status := rr.Code; status != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
 		}
 	})
 
 	t.Run("successful retrieval", func(t *testing.T) {
 		// arrange
 		mockUserStore := new(mockUserStore)
-		handler := NewHandler(mockUserStore, new(MockAuthenticator))
+		handler := NewHandler(mockUserStore, new(MockAuthenticator), new(mockMailer))
 		router := mux.NewRouter()
 		router.HandleFunc("/users/{userID}", handler.handleGetUser)
 
 		expectedUser := &types.User{ID: 1, FirstName: "John", LastName: "Doe"}
-		mockUserStore.On("GetUserByID", 1).Return(expectedUser, nil)
+		mockUserStore.On("GetOrganizationUser", mock.Anything, adminOrgID, 1).Return(expectedUser, nil)
 		req, _ := http.NewRequest(http.MethodGet, "/users/1", nil)
 		rr := httptest.NewRecorder()
 
 		// act
-		router.ServeHTTP(rr, req)
+		router.ServeHTTP(rr, withAdmin(req, 2))
 
 		// assert
 		if status := rr.Code; status != http.StatusOK {
@@ -0,0 +1,151 @@
+package user
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/mailer"
+	"go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUserService_Handle_PasswordReset(t *testing.T) {
+	t.Run("request mails a single-use reset link", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		mockMailer := new(mockMailer)
+		handler := NewHandler(mockUserStore, new(MockAuthenticator), mockMailer)
+
+		mockUserStore.On("GetUserByEmail", mock.Anything, "test@test.com").Return(&types.User{ID: 3, Email: "test@test.com"}, nil)
+		var stored types.UserToken
+		mockUserStore.On("CreateUserToken", mock.Anything, mock.AnythingOfType("types.UserToken")).Run(func(args mock.Arguments) {
+			stored = args.Get(1).(types.UserToken)
+		}).Return(nil)
+		var sent mailer.Message
+		mockMailer.On("Send", mock.AnythingOfType("mailer.Message")).Run(func(args mock.Arguments) {
+			sent = args.Get(0).(mailer.Message)
+		}).Return(nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/password_reset", strings.NewReader(`{"email":"test@test.com"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleRequestPasswordReset(rr, req)
+
+		// assert
+		if status := rr.Code; status != http.StatusAccepted {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusAccepted)
+		}
+		if stored.Purpose != types.TokenPurposePasswordReset || stored.UserID != 3 {
+			t.Errorf("unexpected token stored: %+v", stored)
+		}
+		if stored.TokenHash != auth.HashOpaqueToken(tokenFromMessage(t, sent)) {
+			t.Error("expected only the hash of the mailed token to be stored")
+		}
+		if !stored.ExpiresAt.After(time.Now()) {
+			t.Errorf("expected token to expire in the future, got %v", stored.ExpiresAt)
+		}
+	})
+
+	t.Run("request for an unknown email is accepted silently", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		mockMailer := new(mockMailer)
+		handler := NewHandler(mockUserStore, new(MockAuthenticator), mockMailer)
+
+		mockUserStore.On("GetUserByEmail", mock.Anything, "nobody@test.com").Return(nil, fmt.Errorf("user not found"))
+
+		req, _ := http.NewRequest(http.MethodPost, "/password_reset", strings.NewReader(`{"email":"nobody@test.com"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleRequestPasswordReset(rr, req)
+
+		// assert
+		if status := rr.Code; status != http.StatusAccepted {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusAccepted)
+		}
+		mockMailer.AssertNotCalled(t, "Send", mock.Anything)
+	})
+
+	t.Run("confirm sets the new password and revokes every session", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		mockAuth := new(MockAuthenticator)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
+
+		mockUserStore.On("GetUserToken", mock.Anything, types.TokenPurposePasswordReset, auth.HashOpaqueToken("raw-token")).
+			Return(&types.UserToken{UserID: 3}, nil)
+		mockUserStore.On("GetUserByID", mock.Anything, 3).Return(&types.User{ID: 3, FirstName: "Jane", LastName: "Doe", Email: "jane@test.com"}, nil)
+		mockUserStore.On("ConsumeUserToken", mock.Anything, types.TokenPurposePasswordReset, auth.HashOpaqueToken("raw-token")).
+			Return(&types.UserToken{UserID: 3}, nil)
+		mockAuth.On("HashPassword", "newPassword123").Return("newHash", nil)
+		mockUserStore.On("ChangePassword", mock.Anything, 3, "newHash", "").Return(nil)
+		mockUserStore.On("MarkEmailVerified", mock.Anything, 3).Return(nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/password_reset/confirm", strings.NewReader(`{"token":"raw-token","password":"newPassword123"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleConfirmPasswordReset(rr, req)
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		mockUserStore.AssertExpectations(t)
+		mockAuth.AssertExpectations(t)
+	})
+
+	t.Run("confirm rejects an invalid token", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		mockAuth := new(MockAuthenticator)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
+
+		mockUserStore.On("GetUserToken", mock.Anything, types.TokenPurposePasswordReset, mock.Anything).
+			Return(nil, fmt.Errorf("invalid or expired token"))
+
+		req, _ := http.NewRequest(http.MethodPost, "/password_reset/confirm", strings.NewReader(`{"token":"raw-token","password":"newPassword123"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleConfirmPasswordReset(rr, req)
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+		mockUserStore.AssertNotCalled(t, "ChangePassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("confirm rejects a password with personal information and keeps the token", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		mockAuth := new(MockAuthenticator)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
+
+		mockUserStore.On("GetUserToken", mock.Anything, types.TokenPurposePasswordReset, auth.HashOpaqueToken("raw-token")).
+			Return(&types.UserToken{UserID: 3}, nil)
+		mockUserStore.On("GetUserByID", mock.Anything, 3).Return(&types.User{ID: 3, FirstName: "Jane", LastName: "Doe", Email: "jane@test.com"}, nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/password_reset/confirm", strings.NewReader(`{"token":"raw-token","password":"Janedoe-2026!"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleConfirmPasswordReset(rr, req)
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+		if !strings.Contains(rr.Body.String(), "violations") {
+			t.Errorf("expected the broken rules, got %s", rr.Body.String())
+		}
+		mockUserStore.AssertNotCalled(t, "ConsumeUserToken", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,205 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/mailer"
+	"go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func verifiedUser() *types.User {
+	return &types.User{
+		ID:              5,
+		FirstName:       "Jane",
+		LastName:        "Doe",
+		Email:           "jane@test.com",
+		Password:        "hash",
+		Role:            types.RoleUser,
+		EmailVerifiedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	}
+}
+
+func TestUserService_Handle_Profile(t *testing.T) {
+	t.Run("get returns the current user", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		handler := NewHandler(mockUserStore, new(MockAuthenticator), new(mockMailer))
+
+		mockUserStore.On("GetUserByID", mock.Anything, 5).Return(verifiedUser(), nil)
+
+		req, _ := http.NewRequest(http.MethodGet, "/users/me", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleGetCurrentUser(rr, withUser(req, 5))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		var u types.UserResponse
+		json.Unmarshal(rr.Body.Bytes(), &u)
+		if u.Email != "jane@test.com" {
+			t.Errorf("expected the current user, got %+v", u)
+		}
+		if u.EmailVerifiedAt == nil || u.TOTPEnabledAt != nil || !strings.Contains(rr.Body.String(), `"totpEnabledAt":null`) {
+			t.Errorf("expected timestamps or null, got %s", rr.Body.String())
+		}
+		if strings.Contains(rr.Body.String(), "hash") {
+			t.Error("expected the password hash not to be serialized")
+		}
+	})
+
+	t.Run("patch changes only the given fields", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		handler := NewHandler(mockUserStore, new(MockAuthenticator), new(mockMailer))
+
+		mockUserStore.On("GetUserByID", mock.Anything, 5).Return(verifiedUser(), nil)
+		expected := *verifiedUser()
+		expected.LastName = "Smith"
+		mockUserStore.On("UpdateUser", mock.Anything, mock.MatchedBy(func(u types.User) bool {
+			return u.FirstName == expected.FirstName && u.LastName == expected.LastName && u.Email == expected.Email
+		})).Return(nil)
+
+		req, _ := http.NewRequest(http.MethodPatch, "/users/me", strings.NewReader(`{"lastName":"Smith"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleUpdateCurrentUser(rr, withUser(req, 5))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		mockUserStore.AssertExpectations(t)
+	})
+
+	t.Run("changing the email sends a new verification link", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		mockMailer := new(mockMailer)
+		handler := NewHandler(mockUserStore, new(MockAuthenticator), mockMailer)
+
+		updated := verifiedUser()
+		updated.Email = "new@test.com"
+		updated.EmailVerifiedAt = sql.NullTime{}
+		mockUserStore.On("GetUserByID", mock.Anything, 5).Return(verifiedUser(), nil).Once()
+		mockUserStore.On("GetUserByEmail", mock.Anything, "new@test.com").Return(nil, fmt.Errorf("user not found"))
+		mockUserStore.On("UpdateUser", mock.Anything, mock.AnythingOfType("types.User")).Return(nil)
+		mockUserStore.On("GetUserByID", mock.Anything, 5).Return(updated, nil).Once()
+		mockUserStore.On("CreateUserToken", mock.Anything, mock.AnythingOfType("types.UserToken")).Return(nil)
+		mockMailer.On("Send", mock.AnythingOfType("mailer.Message")).Return(nil)
+
+		req, _ := http.NewRequest(http.MethodPatch, "/users/me", strings.NewReader(`{"email":"new@test.com"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleUpdateCurrentUser(rr, withUser(req, 5))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		mockMailer.AssertNumberOfCalls(t, "Send", 1)
+		if msg := mockMailer.Calls[0].Arguments.Get(0).(mailer.Message); msg.To != "new@test.com" {
+			t.Errorf("expected the link to go to the new address, got %s", msg.To)
+		}
+	})
+
+	t.Run("patch refuses an email of another user", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		handler := NewHandler(mockUserStore, new(MockAuthenticator), new(mockMailer))
+
+		mockUserStore.On("GetUserByID", mock.Anything, 5).Return(verifiedUser(), nil)
+		mockUserStore.On("GetUserByEmail", mock.Anything, "taken@test.com").Return(&types.User{ID: 6}, nil)
+
+		req, _ := http.NewRequest(http.MethodPatch, "/users/me", strings.NewReader(`{"email":"taken@test.com"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleUpdateCurrentUser(rr, withUser(req, 5))
+
+		// assert
+		if status := rr.Code; status != http.StatusConflict {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusConflict)
+		}
+		mockUserStore.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("patch rejects an invalid email", func(t *testing.T) {
+		// arrange
+		handler := NewHandler(new(mockUserStore), new(MockAuthenticator), new(mockMailer))
+
+		req, _ := http.NewRequest(http.MethodPatch, "/users/me", strings.NewReader(`{"email":"not-an-email"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleUpdateCurrentUser(rr, withUser(req, 5))
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestUserService_Handle_ChangePassword(t *testing.T) {
+	t.Run("changes the password and revokes the other sessions", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		mockAuth := new(MockAuthenticator)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
+
+		mockUserStore.On("GetUserByID", mock.Anything, 5).Return(verifiedUser(), nil)
+		mockAuth.On("ComparePasswords", "hash", []byte("old-password")).Return(true)
+		mockAuth.On("HashPassword", "new-password").Return("new-hash", nil)
+		mockUserStore.On("ChangePassword", mock.Anything, 5, "new-hash", "current-session").Return(nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/users/me/password", strings.NewReader(`{"currentPassword":"old-password","newPassword":"new-password"}`))
+		req = withUser(req, 5)
+		req = req.WithContext(context.WithValue(req.Context(), auth.SessionKey, "current-session"))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleChangePassword(rr, req)
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		mockUserStore.AssertExpectations(t)
+	})
+
+	t.Run("rejects a wrong current password", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		mockAuth := new(MockAuthenticator)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
+
+		mockUserStore.On("GetUserByID", mock.Anything, 5).Return(verifiedUser(), nil)
+		mockAuth.On("ComparePasswords", "hash", []byte("guess")).Return(false)
+
+		req, _ := http.NewRequest(http.MethodPost, "/users/me/password", strings.NewReader(`{"currentPassword":"guess","newPassword":"new-password"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleChangePassword(rr, withUser(req, 5))
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+		mockUserStore.AssertNotCalled(t, "ChangePassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
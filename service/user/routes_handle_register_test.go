@@ -2,6 +2,7 @@ package user
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"go-sample-rest-api/types"
 	"net/http"
@@ -15,7 +16,7 @@ import (
 func TestUserService_Handle_Register(t *testing.T) {
 	t.Run("invalid JSON payload", func(t *testing.T) {
 		// arrange
-		handler := NewHandler(new(mockUserStore), new(MockAuthenticator))
+		handler := NewHandler(new(mockUserStore), new(MockAuthenticator), new(mockMailer))
 
 		// act
 		req, _ := http.NewRequest(http.MethodPost, "/register", bytes.NewBufferString("{invalid json"))
@@ -32,7 +33,7 @@ func TestUserService_Handle_Register(t *testing.T) {
 		// arrange
 		mockUserStore := new(mockUserStore)
 		mockAuth := new(MockAuthenticator)
-		handler := NewHandler(mockUserStore, mockAuth)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
 
 		userData := `{"email": "test@test.com", "password": ""}` // Invalid because password is empty
 		req, _ := http.NewRequest(http.MethodPost, "/register", strings.NewReader(userData))
@@ -48,14 +49,40 @@ func TestUserService_Handle_Register(t *testing.T) {
 		}
 	})
 
+	t.Run("password breaks the policy", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		handler := NewHandler(mockUserStore, new(MockAuthenticator), new(mockMailer))
+
+		userData := `{"firstName": "Johnny", "lastName": "Doe", "email": "johnny@doe.com", "password": "johnny"}`
+		req, _ := http.NewRequest(http.MethodPost, "/register", strings.NewReader(userData))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleRegister(rr, req)
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+		var body struct {
+			Violations []string `json:"violations"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &body)
+		if len(body.Violations) != 2 {
+			t.Errorf("expected the length and personal information violations, got %v", body.Violations)
+		}
+		mockUserStore.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything, mock.Anything)
+	})
+
 	t.Run("user already exists", func(t *testing.T) {
 		// arrange
 		mockUserStore := new(mockUserStore)
 		mockAuth := new(MockAuthenticator)
-		handler := NewHandler(mockUserStore, mockAuth)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
 
 		userData := `{"firstName": "John", "lastName": "Doe", "email": "exists@test.com", "password": "password123"}`
-		mockUserStore.On("GetUserByEmail", "exists@test.com").Return(&types.User{}, nil) // User already exists
+		mockUserStore.On("GetUserByEmail", mock.Anything, "exists@test.com").Return(&types.User{}, nil) // User already exists
 
 		req, _ := http.NewRequest(http.MethodPost, "/register", strings.NewReader(userData))
 		req.Header.Set("Content-Type", "application/json")
@@ -76,12 +103,12 @@ func TestUserService_Handle_Register(t *testing.T) {
 		// arrange
 		mockUserStore := new(mockUserStore)
 		mockAuth := new(MockAuthenticator)
-		handler := NewHandler(mockUserStore, mockAuth)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
 
 		userData := `{"firstName": "John", "lastName": "Doe", "email": "john@doe.com", "password": "password123"}`
-		mockUserStore.On("GetUserByEmail", "john@doe.com").Return(nil, fmt.Errorf("not found"))
+		mockUserStore.On("GetUserByEmail", mock.Anything, "john@doe.com").Return(nil, fmt.Errorf("not found"))
 		mockAuth.On("HashPassword", "password123").Return("hashedPassword123", nil)
-		mockUserStore.On("CreateUser", mock.Anything).Return(fmt.Errorf("db error"))
+		mockUserStore.On("CreateUser", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("db error"))
 
 		req, _ := http.NewRequest(http.MethodPost, "/register", strings.NewReader(userData))
 		req.Header.Set("Content-Type", "application/json")
@@ -104,10 +131,10 @@ func TestUserService_Handle_Register(t *testing.T) {
 		// arrange
 		mockUserStore := new(mockUserStore)
 		mockAuth := new(MockAuthenticator)
-		handler := NewHandler(mockUserStore, mockAuth)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
 
 		userData := `{"firstName": "John", "lastName": "Doe", "email": "john@doe.com", "password": "password123"}`
-		mockUserStore.On("GetUserByEmail", "john@doe.com").Return(nil, fmt.Errorf("not found"))
+		mockUserStore.On("GetUserByEmail", mock.Anything, "john@doe.com").Return(nil, fmt.Errorf("not found"))
 		mockAuth.On("HashPassword", "password123").Return(nil, fmt.Errorf("hash error"))
 
 		req, _ := http.NewRequest(http.MethodPost, "/register", strings.NewReader(userData))
@@ -131,12 +158,12 @@ func TestUserService_Handle_Register(t *testing.T) {
 		// arrange
 		mockUserStore := new(mockUserStore)
 		mockAuth := new(MockAuthenticator)
-		handler := NewHandler(mockUserStore, mockAuth)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
 
 		userData := `{"firstName": "John", "lastName": "Doe", "email": "new@user.com", "password": "securePass123"}`
-		mockUserStore.On("GetUserByEmail", "new@user.com").Return(nil, fmt.Errorf("not found")) // No existing user
+		mockUserStore.On("GetUserByEmail", mock.Anything, "new@user.com").Return(nil, fmt.Errorf("not found")) // No existing user
 		mockAuth.On("HashPassword", "securePass123").Return("hashedPassword123", nil)
-		mockUserStore.On("CreateUser", mock.Anything).Return(nil)
+		mockUserStore.On("CreateUser", mock.Anything, mock.Anything, "John Doe").Return(nil)
 
 		req, _ := http.NewRequest(http.MethodPost, "/register", strings.NewReader(userData))
 		req.Header.Set("Content-Type", "application/json")
@@ -153,4 +180,29 @@ func TestUserService_Handle_Register(t *testing.T) {
 		mockUserStore.AssertExpectations(t)
 		mockAuth.AssertExpectations(t)
 	})
+
+	t.Run("registration with an organization name", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		mockAuth := new(MockAuthenticator)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
+
+		userData := `{"firstName": "John", "lastName": "Doe", "email": "new@user.com", "password": "securePass123", "organizationName": "Acme"}`
+		mockUserStore.On("GetUserByEmail", mock.Anything, "new@user.com").Return(nil, fmt.Errorf("not found"))
+		mockAuth.On("HashPassword", "securePass123").Return("hashedPassword123", nil)
+		mockUserStore.On("CreateUser", mock.Anything, mock.AnythingOfType("types.User"), "Acme").Return(nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/register", strings.NewReader(userData))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleRegister(rr, req)
+
+		// assert
+		if status := rr.Code; status != http.StatusCreated {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusCreated)
+		}
+		mockUserStore.AssertExpectations(t)
+	})
 }
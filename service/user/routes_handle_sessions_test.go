@@ -0,0 +1,140 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/customerrors"
+	"go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const (
+	laptopSessionID     = "0b6e5a52-6f1e-4a8e-9d64-1f4f3c1d2a10"
+	otherUsersSessionID = "5d1f7c8e-2b3a-4c9d-8e7f-6a5b4c3d2e1f"
+)
+
+func withSession(req *http.Request, userID int, sessionID string) *http.Request {
+	ctx := context.WithValue(req.Context(), auth.UserKey, userID)
+	return req.WithContext(context.WithValue(ctx, auth.SessionKey, sessionID))
+}
+
+func sessionsRouter(handler *Handler) *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/users/me/sessions", handler.handleListSessions).Methods(http.MethodGet)
+	router.HandleFunc("/users/me/sessions", handler.handleRevokeOtherSessions).Methods(http.MethodDelete)
+	router.HandleFunc("/users/me/sessions/{sessionID}", handler.handleRevokeSession).Methods(http.MethodDelete)
+	return router
+}
+
+func TestUserService_Handle_Sessions(t *testing.T) {
+	t.Run("list marks the current session", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		handler := NewHandler(mockUserStore, new(MockAuthenticator), new(mockMailer))
+
+		now := time.Now()
+		mockUserStore.On("ListSessions", mock.Anything, 5).Return([]types.Session{
+			{ID: "laptop", UserAgent: "Firefox", LastUsedAt: now},
+			{ID: "phone", UserAgent: "Safari", LastUsedAt: now.Add(-time.Hour)},
+		}, nil)
+
+		req, _ := http.NewRequest(http.MethodGet, "/users/me/sessions", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		sessionsRouter(handler).ServeHTTP(rr, withSession(req, 5, "phone"))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		var sessions []types.Session
+		json.Unmarshal(rr.Body.Bytes(), &sessions)
+		if len(sessions) != 2 || sessions[0].Current || !sessions[1].Current {
+			t.Errorf("expected only the phone session to be current, got %+v", sessions)
+		}
+	})
+
+	t.Run("revoke a session of the user", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		handler := NewHandler(mockUserStore, new(MockAuthenticator), new(mockMailer))
+
+		mockUserStore.On("RevokeSession", mock.Anything, 5, laptopSessionID).Return(nil)
+
+		req, _ := http.NewRequest(http.MethodDelete, "/users/me/sessions/"+laptopSessionID, nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		sessionsRouter(handler).ServeHTTP(rr, withSession(req, 5, "phone"))
+
+		// assert
+		if status := rr.Code; status != http.StatusNoContent {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNoContent)
+		}
+		mockUserStore.AssertExpectations(t)
+	})
+
+	t.Run("revoke an unknown session", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		handler := NewHandler(mockUserStore, new(MockAuthenticator), new(mockMailer))
+
+		mockUserStore.On("RevokeSession", mock.Anything, 5, otherUsersSessionID).Return(&customerrors.NotFoundError{ID: otherUsersSessionID})
+
+		req, _ := http.NewRequest(http.MethodDelete, "/users/me/sessions/"+otherUsersSessionID, nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		sessionsRouter(handler).ServeHTTP(rr, withSession(req, 5, "phone"))
+
+		// assert
+		if status := rr.Code; status != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+		}
+	})
+
+	t.Run("revoke a session with an invalid ID", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		handler := NewHandler(mockUserStore, new(MockAuthenticator), new(mockMailer))
+
+		req, _ := http.NewRequest(http.MethodDelete, "/users/me/sessions/laptop", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		sessionsRouter(handler).ServeHTTP(rr, withSession(req, 5, "phone"))
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+		mockUserStore.AssertNotCalled(t, "RevokeSession", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("revoke all other sessions keeps the current one", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		handler := NewHandler(mockUserStore, new(MockAuthenticator), new(mockMailer))
+
+		mockUserStore.On("RevokeOtherSessions", mock.Anything, 5, "phone").Return(nil)
+
+		req, _ := http.NewRequest(http.MethodDelete, "/users/me/sessions", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		sessionsRouter(handler).ServeHTTP(rr, withSession(req, 5, "phone"))
+
+		// assert
+		if status := rr.Code; status != http.StatusNoContent {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNoContent)
+		}
+		mockUserStore.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,327 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func twoFactorUser() *types.User {
+	return &types.User{
+		ID:              5,
+		Email:           "test@test.com",
+		Password:        "hash",
+		EmailVerifiedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		TOTPSecret:      sql.NullString{String: testTOTPSecret, Valid: true},
+		TOTPEnabledAt:   sql.NullTime{Time: time.Now(), Valid: true},
+	}
+}
+
+// withUser returns a request as WithJWTAuth passes it on for the given user.
+func withUser(req *http.Request, userID int) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), auth.UserKey, userID))
+}
+
+func TestUserService_Handle_TwoFactorLogin(t *testing.T) {
+	t.Run("login returns a challenge instead of a token", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		mockAuth := new(MockAuthenticator)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
+
+		mockUserStore.On("GetUserByEmail", mock.Anything, "test@test.com").Return(twoFactorUser(), nil)
+		mockAuth.On("ComparePasswords", "hash", []byte("password123")).Return(true)
+		mockAuth.On("NeedsRehash", "hash").Return(false)
+		mockAuth.On("CreateMFAChallenge", mock.Anything, 5).Return("challenge", nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email":"test@test.com","password":"password123"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleLogin(rr, req)
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		var body map[string]string
+		json.Unmarshal(rr.Body.Bytes(), &body)
+		if body["challengeToken"] != "challenge" || body["token"] != "" {
+			t.Errorf("expected only a challenge token, got %v", body)
+		}
+		mockAuth.AssertNotCalled(t, "CreateJWT", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("challenge and TOTP code are exchanged for a token", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		mockAuth := new(MockAuthenticator)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
+
+		code, _ := auth.TOTPCode(testTOTPSecret, time.Now())
+		mockAuth.On("ValidateMFAChallenge", "challenge").Return(5, nil)
+		mockUserStore.On("GetUserByID", mock.Anything, 5).Return(twoFactorUser(), nil)
+		mockUserStore.On("UseTOTPStep", mock.Anything, 5, mock.AnythingOfType("int64")).Return(nil)
+		mockUserStore.On("ListMemberships", mock.Anything, 5).Return([]types.Membership{{OrgID: 1, UserID: 5}}, nil)
+		mockUserStore.On("CreateSession", mock.Anything, mock.AnythingOfType("types.Session")).Return(nil)
+		mockAuth.On("CreateJWT", mock.Anything, 5, 1, mock.Anything).Return("token", nil)
+
+		body := fmt.Sprintf(`{"challengeToken":"challenge","code":"%s"}`, code)
+		req, _ := http.NewRequest(http.MethodPost, "/login/2fa", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleLoginChallenge(rr, req)
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		mockAuth.AssertExpectations(t)
+	})
+
+	t.Run("a replayed TOTP code is rejected", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		mockAuth := new(MockAuthenticator)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
+
+		code, _ := auth.TOTPCode(testTOTPSecret, time.Now())
+		mockAuth.On("ValidateMFAChallenge", "challenge").Return(5, nil)
+		mockUserStore.On("GetUserByID", mock.Anything, 5).Return(twoFactorUser(), nil)
+		mockUserStore.On("UseTOTPStep", mock.Anything, 5, mock.AnythingOfType("int64")).Return(fmt.Errorf("code already used"))
+
+		body := fmt.Sprintf(`{"challengeToken":"challenge","code":"%s"}`, code)
+		req, _ := http.NewRequest(http.MethodPost, "/login/2fa", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleLoginChallenge(rr, req)
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+		mockAuth.AssertNotCalled(t, "CreateJWT", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("a recovery code is accepted", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		mockAuth := new(MockAuthenticator)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
+
+		mockAuth.On("ValidateMFAChallenge", "challenge").Return(5, nil)
+		mockUserStore.On("GetUserByID", mock.Anything, 5).Return(twoFactorUser(), nil)
+		mockUserStore.On("ConsumeRecoveryCode", mock.Anything, 5, auth.HashOpaqueToken("abcde23456")).Return(nil)
+		mockUserStore.On("ListMemberships", mock.Anything, 5).Return([]types.Membership{{OrgID: 1, UserID: 5}}, nil)
+		mockUserStore.On("CreateSession", mock.Anything, mock.AnythingOfType("types.Session")).Return(nil)
+		mockAuth.On("CreateJWT", mock.Anything, 5, 1, mock.Anything).Return("token", nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/login/2fa", strings.NewReader(`{"challengeToken":"challenge","code":"ABCDE-23456"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleLoginChallenge(rr, req)
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		mockUserStore.AssertExpectations(t)
+	})
+
+	t.Run("an invalid challenge is rejected", func(t *testing.T) {
+		// arrange
+		mockAuth := new(MockAuthenticator)
+		handler := NewHandler(new(mockUserStore), mockAuth, new(mockMailer))
+
+		mockAuth.On("ValidateMFAChallenge", "access-token").Return(0, fmt.Errorf("token has invalid audience"))
+
+		req, _ := http.NewRequest(http.MethodPost, "/login/2fa", strings.NewReader(`{"challengeToken":"access-token","code":"123456"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleLoginChallenge(rr, req)
+
+		// assert
+		if status := rr.Code; status != http.StatusUnauthorized {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("wrong codes are throttled", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		mockAuth := new(MockAuthenticator)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
+		handler.throttle = newLoginThrottle(ThrottlePolicy{
+			FreeAttempts:     1,
+			BaseDelay:        time.Minute,
+			MaxDelay:         time.Hour,
+			LockoutThreshold: 5,
+			LockoutDuration:  time.Hour,
+		}, ThrottlePolicy{FreeAttempts: 100, LockoutThreshold: 100, LockoutDuration: time.Hour})
+
+		mockAuth.On("ValidateMFAChallenge", "challenge").Return(5, nil)
+		mockUserStore.On("GetUserByID", mock.Anything, 5).Return(twoFactorUser(), nil)
+		mockUserStore.On("ConsumeRecoveryCode", mock.Anything, 5, mock.Anything).Return(fmt.Errorf("invalid recovery code"))
+
+		var last *httptest.ResponseRecorder
+		for i := 0; i < 3; i++ {
+			req, _ := http.NewRequest(http.MethodPost, "/login/2fa", strings.NewReader(`{"challengeToken":"challenge","code":"wrong-code"}`))
+			last = httptest.NewRecorder()
+
+			// act
+			handler.handleLoginChallenge(last, req)
+		}
+
+		// assert
+		if status := last.Code; status != http.StatusTooManyRequests {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusTooManyRequests)
+		}
+		mockUserStore.AssertNumberOfCalls(t, "ConsumeRecoveryCode", 2)
+	})
+}
+
+func TestUserService_Handle_TwoFactorEnrollment(t *testing.T) {
+	t.Run("enrollment returns a provisioning URI", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		handler := NewHandler(mockUserStore, new(MockAuthenticator), new(mockMailer))
+
+		mockUserStore.On("GetUserByID", mock.Anything, 5).Return(&types.User{ID: 5, Email: "test@test.com"}, nil)
+		var secret string
+		mockUserStore.On("SetTOTPSecret", mock.Anything, 5, mock.AnythingOfType("string")).Run(func(args mock.Arguments) {
+			secret = args.String(2)
+		}).Return(nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/users/me/2fa", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleEnrollTOTP(rr, withUser(req, 5))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		var enrollment types.TOTPEnrollment
+		json.Unmarshal(rr.Body.Bytes(), &enrollment)
+		if enrollment.Secret != secret || !strings.HasPrefix(enrollment.ProvisioningURI, "otpauth://totp/") {
+			t.Errorf("unexpected enrollment: %+v", enrollment)
+		}
+		if !strings.Contains(enrollment.ProvisioningURI, "secret="+secret) {
+			t.Errorf("expected the URI to carry the stored secret, got %s", enrollment.ProvisioningURI)
+		}
+	})
+
+	t.Run("enrollment is refused while 2FA is enabled", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		handler := NewHandler(mockUserStore, new(MockAuthenticator), new(mockMailer))
+
+		mockUserStore.On("GetUserByID", mock.Anything, 5).Return(twoFactorUser(), nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/users/me/2fa", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleEnrollTOTP(rr, withUser(req, 5))
+
+		// assert
+		if status := rr.Code; status != http.StatusConflict {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusConflict)
+		}
+		mockUserStore.AssertNotCalled(t, "SetTOTPSecret", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("verification enables 2FA and returns hashed recovery codes", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		handler := NewHandler(mockUserStore, new(MockAuthenticator), new(mockMailer))
+
+		pending := &types.User{ID: 5, TOTPSecret: sql.NullString{String: testTOTPSecret, Valid: true}}
+		mockUserStore.On("GetUserByID", mock.Anything, 5).Return(pending, nil)
+		var hashes []string
+		mockUserStore.On("EnableTOTP", mock.Anything, 5, mock.AnythingOfType("int64"), mock.Anything).Run(func(args mock.Arguments) {
+			hashes = args.Get(3).([]string)
+		}).Return(nil)
+
+		code, _ := auth.TOTPCode(testTOTPSecret, time.Now())
+		req, _ := http.NewRequest(http.MethodPost, "/users/me/2fa/verify", strings.NewReader(fmt.Sprintf(`{"code":"%s"}`, code)))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleVerifyTOTP(rr, withUser(req, 5))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		var codes types.RecoveryCodes
+		json.Unmarshal(rr.Body.Bytes(), &codes)
+		if len(codes.RecoveryCodes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+			t.Fatalf("expected %d recovery codes, got %d returned and %d stored", recoveryCodeCount, len(codes.RecoveryCodes), len(hashes))
+		}
+		if hashes[0] != auth.HashOpaqueToken(auth.NormalizeRecoveryCode(codes.RecoveryCodes[0])) {
+			t.Error("expected only hashes of the recovery codes to be stored")
+		}
+	})
+
+	t.Run("verification rejects a wrong code", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		handler := NewHandler(mockUserStore, new(MockAuthenticator), new(mockMailer))
+
+		pending := &types.User{ID: 5, TOTPSecret: sql.NullString{String: testTOTPSecret, Valid: true}}
+		mockUserStore.On("GetUserByID", mock.Anything, 5).Return(pending, nil)
+
+		code, _ := auth.TOTPCode(testTOTPSecret, time.Now().Add(-time.Hour))
+		req, _ := http.NewRequest(http.MethodPost, "/users/me/2fa/verify", strings.NewReader(fmt.Sprintf(`{"code":"%s"}`, code)))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleVerifyTOTP(rr, withUser(req, 5))
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+		mockUserStore.AssertNotCalled(t, "EnableTOTP", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("disabling requires a valid code", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		handler := NewHandler(mockUserStore, new(MockAuthenticator), new(mockMailer))
+
+		mockUserStore.On("GetUserByID", mock.Anything, 5).Return(twoFactorUser(), nil)
+		mockUserStore.On("UseTOTPStep", mock.Anything, 5, mock.AnythingOfType("int64")).Return(nil)
+		mockUserStore.On("DisableTOTP", mock.Anything, 5).Return(nil)
+
+		code, _ := auth.TOTPCode(testTOTPSecret, time.Now())
+		req, _ := http.NewRequest(http.MethodPost, "/users/me/2fa/disable", strings.NewReader(fmt.Sprintf(`{"code":"%s"}`, code)))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleDisableTOTP(rr, withUser(req, 5))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		mockUserStore.AssertExpectations(t)
+	})
+}
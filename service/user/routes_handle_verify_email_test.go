@@ -0,0 +1,185 @@
+package user
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/mailer"
+	"go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// tokenFromMessage extracts the token query parameter from the link in an email body.
+func tokenFromMessage(t *testing.T, msg mailer.Message) string {
+	for _, field := range strings.Fields(msg.Body) {
+		if u, err := url.Parse(field); err == nil && u.Query().Get("token") != "" {
+			return u.Query().Get("token")
+		}
+	}
+	t.Fatalf("no token link found in %q", msg.Body)
+	return ""
+}
+
+func TestUserService_Handle_VerifyEmail(t *testing.T) {
+	t.Run("registration sends a verification email", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		mockAuth := new(MockAuthenticator)
+		mockMailer := new(mockMailer)
+		handler := NewHandler(mockUserStore, mockAuth, mockMailer)
+
+		userData := `{"firstName": "John", "lastName": "Doe", "email": "new@user.com", "password": "securePass123"}`
+		mockUserStore.On("GetUserByEmail", mock.Anything, "new@user.com").Return(nil, fmt.Errorf("not found")).Once()
+		mockAuth.On("HashPassword", "securePass123").Return("hashedPassword123", nil)
+		mockUserStore.On("CreateUser", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockUserStore.On("GetUserByEmail", mock.Anything, "new@user.com").Return(&types.User{ID: 5, Email: "new@user.com"}, nil).Once()
+
+		var stored types.UserToken
+		mockUserStore.On("CreateUserToken", mock.Anything, mock.AnythingOfType("types.UserToken")).Run(func(args mock.Arguments) {
+			stored = args.Get(1).(types.UserToken)
+		}).Return(nil)
+		var sent mailer.Message
+		mockMailer.On("Send", mock.AnythingOfType("mailer.Message")).Run(func(args mock.Arguments) {
+			sent = args.Get(0).(mailer.Message)
+		}).Return(nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/register", strings.NewReader(userData))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleRegister(rr, req)
+
+		// assert
+		if status := rr.Code; status != http.StatusCreated {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusCreated)
+		}
+		if sent.To != "new@user.com" {
+			t.Errorf("expected email to new@user.com, got %q", sent.To)
+		}
+		if stored.UserID != 5 || stored.Purpose != types.TokenPurposeEmailVerification {
+			t.Errorf("unexpected token stored: %+v", stored)
+		}
+		if stored.TokenHash != auth.HashOpaqueToken(tokenFromMessage(t, sent)) {
+			t.Error("expected only the hash of the mailed token to be stored")
+		}
+		mockUserStore.AssertExpectations(t)
+		mockMailer.AssertExpectations(t)
+	})
+
+	t.Run("registration succeeds when the email cannot be sent", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		mockAuth := new(MockAuthenticator)
+		mockMailer := new(mockMailer)
+		handler := NewHandler(mockUserStore, mockAuth, mockMailer)
+
+		userData := `{"firstName": "John", "lastName": "Doe", "email": "new@user.com", "password": "securePass123"}`
+		mockUserStore.On("GetUserByEmail", mock.Anything, "new@user.com").Return(nil, fmt.Errorf("not found")).Once()
+		mockAuth.On("HashPassword", "securePass123").Return("hashedPassword123", nil)
+		mockUserStore.On("CreateUser", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockUserStore.On("GetUserByEmail", mock.Anything, "new@user.com").Return(&types.User{ID: 5, Email: "new@user.com"}, nil).Once()
+		mockUserStore.On("CreateUserToken", mock.Anything, mock.Anything).Return(nil)
+		mockMailer.On("Send", mock.Anything).Return(fmt.Errorf("smtp down"))
+
+		req, _ := http.NewRequest(http.MethodPost, "/register", strings.NewReader(userData))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleRegister(rr, req)
+
+		// assert
+		if status := rr.Code; status != http.StatusCreated {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusCreated)
+		}
+	})
+
+	t.Run("unverified users cannot log in", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		mockAuth := new(MockAuthenticator)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
+
+		mockUserStore.On("GetUserByEmail", mock.Anything, "test@test.com").Return(&types.User{ID: 1, Password: "hash"}, nil)
+		mockAuth.On("ComparePasswords", "hash", []byte("password123")).Return(true)
+
+		req, _ := http.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email":"test@test.com","password":"password123"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleLogin(rr, req)
+
+		// assert
+		if status := rr.Code; status != http.StatusForbidden {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusForbidden)
+		}
+		mockAuth.AssertNotCalled(t, "CreateJWT", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("valid token verifies the email", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		handler := NewHandler(mockUserStore, new(MockAuthenticator), new(mockMailer))
+
+		mockUserStore.On("ConsumeUserToken", mock.Anything, types.TokenPurposeEmailVerification, auth.HashOpaqueToken("raw-token")).
+			Return(&types.UserToken{UserID: 5}, nil)
+		mockUserStore.On("MarkEmailVerified", mock.Anything, 5).Return(nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/verify_email", strings.NewReader(`{"token":"raw-token"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleVerifyEmail(rr, req)
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		mockUserStore.AssertExpectations(t)
+	})
+
+	t.Run("used or expired token is rejected", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		handler := NewHandler(mockUserStore, new(MockAuthenticator), new(mockMailer))
+
+		mockUserStore.On("ConsumeUserToken", mock.Anything, types.TokenPurposeEmailVerification, mock.Anything).
+			Return(nil, fmt.Errorf("invalid or expired token"))
+
+		req, _ := http.NewRequest(http.MethodPost, "/verify_email", strings.NewReader(`{"token":"raw-token"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleVerifyEmail(rr, req)
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+		mockUserStore.AssertNotCalled(t, "MarkEmailVerified", mock.Anything, mock.Anything)
+	})
+
+	t.Run("resend does not reveal unknown emails", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		mockMailer := new(mockMailer)
+		handler := NewHandler(mockUserStore, new(MockAuthenticator), mockMailer)
+
+		mockUserStore.On("GetUserByEmail", mock.Anything, "nobody@test.com").Return(nil, fmt.Errorf("user not found"))
+
+		req, _ := http.NewRequest(http.MethodPost, "/verify_email/resend", strings.NewReader(`{"email":"nobody@test.com"}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		handler.handleResendVerification(rr, req)
+
+		// assert
+		if status := rr.Code; status != http.StatusAccepted {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusAccepted)
+		}
+		mockMailer.AssertNotCalled(t, "Send", mock.Anything)
+	})
+}
@@ -2,6 +2,7 @@ package user
 
 import (
 	"bytes"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"github.com/gorilla/mux"
@@ -11,6 +12,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestUserService(t *testing.T) {
@@ -19,7 +21,7 @@ func TestUserService(t *testing.T) {
 		// arrange
 		mockUserStore := new(mockUserStore)
 		mockAuth := new(MockAuthenticator)
-		handler := NewHandler(mockUserStore, mockAuth)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
 		req, err := http.NewRequest(http.MethodGet, "/user/abc", nil)
 		if err != nil {
 			t.Fatal(err)
@@ -43,10 +45,10 @@ func TestUserService(t *testing.T) {
 		mockUserStore := new(mockUserStore)
 		mockAuth := new(MockAuthenticator)
 
-		handler := NewHandler(mockUserStore, mockAuth)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
 		// Set up mock response
 		expectedUser := &types.User{ID: 42, FirstName: "John Doe"}
-		mockUserStore.On("GetUserByID", 42).Return(expectedUser, nil)
+		mockUserStore.On("GetOrganizationUser", mock.Anything, adminOrgID, 42).Return(expectedUser, nil)
 
 		//act
 		req, err := http.NewRequest(http.MethodGet, "/user/42", nil)
@@ -61,14 +63,14 @@ func TestUserService(t *testing.T) {
 
 		router.HandleFunc("/user/{userID}", handler.handleGetUser).Methods(http.MethodGet)
 
-		router.ServeHTTP(rr, req)
+		router.ServeHTTP(rr, withAdmin(req, 1))
 
 		if rr.Code != http.StatusOK {
 			t.Errorf("expected status code %d, got %d", http.StatusOK, rr.Code)
 		}
 
 		// Additional check to assert the response content if necessary
-		var user types.User
+		var user types.UserResponse
 		if err := json.Unmarshal(rr.Body.Bytes(), &user); err != nil {
 			t.Fatal("failed to unmarshal response")
 		}
@@ -84,7 +86,7 @@ func TestUserService(t *testing.T) {
 		mockUserStore := new(mockUserStore)
 		mockAuth := new(MockAuthenticator)
 
-		handler := NewHandler(mockUserStore, mockAuth)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
 
 		email := "test@test.com"
 		user := types.LoginUserPayload{
@@ -95,14 +97,21 @@ func TestUserService(t *testing.T) {
 			Email:    email,
 			Password: "hashedPassword123", // assuming you are storing hashed passwords
 			ID:       1,
+			EmailVerifiedAt: sql.NullTime{
+				Time:  time.Now(),
+				Valid: true,
+			},
 		}
 
 		expectedToken := "dummyToken123"
 
 		// Correct mock setup
 		mockAuth.On("ComparePasswords", mock.Anything, mock.Anything).Return(true)
-		mockAuth.On("CreateJWT", mock.Anything, mock.Anything).Return(expectedToken, nil)
-		mockUserStore.On("GetUserByEmail", email).Return(mockUser, nil)
+		mockAuth.On("NeedsRehash", mock.Anything).Return(false)
+		mockUserStore.On("ListMemberships", mock.Anything, mock.Anything).Return([]types.Membership{{OrgID: 1, UserID: 1}}, nil)
+		mockUserStore.On("CreateSession", mock.Anything, mock.AnythingOfType("types.Session")).Return(nil)
+		mockAuth.On("CreateJWT", mock.Anything, mock.Anything, 1, mock.Anything).Return(expectedToken, nil)
+		mockUserStore.On("GetUserByEmail", mock.Anything, email).Return(mockUser, nil)
 
 		userData, err := json.Marshal(user)
 		if err != nil {
@@ -133,7 +142,7 @@ func TestUserService(t *testing.T) {
 		mockUserStore := new(mockUserStore)
 		mockAuth := new(MockAuthenticator)
 
-		handler := NewHandler(mockUserStore, mockAuth)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
 
 		// act
 		req, _ := http.NewRequest(http.MethodPost, "/login", bytes.NewBufferString("{invalid json"))
@@ -151,8 +160,8 @@ func TestUserService(t *testing.T) {
 		mockUserStore := new(mockUserStore)
 		mockAuth := new(MockAuthenticator)
 
-		handler := NewHandler(mockUserStore, mockAuth)
-		mockUserStore.On("GetUserByEmail", mock.Anything).Return(nil, fmt.Errorf("not found"))
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
+		mockUserStore.On("GetUserByEmail", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("not found"))
 
 		user := types.LoginUserPayload{
 			Email:    "", // Empty email to trigger validation error
@@ -176,10 +185,11 @@ func TestUserService(t *testing.T) {
 		mockUserStore := new(mockUserStore)
 		mockAuth := new(MockAuthenticator)
 
-		handler := NewHandler(mockUserStore, mockAuth)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
 
 		testEmail := "nonexistent@test.com"
-		mockUserStore.On("GetUserByEmail", mock.Anything).Return(types.User{}, fmt.Errorf("user not found"))
+		mockUserStore.On("GetUserByEmail", mock.Anything, mock.Anything).Return(types.User{}, fmt.Errorf("user not found"))
+		mockAuth.On("ComparePasswords", dummyPasswordHash, []byte("password123")).Return(false)
 
 		// act
 		req, _ := http.NewRequest(http.MethodPost, "/login", strings.NewReader(fmt.Sprintf(`{"email":"%s","password":"password123"}`, testEmail)))
@@ -192,6 +202,7 @@ func TestUserService(t *testing.T) {
 		if status := rr.Code; status != http.StatusBadRequest {
 			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
 		}
+		mockAuth.AssertExpectations(t)
 	})
 
 	t.Run("incorrect password", func(t *testing.T) {
@@ -199,10 +210,10 @@ func TestUserService(t *testing.T) {
 		mockUserStore := new(mockUserStore)
 		mockAuth := new(MockAuthenticator)
 
-		handler := NewHandler(mockUserStore, mockAuth)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
 
 		hashedPassword := "$2a$12$examplebcryptpasswordhash"
-		mockUserStore.On("GetUserByEmail", "test@test.com").Return(&types.User{Password: hashedPassword}, nil)
+		mockUserStore.On("GetUserByEmail", mock.Anything, "test@test.com").Return(&types.User{Password: hashedPassword}, nil)
 		mockAuth.On("ComparePasswords", hashedPassword, []byte("wrongpassword")).Return(false)
 
 		user := types.LoginUserPayload{
@@ -229,12 +240,16 @@ func TestUserService(t *testing.T) {
 		mockUserStore := new(mockUserStore)
 		mockAuth := new(MockAuthenticator)
 
-		handler := NewHandler(mockUserStore, mockAuth)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
 
 		hashedPassword := "$2a$12$examplebcryptpasswordhash"
-		mockUserStore.On("GetUserByEmail", "test@test.com").Return(&types.User{ID: 1, Password: hashedPassword}, nil)
+		verifiedAt := sql.NullTime{Time: time.Now(), Valid: true}
+		mockUserStore.On("GetUserByEmail", mock.Anything, "test@test.com").Return(&types.User{ID: 1, Password: hashedPassword, EmailVerifiedAt: verifiedAt}, nil)
 		mockAuth.On("ComparePasswords", hashedPassword, []byte("password123")).Return(true)
-		mockAuth.On("CreateJWT", mock.Anything, 1).Return("", fmt.Errorf("error creating token"))
+		mockAuth.On("NeedsRehash", hashedPassword).Return(false)
+		mockUserStore.On("ListMemberships", mock.Anything, 1).Return([]types.Membership{{OrgID: 1, UserID: 1}}, nil)
+		mockUserStore.On("CreateSession", mock.Anything, mock.AnythingOfType("types.Session")).Return(nil)
+		mockAuth.On("CreateJWT", mock.Anything, 1, 1, mock.Anything).Return("", fmt.Errorf("error creating token"))
 
 		user := types.LoginUserPayload{
 			Email:    "test@test.com",
@@ -254,5 +269,162 @@ func TestUserService(t *testing.T) {
 			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusInternalServerError)
 		}
 	})
+
+	t.Run("user without an organization", func(t *testing.T) {
+		//arrange
+		mockUserStore := new(mockUserStore)
+		mockAuth := new(MockAuthenticator)
+
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
+
+		hashedPassword := "$2a$12$examplebcryptpasswordhash"
+		verifiedAt := sql.NullTime{Time: time.Now(), Valid: true}
+		mockUserStore.On("GetUserByEmail", mock.Anything, "test@test.com").Return(&types.User{ID: 1, Password: hashedPassword, EmailVerifiedAt: verifiedAt}, nil)
+		mockAuth.On("ComparePasswords", hashedPassword, []byte("password123")).Return(true)
+		mockAuth.On("NeedsRehash", hashedPassword).Return(false)
+		mockUserStore.On("ListMemberships", mock.Anything, 1).Return([]types.Membership{}, nil)
+
+		userData, _ := json.Marshal(types.LoginUserPayload{Email: "test@test.com", Password: "password123"})
+
+		// act
+		req, _ := http.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(userData))
+		req.Header.Set("Content-Type", "application/json")
+
+		rr := httptest.NewRecorder()
+		handler.handleLogin(rr, req)
+
+		// assert
+		if status := rr.Code; status != http.StatusForbidden {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusForbidden)
+		}
+		mockAuth.AssertNotCalled(t, "CreateJWT", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
 	//TODO: 18-07-24 ozgen : add register tests
 }
+
+func TestUserService_Login_BruteForceProtection(t *testing.T) {
+	login := func(handler *Handler, email, password string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"email":"%s","password":"%s"}`, email, password)
+		req, _ := http.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+		req.RemoteAddr = "203.0.113.1:4000"
+		rr := httptest.NewRecorder()
+		handler.handleLogin(rr, req)
+		return rr
+	}
+
+	t.Run("unknown email and wrong password look the same", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		mockAuth := new(MockAuthenticator)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
+
+		mockUserStore.On("GetUserByEmail", mock.Anything, "known@test.com").Return(&types.User{ID: 1, Password: "hash"}, nil)
+		mockUserStore.On("GetUserByEmail", mock.Anything, "unknown@test.com").Return(nil, fmt.Errorf("user not found"))
+		mockAuth.On("ComparePasswords", mock.Anything, mock.Anything).Return(false)
+
+		// act
+		known := login(handler, "known@test.com", "wrong")
+		unknown := login(handler, "unknown@test.com", "wrong")
+
+		// assert
+		if known.Code != unknown.Code || known.Body.String() != unknown.Body.String() {
+			t.Errorf("expected identical responses, got %d %q and %d %q", known.Code, known.Body.String(), unknown.Code, unknown.Body.String())
+		}
+		mockAuth.AssertNumberOfCalls(t, "ComparePasswords", 2)
+	})
+
+	t.Run("repeated failures are rejected with retry after", func(t *testing.T) {
+		// arrange
+		mockUserStore := new(mockUserStore)
+		mockAuth := new(MockAuthenticator)
+		handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
+		handler.throttle = newLoginThrottle(ThrottlePolicy{
+			FreeAttempts:     1,
+			BaseDelay:        time.Minute,
+			MaxDelay:         time.Hour,
+			LockoutThreshold: 5,
+			LockoutDuration:  time.Hour,
+		}, ThrottlePolicy{FreeAttempts: 100, LockoutThreshold: 100, LockoutDuration: time.Hour})
+
+		mockUserStore.On("GetUserByEmail", mock.Anything, "test@test.com").Return(&types.User{ID: 1, Password: "hash"}, nil)
+		mockAuth.On("ComparePasswords", "hash", []byte("wrong")).Return(false)
+
+		// act
+		first := login(handler, "test@test.com", "wrong")
+		second := login(handler, "test@test.com", "wrong")
+		third := login(handler, "TEST@test.com", "correct")
+
+		// assert
+		if first.Code != http.StatusBadRequest || second.Code != http.StatusBadRequest {
+			t.Errorf("expected failures to be rejected with %d, got %d and %d", http.StatusBadRequest, first.Code, second.Code)
+		}
+		if third.Code != http.StatusTooManyRequests {
+			t.Errorf("expected status code %d, got %d", http.StatusTooManyRequests, third.Code)
+		}
+		if third.Header().Get("Retry-After") != "60" {
+			t.Errorf("expected Retry-After of 60 seconds, got %q", third.Header().Get("Retry-After"))
+		}
+		mockAuth.AssertNumberOfCalls(t, "ComparePasswords", 2)
+	})
+}
+
+func TestUserService_Login_DisabledAccount(t *testing.T) {
+	// arrange
+	mockUserStore := new(mockUserStore)
+	mockAuth := new(MockAuthenticator)
+	handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
+
+	disabled := &types.User{
+		ID:              1,
+		Password:        "hash",
+		EmailVerifiedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		DisabledAt:      sql.NullTime{Time: time.Now(), Valid: true},
+	}
+	mockUserStore.On("GetUserByEmail", mock.Anything, "test@test.com").Return(disabled, nil)
+	mockAuth.On("ComparePasswords", "hash", []byte("password123")).Return(true)
+
+	req, _ := http.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email":"test@test.com","password":"password123"}`))
+	rr := httptest.NewRecorder()
+
+	// act
+	handler.handleLogin(rr, req)
+
+	// assert
+	if status := rr.Code; status != http.StatusForbidden {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusForbidden)
+	}
+	mockAuth.AssertNotCalled(t, "CreateJWT", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserService_Login_UpgradesPasswordHash(t *testing.T) {
+	// arrange
+	mockUserStore := new(mockUserStore)
+	mockAuth := new(MockAuthenticator)
+	handler := NewHandler(mockUserStore, mockAuth, new(mockMailer))
+
+	legacy := &types.User{
+		ID:              1,
+		Password:        "$2a$10$legacybcrypthash",
+		EmailVerifiedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	}
+	mockUserStore.On("GetUserByEmail", mock.Anything, "test@test.com").Return(legacy, nil)
+	mockAuth.On("ComparePasswords", legacy.Password, []byte("password123")).Return(true)
+	mockAuth.On("NeedsRehash", legacy.Password).Return(true)
+	mockAuth.On("HashPassword", "password123").Return("$argon2id$new", nil)
+	mockUserStore.On("UpdatePassword", mock.Anything, 1, "$argon2id$new").Return(nil)
+	mockUserStore.On("ListMemberships", mock.Anything, 1).Return([]types.Membership{{OrgID: 1, UserID: 1}}, nil)
+	mockUserStore.On("CreateSession", mock.Anything, mock.AnythingOfType("types.Session")).Return(nil)
+	mockAuth.On("CreateJWT", mock.Anything, 1, 1, mock.Anything).Return("token", nil)
+
+	req, _ := http.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email":"test@test.com","password":"password123"}`))
+	rr := httptest.NewRecorder()
+
+	// act
+	handler.handleLogin(rr, req)
+
+	// assert
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	mockUserStore.AssertCalled(t, "UpdatePassword", mock.Anything, 1, "$argon2id$new")
+}
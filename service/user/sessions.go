@@ -0,0 +1,80 @@
+package user
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	auth2 "go-sample-rest-api/service/auth"
+	"go-sample-rest-api/utils"
+	"net/http"
+)
+
+// handleListSessions godoc
+// @Summary List my sessions
+// @Description Lists the devices the authenticated user is logged in on, most recently used first.
+// @Tags users
+// @Produce json
+// @Success 200 {array} types.Session "Active sessions; current marks the session of this request."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /users/me/sessions [get]
+func (h *Handler) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := h.store.ListSessions(r.Context(), auth2.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	current := auth2.GetSessionIDFromContext(r.Context())
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+
+	utils.WriteJSON(w, http.StatusOK, sessions)
+}
+
+// handleRevokeSession godoc
+// @Summary Revoke a session
+// @Description Logs the authenticated user out on one device. Its access token is rejected from then on.
+// @Tags users
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 204 {object} nil "Session revoked."
+// @Failure 400 {object} types.HTTPError "Bad Request if the session ID is not a UUID."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 404 {object} types.HTTPError "Not Found if the user has no such active session."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /users/me/sessions/{id} [delete]
+func (h *Handler) handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	sessionID := mux.Vars(r)["sessionID"]
+	if _, err := uuid.Parse(sessionID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid session ID: %v", err))
+		return
+	}
+
+	if err := h.store.RevokeSession(r.Context(), auth2.GetUserIDFromContext(r.Context()), sessionID); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRevokeOtherSessions godoc
+// @Summary Revoke all other sessions
+// @Description Logs the authenticated user out everywhere except on the device making the request.
+// @Tags users
+// @Produce json
+// @Success 204 {object} nil "Other sessions revoked."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /users/me/sessions [delete]
+func (h *Handler) handleRevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if err := h.store.RevokeOtherSessions(r.Context(), auth2.GetUserIDFromContext(ctx), auth2.GetSessionIDFromContext(ctx)); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
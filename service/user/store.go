@@ -1,14 +1,47 @@
 package user
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
+	"go-sample-rest-api/audit"
+	"go-sample-rest-api/customerrors"
 	"go-sample-rest-api/db"
 	"go-sample-rest-api/logging"
 	"go-sample-rest-api/types"
+	"strconv"
+	"strings"
 )
 
+const userColumns = "id, firstName, lastName, email, password, createdAt, emailVerifiedAt, totpSecret, totpEnabledAt, disabledAt"
+
+// memberColumns are the userColumns of a users row joined with organization_members.
+const memberColumns = userColumns + ", role"
+
+// inOrganization restricts a statement on users to the members of the
+// organization passed as the given parameter.
+func inOrganization(param int) string {
+	return fmt.Sprintf("id IN (SELECT user_id FROM organization_members WHERE org_id = $%d)", param)
+}
+
+// checkNotShared fails if the user belongs to organizations other than orgID.
+// The users row is shared by all of them, so an admin of one must not
+// disable, delete or erase the account for the others.
+func checkNotShared(ctx context.Context, tx db.DB, orgID, userID int) error {
+	var others int
+	err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM organization_members WHERE user_id = $1 AND org_id <> $2", userID, orgID).Scan(&others)
+	if err != nil {
+		return err
+	}
+
+	if others > 0 {
+		return &customerrors.SharedUserError{ID: strconv.Itoa(userID)}
+	}
+	return nil
+}
+
 type Store struct {
 	db db.DB
 }
@@ -17,9 +50,20 @@ func NewStore(db db.DB) *Store {
 	return &Store{db: db}
 }
 
-func (s *Store) CreateUser(user types.User) error {
+// CreateUser creates the user together with a new organization of the given
+// name, which the user administers. It is a single statement, so there is
+// never a user without an organization.
+func (s *Store) CreateUser(ctx context.Context, user types.User, organizationName string) error {
 	log := logging.GetLogger()
-	_, err := s.db.Exec("INSERT INTO users (firstName, lastName, email, password) VALUES ($1, $2, $3, $4)", user.FirstName, user.LastName, user.Email, user.Password)
+	query := `WITH new_user AS (
+                  INSERT INTO users (firstName, lastName, email, password) VALUES ($1, $2, $3, $4) RETURNING id
+              ), new_org AS (
+                  INSERT INTO organizations (name) VALUES ($5) RETURNING id
+              )
+              INSERT INTO organization_members (org_id, user_id, role)
+              SELECT new_org.id, new_user.id, $6 FROM new_org, new_user`
+
+	_, err := s.db.ExecContext(ctx, query, user.FirstName, user.LastName, user.Email, user.Password, organizationName, types.RoleAdmin)
 	if err != nil {
 		log.WithFields(logrus.Fields{
 			"error": err,
@@ -34,8 +78,8 @@ func (s *Store) CreateUser(user types.User) error {
 	return nil
 }
 
-func (s *Store) GetUserByEmail(email string) (*types.User, error) {
-	rows, err := s.db.Query("SELECT * FROM users WHERE email = $1", email)
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (*types.User, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+userColumns+" FROM users WHERE email = $1", email)
 	if err != nil {
 		return nil, err
 	}
@@ -55,8 +99,8 @@ func (s *Store) GetUserByEmail(email string) (*types.User, error) {
 	return u, nil
 }
 
-func (s *Store) GetUserByID(id int) (*types.User, error) {
-	rows, err := s.db.Query("SELECT * FROM users WHERE id = $1", id)
+func (s *Store) GetUserByID(ctx context.Context, id int) (*types.User, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1", id)
 	if err != nil {
 		return nil, err
 	}
@@ -76,20 +120,722 @@ func (s *Store) GetUserByID(id int) (*types.User, error) {
 	return u, nil
 }
 
+// CreateOrganizationUser creates the user as a member of an existing organization.
+func (s *Store) CreateOrganizationUser(ctx context.Context, orgID int, user types.User, role string, entry types.AuditEntry) error {
+	log := logging.GetLogger()
+	query := `WITH new_user AS (
+                  INSERT INTO users (firstName, lastName, email, password) VALUES ($1, $2, $3, $4) RETURNING id
+              )
+              INSERT INTO organization_members (org_id, user_id, role)
+              SELECT $5, id, $6 FROM new_user RETURNING user_id`
+
+	err := s.db.WithTx(ctx, func(tx db.DB) error {
+		var userID int
+		if err := tx.QueryRowContext(ctx, query, user.FirstName, user.LastName, user.Email, user.Password, orgID, role).Scan(&userID); err != nil {
+			return err
+		}
+
+		return writeAudit(ctx, tx, entry, orgID, userID, nil, map[string]any{"role": role})
+	})
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error": err,
+			"orgID": orgID,
+			"email": user.Email,
+		}).Error("Failed to create user in organization")
+		return err
+	}
+
+	log.WithFields(logrus.Fields{
+		"orgID": orgID,
+		"email": user.Email,
+	}).Info("User created in organization")
+	return nil
+}
+
+// GetOrganizationUser returns a member of the organization with their role in it.
+func (s *Store) GetOrganizationUser(ctx context.Context, orgID, userID int) (*types.User, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+memberColumns+" FROM users JOIN organization_members ON user_id = id WHERE org_id = $1 AND id = $2",
+		orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, &customerrors.NotFoundError{ID: strconv.Itoa(userID)}
+	}
+
+	return scanRowsIntoMember(rows)
+}
+
+// GetOrganizationUsers returns the members among the users with the given IDs,
+// ordered by ID. Users that are not members are left out.
+func (s *Store) GetOrganizationUsers(ctx context.Context, orgID int, userIDs []int) ([]types.User, error) {
+	ids := make([]int64, len(userIDs))
+	for i, id := range userIDs {
+		ids[i] = int64(id)
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+memberColumns+" FROM users JOIN organization_members ON user_id = id WHERE org_id = $1 AND id = ANY($2) ORDER BY id",
+		orgID, pq.Array(ids))
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"error": err,
+			"orgID": orgID,
+		}).Error("Failed to get organization users")
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []types.User{}
+	for rows.Next() {
+		u, err := scanRowsIntoMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *u)
+	}
+
+	return users, rows.Err()
+}
+
+// ListMemberships returns the organizations of the user, oldest membership first.
+func (s *Store) ListMemberships(ctx context.Context, userID int) ([]types.Membership, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT org_id, user_id, role, created_at FROM organization_members WHERE user_id = $1 ORDER BY created_at, org_id", userID)
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"error":  err,
+			"userID": userID,
+		}).Error("Failed to list memberships")
+		return nil, err
+	}
+	defer rows.Close()
+
+	memberships := make([]types.Membership, 0)
+	for rows.Next() {
+		var m types.Membership
+		if err := rows.Scan(&m.OrgID, &m.UserID, &m.Role, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, m)
+	}
+
+	return memberships, rows.Err()
+}
+
+// SetUserRole changes the role of a member of the organization.
+func (s *Store) SetUserRole(ctx context.Context, orgID, userID int, role string, entry types.AuditEntry) error {
+	log := logging.GetLogger()
+	err := s.db.WithTx(ctx, func(tx db.DB) error {
+		var before string
+		err := tx.QueryRowContext(ctx, "SELECT role FROM organization_members WHERE org_id = $1 AND user_id = $2 FOR UPDATE", orgID, userID).Scan(&before)
+		if err == sql.ErrNoRows {
+			return &customerrors.NotFoundError{ID: strconv.Itoa(userID)}
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE organization_members SET role = $1 WHERE org_id = $2 AND user_id = $3", role, orgID, userID); err != nil {
+			return err
+		}
+
+		return writeAudit(ctx, tx, entry, orgID, userID, map[string]any{"role": before}, map[string]any{"role": role})
+	})
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error":  err,
+			"orgID":  orgID,
+			"userID": userID,
+		}).Error("Failed to change role of user")
+		return err
+	}
+
+	log.WithFields(logrus.Fields{
+		"orgID":  orgID,
+		"userID": userID,
+		"role":   role,
+	}).Info("User role changed")
+	return nil
+}
+
+// UpdateUser saves the name and email of the user. Changing the email clears
+// its verification, because the new address has not been confirmed yet.
+func (s *Store) UpdateUser(ctx context.Context, user types.User) error {
+	log := logging.GetLogger()
+	query := `UPDATE users
+              SET firstName = $1, lastName = $2, email = $3,
+                  emailVerifiedAt = CASE WHEN email = $3 THEN emailVerifiedAt ELSE NULL END
+              WHERE id = $4`
+
+	_, err := s.db.ExecContext(ctx, query, user.FirstName, user.LastName, user.Email, user.ID)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error":  err,
+			"userID": user.ID,
+		}).Error("Failed to update user")
+		return err
+	}
+
+	log.WithFields(logrus.Fields{
+		"userID": user.ID,
+	}).Info("User updated successfully")
+	return nil
+}
+
+// ListUsers returns one page of the members of the organization ordered by ID
+// and the total number of members matching the search.
+func (s *Store) ListUsers(ctx context.Context, orgID int, query types.UserListQuery) ([]types.User, int, error) {
+	from := " FROM users JOIN organization_members ON user_id = id WHERE org_id = $1"
+	args := []any{orgID}
+	if query.Search != "" {
+		from += " AND (firstName ILIKE $2 OR lastName ILIKE $2 OR email ILIKE $2)"
+		args = append(args, "%"+escapeLike(query.Search)+"%")
+	}
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*)"+from, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, query.Limit, query.Offset)
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT %s%s ORDER BY id LIMIT $%d OFFSET $%d",
+		memberColumns, from, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []types.User{}
+	for rows.Next() {
+		u, err := scanRowsIntoMember(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, *u)
+	}
+
+	return users, total, rows.Err()
+}
+
+func (s *Store) SetUserDisabled(ctx context.Context, orgID, userID int, disabled bool, entry types.AuditEntry) error {
+	log := logging.GetLogger()
+	query := "UPDATE users SET disabledAt = NULL WHERE id = $1"
+	if disabled {
+		query = "UPDATE users SET disabledAt = COALESCE(disabledAt, CURRENT_TIMESTAMP) WHERE id = $1"
+	}
+
+	err := s.db.WithTx(ctx, func(tx db.DB) error {
+		var before bool
+		err := tx.QueryRowContext(ctx, "SELECT disabledAt IS NOT NULL FROM users WHERE id = $1 AND "+inOrganization(2)+" FOR UPDATE", userID, orgID).Scan(&before)
+		if err == sql.ErrNoRows {
+			return &customerrors.NotFoundError{ID: strconv.Itoa(userID)}
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := checkNotShared(ctx, tx, orgID, userID); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return err
+		}
+
+		return writeAudit(ctx, tx, entry, orgID, userID, map[string]any{"disabled": before}, map[string]any{"disabled": disabled})
+	})
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error":  err,
+			"userID": userID,
+		}).Error("Failed to change disabled state of user")
+		return err
+	}
+
+	log.WithFields(logrus.Fields{
+		"userID":   userID,
+		"disabled": disabled,
+	}).Info("User disabled state changed")
+	return nil
+}
+
+// DeleteUser removes a member of the organization. Tokens, recovery codes and
+// memberships are removed by the database cascade.
+func (s *Store) DeleteUser(ctx context.Context, orgID, userID int, entry types.AuditEntry) error {
+	log := logging.GetLogger()
+	err := s.db.WithTx(ctx, func(tx db.DB) error {
+		var role string
+		err := tx.QueryRowContext(ctx, "SELECT role FROM organization_members WHERE org_id = $1 AND user_id = $2 FOR UPDATE", orgID, userID).Scan(&role)
+		if err == sql.ErrNoRows {
+			return &customerrors.NotFoundError{ID: strconv.Itoa(userID)}
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := checkNotShared(ctx, tx, orgID, userID); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = $1", userID); err != nil {
+			return err
+		}
+
+		return writeAudit(ctx, tx, entry, orgID, userID, map[string]any{"role": role}, nil)
+	})
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error":  err,
+			"userID": userID,
+		}).Error("Failed to delete user")
+		return err
+	}
+
+	log.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Info("User deleted")
+	return nil
+}
+
+// AnonymizeUser erases the personal data of the user but keeps the row, so
+// references to the ID stay valid. The account is disabled and can no longer
+// log in, and its tokens, sessions and recovery codes are deleted. The IP
+// addresses of the audit entries the user made are cleared; the entries keep
+// the user ID as actor, which only points at the anonymized row. Only members
+// of the organization that belong to no other one can be anonymized.
+func (s *Store) AnonymizeUser(ctx context.Context, orgID, userID int, entry types.AuditEntry) error {
+	log := logging.GetLogger()
+	query := `UPDATE users
+              SET firstName = '', lastName = '', email = $1, password = '',
+                  emailVerifiedAt = NULL, totpSecret = NULL, totpEnabledAt = NULL, totpLastStep = NULL,
+                  disabledAt = COALESCE(disabledAt, CURRENT_TIMESTAMP), erasedAt = CURRENT_TIMESTAMP
+              WHERE id = $2 AND ` + inOrganization(3)
+
+	err := s.db.WithTx(ctx, func(tx db.DB) error {
+		res, err := tx.ExecContext(ctx, query, fmt.Sprintf("erased-%d@invalid", userID), userID, orgID)
+		if err != nil {
+			return err
+		}
+
+		if n, _ := res.RowsAffected(); n == 0 {
+			return &customerrors.NotFoundError{ID: strconv.Itoa(userID)}
+		}
+
+		// rolls the anonymization back
+		if err := checkNotShared(ctx, tx, orgID, userID); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM user_tokens WHERE user_id = $1", userID); err != nil {
+			return err
+		}
+
+		// sessions hold the IP addresses and user agents of the user
+		if _, err := tx.ExecContext(ctx, "DELETE FROM user_sessions WHERE user_id = $1", userID); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM user_recovery_codes WHERE user_id = $1", userID); err != nil {
+			return err
+		}
+
+		if err := writeAudit(ctx, tx, entry, orgID, userID, map[string]any{"erased": false}, map[string]any{"erased": true}); err != nil {
+			return err
+		}
+
+		// after the entry of the erasure, which holds the IP when users erase themselves
+		_, err = tx.ExecContext(ctx, "UPDATE audit_log SET ip = '' WHERE actor_type = $1 AND actor_id = $2 AND ip <> ''",
+			types.AuditActorUser, strconv.Itoa(userID))
+		return err
+	})
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error":  err,
+			"userID": userID,
+		}).Error("Failed to anonymize user")
+		return err
+	}
+
+	log.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Info("User anonymized")
+	return nil
+}
+
+func (s *Store) UpdatePassword(ctx context.Context, userID int, hashedPassword string) error {
+	log := logging.GetLogger()
+	_, err := s.db.ExecContext(ctx, "UPDATE users SET password = $1 WHERE id = $2", hashedPassword, userID)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error":  err,
+			"userID": userID,
+		}).Error("Failed to update password")
+		return err
+	}
+
+	log.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Info("Password updated successfully")
+	return nil
+}
+
+// ChangePassword sets the password and revokes the sessions of the user in one
+// transaction, so a stolen token does not outlive the old password. The
+// session keepSessionID stays active; with an empty ID every session is revoked.
+func (s *Store) ChangePassword(ctx context.Context, userID int, hashedPassword string, keepSessionID string) error {
+	log := logging.GetLogger()
+	err := s.db.WithTx(ctx, func(tx db.DB) error {
+		if _, err := tx.ExecContext(ctx, "UPDATE users SET password = $1 WHERE id = $2", hashedPassword, userID); err != nil {
+			return err
+		}
+
+		if keepSessionID == "" {
+			_, err := tx.ExecContext(ctx, "UPDATE user_sessions SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL", userID)
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "UPDATE user_sessions SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL",
+			userID, keepSessionID)
+		return err
+	})
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error":  err,
+			"userID": userID,
+		}).Error("Failed to change password")
+		return err
+	}
+
+	log.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Info("Password changed and sessions revoked")
+	return nil
+}
+
+func (s *Store) MarkEmailVerified(ctx context.Context, userID int) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE users SET emailVerifiedAt = CURRENT_TIMESTAMP WHERE id = $1 AND emailVerifiedAt IS NULL", userID)
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"error":  err,
+			"userID": userID,
+		}).Error("Failed to mark email as verified")
+		return err
+	}
+
+	return nil
+}
+
+// CreateUserToken stores a new token and invalidates any unused token with the
+// same purpose, so only the most recently sent link works.
+func (s *Store) CreateUserToken(ctx context.Context, token types.UserToken) error {
+	err := s.db.WithTx(ctx, func(tx db.DB) error {
+		_, err := tx.ExecContext(ctx, "UPDATE user_tokens SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL",
+			token.UserID, token.Purpose)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, "INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at) VALUES ($1, $2, $3, $4)",
+			token.UserID, token.Purpose, token.TokenHash, token.ExpiresAt)
+		return err
+	})
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"error":   err,
+			"userID":  token.UserID,
+			"purpose": token.Purpose,
+		}).Error("Failed to create user token")
+		return err
+	}
+
+	return nil
+}
+
+// GetUserToken returns an unused, unexpired token without redeeming it.
+func (s *Store) GetUserToken(ctx context.Context, purpose string, tokenHash string) (*types.UserToken, error) {
+	query := `SELECT id, user_id, purpose, token_hash, expires_at, used_at, created_at FROM user_tokens
+              WHERE purpose = $1 AND token_hash = $2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP`
+
+	t := new(types.UserToken)
+	err := s.db.QueryRowContext(ctx, query, purpose, tokenHash).
+		Scan(&t.ID, &t.UserID, &t.Purpose, &t.TokenHash, &t.ExpiresAt, &t.UsedAt, &t.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("invalid or expired token")
+		}
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// ConsumeUserToken marks an unused, unexpired token as used and returns it. The
+// update is a single statement, so a token cannot be redeemed twice.
+func (s *Store) ConsumeUserToken(ctx context.Context, purpose string, tokenHash string) (*types.UserToken, error) {
+	query := `UPDATE user_tokens SET used_at = CURRENT_TIMESTAMP
+              WHERE purpose = $1 AND token_hash = $2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+              RETURNING id, user_id, purpose, token_hash, expires_at, used_at, created_at`
+
+	t := new(types.UserToken)
+	err := s.db.QueryRowContext(ctx, query, purpose, tokenHash).
+		Scan(&t.ID, &t.UserID, &t.Purpose, &t.TokenHash, &t.ExpiresAt, &t.UsedAt, &t.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("invalid or expired token")
+		}
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// SetTOTPSecret stores the secret of a pending enrollment. It does not replace
+// the secret while two-factor authentication is enabled.
+func (s *Store) SetTOTPSecret(ctx context.Context, userID int, secret string) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE users SET totpSecret = $1, totpLastStep = NULL WHERE id = $2 AND totpEnabledAt IS NULL", secret, userID)
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"error":  err,
+			"userID": userID,
+		}).Error("Failed to store TOTP secret")
+		return err
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("two-factor authentication is already enabled")
+	}
+
+	return nil
+}
+
+// EnableTOTP replaces the recovery codes and turns on two-factor authentication.
+// The step of the code that confirmed the enrollment is recorded as used.
+func (s *Store) EnableTOTP(ctx context.Context, userID int, step int64, recoveryCodeHashes []string) error {
+	log := logging.GetLogger()
+	err := s.db.WithTx(ctx, func(tx db.DB) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM user_recovery_codes WHERE user_id = $1", userID); err != nil {
+			return err
+		}
+
+		for _, hash := range recoveryCodeHashes {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)", userID, hash); err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.ExecContext(ctx, "UPDATE users SET totpEnabledAt = CURRENT_TIMESTAMP, totpLastStep = $1 WHERE id = $2 AND totpSecret IS NOT NULL", step, userID)
+		return err
+	})
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error":  err,
+			"userID": userID,
+		}).Error("Failed to enable two-factor authentication")
+		return err
+	}
+
+	log.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Info("Two-factor authentication enabled")
+	return nil
+}
+
+func (s *Store) DisableTOTP(ctx context.Context, userID int) error {
+	log := logging.GetLogger()
+	err := s.db.WithTx(ctx, func(tx db.DB) error {
+		if _, err := tx.ExecContext(ctx, "UPDATE users SET totpSecret = NULL, totpEnabledAt = NULL, totpLastStep = NULL WHERE id = $1", userID); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, "DELETE FROM user_recovery_codes WHERE user_id = $1", userID)
+		return err
+	})
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error":  err,
+			"userID": userID,
+		}).Error("Failed to disable two-factor authentication")
+		return err
+	}
+
+	log.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Info("Two-factor authentication disabled")
+	return nil
+}
+
+// UseTOTPStep records the time step of an accepted code. It fails if that step
+// or a later one was used already, so every code works only once.
+func (s *Store) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE users SET totpLastStep = $1 WHERE id = $2 AND (totpLastStep IS NULL OR totpLastStep < $1)", step, userID)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("code already used")
+	}
+
+	return nil
+}
+
+// ConsumeRecoveryCode marks an unused recovery code of the user as used.
+func (s *Store) ConsumeRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE user_recovery_codes SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL", userID, codeHash)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("invalid recovery code")
+	}
+
+	return nil
+}
+
+func (s *Store) CreateSession(ctx context.Context, session types.Session) error {
+	_, err := s.db.ExecContext(ctx, "INSERT INTO user_sessions (id, user_id, user_agent, ip, expires_at) VALUES ($1, $2, $3, $4, $5)",
+		session.ID, session.UserID, session.UserAgent, session.IP, session.ExpiresAt)
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"error":  err,
+			"userID": session.UserID,
+		}).Error("Failed to create session")
+	}
+
+	return err
+}
+
+// TouchSession records that the session was used. It fails if the session does
+// not belong to the user, was revoked or has expired.
+func (s *Store) TouchSession(ctx context.Context, userID int, sessionID string) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE user_sessions SET last_used_at = CURRENT_TIMESTAMP
+              WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP`, sessionID, userID)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("session revoked or expired")
+	}
+
+	return nil
+}
+
+// ListSessions returns the active sessions of the user, most recently used first.
+func (s *Store) ListSessions(ctx context.Context, userID int) ([]types.Session, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, user_id, user_agent, ip, created_at, last_used_at, expires_at FROM user_sessions
+              WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+              ORDER BY last_used_at DESC`, userID)
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"error":  err,
+			"userID": userID,
+		}).Error("Failed to list sessions")
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]types.Session, 0)
+	for rows.Next() {
+		var session types.Session
+		err := rows.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IP,
+			&session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+func (s *Store) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE user_sessions SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL",
+		sessionID, userID)
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"error":  err,
+			"userID": userID,
+		}).Error("Failed to revoke session")
+		return err
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return &customerrors.NotFoundError{ID: sessionID}
+	}
+
+	return nil
+}
+
+// RevokeOtherSessions revokes every session of the user except keepSessionID.
+func (s *Store) RevokeOtherSessions(ctx context.Context, userID int, keepSessionID string) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE user_sessions SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL",
+		userID, keepSessionID)
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"error":  err,
+			"userID": userID,
+		}).Error("Failed to revoke sessions")
+	}
+
+	return err
+}
+
+// writeAudit completes the entry for a user and appends it to the audit log.
+// Only roles and states are recorded, so erasing a user leaves no personal
+// data in the log.
+func writeAudit(ctx context.Context, tx db.DB, entry types.AuditEntry, orgID, userID int, before, after map[string]any) error {
+	entry.OrgID = orgID
+	entry.ResourceType = "user"
+	entry.ResourceID = strconv.Itoa(userID)
+	entry.Changes = audit.Changes(before, after)
+	return audit.Write(ctx, tx, entry)
+}
+
+// escapeLike escapes the LIKE wildcards, so a search matches them literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
 func scanRowsIntoUser(rows *sql.Rows) (*types.User, error) {
 	user := new(types.User)
 
-	err := rows.Scan(
+	err := rows.Scan(userFields(user)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// scanRowsIntoMember reads the memberColumns, the user and their role.
+func scanRowsIntoMember(rows *sql.Rows) (*types.User, error) {
+	user := new(types.User)
+
+	err := rows.Scan(append(userFields(user), &user.Role)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func userFields(user *types.User) []any {
+	return []any{
 		&user.ID,
 		&user.FirstName,
 		&user.LastName,
 		&user.Email,
 		&user.Password,
 		&user.CreatedAt,
-	)
-	if err != nil {
-		return nil, err
+		&user.EmailVerifiedAt,
+		&user.TOTPSecret,
+		&user.TOTPEnabledAt,
+		&user.DisabledAt,
 	}
-
-	return user, nil
 }
@@ -1,9 +1,11 @@
 package user
 
 import (
-	_ "database/sql"
-	_ "errors"
+	"context"
+	"database/sql"
+	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
+	"go-sample-rest-api/customerrors"
 	db2 "go-sample-rest-api/db"
 	"go-sample-rest-api/types"
 	"reflect"
@@ -38,12 +40,12 @@ func TestStore_CreateUser(t *testing.T) {
 		Password:  "securepassword",
 	}
 
-	mock.ExpectExec("INSERT INTO users").
-		WithArgs(user.FirstName, user.LastName, user.Email, user.Password).
+	mock.ExpectExec("INSERT INTO users (.+) INSERT INTO organizations (.+) INSERT INTO organization_members").
+		WithArgs(user.FirstName, user.LastName, user.Email, user.Password, "John Doe", types.RoleAdmin).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	// act
-	err := store.CreateUser(user)
+	err := store.CreateUser(context.Background(), user, "John Doe")
 
 	// assert
 	if err != nil {
@@ -72,15 +74,15 @@ func TestStore_GetUserByEmail(t *testing.T) {
 		CreatedAt: time.Now(),
 	}
 
-	rows := sqlmock.NewRows([]string{"id", "firstName", "lastName", "email", "password", "createdAt"}).
-		AddRow(expectedUser.ID, expectedUser.FirstName, expectedUser.LastName, expectedUser.Email, expectedUser.Password, expectedUser.CreatedAt)
+	rows := sqlmock.NewRows([]string{"id", "firstName", "lastName", "email", "password", "createdAt", "emailVerifiedAt", "totpSecret", "totpEnabledAt", "disabledAt"}).
+		AddRow(expectedUser.ID, expectedUser.FirstName, expectedUser.LastName, expectedUser.Email, expectedUser.Password, expectedUser.CreatedAt, expectedUser.EmailVerifiedAt, expectedUser.TOTPSecret, expectedUser.TOTPEnabledAt, expectedUser.DisabledAt)
 
-	mock.ExpectQuery("SELECT \\* FROM users WHERE email =").
+	mock.ExpectQuery("SELECT (.+) FROM users WHERE email =").
 		WithArgs(email).
 		WillReturnRows(rows)
 
 	// act
-	user, err := store.GetUserByEmail(email)
+	user, err := store.GetUserByEmail(context.Background(), email)
 
 	// assert
 	if err != nil {
@@ -112,15 +114,15 @@ func TestStore_GetUserByID(t *testing.T) {
 		CreatedAt: time.Now(),
 	}
 
-	rows := sqlmock.NewRows([]string{"id", "firstName", "lastName", "email", "password", "createdAt"}).
-		AddRow(expectedUser.ID, expectedUser.FirstName, expectedUser.LastName, expectedUser.Email, expectedUser.Password, expectedUser.CreatedAt)
+	rows := sqlmock.NewRows([]string{"id", "firstName", "lastName", "email", "password", "createdAt", "emailVerifiedAt", "totpSecret", "totpEnabledAt", "disabledAt"}).
+		AddRow(expectedUser.ID, expectedUser.FirstName, expectedUser.LastName, expectedUser.Email, expectedUser.Password, expectedUser.CreatedAt, expectedUser.EmailVerifiedAt, expectedUser.TOTPSecret, expectedUser.TOTPEnabledAt, expectedUser.DisabledAt)
 
-	mock.ExpectQuery("SELECT \\* FROM users WHERE id =").
+	mock.ExpectQuery("SELECT (.+) FROM users WHERE id =").
 		WithArgs(ID).
 		WillReturnRows(rows)
 
 	// act
-	user, err := store.GetUserByID(ID)
+	user, err := store.GetUserByID(context.Background(), ID)
 
 	// assert
 	if err != nil {
@@ -135,3 +137,861 @@ func TestStore_GetUserByID(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestStore_GetUserByID_withCanceledContext(t *testing.T) {
+	// arrange
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	store := NewStore(db)
+	mock.ExpectQuery("SELECT (.+) FROM users WHERE id =").
+		WithArgs(1).
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// act
+	_, err := store.GetUserByID(ctx, 1)
+
+	// assert
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the query to be canceled, got %v", err)
+	}
+}
+
+func TestStore_CreateOrganizationUser(t *testing.T) {
+	// arrange
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	store := NewStore(db)
+	user := types.User{FirstName: "Jane", LastName: "Doe", Email: "jane@example.com", Password: "hash"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO users (.+) INSERT INTO organization_members (.+) SELECT \\$5, id, \\$6").
+		WithArgs(user.FirstName, user.LastName, user.Email, user.Password, 5, types.RoleUser).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(7))
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(5, "user", "1", "user.invite", "user", "7",
+			types.AuditChanges{"role": {After: types.RoleUser}}, "", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	// act
+	err := store.CreateOrganizationUser(context.Background(), 5, user, types.RoleUser, types.AuditEntry{ActorType: "user", ActorID: "1", Action: "user.invite"})
+
+	// assert
+	if err != nil {
+		t.Errorf("error was not expected while creating user: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStore_GetOrganizationUser(t *testing.T) {
+	columns := []string{"id", "firstName", "lastName", "email", "password", "createdAt", "emailVerifiedAt", "totpSecret", "totpEnabledAt", "disabledAt", "role"}
+
+	t.Run("returns the member with their role", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		mock.ExpectQuery("SELECT (.+) FROM users JOIN organization_members ON user_id = id WHERE org_id = \\$1 AND id = \\$2").
+			WithArgs(5, 1).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(1, "John", "Doe", "john@example.com", "hash", time.Now(), nil, nil, nil, nil, "admin"))
+
+		// act
+		user, err := store.GetOrganizationUser(context.Background(), 5, 1)
+
+		// assert
+		if err != nil {
+			t.Fatalf("error was not expected while getting user: %s", err)
+		}
+		if user.ID != 1 || user.Role != types.RoleAdmin {
+			t.Errorf("unexpected user %+v", user)
+		}
+	})
+
+	t.Run("fails for a user of another organization", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		mock.ExpectQuery("SELECT (.+) FROM users JOIN organization_members").
+			WithArgs(5, 2).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		// act
+		_, err := store.GetOrganizationUser(context.Background(), 5, 2)
+
+		// assert
+		if _, ok := err.(*customerrors.NotFoundError); !ok {
+			t.Errorf("expected a NotFoundError, got %v", err)
+		}
+	})
+}
+
+func TestStore_GetOrganizationUsers(t *testing.T) {
+	// arrange
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	store := NewStore(db)
+	mock.ExpectQuery("SELECT (.+) FROM users JOIN organization_members ON user_id = id WHERE org_id = \\$1 AND id = ANY\\(\\$2\\) ORDER BY id").
+		WithArgs(5, "{1,2}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "firstName", "lastName", "email", "password", "createdAt", "emailVerifiedAt", "totpSecret", "totpEnabledAt", "disabledAt", "role"}).
+			AddRow(1, "John", "Doe", "john@example.com", "hash", time.Now(), nil, nil, nil, nil, "admin"))
+
+	// act
+	users, err := store.GetOrganizationUsers(context.Background(), 5, []int{1, 2})
+
+	// assert
+	if err != nil {
+		t.Fatalf("error was not expected while getting users: %s", err)
+	}
+	if len(users) != 1 || users[0].Role != types.RoleAdmin {
+		t.Errorf("unexpected users %+v", users)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStore_ListMemberships(t *testing.T) {
+	// arrange
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	store := NewStore(db)
+	mock.ExpectQuery("SELECT org_id, user_id, role, created_at FROM organization_members WHERE user_id = \\$1 ORDER BY created_at, org_id").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"org_id", "user_id", "role", "created_at"}).
+			AddRow(5, 1, "admin", time.Now()).
+			AddRow(8, 1, "user", time.Now()))
+
+	// act
+	memberships, err := store.ListMemberships(context.Background(), 1)
+
+	// assert
+	if err != nil {
+		t.Fatalf("error was not expected while listing memberships: %s", err)
+	}
+	if len(memberships) != 2 || memberships[0].OrgID != 5 || memberships[1].Role != types.RoleUser {
+		t.Errorf("unexpected memberships %+v", memberships)
+	}
+}
+
+func TestStore_SetUserRole(t *testing.T) {
+	// arrange
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	store := NewStore(db)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT role FROM organization_members WHERE org_id = \\$1 AND user_id = \\$2 FOR UPDATE").
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(types.RoleUser))
+	mock.ExpectExec("UPDATE organization_members SET role = \\$1 WHERE org_id = \\$2 AND user_id = \\$3").
+		WithArgs(types.RoleAdmin, 5, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(5, "user", "1", "user.set_role", "user", "1",
+			types.AuditChanges{"role": {Before: types.RoleUser, After: types.RoleAdmin}}, "", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT role FROM organization_members").
+		WithArgs(5, 99).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}))
+	mock.ExpectRollback()
+
+	// act
+	entry := types.AuditEntry{ActorType: "user", ActorID: "1", Action: "user.set_role"}
+	err := store.SetUserRole(context.Background(), 5, 1, types.RoleAdmin, entry)
+	missingErr := store.SetUserRole(context.Background(), 5, 99, types.RoleAdmin, entry)
+
+	// assert
+	if err != nil {
+		t.Errorf("error was not expected while changing the role: %s", err)
+	}
+	if _, ok := missingErr.(*customerrors.NotFoundError); !ok {
+		t.Errorf("expected a NotFoundError for a user outside the organization, got %v", missingErr)
+	}
+}
+
+func TestStore_CreateUserToken(t *testing.T) {
+	// arrange
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	store := NewStore(db)
+	token := types.UserToken{
+		UserID:    1,
+		Purpose:   types.TokenPurposePasswordReset,
+		TokenHash: "hash",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE user_tokens SET used_at").
+		WithArgs(token.UserID, token.Purpose).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO user_tokens").
+		WithArgs(token.UserID, token.Purpose, token.TokenHash, token.ExpiresAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	// act
+	err := store.CreateUserToken(context.Background(), token)
+
+	// assert
+	if err != nil {
+		t.Errorf("error was not expected while creating token: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStore_GetUserToken(t *testing.T) {
+	// arrange
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	store := NewStore(db)
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "user_id", "purpose", "token_hash", "expires_at", "used_at", "created_at"}).
+		AddRow(4, 1, types.TokenPurposePasswordReset, "hash", now.Add(time.Hour), nil, now)
+
+	mock.ExpectQuery("SELECT (.+) FROM user_tokens (.+) used_at IS NULL AND expires_at > CURRENT_TIMESTAMP").
+		WithArgs(types.TokenPurposePasswordReset, "hash").
+		WillReturnRows(rows)
+
+	// act
+	token, err := store.GetUserToken(context.Background(), types.TokenPurposePasswordReset, "hash")
+
+	// assert
+	if err != nil {
+		t.Fatalf("error was not expected while reading token: %s", err)
+	}
+	if token.UserID != 1 || token.UsedAt.Valid {
+		t.Errorf("unexpected token returned: %+v", token)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStore_ConsumeUserToken(t *testing.T) {
+	t.Run("returns the token when it is unused", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		now := time.Now()
+		rows := sqlmock.NewRows([]string{"id", "user_id", "purpose", "token_hash", "expires_at", "used_at", "created_at"}).
+			AddRow(4, 1, types.TokenPurposeEmailVerification, "hash", now.Add(time.Hour), now, now)
+
+		mock.ExpectQuery("UPDATE user_tokens SET used_at (.+) used_at IS NULL AND expires_at > CURRENT_TIMESTAMP").
+			WithArgs(types.TokenPurposeEmailVerification, "hash").
+			WillReturnRows(rows)
+
+		// act
+		token, err := store.ConsumeUserToken(context.Background(), types.TokenPurposeEmailVerification, "hash")
+
+		// assert
+		if err != nil {
+			t.Fatalf("error was not expected while consuming token: %s", err)
+		}
+		if token.UserID != 1 || !token.UsedAt.Valid {
+			t.Errorf("unexpected token returned: %+v", token)
+		}
+	})
+
+	t.Run("fails when the token is used, expired or unknown", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		mock.ExpectQuery("UPDATE user_tokens SET used_at").
+			WithArgs(types.TokenPurposeEmailVerification, "hash").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		// act
+		token, err := store.ConsumeUserToken(context.Background(), types.TokenPurposeEmailVerification, "hash")
+
+		// assert
+		if err == nil || token != nil {
+			t.Errorf("expected an error for an unusable token, got %+v", token)
+		}
+	})
+}
+
+func TestStore_EnableTOTP(t *testing.T) {
+	// arrange
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	store := NewStore(db)
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM user_recovery_codes").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO user_recovery_codes").
+		WithArgs(1, "hash1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO user_recovery_codes").
+		WithArgs(1, "hash2").
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("UPDATE users SET totpEnabledAt = CURRENT_TIMESTAMP").
+		WithArgs(int64(42), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// act
+	err := store.EnableTOTP(context.Background(), 1, 42, []string{"hash1", "hash2"})
+
+	// assert
+	if err != nil {
+		t.Errorf("error was not expected while enabling TOTP: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStore_EnableTOTP_withFailingRecoveryCode_rollsBack(t *testing.T) {
+	// arrange
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	store := NewStore(db)
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM user_recovery_codes").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO user_recovery_codes").
+		WithArgs(1, "hash1").
+		WillReturnError(sql.ErrConnDone)
+	mock.ExpectRollback()
+
+	// act
+	err := store.EnableTOTP(context.Background(), 1, 42, []string{"hash1", "hash2"})
+
+	// assert
+	if err != sql.ErrConnDone {
+		t.Errorf("expected sql.ErrConnDone, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStore_DisableTOTP(t *testing.T) {
+	// arrange
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	store := NewStore(db)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE users SET totpSecret = NULL").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM user_recovery_codes").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 10))
+	mock.ExpectCommit()
+
+	// act
+	err := store.DisableTOTP(context.Background(), 1)
+
+	// assert
+	if err != nil {
+		t.Errorf("error was not expected while disabling TOTP: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStore_ChangePassword(t *testing.T) {
+	t.Run("keeps the current session", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE users SET password = ").
+			WithArgs("new-hash", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE user_sessions SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = (.+) AND id <> ").
+			WithArgs(1, "current-session").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		// act
+		err := store.ChangePassword(context.Background(), 1, "new-hash", "current-session")
+
+		// assert
+		if err != nil {
+			t.Errorf("error was not expected while changing the password: %s", err)
+		}
+
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("revokes every session without a current one", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE users SET password = ").
+			WithArgs("new-hash", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE user_sessions SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = \\$1 AND revoked_at IS NULL").
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectCommit()
+
+		// act
+		err := store.ChangePassword(context.Background(), 1, "new-hash", "")
+
+		// assert
+		if err != nil {
+			t.Errorf("error was not expected while changing the password: %s", err)
+		}
+
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("keeps the old password when revoking fails", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE users SET password = ").
+			WithArgs("new-hash", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE user_sessions").
+			WillReturnError(errors.New("connection reset"))
+		mock.ExpectRollback()
+
+		// act
+		err := store.ChangePassword(context.Background(), 1, "new-hash", "")
+
+		// assert
+		if err == nil {
+			t.Errorf("expected an error")
+		}
+
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+}
+
+func TestStore_UseTOTPStep(t *testing.T) {
+	t.Run("accepts a new step", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		mock.ExpectExec("UPDATE users SET totpLastStep = (.+) totpLastStep < ").
+			WithArgs(int64(43), 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		// act
+		err := store.UseTOTPStep(context.Background(), 1, 43)
+
+		// assert
+		if err != nil {
+			t.Errorf("error was not expected while using a TOTP step: %s", err)
+		}
+	})
+
+	t.Run("rejects a replayed step", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		mock.ExpectExec("UPDATE users SET totpLastStep").
+			WithArgs(int64(42), 1).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		// act
+		err := store.UseTOTPStep(context.Background(), 1, 42)
+
+		// assert
+		if err == nil {
+			t.Error("expected an error for a replayed step")
+		}
+	})
+}
+
+func TestStore_ConsumeRecoveryCode(t *testing.T) {
+	// arrange
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	store := NewStore(db)
+	mock.ExpectExec("UPDATE user_recovery_codes SET used_at (.+) used_at IS NULL").
+		WithArgs(1, "hash").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE user_recovery_codes SET used_at").
+		WithArgs(1, "hash").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// act
+	first := store.ConsumeRecoveryCode(context.Background(), 1, "hash")
+	second := store.ConsumeRecoveryCode(context.Background(), 1, "hash")
+
+	// assert
+	if first != nil {
+		t.Errorf("error was not expected while consuming recovery code: %s", first)
+	}
+	if second == nil {
+		t.Error("expected a recovery code to be usable only once")
+	}
+}
+
+func TestStore_UpdateUser(t *testing.T) {
+	// arrange
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	store := NewStore(db)
+	user := types.User{ID: 1, FirstName: "Jane", LastName: "Doe", Email: "jane@example.com"}
+	mock.ExpectExec("UPDATE users SET firstName = (.+) emailVerifiedAt = CASE WHEN email = \\$3 THEN emailVerifiedAt ELSE NULL END").
+		WithArgs(user.FirstName, user.LastName, user.Email, user.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// act
+	err := store.UpdateUser(context.Background(), user)
+
+	// assert
+	if err != nil {
+		t.Errorf("error was not expected while updating user: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStore_ListUsers(t *testing.T) {
+	columns := []string{"id", "firstName", "lastName", "email", "password", "createdAt", "emailVerifiedAt", "totpSecret", "totpEnabledAt", "disabledAt", "role"}
+
+	t.Run("returns a page and the total", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM users JOIN organization_members ON user_id = id WHERE org_id = \\$1$").
+			WithArgs(5).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery("SELECT (.+) FROM users JOIN organization_members (.+) ORDER BY id LIMIT \\$2 OFFSET \\$3").
+			WithArgs(5, 2, 0).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(1, "John", "Doe", "john@example.com", "hash", time.Now(), nil, nil, nil, nil, "admin").
+				AddRow(2, "Jane", "Doe", "jane@example.com", "hash", time.Now(), nil, nil, nil, nil, "user"))
+
+		// act
+		users, total, err := store.ListUsers(context.Background(), 5, types.UserListQuery{Limit: 2})
+
+		// assert
+		if err != nil {
+			t.Fatalf("error was not expected while listing users: %s", err)
+		}
+		if total != 3 || len(users) != 2 || users[0].Role != types.RoleAdmin {
+			t.Errorf("unexpected result: total %d, users %+v", total, users)
+		}
+	})
+
+	t.Run("searches with escaped wildcards", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM (.+) WHERE org_id = \\$1 AND \\(firstName ILIKE \\$2").
+			WithArgs(5, `%50\%\_off%`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery("SELECT (.+) FROM users (.+) LIMIT \\$3 OFFSET \\$4").
+			WithArgs(5, `%50\%\_off%`, 20, 40).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		// act
+		users, total, err := store.ListUsers(context.Background(), 5, types.UserListQuery{Search: "50%_off", Limit: 20, Offset: 40})
+
+		// assert
+		if err != nil {
+			t.Fatalf("error was not expected while listing users: %s", err)
+		}
+		if total != 0 || len(users) != 0 {
+			t.Errorf("unexpected result: total %d, users %+v", total, users)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unfulfilled expectations: %s", err)
+		}
+	})
+}
+
+func TestStore_SetUserDisabled(t *testing.T) {
+	// arrange
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	store := NewStore(db)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT disabledAt IS NOT NULL FROM users WHERE id = \\$1 AND id IN \\(SELECT user_id FROM organization_members WHERE org_id = \\$2\\) FOR UPDATE").
+		WithArgs(1, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"disabled"}).AddRow(false))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM organization_members WHERE user_id = \\$1 AND org_id <> \\$2").
+		WithArgs(1, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("UPDATE users SET disabledAt = COALESCE(.+) WHERE id = \\$1").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(5, "user", "1", "user.disable", "user", "1",
+			types.AuditChanges{"disabled": {Before: false, After: true}}, "", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT disabledAt IS NOT NULL FROM users").
+		WithArgs(99, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"disabled"}))
+	mock.ExpectRollback()
+
+	// act
+	disableErr := store.SetUserDisabled(context.Background(), 5, 1, true, types.AuditEntry{ActorType: "user", ActorID: "1", Action: "user.disable"})
+	missingErr := store.SetUserDisabled(context.Background(), 5, 99, false, types.AuditEntry{})
+
+	// assert
+	if disableErr != nil {
+		t.Errorf("error was not expected while disabling user: %s", disableErr)
+	}
+	if _, ok := missingErr.(*customerrors.NotFoundError); !ok {
+		t.Errorf("expected a NotFoundError for an unknown user, got %v", missingErr)
+	}
+}
+
+func TestStore_DeleteUser(t *testing.T) {
+	// arrange
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	store := NewStore(db)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT role FROM organization_members WHERE org_id = \\$1 AND user_id = \\$2 FOR UPDATE").
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(types.RoleUser))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM organization_members WHERE user_id = \\$1 AND org_id <> \\$2").
+		WithArgs(1, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("DELETE FROM users WHERE id = \\$1").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(5, "user", "2", "user.delete", "user", "1",
+			types.AuditChanges{"role": {Before: types.RoleUser}}, "", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	// act
+	err := store.DeleteUser(context.Background(), 5, 1, types.AuditEntry{ActorType: "user", ActorID: "2", Action: "user.delete"})
+
+	// assert
+	if err != nil {
+		t.Errorf("error was not expected while deleting user: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStore_DeleteUser_ofAnotherOrganization(t *testing.T) {
+	// arrange
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	store := NewStore(db)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT role FROM organization_members").
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(types.RoleUser))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM organization_members").
+		WithArgs(1, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectRollback()
+
+	// act
+	err := store.DeleteUser(context.Background(), 5, 1, types.AuditEntry{ActorType: "user", ActorID: "2", Action: "user.delete"})
+
+	// assert
+	if _, ok := err.(*customerrors.SharedUserError); !ok {
+		t.Errorf("expected a SharedUserError, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStore_AnonymizeUser(t *testing.T) {
+	// arrange
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	store := NewStore(db)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE users SET firstName = '', lastName = '', email = \\$1").
+		WithArgs("erased-1@invalid", 1, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM organization_members WHERE user_id = \\$1 AND org_id <> \\$2").
+		WithArgs(1, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("DELETE FROM user_tokens WHERE user_id = \\$1").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM user_sessions WHERE user_id = \\$1").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM user_recovery_codes WHERE user_id = \\$1").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(5, "user", "2", "user.erase", "user", "1",
+			types.AuditChanges{"erased": {Before: false, After: true}}, "", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE audit_log SET ip = '' WHERE actor_type = \\$1 AND actor_id = \\$2 AND ip <> ''").
+		WithArgs("user", "1").
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE users SET firstName = ''").
+		WithArgs("erased-99@invalid", 99, 5).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	// act
+	entry := types.AuditEntry{ActorType: "user", ActorID: "2", Action: "user.erase"}
+	err := store.AnonymizeUser(context.Background(), 5, 1, entry)
+	missingErr := store.AnonymizeUser(context.Background(), 5, 99, entry)
+
+	// assert
+	if err != nil {
+		t.Errorf("error was not expected while anonymizing user: %s", err)
+	}
+	if _, ok := missingErr.(*customerrors.NotFoundError); !ok {
+		t.Errorf("expected a NotFoundError for an unknown user, got %v", missingErr)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStore_TouchSession(t *testing.T) {
+	// arrange
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	store := NewStore(db)
+	mock.ExpectExec("UPDATE user_sessions SET last_used_at = CURRENT_TIMESTAMP").
+		WithArgs("active", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE user_sessions SET last_used_at = CURRENT_TIMESTAMP").
+		WithArgs("revoked", 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// act
+	activeErr := store.TouchSession(context.Background(), 1, "active")
+	revokedErr := store.TouchSession(context.Background(), 1, "revoked")
+
+	// assert
+	if activeErr != nil {
+		t.Errorf("error was not expected for an active session: %s", activeErr)
+	}
+	if revokedErr == nil {
+		t.Errorf("expected an error for a revoked session")
+	}
+}
+
+func TestStore_ListSessions(t *testing.T) {
+	// arrange
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	store := NewStore(db)
+	now := time.Now()
+	mock.ExpectQuery("SELECT id, user_id, user_agent, ip, created_at, last_used_at, expires_at FROM user_sessions").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "user_agent", "ip", "created_at", "last_used_at", "expires_at"}).
+			AddRow("s1", 1, "curl/8.0", "203.0.113.1", now, now, now.Add(time.Hour)))
+
+	// act
+	sessions, err := store.ListSessions(context.Background(), 1)
+
+	// assert
+	if err != nil {
+		t.Fatalf("error was not expected while listing sessions: %s", err)
+	}
+	if len(sessions) != 1 || sessions[0].UserAgent != "curl/8.0" {
+		t.Errorf("unexpected sessions: %+v", sessions)
+	}
+}
+
+func TestStore_RevokeSession(t *testing.T) {
+	// arrange
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	store := NewStore(db)
+	mock.ExpectExec("UPDATE user_sessions SET revoked_at = CURRENT_TIMESTAMP WHERE id = \\$1 AND user_id = \\$2").
+		WithArgs("other-users-session", 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE user_sessions SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = \\$1 AND id <> \\$2").
+		WithArgs(1, "current").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	// act
+	missingErr := store.RevokeSession(context.Background(), 1, "other-users-session")
+	othersErr := store.RevokeOtherSessions(context.Background(), 1, "current")
+
+	// assert
+	if _, ok := missingErr.(*customerrors.NotFoundError); !ok {
+		t.Errorf("expected a NotFoundError for a session of another user, got %v", missingErr)
+	}
+	if othersErr != nil {
+		t.Errorf("error was not expected while revoking sessions: %s", othersErr)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,148 @@
+package user
+
+import (
+	"fmt"
+	"go-sample-rest-api/config"
+	"go-sample-rest-api/utils"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ThrottlePolicy controls how failed logins for one key (an account or a client IP) are slowed down.
+type ThrottlePolicy struct {
+	// FreeAttempts is the number of failures allowed before any delay is applied.
+	FreeAttempts int
+	// BaseDelay is the delay after the first failure past FreeAttempts. It doubles with every further failure.
+	BaseDelay time.Duration
+	// MaxDelay caps the exponential backoff.
+	MaxDelay time.Duration
+	// LockoutThreshold is the number of failures after which the key is locked out.
+	LockoutThreshold int
+	// LockoutDuration is how long a lockout lasts. Failures are forgotten after this much inactivity.
+	LockoutDuration time.Duration
+}
+
+// DefaultThrottlePolicies returns the per-account and per-IP policies from the configuration.
+func DefaultThrottlePolicies() (account ThrottlePolicy, ip ThrottlePolicy) {
+	cfg := config.Envs
+	account = ThrottlePolicy{
+		FreeAttempts:     int(cfg.LoginFreeAttempts),
+		BaseDelay:        time.Duration(cfg.LoginBackoffBaseInSeconds) * time.Second,
+		MaxDelay:         time.Duration(cfg.LoginMaxBackoffInSeconds) * time.Second,
+		LockoutThreshold: int(cfg.LoginLockoutThreshold),
+		LockoutDuration:  time.Duration(cfg.LoginLockoutInSeconds) * time.Second,
+	}
+
+	// many users can share an address behind NAT, so the IP gets more headroom
+	ip = account
+	ip.FreeAttempts = int(cfg.LoginIPLockoutThreshold) / 2
+	ip.LockoutThreshold = int(cfg.LoginIPLockoutThreshold)
+
+	return account, ip
+}
+
+type attempts struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+}
+
+// loginThrottle tracks failed logins in memory, keyed by account and by client IP.
+type loginThrottle struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	account  ThrottlePolicy
+	ip       ThrottlePolicy
+	accounts map[string]*attempts
+	ips      map[string]*attempts
+}
+
+func newLoginThrottle(account, ip ThrottlePolicy) *loginThrottle {
+	return &loginThrottle{
+		now:      time.Now,
+		account:  account,
+		ip:       ip,
+		accounts: map[string]*attempts{},
+		ips:      map[string]*attempts{},
+	}
+}
+
+// RetryAfter returns how long the caller has to wait before the next attempt for
+// the account or from the IP is evaluated, or zero if it may proceed now.
+func (t *loginThrottle) RetryAfter(account, ip string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	wait := time.Duration(0)
+	for _, a := range []*attempts{t.accounts[account], t.ips[ip]} {
+		if a != nil && a.blockedUntil.After(now) && a.blockedUntil.Sub(now) > wait {
+			wait = a.blockedUntil.Sub(now)
+		}
+	}
+
+	return wait
+}
+
+// Failure records a failed login for the account and the IP.
+func (t *loginThrottle) Failure(account, ip string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.fail(t.accounts, account, t.account, now)
+	t.fail(t.ips, ip, t.ip, now)
+}
+
+// Success forgets the failures of the account. The IP keeps its record, so one
+// valid account cannot be used to reset the counter while guessing others.
+func (t *loginThrottle) Success(account string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.accounts, account)
+}
+
+func (t *loginThrottle) fail(records map[string]*attempts, key string, policy ThrottlePolicy, now time.Time) {
+	if len(records) > 10000 {
+		sweep(records, policy, now)
+	}
+
+	a, ok := records[key]
+	if !ok || now.Sub(a.lastFailure) > policy.LockoutDuration {
+		a = &attempts{}
+		records[key] = a
+	}
+
+	a.failures++
+	a.lastFailure = now
+
+	switch {
+	case a.failures >= policy.LockoutThreshold:
+		a.blockedUntil = now.Add(policy.LockoutDuration)
+	case a.failures > policy.FreeAttempts:
+		delay := policy.BaseDelay << (a.failures - policy.FreeAttempts - 1)
+		if delay > policy.MaxDelay || delay <= 0 {
+			delay = policy.MaxDelay
+		}
+		a.blockedUntil = now.Add(delay)
+	}
+}
+
+// sweep drops records whose failures have been forgotten, bounding memory use.
+func sweep(records map[string]*attempts, policy ThrottlePolicy, now time.Time) {
+	for key, a := range records {
+		if now.Sub(a.lastFailure) > policy.LockoutDuration && !a.blockedUntil.After(now) {
+			delete(records, key)
+		}
+	}
+}
+
+// writeTooManyAttempts answers a throttled request with 429 and a Retry-After header in whole seconds.
+func writeTooManyAttempts(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	utils.WriteError(w, http.StatusTooManyRequests, fmt.Errorf("too many failed attempts, try again later"))
+}
@@ -0,0 +1,107 @@
+package user
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestThrottle() (*loginThrottle, *time.Time) {
+	now := time.Date(2024, 7, 20, 12, 0, 0, 0, time.UTC)
+	policy := ThrottlePolicy{
+		FreeAttempts:     2,
+		BaseDelay:        time.Second,
+		MaxDelay:         8 * time.Second,
+		LockoutThreshold: 6,
+		LockoutDuration:  15 * time.Minute,
+	}
+	ipPolicy := policy
+	ipPolicy.FreeAttempts = 10
+	ipPolicy.LockoutThreshold = 20
+
+	throttle := newLoginThrottle(policy, ipPolicy)
+	throttle.now = func() time.Time { return now }
+	return throttle, &now
+}
+
+func TestLoginThrottle(t *testing.T) {
+	t.Run("free attempts are not delayed", func(t *testing.T) {
+		throttle, _ := newTestThrottle()
+
+		throttle.Failure("a@test.com", "1.2.3.4")
+		throttle.Failure("a@test.com", "1.2.3.4")
+
+		if wait := throttle.RetryAfter("a@test.com", "1.2.3.4"); wait != 0 {
+			t.Errorf("expected no delay, got %v", wait)
+		}
+	})
+
+	t.Run("delay doubles with every further failure", func(t *testing.T) {
+		throttle, now := newTestThrottle()
+		throttle.Failure("a@test.com", "1.2.3.4")
+		throttle.Failure("a@test.com", "1.2.3.4")
+
+		for _, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+			throttle.Failure("a@test.com", "1.2.3.4")
+			if wait := throttle.RetryAfter("a@test.com", "1.2.3.4"); wait != expected {
+				t.Errorf("expected delay %v, got %v", expected, wait)
+			}
+			*now = now.Add(expected)
+		}
+	})
+
+	t.Run("account is locked out after the threshold", func(t *testing.T) {
+		throttle, now := newTestThrottle()
+		for i := 0; i < 6; i++ {
+			throttle.Failure("a@test.com", "1.2.3.4")
+		}
+
+		if wait := throttle.RetryAfter("a@test.com", "5.6.7.8"); wait != 15*time.Minute {
+			t.Errorf("expected lockout from any IP, got %v", wait)
+		}
+
+		*now = now.Add(15 * time.Minute)
+		if wait := throttle.RetryAfter("a@test.com", "5.6.7.8"); wait != 0 {
+			t.Errorf("expected lockout to expire, got %v", wait)
+		}
+	})
+
+	t.Run("IP is throttled across accounts", func(t *testing.T) {
+		throttle, _ := newTestThrottle()
+		for i := 0; i < 11; i++ {
+			throttle.Failure("user"+string(rune('a'+i))+"@test.com", "1.2.3.4")
+		}
+
+		if wait := throttle.RetryAfter("fresh@test.com", "1.2.3.4"); wait == 0 {
+			t.Error("expected the IP to be delayed")
+		}
+		if wait := throttle.RetryAfter("fresh@test.com", "5.6.7.8"); wait != 0 {
+			t.Errorf("expected other IPs to be unaffected, got %v", wait)
+		}
+	})
+
+	t.Run("success resets the account", func(t *testing.T) {
+		throttle, _ := newTestThrottle()
+		for i := 0; i < 4; i++ {
+			throttle.Failure("a@test.com", "1.2.3.4")
+		}
+		throttle.Success("a@test.com")
+
+		if wait := throttle.RetryAfter("a@test.com", "5.6.7.8"); wait != 0 {
+			t.Errorf("expected no delay after success, got %v", wait)
+		}
+	})
+
+	t.Run("failures are forgotten after inactivity", func(t *testing.T) {
+		throttle, now := newTestThrottle()
+		for i := 0; i < 5; i++ {
+			throttle.Failure("a@test.com", "1.2.3.4")
+		}
+
+		*now = now.Add(16 * time.Minute)
+		throttle.Failure("a@test.com", "1.2.3.4")
+
+		if wait := throttle.RetryAfter("a@test.com", "5.6.7.8"); wait != 0 {
+			t.Errorf("expected counter to restart, got %v", wait)
+		}
+	})
+}
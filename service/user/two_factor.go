@@ -0,0 +1,255 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"go-sample-rest-api/config"
+	"go-sample-rest-api/logging"
+	auth2 "go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"go-sample-rest-api/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recoveryCodeCount is the number of recovery codes handed out when 2FA is enabled.
+const recoveryCodeCount = 10
+
+// handleLoginChallenge godoc
+// @Summary Complete two-factor login
+// @Description Exchanges the challenge token returned by /login and a TOTP or recovery code for a JWT.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param challenge body types.LoginChallengePayload true "Challenge token and code"
+// @Success 200 {object} map[string]string "token: JWT Token on successful login."
+// @Failure 400 {object} types.HTTPError "Bad Request when the payload is invalid or the code is wrong."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the challenge token is invalid or expired."
+// @Failure 429 {object} types.HTTPError "Too many failed attempts for the account or client; see Retry-After."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /login/2fa [post]
+func (h *Handler) handleLoginChallenge(w http.ResponseWriter, r *http.Request) {
+	var payload types.LoginChallengePayload
+	if !parseAndValidate(w, r, &payload) {
+		return
+	}
+
+	userID, err := h.auth.ValidateMFAChallenge(payload.ChallengeToken)
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid or expired challenge"))
+		return
+	}
+
+	u, err := h.store.GetUserByID(r.Context(), userID)
+	if err != nil || !u.TOTPEnabledAt.Valid || u.DisabledAt.Valid {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid or expired challenge"))
+		return
+	}
+
+	if !h.checkSecondFactor(w, r, u, payload.Code) {
+		return
+	}
+
+	secret := []byte(config.Envs.JWTSecret)
+	token, err := auth2.IssueAccessToken(h.auth, h.store, r, secret, u.ID)
+	if err != nil {
+		writeLoginError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"token": token})
+}
+
+// handleEnrollTOTP godoc
+// @Summary Start two-factor enrollment
+// @Description Generates a TOTP secret for the current user. Scan the provisioning URI as a QR code and confirm with /users/me/2fa/verify.
+// @Tags users
+// @Produce json
+// @Success 200 {object} types.TOTPEnrollment "Secret and otpauth provisioning URI."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 409 {object} types.HTTPError "Conflict if two-factor authentication is already enabled."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /users/me/2fa [post]
+func (h *Handler) handleEnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if u.TOTPEnabledAt.Valid {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("two-factor authentication is already enabled"))
+		return
+	}
+
+	secret, err := auth2.NewTOTPSecret()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := h.store.SetTOTPSecret(r.Context(), u.ID, secret); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: auth2.TOTPProvisioningURI(config.Envs.TOTPIssuer, u.Email, secret),
+	})
+}
+
+// handleVerifyTOTP godoc
+// @Summary Confirm two-factor enrollment
+// @Description Enables two-factor authentication once the authenticator app produces a valid code. The recovery codes are only shown in this response.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param code body types.TOTPCodePayload true "Current TOTP code"
+// @Success 200 {object} types.RecoveryCodes "Single-use recovery codes."
+// @Failure 400 {object} types.HTTPError "Bad Request if enrollment was not started or the code is wrong."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 409 {object} types.HTTPError "Conflict if two-factor authentication is already enabled."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /users/me/2fa/verify [post]
+func (h *Handler) handleVerifyTOTP(w http.ResponseWriter, r *http.Request) {
+	var payload types.TOTPCodePayload
+	if !parseAndValidate(w, r, &payload) {
+		return
+	}
+
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if u.TOTPEnabledAt.Valid {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("two-factor authentication is already enabled"))
+		return
+	}
+
+	if !u.TOTPSecret.Valid {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("two-factor enrollment was not started"))
+		return
+	}
+
+	step, ok := auth2.ValidateTOTP(u.TOTPSecret.String, payload.Code, time.Now())
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid code"))
+		return
+	}
+
+	codes, err := auth2.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth2.HashOpaqueToken(auth2.NormalizeRecoveryCode(code))
+	}
+
+	if err := h.store.EnableTOTP(r.Context(), u.ID, step, hashes); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.RecoveryCodes{RecoveryCodes: codes})
+}
+
+// handleDisableTOTP godoc
+// @Summary Disable two-factor authentication
+// @Description Turns off two-factor authentication after checking a TOTP or recovery code.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param code body types.TOTPCodePayload true "TOTP or recovery code"
+// @Success 200 {object} nil "Two-factor authentication disabled."
+// @Failure 400 {object} types.HTTPError "Bad Request if two-factor authentication is not enabled or the code is wrong."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 429 {object} types.HTTPError "Too many failed attempts; see Retry-After."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /users/me/2fa/disable [post]
+func (h *Handler) handleDisableTOTP(w http.ResponseWriter, r *http.Request) {
+	var payload types.TOTPCodePayload
+	if !parseAndValidate(w, r, &payload) {
+		return
+	}
+
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if !u.TOTPEnabledAt.Valid {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("two-factor authentication is not enabled"))
+		return
+	}
+
+	if !h.checkSecondFactor(w, r, u, payload.Code) {
+		return
+	}
+
+	if err := h.store.DisableTOTP(r.Context(), u.ID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+// checkSecondFactor verifies a TOTP or recovery code for the user. Failures are
+// throttled like password attempts for the same account, and a 400 or 429
+// response is written when the check does not pass.
+func (h *Handler) checkSecondFactor(w http.ResponseWriter, r *http.Request, u *types.User, code string) bool {
+	account := strings.ToLower(u.Email)
+	ip := utils.ClientIP(r, config.Envs.TrustProxyHeaders)
+	if wait := h.throttle.RetryAfter(account, ip); wait > 0 {
+		writeTooManyAttempts(w, wait)
+		return false
+	}
+
+	if !h.verifySecondFactor(r.Context(), u, code) {
+		h.throttle.Failure(account, ip)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid code"))
+		return false
+	}
+
+	h.throttle.Success(account)
+	return true
+}
+
+// verifySecondFactor accepts a TOTP code that was not used before or an unused recovery code.
+func (h *Handler) verifySecondFactor(ctx context.Context, u *types.User, code string) bool {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+
+	if _, err := strconv.Atoi(code); err == nil && len(code) == 6 {
+		step, ok := auth2.ValidateTOTP(u.TOTPSecret.String, code, time.Now())
+		return ok && h.store.UseTOTPStep(ctx, u.ID, step) == nil
+	}
+
+	if err := h.store.ConsumeRecoveryCode(ctx, u.ID, auth2.HashOpaqueToken(auth2.NormalizeRecoveryCode(code))); err != nil {
+		return false
+	}
+
+	logging.GetLogger().WithFields(logrus.Fields{
+		"userID": u.ID,
+	}).Info("Recovery code used")
+	return true
+}
+
+// currentUser loads the authenticated user, writing a 401 response if it no longer exists.
+func (h *Handler) currentUser(w http.ResponseWriter, r *http.Request) (*types.User, bool) {
+	u, err := h.store.GetUserByID(r.Context(), auth2.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("unauthorized"))
+		return nil, false
+	}
+
+	// the role belongs to the membership in the organization of the token
+	u.Role = auth2.GetUserRoleFromContext(r.Context())
+	return u, true
+}
@@ -0,0 +1,84 @@
+package webhook
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/types"
+	"time"
+)
+
+type mockWebhookStore struct {
+	mock.Mock
+}
+
+func (m *mockWebhookStore) CreateWebhook(ctx context.Context, webhook types.Webhook, entry types.AuditEntry) (*types.Webhook, error) {
+	args := m.Called(ctx, webhook, entry)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.Webhook), args.Error(1)
+}
+
+func (m *mockWebhookStore) GetWebhook(ctx context.Context, orgID, webhookID int) (*types.Webhook, error) {
+	args := m.Called(ctx, orgID, webhookID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*types.Webhook), args.Error(1)
+}
+
+func (m *mockWebhookStore) ListWebhooks(ctx context.Context, orgID int) ([]types.Webhook, error) {
+	args := m.Called(ctx, orgID)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.Webhook), args.Error(1)
+}
+
+func (m *mockWebhookStore) UpdateWebhook(ctx context.Context, webhook types.Webhook, entry types.AuditEntry) error {
+	args := m.Called(ctx, webhook, entry)
+	return args.Error(0)
+}
+
+func (m *mockWebhookStore) DeleteWebhook(ctx context.Context, orgID, webhookID int, entry types.AuditEntry) error {
+	args := m.Called(ctx, orgID, webhookID, entry)
+	return args.Error(0)
+}
+
+func (m *mockWebhookStore) ListDeliveries(ctx context.Context, orgID, webhookID int, status string, limit, offset int) ([]types.WebhookDelivery, int, error) {
+	args := m.Called(ctx, orgID, webhookID, status, limit, offset)
+	if args.Error(2) != nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]types.WebhookDelivery), args.Int(1), args.Error(2)
+}
+
+func (m *mockWebhookStore) RetryDelivery(ctx context.Context, orgID, webhookID int, deliveryID int64) error {
+	args := m.Called(ctx, orgID, webhookID, deliveryID)
+	return args.Error(0)
+}
+
+type mockOutbox struct {
+	mock.Mock
+}
+
+func (m *mockOutbox) PublishEvents(ctx context.Context, limit int) ([]types.Event, error) {
+	args := m.Called(ctx, limit)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]types.Event), args.Error(1)
+}
+
+func (m *mockOutbox) ClaimDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]Delivery, error) {
+	args := m.Called(ctx, now, limit, lease)
+	if args.Error(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]Delivery), args.Error(1)
+}
+
+func (m *mockOutbox) RecordAttempt(ctx context.Context, attempt Attempt) error {
+	args := m.Called(ctx, attempt)
+	return args.Error(0)
+}
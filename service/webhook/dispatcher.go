@@ -0,0 +1,303 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"go-sample-rest-api/config"
+	"go-sample-rest-api/logging"
+	"go-sample-rest-api/types"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// SignatureHeader carries the signature of a delivery, see Sign.
+const SignatureHeader = "X-Webhook-Signature"
+
+// Delivery is a claimed delivery with what is needed to make it.
+type Delivery struct {
+	ID       int64
+	Attempts int
+	URL      string
+	Secret   string
+	Event    types.Event
+}
+
+// Attempt is the outcome of an attempt to make a delivery. StatusCode is 0
+// when no response was received.
+type Attempt struct {
+	DeliveryID    int64
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	StatusCode    int
+	Error         string
+	At            time.Time
+}
+
+// Outbox publishes events and keeps track of their deliveries.
+type Outbox interface {
+	PublishEvents(ctx context.Context, limit int) ([]types.Event, error)
+	ClaimDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]Delivery, error)
+	RecordAttempt(ctx context.Context, attempt Attempt) error
+}
+
+type DispatcherConfig struct {
+	Interval    time.Duration
+	BatchSize   int
+	MaxAttempts int
+	BackoffBase time.Duration
+	MaxBackoff  time.Duration
+	Timeout     time.Duration
+	// Concurrency is the number of deliveries of a batch made at once.
+	Concurrency int
+	// AllowPrivateNetworks lets deliveries reach loopback, private and
+	// link-local addresses, which is only meant for development.
+	AllowPrivateNetworks bool
+}
+
+// LoadDispatcherConfig reads the WEBHOOK_* settings.
+func LoadDispatcherConfig(cfg config.Config) DispatcherConfig {
+	return DispatcherConfig{
+		Interval:    time.Duration(cfg.WebhookIntervalInSeconds) * time.Second,
+		BatchSize:   int(cfg.WebhookBatchSize),
+		MaxAttempts: int(cfg.WebhookMaxAttempts),
+		BackoffBase: time.Duration(cfg.WebhookBackoffBaseInSeconds) * time.Second,
+		MaxBackoff:  time.Duration(cfg.WebhookMaxBackoffInSeconds) * time.Second,
+		Timeout:     time.Duration(cfg.WebhookTimeoutInSeconds) * time.Second,
+		Concurrency: int(cfg.WebhookConcurrency),
+
+		AllowPrivateNetworks: cfg.WebhookAllowPrivateNetworks,
+	}
+}
+
+// Dispatcher publishes the events of the outbox and delivers them to the
+// subscribed webhooks. Failed deliveries are retried with exponential backoff
+// until MaxAttempts, after which they are dead. Several replicas can run a
+// dispatcher at the same time.
+type Dispatcher struct {
+	outbox Outbox
+	client *http.Client
+	cfg    DispatcherConfig
+}
+
+// NewDispatcher returns a dispatcher whose deliveries cannot reach the
+// network of the server, unless cfg allows it. Redirects are not followed, so
+// a 3xx answer fails the attempt.
+func NewDispatcher(outbox Outbox, cfg DispatcherConfig) *Dispatcher {
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivateNetworks {
+		dialer.Control = refusePrivateAddress
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would connect to the URL in place of the dialer
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	client := &http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return &Dispatcher{outbox: outbox, client: client, cfg: cfg}
+}
+
+// sharedPrefixes are internal ranges the netip.Addr predicates do not cover:
+// carrier-grade NAT and the NAT64 prefix, which maps to any IPv4 address.
+var sharedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// refusePrivateAddress is the net.Dialer Control of deliveries. It runs after
+// the host name is resolved, so a name that resolves to an internal address
+// is refused as well as the address itself.
+func refusePrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("refusing to connect to internal address %s", ip)
+	}
+	for _, prefix := range sharedPrefixes {
+		if prefix.Contains(ip) {
+			return fmt.Errorf("refusing to connect to internal address %s", ip)
+		}
+	}
+
+	return nil
+}
+
+// Run dispatches every Interval until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.Dispatch(ctx); err != nil {
+			logging.GetLogger().WithFields(logrus.Fields{
+				"error": err,
+			}).Error("Failed to dispatch webhooks")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch publishes all pending events and makes the deliveries that are due.
+func (d *Dispatcher) Dispatch(ctx context.Context) error {
+	for {
+		events, err := d.outbox.PublishEvents(ctx, d.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+		if len(events) < d.cfg.BatchSize {
+			break
+		}
+	}
+
+	concurrency := max(d.cfg.Concurrency, 1)
+	// A claimed delivery is not made again before every delivery of the
+	// batch could have timed out.
+	rounds := (d.cfg.BatchSize + concurrency - 1) / concurrency
+	lease := d.cfg.Timeout*time.Duration(rounds) + d.cfg.Interval
+	for {
+		deliveries, err := d.outbox.ClaimDeliveries(ctx, time.Now(), d.cfg.BatchSize, lease)
+		if err != nil {
+			return err
+		}
+
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, concurrency)
+		for _, delivery := range deliveries {
+			slots <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-slots }()
+				// a failure to record is logged by the store; the lease
+				// runs out and the delivery is made again
+				d.outbox.RecordAttempt(ctx, d.deliver(ctx, delivery))
+			}()
+		}
+		wg.Wait()
+		if len(deliveries) < d.cfg.BatchSize {
+			return nil
+		}
+	}
+}
+
+// deliver posts the event to the URL of the delivery. Any 2xx response counts
+// as delivered.
+func (d *Dispatcher) deliver(ctx context.Context, delivery Delivery) Attempt {
+	now := time.Now()
+	attempt := Attempt{DeliveryID: delivery.ID, Attempts: delivery.Attempts + 1, At: now}
+
+	statusCode, err := d.post(ctx, delivery, now)
+	attempt.StatusCode = statusCode
+	if err == nil && statusCode >= 200 && statusCode < 300 {
+		attempt.Status = types.DeliveryDelivered
+		attempt.NextAttemptAt = now
+		return attempt
+	}
+
+	if err != nil {
+		attempt.Error = err.Error()
+	} else {
+		attempt.Error = fmt.Sprintf("unexpected status code %d", statusCode)
+	}
+	if attempt.Attempts >= d.cfg.MaxAttempts {
+		attempt.Status = types.DeliveryDead
+		attempt.NextAttemptAt = now
+	} else {
+		attempt.Status = types.DeliveryPending
+		attempt.NextAttemptAt = now.Add(d.backoff(attempt.Attempts))
+	}
+
+	logging.GetLogger().WithFields(logrus.Fields{
+		"deliveryID": delivery.ID,
+		"attempts":   attempt.Attempts,
+		"status":     attempt.Status,
+		"error":      attempt.Error,
+	}).Warn("Webhook delivery failed")
+	return attempt
+}
+
+func (d *Dispatcher) post(ctx context.Context, delivery Delivery, now time.Time) (int, error) {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", delivery.Event.Type)
+	req.Header.Set("X-Webhook-Event-ID", strconv.FormatInt(delivery.Event.ID, 10))
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, now, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	return resp.StatusCode, nil
+}
+
+// backoff returns the wait after the failed attempt: BackoffBase doubled for
+// every earlier attempt, at most MaxBackoff.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.cfg.BackoffBase
+	for i := 1; i < attempts && wait < d.cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.cfg.MaxBackoff {
+		wait = d.cfg.MaxBackoff
+	}
+	return wait
+}
+
+// Sign returns the signature of a body sent at the time, in the form
+// t=<unix time>,v1=<hex HMAC-SHA256 of "<unix time>.<body>" with the secret>.
+// Receivers recompute it and reject old times to prevent replays.
+func Sign(secret string, at time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
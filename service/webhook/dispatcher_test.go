@@ -0,0 +1,266 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/types"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testConfig = DispatcherConfig{
+	Interval:    time.Second,
+	BatchSize:   10,
+	MaxAttempts: 3,
+	BackoffBase: time.Minute,
+	MaxBackoff:  10 * time.Minute,
+	Timeout:     time.Second,
+	Concurrency: 4,
+	// the test servers listen on loopback
+	AllowPrivateNetworks: true,
+}
+
+func testDelivery(url string, attempts int) Delivery {
+	return Delivery{
+		ID:       42,
+		Attempts: attempts,
+		URL:      url,
+		Secret:   "whsec_test",
+		Event: types.Event{
+			ID:    7,
+			Type:  types.EventCameraCreated,
+			OrgID: 3,
+			Data:  json.RawMessage(`{"cam_id":"cam-1"}`),
+		},
+	}
+}
+
+func TestDispatcher_Dispatch(t *testing.T) {
+	t.Run("delivers signed events", func(t *testing.T) {
+		// arrange
+		var signature, eventType string
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			signature = r.Header.Get(SignatureHeader)
+			eventType = r.Header.Get("X-Webhook-Event")
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		outbox := new(mockOutbox)
+		outbox.On("PublishEvents", mock.Anything, 10).Return([]types.Event{}, nil)
+		outbox.On("ClaimDeliveries", mock.Anything, mock.Anything, 10, 4*time.Second).
+			Return([]Delivery{testDelivery(server.URL, 0)}, nil)
+		var attempt Attempt
+		outbox.On("RecordAttempt", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) { attempt = args.Get(1).(Attempt) }).
+			Return(nil)
+
+		// act
+		err := NewDispatcher(outbox, testConfig).Dispatch(context.Background())
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, types.DeliveryDelivered, attempt.Status)
+		assert.Equal(t, 1, attempt.Attempts)
+		assert.Equal(t, http.StatusNoContent, attempt.StatusCode)
+		assert.Equal(t, types.EventCameraCreated, eventType)
+		assert.JSONEq(t, `{"id":7,"type":"camera.created","org_id":3,"data":{"cam_id":"cam-1"},"created_at":"0001-01-01T00:00:00Z"}`, string(body))
+		assert.Equal(t, Sign("whsec_test", attempt.At, body), signature)
+	})
+
+	t.Run("retries failed deliveries with backoff", func(t *testing.T) {
+		// arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		outbox := new(mockOutbox)
+		outbox.On("PublishEvents", mock.Anything, 10).Return([]types.Event{}, nil)
+		outbox.On("ClaimDeliveries", mock.Anything, mock.Anything, 10, mock.Anything).
+			Return([]Delivery{testDelivery(server.URL, 1)}, nil)
+		var attempt Attempt
+		outbox.On("RecordAttempt", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) { attempt = args.Get(1).(Attempt) }).
+			Return(nil)
+
+		// act
+		err := NewDispatcher(outbox, testConfig).Dispatch(context.Background())
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, types.DeliveryPending, attempt.Status)
+		assert.Equal(t, 2, attempt.Attempts)
+		assert.Equal(t, http.StatusBadGateway, attempt.StatusCode)
+		assert.Equal(t, "unexpected status code 502", attempt.Error)
+		assert.Equal(t, attempt.At.Add(2*time.Minute), attempt.NextAttemptAt)
+	})
+
+	t.Run("gives up after the last attempt", func(t *testing.T) {
+		// arrange
+		outbox := new(mockOutbox)
+		outbox.On("PublishEvents", mock.Anything, 10).Return([]types.Event{}, nil)
+		outbox.On("ClaimDeliveries", mock.Anything, mock.Anything, 10, mock.Anything).
+			Return([]Delivery{testDelivery("http://127.0.0.1:0", 2)}, nil)
+		var attempt Attempt
+		outbox.On("RecordAttempt", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) { attempt = args.Get(1).(Attempt) }).
+			Return(nil)
+
+		// act
+		err := NewDispatcher(outbox, testConfig).Dispatch(context.Background())
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, types.DeliveryDead, attempt.Status)
+		assert.Equal(t, 3, attempt.Attempts)
+		assert.Zero(t, attempt.StatusCode)
+		assert.NotEmpty(t, attempt.Error)
+	})
+
+	t.Run("refuses internal addresses", func(t *testing.T) {
+		// arrange
+		called := false
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		}))
+		defer server.Close()
+
+		outbox := new(mockOutbox)
+		outbox.On("PublishEvents", mock.Anything, 10).Return([]types.Event{}, nil)
+		outbox.On("ClaimDeliveries", mock.Anything, mock.Anything, 10, mock.Anything).
+			Return([]Delivery{testDelivery(server.URL, 0)}, nil)
+		var attempt Attempt
+		outbox.On("RecordAttempt", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) { attempt = args.Get(1).(Attempt) }).
+			Return(nil)
+		cfg := testConfig
+		cfg.AllowPrivateNetworks = false
+
+		// act
+		err := NewDispatcher(outbox, cfg).Dispatch(context.Background())
+
+		// assert
+		assert.NoError(t, err)
+		assert.False(t, called)
+		assert.Equal(t, types.DeliveryPending, attempt.Status)
+		assert.Contains(t, attempt.Error, "refusing to connect to internal address 127.0.0.1")
+	})
+
+	t.Run("does not follow redirects", func(t *testing.T) {
+		// arrange
+		redirected := false
+		target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			redirected = true
+		}))
+		defer target.Close()
+		server := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+		defer server.Close()
+
+		outbox := new(mockOutbox)
+		outbox.On("PublishEvents", mock.Anything, 10).Return([]types.Event{}, nil)
+		outbox.On("ClaimDeliveries", mock.Anything, mock.Anything, 10, mock.Anything).
+			Return([]Delivery{testDelivery(server.URL, 0)}, nil)
+		var attempt Attempt
+		outbox.On("RecordAttempt", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) { attempt = args.Get(1).(Attempt) }).
+			Return(nil)
+
+		// act
+		err := NewDispatcher(outbox, testConfig).Dispatch(context.Background())
+
+		// assert
+		assert.NoError(t, err)
+		assert.False(t, redirected)
+		assert.Equal(t, types.DeliveryPending, attempt.Status)
+		assert.Equal(t, http.StatusTemporaryRedirect, attempt.StatusCode)
+	})
+
+	t.Run("makes at most Concurrency deliveries at once", func(t *testing.T) {
+		// arrange
+		var inFlight, most atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				m := most.Load()
+				if n <= m || most.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		deliveries := make([]Delivery, 9)
+		for i := range deliveries {
+			deliveries[i] = testDelivery(server.URL, 0)
+		}
+		outbox := new(mockOutbox)
+		outbox.On("PublishEvents", mock.Anything, 10).Return([]types.Event{}, nil)
+		outbox.On("ClaimDeliveries", mock.Anything, mock.Anything, 10, mock.Anything).Return(deliveries, nil)
+		outbox.On("RecordAttempt", mock.Anything, mock.Anything).Return(nil)
+
+		// act
+		err := NewDispatcher(outbox, testConfig).Dispatch(context.Background())
+
+		// assert
+		assert.NoError(t, err)
+		outbox.AssertNumberOfCalls(t, "RecordAttempt", 9)
+		assert.Greater(t, most.Load(), int32(1))
+		assert.LessOrEqual(t, most.Load(), int32(testConfig.Concurrency))
+	})
+
+	t.Run("publishes until the outbox is empty", func(t *testing.T) {
+		// arrange
+		outbox := new(mockOutbox)
+		outbox.On("PublishEvents", mock.Anything, 10).Return(make([]types.Event, 10), nil).Once()
+		outbox.On("PublishEvents", mock.Anything, 10).Return(make([]types.Event, 3), nil).Once()
+		outbox.On("ClaimDeliveries", mock.Anything, mock.Anything, 10, mock.Anything).Return([]Delivery{}, nil)
+
+		// act
+		err := NewDispatcher(outbox, testConfig).Dispatch(context.Background())
+
+		// assert
+		assert.NoError(t, err)
+		outbox.AssertNumberOfCalls(t, "PublishEvents", 2)
+	})
+}
+
+func TestRefusePrivateAddress(t *testing.T) {
+	for _, address := range []string{"127.0.0.1:80", "[::1]:443", "10.1.2.3:80", "172.16.0.1:80", "192.168.1.1:80",
+		"169.254.169.254:80", "[fe80::1]:80", "0.0.0.0:80", "[::]:80", "[::ffff:127.0.0.1]:80", "[fd00::1]:80",
+		"100.64.0.1:80", "100.127.255.254:80", "[64:ff9b::a9fe:a9fe]:80"} {
+		assert.Error(t, refusePrivateAddress("tcp", address, nil), address)
+	}
+
+	for _, address := range []string{"93.184.216.34:443", "100.128.0.1:443", "[2606:2800:220:1:248:1893:25c8:1946]:443"} {
+		assert.NoError(t, refusePrivateAddress("tcp", address, nil), address)
+	}
+}
+
+func TestDispatcher_backoff(t *testing.T) {
+	d := NewDispatcher(nil, testConfig)
+
+	assert.Equal(t, time.Minute, d.backoff(1))
+	assert.Equal(t, 2*time.Minute, d.backoff(2))
+	assert.Equal(t, 8*time.Minute, d.backoff(4))
+	assert.Equal(t, 10*time.Minute, d.backoff(5))
+	assert.Equal(t, 10*time.Minute, d.backoff(50))
+}
+
+func TestSign(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+
+	// echo -n '1700000000.{}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "t=1700000000,v1=b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163", Sign("secret", at, []byte("{}")))
+}
@@ -0,0 +1,342 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"go-sample-rest-api/audit"
+	"go-sample-rest-api/customerrors"
+	auth2 "go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"go-sample-rest-api/utils"
+	"net/http"
+	"strconv"
+)
+
+// Actions of the audit entries of webhooks.
+const (
+	ActionCreateWebhook = "webhook.create"
+	ActionUpdateWebhook = "webhook.update"
+	ActionDeleteWebhook = "webhook.delete"
+)
+
+// Handler lets the admins of the organization of the access token manage its
+// webhooks and inspect their deliveries.
+type Handler struct {
+	store     types.WebhookStore
+	userStore types.UserStore
+}
+
+func NewHandler(store types.WebhookStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, userStore: userStore}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/webhooks", auth2.WithAdmin(h.handleListWebhooks, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/webhooks", auth2.WithAdmin(h.handleCreateWebhook, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/webhooks/{webhookID}", auth2.WithAdmin(h.handleGetWebhook, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/webhooks/{webhookID}", auth2.WithAdmin(h.handleUpdateWebhook, h.userStore)).Methods(http.MethodPatch)
+	router.HandleFunc("/webhooks/{webhookID}", auth2.WithAdmin(h.handleDeleteWebhook, h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/webhooks/{webhookID}/deliveries", auth2.WithAdmin(h.handleListDeliveries, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/webhooks/{webhookID}/deliveries/{deliveryID}/retry", auth2.WithAdmin(h.handleRetryDelivery, h.userStore)).Methods(http.MethodPost)
+}
+
+// handleListWebhooks godoc
+// @Summary List webhooks
+// @Description Returns the webhooks of the organization without their secrets. Admins only.
+// @Tags webhooks
+// @Produce json
+// @Success 200 {array} types.Webhook "The webhooks."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 403 {object} types.HTTPError "Forbidden if the user is not an admin."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /webhooks [get]
+func (h *Handler) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := h.store.ListWebhooks(r.Context(), auth2.GetOrgIDFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, webhooks)
+}
+
+// handleCreateWebhook godoc
+// @Summary Create a webhook
+// @Description Subscribes a URL to camera events of the organization. No event types subscribes to all of them. The response carries the signing secret, which is not shown again. Admins only.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body types.WebhookPayload true "Webhook"
+// @Success 201 {object} types.WebhookCreatedResponse "The new webhook with its secret."
+// @Failure 400 {object} types.HTTPError "Bad Request when the payload is invalid."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 403 {object} types.HTTPError "Forbidden if the user is not an admin."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /webhooks [post]
+func (h *Handler) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var payload types.WebhookPayload
+	if !parsePayload(w, r, &payload) {
+		return
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	webhook, err := h.store.CreateWebhook(r.Context(), types.Webhook{
+		OrgID:      auth2.GetOrgIDFromContext(r.Context()),
+		URL:        payload.URL,
+		EventTypes: types.EventTypes(payload.EventTypes),
+		Active:     true,
+		Secret:     secret,
+	}, audit.FromRequest(r, ActionCreateWebhook))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, types.WebhookCreatedResponse{Webhook: *webhook, Secret: webhook.Secret})
+}
+
+// handleGetWebhook godoc
+// @Summary Get a webhook
+// @Tags webhooks
+// @Produce json
+// @Param webhookID path int true "Webhook ID"
+// @Success 200 {object} types.Webhook "The webhook."
+// @Failure 400 {object} types.HTTPError "Bad Request when the ID is invalid."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 403 {object} types.HTTPError "Forbidden if the user is not an admin."
+// @Failure 404 {object} types.HTTPError "Not Found if the organization has no such webhook."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /webhooks/{webhookID} [get]
+func (h *Handler) handleGetWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookID, ok := parseID(w, r, "webhookID")
+	if !ok {
+		return
+	}
+
+	webhook, err := h.store.GetWebhook(r.Context(), auth2.GetOrgIDFromContext(r.Context()), webhookID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, webhook)
+}
+
+// handleUpdateWebhook godoc
+// @Summary Update a webhook
+// @Description Changes the URL, the event types or pauses the webhook with active false. A paused webhook gets no deliveries for the events in between. Admins only.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhookID path int true "Webhook ID"
+// @Param webhook body types.WebhookUpdatePayload true "Fields to change"
+// @Success 200 {object} types.Webhook "The updated webhook."
+// @Failure 400 {object} types.HTTPError "Bad Request when the ID or payload is invalid."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 403 {object} types.HTTPError "Forbidden if the user is not an admin."
+// @Failure 404 {object} types.HTTPError "Not Found if the organization has no such webhook."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /webhooks/{webhookID} [patch]
+func (h *Handler) handleUpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookID, ok := parseID(w, r, "webhookID")
+	if !ok {
+		return
+	}
+
+	var payload types.WebhookUpdatePayload
+	if !parsePayload(w, r, &payload) {
+		return
+	}
+
+	webhook, err := h.store.GetWebhook(r.Context(), auth2.GetOrgIDFromContext(r.Context()), webhookID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if payload.URL != nil {
+		webhook.URL = *payload.URL
+	}
+	if payload.EventTypes != nil {
+		webhook.EventTypes = types.EventTypes(*payload.EventTypes)
+	}
+	if payload.Active != nil {
+		webhook.Active = *payload.Active
+	}
+
+	if err := h.store.UpdateWebhook(r.Context(), *webhook, audit.FromRequest(r, ActionUpdateWebhook)); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, webhook)
+}
+
+// handleDeleteWebhook godoc
+// @Summary Delete a webhook
+// @Description Deletes the webhook and its deliveries. Admins only.
+// @Tags webhooks
+// @Param webhookID path int true "Webhook ID"
+// @Success 204 "The webhook was deleted."
+// @Failure 400 {object} types.HTTPError "Bad Request when the ID is invalid."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 403 {object} types.HTTPError "Forbidden if the user is not an admin."
+// @Failure 404 {object} types.HTTPError "Not Found if the organization has no such webhook."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /webhooks/{webhookID} [delete]
+func (h *Handler) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookID, ok := parseID(w, r, "webhookID")
+	if !ok {
+		return
+	}
+
+	if err := h.store.DeleteWebhook(r.Context(), auth2.GetOrgIDFromContext(r.Context()), webhookID, audit.FromRequest(r, ActionDeleteWebhook)); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListDeliveries godoc
+// @Summary List webhook deliveries
+// @Description Lists the deliveries of a webhook, newest first. status=dead shows the deliveries that ran out of attempts. Admins only.
+// @Tags webhooks
+// @Produce json
+// @Param webhookID path int true "Webhook ID"
+// @Param status query string false "pending, delivered or dead"
+// @Param page query int false "Page number, starting at 1"
+// @Param pageSize query int false "Deliveries per page (default 20, max 100)"
+// @Success 200 {object} types.WebhookDeliveryList "One page of deliveries."
+// @Failure 400 {object} types.HTTPError "Bad Request if a parameter is invalid."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 403 {object} types.HTTPError "Forbidden if the user is not an admin."
+// @Failure 404 {object} types.HTTPError "Not Found if the organization has no such webhook."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /webhooks/{webhookID}/deliveries [get]
+func (h *Handler) handleListDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhookID, ok := parseID(w, r, "webhookID")
+	if !ok {
+		return
+	}
+
+	page, pageSize, err := utils.GetPagination(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", types.DeliveryPending, types.DeliveryDelivered, types.DeliveryDead:
+	default:
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid status: %q", status))
+		return
+	}
+
+	orgID := auth2.GetOrgIDFromContext(r.Context())
+	if _, err := h.store.GetWebhook(r.Context(), orgID, webhookID); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	deliveries, total, err := h.store.ListDeliveries(r.Context(), orgID, webhookID, status, pageSize, (page-1)*pageSize)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.WebhookDeliveryList{
+		Deliveries: deliveries,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+	})
+}
+
+// handleRetryDelivery godoc
+// @Summary Retry a dead delivery
+// @Description Queues a dead delivery again with a fresh set of attempts. Admins only.
+// @Tags webhooks
+// @Param webhookID path int true "Webhook ID"
+// @Param deliveryID path int true "Delivery ID"
+// @Success 202 "The delivery was queued."
+// @Failure 400 {object} types.HTTPError "Bad Request when an ID is invalid."
+// @Failure 401 {object} types.HTTPError "Unauthorized if the token is missing, invalid or expired."
+// @Failure 403 {object} types.HTTPError "Forbidden if the user is not an admin."
+// @Failure 404 {object} types.HTTPError "Not Found if the webhook has no such dead delivery."
+// @Failure 500 {object} types.HTTPError "Internal Server Error"
+// @Router /webhooks/{webhookID}/deliveries/{deliveryID}/retry [post]
+func (h *Handler) handleRetryDelivery(w http.ResponseWriter, r *http.Request) {
+	webhookID, ok := parseID(w, r, "webhookID")
+	if !ok {
+		return
+	}
+	deliveryID, err := strconv.ParseInt(mux.Vars(r)["deliveryID"], 10, 64)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid deliveryID"))
+		return
+	}
+
+	if err := h.store.RetryDelivery(r.Context(), auth2.GetOrgIDFromContext(r.Context()), webhookID, deliveryID); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// newSecret returns a random signing secret.
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func parseID(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)[name])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid %s", name))
+		return 0, false
+	}
+
+	return id, true
+}
+
+func parsePayload(w http.ResponseWriter, r *http.Request, payload any) bool {
+	if err := utils.ParseJSON(r, payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return false
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", errors))
+		return false
+	}
+
+	return true
+}
+
+// writeStoreError answers 404 for a missing webhook or delivery and 500 for
+// anything else.
+func writeStoreError(w http.ResponseWriter, err error) {
+	var notFound *customerrors.NotFoundError
+	if errors.As(err, &notFound) {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteError(w, http.StatusInternalServerError, err)
+}
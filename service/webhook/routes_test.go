@@ -0,0 +1,277 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"go-sample-rest-api/customerrors"
+	"go-sample-rest-api/service/auth"
+	"go-sample-rest-api/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const orgID = 3
+
+func withOrganization(req *http.Request) *http.Request {
+	ctx := context.WithValue(req.Context(), auth.UserKey, 1)
+	return req.WithContext(context.WithValue(ctx, auth.OrgKey, orgID))
+}
+
+func webhooksRouter(handler *Handler) *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/webhooks", handler.handleListWebhooks).Methods(http.MethodGet)
+	router.HandleFunc("/webhooks", handler.handleCreateWebhook).Methods(http.MethodPost)
+	router.HandleFunc("/webhooks/{webhookID}", handler.handleGetWebhook).Methods(http.MethodGet)
+	router.HandleFunc("/webhooks/{webhookID}", handler.handleUpdateWebhook).Methods(http.MethodPatch)
+	router.HandleFunc("/webhooks/{webhookID}", handler.handleDeleteWebhook).Methods(http.MethodDelete)
+	router.HandleFunc("/webhooks/{webhookID}/deliveries", handler.handleListDeliveries).Methods(http.MethodGet)
+	router.HandleFunc("/webhooks/{webhookID}/deliveries/{deliveryID}/retry", handler.handleRetryDelivery).Methods(http.MethodPost)
+	return router
+}
+
+func TestWebhookService_Handle_Create(t *testing.T) {
+	t.Run("creates an active webhook and returns its secret once", func(t *testing.T) {
+		// arrange
+		store := new(mockWebhookStore)
+		handler := NewHandler(store, nil)
+
+		var created types.Webhook
+		store.On("CreateWebhook", mock.Anything, mock.AnythingOfType("types.Webhook"), mock.Anything).
+			Run(func(args mock.Arguments) {
+				created = args.Get(1).(types.Webhook)
+				created.ID = 5
+			}).
+			Return(&created, nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/webhooks",
+			strings.NewReader(`{"url":"https://example.com/hooks","event_types":["camera.created"]}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		webhooksRouter(handler).ServeHTTP(rr, withOrganization(req))
+
+		// assert
+		if status := rr.Code; status != http.StatusCreated {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusCreated)
+		}
+		if created.OrgID != orgID || !created.Active || created.URL != "https://example.com/hooks" ||
+			len(created.EventTypes) != 1 || !strings.HasPrefix(created.Secret, "whsec_") {
+			t.Errorf("unexpected webhook: %+v", created)
+		}
+		var response types.WebhookCreatedResponse
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		if response.Secret != created.Secret || response.ID != 5 {
+			t.Errorf("unexpected response: %s", rr.Body.String())
+		}
+		store.AssertExpectations(t)
+	})
+
+	t.Run("invalid payload", func(t *testing.T) {
+		for name, body := range map[string]string{
+			"missing url":        `{"event_types":[]}`,
+			"not a http url":     `{"url":"ftp://example.com"}`,
+			"unknown event type": `{"url":"https://example.com","event_types":["camera.deleted"]}`,
+		} {
+			t.Run(name, func(t *testing.T) {
+				// arrange
+				store := new(mockWebhookStore)
+				handler := NewHandler(store, nil)
+
+				req, _ := http.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(body))
+				rr := httptest.NewRecorder()
+
+				// act
+				webhooksRouter(handler).ServeHTTP(rr, withOrganization(req))
+
+				// assert
+				if status := rr.Code; status != http.StatusBadRequest {
+					t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+				}
+				store.AssertNotCalled(t, "CreateWebhook", mock.Anything, mock.Anything, mock.Anything)
+			})
+		}
+	})
+}
+
+func TestWebhookService_Handle_List(t *testing.T) {
+	t.Run("does not return secrets", func(t *testing.T) {
+		// arrange
+		store := new(mockWebhookStore)
+		handler := NewHandler(store, nil)
+
+		store.On("ListWebhooks", mock.Anything, orgID).
+			Return([]types.Webhook{{ID: 5, OrgID: orgID, URL: "https://example.com", Secret: "whsec_secret"}}, nil)
+
+		req, _ := http.NewRequest(http.MethodGet, "/webhooks", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		webhooksRouter(handler).ServeHTTP(rr, withOrganization(req))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		if strings.Contains(rr.Body.String(), "whsec_secret") {
+			t.Errorf("response contains the secret: %s", rr.Body.String())
+		}
+	})
+}
+
+func TestWebhookService_Handle_Update(t *testing.T) {
+	t.Run("changes only the given fields", func(t *testing.T) {
+		// arrange
+		store := new(mockWebhookStore)
+		handler := NewHandler(store, nil)
+
+		store.On("GetWebhook", mock.Anything, orgID, 5).Return(&types.Webhook{
+			ID: 5, OrgID: orgID, URL: "https://example.com", EventTypes: types.EventTypes{"camera.created"}, Active: true,
+		}, nil)
+		store.On("UpdateWebhook", mock.Anything, types.Webhook{
+			ID: 5, OrgID: orgID, URL: "https://example.com", EventTypes: types.EventTypes{"camera.created"}, Active: false,
+		}, mock.Anything).Return(nil)
+
+		req, _ := http.NewRequest(http.MethodPatch, "/webhooks/5", strings.NewReader(`{"active":false}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		webhooksRouter(handler).ServeHTTP(rr, withOrganization(req))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		store.AssertExpectations(t)
+	})
+
+	t.Run("unknown event type", func(t *testing.T) {
+		// arrange
+		store := new(mockWebhookStore)
+		handler := NewHandler(store, nil)
+
+		req, _ := http.NewRequest(http.MethodPatch, "/webhooks/5", strings.NewReader(`{"event_types":["nope"]}`))
+		rr := httptest.NewRecorder()
+
+		// act
+		webhooksRouter(handler).ServeHTTP(rr, withOrganization(req))
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+		store.AssertNotCalled(t, "UpdateWebhook", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestWebhookService_Handle_Delete(t *testing.T) {
+	t.Run("unknown webhook", func(t *testing.T) {
+		// arrange
+		store := new(mockWebhookStore)
+		handler := NewHandler(store, nil)
+
+		store.On("DeleteWebhook", mock.Anything, orgID, 9, mock.Anything).Return(&customerrors.NotFoundError{ID: "9"})
+
+		req, _ := http.NewRequest(http.MethodDelete, "/webhooks/9", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		webhooksRouter(handler).ServeHTTP(rr, withOrganization(req))
+
+		// assert
+		if status := rr.Code; status != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+		}
+	})
+}
+
+func TestWebhookService_Handle_ListDeliveries(t *testing.T) {
+	t.Run("lists the dead deliveries", func(t *testing.T) {
+		// arrange
+		store := new(mockWebhookStore)
+		handler := NewHandler(store, nil)
+
+		store.On("GetWebhook", mock.Anything, orgID, 5).Return(&types.Webhook{ID: 5, OrgID: orgID}, nil)
+		store.On("ListDeliveries", mock.Anything, orgID, 5, types.DeliveryDead, 10, 10).
+			Return([]types.WebhookDelivery{{ID: 42, WebhookID: 5, Status: types.DeliveryDead}}, 11, nil)
+
+		req, _ := http.NewRequest(http.MethodGet, "/webhooks/5/deliveries?status=dead&page=2&pageSize=10", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		webhooksRouter(handler).ServeHTTP(rr, withOrganization(req))
+
+		// assert
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		var list types.WebhookDeliveryList
+		json.Unmarshal(rr.Body.Bytes(), &list)
+		if list.Total != 11 || list.Page != 2 || len(list.Deliveries) != 1 || list.Deliveries[0].ID != 42 {
+			t.Errorf("unexpected deliveries: %+v", list)
+		}
+		store.AssertExpectations(t)
+	})
+
+	t.Run("invalid status", func(t *testing.T) {
+		// arrange
+		store := new(mockWebhookStore)
+		handler := NewHandler(store, nil)
+
+		req, _ := http.NewRequest(http.MethodGet, "/webhooks/5/deliveries?status=failed", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		webhooksRouter(handler).ServeHTTP(rr, withOrganization(req))
+
+		// assert
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+		store.AssertNotCalled(t, "ListDeliveries", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestWebhookService_Handle_RetryDelivery(t *testing.T) {
+	t.Run("queues the dead delivery", func(t *testing.T) {
+		// arrange
+		store := new(mockWebhookStore)
+		handler := NewHandler(store, nil)
+
+		store.On("RetryDelivery", mock.Anything, orgID, 5, int64(42)).Return(nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/webhooks/5/deliveries/42/retry", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		webhooksRouter(handler).ServeHTTP(rr, withOrganization(req))
+
+		// assert
+		if status := rr.Code; status != http.StatusAccepted {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusAccepted)
+		}
+		store.AssertExpectations(t)
+	})
+
+	t.Run("delivery that is not dead", func(t *testing.T) {
+		// arrange
+		store := new(mockWebhookStore)
+		handler := NewHandler(store, nil)
+
+		store.On("RetryDelivery", mock.Anything, orgID, 5, int64(42)).Return(&customerrors.NotFoundError{ID: "42"})
+
+		req, _ := http.NewRequest(http.MethodPost, "/webhooks/5/deliveries/42/retry", nil)
+		rr := httptest.NewRecorder()
+
+		// act
+		webhooksRouter(handler).ServeHTTP(rr, withOrganization(req))
+
+		// assert
+		if status := rr.Code; status != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+		}
+	})
+}
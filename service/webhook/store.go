@@ -0,0 +1,394 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"github.com/sirupsen/logrus"
+	"go-sample-rest-api/audit"
+	"go-sample-rest-api/customerrors"
+	"go-sample-rest-api/db"
+	"go-sample-rest-api/logging"
+	"go-sample-rest-api/types"
+	"strconv"
+	"time"
+)
+
+const webhookColumns = "id, org_id, url, secret, event_types, active, created_at"
+
+const deliveryColumns = `d.id, d.webhook_id, d.event_id, e.type, d.status, d.attempts, d.next_attempt_at,
+              d.last_status_code, d.last_error, d.delivered_at, d.created_at`
+
+type Store struct {
+	db db.DB
+}
+
+func NewStore(db db.DB) *Store {
+	return &Store{db: db}
+}
+
+// CreateWebhook saves the webhook and records entry in the audit log.
+func (s *Store) CreateWebhook(ctx context.Context, webhook types.Webhook, entry types.AuditEntry) (*types.Webhook, error) {
+	log := logging.GetLogger()
+	w := new(types.Webhook)
+	err := s.db.WithTx(ctx, func(tx db.DB) error {
+		err := scanWebhook(tx.QueryRowContext(ctx, "INSERT INTO webhooks (org_id, url, secret, event_types, active) VALUES ($1, $2, $3, $4, $5) RETURNING "+webhookColumns,
+			webhook.OrgID, webhook.URL, webhook.Secret, webhook.EventTypes, webhook.Active), w)
+		if err != nil {
+			return err
+		}
+
+		return writeAudit(ctx, tx, entry, *w, nil, auditState(*w))
+	})
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"orgID": webhook.OrgID,
+			"error": err,
+		}).Error("Error creating webhook")
+		return nil, err
+	}
+
+	log.WithFields(logrus.Fields{
+		"orgID":     w.OrgID,
+		"webhookID": w.ID,
+	}).Info("Webhook created")
+	return w, nil
+}
+
+func (s *Store) GetWebhook(ctx context.Context, orgID, webhookID int) (*types.Webhook, error) {
+	w := new(types.Webhook)
+	err := scanWebhook(s.db.QueryRowContext(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE id = $1 AND org_id = $2", webhookID, orgID), w)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &customerrors.NotFoundError{ID: strconv.Itoa(webhookID)}
+		}
+		logging.GetLogger().WithFields(logrus.Fields{
+			"orgID":     orgID,
+			"webhookID": webhookID,
+			"error":     err,
+		}).Error("Error retrieving webhook")
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// ListWebhooks returns the webhooks of the organization, oldest first.
+func (s *Store) ListWebhooks(ctx context.Context, orgID int) ([]types.Webhook, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE org_id = $1 ORDER BY id", orgID)
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"orgID": orgID,
+			"error": err,
+		}).Error("Error listing webhooks")
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := make([]types.Webhook, 0)
+	for rows.Next() {
+		var w types.Webhook
+		if err := scanWebhook(rows, &w); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// UpdateWebhook saves the URL, event types and state of the webhook. The
+// secret cannot be changed.
+func (s *Store) UpdateWebhook(ctx context.Context, webhook types.Webhook, entry types.AuditEntry) error {
+	log := logging.GetLogger()
+	err := s.db.WithTx(ctx, func(tx db.DB) error {
+		var before types.Webhook
+		err := scanWebhook(tx.QueryRowContext(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE id = $1 AND org_id = $2 FOR UPDATE", webhook.ID, webhook.OrgID), &before)
+		if err == sql.ErrNoRows {
+			return &customerrors.NotFoundError{ID: strconv.Itoa(webhook.ID)}
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE webhooks SET url = $1, event_types = $2, active = $3 WHERE id = $4 AND org_id = $5",
+			webhook.URL, webhook.EventTypes, webhook.Active, webhook.ID, webhook.OrgID); err != nil {
+			return err
+		}
+
+		return writeAudit(ctx, tx, entry, before, auditState(before), auditState(webhook))
+	})
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"orgID":     webhook.OrgID,
+			"webhookID": webhook.ID,
+			"error":     err,
+		}).Error("Error updating webhook")
+		return err
+	}
+
+	return nil
+}
+
+// DeleteWebhook deletes the webhook and its deliveries.
+func (s *Store) DeleteWebhook(ctx context.Context, orgID, webhookID int, entry types.AuditEntry) error {
+	log := logging.GetLogger()
+	err := s.db.WithTx(ctx, func(tx db.DB) error {
+		var before types.Webhook
+		err := scanWebhook(tx.QueryRowContext(ctx, "DELETE FROM webhooks WHERE id = $1 AND org_id = $2 RETURNING "+webhookColumns, webhookID, orgID), &before)
+		if err == sql.ErrNoRows {
+			return &customerrors.NotFoundError{ID: strconv.Itoa(webhookID)}
+		}
+		if err != nil {
+			return err
+		}
+
+		return writeAudit(ctx, tx, entry, before, auditState(before), nil)
+	})
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"orgID":     orgID,
+			"webhookID": webhookID,
+			"error":     err,
+		}).Error("Error deleting webhook")
+		return err
+	}
+
+	log.WithFields(logrus.Fields{
+		"orgID":     orgID,
+		"webhookID": webhookID,
+	}).Info("Webhook deleted")
+	return nil
+}
+
+func (s *Store) ListDeliveries(ctx context.Context, orgID, webhookID int, status string, limit, offset int) ([]types.WebhookDelivery, int, error) {
+	where := "w.org_id = $1 AND d.webhook_id = $2"
+	args := []any{orgID, webhookID}
+	if status != "" {
+		where += " AND d.status = $3"
+		args = append(args, status)
+	}
+	from := ` FROM webhook_deliveries d
+              JOIN webhooks w ON w.id = d.webhook_id
+              JOIN outbox_events e ON e.id = d.event_id
+              WHERE ` + where
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*)"+from, args...).Scan(&total); err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"orgID":     orgID,
+			"webhookID": webhookID,
+			"error":     err,
+		}).Error("Error counting webhook deliveries")
+		return nil, 0, err
+	}
+
+	args = append(args, limit, offset)
+	query := "SELECT " + deliveryColumns + from + " ORDER BY d.id DESC LIMIT $" + strconv.Itoa(len(args)-1) + " OFFSET $" + strconv.Itoa(len(args))
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"orgID":     orgID,
+			"webhookID": webhookID,
+			"error":     err,
+		}).Error("Error listing webhook deliveries")
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]types.WebhookDelivery, 0)
+	for rows.Next() {
+		var d types.WebhookDelivery
+		var statusCode sql.NullInt64
+		var deliveredAt sql.NullTime
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&statusCode, &d.LastError, &deliveredAt, &d.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		if statusCode.Valid {
+			code := int(statusCode.Int64)
+			d.LastStatusCode = &code
+		}
+		if deliveredAt.Valid {
+			d.DeliveredAt = &deliveredAt.Time
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, total, rows.Err()
+}
+
+// RetryDelivery fails with a NotFoundError unless the delivery belongs to the
+// webhook of the organization and is dead.
+func (s *Store) RetryDelivery(ctx context.Context, orgID, webhookID int, deliveryID int64) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE webhook_deliveries d
+              SET status = $1, attempts = 0, next_attempt_at = CURRENT_TIMESTAMP, last_error = ''
+              FROM webhooks w
+              WHERE w.id = d.webhook_id AND w.org_id = $2 AND d.webhook_id = $3 AND d.id = $4 AND d.status = $5`,
+		types.DeliveryPending, orgID, webhookID, deliveryID, types.DeliveryDead)
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"webhookID":  webhookID,
+			"deliveryID": deliveryID,
+			"error":      err,
+		}).Error("Error retrying webhook delivery")
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return &customerrors.NotFoundError{ID: strconv.FormatInt(deliveryID, 10)}
+	}
+
+	return nil
+}
+
+// PublishEvents creates a delivery of every unpublished event for each active
+// webhook subscribed to it, and marks the events published. It handles at
+// most limit events, oldest first, and returns them. Events that another
+// replica is publishing are skipped.
+func (s *Store) PublishEvents(ctx context.Context, limit int) ([]types.Event, error) {
+	var events []types.Event
+	err := s.db.WithTx(ctx, func(tx db.DB) error {
+		rows, err := tx.QueryContext(ctx, `SELECT id, org_id, type, data, created_at FROM outbox_events
+              WHERE published_at IS NULL ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED`, limit)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var e types.Event
+			if err := rows.Scan(&e.ID, &e.OrgID, &e.Type, &e.Data, &e.CreatedAt); err != nil {
+				rows.Close()
+				return err
+			}
+			events = append(events, e)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, e := range events {
+			if _, err := tx.ExecContext(ctx, `INSERT INTO webhook_deliveries (webhook_id, event_id)
+              SELECT id, $1 FROM webhooks
+              WHERE org_id = $2 AND active AND (event_types = '[]'::jsonb OR event_types ? $3)
+              ON CONFLICT DO NOTHING`, e.ID, e.OrgID, e.Type); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, "UPDATE outbox_events SET published_at = CURRENT_TIMESTAMP WHERE id = $1", e.ID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Error publishing events")
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// ClaimDeliveries returns at most limit pending deliveries of active webhooks
+// that are due at now, and postpones them by lease so no other replica makes
+// them at the same time.
+func (s *Store) ClaimDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]Delivery, error) {
+	var deliveries []Delivery
+	err := s.db.WithTx(ctx, func(tx db.DB) error {
+		rows, err := tx.QueryContext(ctx, `SELECT d.id, d.attempts, w.url, w.secret, e.id, e.org_id, e.type, e.data, e.created_at
+              FROM webhook_deliveries d
+              JOIN webhooks w ON w.id = d.webhook_id
+              JOIN outbox_events e ON e.id = d.event_id
+              WHERE d.status = $1 AND d.next_attempt_at <= $2 AND w.active
+              ORDER BY d.next_attempt_at, d.id LIMIT $3
+              FOR UPDATE OF d SKIP LOCKED`, types.DeliveryPending, now, limit)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var d Delivery
+			if err := rows.Scan(&d.ID, &d.Attempts, &d.URL, &d.Secret,
+				&d.Event.ID, &d.Event.OrgID, &d.Event.Type, &d.Event.Data, &d.Event.CreatedAt); err != nil {
+				rows.Close()
+				return err
+			}
+			deliveries = append(deliveries, d)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, d := range deliveries {
+			if _, err := tx.ExecContext(ctx, "UPDATE webhook_deliveries SET next_attempt_at = $1 WHERE id = $2", now.Add(lease), d.ID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Error claiming webhook deliveries")
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// RecordAttempt saves the outcome of an attempt to make a delivery.
+func (s *Store) RecordAttempt(ctx context.Context, attempt Attempt) error {
+	var statusCode sql.NullInt64
+	if attempt.StatusCode != 0 {
+		statusCode = sql.NullInt64{Int64: int64(attempt.StatusCode), Valid: true}
+	}
+	var deliveredAt sql.NullTime
+	if attempt.Status == types.DeliveryDelivered {
+		deliveredAt = sql.NullTime{Time: attempt.At, Valid: true}
+	}
+
+	_, err := s.db.ExecContext(ctx, `UPDATE webhook_deliveries
+              SET status = $1, attempts = $2, next_attempt_at = $3, last_status_code = $4, last_error = $5, delivered_at = $6
+              WHERE id = $7`,
+		attempt.Status, attempt.Attempts, attempt.NextAttemptAt, statusCode, attempt.Error, deliveredAt, attempt.DeliveryID)
+	if err != nil {
+		logging.GetLogger().WithFields(logrus.Fields{
+			"deliveryID": attempt.DeliveryID,
+			"error":      err,
+		}).Error("Error recording webhook delivery attempt")
+	}
+
+	return err
+}
+
+// writeAudit completes the entry for a webhook and appends it to the audit log.
+func writeAudit(ctx context.Context, tx db.DB, entry types.AuditEntry, w types.Webhook, before, after map[string]any) error {
+	entry.OrgID = w.OrgID
+	entry.ResourceType = "webhook"
+	entry.ResourceID = strconv.Itoa(w.ID)
+	entry.Changes = audit.Changes(before, after)
+	return audit.Write(ctx, tx, entry)
+}
+
+// auditState returns the fields of a webhook that the audit log compares. The
+// secret is left out.
+func auditState(w types.Webhook) map[string]any {
+	eventTypes := []string(w.EventTypes)
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+
+	return map[string]any{
+		"url":         w.URL,
+		"event_types": eventTypes,
+		"active":      w.Active,
+	}
+}
+
+func scanWebhook(row interface{ Scan(dest ...any) error }, w *types.Webhook) error {
+	return row.Scan(&w.ID, &w.OrgID, &w.URL, &w.Secret, &w.EventTypes, &w.Active, &w.CreatedAt)
+}
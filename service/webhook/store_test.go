@@ -0,0 +1,236 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"go-sample-rest-api/customerrors"
+	db2 "go-sample-rest-api/db"
+	"go-sample-rest-api/types"
+	"testing"
+	"time"
+)
+
+var webhookRowColumns = []string{"id", "org_id", "url", "secret", "event_types", "active", "created_at"}
+
+func setupMockDB(t *testing.T) (*db2.SQLDB, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	return db2.NewSQLDB(db), mock, func() { db.Close() }
+}
+
+func TestStore_CreateWebhook(t *testing.T) {
+	t.Run("CreateWebhook_recordsAuditEntryWithoutSecret", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		webhook := types.Webhook{OrgID: 3, URL: "https://example.com", EventTypes: types.EventTypes{"camera.created"}, Active: true, Secret: "whsec_x"}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`^INSERT INTO webhooks \(org_id, url, secret, event_types, active\) VALUES \(\$1, \$2, \$3, \$4, \$5\) RETURNING`).
+			WithArgs(3, "https://example.com", "whsec_x", webhook.EventTypes, true).
+			WillReturnRows(sqlmock.NewRows(webhookRowColumns).AddRow(5, 3, "https://example.com", "whsec_x", []byte(`["camera.created"]`), true, time.Now()))
+		mock.ExpectExec(`^INSERT INTO audit_log`).
+			WithArgs(3, "user", "1", "webhook.create", "webhook", "5", types.AuditChanges{
+				"url":         {After: "https://example.com"},
+				"event_types": {After: []string{"camera.created"}},
+				"active":      {After: true},
+			}, "", "").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		// act
+		created, err := store.CreateWebhook(context.Background(), webhook, types.AuditEntry{ActorType: "user", ActorID: "1", Action: "webhook.create"})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 5, created.ID)
+		assert.Equal(t, types.EventTypes{"camera.created"}, created.EventTypes)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStore_GetWebhook(t *testing.T) {
+	t.Run("GetWebhook_ofOtherOrganization_returnsNotFound", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		mock.ExpectQuery(`^SELECT .* FROM webhooks WHERE id = \$1 AND org_id = \$2$`).
+			WithArgs(5, 3).
+			WillReturnRows(sqlmock.NewRows(webhookRowColumns))
+
+		// act
+		_, err := store.GetWebhook(context.Background(), 3, 5)
+
+		// assert
+		var notFound *customerrors.NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+	})
+}
+
+func TestStore_DeleteWebhook(t *testing.T) {
+	t.Run("DeleteWebhook_recordsAuditEntry", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`^DELETE FROM webhooks WHERE id = \$1 AND org_id = \$2 RETURNING`).
+			WithArgs(5, 3).
+			WillReturnRows(sqlmock.NewRows(webhookRowColumns).AddRow(5, 3, "https://example.com", "whsec_x", []byte(`[]`), true, time.Now()))
+		mock.ExpectExec(`^INSERT INTO audit_log`).
+			WithArgs(3, "user", "1", "webhook.delete", "webhook", "5", sqlmock.AnyArg(), "", "").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		// act
+		err := store.DeleteWebhook(context.Background(), 3, 5, types.AuditEntry{ActorType: "user", ActorID: "1", Action: "webhook.delete"})
+
+		// assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStore_ListDeliveries(t *testing.T) {
+	t.Run("ListDeliveries_withStatus_filtersAndPages", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		now := time.Now()
+		mock.ExpectQuery(`^SELECT COUNT\(\*\) FROM webhook_deliveries d .* WHERE w.org_id = \$1 AND d.webhook_id = \$2 AND d.status = \$3$`).
+			WithArgs(3, 5, "dead").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(11))
+		mock.ExpectQuery(`WHERE w.org_id = \$1 AND d.webhook_id = \$2 AND d.status = \$3 ORDER BY d.id DESC LIMIT \$4 OFFSET \$5$`).
+			WithArgs(3, 5, "dead", 10, 10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "webhook_id", "event_id", "type", "status", "attempts", "next_attempt_at", "last_status_code", "last_error", "delivered_at", "created_at"}).
+				AddRow(42, 5, 7, "camera.created", "dead", 10, now, 502, "unexpected status code 502", nil, now))
+
+		// act
+		deliveries, total, err := store.ListDeliveries(context.Background(), 3, 5, "dead", 10, 10)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 11, total)
+		assert.Len(t, deliveries, 1)
+		assert.Equal(t, 502, *deliveries[0].LastStatusCode)
+		assert.Nil(t, deliveries[0].DeliveredAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStore_RetryDelivery(t *testing.T) {
+	t.Run("RetryDelivery_withoutDeadDelivery_returnsNotFound", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		mock.ExpectExec(`^UPDATE webhook_deliveries d\s+SET status = \$1, attempts = 0`).
+			WithArgs("pending", 3, 5, int64(42), "dead").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		// act
+		err := store.RetryDelivery(context.Background(), 3, 5, 42)
+
+		// assert
+		var notFound *customerrors.NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+	})
+}
+
+func TestStore_PublishEvents(t *testing.T) {
+	t.Run("PublishEvents_fansOutAndMarksPublished", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		now := time.Now()
+		mock.ExpectBegin()
+		mock.ExpectQuery(`^SELECT id, org_id, type, data, created_at FROM outbox_events\s+WHERE published_at IS NULL ORDER BY id LIMIT \$1 FOR UPDATE SKIP LOCKED$`).
+			WithArgs(50).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "org_id", "type", "data", "created_at"}).
+				AddRow(7, 3, "camera.created", []byte(`{"cam_id":"cam-1"}`), now))
+		mock.ExpectExec(`^INSERT INTO webhook_deliveries \(webhook_id, event_id\)\s+SELECT id, \$1 FROM webhooks\s+WHERE org_id = \$2 AND active AND \(event_types = '\[\]'::jsonb OR event_types \? \$3\)`).
+			WithArgs(int64(7), 3, "camera.created").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(`^UPDATE outbox_events SET published_at = CURRENT_TIMESTAMP WHERE id = \$1$`).
+			WithArgs(int64(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		// act
+		events, err := store.PublishEvents(context.Background(), 50)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, []types.Event{{ID: 7, OrgID: 3, Type: "camera.created", Data: json.RawMessage(`{"cam_id":"cam-1"}`), CreatedAt: now}}, events)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStore_ClaimDeliveries(t *testing.T) {
+	t.Run("ClaimDeliveries_postponesClaimedDeliveries", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		now := time.Now()
+		mock.ExpectBegin()
+		mock.ExpectQuery(`WHERE d.status = \$1 AND d.next_attempt_at <= \$2 AND w.active\s+ORDER BY d.next_attempt_at, d.id LIMIT \$3\s+FOR UPDATE OF d SKIP LOCKED$`).
+			WithArgs("pending", now, 10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "attempts", "url", "secret", "event_id", "org_id", "type", "data", "created_at"}).
+				AddRow(42, 1, "https://example.com", "whsec_x", 7, 3, "camera.created", []byte(`{}`), now))
+		mock.ExpectExec(`^UPDATE webhook_deliveries SET next_attempt_at = \$1 WHERE id = \$2$`).
+			WithArgs(now.Add(time.Minute), int64(42)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		// act
+		deliveries, err := store.ClaimDeliveries(context.Background(), now, 10, time.Minute)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Len(t, deliveries, 1)
+		assert.Equal(t, "https://example.com", deliveries[0].URL)
+		assert.Equal(t, int64(7), deliveries[0].Event.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStore_RecordAttempt(t *testing.T) {
+	t.Run("RecordAttempt_withDelivered_setsDeliveredAt", func(t *testing.T) {
+		// arrange
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+
+		store := NewStore(db)
+		now := time.Now()
+		mock.ExpectExec(`^UPDATE webhook_deliveries\s+SET status = \$1, attempts = \$2, next_attempt_at = \$3, last_status_code = \$4, last_error = \$5, delivered_at = \$6\s+WHERE id = \$7$`).
+			WithArgs("delivered", 2, now, sql.NullInt64{Int64: 200, Valid: true}, "", sql.NullTime{Time: now, Valid: true}, int64(42)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		// act
+		err := store.RecordAttempt(context.Background(), Attempt{
+			DeliveryID: 42, Status: types.DeliveryDelivered, Attempts: 2, NextAttemptAt: now, StatusCode: 200, At: now,
+		})
+
+		// assert
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
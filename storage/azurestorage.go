@@ -8,6 +8,7 @@ import (
 	"go-sample-rest-api/logging"
 	"io/ioutil"
 	"net/url"
+	"time"
 
 	"github.com/Azure/azure-storage-blob-go/azblob"
 )
@@ -15,6 +16,15 @@ import (
 type ImageStore interface {
 	UploadImage(ctx context.Context, blobName string, imageData []byte) error
 	DownloadImage(ctx context.Context, blobName string) ([]byte, error)
+	DeleteImage(ctx context.Context, blobName string) error
+	ListImages(ctx context.Context) ([]ImageInfo, error)
+}
+
+// ImageInfo describes a stored blob.
+type ImageInfo struct {
+	Name         string
+	SizeBytes    int64
+	LastModified time.Time
 }
 
 type AzureStorage struct {
@@ -89,3 +99,42 @@ func (az *AzureStorage) DownloadImage(ctx context.Context, blobName string) ([]b
 
 	return data, nil
 }
+
+// DeleteImage removes the blob. A blob that does not exist is not an error.
+func (az *AzureStorage) DeleteImage(ctx context.Context, blobName string) error {
+	containerURL := az.ServiceURL.NewContainerURL(az.ContainerName)
+	blobURL := containerURL.NewBlockBlobURL(blobName)
+
+	_, err := blobURL.Delete(ctx, azblob.DeleteSnapshotsOptionInclude, azblob.BlobAccessConditions{})
+	if err != nil {
+		if stgErr, ok := err.(azblob.StorageError); ok && stgErr.ServiceCode() == azblob.ServiceCodeBlobNotFound {
+			return nil
+		}
+		return &customerrors.AzureStorageError{Message: err.Error()}
+	}
+	return nil
+}
+
+// ListImages returns every blob of the container.
+func (az *AzureStorage) ListImages(ctx context.Context) ([]ImageInfo, error) {
+	containerURL := az.ServiceURL.NewContainerURL(az.ContainerName)
+
+	var images []ImageInfo
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		segment, err := containerURL.ListBlobsFlatSegment(ctx, marker, azblob.ListBlobsSegmentOptions{})
+		if err != nil {
+			return nil, &customerrors.AzureStorageError{Message: err.Error()}
+		}
+		marker = segment.NextMarker
+
+		for _, blob := range segment.Segment.BlobItems {
+			image := ImageInfo{Name: blob.Name, LastModified: blob.Properties.LastModified}
+			if blob.Properties.ContentLength != nil {
+				image.SizeBytes = *blob.Properties.ContentLength
+			}
+			images = append(images, image)
+		}
+	}
+
+	return images, nil
+}
@@ -0,0 +1,92 @@
+package types
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	AuditActorUser   = "user"
+	AuditActorSystem = "system"
+)
+
+// AuditEntry records one change of a resource: who made it, from where, and
+// the values of the fields it changed.
+type AuditEntry struct {
+	ID           int64        `json:"id"`
+	OrgID        int          `json:"org_id"`
+	ActorType    string       `json:"actor_type"`
+	ActorID      string       `json:"actor_id"`
+	Action       string       `json:"action"`
+	ResourceType string       `json:"resource_type"`
+	ResourceID   string       `json:"resource_id"`
+	Changes      AuditChanges `json:"changes"`
+	RequestID    string       `json:"request_id"`
+	IP           string       `json:"ip"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+// AuditChange is the value of a field before and after a change. Before is
+// null for a created resource and After for a deleted one.
+type AuditChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// AuditChanges maps field names to their change. It is stored as a JSON object.
+type AuditChanges map[string]AuditChange
+
+func (c AuditChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(c)
+}
+
+func (c *AuditChanges) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*c = AuditChanges{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into audit changes", src)
+	}
+
+	return json.Unmarshal(data, c)
+}
+
+// AuditFilter selects audit entries of an organization. Empty fields match
+// everything.
+type AuditFilter struct {
+	ActorType    string
+	ActorID      string
+	Action       string
+	ResourceType string
+	ResourceID   string
+	RequestID    string
+	From         *time.Time
+	To           *time.Time
+	Limit        int
+	Offset       int
+}
+
+type AuditEntryList struct {
+	Entries  []AuditEntry `json:"entries"`
+	Total    int          `json:"total"`
+	Page     int          `json:"page"`
+	PageSize int          `json:"pageSize"`
+}
+
+type AuditLogStore interface {
+	ListAuditEntries(ctx context.Context, orgID int, filter AuditFilter) ([]AuditEntry, int, error)
+	ListResourceChanges(ctx context.Context, orgID int, resourceType string, resourceIDs []string, field string) ([]AuditEntry, error)
+	ListActorEntries(ctx context.Context, orgID int, actorType, actorID string) ([]AuditEntry, error)
+}
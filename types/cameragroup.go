@@ -0,0 +1,32 @@
+package types
+
+import (
+	"context"
+	"time"
+)
+
+// CameraGroup is a site or any other set of cameras of an organization. A
+// camera belongs to at most one group.
+type CameraGroup struct {
+	ID          int       `json:"id"`
+	OrgID       int       `json:"org_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type CameraGroupPayload struct {
+	Name        string `json:"name" validate:"required,max=255"`
+	Description string `json:"description" validate:"max=1000"`
+}
+
+// CameraGroupStore manages groups. Every method is limited to the groups of
+// one organization, given as orgID or as the OrgID of the group. Changes are
+// recorded in the audit log with entry in the same transaction.
+type CameraGroupStore interface {
+	CreateCameraGroup(ctx context.Context, group CameraGroup, entry AuditEntry) (*CameraGroup, error)
+	GetCameraGroup(ctx context.Context, orgID, groupID int) (*CameraGroup, error)
+	ListCameraGroups(ctx context.Context, orgID int) ([]CameraGroup, error)
+	UpdateCameraGroup(ctx context.Context, group CameraGroup, entry AuditEntry) error
+	DeleteCameraGroup(ctx context.Context, orgID, groupID int, entry AuditEntry) error
+}
@@ -1,20 +1,60 @@
 package types
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 )
 
 type CameraMetadata struct {
-	CamID               string         `json:"cam_id"`
-	ImageId             sql.NullString `json:"image_id"`
-	CameraName          string         `json:"camera_name"`
-	FirmwareVersion     string         `json:"firmware_version"`
-	ContainerName       sql.NullString `json:"container_name"`
-	NameOfStoredPicture sql.NullString `json:"name_of_stored_picture"`
-	CreatedAt           sql.NullTime   `json:"createdAt"`
-	OnboardedAt         sql.NullTime   `json:"onboarded_at"`
-	InitializedAt       sql.NullTime   `json:"initialized_at"`
+	CamID               string          `json:"cam_id"`
+	ImageId             sql.NullString  `json:"image_id"`
+	CameraName          string          `json:"camera_name"`
+	FirmwareVersion     string          `json:"firmware_version"`
+	ContainerName       sql.NullString  `json:"container_name"`
+	NameOfStoredPicture sql.NullString  `json:"name_of_stored_picture"`
+	CreatedAt           sql.NullTime    `json:"createdAt"`
+	OnboardedAt         sql.NullTime    `json:"onboarded_at"`
+	InitializedAt       sql.NullTime    `json:"initialized_at"`
+	OwnerID             sql.NullInt64   `json:"owner_id"`
+	OrgID               int             `json:"org_id"`
+	ImageSizeBytes      int64           `json:"image_size_bytes"`
+	GroupID             sql.NullInt64   `json:"group_id"`
+	Tags                Tags            `json:"tags"`
+	Latitude            sql.NullFloat64 `json:"latitude"`
+	Longitude           sql.NullFloat64 `json:"longitude"`
+	Heading             sql.NullFloat64 `json:"heading"`
+	FieldOfView         sql.NullFloat64 `json:"field_of_view"`
+}
+
+// Tags are the key=value labels of a camera. They are stored as a JSON object.
+type Tags map[string]string
+
+func (t Tags) Value() (driver.Value, error) {
+	if t == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(t)
+}
+
+func (t *Tags) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*t = Tags{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into tags", src)
+	}
+
+	return json.Unmarshal(data, t)
 }
 
 type CameraMetadataPayload struct {
@@ -27,6 +67,104 @@ type CameraMetadataResponse struct {
 	CameraName      string    `json:"camera_name"`
 	FirmwareVersion string    `json:"firmware_version"`
 	CreatedAt       time.Time `json:"createdAt"`
+	GroupID         *int64    `json:"group_id"`
+	Tags            Tags      `json:"tags"`
+	Latitude        *float64  `json:"latitude"`
+	Longitude       *float64  `json:"longitude"`
+	Heading         *float64  `json:"heading"`
+	FieldOfView     *float64  `json:"field_of_view"`
+}
+
+// TagRequirement is one term of a tag selector such as site=berlin, env!=test,
+// outdoor (the key exists) or !outdoor (it does not).
+type TagRequirement struct {
+	Key      string
+	Operator string
+	Value    string
+}
+
+const (
+	TagEquals    = "="
+	TagNotEquals = "!="
+	TagExists    = "exists"
+	TagNotExists = "!exists"
+)
+
+// CameraFilter selects the cameras of an organization. The zero value selects
+// all of them; Limit 0 returns every match.
+type CameraFilter struct {
+	GroupID  *int
+	Selector []TagRequirement
+	// Within and Near only match cameras with a location. Near also sorts
+	// the cameras by distance, nearest first.
+	Within *BoundingBox
+	Near   *GeoCircle
+	Limit  int
+	Offset int
+}
+
+// BoundingBox is an area in degrees. A box with MinLongitude greater than
+// MaxLongitude crosses the antimeridian.
+type BoundingBox struct {
+	MinLongitude float64
+	MinLatitude  float64
+	MaxLongitude float64
+	MaxLatitude  float64
+}
+
+// GeoCircle is the area within RadiusMeters of a point.
+type GeoCircle struct {
+	Latitude     float64
+	Longitude    float64
+	RadiusMeters float64
+}
+
+// CameraLocationPayload places a camera. Heading is the compass direction the
+// camera faces, clockwise from north; FieldOfView its horizontal angle.
+type CameraLocationPayload struct {
+	Latitude    *float64 `json:"latitude" validate:"required,min=-90,max=90"`
+	Longitude   *float64 `json:"longitude" validate:"required,min=-180,max=180"`
+	Heading     *float64 `json:"heading" validate:"omitempty,min=0,lt=360"`
+	FieldOfView *float64 `json:"field_of_view" validate:"omitempty,gt=0,max=360"`
+}
+
+type CameraList struct {
+	Cameras  []CameraMetadataResponse `json:"cameras"`
+	Total    int                      `json:"total"`
+	Page     int                      `json:"page"`
+	PageSize int                      `json:"pageSize"`
+}
+
+type CameraGroupAssignmentPayload struct {
+	// GroupID null removes the camera from its group.
+	GroupID *int `json:"group_id"`
+}
+
+type CameraTagsPayload struct {
+	Tags Tags `json:"tags"`
+}
+
+// CameraBulkPayload changes every camera matching the group and selector. At
+// least one of them is required, so a mistake cannot change the whole fleet.
+type CameraBulkPayload struct {
+	GroupID    *int     `json:"group_id"`
+	Selector   string   `json:"selector"`
+	SetGroupID *int     `json:"set_group_id"`
+	ClearGroup bool     `json:"clear_group"`
+	SetTags    Tags     `json:"set_tags"`
+	RemoveTags []string `json:"remove_tags"`
+}
+
+// CameraBulkUpdate is the change of a bulk operation.
+type CameraBulkUpdate struct {
+	SetGroupID *int
+	ClearGroup bool
+	SetTags    Tags
+	RemoveTags []string
+}
+
+type CameraBulkResponse struct {
+	Updated int64 `json:"updated"`
 }
 
 type ImageUploadedResponse struct {
@@ -36,8 +174,44 @@ type ImageUploadedResponse struct {
 	ImageId         string `json:"image_id"`
 }
 
+// ImageReference is the image a camera points at.
+type ImageReference struct {
+	OrgID   int
+	CamID   string
+	ImageID string
+}
+
+// Usage is what an organization stores.
+type Usage struct {
+	Cameras      int64 `json:"cameras"`
+	StorageBytes int64 `json:"storageBytes"`
+}
+
+// Quota limits the usage of an organization. Zero means unlimited.
+type Quota struct {
+	MaxCameras      int64 `json:"maxCameras"`
+	MaxStorageBytes int64 `json:"maxStorageBytes"`
+}
+
+type UsageResponse struct {
+	Usage Usage `json:"usage"`
+	Quota Quota `json:"quota"`
+}
+
+// CameraMetadataStore manages cameras. Every method but ClaimImageID is
+// limited to the cameras of one organization, given as orgID or as the OrgID
+// of the camera. The
+// methods changing cameras record entry, completed with the camera and its
+// changes, in the audit log.
 type CameraMetadataStore interface {
-	CreateCameraMetadata(camera CameraMetadata) (*CameraMetadata, error)
-	GetCameraMetadataByID(camID string) (*CameraMetadata, error)
-	UpdateCameraMetadata(camera CameraMetadata) (*CameraMetadata, error)
+	CreateCameraMetadata(ctx context.Context, camera CameraMetadata, entry AuditEntry) (*CameraMetadata, error)
+	GetCameraMetadataByID(ctx context.Context, orgID int, camID string) (*CameraMetadata, error)
+	UpdateCameraMetadata(ctx context.Context, orgID int, camID string, change func(c *CameraMetadata) error, entry AuditEntry) (*CameraMetadata, error)
+	ListCameraMetadataByOwner(ctx context.Context, orgID, ownerID int) ([]CameraMetadata, error)
+	ReassignCameraMetadata(ctx context.Context, orgID, fromOwnerID, toOwnerID int, entry AuditEntry) error
+	DeleteCameraMetadata(ctx context.Context, orgID int, camID string, entry AuditEntry) error
+	ClaimImageID(ctx context.Context, imageID, camID string) (bool, error)
+	GetUsage(ctx context.Context, orgID int) (*Usage, error)
+	ListCameraMetadata(ctx context.Context, orgID int, filter CameraFilter) ([]CameraMetadata, int, error)
+	BulkUpdateCameraMetadata(ctx context.Context, orgID int, filter CameraFilter, update CameraBulkUpdate, entry AuditEntry) (int64, error)
 }
@@ -0,0 +1,40 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Types of the events published for cameras.
+const (
+	EventCameraCreated       = "camera.created"
+	EventCameraInitialized   = "camera.initialized"
+	EventCameraImageUploaded = "camera.image_uploaded"
+)
+
+// Event is a change that downstream systems can react to. Data holds the
+// resource after the change.
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	OrgID     int             `json:"org_id"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// CameraEvent is the data of the camera events.
+type CameraEvent struct {
+	CameraMetadataResponse
+	ImageID *string `json:"image_id"`
+}
+
+// LiveImageMessage is sent to the live feed of a camera for every uploaded
+// image.
+type LiveImageMessage struct {
+	EventID    int64       `json:"event_id"`
+	Camera     CameraEvent `json:"camera"`
+	UploadedAt time.Time   `json:"uploaded_at"`
+	// Thumbnail is a base64 encoded JPEG, present when one was asked for
+	// and the image could be read.
+	Thumbnail []byte `json:"thumbnail,omitempty"`
+}
@@ -0,0 +1,25 @@
+package types
+
+// GeoJSONFeatureCollection is a GeoJSON (RFC 7946) FeatureCollection. Total,
+// Page and PageSize are foreign members for paging through large results.
+type GeoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []GeoJSONFeature `json:"features"`
+	Total    int              `json:"total"`
+	Page     int              `json:"page"`
+	PageSize int              `json:"pageSize"`
+}
+
+// GeoJSONFeature is a camera. Geometry is null for a camera without a location.
+type GeoJSONFeature struct {
+	Type       string                 `json:"type"`
+	ID         string                 `json:"id"`
+	Geometry   *GeoJSONPoint          `json:"geometry"`
+	Properties CameraMetadataResponse `json:"properties"`
+}
+
+// GeoJSONPoint holds longitude and latitude, in this order.
+type GeoJSONPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
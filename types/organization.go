@@ -0,0 +1,42 @@
+package types
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Organization is a tenant. Users take part in it through a Membership, and
+// every camera belongs to exactly one organization.
+type Organization struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Membership grants a user a role in an organization.
+type Membership struct {
+	OrgID     int       `json:"organizationId"`
+	UserID    int       `json:"userId"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// PersonalOrganizationName names the organization created for a new user who
+// did not choose a name.
+func PersonalOrganizationName(firstName, lastName, email string) string {
+	if name := strings.TrimSpace(firstName + " " + lastName); name != "" {
+		return name
+	}
+
+	return email
+}
+
+type UpdateOrganizationPayload struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+type OrganizationStore interface {
+	GetOrganizationByID(ctx context.Context, orgID int) (*Organization, error)
+	RenameOrganization(ctx context.Context, orgID int, name string, entry AuditEntry) error
+}
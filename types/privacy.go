@@ -0,0 +1,86 @@
+package types
+
+import (
+	"database/sql"
+	"time"
+)
+
+// DataExport is everything the service stores about a user, as returned by the
+// data export endpoints.
+type DataExport struct {
+	ExportedAt time.Time      `json:"exportedAt"`
+	Profile    UserResponse   `json:"profile"`
+	Cameras    []CameraExport `json:"cameras"`
+	// Memberships are the organizations of the user. An export by an admin
+	// only holds the membership in the admin's organization.
+	Memberships []Membership `json:"memberships"`
+	// Sessions are the active logins of the user.
+	Sessions []Session `json:"sessions"`
+	// AuditEntries record what the user did in the organization.
+	AuditEntries []AuditEntry `json:"auditEntries"`
+}
+
+// CameraExport is a camera of a data export.
+type CameraExport struct {
+	CamID           string     `json:"cam_id"`
+	CameraName      string     `json:"camera_name"`
+	FirmwareVersion string     `json:"firmware_version"`
+	ImageID         *string    `json:"image_id"`
+	ImageSizeBytes  int64      `json:"image_size_bytes"`
+	CreatedAt       *time.Time `json:"createdAt"`
+	OnboardedAt     *time.Time `json:"onboarded_at"`
+	InitializedAt   *time.Time `json:"initialized_at"`
+	GroupID         *int64     `json:"group_id"`
+	Tags            Tags       `json:"tags"`
+	Latitude        *float64   `json:"latitude"`
+	Longitude       *float64   `json:"longitude"`
+	Heading         *float64   `json:"heading"`
+	FieldOfView     *float64   `json:"field_of_view"`
+}
+
+func NewCameraExport(c CameraMetadata) CameraExport {
+	export := CameraExport{
+		CamID:           c.CamID,
+		CameraName:      c.CameraName,
+		FirmwareVersion: c.FirmwareVersion,
+		ImageSizeBytes:  c.ImageSizeBytes,
+		CreatedAt:       timePointer(c.CreatedAt),
+		OnboardedAt:     timePointer(c.OnboardedAt),
+		InitializedAt:   timePointer(c.InitializedAt),
+		Tags:            c.Tags,
+		Latitude:        floatPointer(c.Latitude),
+		Longitude:       floatPointer(c.Longitude),
+		Heading:         floatPointer(c.Heading),
+		FieldOfView:     floatPointer(c.FieldOfView),
+	}
+	if c.ImageId.Valid {
+		export.ImageID = &c.ImageId.String
+	}
+	if c.GroupID.Valid {
+		export.GroupID = &c.GroupID.Int64
+	}
+
+	return export
+}
+
+func floatPointer(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Float64
+}
+
+// EraseAccountPayload confirms the erasure of the current account. Without a
+// password or code, the session of the request must have started recently.
+type EraseAccountPayload struct {
+	Password string `json:"password"`
+	// Code is a TOTP code, accepted in place of the password when two-factor
+	// authentication is enabled.
+	Code string `json:"code"`
+}
+
+// EraseUserPayload controls what happens to the cameras of an erased user:
+// they move to ReassignTo when it is set and are deleted otherwise.
+type EraseUserPayload struct {
+	ReassignTo *int `json:"reassignTo,omitempty" validate:"omitempty,gt=0"`
+}
@@ -1,21 +1,101 @@
 package types
 
-import "time"
+import (
+	"context"
+	"database/sql"
+	"time"
+)
 
+// User is a user as stored. The API returns it as UserResponse.
 type User struct {
-	ID        int       `json:"id"`
-	FirstName string    `json:"firstName"`
-	LastName  string    `json:"lastName"`
-	Email     string    `json:"email"`
-	Password  string    `json:"-"`
-	CreatedAt time.Time `json:"createdAt"`
+	ID              int            `json:"id"`
+	FirstName       string         `json:"firstName"`
+	LastName        string         `json:"lastName"`
+	Email           string         `json:"email"`
+	Password        string         `json:"-"`
+	CreatedAt       time.Time      `json:"createdAt"`
+	EmailVerifiedAt sql.NullTime   `json:"-"`
+	TOTPSecret      sql.NullString `json:"-"`
+	TOTPEnabledAt   sql.NullTime   `json:"-"`
+	// Role is the role of the user in the organization they were loaded for.
+	Role       string       `json:"role,omitempty"`
+	DisabledAt sql.NullTime `json:"-"`
+}
+
+type UserResponse struct {
+	ID              int        `json:"id"`
+	FirstName       string     `json:"firstName"`
+	LastName        string     `json:"lastName"`
+	Email           string     `json:"email"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt"`
+	TOTPEnabledAt   *time.Time `json:"totpEnabledAt"`
+	Role            string     `json:"role,omitempty"`
+	DisabledAt      *time.Time `json:"disabledAt"`
+}
+
+func NewUserResponse(u User) UserResponse {
+	return UserResponse{
+		ID:              u.ID,
+		FirstName:       u.FirstName,
+		LastName:        u.LastName,
+		Email:           u.Email,
+		CreatedAt:       u.CreatedAt,
+		EmailVerifiedAt: timePointer(u.EmailVerifiedAt),
+		TOTPEnabledAt:   timePointer(u.TOTPEnabledAt),
+		Role:            u.Role,
+		DisabledAt:      timePointer(u.DisabledAt),
+	}
+}
+
+func timePointer(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+const (
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposePasswordReset     = "password_reset"
+)
+
+// UserToken is a single-use token sent to the user by email. Only the hash of the token is stored.
+type UserToken struct {
+	ID        int          `json:"id"`
+	UserID    int          `json:"userId"`
+	Purpose   string       `json:"purpose"`
+	TokenHash string       `json:"-"`
+	ExpiresAt time.Time    `json:"expiresAt"`
+	UsedAt    sql.NullTime `json:"-"`
+	CreatedAt time.Time    `json:"createdAt"`
 }
 
 type RegisterUserPayload struct {
 	FirstName string `json:"firstName" validate:"required"`
 	LastName  string `json:"lastName" validate:"required"`
 	Email     string `json:"email" validate:"required,email"`
-	Password  string `json:"password" validate:"required,min=3,max=130"`
+	Password  string `json:"password" validate:"required"`
+	// OrganizationName names the organization created for the new user.
+	OrganizationName string `json:"organizationName" validate:"omitempty,max=255"`
+}
+
+// CreateUserPayload adds a new user to the organization of the admin. The user
+// chooses a password through the invitation link sent to the email address.
+type CreateUserPayload struct {
+	FirstName string `json:"firstName" validate:"required"`
+	LastName  string `json:"lastName" validate:"required"`
+	Email     string `json:"email" validate:"required,email"`
+	Role      string `json:"role" validate:"omitempty,oneof=user admin"`
+}
+
+type UpdateRolePayload struct {
+	Role string `json:"role" validate:"required,oneof=user admin"`
 }
 
 type LoginUserPayload struct {
@@ -23,15 +103,115 @@ type LoginUserPayload struct {
 	Password string `json:"password" validate:"required"`
 }
 
+type EmailPayload struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type VerifyEmailPayload struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ResetPasswordPayload struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// UpdateUserPayload changes the profile of the current user. Omitted fields are left unchanged.
+type UpdateUserPayload struct {
+	FirstName *string `json:"firstName" validate:"omitempty,min=1"`
+	LastName  *string `json:"lastName" validate:"omitempty,min=1"`
+	Email     *string `json:"email" validate:"omitempty,email"`
+}
+
+type ChangePasswordPayload struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required"`
+}
+
+// UserListQuery selects a page of users. Search matches the name or email.
+type UserListQuery struct {
+	Search string
+	Limit  int
+	Offset int
+}
+
+type UserList struct {
+	Users    []UserResponse `json:"users"`
+	Total    int            `json:"total"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"pageSize"`
+}
+
+type LoginChallengePayload struct {
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+	// Code is either the current TOTP code or an unused recovery code.
+	Code string `json:"code" validate:"required"`
+}
+
+type TOTPCodePayload struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type TOTPEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningUri"`
+}
+
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// Session is a login of a user on one device. Its ID is the jti claim of the
+// access token issued for the login.
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     int       `json:"-"`
+	UserAgent  string    `json:"userAgent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	// Current marks the session of the token that made the request.
+	Current bool `json:"current"`
+}
+
+// UserStore manages users. The methods taking an orgID only see users who are
+// members of that organization, so one tenant cannot reach the users of another.
 type UserStore interface {
-	GetUserByEmail(email string) (*User, error)
-	GetUserByID(id int) (*User, error)
-	CreateUser(User) error
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+	GetUserByID(ctx context.Context, id int) (*User, error)
+	CreateUser(ctx context.Context, user User, organizationName string) error
+	UpdateUser(ctx context.Context, user User) error
+	CreateOrganizationUser(ctx context.Context, orgID int, user User, role string, entry AuditEntry) error
+	GetOrganizationUser(ctx context.Context, orgID, userID int) (*User, error)
+	GetOrganizationUsers(ctx context.Context, orgID int, userIDs []int) ([]User, error)
+	ListUsers(ctx context.Context, orgID int, query UserListQuery) ([]User, int, error)
+	ListMemberships(ctx context.Context, userID int) ([]Membership, error)
+	SetUserRole(ctx context.Context, orgID, userID int, role string, entry AuditEntry) error
+	SetUserDisabled(ctx context.Context, orgID, userID int, disabled bool, entry AuditEntry) error
+	DeleteUser(ctx context.Context, orgID, userID int, entry AuditEntry) error
+	AnonymizeUser(ctx context.Context, orgID, userID int, entry AuditEntry) error
+	UpdatePassword(ctx context.Context, userID int, hashedPassword string) error
+	ChangePassword(ctx context.Context, userID int, hashedPassword string, keepSessionID string) error
+	MarkEmailVerified(ctx context.Context, userID int) error
+	CreateUserToken(ctx context.Context, token UserToken) error
+	GetUserToken(ctx context.Context, purpose string, tokenHash string) (*UserToken, error)
+	ConsumeUserToken(ctx context.Context, purpose string, tokenHash string) (*UserToken, error)
+	SetTOTPSecret(ctx context.Context, userID int, secret string) error
+	EnableTOTP(ctx context.Context, userID int, step int64, recoveryCodeHashes []string) error
+	DisableTOTP(ctx context.Context, userID int) error
+	UseTOTPStep(ctx context.Context, userID int, step int64) error
+	ConsumeRecoveryCode(ctx context.Context, userID int, codeHash string) error
+	CreateSession(ctx context.Context, session Session) error
+	TouchSession(ctx context.Context, userID int, sessionID string) error
+	ListSessions(ctx context.Context, userID int) ([]Session, error)
+	RevokeSession(ctx context.Context, userID int, sessionID string) error
+	RevokeOtherSessions(ctx context.Context, userID int, keepSessionID string) error
 }
 
 type Auth interface {
 	ComparePasswords(storedPassword string, suppliedPassword []byte) bool
-	CreateJWT(secret []byte, userID int) (string, error)
+	CreateJWT(secret []byte, userID, orgID int, sessionID string) (string, error)
 }
 
 type Handler struct {
@@ -0,0 +1,112 @@
+package types
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Statuses of webhook deliveries. A dead delivery ran out of attempts.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// Webhook subscribes a URL to the events of an organization. The events are
+// signed with Secret, which is only shown when the webhook is created.
+type Webhook struct {
+	ID         int        `json:"id"`
+	OrgID      int        `json:"org_id"`
+	URL        string     `json:"url"`
+	EventTypes EventTypes `json:"event_types"`
+	Active     bool       `json:"active"`
+	Secret     string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// EventTypes are the events a webhook subscribed to. Empty subscribes to all
+// of them. They are stored as a JSON array.
+type EventTypes []string
+
+func (e EventTypes) Value() (driver.Value, error) {
+	if e == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(e)
+}
+
+func (e *EventTypes) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*e = EventTypes{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into event types", src)
+	}
+
+	return json.Unmarshal(data, e)
+}
+
+type WebhookPayload struct {
+	URL        string   `json:"url" validate:"required,http_url,max=2048"`
+	EventTypes []string `json:"event_types" validate:"dive,oneof=camera.created camera.initialized camera.image_uploaded"`
+}
+
+// WebhookUpdatePayload changes the fields that are set.
+type WebhookUpdatePayload struct {
+	URL        *string   `json:"url" validate:"omitempty,http_url,max=2048"`
+	EventTypes *[]string `json:"event_types" validate:"omitempty,dive,oneof=camera.created camera.initialized camera.image_uploaded"`
+	Active     *bool     `json:"active"`
+}
+
+// WebhookCreatedResponse is the only response that carries the secret.
+type WebhookCreatedResponse struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+// WebhookDelivery is the delivery of one event to one webhook.
+type WebhookDelivery struct {
+	ID             int64      `json:"id"`
+	WebhookID      int        `json:"webhook_id"`
+	EventID        int64      `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastStatusCode *int       `json:"last_status_code"`
+	LastError      string     `json:"last_error"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type WebhookDeliveryList struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Total      int               `json:"total"`
+	Page       int               `json:"page"`
+	PageSize   int               `json:"pageSize"`
+}
+
+// WebhookStore manages the webhooks of an organization, given as orgID or as
+// the OrgID of the webhook. Changes of webhooks are recorded in the audit log
+// with entry in the same transaction.
+type WebhookStore interface {
+	CreateWebhook(ctx context.Context, webhook Webhook, entry AuditEntry) (*Webhook, error)
+	GetWebhook(ctx context.Context, orgID, webhookID int) (*Webhook, error)
+	ListWebhooks(ctx context.Context, orgID int) ([]Webhook, error)
+	UpdateWebhook(ctx context.Context, webhook Webhook, entry AuditEntry) error
+	DeleteWebhook(ctx context.Context, orgID, webhookID int, entry AuditEntry) error
+	// ListDeliveries returns the deliveries of the webhook with the status,
+	// or all of them for an empty status, newest first, and their total.
+	ListDeliveries(ctx context.Context, orgID, webhookID int, status string, limit, offset int) ([]WebhookDelivery, int, error)
+	// RetryDelivery queues a dead delivery again with fresh attempts.
+	RetryDelivery(ctx context.Context, orgID, webhookID int, deliveryID int64) error
+}
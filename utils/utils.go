@@ -1,11 +1,16 @@
 package utils
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"net"
 	"net/http"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -30,6 +35,19 @@ func GetEnvAsInt(key string, fallback int64) int64 {
 	return fallback
 }
 
+func GetEnvAsBool(key string, fallback bool) bool {
+	if value, ok := os.LookupEnv(key); ok {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fallback
+		}
+
+		return b
+	}
+
+	return fallback
+}
+
 var Validate = validator.New()
 
 func WriteJSON(w http.ResponseWriter, status int, v any) error {
@@ -38,7 +56,16 @@ func WriteJSON(w http.ResponseWriter, status int, v any) error {
 	return json.NewEncoder(w).Encode(v)
 }
 
+// WriteError answers with the message of err. An error with a code, such as
+// those of package customerrors, is also sent with the code and its fields as
+// details, so clients do not have to parse the message.
 func WriteError(w http.ResponseWriter, status int, err error) {
+	var coded interface{ Code() string }
+	if errors.As(err, &coded) {
+		WriteJSON(w, status, map[string]any{"error": err.Error(), "code": coded.Code(), "details": coded})
+		return
+	}
+
 	WriteJSON(w, status, map[string]string{"error": err.Error()})
 }
 
@@ -62,7 +89,7 @@ func GetTokenFromRequest(r *http.Request) string {
 	tokenQuery := r.URL.Query().Get("token")
 
 	if tokenAuth != "" {
-		return tokenAuth
+		return strings.TrimPrefix(tokenAuth, "Bearer ")
 	}
 
 	if tokenQuery != "" {
@@ -77,3 +104,89 @@ func NormalizeBase64(base64str string) string {
 	base64str = strings.ReplaceAll(base64str, ` `, `+`)
 	return base64str
 }
+
+// ClientIP returns the address of the client. The X-Forwarded-For and X-Real-IP
+// headers are only honoured when the service runs behind a trusted proxy.
+func ClientIP(r *http.Request, trustProxyHeaders bool) string {
+	if trustProxyHeaders {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			return strings.TrimSpace(first)
+		}
+		if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+			return realIP
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// RequestIDHeader carries the ID of a request, for example from a load
+// balancer, and is echoed in the response.
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// WithRequestID reuses a well-formed X-Request-ID of the request or generates
+// a new one, and makes it available through GetRequestID.
+func WithRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, id := ContextWithRequestID(r.Context(), r.Header.Get(RequestIDHeader))
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ContextWithRequestID reuses a well-formed id or generates a new one, and
+// returns it together with ctx carrying it for GetRequestID.
+func ContextWithRequestID(ctx context.Context, id string) (context.Context, string) {
+	if !requestIDPattern.MatchString(id) {
+		id = uuid.NewString()
+	}
+
+	return context.WithValue(ctx, requestIDKey{}, id), id
+}
+
+// GetRequestID returns the ID set by WithRequestID, or "" outside of a request.
+func GetRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// GetPagination reads the 1-based page and pageSize query parameters, falling
+// back to the first page of DefaultPageSize entries and capping the size at MaxPageSize.
+func GetPagination(r *http.Request) (page int, pageSize int, err error) {
+	query := r.URL.Query()
+	page, pageSize = 1, DefaultPageSize
+
+	if v := query.Get("page"); v != "" {
+		page, err = strconv.Atoi(v)
+		if err != nil || page < 1 {
+			return 0, 0, fmt.Errorf("invalid page: %q", v)
+		}
+	}
+
+	if v := query.Get("pageSize"); v != "" {
+		pageSize, err = strconv.Atoi(v)
+		if err != nil || pageSize < 1 {
+			return 0, 0, fmt.Errorf("invalid pageSize: %q", v)
+		}
+		if pageSize > MaxPageSize {
+			pageSize = MaxPageSize
+		}
+	}
+
+	return page, pageSize, nil
+}
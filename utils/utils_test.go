@@ -77,13 +77,81 @@ func TestGetTokenFromRequest(t *testing.T) {
 
 	assert.Equal(t, "headerToken", GetTokenFromRequest(r))
 
+	// Test with a bearer scheme prefix
+	r.Header.Set("Authorization", "Bearer headerToken")
+	assert.Equal(t, "headerToken", GetTokenFromRequest(r))
+
 	// Test without the Authorization header
 	r.Header.Del("Authorization")
 	assert.Equal(t, "queryToken", GetTokenFromRequest(r))
 }
 
+func TestGetEnvAsBool(t *testing.T) {
+	t.Setenv("TEST_BOOL", "true")
+	assert.True(t, GetEnvAsBool("TEST_BOOL", false))
+
+	t.Setenv("TEST_BOOL", "not-a-bool")
+	assert.False(t, GetEnvAsBool("TEST_BOOL", false))
+
+	assert.True(t, GetEnvAsBool("TEST_BOOL_MISSING", true))
+}
+
+func TestClientIP(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.1:5000"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+
+	assert.Equal(t, "10.0.0.1", ClientIP(r, false))
+	assert.Equal(t, "203.0.113.7", ClientIP(r, true))
+
+	r.Header.Del("X-Forwarded-For")
+	r.Header.Set("X-Real-IP", "203.0.113.8")
+	assert.Equal(t, "203.0.113.8", ClientIP(r, true))
+}
+
 func TestNormalizeBase64(t *testing.T) {
 	input := "a\\// b"
 	expected := "a//+b"
 	assert.Equal(t, expected, NormalizeBase64(input))
 }
+
+func TestGetPagination(t *testing.T) {
+	page, pageSize, err := GetPagination(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, page)
+	assert.Equal(t, DefaultPageSize, pageSize)
+
+	page, pageSize, err = GetPagination(httptest.NewRequest(http.MethodGet, "/?page=3&pageSize=500", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, page)
+	assert.Equal(t, MaxPageSize, pageSize)
+
+	_, _, err = GetPagination(httptest.NewRequest(http.MethodGet, "/?page=0", nil))
+	assert.NotNil(t, err)
+
+	_, _, err = GetPagination(httptest.NewRequest(http.MethodGet, "/?pageSize=abc", nil))
+	assert.NotNil(t, err)
+}
+
+func TestWithRequestID(t *testing.T) {
+	var seen string
+	handler := WithRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = GetRequestID(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "lb-1234")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if seen != "lb-1234" || rr.Header().Get(RequestIDHeader) != "lb-1234" {
+		t.Errorf("expected the request ID of the header, got %q", seen)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "not a valid\nid")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if seen == "" || seen == "not a valid\nid" || rr.Header().Get(RequestIDHeader) != seen {
+		t.Errorf("expected a generated request ID, got %q", seen)
+	}
+}